@@ -0,0 +1,312 @@
+package airdrop
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// EscrowAddress is the account holding airdrop funds until they are claimed or returned to the treasury
+const EscrowAddress = "airdrop_escrow"
+
+// Airdrop transaction types
+const (
+    TxTypeAirdropCreate = "airdrop_create" // Sender: the operator; Amount: funds moved into the airdrop; Data: airdrop, its ID; root, the Merkle root of its allocations; expiresAt, Unix time claims close
+    TxTypeAirdropClaim  = "airdrop_claim"  // Sender: the recipient; Data: airdrop; amount, the recipient's allocation; proof, the Merkle path from its leaf to the root
+)
+
+// Airdrop statuses
+const (
+    StatusActive  = "active"  // Open for claims until it expires
+    StatusExpired = "expired" // Closed; what was left unclaimed went to the treasury
+)
+
+// Airdrop is funds an operator committed to a Merkle root of allocations, claimed by each recipient with a proof
+type Airdrop struct {
+    ID        string                  `json:"id"`
+    Operator  string                  `json:"operator"`
+    Root      string                  `json:"root"` // Merkle root of the allocations' leaves
+    Funded    token.Amount            `json:"funded"`
+    Claimed   token.Amount            `json:"claimed"`
+    ExpiresAt int64                   `json:"expiresAt"` // Unix time from which nothing more can be claimed
+    Height    int64                   `json:"height"`    // Block that created it
+    Status    string                  `json:"status"`
+    Returned  token.Amount            `json:"returned,omitempty"` // Sent to the treasury unclaimed at expiry
+    Claims    map[string]token.Amount `json:"claims"`             // Claimed by each recipient
+}
+
+// Remaining returns the funds an airdrop still holds
+func (a *Airdrop) Remaining() token.Amount {
+    return a.Funded - a.Claimed - a.Returned
+}
+
+// Airdrops is a module keeping the airdrops created on chain and paying out their claims
+// It is safe for concurrent use
+type Airdrops struct {
+    airdrops map[string]*Airdrop
+
+    // Time of the last block applied, which claims are checked against
+    blockTime int64
+
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// NewAirdrops creates an empty airdrop module
+func NewAirdrops() *Airdrops {
+    return &Airdrops{
+        airdrops: make(map[string]*Airdrop),
+    }
+}
+
+// GetAirdrop returns an airdrop and what has been claimed from it
+func (ad *Airdrops) GetAirdrop(id string) (Airdrop, error) {
+    ad.mutex.Lock()
+    defer ad.mutex.Unlock()
+
+    airdrop, exists := ad.airdrops[id]
+    if !exists {
+        return Airdrop{}, fmt.Errorf("airdrop %s not found", id)
+    }
+
+    return airdrop.clone(), nil
+}
+
+// GetAirdrops returns every airdrop, oldest first
+func (ad *Airdrops) GetAirdrops() []Airdrop {
+    ad.mutex.Lock()
+    defer ad.mutex.Unlock()
+
+    airdrops := make([]Airdrop, 0, len(ad.airdrops))
+    for _, airdrop := range ad.sortedAirdrops() {
+        airdrops = append(airdrops, airdrop.clone())
+    }
+
+    return airdrops
+}
+
+// CheckTransaction reports whether an airdrop transaction would succeed against the airdrops as of the latest block
+// Balances are only known when the transaction is applied, so funds the operator can't cover pass here
+func (ad *Airdrops) CheckTransaction(tx core.Transaction) error {
+    if tx.Type != TxTypeAirdropCreate && tx.Type != TxTypeAirdropClaim {
+        return nil
+    }
+
+    if err := core.VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+
+    ad.mutex.Lock()
+    defer ad.mutex.Unlock()
+
+    if tx.Type == TxTypeAirdropCreate {
+        return ad.checkCreate(tx, ad.blockTime)
+    }
+
+    return ad.checkClaim(tx, ad.blockTime)
+}
+
+// ApplyTransaction funds a new airdrop from its operator, or pays a recipient's claim out of the escrow
+// A transaction that fails its checks at the block's time, funds the operator can't cover, or a transaction its sender
+// didn't sign does nothing, since blocks from peers are applied without the admission checks
+func (ad *Airdrops) ApplyTransaction(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    if tx.Type != TxTypeAirdropCreate && tx.Type != TxTypeAirdropClaim {
+        return
+    }
+    if core.VerifyTransactionSignature(tx) != nil {
+        return
+    }
+
+    ad.mutex.Lock()
+    defer ad.mutex.Unlock()
+
+    switch tx.Type {
+    case TxTypeAirdropCreate:
+        if ad.checkCreate(tx, header.Timestamp) != nil {
+            return
+        }
+        if state.Transfer(tx.Sender, EscrowAddress, tx.Amount) != nil {
+            return
+        }
+
+        expiresAt, _ := txInt64(tx, "expiresAt")
+        ad.airdrops[txAirdrop(tx)] = &Airdrop{
+            ID:        txAirdrop(tx),
+            Operator:  tx.Sender,
+            Root:      txString(tx, "root"),
+            Funded:    tx.Amount,
+            ExpiresAt: expiresAt,
+            Height:    header.Index,
+            Status:    StatusActive,
+            Claims:    make(map[string]token.Amount),
+        }
+
+    case TxTypeAirdropClaim:
+        if ad.checkClaim(tx, header.Timestamp) != nil {
+            return
+        }
+        airdrop := ad.airdrops[txAirdrop(tx)]
+        amount, _ := token.DataAmount(txValue(tx, "amount"))
+        if state.Transfer(EscrowAddress, tx.Sender, amount) != nil {
+            return
+        }
+
+        airdrop.Claims[tx.Sender] = amount
+        airdrop.Claimed += amount
+    }
+}
+
+// EndBlock closes the airdrops that expired by the block's time and returns what they hold unclaimed to the treasury
+func (ad *Airdrops) EndBlock(header core.BlockHeader, state *core.State) {
+    ad.mutex.Lock()
+    defer ad.mutex.Unlock()
+
+    ad.blockTime = header.Timestamp
+
+    for _, airdrop := range ad.sortedAirdrops() {
+        if airdrop.Status != StatusActive || airdrop.ExpiresAt > header.Timestamp {
+            continue
+        }
+
+        airdrop.Status = StatusExpired
+        if remaining := airdrop.Remaining(); remaining > 0 && state.ReturnToTreasury(EscrowAddress, remaining) == nil {
+            airdrop.Returned = remaining
+        }
+    }
+}
+
+// Reset forgets every airdrop before the chain replays blocks into the module
+func (ad *Airdrops) Reset() {
+    ad.mutex.Lock()
+    defer ad.mutex.Unlock()
+
+    ad.airdrops = make(map[string]*Airdrop)
+    ad.blockTime = 0
+}
+
+// Fork returns an empty airdrop module
+func (ad *Airdrops) Fork() core.Module {
+    return NewAirdrops()
+}
+
+// checkCreate validates an airdrop creation at a block time
+// The caller must hold the lock
+func (ad *Airdrops) checkCreate(tx core.Transaction, now int64) error {
+    id := txAirdrop(tx)
+    if id == "" {
+        return errors.New("airdrop ID is required")
+    }
+    if _, exists := ad.airdrops[id]; exists {
+        return fmt.Errorf("airdrop %s already exists", id)
+    }
+    if tx.Amount <= 0 {
+        return errors.New("airdrop must be funded")
+    }
+    if txString(tx, "root") == "" {
+        return errors.New("merkle root is required")
+    }
+
+    expiresAt, ok := txInt64(tx, "expiresAt")
+    if !ok || expiresAt <= now {
+        return errors.New("airdrop must expire in the future")
+    }
+
+    return nil
+}
+
+// checkClaim validates a claim at a block time: the airdrop must be open, the recipient must not have claimed,
+// and the proof must lead from the recipient's leaf to the airdrop's root
+// The caller must hold the lock
+func (ad *Airdrops) checkClaim(tx core.Transaction, now int64) error {
+    airdrop, exists := ad.airdrops[txAirdrop(tx)]
+    if !exists {
+        return fmt.Errorf("airdrop %s not found", txAirdrop(tx))
+    }
+    if airdrop.Status != StatusActive || now >= airdrop.ExpiresAt {
+        return fmt.Errorf("airdrop %s has expired", airdrop.ID)
+    }
+    if _, claimed := airdrop.Claims[tx.Sender]; claimed {
+        return fmt.Errorf("%s has already claimed from airdrop %s", tx.Sender, airdrop.ID)
+    }
+
+    amount, ok := token.DataAmount(txValue(tx, "amount"))
+    if !ok || amount <= 0 {
+        return errors.New("claim amount must be positive")
+    }
+    proof, ok := txProof(tx)
+    if !ok || !core.VerifyMerkleProof(LeafHash(tx.Sender, amount), proof, airdrop.Root) {
+        return errors.New("invalid airdrop proof")
+    }
+    if amount > airdrop.Remaining() {
+        return fmt.Errorf("airdrop %s has only %s left", airdrop.ID, airdrop.Remaining())
+    }
+
+    return nil
+}
+
+// sortedAirdrops returns the airdrops ordered by creation height, then ID
+// The caller must hold the lock
+func (ad *Airdrops) sortedAirdrops() []*Airdrop {
+    airdrops := make([]*Airdrop, 0, len(ad.airdrops))
+    for _, airdrop := range ad.airdrops {
+        airdrops = append(airdrops, airdrop)
+    }
+    sort.Slice(airdrops, func(i, j int) bool {
+        if airdrops[i].Height != airdrops[j].Height {
+            return airdrops[i].Height < airdrops[j].Height
+        }
+        return airdrops[i].ID < airdrops[j].ID
+    })
+
+    return airdrops
+}
+
+// clone returns a copy of an airdrop that shares no memory with it
+func (a *Airdrop) clone() Airdrop {
+    copied := *a
+    copied.Claims = make(map[string]token.Amount, len(a.Claims))
+    for recipient, amount := range a.Claims {
+        copied.Claims[recipient] = amount
+    }
+
+    return copied
+}
+
+// txValue returns a value from a transaction's data
+func txValue(tx core.Transaction, key string) interface{} {
+    data, _ := tx.Data.(map[string]interface{})
+    return data[key]
+}
+
+// txString returns a string from a transaction's data
+func txString(tx core.Transaction, key string) string {
+    value, _ := txValue(tx, key).(string)
+    return value
+}
+
+// txInt64 returns an integer from a transaction's data, whether it was built locally or decoded from JSON
+func txInt64(tx core.Transaction, key string) (int64, bool) {
+    switch value := txValue(tx, key).(type) {
+    case int64:
+        return value, true
+    case int:
+        return int64(value), true
+    case float64:
+        return int64(value), true
+    case json.Number:
+        parsed, err := value.Int64()
+        return parsed, err == nil
+    }
+
+    return 0, false
+}
+
+// txAirdrop returns the airdrop an airdrop transaction is for
+func txAirdrop(tx core.Transaction) string {
+    return txString(tx, "airdrop")
+}
@@ -0,0 +1,114 @@
+package airdrop
+
+import (
+    "testing"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TestAirdropCreateClaimExpire checks that an airdrop built locally, with an integer expiry, is funded, pays a claim
+// with a valid proof, and returns what is left unclaimed to the treasury once it expires
+func TestAirdropCreateClaimExpire(t *testing.T) {
+    keys := make([]*crypto.KeyPair, 3)
+    for i := range keys {
+        keyPair, err := crypto.GenerateKeyPair()
+        if err != nil {
+            t.Fatal(err)
+        }
+        keys[i] = keyPair
+    }
+    operator, alice, bob := keys[0], keys[1], keys[2]
+    operatorAddress := crypto.GetAddressFromPublicKey(operator.PublicKey)
+    aliceAddress := crypto.GetAddressFromPublicKey(alice.PublicKey)
+    bobAddress := crypto.GetAddressFromPublicKey(bob.PublicKey)
+
+    state := core.NewState()
+    state.Mint(operatorAddress, 100*token.ILYZ)
+
+    allocations := []Allocation{
+        {Address: aliceAddress, Amount: 30 * token.ILYZ},
+        {Address: bobAddress, Amount: 70 * token.ILYZ},
+    }
+    const created, expiresAt = int64(1000), int64(2000)
+
+    ad := NewAirdrops()
+    create := signed(t, operator, core.Transaction{
+        Type:   TxTypeAirdropCreate,
+        Sender: operatorAddress,
+        Amount: 100 * token.ILYZ,
+        Data: map[string]interface{}{
+            "airdrop":   "launch",
+            "root":      ComputeRoot(allocations),
+            "expiresAt": expiresAt,
+        },
+        Timestamp: created,
+    })
+    ad.ApplyTransaction(create, core.BlockHeader{Index: 1, Timestamp: created}, state)
+    ad.EndBlock(core.BlockHeader{Index: 1, Timestamp: created}, state)
+
+    airdrop, err := ad.GetAirdrop("launch")
+    if err != nil {
+        t.Fatalf("airdrop with an integer expiry was not created: %v", err)
+    }
+    if airdrop.ExpiresAt != expiresAt || airdrop.Funded != 100*token.ILYZ {
+        t.Fatalf("airdrop expires at %d funded with %s", airdrop.ExpiresAt, airdrop.Funded)
+    }
+    if state.Balances[EscrowAddress] != 100*token.ILYZ || state.Balances[operatorAddress] != 0 {
+        t.Fatalf("escrow holds %s and operator %s", state.Balances[EscrowAddress], state.Balances[operatorAddress])
+    }
+
+    allocation, proof, err := BuildProof(allocations, aliceAddress)
+    if err != nil {
+        t.Fatal(err)
+    }
+    claim := signed(t, alice, ClaimTransaction("launch", allocation, proof, created+1))
+    if err := ad.CheckTransaction(claim); err != nil {
+        t.Fatalf("valid claim rejected: %v", err)
+    }
+    ad.ApplyTransaction(claim, core.BlockHeader{Index: 2, Timestamp: created + 1}, state)
+    ad.EndBlock(core.BlockHeader{Index: 2, Timestamp: created + 1}, state)
+
+    if state.Balances[aliceAddress] != 30*token.ILYZ {
+        t.Fatalf("claimant holds %s, want 30", state.Balances[aliceAddress])
+    }
+    if err := ad.CheckTransaction(claim); err == nil {
+        t.Fatal("second claim by the same recipient was accepted")
+    }
+
+    // Bob never claims, so his allocation goes to the treasury at expiry and can't be claimed after
+    ad.EndBlock(core.BlockHeader{Index: 3, Timestamp: expiresAt}, state)
+
+    airdrop, _ = ad.GetAirdrop("launch")
+    if airdrop.Status != StatusExpired || airdrop.Returned != 70*token.ILYZ {
+        t.Fatalf("airdrop is %s having returned %s, want expired having returned 70", airdrop.Status, airdrop.Returned)
+    }
+    if state.Balances[core.TreasuryAddress] != 70*token.ILYZ || state.Balances[EscrowAddress] != 0 {
+        t.Fatalf("treasury holds %s and escrow %s", state.Balances[core.TreasuryAddress], state.Balances[EscrowAddress])
+    }
+
+    allocation, proof, err = BuildProof(allocations, bobAddress)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := ad.CheckTransaction(signed(t, bob, ClaimTransaction("launch", allocation, proof, expiresAt+1))); err == nil {
+        t.Fatal("claim after expiry was accepted")
+    }
+}
+
+// signed returns a transaction signed with a key pair
+func signed(t *testing.T, keyPair *crypto.KeyPair, tx core.Transaction) core.Transaction {
+    tx.PublicKey = crypto.PublicKeyToHex(keyPair.PublicKey)
+    tx.ID = core.ComputeTransactionID(tx)
+
+    payload, err := core.TransactionSigningPayload(tx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if tx.Signature, err = keyPair.Sign(payload); err != nil {
+        t.Fatal(err)
+    }
+
+    return tx
+}
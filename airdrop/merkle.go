@@ -0,0 +1,97 @@
+package airdrop
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Allocation is what one recipient may claim from an airdrop
+type Allocation struct {
+    Address string       `json:"address"`
+    Amount  token.Amount `json:"amount"`
+}
+
+// LeafHash returns the Merkle leaf committing to an allocation: the hash of the address and the amount in base units
+func LeafHash(address string, amount token.Amount) string {
+    hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", address, int64(amount))))
+    return hex.EncodeToString(hash[:])
+}
+
+// ComputeRoot returns the Merkle root an operator commits to when creating an airdrop for a list of allocations
+// Allocations are ordered by address first, so the root doesn't depend on the order they're listed in
+func ComputeRoot(allocations []Allocation) string {
+    return core.ComputeMerkleRoot(allocationLeaves(sortedAllocations(allocations)))
+}
+
+// BuildProof returns the proof a recipient claims their allocation with, from the same list of allocations as the root
+func BuildProof(allocations []Allocation, address string) (Allocation, []core.MerkleProofStep, error) {
+    sorted := sortedAllocations(allocations)
+    for i, allocation := range sorted {
+        if allocation.Address != address {
+            continue
+        }
+
+        steps, err := core.BuildMerkleProof(allocationLeaves(sorted), i)
+        if err != nil {
+            return Allocation{}, nil, err
+        }
+        return allocation, steps, nil
+    }
+
+    return Allocation{}, nil, fmt.Errorf("%s has no allocation", address)
+}
+
+// ClaimTransaction returns an unsigned transaction claiming an allocation from an airdrop with its proof
+func ClaimTransaction(id string, allocation Allocation, proof []core.MerkleProofStep, timestamp int64) core.Transaction {
+    return core.Transaction{
+        Type:   TxTypeAirdropClaim,
+        Sender: allocation.Address,
+        Data: map[string]interface{}{
+            "airdrop": id,
+            "amount":  allocation.Amount,
+            "proof":   proof,
+        },
+        Timestamp: timestamp,
+    }
+}
+
+// sortedAllocations returns a copy of a list of allocations ordered by address
+func sortedAllocations(allocations []Allocation) []Allocation {
+    sorted := append([]Allocation(nil), allocations...)
+    sort.Slice(sorted, func(i, j int) bool {
+        return sorted[i].Address < sorted[j].Address
+    })
+
+    return sorted
+}
+
+// allocationLeaves returns the Merkle leaves of a list of allocations, in order
+func allocationLeaves(allocations []Allocation) []string {
+    leaves := make([]string, len(allocations))
+    for i, allocation := range allocations {
+        leaves[i] = LeafHash(allocation.Address, allocation.Amount)
+    }
+
+    return leaves
+}
+
+// txProof reads the Merkle proof a claim carries, whether built locally or decoded from JSON
+func txProof(tx core.Transaction) ([]core.MerkleProofStep, bool) {
+    encoded, err := json.Marshal(txValue(tx, "proof"))
+    if err != nil {
+        return nil, false
+    }
+
+    var steps []core.MerkleProofStep
+    if err := json.Unmarshal(encoded, &steps); err != nil {
+        return nil, false
+    }
+
+    return steps, true
+}
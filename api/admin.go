@@ -0,0 +1,291 @@
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "net"
+    "net/http"
+
+    "github.com/txaimhawj/chulubmeadditional-files/consensus"
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// AdminServer exposes operator-only endpoints and should only listen on a local address
+type AdminServer struct {
+    Blockchain *core.Blockchain
+    Consensus  *consensus.ProofOfPlay
+    server     *http.Server
+}
+
+// RollbackRequest is the body of POST /admin/rollback
+type RollbackRequest struct {
+    Height int64 `json:"height"`
+}
+
+// RollbackResponse reports the result of a rollback
+type RollbackResponse struct {
+    Height               int64 `json:"height"`
+    RemovedBlocks        int   `json:"removedBlocks"`
+    RevertedTransactions int   `json:"revertedTransactions"`
+}
+
+// MatchRewardRequest is the body of POST /admin/matches/{id}/rewards
+type MatchRewardRequest struct {
+    Player      string  `json:"player"`
+    Duration    int64   `json:"duration"`    // Seconds the match lasted
+    Rank        int     `json:"rank"`
+    Performance float64 `json:"performance"` // Score between 0 and 100
+    Pool        string  `json:"pool,omitempty"`
+    Mode        string  `json:"mode,omitempty"` // Game mode the match was played in, for the season's multiplier
+}
+
+// MatchSettlementRequest is the body of POST /admin/matches/{id}/settlement
+type MatchSettlementRequest struct {
+    Server  string               `json:"server"` // Game server that hosted the match and sends its result
+    Players []token.PlayerResult `json:"players"`
+    Pool    string               `json:"pool,omitempty"`
+    Mode    string               `json:"mode,omitempty"` // Game mode the match was played in, for the season's multiplier
+}
+
+// MatchSettlementResponse is the rewards held in escrow for a settled match and the game result reporting them all,
+// for the game server to sign and submit
+type MatchSettlementResponse struct {
+    Escrow      token.MatchEscrow `json:"escrow"`
+    Transaction core.Transaction  `json:"transaction"`
+}
+
+// VoidMatchResponse reports the rewards a voided match returned to the budget
+type VoidMatchResponse struct {
+    MatchID  string       `json:"matchId"`
+    Returned token.Amount `json:"returned"`
+}
+
+// NewAdminServer creates a new admin API for the given chain and consensus engine
+func NewAdminServer(blockchain *core.Blockchain, pop *consensus.ProofOfPlay) *AdminServer {
+    return &AdminServer{
+        Blockchain: blockchain,
+        Consensus:  pop,
+    }
+}
+
+// Handler returns the HTTP handler serving all admin routes
+func (as *AdminServer) Handler() http.Handler {
+    mux := http.NewServeMux()
+    mux.HandleFunc("POST /admin/rollback", as.handleRollback)
+    mux.HandleFunc("POST /admin/replay", as.handleReplay)
+    mux.HandleFunc("POST /admin/reward-pools", as.handleAddRewardPool)
+    mux.HandleFunc("POST /admin/matches/{id}/rewards", as.handleReserveMatchReward)
+    mux.HandleFunc("POST /admin/matches/{id}/settlement", as.handleSettleMatch)
+    mux.HandleFunc("DELETE /admin/matches/{id}/rewards", as.handleVoidMatchRewards)
+    return mux
+}
+
+// Start starts serving the admin API on the given address (e.g. "127.0.0.1:8081")
+func (as *AdminServer) Start(address string) error {
+    if as.server != nil {
+        return errors.New("admin server is already running")
+    }
+
+    // Listen before returning so a bad or busy address is reported to the caller
+    listener, err := net.Listen("tcp", address)
+    if err != nil {
+        return err
+    }
+
+    as.server = &http.Server{
+        Addr:    address,
+        Handler: as.Handler(),
+    }
+
+    go as.server.Serve(listener)
+
+    return nil
+}
+
+// Stop stops the admin server
+func (as *AdminServer) Stop() error {
+    if as.server == nil {
+        return errors.New("admin server is not running")
+    }
+
+    err := as.server.Close()
+    as.server = nil
+
+    return err
+}
+
+// handleRollback handles POST /admin/rollback
+func (as *AdminServer) handleRollback(w http.ResponseWriter, r *http.Request) {
+    var request RollbackRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid request body")
+        return
+    }
+
+    removed, err := as.Blockchain.RollbackTo(request.Height)
+    if err != nil {
+        if errors.Is(err, core.ErrStatePruned) {
+            writeStateError(w, err)
+            return
+        }
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    reverted := 0
+    for _, block := range removed {
+        for _, tx := range block.Transactions {
+            if !core.IsProducerTransaction(tx) {
+                reverted++
+            }
+        }
+    }
+
+    writeJSON(w, http.StatusOK, RollbackResponse{
+        Height:               request.Height,
+        RemovedBlocks:        len(removed),
+        RevertedTransactions: reverted,
+    })
+}
+
+// handleReplay handles POST /admin/replay, optionally with ?stopOnMismatch=true
+func (as *AdminServer) handleReplay(w http.ResponseWriter, r *http.Request) {
+    stopOnMismatch := r.URL.Query().Get("stopOnMismatch") == "true"
+
+    report, _, err := as.Blockchain.Replay(stopOnMismatch)
+    if err != nil {
+        writeError(w, http.StatusConflict, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, report)
+}
+
+// handleAddRewardPool handles POST /admin/reward-pools, opening a tournament or event reward pool
+func (as *AdminServer) handleAddRewardPool(w http.ResponseWriter, r *http.Request) {
+    if as.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    var pool token.RewardPool
+    if err := json.NewDecoder(r.Body).Decode(&pool); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid request body")
+        return
+    }
+
+    if err := as.Blockchain.Economics.AddRewardPool(pool); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    created, _ := as.Blockchain.Economics.GetRewardPool(pool.ID)
+    writeJSON(w, http.StatusCreated, created)
+}
+
+// handleReserveMatchReward handles POST /admin/matches/{id}/rewards, holding a player's reward for a match in escrow
+// until the game server's result for it is finalized; the escrow returned is what the result must report
+func (as *AdminServer) handleReserveMatchReward(w http.ResponseWriter, r *http.Request) {
+    if as.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    var request MatchRewardRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid request body")
+        return
+    }
+
+    matchID := r.PathValue("id")
+    if _, err := as.Blockchain.GetMatchResult(matchID); err == nil {
+        writeError(w, http.StatusConflict, "match has already been reported")
+        return
+    }
+
+    // A player still in their first days after being referred earns their referrer a share beside the reward,
+    // reserved together with it so the match is never half reserved
+    var referral token.PlayerReferral
+    if bound, err := as.Blockchain.GetReferral(request.Player); err == nil {
+        referral = token.PlayerReferral{Referrer: bound.Referrer, BoundAt: bound.BoundAt}
+    }
+
+    _, err := as.Blockchain.Economics.ReserveGameReward(matchID, request.Player, request.Duration, request.Rank,
+        request.Performance, request.Pool, request.Mode, referral)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    escrow, _ := as.Blockchain.Economics.GetMatchEscrow(matchID)
+    writeJSON(w, http.StatusCreated, escrow)
+}
+
+// handleSettleMatch handles POST /admin/matches/{id}/settlement, holding every player's reward for a match in escrow
+// at once, with the limits applied across the match, and returning the unsigned game result that reports them
+func (as *AdminServer) handleSettleMatch(w http.ResponseWriter, r *http.Request) {
+    if as.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    var request MatchSettlementRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid request body")
+        return
+    }
+    if request.Server == "" {
+        writeError(w, http.StatusBadRequest, "server is required")
+        return
+    }
+
+    matchID := r.PathValue("id")
+    if _, err := as.Blockchain.GetMatchResult(matchID); err == nil {
+        writeError(w, http.StatusConflict, "match has already been reported")
+        return
+    }
+
+    // Players still in their first days after being referred earn their referrers a share beside their rewards,
+    // reserved together with the rewards so the match is never half settled
+    referrals := make(map[string]token.PlayerReferral)
+    for _, result := range request.Players {
+        if referral, err := as.Blockchain.GetReferral(result.Player); err == nil {
+            referrals[result.Player] = token.PlayerReferral{Referrer: referral.Referrer, BoundAt: referral.BoundAt}
+        }
+    }
+
+    escrow, err := as.Blockchain.Economics.SettleMatch(matchID, request.Players, referrals, request.Pool, request.Mode)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusCreated, MatchSettlementResponse{
+        Escrow:      escrow,
+        Transaction: core.GameResultTransaction(request.Server, escrow, as.Blockchain.Clock.Now()),
+    })
+}
+
+// handleVoidMatchRewards handles DELETE /admin/matches/{id}/rewards, returning the rewards of a match abandoned
+// before its result was reported to the budget; a reported match is voided on chain by its game server instead
+func (as *AdminServer) handleVoidMatchRewards(w http.ResponseWriter, r *http.Request) {
+    if as.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    matchID := r.PathValue("id")
+    if _, err := as.Blockchain.GetMatchResult(matchID); err == nil {
+        writeError(w, http.StatusConflict, "match has been reported; its game server must void it on chain")
+        return
+    }
+
+    returned, err := as.Blockchain.Economics.VoidMatchRewards(matchID)
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, VoidMatchResponse{MatchID: matchID, Returned: returned})
+}
@@ -0,0 +1,1854 @@
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/airdrop"
+    "github.com/txaimhawj/chulubmeadditional-files/consensus"
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/nft"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Default and maximum page sizes for paginated REST responses
+const (
+    DefaultPageLimit = 20
+    MaxPageLimit     = 100
+)
+
+// RESTServer exposes chain and NFT data over plain HTTP/JSON for block explorers and game backends
+type RESTServer struct {
+    Blockchain    *core.Blockchain
+    NFTSystem     *nft.NFTSystem
+    Subscriptions *WebSocketServer
+
+    // Consensus engine whose block votes are served, if any
+    Consensus *consensus.ProofOfPlay
+
+    // Fetches and verifies off-chain NFT metadata
+    Metadata *nft.MetadataFetcher
+
+    // Tallies emission for the supply reports, if any
+    Emission *core.EmissionReport
+
+    // Prices ILYZ in fiat from reporters' signed submissions, if any
+    Oracle token.Oracle
+
+    // Merkle airdrops and their claims, if any
+    Airdrops *airdrop.Airdrops
+
+    // Called after a submitted transaction enters the mempool, e.g. to gossip it to peers
+    OnTransaction func(tx core.Transaction)
+
+    server *http.Server
+}
+
+// Page is the envelope returned by every paginated endpoint
+type Page struct {
+    Data  interface{} `json:"data"`
+    Page  int         `json:"page"`
+    Limit int         `json:"limit"`
+    Total int         `json:"total"`
+}
+
+// StatusResponse summarizes the node's view of the chain
+type StatusResponse struct {
+    ChainID             string `json:"chainId"`
+    Height              int64  `json:"height"`
+    LatestHash          string `json:"latestHash"`
+    FinalizedHeight     int64  `json:"finalizedHeight"` // Highest block decided by precommits, or -1
+    FinalizedHash       string `json:"finalizedHash,omitempty"`
+    PendingTransactions int    `json:"pendingTransactions"`
+}
+
+// BalanceResponse is an address's balance as of a block height
+type BalanceResponse struct {
+    Address string                  `json:"address"`
+    Balance token.Amount            `json:"balance"`          // ILYZ
+    Assets  map[string]token.Amount `json:"assets,omitempty"` // Other assets held, by asset ID
+    Locked  token.Amount            `json:"locked,omitempty"` // ILYZ staked in open tournaments, not part of the balance
+    Height  int64                   `json:"height"`
+}
+
+// TransactionResponse is a confirmed transaction together with the height of its block
+type TransactionResponse struct {
+    Transaction core.Transaction `json:"transaction"`
+    BlockHeight int64            `json:"blockHeight"`
+}
+
+// CollectionResponse is a collection together with its market stats
+type CollectionResponse struct {
+    *nft.Collection
+    Stats nft.CollectionStats `json:"stats"`
+}
+
+// DelegatorResponse is a token holder's delegations and the delegated stake it has moving
+type DelegatorResponse struct {
+    Delegator     string                   `json:"delegator"`
+    Delegations   []consensus.Delegation   `json:"delegations"`
+    Undelegations []consensus.Undelegation `json:"undelegations"`
+    Redelegations []consensus.Redelegation `json:"redelegations"`
+}
+
+// ValidatorStakesResponse is the NFTs staked to a validator and the boost they give its weight
+type ValidatorStakesResponse struct {
+    Validator string     `json:"validator"`
+    Boost     float64    `json:"boost"`
+    NFTs      []*nft.NFT `json:"nfts"`
+}
+
+// NFTProofResponse is an NFT inclusion proof together with the state root the registry root is part of
+type NFTProofResponse struct {
+    *nft.NFTProof
+    Root  string               `json:"root"`
+    State core.StateCommitment `json:"state"`
+}
+
+// NFTSnapshotResponse is a registry snapshot together with the state root its root is part of
+type NFTSnapshotResponse struct {
+    *nft.RegistrySnapshot
+    State core.StateCommitment `json:"state"`
+}
+
+// EmissionScheduleResponse is the emission schedule the chain mints by and where the current year stands in it
+type EmissionScheduleResponse struct {
+    Hash      string               `json:"hash"` // Peers only connect to nodes with the same hash
+    Schedule  token.SupplySchedule `json:"schedule"`
+    Year      int                  `json:"year"`
+    YearlyCap token.Amount         `json:"yearlyCap"`
+    Remaining token.Amount         `json:"remaining"` // Left to mint this year
+}
+
+// ReferralProgramResponse is the referral program and what is left of its budget this year
+type ReferralProgramResponse struct {
+    Program   token.ReferralProgram `json:"program"`
+    Paid      token.Amount          `json:"paid"` // Reserved or minted this year
+    Remaining token.Amount          `json:"remaining"`
+}
+
+// SeasonsResponse is the season calendar game rewards are scaled by and the season running, if any
+type SeasonsResponse struct {
+    Seasons []token.Season `json:"seasons"`
+    Current *token.Season  `json:"current,omitempty"`
+}
+
+// BaseFeeResponse is the least fee the next block's transactions must pay and the market that set it
+type BaseFeeResponse struct {
+    BaseFee token.Amount    `json:"baseFee"`
+    Surplus token.Amount    `json:"surplus"` // Burned from each transaction's fee
+    Market  token.FeeMarket `json:"market"`
+}
+
+// NewRESTServer creates a new REST gateway for the given chain and NFT system
+func NewRESTServer(blockchain *core.Blockchain, nftSystem *nft.NFTSystem) *RESTServer {
+    return &RESTServer{
+        Blockchain:    blockchain,
+        NFTSystem:     nftSystem,
+        Subscriptions: NewWebSocketServer(blockchain.Events),
+        Metadata:      nft.NewMetadataFetcher(nft.DefaultIPFSGateway),
+    }
+}
+
+// Handler returns the HTTP handler serving all REST routes
+func (rs *RESTServer) Handler() http.Handler {
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /status", rs.handleGetStatus)
+    mux.HandleFunc("GET /metrics", rs.handleGetMetrics)
+    mux.HandleFunc("GET /supply", rs.handleGetSupply)
+    mux.HandleFunc("GET /fees", rs.handleGetChainFeePolicy)
+    mux.HandleFunc("GET /fees/base", rs.handleGetBaseFee)
+    mux.HandleFunc("GET /treasury", rs.handleGetTreasury)
+    mux.HandleFunc("GET /treasury/spends", rs.handleGetTreasurySpends)
+    mux.HandleFunc("GET /emission/ceilings", rs.handleGetEmissionCeilings)
+    mux.HandleFunc("GET /emission/schedule", rs.handleGetEmissionSchedule)
+    mux.HandleFunc("GET /reports/supply", rs.handleGetSupplyReport)
+    mux.HandleFunc("GET /reports/emission", rs.handleGetEmissionReport)
+    mux.HandleFunc("GET /reward-pools", rs.handleGetRewardPools)
+    mux.HandleFunc("GET /seasons", rs.handleGetSeasons)
+    mux.HandleFunc("GET /reward-pools/{id}", rs.handleGetRewardPool)
+    mux.HandleFunc("GET /matches/{id}", rs.handleGetMatch)
+    mux.HandleFunc("GET /tournaments/{id}", rs.handleGetTournament)
+    mux.HandleFunc("GET /airdrops", rs.handleGetAirdrops)
+    mux.HandleFunc("GET /airdrops/{id}", rs.handleGetAirdrop)
+    mux.HandleFunc("GET /assets", rs.handleGetAssets)
+    mux.HandleFunc("GET /assets/{id}", rs.handleGetAsset)
+    mux.HandleFunc("GET /blocks/{height}", rs.handleGetBlock)
+    mux.HandleFunc("GET /blocks/{height}/header", rs.handleGetHeader)
+    mux.HandleFunc("GET /blocks/{height}/votes", rs.handleGetBlockVotes)
+    mux.HandleFunc("GET /blocks/{height}/rounds", rs.handleGetBlockRounds)
+    mux.HandleFunc("GET /blocks/{height}/proposers", rs.handleGetBlockProposers)
+    mux.HandleFunc("GET /finalized", rs.handleGetFinalized)
+    mux.HandleFunc("GET /headers", rs.handleGetHeaders)
+    mux.HandleFunc("GET /bodies/{hash}", rs.handleGetBody)
+    mux.HandleFunc("GET /txs/{id}/proof", rs.handleGetTransactionProof)
+    mux.HandleFunc("GET /txs/{id}/receipt", rs.handleGetRewardReceipt)
+    mux.HandleFunc("GET /txs/{id}", rs.handleGetTransaction)
+    mux.HandleFunc("POST /txs", rs.handleSubmitTransaction)
+    mux.HandleFunc("POST /attestations", rs.handleSubmitAttestation)
+    mux.HandleFunc("GET /addresses/{addr}/txs", rs.handleGetAddressTransactions)
+    mux.HandleFunc("GET /addresses/{addr}/balance", rs.handleGetAddressBalance)
+    mux.HandleFunc("GET /addresses/{addr}/vesting", rs.handleGetAddressVesting)
+    mux.HandleFunc("GET /addresses/{addr}/usable-nfts", rs.handleGetUsableNFTs)
+    mux.HandleFunc("GET /addresses/{addr}/trades", rs.handleGetAddressTrades)
+    mux.HandleFunc("GET /addresses/{addr}/delegations", rs.handleGetAddressDelegations)
+    mux.HandleFunc("GET /addresses/{addr}/locks", rs.handleGetAddressLocks)
+    mux.HandleFunc("GET /addresses/{addr}/referral", rs.handleGetAddressReferral)
+    mux.HandleFunc("GET /addresses/{addr}/referrals", rs.handleGetAddressReferrals)
+    mux.HandleFunc("GET /referrals/program", rs.handleGetReferralProgram)
+    mux.HandleFunc("GET /prices/{currency}", rs.handleGetPrice)
+    mux.HandleFunc("POST /prices", rs.handleSubmitPrice)
+    mux.HandleFunc("GET /validators", rs.handleGetValidators)
+    mux.HandleFunc("GET /validators/pending", rs.handleGetPendingValidators)
+    mux.HandleFunc("GET /validators/{addr}", rs.handleGetValidator)
+    mux.HandleFunc("GET /validators/{addr}/stakes", rs.handleGetValidatorStakes)
+    mux.HandleFunc("GET /validators/{addr}/delegations", rs.handleGetValidatorDelegations)
+    mux.HandleFunc("GET /validators/{addr}/commission", rs.handleGetValidatorCommission)
+    mux.HandleFunc("GET /validators/{addr}/liveness", rs.handleGetValidatorLiveness)
+    mux.HandleFunc("GET /governance/proposals", rs.handleGetProposals)
+    mux.HandleFunc("GET /governance/proposals/{id}", rs.handleGetProposal)
+    mux.HandleFunc("GET /governance/params", rs.handleGetGovernedParams)
+    mux.HandleFunc("GET /governance/yield", rs.handleGetYieldRate)
+    mux.HandleFunc("GET /governance/params/versions", rs.handleGetParamVersions)
+    mux.HandleFunc("GET /governance/params/versions/{version}", rs.handleGetParamVersion)
+    mux.HandleFunc("GET /evidence", rs.handleGetEvidence)
+    mux.HandleFunc("GET /evidence/pending", rs.handleGetPendingEvidence)
+    mux.HandleFunc("GET /nfts", rs.handleGetNFTs)
+    mux.HandleFunc("GET /nfts/search", rs.handleSearchNFTs)
+    mux.HandleFunc("GET /nfts/snapshot", rs.handleGetNFTSnapshot)
+    mux.HandleFunc("GET /nfts/{id}/proof", rs.handleGetNFTProof)
+    mux.HandleFunc("GET /nfts/{id}/provenance", rs.handleGetNFTProvenance)
+    mux.HandleFunc("GET /nfts/{id}/metadata", rs.handleGetNFTMetadata)
+    mux.HandleFunc("GET /nfts/{id}/yield", rs.handleGetNFTYieldProjection)
+    mux.HandleFunc("GET /nfts/{id}/sales", rs.handleGetNFTSales)
+    mux.HandleFunc("GET /nfts/{id}/games/{game}", rs.handleGetNFTGameMetadata)
+    mux.HandleFunc("GET /market", rs.handleGetMarket)
+    mux.HandleFunc("GET /market/stats", rs.handleGetMarketStats)
+    mux.HandleFunc("GET /market/types", rs.handleGetMarketTypes)
+    mux.HandleFunc("GET /market/orderbook", rs.handleGetOrderBook)
+    mux.HandleFunc("GET /market/fees", rs.handleGetFeePolicy)
+    mux.HandleFunc("GET /orders/{id}", rs.handleGetOrder)
+    mux.HandleFunc("GET /collections/{id}", rs.handleGetCollection)
+    mux.HandleFunc("GET /drops", rs.handleGetDrops)
+    mux.HandleFunc("GET /drops/{id}", rs.handleGetDrop)
+    mux.HandleFunc("GET /auctions", rs.handleGetAuctions)
+    mux.HandleFunc("GET /auctions/{id}", rs.handleGetAuction)
+    mux.Handle("GET /ws", rs.Subscriptions)
+    return mux
+}
+
+// Start starts serving the REST API on the given address (e.g. ":8080")
+func (rs *RESTServer) Start(address string) error {
+    if rs.server != nil {
+        return errors.New("REST server is already running")
+    }
+
+    // Listen before returning so a bad or busy address is reported to the caller
+    listener, err := net.Listen("tcp", address)
+    if err != nil {
+        return err
+    }
+
+    rs.server = &http.Server{
+        Addr:    address,
+        Handler: rs.Handler(),
+    }
+
+    go rs.server.Serve(listener)
+
+    return nil
+}
+
+// Stop stops the REST server
+func (rs *RESTServer) Stop() error {
+    if rs.server == nil {
+        return errors.New("REST server is not running")
+    }
+
+    err := rs.server.Close()
+    rs.server = nil
+
+    return err
+}
+
+// handleGetStatus handles GET /status
+func (rs *RESTServer) handleGetStatus(w http.ResponseWriter, r *http.Request) {
+    latestBlock := rs.Blockchain.GetLatestBlock()
+
+    status := StatusResponse{
+        ChainID:             rs.Blockchain.ChainID,
+        Height:              latestBlock.Index,
+        LatestHash:          latestBlock.Hash,
+        FinalizedHeight:     -1,
+        PendingTransactions: rs.Blockchain.GetPendingTransactionCount(),
+    }
+    if rs.Consensus != nil {
+        if head, found := rs.Consensus.FinalizedHead(); found {
+            status.FinalizedHeight = head.Height
+            status.FinalizedHash = head.BlockHash
+        }
+    }
+
+    writeJSON(w, http.StatusOK, status)
+}
+
+// handleGetMetrics handles GET /metrics in the Prometheus text exposition format
+func (rs *RESTServer) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
+    stats := rs.Blockchain.Stats()
+
+    type metric struct {
+        name       string
+        metricType string
+        help       string
+        value      float64
+    }
+
+    metrics := []metric{
+        {"nexuschain_height", "gauge", "Height of the latest block", float64(stats.Height)},
+        {"nexuschain_block_interval_seconds", "gauge", "Average seconds between recent blocks", stats.AverageBlockInterval},
+        {"nexuschain_tx_per_second", "gauge", "Confirmed transactions per second over recent blocks", stats.TxPerSecond},
+        {"nexuschain_mempool_depth", "gauge", "Number of pending transactions", float64(stats.MempoolDepth)},
+        {"nexuschain_transactions_total", "counter", "Confirmed transactions on the canonical chain", float64(stats.TotalTransactions)},
+        {"nexuschain_reorgs_total", "counter", "Number of times blocks were removed from the chain tip", float64(stats.Reorgs)},
+        {"nexuschain_reorg_max_depth", "gauge", "Largest number of blocks removed in one reorg", float64(stats.MaxReorgDepth)},
+    }
+    if supply, err := rs.Blockchain.GetSupplyAt(stats.Height); err == nil {
+        metrics = append(metrics,
+            metric{"nexuschain_supply", "gauge", "ILYZ held by accounts or locked by modules", supply.Total.Float()},
+            metric{"nexuschain_fees_burned_total", "counter", "ILYZ of transaction fees destroyed rather than paid to block producers", supply.FeesBurned.Float()},
+        )
+    }
+    if rs.Consensus != nil {
+        rounds := rs.Consensus.GetRoundMetrics()
+        finalized := int64(-1)
+        if head, found := rs.Consensus.FinalizedHead(); found {
+            finalized = head.Height
+        }
+        metrics = append(metrics,
+            metric{"nexuschain_finalized_height", "gauge", "Height of the latest block decided by precommits, or -1", float64(finalized)},
+            metric{"nexuschain_skipped_rounds_total", "counter", "Consensus rounds that timed out without a block being proposed or finalized", float64(rounds.SkippedRounds)},
+            metric{"nexuschain_round_max", "gauge", "Highest round any height has been skipped to", float64(rounds.MaxRound)},
+        )
+    }
+
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    for _, metric := range metrics {
+        fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+        fmt.Fprintf(w, "# TYPE %s %s\n", metric.name, metric.metricType)
+        fmt.Fprintf(w, "%s %s\n", metric.name, strconv.FormatFloat(metric.value, 'g', -1, 64))
+    }
+}
+
+// handleGetSupply handles GET /supply, the token supply and fees burned, optionally at ?height=
+func (rs *RESTServer) handleGetSupply(w http.ResponseWriter, r *http.Request) {
+    height := rs.Blockchain.GetHeight()
+
+    if value := r.URL.Query().Get("height"); value != "" {
+        parsed, err := strconv.ParseInt(value, 10, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, "invalid block height")
+            return
+        }
+        height = parsed
+    }
+
+    supply, err := rs.Blockchain.GetSupplyAt(height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, supply)
+}
+
+// handleGetChainFeePolicy handles GET /fees, how the next block's transaction fees are split
+func (rs *RESTServer) handleGetChainFeePolicy(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, rs.Blockchain.GetFeePolicy())
+}
+
+// handleGetBaseFee handles GET /fees/base, the fee market's base fee for the next block
+func (rs *RESTServer) handleGetBaseFee(w http.ResponseWriter, r *http.Request) {
+    baseFee, market, ok := rs.Blockchain.GetBaseFee()
+    if !ok {
+        writeError(w, http.StatusNotImplemented, "fee market not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, BaseFeeResponse{
+        BaseFee: baseFee,
+        Surplus: market.Surplus(baseFee),
+        Market:  market,
+    })
+}
+
+// handleGetTreasury handles GET /treasury, the community treasury's balance and what has entered and left it,
+// as of ?height= or the latest block
+func (rs *RESTServer) handleGetTreasury(w http.ResponseWriter, r *http.Request) {
+    height := rs.Blockchain.GetHeight()
+
+    if value := r.URL.Query().Get("height"); value != "" {
+        parsed, err := strconv.ParseInt(value, 10, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, "invalid block height")
+            return
+        }
+        height = parsed
+    }
+
+    treasury, err := rs.Blockchain.GetTreasuryAt(height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, treasury)
+}
+
+// handleGetTreasurySpends handles GET /treasury/spends, the proposals that spend from the treasury and whether each was paid
+func (rs *RESTServer) handleGetTreasurySpends(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetTreasurySpends())
+}
+
+// handleGetEmissionCeilings handles GET /emission/ceilings, what this hour and day have minted against their ceilings
+func (rs *RESTServer) handleGetEmissionCeilings(w http.ResponseWriter, r *http.Request) {
+    if rs.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Blockchain.Economics.GetBreakerStatus())
+}
+
+// handleGetEmissionSchedule handles GET /emission/schedule, the yearly supply caps and the current year's
+func (rs *RESTServer) handleGetEmissionSchedule(w http.ResponseWriter, r *http.Request) {
+    if rs.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    schedule, year := rs.Blockchain.Economics.GetSchedule()
+    hash, err := core.CanonicalHash(schedule)
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, EmissionScheduleResponse{
+        Hash:      hash,
+        Schedule:  schedule,
+        Year:      year,
+        YearlyCap: schedule.CapForYear(year),
+        Remaining: rs.Blockchain.Economics.GetRemainingYearlySupply(),
+    })
+}
+
+// handleGetSupplyReport handles GET /reports/supply, the supply, burned total, staking ratio, this day's, month's
+// and year's emission and the supply projected for the end of the year
+func (rs *RESTServer) handleGetSupplyReport(w http.ResponseWriter, r *http.Request) {
+    if rs.Emission == nil {
+        writeError(w, http.StatusNotImplemented, "emission report not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Emission.GetReport())
+}
+
+// handleGetEmissionReport handles GET /reports/emission?period=, what every day, month or year minted and burned
+func (rs *RESTServer) handleGetEmissionReport(w http.ResponseWriter, r *http.Request) {
+    if rs.Emission == nil {
+        writeError(w, http.StatusNotImplemented, "emission report not available")
+        return
+    }
+
+    granularity := r.URL.Query().Get("period")
+    if granularity == "" {
+        granularity = core.PeriodDay
+    }
+
+    emission, known := rs.Emission.GetEmission(granularity)
+    if !known {
+        writeError(w, http.StatusBadRequest, "period must be day, month or year")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, emission)
+}
+
+// handleGetRewardPools handles GET /reward-pools, the tournament and event reward pools by start time
+func (rs *RESTServer) handleGetRewardPools(w http.ResponseWriter, r *http.Request) {
+    if rs.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Blockchain.Economics.GetRewardPools())
+}
+
+// handleGetAssets handles GET /assets, the assets the chain holds beside ILYZ and their supply
+func (rs *RESTServer) handleGetAssets(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, rs.Blockchain.GetAssets())
+}
+
+// handleGetAsset handles GET /assets/{id}, an asset's transferability, minters and supply
+func (rs *RESTServer) handleGetAsset(w http.ResponseWriter, r *http.Request) {
+    asset, err := rs.Blockchain.GetAsset(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, asset)
+}
+
+// handleGetReferralProgram handles GET /referrals/program, the share referrers earn and the year's referral budget
+func (rs *RESTServer) handleGetReferralProgram(w http.ResponseWriter, r *http.Request) {
+    if rs.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    program, paid := rs.Blockchain.Economics.GetReferralProgram()
+    writeJSON(w, http.StatusOK, ReferralProgramResponse{
+        Program:   program,
+        Paid:      paid,
+        Remaining: program.Budget - paid,
+    })
+}
+
+// handleGetPrice handles GET /prices/{currency}, the current price of ILYZ in a fiat currency such as usd
+func (rs *RESTServer) handleGetPrice(w http.ResponseWriter, r *http.Request) {
+    if rs.Oracle == nil {
+        writeError(w, http.StatusNotImplemented, "price oracle not available")
+        return
+    }
+
+    quote, err := rs.Oracle.Price(token.NativeAsset + "/" + strings.ToUpper(r.PathValue("currency")))
+    switch {
+    case errors.Is(err, token.ErrPriceUnavailable):
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    case errors.Is(err, token.ErrPriceStale):
+        writeError(w, http.StatusServiceUnavailable, err.Error())
+        return
+    case err != nil:
+        writeError(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, quote)
+}
+
+// handleSubmitPrice handles POST /prices, an authorized reporter's signed price for a pair such as ILYZ/USD
+func (rs *RESTServer) handleSubmitPrice(w http.ResponseWriter, r *http.Request) {
+    if rs.Oracle == nil {
+        writeError(w, http.StatusNotImplemented, "price oracle not available")
+        return
+    }
+
+    var submission token.PriceSubmission
+    if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid price submission")
+        return
+    }
+
+    if err := rs.Oracle.Submit(submission); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusAccepted, submission)
+}
+
+// handleGetSeasons handles GET /seasons, the competitive seasons scaling game rewards and the one running now
+func (rs *RESTServer) handleGetSeasons(w http.ResponseWriter, r *http.Request) {
+    if rs.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    seasons := rs.Blockchain.Economics.GetParams().Seasons
+    response := SeasonsResponse{Seasons: seasons}
+    if response.Seasons == nil {
+        response.Seasons = []token.Season{}
+    }
+    if current, running := token.CurrentSeason(seasons, time.Now().Unix()); running {
+        response.Current = &current
+    }
+
+    writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetRewardPool handles GET /reward-pools/{id}, a reward pool with what it has paid so far
+func (rs *RESTServer) handleGetRewardPool(w http.ResponseWriter, r *http.Request) {
+    if rs.Blockchain.Economics == nil {
+        writeError(w, http.StatusNotImplemented, "token economics not available")
+        return
+    }
+
+    pool, err := rs.Blockchain.Economics.GetRewardPool(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, pool)
+}
+
+// handleGetMatch handles GET /matches/{id}, a match's reported result and whether its rewards were paid or voided
+func (rs *RESTServer) handleGetMatch(w http.ResponseWriter, r *http.Request) {
+    result, err := rs.Blockchain.GetMatchResult(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, result)
+}
+
+// handleGetTournament handles GET /tournaments/{id}, a tournament's entry stakes and whether they were released or paid out
+func (rs *RESTServer) handleGetTournament(w http.ResponseWriter, r *http.Request) {
+    tournament, err := rs.Blockchain.GetTournament(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, tournament)
+}
+
+// handleGetAirdrops handles GET /airdrops, every airdrop created on chain, oldest first
+func (rs *RESTServer) handleGetAirdrops(w http.ResponseWriter, r *http.Request) {
+    if rs.Airdrops == nil {
+        writeError(w, http.StatusNotImplemented, "airdrops not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Airdrops.GetAirdrops())
+}
+
+// handleGetAirdrop handles GET /airdrops/{id}, an airdrop and what each recipient has claimed from it
+func (rs *RESTServer) handleGetAirdrop(w http.ResponseWriter, r *http.Request) {
+    if rs.Airdrops == nil {
+        writeError(w, http.StatusNotImplemented, "airdrops not available")
+        return
+    }
+
+    airdrop, err := rs.Airdrops.GetAirdrop(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, airdrop)
+}
+
+// handleGetBlock handles GET /blocks/{height}
+func (rs *RESTServer) handleGetBlock(w http.ResponseWriter, r *http.Request) {
+    height, err := strconv.ParseInt(r.PathValue("height"), 10, 64)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, "invalid block height")
+        return
+    }
+
+    block, err := rs.Blockchain.GetBlockByHeight(height)
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, block)
+}
+
+// handleGetHeader handles GET /blocks/{height}/header
+func (rs *RESTServer) handleGetHeader(w http.ResponseWriter, r *http.Request) {
+    height, err := strconv.ParseInt(r.PathValue("height"), 10, 64)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, "invalid block height")
+        return
+    }
+
+    header, err := rs.Blockchain.GetHeaderByHeight(height)
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, header)
+}
+
+// handleGetBlockVotes handles GET /blocks/{height}/votes, the weighted tally of each block voted on at a height
+func (rs *RESTServer) handleGetBlockVotes(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    height, err := strconv.ParseInt(r.PathValue("height"), 10, 64)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, "invalid block height")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetTallies(height))
+}
+
+// handleGetBlockRounds handles GET /blocks/{height}/rounds, the prevotes and precommits at a height and its decision if any
+func (rs *RESTServer) handleGetBlockRounds(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    height, err := strconv.ParseInt(r.PathValue("height"), 10, 64)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, "invalid block height")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetRoundState(height))
+}
+
+// handleGetBlockProposers handles GET /blocks/{height}/proposers, the order validators may propose a height in, round 0 first
+func (rs *RESTServer) handleGetBlockProposers(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    height, err := strconv.ParseInt(r.PathValue("height"), 10, 64)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, "invalid block height")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.ProposerSchedule(height))
+}
+
+// handleGetEvidence handles GET /evidence, the misbehavior evidence committed on chain oldest first
+func (rs *RESTServer) handleGetEvidence(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetEvidence())
+}
+
+// handleGetFinalized handles GET /finalized, the latest finalized block and the precommits that justify it
+func (rs *RESTServer) handleGetFinalized(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    head, found := rs.Consensus.FinalizedHead()
+    if !found {
+        writeError(w, http.StatusNotFound, "no block finalized")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, head)
+}
+
+// handleGetProposals handles GET /governance/proposals, every governance proposal oldest first
+func (rs *RESTServer) handleGetProposals(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetProposals())
+}
+
+// handleGetProposal handles GET /governance/proposals/{id}, a proposal with its votes and, once voting ended, its tally
+func (rs *RESTServer) handleGetProposal(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    proposal, err := rs.Consensus.GetProposal(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, proposal)
+}
+
+// handleGetGovernedParams handles GET /governance/params, the current value of every setting governance can change
+func (rs *RESTServer) handleGetGovernedParams(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetGovernedParams())
+}
+
+// handleGetYieldRate handles GET /governance/yield, the yield rate set for the current epoch
+// and the staked share of the supply it was set from
+func (rs *RESTServer) handleGetYieldRate(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetYieldRate())
+}
+
+// handleGetParamVersions handles GET /governance/params/versions, every version of the governed settings oldest first
+func (rs *RESTServer) handleGetParamVersions(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetParamVersions())
+}
+
+// handleGetParamVersion handles GET /governance/params/versions/{version}, the settings blocks referencing a version ran under
+func (rs *RESTServer) handleGetParamVersion(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    version, err := strconv.ParseInt(r.PathValue("version"), 10, 64)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, "invalid parameter version")
+        return
+    }
+
+    params, err := rs.Consensus.GetParamVersion(version)
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, params)
+}
+
+// handleGetPendingEvidence handles GET /evidence/pending, the verified evidence this node holds waiting for a block to commit it
+func (rs *RESTServer) handleGetPendingEvidence(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetPooledEvidence())
+}
+
+// handleGetHeaders handles GET /headers?from=&to= for header-only sync
+func (rs *RESTServer) handleGetHeaders(w http.ResponseWriter, r *http.Request) {
+    from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, "invalid from height")
+        return
+    }
+
+    to := from + core.MaxHeadersPerRequest - 1
+    if value := r.URL.Query().Get("to"); value != "" {
+        to, err = strconv.ParseInt(value, 10, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, "invalid to height")
+            return
+        }
+    }
+
+    headers, err := rs.Blockchain.GetHeadersRange(from, to)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, headers)
+}
+
+// handleGetBody handles GET /bodies/{hash}
+func (rs *RESTServer) handleGetBody(w http.ResponseWriter, r *http.Request) {
+    body, err := rs.Blockchain.GetBody(r.PathValue("hash"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, body)
+}
+
+// handleGetTransactionProof handles GET /txs/{id}/proof
+func (rs *RESTServer) handleGetTransactionProof(w http.ResponseWriter, r *http.Request) {
+    proof, err := rs.Blockchain.GetTxInclusionProof(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, proof)
+}
+
+// handleGetRewardReceipt handles GET /txs/{id}/receipt, what a match reward paid each player and how each reward
+// was calculated
+func (rs *RESTServer) handleGetRewardReceipt(w http.ResponseWriter, r *http.Request) {
+    receipt, err := rs.Blockchain.GetRewardReceipt(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, receipt)
+}
+
+// handleGetTransaction handles GET /txs/{id}
+func (rs *RESTServer) handleGetTransaction(w http.ResponseWriter, r *http.Request) {
+    tx, height, err := rs.Blockchain.GetTransactionByID(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, TransactionResponse{
+        Transaction: tx,
+        BlockHeight: height,
+    })
+}
+
+// handleSubmitTransaction handles POST /txs, adding a transaction to the mempool
+func (rs *RESTServer) handleSubmitTransaction(w http.ResponseWriter, r *http.Request) {
+    var tx core.Transaction
+    if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid transaction")
+        return
+    }
+
+    if tx.Type == "" || tx.Sender == "" {
+        writeError(w, http.StatusBadRequest, "transaction type and sender are required")
+        return
+    }
+
+    if err := rs.Blockchain.CreateTransaction(tx); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    if rs.OnTransaction != nil {
+        rs.OnTransaction(tx)
+    }
+
+    writeJSON(w, http.StatusAccepted, tx)
+}
+
+// handleSubmitAttestation handles POST /attestations, a game server's signed record of a player's match activity
+// The attestation is submitted as a transaction, and raises the play score once a block commits it
+func (rs *RESTServer) handleSubmitAttestation(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    var attestation consensus.ActivityAttestation
+    if err := json.NewDecoder(r.Body).Decode(&attestation); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid attestation")
+        return
+    }
+
+    baseFee, _, _ := rs.Blockchain.GetBaseFee()
+    tx := consensus.AttestationTransaction(attestation, baseFee, time.Now().Unix())
+    if err := rs.Blockchain.CreateTransaction(tx); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    if rs.OnTransaction != nil {
+        rs.OnTransaction(tx)
+    }
+
+    writeJSON(w, http.StatusAccepted, tx)
+}
+
+// handleGetAddressTransactions handles GET /addresses/{addr}/txs
+func (rs *RESTServer) handleGetAddressTransactions(w http.ResponseWriter, r *http.Request) {
+    page, limit, err := parsePagination(r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    transactions := rs.Blockchain.GetTransactionsByAddress(r.PathValue("addr"))
+    start, end := pageBounds(len(transactions), page, limit)
+
+    writeJSON(w, http.StatusOK, Page{
+        Data:  transactions[start:end],
+        Page:  page,
+        Limit: limit,
+        Total: len(transactions),
+    })
+}
+
+// handleGetAddressBalance handles GET /addresses/{addr}/balance, optionally at ?height=
+func (rs *RESTServer) handleGetAddressBalance(w http.ResponseWriter, r *http.Request) {
+    height := rs.Blockchain.GetHeight()
+
+    if value := r.URL.Query().Get("height"); value != "" {
+        parsed, err := strconv.ParseInt(value, 10, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, "invalid block height")
+            return
+        }
+        height = parsed
+    }
+
+    address := r.PathValue("addr")
+    balance, err := rs.Blockchain.GetBalanceAt(address, height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    assets, err := rs.Blockchain.GetAssetHoldingsAt(address, height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    locked, err := rs.Blockchain.GetTournamentStakesAt(address, height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, BalanceResponse{
+        Address: address,
+        Balance: balance,
+        Assets:  assets,
+        Locked:  locked,
+        Height:  height,
+    })
+}
+
+// handleGetAddressVesting handles GET /addresses/{addr}/vesting, how much of the address's genesis allocation
+// has vested and how much is locked, as of ?height= or the latest block
+func (rs *RESTServer) handleGetAddressVesting(w http.ResponseWriter, r *http.Request) {
+    height := rs.Blockchain.GetHeight()
+
+    if value := r.URL.Query().Get("height"); value != "" {
+        parsed, err := strconv.ParseInt(value, 10, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, "invalid block height")
+            return
+        }
+        height = parsed
+    }
+
+    status, err := rs.Blockchain.GetVestingAt(r.PathValue("addr"), height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, status)
+}
+
+// handleGetAddressTrades handles GET /addresses/{addr}/trades, the address's NFT purchases and sales newest first, paged by ?cursor=
+func (rs *RESTServer) handleGetAddressTrades(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    page, err := rs.NFTSystem.GetTradesByAddress(r.PathValue("addr"), r.URL.Query().Get("cursor"))
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, page)
+}
+
+// handleGetValidator handles GET /validators/{addr}, a validator's stake with its bonded and unbonding parts
+func (rs *RESTServer) handleGetValidator(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    validator, err := rs.Consensus.GetValidator(r.PathValue("addr"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, validator)
+}
+
+// handleGetValidators handles GET /validators, a snapshot of the registered and pending validators
+// With ?height=, it returns the validators as the epoch containing that height started
+func (rs *RESTServer) handleGetValidators(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    if r.URL.Query().Get("height") == "" {
+        writeJSON(w, http.StatusOK, rs.Consensus.ValidatorSet())
+        return
+    }
+
+    height, err := strconv.ParseInt(r.URL.Query().Get("height"), 10, 64)
+    if err != nil || height < 0 {
+        writeError(w, http.StatusBadRequest, "invalid block height")
+        return
+    }
+
+    set, err := rs.Consensus.GetValidatorSetAtHeight(height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, set)
+}
+
+// handleGetPendingValidators handles GET /validators/pending, the validators registered on chain that join at the next epoch
+func (rs *RESTServer) handleGetPendingValidators(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetPendingValidators())
+}
+
+// handleGetValidatorDelegations handles GET /validators/{addr}/delegations, the stake token holders delegate to a validator
+func (rs *RESTServer) handleGetValidatorDelegations(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetDelegations(r.PathValue("addr")))
+}
+
+// handleGetValidatorCommission handles GET /validators/{addr}/commission, a validator's commission rate
+// and how far and how soon it can change, for wallets to show before delegating
+func (rs *RESTServer) handleGetValidatorCommission(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    commission, err := rs.Consensus.GetCommission(r.PathValue("addr"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, commission)
+}
+
+// handleGetValidatorLiveness handles GET /validators/{addr}/liveness, a validator's recent heartbeats, votes, proposals and uptime
+func (rs *RESTServer) handleGetValidatorLiveness(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    liveness, err := rs.Consensus.GetLiveness(r.PathValue("addr"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, liveness)
+}
+
+// handleGetAddressDelegations handles GET /addresses/{addr}/delegations, an address's delegations and accrued rewards
+func (rs *RESTServer) handleGetAddressDelegations(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    delegator := r.PathValue("addr")
+    delegations, undelegations, redelegations := rs.Consensus.GetDelegatorStakes(delegator)
+
+    writeJSON(w, http.StatusOK, DelegatorResponse{
+        Delegator:     delegator,
+        Delegations:   delegations,
+        Undelegations: undelegations,
+        Redelegations: redelegations,
+    })
+}
+
+// handleGetAddressLocks handles GET /addresses/{addr}/locks, the tokens an address has locked for staking rewards
+func (rs *RESTServer) handleGetAddressLocks(w http.ResponseWriter, r *http.Request) {
+    if rs.Consensus == nil {
+        writeError(w, http.StatusNotImplemented, "consensus not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.Consensus.GetStakeLocks(r.PathValue("addr")))
+}
+
+// handleGetAddressReferral handles GET /addresses/{addr}/referral, who referred the address
+func (rs *RESTServer) handleGetAddressReferral(w http.ResponseWriter, r *http.Request) {
+    referral, err := rs.Blockchain.GetReferral(r.PathValue("addr"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, referral)
+}
+
+// handleGetAddressReferrals handles GET /addresses/{addr}/referrals, the players the address referred
+func (rs *RESTServer) handleGetAddressReferrals(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, rs.Blockchain.GetReferredPlayers(r.PathValue("addr")))
+}
+
+// handleGetValidatorStakes handles GET /validators/{addr}/stakes, the NFTs staked to a validator and the boost they give
+func (rs *RESTServer) handleGetValidatorStakes(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    validator := r.PathValue("addr")
+
+    writeJSON(w, http.StatusOK, ValidatorStakesResponse{
+        Validator: validator,
+        Boost:     rs.NFTSystem.ValidatorBoost(validator),
+        NFTs:      rs.NFTSystem.GetStakedNFTs(validator),
+    })
+}
+
+// handleGetNFTs handles GET /nfts, filtered by any of ?owner=, ?collection= and ?type=
+func (rs *RESTServer) handleGetNFTs(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    page, limit, err := parsePagination(r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    owner := r.URL.Query().Get("owner")
+    collectionID := r.URL.Query().Get("collection")
+    nftType := r.URL.Query().Get("type")
+
+    var nfts []*nft.NFT
+    if owner != "" {
+        nfts = rs.NFTSystem.GetNFTsByOwner(owner)
+    } else if collectionID != "" {
+        nfts = rs.NFTSystem.GetNFTsByCollection(collectionID)
+    } else if nftType != "" {
+        nfts = rs.NFTSystem.GetNFTsByType(nftType)
+    } else {
+        writeError(w, http.StatusBadRequest, "owner, collection or type filter is required")
+        return
+    }
+
+    // Apply the remaining filters on top of the one used to look NFTs up
+    filtered := []*nft.NFT{}
+    for _, item := range nfts {
+        if collectionID != "" && item.CollectionID != collectionID {
+            continue
+        }
+        if nftType != "" && item.Type != nftType {
+            continue
+        }
+        filtered = append(filtered, item)
+    }
+    nfts = filtered
+
+    // Sort by ID so pages are stable across requests
+    sort.Slice(nfts, func(i, j int) bool {
+        return nfts[i].ID < nfts[j].ID
+    })
+
+    start, end := pageBounds(len(nfts), page, limit)
+
+    writeJSON(w, http.StatusOK, Page{
+        Data:  nfts[start:end],
+        Page:  page,
+        Limit: limit,
+        Total: len(nfts),
+    })
+}
+
+// handleSearchNFTs handles GET /nfts/search
+// Filters: type, owner, collection, listed, and attr.<field>=value, attr.<field>.min= and attr.<field>.max= for indexed attributes
+func (rs *RESTServer) handleSearchNFTs(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    page, limit, err := parsePagination(r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    values := r.URL.Query()
+    query := nft.SearchQuery{
+        Type:       values.Get("type"),
+        Owner:      values.Get("owner"),
+        Collection: values.Get("collection"),
+    }
+
+    if listed := values.Get("listed"); listed != "" {
+        parsed, err := strconv.ParseBool(listed)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, "listed must be true or false")
+            return
+        }
+        query.Listed = &parsed
+    }
+
+    // Collect the attribute filters, merging the value and bounds given for each field
+    filters := make(map[string]*nft.AttributeFilter)
+    fields := []string{}
+    for key := range values {
+        if !strings.HasPrefix(key, "attr.") {
+            continue
+        }
+
+        field := strings.TrimPrefix(key, "attr.")
+        bound := ""
+        if strings.HasSuffix(field, ".min") || strings.HasSuffix(field, ".max") {
+            bound = field[len(field)-3:]
+            field = field[:len(field)-4]
+        }
+
+        filter, exists := filters[field]
+        if !exists {
+            filter = &nft.AttributeFilter{Field: field}
+            filters[field] = filter
+            fields = append(fields, field)
+        }
+
+        value := values.Get(key)
+        if bound == "" {
+            filter.Equals = value
+            continue
+        }
+
+        number, err := strconv.ParseFloat(value, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, fmt.Sprintf("%s must be a number", key))
+            return
+        }
+        if bound == "min" {
+            filter.Min = &number
+        } else {
+            filter.Max = &number
+        }
+    }
+
+    // Apply filters in field order so errors are stable
+    sort.Strings(fields)
+    for _, field := range fields {
+        query.Attributes = append(query.Attributes, *filters[field])
+    }
+
+    nfts, err := rs.NFTSystem.Search(query)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    start, end := pageBounds(len(nfts), page, limit)
+
+    writeJSON(w, http.StatusOK, Page{
+        Data:  nfts[start:end],
+        Page:  page,
+        Limit: limit,
+        Total: len(nfts),
+    })
+}
+
+// handleGetNFTProof handles GET /nfts/{id}/proof, an inclusion proof for an NFT against the latest state root
+func (rs *RESTServer) handleGetNFTProof(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    proof, root, err := rs.NFTSystem.GetNFTProof(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    state, err := rs.Blockchain.GetStateCommitment(rs.NFTSystem.Height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, NFTProofResponse{NFTProof: proof, Root: root, State: state})
+}
+
+// handleGetNFTProvenance handles GET /nfts/{id}/provenance, the NFT's ownership history checked against the chain
+func (rs *RESTServer) handleGetNFTProvenance(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    provenance, err := rs.NFTSystem.VerifyProvenance(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, provenance)
+}
+
+// handleGetNFTSnapshot handles GET /nfts/snapshot, every NFT with an inclusion proof against the latest state root
+func (rs *RESTServer) handleGetNFTSnapshot(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    snapshot, err := rs.NFTSystem.Snapshot()
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+
+    state, err := rs.Blockchain.GetStateCommitment(snapshot.Height)
+    if err != nil {
+        writeStateError(w, err)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, NFTSnapshotResponse{RegistrySnapshot: snapshot, State: state})
+}
+
+// handleGetUsableNFTs handles GET /addresses/{addr}/usable-nfts, the NFTs an address owns or rents and may use in game
+func (rs *RESTServer) handleGetUsableNFTs(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    page, limit, err := parsePagination(r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    nfts := rs.NFTSystem.GetNFTsUsableBy(r.PathValue("addr"))
+
+    // Sort by ID so pages are stable across requests
+    sort.Slice(nfts, func(i, j int) bool {
+        return nfts[i].ID < nfts[j].ID
+    })
+
+    start, end := pageBounds(len(nfts), page, limit)
+
+    writeJSON(w, http.StatusOK, Page{
+        Data:  nfts[start:end],
+        Page:  page,
+        Limit: limit,
+        Total: len(nfts),
+    })
+}
+
+// handleGetNFTYieldProjection handles GET /nfts/{id}/yield, what a yield generator is expected to yield on ?staked=
+// over ?days=, a year if unset, compounding or not as it is set to
+func (rs *RESTServer) handleGetNFTYieldProjection(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    staked, err := token.ParseAmount(r.URL.Query().Get("staked"))
+    if err != nil || staked < 0 {
+        writeError(w, http.StatusBadRequest, "invalid staked amount")
+        return
+    }
+
+    days := 365.0
+    if value := r.URL.Query().Get("days"); value != "" {
+        days, err = strconv.ParseFloat(value, 64)
+        if err != nil || days < 0 {
+            writeError(w, http.StatusBadRequest, "invalid number of days")
+            return
+        }
+    }
+
+    projection, err := rs.NFTSystem.ProjectYield(r.PathValue("id"), staked, days)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, projection)
+}
+
+// handleGetNFTMetadata handles GET /nfts/{id}/metadata
+// Off-chain documents are only returned once they match the committed hash and the NFT type's schema
+func (rs *RESTServer) handleGetNFTMetadata(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    item, err := rs.NFTSystem.GetNFT(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    metadata, err := rs.Metadata.FetchNFTMetadata(r.Context(), item)
+    if err != nil {
+        writeError(w, http.StatusBadGateway, err.Error())
+        return
+    }
+
+    if item.MetadataURI != "" {
+        if err := rs.NFTSystem.ValidateMetadata(item.Type, metadata); err != nil {
+            writeError(w, http.StatusBadGateway, err.Error())
+            return
+        }
+    }
+
+    writeJSON(w, http.StatusOK, metadata)
+}
+
+// handleGetMarket handles GET /market, listing NFTs for sale
+// Filters: ?type=, ?collection=, ?owner=, ?minPrice=, ?maxPrice=, ?listedAfter=; order: ?sort=; paging: ?cursor=, ?limit=
+func (rs *RESTServer) handleGetMarket(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    params := r.URL.Query()
+    query := nft.MarketQuery{
+        Type:         params.Get("type"),
+        CollectionID: params.Get("collection"),
+        Owner:        params.Get("owner"),
+        Sort:         params.Get("sort"),
+        Cursor:       params.Get("cursor"),
+    }
+
+    var err error
+    if value := params.Get("minPrice"); value != "" {
+        if query.MinPrice, err = token.ParseAmount(value); err != nil || query.MinPrice < 0 {
+            writeError(w, http.StatusBadRequest, "minPrice must be a non-negative ILYZ amount")
+            return
+        }
+    }
+    if value := params.Get("maxPrice"); value != "" {
+        if query.MaxPrice, err = token.ParseAmount(value); err != nil || query.MaxPrice < 0 {
+            writeError(w, http.StatusBadRequest, "maxPrice must be a non-negative ILYZ amount")
+            return
+        }
+    }
+    if value := params.Get("listedAfter"); value != "" {
+        if query.ListedAfter, err = strconv.ParseInt(value, 10, 64); err != nil {
+            writeError(w, http.StatusBadRequest, "listedAfter must be a unix timestamp")
+            return
+        }
+    }
+    if value := params.Get("limit"); value != "" {
+        if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit < 1 {
+            writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+            return
+        }
+    }
+
+    page, err := rs.NFTSystem.QueryMarket(query)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, page)
+}
+
+// handleGetNFTGameMetadata handles GET /nfts/{id}/games/{game}, the metadata one game attaches to an NFT
+func (rs *RESTServer) handleGetNFTGameMetadata(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    metadata, err := rs.NFTSystem.GetGameMetadata(r.PathValue("id"), r.PathValue("game"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, metadata)
+}
+
+// handleGetNFTSales handles GET /nfts/{id}/sales, an NFT's sale history oldest first
+func (rs *RESTServer) handleGetNFTSales(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.NFTSystem.GetSaleHistory(r.PathValue("id")))
+}
+
+// handleGetMarketStats handles GET /market/stats, optionally limited by ?collection= and ?type=
+func (rs *RESTServer) handleGetMarketStats(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.NFTSystem.GetMarketStats(r.URL.Query().Get("collection"), r.URL.Query().Get("type")))
+}
+
+// handleGetMarketTypes handles GET /market/types, sale totals and average price per NFT type
+func (rs *RESTServer) handleGetMarketTypes(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.NFTSystem.GetAveragePriceByType())
+}
+
+// handleGetOrderBook handles GET /market/orderbook, the asks and bids for ?nft= or ?collection=
+func (rs *RESTServer) handleGetOrderBook(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    nftID := r.URL.Query().Get("nft")
+    collectionID := r.URL.Query().Get("collection")
+    if nftID == "" && collectionID == "" {
+        writeError(w, http.StatusBadRequest, "nft or collection is required")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.NFTSystem.GetOrderBook(nftID, collectionID))
+}
+
+// handleGetFeePolicy handles GET /market/fees, the marketplace fee policy in force
+func (rs *RESTServer) handleGetFeePolicy(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, rs.NFTSystem.GetFeePolicy())
+}
+
+// handleGetOrder handles GET /orders/{id}
+func (rs *RESTServer) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    order, err := rs.NFTSystem.GetOrder(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, order)
+}
+
+// parsePagination reads the 1-indexed ?page= and ?limit= query parameters
+func parsePagination(r *http.Request) (int, int, error) {
+    page := 1
+    limit := DefaultPageLimit
+
+    if value := r.URL.Query().Get("page"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 {
+            return 0, 0, errors.New("page must be a positive integer")
+        }
+        page = parsed
+    }
+
+    if value := r.URL.Query().Get("limit"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 {
+            return 0, 0, errors.New("limit must be a positive integer")
+        }
+        limit = parsed
+    }
+
+    if limit > MaxPageLimit {
+        limit = MaxPageLimit
+    }
+
+    return page, limit, nil
+}
+
+// pageBounds returns the slice bounds for a page over a collection of the given size
+// A page past the end is empty, checked before multiplying so a huge page number can't overflow
+func pageBounds(total int, page int, limit int) (int, int) {
+    if page-1 > total/limit {
+        return total, total
+    }
+
+    start := (page - 1) * limit
+    if start > total {
+        start = total
+    }
+
+    end := start + limit
+    if end > total {
+        end = total
+    }
+
+    return start, end
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(body)
+}
+
+// writeStateError maps state query errors to HTTP responses
+// Pruned heights return 410 Gone so clients know to retry against an archive node
+func writeStateError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, core.ErrStatePruned):
+        writeError(w, http.StatusGone, err.Error())
+    case errors.Is(err, core.ErrStateNotAvailable):
+        writeError(w, http.StatusNotFound, err.Error())
+    default:
+        writeError(w, http.StatusInternalServerError, err.Error())
+    }
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+    writeJSON(w, status, map[string]string{
+        "error": message,
+    })
+}
+
+// handleGetCollection handles GET /collections/{id}
+func (rs *RESTServer) handleGetCollection(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    collection, err := rs.NFTSystem.GetCollection(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    stats, err := rs.NFTSystem.GetCollectionStats(collection.ID)
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, CollectionResponse{Collection: collection, Stats: stats})
+}
+
+// handleGetDrops handles GET /drops, listing open drops soonest ending first
+func (rs *RESTServer) handleGetDrops(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    page, limit, err := parsePagination(r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    drops := rs.NFTSystem.GetOpenDrops()
+    start, end := pageBounds(len(drops), page, limit)
+
+    writeJSON(w, http.StatusOK, Page{
+        Data:  drops[start:end],
+        Page:  page,
+        Limit: limit,
+        Total: len(drops),
+    })
+}
+
+// handleGetDrop handles GET /drops/{id}
+func (rs *RESTServer) handleGetDrop(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    drop, err := rs.NFTSystem.GetDrop(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, drop)
+}
+
+// handleGetAuctions handles GET /auctions, listing active auctions soonest ending first
+func (rs *RESTServer) handleGetAuctions(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    page, limit, err := parsePagination(r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    auctions := rs.NFTSystem.GetActiveAuctions()
+    start, end := pageBounds(len(auctions), page, limit)
+
+    writeJSON(w, http.StatusOK, Page{
+        Data:  auctions[start:end],
+        Page:  page,
+        Limit: limit,
+        Total: len(auctions),
+    })
+}
+
+// handleGetAuction handles GET /auctions/{id}
+func (rs *RESTServer) handleGetAuction(w http.ResponseWriter, r *http.Request) {
+    if rs.NFTSystem == nil {
+        writeError(w, http.StatusNotImplemented, "NFT system not available")
+        return
+    }
+
+    auction, err := rs.NFTSystem.GetAuction(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    }
+
+    writeJSON(w, http.StatusOK, auction)
+}
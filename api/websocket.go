@@ -0,0 +1,403 @@
+package api
+
+import (
+    "bufio"
+    "crypto/sha1"
+    "encoding/base64"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// Subscription topics accepted over the WebSocket endpoint
+const (
+    TopicNewHeads        = "newHeads"
+    TopicPendingTxs      = "pendingTxs"
+    TopicEvents          = "events"
+    TopicNFTEvents       = "nftEvents"
+    TopicValidatorEvents = "validatorEvents"
+    TopicSeasonEvents    = "seasonEvents"
+    TopicYearEvents      = "yearEvents"
+)
+
+// Default WebSocket limits
+const (
+    DefaultMaxSubscriptionsPerConnection = 16
+    maxWebSocketFrameSize                = 64 * 1024
+    webSocketEventBuffer                 = 256
+)
+
+// WebSocket opcodes (RFC 6455)
+const (
+    wsOpText  = 0x1
+    wsOpClose = 0x8
+    wsOpPing  = 0x9
+    wsOpPong  = 0xA
+)
+
+// GUID appended to the client key during the opening handshake (RFC 6455)
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketServer pushes chain events to clients so they don't have to poll
+type WebSocketServer struct {
+    Events                        *core.EventHub
+    MaxSubscriptionsPerConnection int
+}
+
+// SubscriptionRequest is a client request sent over the WebSocket
+// e.g. {"id":1,"method":"subscribe","params":{"topic":"events","address":"..."}}
+type SubscriptionRequest struct {
+    ID     interface{}        `json:"id"`
+    Method string             `json:"method"` // "subscribe" or "unsubscribe"
+    Params SubscriptionParams `json:"params"`
+}
+
+// SubscriptionParams describes what a subscription should receive
+type SubscriptionParams struct {
+    Topic          string `json:"topic"`
+    Address        string   `json:"address,omitempty"`        // Optional filter for the events, nftEvents and validatorEvents topics
+    NFTID          string   `json:"nftId,omitempty"`          // Optional filter for the events and nftEvents topics
+    Collection     string   `json:"collection,omitempty"`     // Optional filter for the nftEvents topic
+    Kinds          []string `json:"kinds,omitempty"`          // Optional filter for the nftEvents, validatorEvents and seasonEvents topics, e.g. ["sale","transfer"]
+    SubscriptionID string   `json:"subscriptionId,omitempty"` // Used by unsubscribe
+}
+
+// SubscriptionResponse answers a client request
+type SubscriptionResponse struct {
+    ID     interface{} `json:"id"`
+    Result interface{} `json:"result,omitempty"`
+    Error  string      `json:"error,omitempty"`
+}
+
+// SubscriptionNotification delivers an event for an active subscription
+type SubscriptionNotification struct {
+    Subscription string     `json:"subscription"`
+    Event        core.ChainEvent `json:"event"`
+}
+
+// wsConnection is a single client connection and its subscriptions
+type wsConnection struct {
+    conn          net.Conn
+    reader        *bufio.Reader
+    subscriptions map[string]SubscriptionParams
+    nextID        int
+    writeMutex    sync.Mutex
+    mutex         sync.Mutex
+}
+
+// NewWebSocketServer creates a WebSocket endpoint fed from the given event hub
+func NewWebSocketServer(events *core.EventHub) *WebSocketServer {
+    return &WebSocketServer{
+        Events:                        events,
+        MaxSubscriptionsPerConnection: DefaultMaxSubscriptionsPerConnection,
+    }
+}
+
+// ServeHTTP upgrades the request to a WebSocket and serves subscriptions until the client disconnects
+func (ws *WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    conn, reader, err := upgradeWebSocket(w, r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    defer conn.Close()
+
+    client := &wsConnection{
+        conn:          conn,
+        reader:        reader,
+        subscriptions: make(map[string]SubscriptionParams),
+        nextID:        1,
+    }
+
+    hubID, events := ws.Events.Subscribe(webSocketEventBuffer)
+    defer ws.Events.Unsubscribe(hubID)
+
+    // Forward hub events matching this connection's subscriptions
+    go client.forwardEvents(events)
+
+    for {
+        opcode, payload, err := client.readFrame()
+        if err != nil {
+            return
+        }
+
+        switch opcode {
+        case wsOpText:
+            client.handleRequest(payload, ws.MaxSubscriptionsPerConnection)
+        case wsOpPing:
+            client.writeFrame(wsOpPong, payload)
+        case wsOpClose:
+            client.writeFrame(wsOpClose, nil)
+            return
+        }
+    }
+}
+
+// handleRequest processes a single subscribe/unsubscribe request
+func (c *wsConnection) handleRequest(payload []byte, maxSubscriptions int) {
+    var request SubscriptionRequest
+    if err := json.Unmarshal(payload, &request); err != nil {
+        c.writeJSON(SubscriptionResponse{Error: "invalid request"})
+        return
+    }
+
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    switch request.Method {
+    case "subscribe":
+        switch request.Params.Topic {
+        case TopicNewHeads, TopicPendingTxs, TopicEvents, TopicNFTEvents, TopicValidatorEvents, TopicSeasonEvents, TopicYearEvents:
+        default:
+            c.writeJSON(SubscriptionResponse{ID: request.ID, Error: "unknown topic"})
+            return
+        }
+
+        if len(c.subscriptions) >= maxSubscriptions {
+            c.writeJSON(SubscriptionResponse{ID: request.ID, Error: "subscription limit reached"})
+            return
+        }
+
+        subscriptionID := strconv.Itoa(c.nextID)
+        c.nextID++
+        c.subscriptions[subscriptionID] = request.Params
+
+        c.writeJSON(SubscriptionResponse{ID: request.ID, Result: subscriptionID})
+
+    case "unsubscribe":
+        if _, exists := c.subscriptions[request.Params.SubscriptionID]; !exists {
+            c.writeJSON(SubscriptionResponse{ID: request.ID, Error: "subscription not found"})
+            return
+        }
+
+        delete(c.subscriptions, request.Params.SubscriptionID)
+        c.writeJSON(SubscriptionResponse{ID: request.ID, Result: true})
+
+    default:
+        c.writeJSON(SubscriptionResponse{ID: request.ID, Error: "unknown method"})
+    }
+}
+
+// forwardEvents delivers hub events to every matching subscription until the hub channel closes
+func (c *wsConnection) forwardEvents(events <-chan core.ChainEvent) {
+    for event := range events {
+        c.mutex.Lock()
+        for subscriptionID, params := range c.subscriptions {
+            if params.matches(event) {
+                c.writeJSON(SubscriptionNotification{
+                    Subscription: subscriptionID,
+                    Event:        event,
+                })
+            }
+        }
+        c.mutex.Unlock()
+    }
+}
+
+// matches reports whether an event should be delivered to a subscription
+func (p SubscriptionParams) matches(event core.ChainEvent) bool {
+    switch p.Topic {
+    case TopicNewHeads:
+        return event.Type == core.EventNewHead
+    case TopicPendingTxs:
+        return event.Type == core.EventPendingTx
+    case TopicEvents:
+        if event.Type != core.EventTx {
+            return false
+        }
+        return p.matchesNFTAndAddress(event)
+    case TopicNFTEvents:
+        if event.Type != core.EventNFT {
+            return false
+        }
+        if p.Collection != "" && event.Collection != p.Collection {
+            return false
+        }
+        if len(p.Kinds) > 0 && !containsKind(p.Kinds, event.Kind) {
+            return false
+        }
+        return p.matchesNFTAndAddress(event)
+    case TopicValidatorEvents:
+        if event.Type != core.EventValidator {
+            return false
+        }
+        if len(p.Kinds) > 0 && !containsKind(p.Kinds, event.Kind) {
+            return false
+        }
+        return p.matchesNFTAndAddress(event)
+    case TopicSeasonEvents:
+        if event.Type != core.EventSeason {
+            return false
+        }
+        return len(p.Kinds) == 0 || containsKind(p.Kinds, event.Kind)
+    case TopicYearEvents:
+        return event.Type == core.EventYear
+    }
+
+    return false
+}
+
+// matchesNFTAndAddress applies the NFT and address filters shared by the event topics
+func (p SubscriptionParams) matchesNFTAndAddress(event core.ChainEvent) bool {
+    if p.NFTID != "" && event.NFTID != p.NFTID {
+        return false
+    }
+    if p.Address != "" {
+        for _, address := range event.Addresses {
+            if address == p.Address {
+                return true
+            }
+        }
+        return false
+    }
+    return true
+}
+
+// containsKind reports whether an event kind is in a subscription's kind filter
+func containsKind(kinds []string, kind string) bool {
+    for _, candidate := range kinds {
+        if candidate == kind {
+            return true
+        }
+    }
+    return false
+}
+
+// writeJSON sends a JSON text frame
+func (c *wsConnection) writeJSON(v interface{}) error {
+    data, err := json.Marshal(v)
+    if err != nil {
+        return err
+    }
+
+    return c.writeFrame(wsOpText, data)
+}
+
+// writeFrame writes a single unmasked, unfragmented frame
+func (c *wsConnection) writeFrame(opcode byte, payload []byte) error {
+    c.writeMutex.Lock()
+    defer c.writeMutex.Unlock()
+
+    header := []byte{0x80 | opcode}
+    length := len(payload)
+
+    switch {
+    case length < 126:
+        header = append(header, byte(length))
+    case length <= 0xFFFF:
+        header = append(header, 126, 0, 0)
+        binary.BigEndian.PutUint16(header[2:], uint16(length))
+    default:
+        header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+        binary.BigEndian.PutUint64(header[2:], uint64(length))
+    }
+
+    if _, err := c.conn.Write(header); err != nil {
+        return err
+    }
+
+    _, err := c.conn.Write(payload)
+    return err
+}
+
+// readFrame reads a single client frame and unmasks its payload
+func (c *wsConnection) readFrame() (byte, []byte, error) {
+    header := make([]byte, 2)
+    if _, err := io.ReadFull(c.reader, header); err != nil {
+        return 0, nil, err
+    }
+
+    if header[0]&0x80 == 0 {
+        return 0, nil, errors.New("fragmented frames are not supported")
+    }
+
+    opcode := header[0] & 0x0F
+    masked := header[1]&0x80 != 0
+    length := uint64(header[1] & 0x7F)
+
+    // Clients must mask every frame (RFC 6455 section 5.1)
+    if !masked {
+        return 0, nil, errors.New("client frame is not masked")
+    }
+
+    switch length {
+    case 126:
+        extended := make([]byte, 2)
+        if _, err := io.ReadFull(c.reader, extended); err != nil {
+            return 0, nil, err
+        }
+        length = uint64(binary.BigEndian.Uint16(extended))
+    case 127:
+        extended := make([]byte, 8)
+        if _, err := io.ReadFull(c.reader, extended); err != nil {
+            return 0, nil, err
+        }
+        length = binary.BigEndian.Uint64(extended)
+    }
+
+    if length > maxWebSocketFrameSize {
+        return 0, nil, errors.New("frame too large")
+    }
+
+    mask := make([]byte, 4)
+    if _, err := io.ReadFull(c.reader, mask); err != nil {
+        return 0, nil, err
+    }
+
+    payload := make([]byte, length)
+    if _, err := io.ReadFull(c.reader, payload); err != nil {
+        return 0, nil, err
+    }
+
+    for i := range payload {
+        payload[i] ^= mask[i%4]
+    }
+
+    return opcode, payload, nil
+}
+
+// upgradeWebSocket performs the server side of the WebSocket opening handshake
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (net.Conn, *bufio.Reader, error) {
+    if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+        return nil, nil, errors.New("expected websocket upgrade")
+    }
+
+    key := r.Header.Get("Sec-WebSocket-Key")
+    if key == "" {
+        return nil, nil, errors.New("missing Sec-WebSocket-Key header")
+    }
+
+    hijacker, ok := w.(http.Hijacker)
+    if !ok {
+        return nil, nil, errors.New("connection does not support hijacking")
+    }
+
+    conn, rw, err := hijacker.Hijack()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    hash := sha1.Sum([]byte(key + webSocketGUID))
+    accept := base64.StdEncoding.EncodeToString(hash[:])
+
+    response := fmt.Sprintf(
+        "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+        accept,
+    )
+
+    if _, err := conn.Write([]byte(response)); err != nil {
+        conn.Close()
+        return nil, nil, err
+    }
+
+    return conn, rw.Reader, nil
+}
@@ -0,0 +1,648 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "os/signal"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/consensus"
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/node"
+    "github.com/txaimhawj/chulubmeadditional-files/simulation"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+    "github.com/txaimhawj/chulubmeadditional-files/wallet"
+)
+
+const (
+    defaultHomeDir     = ".nexuschain"
+    defaultRESTAddress = "http://127.0.0.1:8080"
+)
+
+const usage = `nexuschaind - Nexus Legends chain node
+
+Usage:
+  nexuschaind init --chain-id ID [--home DIR] [--validator] [--archive]
+  nexuschaind start [--home DIR]
+  nexuschaind status [--rest URL]
+  nexuschaind wallet create --out FILE
+  nexuschaind tx send --wallet FILE --to ADDRESS --amount N [--rest URL]
+  nexuschaind export --out FILE [--home DIR] [--from H] [--to H]
+  nexuschaind import --in FILE [--home DIR]
+  nexuschaind validator register --wallet FILE --stake N [--game-node] [--commission RATE] [--rest URL]
+  nexuschaind validator commission --wallet FILE --rate RATE [--rest URL]
+  nexuschaind validator rotate-key --wallet FILE --new-key FILE [--old-key FILE] [--rest URL]
+  nexuschaind gov propose --wallet FILE --deposit N --title TITLE [--description TEXT] --param NAME=VALUE... [--rest URL]
+  nexuschaind gov vote --wallet FILE --proposal ID --option yes|no|abstain [--rest URL]
+  nexuschaind simulate [--honest N] [--silent N] [--equivocating N] [--heights H] [--rounds R] [--seed S]
+`
+
+func main() {
+    if len(os.Args) < 2 {
+        fmt.Fprint(os.Stderr, usage)
+        os.Exit(2)
+    }
+
+    var err error
+    switch os.Args[1] {
+    case "init":
+        err = runInit(os.Args[2:])
+    case "start":
+        err = runStart(os.Args[2:])
+    case "status":
+        err = runStatus(os.Args[2:])
+    case "wallet":
+        err = runWallet(os.Args[2:])
+    case "tx":
+        err = runTx(os.Args[2:])
+    case "export":
+        err = runExport(os.Args[2:])
+    case "import":
+        err = runImport(os.Args[2:])
+    case "validator":
+        err = runValidator(os.Args[2:])
+    case "gov":
+        err = runGov(os.Args[2:])
+    case "simulate":
+        err = runSimulate(os.Args[2:])
+    case "help", "-h", "--help":
+        fmt.Print(usage)
+    default:
+        err = fmt.Errorf("unknown command %q", os.Args[1])
+    }
+
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "Error:", err)
+        os.Exit(1)
+    }
+}
+
+// runInit writes a genesis file and node configuration to the home directory
+func runInit(args []string) error {
+    flags := flag.NewFlagSet("init", flag.ExitOnError)
+    home := flags.String("home", defaultHome(), "node home directory")
+    chainID := flags.String("chain-id", "", "network chain ID")
+    validator := flags.Bool("validator", false, "create a validator key for this node")
+    archive := flags.Bool("archive", false, "run as an archive node")
+    flags.Parse(args)
+
+    if *chainID == "" {
+        return errors.New("--chain-id is required")
+    }
+
+    config, genesis, err := node.Init(*home, node.InitOptions{
+        ChainID:   *chainID,
+        Validator: *validator,
+        Archive:   *archive,
+    })
+    if err != nil {
+        return err
+    }
+
+    fmt.Printf("Initialized %s for chain %q (genesis %s)\n", *home, genesis.ChainID, genesis.Hash())
+    if config.ValidatorAddress != "" {
+        fmt.Printf("Validator address: %s\n", config.ValidatorAddress)
+    }
+
+    return nil
+}
+
+// runStart runs the node until interrupted
+func runStart(args []string) error {
+    flags := flag.NewFlagSet("start", flag.ExitOnError)
+    home := flags.String("home", defaultHome(), "node home directory")
+    flags.Parse(args)
+
+    n, err := node.Open(*home)
+    if err != nil {
+        return err
+    }
+
+    if err := n.Start(); err != nil {
+        return err
+    }
+
+    fmt.Printf("Node %s started on chain %q at height %d\n", n.Config.NodeID, n.Chain.ChainID, n.Chain.GetHeight())
+    fmt.Printf("REST API on %s, admin API on %s, p2p on port %d\n", n.Config.RESTAddress, n.Config.AdminAddress, n.Config.P2PPort)
+
+    interrupt := make(chan os.Signal, 1)
+    signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+    <-interrupt
+
+    fmt.Println("Shutting down")
+    return n.Stop()
+}
+
+// runStatus prints the status reported by a running node
+func runStatus(args []string) error {
+    flags := flag.NewFlagSet("status", flag.ExitOnError)
+    rest := flags.String("rest", defaultRESTAddress, "REST API URL of the node")
+    flags.Parse(args)
+
+    response, err := http.Get(strings.TrimRight(*rest, "/") + "/status")
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+
+    return printResponse(response)
+}
+
+// runWallet handles wallet subcommands
+func runWallet(args []string) error {
+    if len(args) < 1 || args[0] != "create" {
+        return errors.New("usage: nexuschaind wallet create --out FILE")
+    }
+
+    flags := flag.NewFlagSet("wallet create", flag.ExitOnError)
+    out := flags.String("out", "", "file to write the wallet to")
+    flags.Parse(args[1:])
+
+    if *out == "" {
+        return errors.New("--out is required")
+    }
+
+    newWallet, err := wallet.CreateWallet()
+    if err != nil {
+        return err
+    }
+
+    walletJSON, err := wallet.SaveWallet(newWallet, true)
+    if err != nil {
+        return err
+    }
+
+    if err := os.WriteFile(*out, []byte(walletJSON), 0600); err != nil {
+        return err
+    }
+
+    fmt.Printf("Created wallet %s\n", newWallet.Address)
+    return nil
+}
+
+// runTx handles transaction subcommands
+func runTx(args []string) error {
+    if len(args) < 1 || args[0] != "send" {
+        return errors.New("usage: nexuschaind tx send --wallet FILE --to ADDRESS --amount N")
+    }
+
+    flags := flag.NewFlagSet("tx send", flag.ExitOnError)
+    walletFile := flags.String("wallet", "", "wallet file holding the sender's key")
+    to := flags.String("to", "", "recipient address")
+    amount := amountFlag(flags, "amount", "amount of ILYZ to send")
+    rest := flags.String("rest", defaultRESTAddress, "REST API URL of the node")
+    flags.Parse(args[1:])
+
+    if *walletFile == "" || *to == "" || *amount <= 0 {
+        return errors.New("--wallet, --to and a positive --amount are required")
+    }
+
+    sender, err := loadWalletFile(*walletFile)
+    if err != nil {
+        return err
+    }
+
+    return submitTransaction(sender, core.Transaction{
+        Type:      "token_transfer",
+        Sender:    sender.Address,
+        Recipient: *to,
+        Amount:    *amount,
+    }, *rest)
+}
+
+// loadWalletFile reads a wallet saved by wallet create
+func loadWalletFile(path string) (*wallet.Wallet, error) {
+    walletJSON, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    return wallet.LoadWallet(string(walletJSON))
+}
+
+// submitTransaction signs a transaction with a wallet's key and submits it to a node's REST API
+func submitTransaction(sender *wallet.Wallet, tx core.Transaction, rest string) error {
+    tx.Timestamp = time.Now().Unix()
+    tx.PublicKey = sender.PublicKey
+
+    // Sign the transaction body, then identify it by the hash of its content
+    unsigned, err := core.TransactionSigningPayload(tx)
+    if err != nil {
+        return err
+    }
+
+    tx.Signature, err = sender.SignTransaction(unsigned)
+    if err != nil {
+        return err
+    }
+    tx.ID = core.ComputeTransactionID(tx)
+
+    body, err := json.Marshal(tx)
+    if err != nil {
+        return err
+    }
+
+    response, err := http.Post(strings.TrimRight(rest, "/")+"/txs", "application/json", bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+
+    return printResponse(response)
+}
+
+// runExport exports blocks from a stopped node's chain data
+func runExport(args []string) error {
+    flags := flag.NewFlagSet("export", flag.ExitOnError)
+    home := flags.String("home", defaultHome(), "node home directory")
+    out := flags.String("out", "", "file to write the export to")
+    from := flags.Int64("from", 0, "first height to export")
+    to := flags.Int64("to", -1, "last height to export (default: latest)")
+    flags.Parse(args)
+
+    if *out == "" {
+        return errors.New("--out is required")
+    }
+
+    bc, err := openChain(*home)
+    if err != nil {
+        return err
+    }
+
+    if *to < 0 {
+        *to = bc.GetHeight()
+    }
+
+    if err := bc.ExportChain(*out, *from, *to); err != nil {
+        return err
+    }
+
+    fmt.Printf("Exported blocks %d-%d to %s\n", *from, *to, *out)
+    return nil
+}
+
+// runImport imports an export file into a stopped node's chain data
+func runImport(args []string) error {
+    flags := flag.NewFlagSet("import", flag.ExitOnError)
+    home := flags.String("home", defaultHome(), "node home directory")
+    in := flags.String("in", "", "export file to import")
+    flags.Parse(args)
+
+    if *in == "" {
+        return errors.New("--in is required")
+    }
+
+    bc, err := openChain(*home)
+    if err != nil {
+        return err
+    }
+
+    imported, err := bc.ImportChain(*in)
+    if err != nil {
+        return err
+    }
+
+    if err := node.SaveChain(*home, bc); err != nil {
+        return err
+    }
+
+    fmt.Printf("Imported %d blocks, chain height is now %d\n", imported, bc.GetHeight())
+    return nil
+}
+
+// runValidator handles validator subcommands
+func runValidator(args []string) error {
+    if len(args) < 1 {
+        return errors.New("usage: nexuschaind validator register|commission|rotate-key")
+    }
+
+    switch args[0] {
+    case "register":
+        return runValidatorRegister(args[1:])
+    case "commission":
+        return runValidatorCommission(args[1:])
+    case "rotate-key":
+        return runValidatorRotateKey(args[1:])
+    }
+
+    return fmt.Errorf("unknown validator subcommand %q", args[0])
+}
+
+// runValidatorRegister submits a transaction locking the stake, with the wallet key's proof of possession;
+// the validator joins at the start of the next epoch
+func runValidatorRegister(args []string) error {
+    flags := flag.NewFlagSet("validator register", flag.ExitOnError)
+    walletFile := flags.String("wallet", "", "wallet file holding the validator's key")
+    stake := amountFlag(flags, "stake", "amount of ILYZ staked")
+    gameNode := flags.Bool("game-node", false, "whether the validator runs a game server")
+    commission := flags.Float64("commission", consensus.DefaultCommission, "share of delegators' rewards the validator keeps")
+    rest := flags.String("rest", defaultRESTAddress, "REST API URL of the node")
+    flags.Parse(args)
+
+    if *walletFile == "" || *stake <= 0 {
+        return errors.New("--wallet and a positive --stake are required")
+    }
+
+    validator, err := loadWalletFile(*walletFile)
+    if err != nil {
+        return err
+    }
+
+    payload, err := consensus.RegistrationProofPayload(validator.Address, validator.PublicKey)
+    if err != nil {
+        return err
+    }
+
+    proof, err := validator.SignTransaction(payload)
+    if err != nil {
+        return err
+    }
+
+    return submitTransaction(validator, core.Transaction{
+        Type:   consensus.TxTypeRegisterValidator,
+        Sender: validator.Address,
+        Amount: *stake,
+        Data: map[string]interface{}{
+            "consensusKey": validator.PublicKey,
+            "proof":        proof,
+            "gameNode":     *gameNode,
+            "commission":   *commission,
+        },
+    }, *rest)
+}
+
+// runValidatorCommission submits a transaction changing the validator's commission rate,
+// by at most the network's maximum change and at most once a day
+func runValidatorCommission(args []string) error {
+    flags := flag.NewFlagSet("validator commission", flag.ExitOnError)
+    walletFile := flags.String("wallet", "", "wallet file holding the validator's key")
+    rate := flags.Float64("rate", -1, "new commission rate between 0 and 1")
+    rest := flags.String("rest", defaultRESTAddress, "REST API URL of the node")
+    flags.Parse(args)
+
+    if *walletFile == "" || *rate < 0 || *rate > 1 {
+        return errors.New("--wallet and a --rate between 0 and 1 are required")
+    }
+
+    validator, err := loadWalletFile(*walletFile)
+    if err != nil {
+        return err
+    }
+
+    return submitTransaction(validator, core.Transaction{
+        Type:   consensus.TxTypeSetCommission,
+        Sender: validator.Address,
+        Data:   map[string]interface{}{"rate": *rate},
+    }, *rest)
+}
+
+// runValidatorRotateKey submits a transaction replacing the validator's consensus key from the next epoch,
+// signed by both the current key and the new one; the node's validator key is switched to the new one once it takes effect
+func runValidatorRotateKey(args []string) error {
+    flags := flag.NewFlagSet("validator rotate-key", flag.ExitOnError)
+    walletFile := flags.String("wallet", "", "wallet file holding the validator's key")
+    oldKeyFile := flags.String("old-key", "", "wallet file holding the current consensus key, if it isn't the validator's key")
+    newKeyFile := flags.String("new-key", "", "wallet file holding the new consensus key")
+    rest := flags.String("rest", defaultRESTAddress, "REST API URL of the node")
+    flags.Parse(args)
+
+    if *walletFile == "" || *newKeyFile == "" {
+        return errors.New("--wallet and --new-key are required")
+    }
+    if *oldKeyFile == "" {
+        *oldKeyFile = *walletFile
+    }
+
+    validator, err := loadWalletFile(*walletFile)
+    if err != nil {
+        return err
+    }
+    oldKey, err := loadWalletFile(*oldKeyFile)
+    if err != nil {
+        return err
+    }
+    newKey, err := loadWalletFile(*newKeyFile)
+    if err != nil {
+        return err
+    }
+
+    payload, err := consensus.KeyRotationPayload(validator.Address, oldKey.PublicKey, newKey.PublicKey)
+    if err != nil {
+        return err
+    }
+
+    oldProof, err := oldKey.SignTransaction(payload)
+    if err != nil {
+        return err
+    }
+    newProof, err := newKey.SignTransaction(payload)
+    if err != nil {
+        return err
+    }
+
+    return submitTransaction(validator, core.Transaction{
+        Type:   consensus.TxTypeRotateKey,
+        Sender: validator.Address,
+        Data: map[string]interface{}{
+            "oldKey":   oldKey.PublicKey,
+            "newKey":   newKey.PublicKey,
+            "oldProof": oldProof,
+            "newProof": newProof,
+        },
+    }, *rest)
+}
+
+// runGov handles governance subcommands
+func runGov(args []string) error {
+    if len(args) < 1 {
+        return errors.New("usage: nexuschaind gov propose|vote")
+    }
+
+    switch args[0] {
+    case "propose":
+        return runGovPropose(args[1:])
+    case "vote":
+        return runGovVote(args[1:])
+    }
+
+    return fmt.Errorf("unknown gov subcommand %q", args[0])
+}
+
+// runGovPropose submits a parameter change proposal with a deposit, refunded unless the proposal misses quorum
+func runGovPropose(args []string) error {
+    changes := paramChanges{}
+    flags := flag.NewFlagSet("gov propose", flag.ExitOnError)
+    walletFile := flags.String("wallet", "", "wallet file holding the proposer's key")
+    deposit := amountFlag(flags, "deposit", "amount of ILYZ deposited")
+    title := flags.String("title", "", "title of the proposal")
+    description := flags.String("description", "", "description of the proposal")
+    flags.Var(changes, "param", "parameter change as NAME=VALUE, repeatable")
+    rest := flags.String("rest", defaultRESTAddress, "REST API URL of the node")
+    flags.Parse(args)
+
+    if *walletFile == "" || *deposit <= 0 || *title == "" || len(changes) == 0 {
+        return errors.New("--wallet, a positive --deposit, --title and at least one --param are required")
+    }
+
+    proposer, err := loadWalletFile(*walletFile)
+    if err != nil {
+        return err
+    }
+
+    return submitTransaction(proposer, core.Transaction{
+        Type:   consensus.TxTypeProposeParams,
+        Sender: proposer.Address,
+        Amount: *deposit,
+        Data: map[string]interface{}{
+            "title":       *title,
+            "description": *description,
+            "changes":     map[string]interface{}(changes),
+        },
+    }, *rest)
+}
+
+// runGovVote submits a vote on an open proposal; voting again replaces the earlier vote
+func runGovVote(args []string) error {
+    flags := flag.NewFlagSet("gov vote", flag.ExitOnError)
+    walletFile := flags.String("wallet", "", "wallet file holding the voter's key")
+    proposal := flags.String("proposal", "", "ID of the proposal")
+    option := flags.String("option", "", "yes, no or abstain")
+    rest := flags.String("rest", defaultRESTAddress, "REST API URL of the node")
+    flags.Parse(args)
+
+    if *walletFile == "" || *proposal == "" || *option == "" {
+        return errors.New("--wallet, --proposal and --option are required")
+    }
+
+    voter, err := loadWalletFile(*walletFile)
+    if err != nil {
+        return err
+    }
+
+    return submitTransaction(voter, core.Transaction{
+        Type:   consensus.TxTypeGovVote,
+        Sender: voter.Address,
+        Data:   map[string]interface{}{"proposal": *proposal, "option": *option},
+    }, *rest)
+}
+
+// runSimulate runs consensus voting between simulated validators on a scripted clock and reports
+// whether every height was decided and no two honest validators decided differently
+func runSimulate(args []string) error {
+    flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+    honest := flags.Int("honest", 4, "honest validators")
+    silent := flags.Int("silent", 0, "validators that never propose or vote")
+    equivocating := flags.Int("equivocating", 0, "validators that propose and vote for two blocks in every round")
+    heights := flags.Int64("heights", 10, "heights to finalize")
+    rounds := flags.Int64("rounds", 10, "rounds tried at each height before the run stops as stalled")
+    seed := flags.Int64("seed", 1, "seed validator keys are derived from")
+    flags.Parse(args)
+
+    config := simulation.Config{Heights: *heights, MaxRounds: *rounds, Seed: *seed, Start: time.Unix(0, 0)}
+    for _, group := range []struct {
+        behavior string
+        count    int
+    }{{simulation.BehaviorHonest, *honest}, {simulation.BehaviorSilent, *silent}, {simulation.BehaviorEquivocating, *equivocating}} {
+        for i := 0; i < group.count; i++ {
+            config.Validators = append(config.Validators, simulation.ValidatorSpec{Behavior: group.behavior, Stake: 1000 * token.ILYZ})
+        }
+    }
+
+    sim, err := simulation.New(config)
+    if err != nil {
+        return err
+    }
+    result, err := sim.Run()
+    if err != nil {
+        return err
+    }
+
+    for _, height := range result.Heights {
+        if height.Round < 0 {
+            fmt.Printf("Height %d: stalled after %d rounds\n", height.Height, *rounds)
+            continue
+        }
+        fmt.Printf("Height %d: decided in round %d by %d of %d honest validators\n", height.Height, height.Round, height.Decided, result.Honest)
+    }
+    fmt.Printf("Evidence caught: %d, simulated time: %s\n", len(result.Evidence), result.Elapsed)
+    fmt.Printf("Live: %t, safe: %t\n", result.Live(), result.Safe())
+
+    if !result.Safe() {
+        return fmt.Errorf("honest validators decided different blocks: %s", strings.Join(result.Conflicts, "; "))
+    }
+    return nil
+}
+
+// paramChanges collects repeated NAME=VALUE flags
+type paramChanges map[string]interface{}
+
+func (p paramChanges) String() string {
+    return fmt.Sprint(map[string]interface{}(p))
+}
+
+func (p paramChanges) Set(value string) error {
+    name, number, found := strings.Cut(value, "=")
+    if !found || name == "" {
+        return errors.New("expected NAME=VALUE")
+    }
+
+    parsed, err := strconv.ParseFloat(number, 64)
+    if err != nil {
+        return fmt.Errorf("value of %q must be a number", name)
+    }
+    p[name] = parsed
+
+    return nil
+}
+
+// amountFlag defines a flag taking a decimal ILYZ amount, such as 12.5
+func amountFlag(flags *flag.FlagSet, name string, usage string) *token.Amount {
+    amount := new(token.Amount)
+    flags.Var(amount, name, usage)
+    return amount
+}
+
+// openChain restores the chain saved in a stopped node's home directory, with the modules that execute its blocks
+func openChain(home string) (*core.Blockchain, error) {
+    n, err := node.Open(home)
+    if err != nil {
+        return nil, err
+    }
+
+    return n.Chain, nil
+}
+
+// defaultHome returns the default node home directory
+func defaultHome() string {
+    userHome, err := os.UserHomeDir()
+    if err != nil {
+        return defaultHomeDir
+    }
+
+    return filepath.Join(userHome, defaultHomeDir)
+}
+
+// printResponse prints an API response body, returning an error for non-2xx statuses
+func printResponse(response *http.Response) error {
+    body, err := io.ReadAll(response.Body)
+    if err != nil {
+        return err
+    }
+
+    if response.StatusCode >= 300 {
+        return fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(body)))
+    }
+
+    fmt.Println(strings.TrimSpace(string(body)))
+    return nil
+}
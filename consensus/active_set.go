@@ -0,0 +1,85 @@
+package consensus
+
+import (
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// DefaultMaxValidators is how many validators are in the active set during an epoch
+const DefaultMaxValidators = 100
+
+// Validator event kinds
+const (
+    EventKindValidatorEnter = "validator_enter"
+    EventKindValidatorExit  = "validator_exit"
+)
+
+// ValidatorEvent is the payload of a validator chain event
+type ValidatorEvent struct {
+    Kind    string  `json:"kind"`
+    Address string  `json:"address"`
+    Weight  float64 `json:"weight"` // Consensus weight the validator was ranked by
+    Epoch   int64   `json:"epoch"`  // First block of the epoch the change takes effect in
+}
+
+// DrainEvents returns and clears the events recorded for the blocks executed since the last call
+func (pop *ProofOfPlay) DrainEvents() []core.ChainEvent {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    events := pop.events
+    pop.events = nil
+
+    return events
+}
+
+// rotateActiveSet ranks the validators by consensus weight as an epoch starts with the next block
+// The top MaxValidators not jailed are active for the epoch; the rest wait as candidates, and a validator
+// unjailed during the epoch only returns at the next one
+// The caller must hold the lock
+func (pop *ProofOfPlay) rotateActiveSet(header core.BlockHeader) {
+    ranked := make([]Validator, 0, len(pop.validators))
+    for _, validator := range pop.validators {
+        if !validator.Jailed && !validator.JailedForDowntime {
+            ranked = append(ranked, validator)
+        }
+    }
+    sort.Slice(ranked, func(i, j int) bool {
+        if ranked[i].ConsensusWeight() != ranked[j].ConsensusWeight() {
+            return ranked[i].ConsensusWeight() > ranked[j].ConsensusWeight()
+        }
+        return ranked[i].Address < ranked[j].Address
+    })
+
+    selected := make(map[string]bool, pop.MaxValidators)
+    for i := 0; i < len(ranked) && i < pop.MaxValidators; i++ {
+        selected[ranked[i].Address] = true
+    }
+
+    for i := range pop.validators {
+        validator := &pop.validators[i]
+        candidate := !selected[validator.Address]
+        if candidate == validator.Candidate {
+            continue
+        }
+        validator.Candidate = candidate
+
+        kind := EventKindValidatorEnter
+        if candidate {
+            kind = EventKindValidatorExit
+        }
+        pop.events = append(pop.events, core.ChainEvent{
+            Type:        core.EventValidator,
+            BlockHeight: header.Index,
+            Addresses:   []string{validator.Address},
+            Kind:        kind,
+            Data: ValidatorEvent{
+                Kind:    kind,
+                Address: validator.Address,
+                Weight:  validator.ConsensusWeight(),
+                Epoch:   header.Index + 1,
+            },
+        })
+    }
+}
@@ -0,0 +1,194 @@
+package consensus
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeAttestation commits a game server's activity attestation, raising its player's play score
+// Data: attestation, an ActivityAttestation; anyone may submit it, since the server's signature proves it
+// Play scores weigh in governance, so they are derived from the chain like stake rather than tracked per node
+const TxTypeAttestation = "activity_attestation"
+
+// ActivityAttestation is a game server's signed statement that a player earned activity in a match
+// Only attestations from registered game servers raise play scores, and each match counts once per player
+type ActivityAttestation struct {
+    MatchID   string  `json:"matchId"`
+    Player    string  `json:"player"`   // Validator address credited with the activity
+    Activity  float64 `json:"activity"`       // Raw activity, scored by the mode's curve
+    Mode      string  `json:"mode,omitempty"` // Game mode of the match, casual if empty
+    Server    string  `json:"server"`         // Address of the attesting game server
+    Timestamp int64   `json:"timestamp"`
+    PublicKey string  `json:"publicKey,omitempty"` // Server's hex public key, needed to verify the signature
+    Signature string  `json:"signature,omitempty"`
+}
+
+// attestationKey identifies one player's claim on one match
+type attestationKey struct {
+    matchID string
+    player  string
+}
+
+// AttestationSigningPayload returns the bytes a game server signs for an attestation
+func AttestationSigningPayload(a ActivityAttestation) ([]byte, error) {
+    return core.CanonicalEncode(struct {
+        MatchID   string  `json:"matchId"`
+        Player    string  `json:"player"`
+        Activity  float64 `json:"activity"`
+        Mode      string  `json:"mode,omitempty"`
+        Timestamp int64   `json:"timestamp"`
+    }{
+        MatchID:   a.MatchID,
+        Player:    a.Player,
+        Activity:  a.Activity,
+        Mode:      a.Mode,
+        Timestamp: a.Timestamp,
+    })
+}
+
+// Sign fills in the server, public key and signature of an attestation for a game server's key pair
+func (a *ActivityAttestation) Sign(keyPair *crypto.KeyPair) error {
+    payload, err := AttestationSigningPayload(*a)
+    if err != nil {
+        return err
+    }
+
+    signature, err := keyPair.Sign(payload)
+    if err != nil {
+        return err
+    }
+
+    a.Server = crypto.GetAddressFromPublicKey(keyPair.PublicKey)
+    a.PublicKey = crypto.PublicKeyToHex(keyPair.PublicKey)
+    a.Signature = signature
+    return nil
+}
+
+// Verify checks that an attestation is complete and was signed by its server
+func (a ActivityAttestation) Verify() error {
+    if a.MatchID == "" || a.Player == "" {
+        return errors.New("match ID and player are required")
+    }
+    if a.Activity <= 0 || math.IsInf(a.Activity, 0) || math.IsNaN(a.Activity) {
+        return errors.New("activity must be positive")
+    }
+    if a.Signature == "" || a.PublicKey == "" {
+        return errors.New("attestation is not signed")
+    }
+
+    publicKey, err := crypto.HexToPublicKey(a.PublicKey)
+    if err != nil {
+        return fmt.Errorf("invalid attestation public key: %w", err)
+    }
+
+    if crypto.GetAddressFromPublicKey(publicKey) != a.Server {
+        return errors.New("attestation public key does not match its server")
+    }
+
+    payload, err := AttestationSigningPayload(a)
+    if err != nil {
+        return err
+    }
+
+    valid, err := crypto.Verify(payload, a.Signature, publicKey)
+    if err != nil || !valid {
+        return errors.New("invalid attestation signature")
+    }
+
+    return nil
+}
+
+// AttestationTransaction returns an unsigned transaction committing an attestation, sent by its game server,
+// which pays the fee
+func AttestationTransaction(attestation ActivityAttestation, fee token.Amount, timestamp int64) core.Transaction {
+    tx := core.Transaction{
+        Type:      TxTypeAttestation,
+        Sender:    attestation.Server,
+        Fee:       fee,
+        Data:      map[string]interface{}{"attestation": attestation},
+        Timestamp: timestamp,
+    }
+    tx.ID = core.ComputeTransactionID(tx)
+
+    return tx
+}
+
+// AuthenticateTransaction vouches for the sender of an attestation transaction, which is left unsigned:
+// the attestation it carries must be signed by its game server, who sends the transaction and pays its fee
+// Other transaction types are left to their signature
+func (pop *ProofOfPlay) AuthenticateTransaction(tx core.Transaction) (bool, error) {
+    if tx.Type != TxTypeAttestation {
+        return false, nil
+    }
+
+    attestation, err := decodeAttestation(tx)
+    if err != nil {
+        return true, err
+    }
+    if err := attestation.Verify(); err != nil {
+        return true, err
+    }
+    if attestation.Server != tx.Sender {
+        return true, errors.New("attestation transaction is not sent by the attestation's game server")
+    }
+
+    return true, nil
+}
+
+// decodeAttestation reads the attestation carried by an attestation transaction
+func decodeAttestation(tx core.Transaction) (*ActivityAttestation, error) {
+    data, ok := tx.Data.(map[string]interface{})
+    if !ok || data["attestation"] == nil {
+        return nil, errors.New("attestation is required")
+    }
+
+    encoded, err := json.Marshal(data["attestation"])
+    if err != nil {
+        return nil, err
+    }
+
+    var attestation ActivityAttestation
+    if err := json.Unmarshal(encoded, &attestation); err != nil {
+        return nil, fmt.Errorf("invalid attestation: %w", err)
+    }
+
+    return &attestation, nil
+}
+
+// checkAttestation validates a verified attestation against the game servers and the matches already claimed
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkAttestation(attestation *ActivityAttestation) error {
+    if !pop.gameServers[attestation.Server] {
+        return errors.New("attestation is not from a game server genesis or governance allowed")
+    }
+    if pop.attested[attestationKey{matchID: attestation.MatchID, player: attestation.Player}] {
+        return errors.New("match has already been claimed by this player")
+    }
+    if pop.validator(attestation.Player) == nil {
+        return errors.New("validator not found")
+    }
+    if _, exists := pop.PlayModes[attestationMode(attestation)]; !exists {
+        return fmt.Errorf("unknown game mode %q", attestationMode(attestation))
+    }
+
+    return nil
+}
+
+// applyAttestation adds the activity in a checked attestation to its player's play score, scored by its mode
+// A match past the mode's daily cap still counts as activity and is still claimed, but adds nothing
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyAttestation(attestation *ActivityAttestation, header core.BlockHeader) {
+    key, credit := pop.playCredit(attestation, header)
+    pop.playCredits[key] += credit
+
+    validator := pop.validator(attestation.Player)
+    validator.PlayScore += credit
+    validator.LastActivity = header.Timestamp
+    pop.attested[attestationKey{matchID: attestation.MatchID, player: attestation.Player}] = true
+}
@@ -0,0 +1,374 @@
+package consensus
+
+import (
+    "errors"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+)
+
+// Steps of the two-phase vote that finalizes a height
+const (
+    StepPrevote   = "prevote"   // First phase: the block a validator backs in a round, or nil
+    StepPrecommit = "precommit" // Second phase: a commitment to the block 2/3 of the weight prevoted in the round, or nil
+)
+
+// roundStep identifies one step of one round at a height
+type roundStep struct {
+    round int64
+    step  string
+}
+
+// heightRounds is the two-phase voting at one height
+type heightRounds struct {
+    votes       map[roundStep]map[string]Vote // By round and step, then validator
+    lockedRound int64                         // Round this node's validator locked in, -1 while unlocked
+    lockedBlock string                        // Block this node's validator is locked on
+    decision    *Decision
+}
+
+// Decision is the block finalized at a height: more than 2/3 of the weight precommitted it in one round
+// Since any two 2/3 majorities share more than 1/3 of the weight, two blocks can only both be decided
+// at a height if more than 1/3 of the weight signed conflicting votes, which is slashable
+type Decision struct {
+    Height     int64  `json:"height"`
+    Round      int64  `json:"round"`
+    BlockHash  string `json:"blockHash"`
+    Precommits []Vote `json:"precommits"` // The precommits for the block in its round, ordered by validator
+}
+
+// Justification is the latest finalized block with the precommits that justify it
+// Clients treat blocks at or below its height as final and those above it as tentative
+type Justification struct {
+    Decision
+    SignedWeight float64 `json:"signedWeight"` // Weight of the precommits, by the current active validators
+    TotalWeight  float64 `json:"totalWeight"`  // Weight of every active validator
+}
+
+// StepTally is the weighted votes of one step of a round, by block hash; nil votes are under the empty hash
+type StepTally struct {
+    Round       int64              `json:"round"`
+    Step        string             `json:"step"`
+    Weights     map[string]float64 `json:"weights"`
+    VotedWeight float64            `json:"votedWeight"` // Weight of every vote in the step, nil included
+    TotalWeight float64            `json:"totalWeight"` // Weight of every active validator, voting or not
+}
+
+// RoundState is the two-phase voting at a height as seen by this node
+type RoundState struct {
+    Height      int64       `json:"height"`
+    LockedRound int64       `json:"lockedRound"`
+    LockedBlock string      `json:"lockedBlock,omitempty"`
+    Steps       []StepTally `json:"steps"` // By round, prevotes before precommits
+    Decision    *Decision   `json:"decision,omitempty"`
+}
+
+// ConsensusWeight returns a validator's weight in finalizing blocks and proposing them, its own and delegated stake
+// Unlike voting weight it leaves out play score, which is tracked per node, so every node agrees on it
+func (v Validator) ConsensusWeight() float64 {
+    return (v.Stake + v.Delegated).Float()
+}
+
+// Prevote signs, records and returns a validator's prevote in a round for the block proposed in it
+// A validator locked on a block prevotes nil for any other proposal, unless 2/3 of the weight prevoted
+// that proposal in a round after the lock, which shows the locked block can no longer be decided
+// It returns nil if the height is decided or the validator already prevoted in the round
+func (pop *ProofOfPlay) Prevote(height int64, round int64, proposal string, keyPair *crypto.KeyPair) (*Vote, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    rounds := pop.roundsAt(height)
+    if rounds.decision != nil || pop.hasStepVote(rounds, height, round, StepPrevote, keyPair) {
+        return nil, nil
+    }
+
+    choice := proposal
+    if rounds.lockedBlock != "" && proposal != rounds.lockedBlock && pop.polkaRound(rounds, proposal, round) <= rounds.lockedRound {
+        choice = ""
+    }
+
+    return pop.signStepVote(Vote{Height: height, Round: round, Step: StepPrevote, BlockHash: choice, Approve: choice != ""}, keyPair)
+}
+
+// Precommit signs, records and returns a validator's precommit in a round once 2/3 of the weight prevoted alike in it
+// With 2/3 prevoting a block the validator locks on it and precommits it; with 2/3 prevoting nil it unlocks and precommits nil
+// It returns nil while there is no such majority, if the height is decided or if the validator already precommitted in the round
+func (pop *ProofOfPlay) Precommit(height int64, round int64, keyPair *crypto.KeyPair) (*Vote, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    rounds := pop.roundsAt(height)
+    if rounds.decision != nil || pop.hasStepVote(rounds, height, round, StepPrecommit, keyPair) {
+        return nil, nil
+    }
+
+    block, found := pop.majority(rounds, round, StepPrevote)
+    if !found {
+        return nil, nil
+    }
+
+    // Lock before signing, so the lock is persisted with the precommit; a precommit that isn't signed keeps the old lock
+    lockedRound, lockedBlock := rounds.lockedRound, rounds.lockedBlock
+    if round >= rounds.lockedRound {
+        rounds.lockedRound, rounds.lockedBlock = round, block
+        if block == "" {
+            rounds.lockedRound = -1
+        }
+    }
+
+    vote, err := pop.signStepVote(Vote{Height: height, Round: round, Step: StepPrecommit, BlockHash: block, Approve: block != ""}, keyPair)
+    if err != nil {
+        rounds.lockedRound, rounds.lockedBlock = lockedRound, lockedBlock
+        return nil, err
+    }
+
+    return vote, nil
+}
+
+// GetDecision returns the block finalized at a height, if it has been
+func (pop *ProofOfPlay) GetDecision(height int64) (*Decision, bool) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    rounds, exists := pop.rounds[height]
+    if !exists || rounds.decision == nil {
+        return nil, false
+    }
+
+    decision := *rounds.decision
+    return &decision, true
+}
+
+// IsFinalized reports whether a block has been decided at its height
+func (pop *ProofOfPlay) IsFinalized(height int64, blockHash string) bool {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.finalized(BlockRef{Height: height, Hash: blockHash})
+}
+
+// FinalizedHead returns the highest block finalized among the heights whose votes this node keeps,
+// with the precommits that finalized it
+func (pop *ProofOfPlay) FinalizedHead() (*Justification, bool) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    var head *Decision
+    for height, rounds := range pop.rounds {
+        if rounds.decision != nil && (head == nil || height > head.Height) {
+            head = rounds.decision
+        }
+    }
+    if head == nil {
+        return nil, false
+    }
+
+    justification := &Justification{Decision: *head}
+    justification.Precommits = append([]Vote{}, head.Precommits...)
+
+    tally := pop.stepTally(pop.rounds[head.Height], head.Round, StepPrecommit)
+    justification.SignedWeight = tally.Weights[head.BlockHash]
+    justification.TotalWeight = tally.TotalWeight
+
+    return justification, true
+}
+
+// GetRoundState returns the prevotes and precommits seen at a height, this node's lock and the decision if any
+func (pop *ProofOfPlay) GetRoundState(height int64) RoundState {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    state := RoundState{Height: height, LockedRound: -1, Steps: []StepTally{}}
+    rounds, exists := pop.rounds[height]
+    if !exists {
+        return state
+    }
+
+    state.LockedRound = rounds.lockedRound
+    state.LockedBlock = rounds.lockedBlock
+    if rounds.decision != nil {
+        decision := *rounds.decision
+        state.Decision = &decision
+    }
+
+    for key := range rounds.votes {
+        state.Steps = append(state.Steps, pop.stepTally(rounds, key.round, key.step))
+    }
+    sort.Slice(state.Steps, func(i, j int) bool {
+        if state.Steps[i].Round != state.Steps[j].Round {
+            return state.Steps[i].Round < state.Steps[j].Round
+        }
+        return state.Steps[i].Step == StepPrevote && state.Steps[j].Step == StepPrecommit
+    })
+
+    return state
+}
+
+// castStepVote records a verified prevote or precommit, deciding the height once 2/3 of the weight precommits a block
+// A vote conflicting with one the validator signed in the same step is not counted; the evidence is returned instead
+// The caller must hold the lock
+func (pop *ProofOfPlay) castStepVote(vote Vote) *Evidence {
+    rounds := pop.roundsAt(vote.Height)
+    key := roundStep{round: vote.Round, step: vote.Step}
+
+    votes, exists := rounds.votes[key]
+    if !exists {
+        votes = make(map[string]Vote)
+        rounds.votes[key] = votes
+    }
+
+    if earlier, voted := votes[vote.Validator]; voted {
+        if votesConflict(earlier, vote) {
+            return newVoteEvidence(earlier, vote)
+        }
+        return nil
+    }
+
+    votes[vote.Validator] = vote
+    pop.recordVote(vote)
+
+    if vote.Step == StepPrecommit && rounds.decision == nil {
+        if block, found := pop.majority(rounds, vote.Round, StepPrecommit); found && block != "" {
+            decision := &Decision{Height: vote.Height, Round: vote.Round, BlockHash: block, Precommits: []Vote{}}
+            for _, precommit := range votes {
+                if precommit.BlockHash == block {
+                    decision.Precommits = append(decision.Precommits, precommit)
+                }
+            }
+            sort.Slice(decision.Precommits, func(i, j int) bool {
+                return decision.Precommits[i].Validator < decision.Precommits[j].Validator
+            })
+            rounds.decision = decision
+        }
+    }
+    pop.persistRounds(vote.Height)
+
+    return nil
+}
+
+// signStepVote signs a prevote or precommit with a validator's key pair and records it
+// Nothing conflicting with the persisted sign state is signed, and the vote is durable before it is returned to broadcast
+// The caller must hold the lock
+func (pop *ProofOfPlay) signStepVote(vote Vote, keyPair *crypto.KeyPair) (*Vote, error) {
+    vote.Validator = pop.signer(keyPair, vote.Height)
+    validator := pop.validator(vote.Validator)
+    if validator == nil {
+        return nil, errors.New("validator not registered")
+    }
+    if !validator.Active() {
+        return nil, errors.New("validator is not in the active set")
+    }
+    if err := pop.checkSignVote(vote); err != nil {
+        return nil, err
+    }
+
+    vote.Timestamp = pop.now()
+    if err := vote.Sign(keyPair); err != nil {
+        return nil, err
+    }
+
+    if evidence := pop.castStepVote(vote); evidence != nil {
+        return nil, errors.New("vote conflicts with one already cast")
+    }
+    if err := pop.recordSignVote(vote); err != nil {
+        return nil, err
+    }
+
+    return &vote, nil
+}
+
+// stepVote returns the vote a validator cast in the same step of the same round as a vote, if any
+// The rounds may be nil, for a height nothing has been voted at
+func (rounds *heightRounds) stepVote(vote Vote) (Vote, bool) {
+    if rounds == nil {
+        return Vote{}, false
+    }
+
+    earlier, voted := rounds.votes[roundStep{round: vote.Round, step: vote.Step}][vote.Validator]
+    return earlier, voted
+}
+
+// hasStepVote reports whether the key pair's validator has voted in a step of a round
+// The caller must hold the lock
+func (pop *ProofOfPlay) hasStepVote(rounds *heightRounds, height int64, round int64, step string, keyPair *crypto.KeyPair) bool {
+    _, voted := rounds.votes[roundStep{round: round, step: step}][pop.signer(keyPair, height)]
+    return voted
+}
+
+// majority returns the block, or nil as the empty hash, that more than 2/3 of the weight voted for in a step of a round
+// The caller must hold the lock
+func (pop *ProofOfPlay) majority(rounds *heightRounds, round int64, step string) (string, bool) {
+    tally := pop.stepTally(rounds, round, step)
+    for block, weight := range tally.Weights {
+        if quorum(weight, tally.TotalWeight) {
+            return block, true
+        }
+    }
+
+    return "", false
+}
+
+// polkaRound returns the latest round before a given one in which 2/3 of the weight prevoted a block, or -1
+// The caller must hold the lock
+func (pop *ProofOfPlay) polkaRound(rounds *heightRounds, block string, before int64) int64 {
+    latest := int64(-1)
+    for key := range rounds.votes {
+        if key.step != StepPrevote || key.round >= before || key.round <= latest {
+            continue
+        }
+        if majority, found := pop.majority(rounds, key.round, StepPrevote); found && majority == block {
+            latest = key.round
+        }
+    }
+
+    return latest
+}
+
+// stepTally weighs the votes in a step of a round by the current active validators
+// The caller must hold the lock
+func (pop *ProofOfPlay) stepTally(rounds *heightRounds, round int64, step string) StepTally {
+    tally := StepTally{Round: round, Step: step, Weights: make(map[string]float64)}
+
+    weights := make(map[string]float64, len(pop.validators))
+    for _, validator := range pop.validators {
+        if validator.Active() {
+            weights[validator.Address] = validator.ConsensusWeight()
+            tally.TotalWeight += validator.ConsensusWeight()
+        }
+    }
+
+    for address, vote := range rounds.votes[roundStep{round: round, step: step}] {
+        weight, active := weights[address]
+        if !active {
+            continue
+        }
+        tally.Weights[vote.BlockHash] += weight
+        tally.VotedWeight += weight
+    }
+
+    return tally
+}
+
+// roundsAt returns the voting at a height, starting it if needed
+// The caller must hold the lock
+func (pop *ProofOfPlay) roundsAt(height int64) *heightRounds {
+    rounds, exists := pop.rounds[height]
+    if !exists {
+        rounds = &heightRounds{votes: make(map[roundStep]map[string]Vote), lockedRound: -1}
+        pop.rounds[height] = rounds
+    }
+
+    return rounds
+}
+
+// finalized reports whether a block has been decided at its height
+// The caller must hold the lock
+func (pop *ProofOfPlay) finalized(block BlockRef) bool {
+    rounds, exists := pop.rounds[block.Height]
+    return exists && rounds.decision != nil && rounds.decision.BlockHash == block.Hash
+}
+
+// quorum reports whether a weight is more than 2/3 of the total
+func quorum(weight float64, total float64) bool {
+    return total > 0 && weight*3 > total*2
+}
@@ -0,0 +1,126 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Bonding transaction types; Sender must be a registered validator
+const (
+    TxTypeBond   = "validator_bond"   // Amount: tokens moved from the validator's wallet into its stake
+    TxTypeUnbond = "validator_unbond" // Amount: bonded stake to return to the wallet once the unbonding period is over
+)
+
+// StakeEscrowAddress is the account holding the stake validators registered or bonded on chain until it is unbonded
+const StakeEscrowAddress = "validator_stake"
+
+// DefaultUnbondingPeriod is how long unbonded stake waits before it is released, in seconds (7 days)
+const DefaultUnbondingPeriod = 7 * 24 * 60 * 60
+
+// Unbonding is stake on its way out of a validator
+// It no longer weighs in selection or votes but can still be slashed until it is released
+type Unbonding struct {
+    Amount      token.Amount `json:"amount"`
+    Height      int64        `json:"height"`      // Block the unbond was committed in
+    ReleaseTime int64        `json:"releaseTime"` // Block time from which the stake is back in the wallet
+}
+
+// GetValidator returns a copy of a registered validator, with its bonded and unbonding stake
+func (pop *ProofOfPlay) GetValidator(address string) (Validator, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    validator := pop.validator(address)
+    if validator == nil {
+        return Validator{}, errors.New("validator not registered")
+    }
+
+    return validator.clone(), nil
+}
+
+// UnbondingStake returns the stake a validator has waiting to be released
+func (v Validator) UnbondingStake() token.Amount {
+    total := token.Amount(0)
+    for _, unbonding := range v.Unbonding {
+        total += unbonding.Amount
+    }
+
+    return total
+}
+
+// checkBonding validates a bond or unbond transaction against the validator set
+// Balances are only known when the transaction is applied, so a bond the sender can't afford passes here
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkBonding(tx core.Transaction) error {
+    validator := pop.validator(tx.Sender)
+    if validator == nil {
+        return errors.New("validator not registered")
+    }
+    if tx.Amount <= 0 {
+        return errors.New("amount must be positive")
+    }
+
+    if tx.Type == TxTypeUnbond && tx.Amount > validator.Bonded {
+        return fmt.Errorf("only %s is bonded", validator.Bonded)
+    }
+    // Validators registered on chain keep the minimum stake locked
+    if tx.Type == TxTypeUnbond && validator.RegisteredAt > 0 && validator.Stake-tx.Amount < pop.MinValidatorStake {
+        return fmt.Errorf("validator must keep at least %s staked", pop.MinValidatorStake)
+    }
+
+    return nil
+}
+
+// applyBonding executes a checked bond or unbond transaction
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyBonding(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    validator := pop.validator(tx.Sender)
+
+    switch tx.Type {
+    case TxTypeBond:
+        // A validator who can't cover the bond bonds nothing
+        if state.Transfer(tx.Sender, StakeEscrowAddress, tx.Amount) != nil {
+            return
+        }
+
+        validator.Stake += tx.Amount
+        validator.Bonded += tx.Amount
+
+    case TxTypeUnbond:
+        validator.Stake -= tx.Amount
+        validator.Bonded -= tx.Amount
+        validator.Unbonding = append(validator.Unbonding, Unbonding{
+            Amount:      tx.Amount,
+            Height:      header.Index,
+            ReleaseTime: header.Timestamp + pop.UnbondingPeriod,
+        })
+    }
+}
+
+// releaseUnbonded moves the unbonding stake released by a block time from the escrow to validators' wallets;
+// stake the escrow can't cover keeps unbonding until it can
+// The caller must hold the lock
+func (pop *ProofOfPlay) releaseUnbonded(timestamp int64, state *core.State) {
+    for i := range pop.validators {
+        validator := &pop.validators[i]
+
+        pending := []Unbonding{}
+        for _, unbonding := range validator.Unbonding {
+            if unbonding.ReleaseTime > timestamp {
+                pending = append(pending, unbonding)
+                continue
+            }
+            if unbonding.Amount > 0 && state.Transfer(StakeEscrowAddress, validator.Address, unbonding.Amount) != nil {
+                pending = append(pending, unbonding)
+            }
+        }
+
+        if len(pending) == 0 {
+            pending = nil
+        }
+        validator.Unbonding = pending
+    }
+}
@@ -0,0 +1,100 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+    "math"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// DefaultMaxCommissionChange is the most a validator's commission rate can move in one change (1 percentage point)
+const DefaultMaxCommissionChange = 0.01
+
+// CommissionChangeInterval is how long a validator waits between commission changes, in seconds of block time (1 day)
+const CommissionChangeInterval = 24 * 60 * 60
+
+// commissionTolerance absorbs float rounding when comparing a change with the maximum
+const commissionTolerance = 1e-9
+
+// CommissionRates is a validator's commission and how far it can change next, for delegators to check before delegating
+type CommissionRates struct {
+    Validator      string  `json:"validator"`
+    Rate           float64 `json:"rate"`                // Share of its delegators' rewards the validator keeps
+    UpdatedAt      int64   `json:"updatedAt,omitempty"` // Block time the rate was last changed
+    NextChangeTime int64   `json:"nextChangeTime"`      // Block time from which the rate may change again
+    MinNextRate    float64 `json:"minNextRate"`         // Range the next change must stay within
+    MaxNextRate    float64 `json:"maxNextRate"`
+}
+
+// GetCommission returns a validator's commission rate and the range its next change must stay within
+func (pop *ProofOfPlay) GetCommission(address string) (CommissionRates, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    validator := pop.validator(address)
+    if validator == nil {
+        return CommissionRates{}, errors.New("validator not registered")
+    }
+
+    rates := CommissionRates{
+        Validator:   validator.Address,
+        Rate:        validator.Commission,
+        UpdatedAt:   validator.CommissionUpdated,
+        MinNextRate: 0,
+        MaxNextRate: 1,
+    }
+    if validator.CommissionUpdated > 0 {
+        rates.NextChangeTime = validator.CommissionUpdated + CommissionChangeInterval
+    }
+    if pop.MaxCommissionChange > 0 {
+        rates.MinNextRate = math.Max(0, validator.Commission-pop.MaxCommissionChange)
+        rates.MaxNextRate = math.Min(1, validator.Commission+pop.MaxCommissionChange)
+    }
+
+    return rates, nil
+}
+
+// checkCommission validates a set commission transaction at a given block time
+// A validator changes its rate at most once a day, by at most the maximum change
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkCommission(tx core.Transaction, now int64) error {
+    validator := pop.validator(tx.Sender)
+    if validator == nil {
+        return errors.New("validator not registered")
+    }
+
+    rate, ok := txRate(tx)
+    if !ok || rate < 0 || rate > 1 {
+        return errors.New("commission rate must be between 0 and 1")
+    }
+    if rate == validator.Commission {
+        return errors.New("commission rate is unchanged")
+    }
+    if pop.MaxCommissionChange > 0 && math.Abs(rate-validator.Commission) > pop.MaxCommissionChange+commissionTolerance {
+        return fmt.Errorf("commission rate can change by at most %.4f at a time", pop.MaxCommissionChange)
+    }
+    if validator.CommissionUpdated > 0 && now < validator.CommissionUpdated+CommissionChangeInterval {
+        return fmt.Errorf("commission rate can't change again until %d", validator.CommissionUpdated+CommissionChangeInterval)
+    }
+
+    return nil
+}
+
+// applyCommission executes a checked set commission transaction
+// Rewards already shared keep the old rate; the new one applies from the next reward paid
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyCommission(tx core.Transaction, header core.BlockHeader) {
+    rate, _ := txRate(tx)
+
+    validator := pop.validator(tx.Sender)
+    validator.Commission = rate
+    validator.CommissionUpdated = header.Timestamp
+}
+
+// txRate returns the commission rate a set commission transaction asks for
+func txRate(tx core.Transaction) (float64, bool) {
+    data, _ := tx.Data.(map[string]interface{})
+    rate, ok := data["rate"].(float64)
+    return rate, ok
+}
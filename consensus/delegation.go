@@ -0,0 +1,392 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Delegation transaction types; Recipient is the validator delegated to
+const (
+    TxTypeDelegate        = "delegate"         // Amount: tokens moved from the sender's wallet into a delegation
+    TxTypeUndelegate      = "undelegate"       // Amount: delegated tokens to return to the wallet once the unbonding period is over
+    TxTypeRedelegate      = "redelegate"       // Amount, Data: from, the validator the delegation moves away from
+    TxTypeWithdrawRewards = "withdraw_rewards" // Pays the rewards a delegation has accrued to the sender's wallet
+    TxTypeSetCommission   = "set_commission"   // Data: rate between 0 and 1, within the daily change limit; Sender is the validator, no Recipient
+)
+
+// DelegationEscrowAddress is the account holding delegated stake until it is undelegated,
+// and the rewards delegations have accrued until they are withdrawn
+const DelegationEscrowAddress = "delegations"
+
+// DefaultCommission is the share of its delegators' rewards a validator keeps until it sets its own rate (10%)
+const DefaultCommission = 0.10
+
+// Delegation is a token holder's stake backing a validator it doesn't run
+type Delegation struct {
+    Delegator string       `json:"delegator"`
+    Validator string       `json:"validator"`
+    Amount    token.Amount `json:"amount"`
+    Rewards   token.Amount `json:"rewards"` // Accrued and not yet withdrawn
+}
+
+// Undelegation is delegated stake on its way back to the delegator's wallet
+// Like unbonding stake it can still be slashed for the validator's misbehavior until it is released
+type Undelegation struct {
+    Delegator   string       `json:"delegator"`
+    Validator   string       `json:"validator"`
+    Amount      token.Amount `json:"amount"`
+    Height      int64        `json:"height"`
+    ReleaseTime int64        `json:"releaseTime"`
+}
+
+// Redelegation is delegated stake moved straight to another validator
+// Until it completes, the moved stake answers for the source validator's misbehavior,
+// and the delegator can't move stake away from the destination again
+type Redelegation struct {
+    Delegator    string       `json:"delegator"`
+    From         string       `json:"from"`
+    To           string       `json:"to"`
+    Amount       token.Amount `json:"amount"`
+    Height       int64        `json:"height"`
+    CompleteTime int64        `json:"completeTime"`
+}
+
+// delegationKey identifies the delegation of one delegator to one validator
+type delegationKey struct {
+    delegator string
+    validator string
+}
+
+// GetDelegations returns the delegations backing a validator, ordered by delegator
+func (pop *ProofOfPlay) GetDelegations(validator string) []Delegation {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    delegations := []Delegation{}
+    for _, delegation := range pop.delegationsTo(validator) {
+        delegations = append(delegations, *delegation)
+    }
+
+    return delegations
+}
+
+// GetDelegatorStakes returns a delegator's delegations, undelegations and redelegations still in progress
+func (pop *ProofOfPlay) GetDelegatorStakes(delegator string) ([]Delegation, []Undelegation, []Redelegation) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    delegations := []Delegation{}
+    for key, delegation := range pop.delegations {
+        if key.delegator == delegator {
+            delegations = append(delegations, *delegation)
+        }
+    }
+    sort.Slice(delegations, func(i, j int) bool {
+        return delegations[i].Validator < delegations[j].Validator
+    })
+
+    undelegations := []Undelegation{}
+    for _, undelegation := range pop.undelegations {
+        if undelegation.Delegator == delegator {
+            undelegations = append(undelegations, undelegation)
+        }
+    }
+
+    redelegations := []Redelegation{}
+    for _, redelegation := range pop.redelegations {
+        if redelegation.Delegator == delegator {
+            redelegations = append(redelegations, redelegation)
+        }
+    }
+
+    return delegations, undelegations, redelegations
+}
+
+// checkDelegation validates a delegation transaction against the validator set and existing delegations
+// Balances are only known when the transaction is applied, so a delegation the sender can't afford passes here
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkDelegation(tx core.Transaction) error {
+    // Delegators can still undelegate and withdraw from a validator that has been pruned
+    validator := pop.validator(tx.Recipient)
+    delegation := pop.delegations[delegationKey{delegator: tx.Sender, validator: tx.Recipient}]
+
+    switch tx.Type {
+    case TxTypeDelegate:
+        if validator == nil {
+            return errors.New("validator not registered")
+        }
+        if tx.Sender == tx.Recipient {
+            return errors.New("validators bond their own stake rather than delegate it")
+        }
+        if !validator.Active() {
+            return errors.New("validator has been removed from the active set")
+        }
+        if tx.Amount <= 0 {
+            return errors.New("amount must be positive")
+        }
+
+    case TxTypeUndelegate:
+        if tx.Amount <= 0 {
+            return errors.New("amount must be positive")
+        }
+        if delegation == nil || tx.Amount > delegation.Amount {
+            return errors.New("amount exceeds the delegation")
+        }
+
+    case TxTypeRedelegate:
+        from := redelegationSource(tx)
+        source := pop.delegations[delegationKey{delegator: tx.Sender, validator: from}]
+        if from == "" || from == tx.Recipient {
+            return errors.New("redelegation needs a different source validator")
+        }
+        if validator == nil {
+            return errors.New("validator not registered")
+        }
+        if tx.Sender == tx.Recipient {
+            return errors.New("validators bond their own stake rather than delegate it")
+        }
+        if !validator.Active() {
+            return errors.New("validator has been removed from the active set")
+        }
+        if tx.Amount <= 0 {
+            return errors.New("amount must be positive")
+        }
+        if source == nil || tx.Amount > source.Amount {
+            return errors.New("amount exceeds the delegation")
+        }
+
+        // Stake can't hop again before its last move completes, or it could outrun a slash
+        for _, redelegation := range pop.redelegations {
+            if redelegation.Delegator == tx.Sender && redelegation.To == from {
+                return fmt.Errorf("stake redelegated to %s can't be moved until %d", from, redelegation.CompleteTime)
+            }
+        }
+
+    case TxTypeWithdrawRewards:
+        if delegation == nil || delegation.Rewards <= 0 {
+            return errors.New("no rewards to withdraw")
+        }
+    }
+
+    return nil
+}
+
+// applyDelegation executes a checked delegation transaction
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyDelegation(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    validator := pop.validator(tx.Recipient)
+    key := delegationKey{delegator: tx.Sender, validator: tx.Recipient}
+
+    switch tx.Type {
+    case TxTypeDelegate:
+        // A delegator who can't cover the delegation delegates nothing
+        if state.Transfer(tx.Sender, DelegationEscrowAddress, tx.Amount) != nil {
+            return
+        }
+
+        pop.delegation(key).Amount += tx.Amount
+        validator.Delegated += tx.Amount
+
+    case TxTypeUndelegate:
+        pop.delegations[key].Amount -= tx.Amount
+        if validator != nil {
+            validator.Delegated -= tx.Amount
+        }
+        pop.undelegations = append(pop.undelegations, Undelegation{
+            Delegator:   tx.Sender,
+            Validator:   tx.Recipient,
+            Amount:      tx.Amount,
+            Height:      header.Index,
+            ReleaseTime: header.Timestamp + pop.UnbondingPeriod,
+        })
+        pop.pruneDelegation(key)
+
+    case TxTypeRedelegate:
+        from := redelegationSource(tx)
+        sourceKey := delegationKey{delegator: tx.Sender, validator: from}
+
+        pop.delegations[sourceKey].Amount -= tx.Amount
+        if source := pop.validator(from); source != nil {
+            source.Delegated -= tx.Amount
+        }
+        pop.delegation(key).Amount += tx.Amount
+        validator.Delegated += tx.Amount
+
+        pop.redelegations = append(pop.redelegations, Redelegation{
+            Delegator:    tx.Sender,
+            From:         from,
+            To:           tx.Recipient,
+            Amount:       tx.Amount,
+            Height:       header.Index,
+            CompleteTime: header.Timestamp + pop.UnbondingPeriod,
+        })
+        pop.pruneDelegation(sourceKey)
+
+    case TxTypeWithdrawRewards:
+        delegation := pop.delegations[key]
+        if delegation.Rewards > 0 && state.Transfer(DelegationEscrowAddress, tx.Sender, delegation.Rewards) != nil {
+            return
+        }
+        delegation.Rewards = 0
+        pop.pruneDelegation(key)
+    }
+}
+
+// shareReward passes the delegators' part of a reward paid to a validator on to them, less the validator's commission
+// Delegators and locks delegated to the validator earn in proportion to the stake they add to it;
+// a lock's part is paid straight to its owner
+// The caller must hold the lock
+func (pop *ProofOfPlay) shareReward(address string, amount token.Amount, state *core.State) {
+    validator := pop.validator(address)
+    if validator == nil || validator.Delegated <= 0 || amount <= 0 {
+        return
+    }
+
+    share := amount.Scale(validator.Delegated, validator.Stake+validator.Delegated)
+    share -= share.MulRate(validator.Commission)
+    if share <= 0 {
+        return
+    }
+
+    // Only what the delegations and locks are credited leaves the validator's wallet, so rounding never loses units;
+    // delegations' rewards wait in the delegation escrow until they are withdrawn
+    delegations := pop.delegationsTo(validator.Address)
+    accrued := token.Amount(0)
+    for _, delegation := range delegations {
+        accrued += share.Scale(delegation.Amount, validator.Delegated)
+    }
+    if accrued > 0 && state.Transfer(validator.Address, DelegationEscrowAddress, accrued) != nil {
+        return
+    }
+    for _, delegation := range delegations {
+        delegation.Rewards += share.Scale(delegation.Amount, validator.Delegated)
+    }
+    for _, lock := range pop.locksWith(validator.Address) {
+        reward := share.Scale(lock.Amount, validator.Delegated)
+        if reward <= 0 || state.Transfer(validator.Address, lock.Owner, reward) != nil {
+            continue
+        }
+        lock.Rewards += reward
+    }
+}
+
+// releaseUndelegated moves the undelegated stake released by a block time from the escrow to delegators' wallets,
+// keeping what the escrow can't cover undelegating until it can, and completes the redelegations whose cooldown is over
+// The caller must hold the lock
+func (pop *ProofOfPlay) releaseUndelegated(timestamp int64, state *core.State) {
+    pending := []Undelegation{}
+    for _, undelegation := range pop.undelegations {
+        if undelegation.ReleaseTime > timestamp {
+            pending = append(pending, undelegation)
+            continue
+        }
+        if undelegation.Amount > 0 && state.Transfer(DelegationEscrowAddress, undelegation.Delegator, undelegation.Amount) != nil {
+            pending = append(pending, undelegation)
+        }
+    }
+    pop.undelegations = pending
+
+    moving := []Redelegation{}
+    for _, redelegation := range pop.redelegations {
+        if redelegation.CompleteTime > timestamp {
+            moving = append(moving, redelegation)
+        }
+    }
+    pop.redelegations = moving
+}
+
+// slashDelegations takes the slash fraction of the stake delegated to a misbehaving validator,
+// including stake undelegating or redelegated away from it, and returns the total taken
+// The caller must hold the lock
+func (pop *ProofOfPlay) slashDelegations(validator *Validator) token.Amount {
+    slashed := token.Amount(0)
+
+    for _, delegation := range pop.delegationsTo(validator.Address) {
+        cut := delegation.Amount.MulRate(pop.SlashFraction)
+        delegation.Amount -= cut
+        validator.Delegated -= cut
+        slashed += cut
+    }
+
+    for i := range pop.undelegations {
+        if pop.undelegations[i].Validator == validator.Address {
+            cut := pop.undelegations[i].Amount.MulRate(pop.SlashFraction)
+            pop.undelegations[i].Amount -= cut
+            slashed += cut
+        }
+    }
+
+    // Redelegated stake is taken from the delegation it moved into
+    for i := range pop.redelegations {
+        redelegation := &pop.redelegations[i]
+        if redelegation.From != validator.Address {
+            continue
+        }
+
+        cut := redelegation.Amount.MulRate(pop.SlashFraction)
+        redelegation.Amount -= cut
+
+        destination := pop.delegations[delegationKey{delegator: redelegation.Delegator, validator: redelegation.To}]
+        if destination == nil {
+            continue
+        }
+        if cut > destination.Amount {
+            cut = destination.Amount
+        }
+        destination.Amount -= cut
+        if to := pop.validator(redelegation.To); to != nil {
+            to.Delegated -= cut
+        }
+        slashed += cut
+    }
+
+    return slashed
+}
+
+// delegationsTo returns the delegations backing a validator, ordered by delegator so sums over them agree on every node
+// The caller must hold the lock
+func (pop *ProofOfPlay) delegationsTo(validator string) []*Delegation {
+    delegations := []*Delegation{}
+    for key, delegation := range pop.delegations {
+        if key.validator == validator {
+            delegations = append(delegations, delegation)
+        }
+    }
+
+    sort.Slice(delegations, func(i, j int) bool {
+        return delegations[i].Delegator < delegations[j].Delegator
+    })
+
+    return delegations
+}
+
+// delegation returns a delegation, creating it if it doesn't exist
+// The caller must hold the lock
+func (pop *ProofOfPlay) delegation(key delegationKey) *Delegation {
+    delegation, exists := pop.delegations[key]
+    if !exists {
+        delegation = &Delegation{Delegator: key.delegator, Validator: key.validator}
+        pop.delegations[key] = delegation
+    }
+
+    return delegation
+}
+
+// pruneDelegation forgets a delegation with no stake and no rewards left
+// The caller must hold the lock
+func (pop *ProofOfPlay) pruneDelegation(key delegationKey) {
+    if delegation, exists := pop.delegations[key]; exists && delegation.Amount <= 0 && delegation.Rewards <= 0 {
+        delete(pop.delegations, key)
+    }
+}
+
+// redelegationSource returns the validator a redelegation moves stake away from
+func redelegationSource(tx core.Transaction) string {
+    data, _ := tx.Data.(map[string]interface{})
+    from, _ := data["from"].(string)
+    return from
+}
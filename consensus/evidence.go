@@ -0,0 +1,460 @@
+package consensus
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeEvidence commits proof of a validator's misbehavior, slashing it and removing it from the active set
+// Data: evidence, an Evidence object; anyone may submit it, since the evidence proves itself
+const TxTypeEvidence = "consensus_evidence"
+
+// Kinds of misbehavior
+const (
+    EvidenceDoubleSign      = "double_sign"      // Two different blocks signed at the same height
+    EvidenceDoubleVote      = "double_vote"      // Conflicting votes at the same height
+    EvidenceInvalidProposal = "invalid_proposal" // A signed block carrying a transaction no node would accept
+)
+
+// DefaultSlashFraction is the share of its stake a validator loses for misbehaving (5%)
+const DefaultSlashFraction = 0.05
+
+// DefaultMaxEvidenceAge is how many blocks after the misbehavior its evidence can still be committed
+const DefaultMaxEvidenceAge = 1000
+
+// Evidence proves a validator misbehaved with messages it signed: two that conflict, or a block that is invalid
+type Evidence struct {
+    Kind      string             `json:"kind"`
+    Validator string             `json:"validator"`
+    Height    int64              `json:"height"`
+    Headers   []core.BlockHeader `json:"headers,omitempty"` // The two blocks of a double sign
+    Votes     []Vote             `json:"votes,omitempty"`   // The two votes of a double vote
+    Block     *core.Block        `json:"block,omitempty"`   // The block of an invalid proposal
+}
+
+// CommittedEvidence is evidence executed on chain, with the slash it caused
+type CommittedEvidence struct {
+    Evidence
+    ID          string       `json:"id"`
+    BlockHeight int64        `json:"blockHeight"`
+    Slashed     token.Amount `json:"slashed"` // Stake the validator and its delegators lost, including stake still unbonding
+}
+
+// headerKey identifies the block a validator signed at a height
+type headerKey struct {
+    height    int64
+    validator string
+}
+
+// VoteSigningPayload returns the bytes a validator signs for a vote
+// Round and step are left out of approval votes, so they sign the same bytes as before prevotes and precommits
+func VoteSigningPayload(v Vote) ([]byte, error) {
+    return core.CanonicalEncode(struct {
+        Height    int64  `json:"height"`
+        Round     int64  `json:"round,omitempty"`
+        Step      string `json:"step,omitempty"`
+        BlockHash string `json:"blockHash"`
+        Approve   bool   `json:"approve"`
+    }{
+        Height:    v.Height,
+        Round:     v.Round,
+        Step:      v.Step,
+        BlockHash: v.BlockHash,
+        Approve:   v.Approve,
+    })
+}
+
+// Sign fills in the public key and signature of a vote for a validator's key pair,
+// and the validator if unset, as the address of the key pair
+func (v *Vote) Sign(keyPair *crypto.KeyPair) error {
+    payload, err := VoteSigningPayload(*v)
+    if err != nil {
+        return err
+    }
+
+    signature, err := keyPair.Sign(payload)
+    if err != nil {
+        return err
+    }
+
+    if v.Validator == "" {
+        v.Validator = crypto.GetAddressFromPublicKey(keyPair.PublicKey)
+    }
+    v.PublicKey = crypto.PublicKeyToHex(keyPair.PublicKey)
+    v.Signature = signature
+    return nil
+}
+
+// Verify checks that a vote was signed by its validator, with the key its address is derived from
+func (v Vote) Verify() error {
+    if err := v.verifySignature(); err != nil {
+        return err
+    }
+
+    publicKey, _ := crypto.HexToPublicKey(v.PublicKey)
+    if crypto.GetAddressFromPublicKey(publicKey) != v.Validator {
+        return errors.New("vote public key does not match its validator")
+    }
+
+    return nil
+}
+
+// verifySignature checks that a vote is well formed and was signed by the public key it carries
+func (v Vote) verifySignature() error {
+    if v.Signature == "" || v.PublicKey == "" {
+        return errors.New("vote is not signed")
+    }
+    if v.Round < 0 {
+        return errors.New("round can't be negative")
+    }
+    switch v.Step {
+    case "":
+        if v.BlockHash == "" {
+            return errors.New("block hash is required")
+        }
+    case StepPrevote, StepPrecommit:
+        if v.Approve != (v.BlockHash != "") {
+            return errors.New("only votes for a block approve")
+        }
+    default:
+        return fmt.Errorf("unknown vote step %q", v.Step)
+    }
+
+    publicKey, err := crypto.HexToPublicKey(v.PublicKey)
+    if err != nil {
+        return fmt.Errorf("invalid vote public key: %w", err)
+    }
+
+    payload, err := VoteSigningPayload(v)
+    if err != nil {
+        return err
+    }
+
+    valid, err := crypto.Verify(payload, v.Signature, publicKey)
+    if err != nil || !valid {
+        return errors.New("invalid vote signature")
+    }
+
+    return nil
+}
+
+// votesConflict reports whether two votes by one validator in one round at one height contradict each other:
+// approving two different blocks, or both approving and rejecting the same block
+// Prevotes and precommits only conflict within the same step, where they back different blocks
+func votesConflict(a Vote, b Vote) bool {
+    if a.Validator != b.Validator || a.Height != b.Height || a.Round != b.Round || a.Step != b.Step {
+        return false
+    }
+    if a.Step != "" {
+        return a.BlockHash != b.BlockHash
+    }
+    if a.BlockHash == b.BlockHash {
+        return a.Approve != b.Approve
+    }
+
+    return a.Approve && b.Approve
+}
+
+// newVoteEvidence returns the evidence of two conflicting votes
+func newVoteEvidence(a Vote, b Vote) *Evidence {
+    votes := []Vote{a, b}
+    sort.Slice(votes, func(i, j int) bool {
+        return votes[i].Signature < votes[j].Signature
+    })
+
+    return &Evidence{Kind: EvidenceDoubleVote, Validator: a.Validator, Height: a.Height, Votes: votes}
+}
+
+// newHeaderEvidence returns the evidence of two blocks signed at one height
+func newHeaderEvidence(a core.BlockHeader, b core.BlockHeader) *Evidence {
+    headers := []core.BlockHeader{a, b}
+    sort.Slice(headers, func(i, j int) bool {
+        return headers[i].Hash < headers[j].Hash
+    })
+
+    return &Evidence{Kind: EvidenceDoubleSign, Validator: a.Validator, Height: a.Index, Headers: headers}
+}
+
+// InvalidProposalEvidence returns the evidence against the producer of a signed block with an invalid transaction,
+// or nil if the block doesn't prove its producer misbehaved
+// Only checks every node can repeat without the chain count, so the evidence can be verified by anyone
+func InvalidProposalEvidence(block core.Block) *Evidence {
+    if block.Signature == "" || core.VerifySignedHeader(block.BlockHeader) != nil {
+        return nil
+    }
+    if block.Hash != core.CalculateHeaderHash(block.BlockHeader) || block.TxRoot != core.ComputeTxRoot(block.Transactions) {
+        return nil
+    }
+    if invalidTransaction(block) == nil {
+        return nil
+    }
+
+    return &Evidence{Kind: EvidenceInvalidProposal, Validator: block.Validator, Height: block.Index, Block: &block}
+}
+
+// invalidTransaction returns the error of the first transaction in a block no node would accept, or nil
+func invalidTransaction(block core.Block) error {
+    for _, tx := range block.Transactions {
+        if err := core.ValidateTransaction(tx); err != nil {
+            return fmt.Errorf("transaction %s: %w", tx.ID, err)
+        }
+    }
+
+    return nil
+}
+
+// ID returns the evidence's ID, the hash of its canonical encoding
+// The conflicting messages are kept in a fixed order, so the same misbehavior always has the same ID
+func (e *Evidence) ID() string {
+    hash, _ := core.CanonicalHash(e)
+    return hash
+}
+
+// Verify checks that the evidence holds two conflicting messages signed by the accused validator,
+// or an invalid block it signed, with the key the validator's address is derived from
+func (e *Evidence) Verify() error {
+    return e.verify(func(address string, publicKey string, height int64) error {
+        key, err := crypto.HexToPublicKey(publicKey)
+        if err != nil || crypto.GetAddressFromPublicKey(key) != address {
+            return errors.New("public key does not match the accused validator")
+        }
+        return nil
+    })
+}
+
+// verify checks the evidence, with a check that each message's key is the accused validator's at its height
+func (e *Evidence) verify(checkSigner func(address string, publicKey string, height int64) error) error {
+    switch e.Kind {
+    case EvidenceDoubleSign:
+        if len(e.Headers) != 2 {
+            return errors.New("double sign evidence needs two headers")
+        }
+        for _, header := range e.Headers {
+            if header.Validator != e.Validator || header.Index != e.Height {
+                return errors.New("header does not match the accused validator and height")
+            }
+            if err := core.VerifySignedHeader(header); err != nil {
+                return err
+            }
+            if err := checkSigner(header.Validator, header.PublicKey, header.Index); err != nil {
+                return err
+            }
+        }
+        if e.Headers[0].Hash == e.Headers[1].Hash {
+            return errors.New("headers are the same block")
+        }
+
+    case EvidenceDoubleVote:
+        if len(e.Votes) != 2 {
+            return errors.New("double vote evidence needs two votes")
+        }
+        for _, vote := range e.Votes {
+            if vote.Validator != e.Validator || vote.Height != e.Height {
+                return errors.New("vote does not match the accused validator and height")
+            }
+            if err := vote.verifySignature(); err != nil {
+                return err
+            }
+            if err := checkSigner(vote.Validator, vote.PublicKey, vote.Height); err != nil {
+                return err
+            }
+        }
+        if !votesConflict(e.Votes[0], e.Votes[1]) {
+            return errors.New("votes do not conflict")
+        }
+
+    case EvidenceInvalidProposal:
+        if e.Block == nil {
+            return errors.New("invalid proposal evidence needs the block")
+        }
+        if e.Block.Validator != e.Validator || e.Block.Index != e.Height {
+            return errors.New("block does not match the accused validator and height")
+        }
+        if err := core.VerifySignedHeader(e.Block.BlockHeader); err != nil {
+            return err
+        }
+        if err := checkSigner(e.Validator, e.Block.PublicKey, e.Height); err != nil {
+            return err
+        }
+
+        // The signature only covers the hash, so the header and body must be the ones the hash commits to
+        if e.Block.Hash != core.CalculateHeaderHash(e.Block.BlockHeader) {
+            return errors.New("block hash does not match its header")
+        }
+        if e.Block.TxRoot != core.ComputeTxRoot(e.Block.Transactions) {
+            return errors.New("block transaction root does not match its body")
+        }
+        if invalidTransaction(*e.Block) == nil {
+            return errors.New("block transactions are valid")
+        }
+
+    default:
+        return fmt.Errorf("unknown evidence kind %q", e.Kind)
+    }
+
+    return nil
+}
+
+// ObserveHeader notes a signed block header seen from the network or produced locally
+// If the producer signed a different block at the same height, the evidence against it is returned
+// Unsigned headers are ignored, since they prove nothing
+func (pop *ProofOfPlay) ObserveHeader(header core.BlockHeader) (*Evidence, error) {
+    if header.Signature == "" {
+        return nil, nil
+    }
+    if err := core.VerifySignedHeader(header); err != nil {
+        return nil, err
+    }
+
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if err := pop.checkSigner(header.Validator, header.PublicKey, header.Index); err != nil {
+        return nil, err
+    }
+
+    key := headerKey{height: header.Index, validator: header.Validator}
+    earlier, seen := pop.headers[key]
+    if !seen {
+        pop.headers[key] = header
+        return nil, nil
+    }
+    if earlier.Hash == header.Hash {
+        return nil, nil
+    }
+
+    return newHeaderEvidence(earlier, header), nil
+}
+
+// GetEvidence returns the evidence committed on chain, oldest first
+func (pop *ProofOfPlay) GetEvidence() []CommittedEvidence {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    committed := []CommittedEvidence{}
+    for _, evidence := range pop.evidence {
+        committed = append(committed, *evidence)
+    }
+
+    sort.Slice(committed, func(i, j int) bool {
+        if committed[i].BlockHeight != committed[j].BlockHeight {
+            return committed[i].BlockHeight < committed[j].BlockHeight
+        }
+        return committed[i].ID < committed[j].ID
+    })
+
+    return committed
+}
+
+// EvidenceTransaction returns an unsigned transaction committing evidence, sent by a reporter
+func EvidenceTransaction(evidence *Evidence, reporter string, timestamp int64) core.Transaction {
+    tx := core.Transaction{
+        Type:      TxTypeEvidence,
+        Sender:    reporter,
+        Data:      map[string]interface{}{"evidence": evidence},
+        Timestamp: timestamp,
+    }
+    tx.ID = core.ComputeTransactionID(tx)
+
+    return tx
+}
+
+// decodeEvidence reads the evidence carried by an evidence transaction
+func decodeEvidence(tx core.Transaction) (*Evidence, error) {
+    data, ok := tx.Data.(map[string]interface{})
+    if !ok || data["evidence"] == nil {
+        return nil, errors.New("evidence is required")
+    }
+
+    encoded, err := json.Marshal(data["evidence"])
+    if err != nil {
+        return nil, err
+    }
+
+    var evidence Evidence
+    if err := json.Unmarshal(encoded, &evidence); err != nil {
+        return nil, fmt.Errorf("invalid evidence: %w", err)
+    }
+
+    return &evidence, nil
+}
+
+// checkEvidence validates evidence against the validator set, for commitment in the block at a height
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkEvidence(evidence *Evidence, height int64) error {
+    if err := evidence.verify(pop.checkSigner); err != nil {
+        return err
+    }
+
+    if pop.MaxEvidenceAge > 0 && height-evidence.Height > pop.MaxEvidenceAge {
+        return fmt.Errorf("evidence from height %d has expired", evidence.Height)
+    }
+
+    if _, committed := pop.evidence[evidence.ID()]; committed {
+        return errors.New("evidence has already been committed")
+    }
+
+    validator := pop.validator(evidence.Validator)
+    if validator == nil {
+        return errors.New("validator not registered")
+    }
+    if validator.Jailed {
+        return errors.New("validator has already been removed from the active set")
+    }
+    if !pop.wasValidator(evidence.Validator, evidence.Height) {
+        return fmt.Errorf("%s was not a validator at height %d", evidence.Validator, evidence.Height)
+    }
+
+    return nil
+}
+
+// slash takes the slash fraction of a misbehaving validator's stake, including stake still unbonding
+// and stake delegated to it, and removes it from the active set
+// What is taken from the escrows holding stake on chain is burned; stake registered off chain was never on the ledger
+// The caller must hold the lock
+func (pop *ProofOfPlay) slash(evidence *Evidence, height int64, state *core.State) {
+    validator := pop.validator(evidence.Validator)
+    slashed := validator.Stake.MulRate(pop.SlashFraction)
+
+    validator.Stake -= slashed
+    bonded := validator.Bonded.MulRate(pop.SlashFraction)
+    validator.Bonded -= bonded
+    for i := range validator.Unbonding {
+        cut := validator.Unbonding[i].Amount.MulRate(pop.SlashFraction)
+        validator.Unbonding[i].Amount -= cut
+        slashed += cut
+        bonded += cut
+    }
+    delegated := pop.slashDelegations(validator)
+    locked := pop.slashLocks(validator)
+    slashed += delegated + locked
+    validator.Jailed = true
+
+    burnSlashed(state, StakeEscrowAddress, bonded)
+    burnSlashed(state, DelegationEscrowAddress, delegated)
+    burnSlashed(state, StakeLockEscrowAddress, locked)
+
+    id := evidence.ID()
+    pop.evidence[id] = &CommittedEvidence{
+        Evidence:    *evidence,
+        ID:          id,
+        BlockHeight: height,
+        Slashed:     slashed,
+    }
+}
+
+// burnSlashed burns slashed stake out of the escrow holding it, never more than the escrow holds
+func burnSlashed(state *core.State, escrow string, amount token.Amount) {
+    if held := state.Balances[escrow]; amount > held {
+        amount = held
+    }
+    if amount > 0 {
+        state.Burn(escrow, amount)
+    }
+}
@@ -0,0 +1,85 @@
+package consensus
+
+import (
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// PooledEvidence is verified evidence waiting for a block to commit it
+type PooledEvidence struct {
+    Evidence
+    ID         string `json:"id"`
+    ReceivedAt int64  `json:"receivedAt"` // When this node first saw the evidence
+}
+
+// AddEvidence verifies evidence from a peer or this node and pools it until a block commits it
+// It returns false for evidence already pooled, so only new evidence is gossiped on
+func (pop *ProofOfPlay) AddEvidence(evidence *Evidence) (bool, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if err := pop.checkEvidence(evidence, pop.blockHeight+1); err != nil {
+        return false, err
+    }
+
+    id := evidence.ID()
+    if _, pooled := pop.evidencePool[id]; pooled {
+        return false, nil
+    }
+
+    pop.evidencePool[id] = &PooledEvidence{Evidence: *evidence, ID: id, ReceivedAt: pop.now()}
+    return true, nil
+}
+
+// GetPooledEvidence returns the evidence waiting to be committed, oldest misbehavior first
+func (pop *ProofOfPlay) GetPooledEvidence() []PooledEvidence {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.pooledEvidence()
+}
+
+// EvidenceTransactions returns transactions committing the pooled evidence, sent by the producer of the next block
+func (pop *ProofOfPlay) EvidenceTransactions(producer string, timestamp int64) []core.Transaction {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    transactions := []core.Transaction{}
+    for _, pooled := range pop.pooledEvidence() {
+        evidence := pooled.Evidence
+        transactions = append(transactions, EvidenceTransaction(&evidence, producer, timestamp))
+    }
+
+    return transactions
+}
+
+// pooledEvidence returns the pooled evidence ordered by height, then ID
+// The caller must hold the lock
+func (pop *ProofOfPlay) pooledEvidence() []PooledEvidence {
+    pooled := make([]PooledEvidence, 0, len(pop.evidencePool))
+    for _, evidence := range pop.evidencePool {
+        pooled = append(pooled, *evidence)
+    }
+
+    sort.Slice(pooled, func(i, j int) bool {
+        if pooled[i].Height != pooled[j].Height {
+            return pooled[i].Height < pooled[j].Height
+        }
+        return pooled[i].ID < pooled[j].ID
+    })
+
+    return pooled
+}
+
+// pruneEvidencePool drops the pooled evidence that was committed, has expired or is against a validator already jailed,
+// none of which the block after a height could commit
+// The caller must hold the lock
+func (pop *ProofOfPlay) pruneEvidencePool(height int64) {
+    for id, pooled := range pop.evidencePool {
+        evidence := pooled.Evidence
+        if pop.checkEvidence(&evidence, height+1) != nil {
+            delete(pop.evidencePool, id)
+        }
+    }
+}
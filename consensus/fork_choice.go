@@ -0,0 +1,118 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// ForkScore is how strongly the votes seen by this node support a branch
+type ForkScore struct {
+    Tip             BlockRef `json:"tip"`
+    Conflicts       bool     `json:"conflicts"`       // Holds a block other than the one decided at its height
+    FinalizedHeight int64    `json:"finalizedHeight"` // Highest block on the branch decided by precommits, or -1
+    JustifiedHeight int64    `json:"justifiedHeight"` // Highest block on the branch 2/3 of the weight prevoted in a round, or -1
+    Weight          float64  `json:"weight"`          // Sum over the branch's blocks of the most weight that prevoted each in a round
+    Length          int      `json:"length"`
+}
+
+// ChooseBranch returns the index of the branch with the greatest finalized and justified weight
+// Branches are compared by their highest finalized block, then their highest justified block,
+// then the prevote weight behind their blocks, then their length, and finally by the lowest tip hash,
+// so nodes that have seen the same votes choose the same branch whatever order the branches come in
+// A branch holding a block other than one decided at its height is never chosen
+func (pop *ProofOfPlay) ChooseBranch(branches [][]core.BlockHeader) (int, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    best := -1
+    var bestScore ForkScore
+    for i, branch := range branches {
+        score, err := pop.scoreBranch(branch)
+        if err != nil {
+            return -1, fmt.Errorf("branch %d: %w", i, err)
+        }
+        if score.Conflicts {
+            continue
+        }
+        if best < 0 || score.beats(bestScore) {
+            best, bestScore = i, score
+        }
+    }
+
+    if best < 0 {
+        return -1, errors.New("every branch conflicts with a finalized block")
+    }
+
+    return best, nil
+}
+
+// ScoreBranch returns the support for a branch of headers, lowest first, from the votes seen by this node
+func (pop *ProofOfPlay) ScoreBranch(branch []core.BlockHeader) (ForkScore, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.scoreBranch(branch)
+}
+
+// scoreBranch weighs a branch by the decisions and prevotes at its heights
+// An empty branch scores nothing, standing for a chain with no blocks above the fork point
+// The caller must hold the lock
+func (pop *ProofOfPlay) scoreBranch(branch []core.BlockHeader) (ForkScore, error) {
+    score := ForkScore{FinalizedHeight: -1, JustifiedHeight: -1, Length: len(branch)}
+
+    for i, header := range branch {
+        if i > 0 && (header.Index != branch[i-1].Index+1 || header.PrevHash != branch[i-1].Hash) {
+            return ForkScore{}, fmt.Errorf("block %d does not link to the block before it", header.Index)
+        }
+        score.Tip = BlockRef{Height: header.Index, Hash: header.Hash}
+
+        rounds, exists := pop.rounds[header.Index]
+        if !exists {
+            continue
+        }
+
+        if rounds.decision != nil {
+            if rounds.decision.BlockHash != header.Hash {
+                score.Conflicts = true
+                continue
+            }
+            score.FinalizedHeight = header.Index
+        }
+
+        support := 0.0
+        for key := range rounds.votes {
+            if key.step != StepPrevote {
+                continue
+            }
+            tally := pop.stepTally(rounds, key.round, key.step)
+            weight := tally.Weights[header.Hash]
+            if weight > support {
+                support = weight
+            }
+            if quorum(weight, tally.TotalWeight) {
+                score.JustifiedHeight = header.Index
+            }
+        }
+        score.Weight += support
+    }
+
+    return score, nil
+}
+
+// beats reports whether a branch's score ranks above another's
+func (s ForkScore) beats(other ForkScore) bool {
+    switch {
+    case s.FinalizedHeight != other.FinalizedHeight:
+        return s.FinalizedHeight > other.FinalizedHeight
+    case s.JustifiedHeight != other.JustifiedHeight:
+        return s.JustifiedHeight > other.JustifiedHeight
+    case s.Weight != other.Weight:
+        return s.Weight > other.Weight
+    case s.Length != other.Length:
+        return s.Length > other.Length
+    }
+
+    return s.Tip.Hash < other.Tip.Hash
+}
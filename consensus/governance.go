@@ -0,0 +1,922 @@
+package consensus
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Governance transaction types
+const (
+    TxTypeProposeParams = "gov_propose" // Amount: deposit; Data: title, description, changes (parameter name to new value), spend (recipient and amount paid from the treasury), season (a token.Season added to the reward calendar), gameServers (addresses to add and remove), settings (module settings name to the module's new settings), activation (height the proposal takes effect from)
+    TxTypeGovVote       = "gov_vote"    // Data: proposal, the proposal ID; option, yes, no or abstain
+)
+
+// DepositEscrowAddress is the account holding proposal deposits until voting ends
+const DepositEscrowAddress = "governance_deposits"
+
+// Options a governance vote can take
+const (
+    OptionYes     = "yes"
+    OptionNo      = "no"
+    OptionAbstain = "abstain"
+)
+
+// Statuses of a proposal
+const (
+    ProposalVoting   = "voting"   // Open for votes until its voting end height
+    ProposalPassed   = "passed"   // Reached quorum with enough yes weight; its changes are applied and its spend paid at its activation height
+    ProposalRejected = "rejected" // Reached quorum without enough yes weight
+    ProposalExpired  = "expired"  // Didn't reach quorum; its deposit was burned
+)
+
+// Default governance settings
+const (
+    DefaultMinDeposit    = 100 * token.ILYZ // Least deposit a proposal can be submitted with
+    DefaultVotingPeriod  = 1000             // Blocks a proposal is open for votes
+    DefaultQuorum        = 0.334            // Share of the total weight that must vote for a tally to count
+    DefaultPassThreshold = 0.5              // Share of the yes and no weight that must vote yes for a proposal to pass
+)
+
+// Proposal is a parameter change or treasury spend put to a governance vote
+type Proposal struct {
+    ID              string             `json:"id"` // ID of the transaction that submitted it
+    Proposer        string             `json:"proposer"`
+    Title           string             `json:"title"`
+    Description     string             `json:"description,omitempty"`
+    Changes         map[string]float64 `json:"changes"`         // New value of each parameter changed
+    Spend           *TreasurySpend     `json:"spend,omitempty"` // Payment from the treasury, if the proposal makes one
+    Season          *token.Season      `json:"season,omitempty"` // Season added to the reward calendar, replacing any with its ID
+    Deposit         token.Amount       `json:"deposit"` // Held in the deposit escrow while voting is open
+    SubmitHeight    int64              `json:"submitHeight"`
+    VotingEndHeight int64              `json:"votingEndHeight"` // Last block votes are counted from
+    Status          string             `json:"status"`
+    Votes           map[string]string  `json:"votes"`           // Option by voter address
+    Tally           *ProposalTally     `json:"tally,omitempty"` // Set once voting ends
+
+    // Game servers allowed or stopped reporting matches and attesting, and the new settings of each module named,
+    // replacing those genesis or an earlier proposal set
+    GameServers *GameServerChange          `json:"gameServers,omitempty"`
+    Settings    map[string]json.RawMessage `json:"settings,omitempty"`
+
+    // First block the proposal takes effect for, the one after voting ends unless the proposal asks for a later one
+    ActivationHeight int64 `json:"activationHeight,omitempty"`
+    Activated        bool  `json:"activated,omitempty"`
+
+    // Set once voting ends if the deposit went back to the proposer, unless the escrow couldn't cover it
+    DepositRefunded bool `json:"depositRefunded,omitempty"`
+}
+
+// TreasurySpend is a payment from the community treasury a proposal asks for
+type TreasurySpend struct {
+    Recipient string       `json:"recipient"`
+    Amount    token.Amount `json:"amount"`
+    Paid      bool         `json:"paid"` // Set once the proposal passes, unless the treasury couldn't cover it
+}
+
+// GameServerChange is the game servers a proposal allows and stops reporting matches and attesting to activity
+type GameServerChange struct {
+    Add    []string `json:"add,omitempty"`
+    Remove []string `json:"remove,omitempty"`
+}
+
+// ProposalTally is the weight behind each option of a proposal when its voting ended
+type ProposalTally struct {
+    Yes         float64 `json:"yes"`
+    No          float64 `json:"no"`
+    Abstain     float64 `json:"abstain"`
+    TotalWeight float64 `json:"totalWeight"` // Weight of every voter who could have voted
+}
+
+// governedParam is a consensus setting governance can change
+type governedParam struct {
+    get   func(pop *ProofOfPlay) float64
+    set   func(pop *ProofOfPlay, value float64)
+    valid func(value float64) bool
+}
+
+// governedParams are the settings governance can change, by the name proposals use
+var governedParams = map[string]governedParam{
+    "max_validators": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.MaxValidators) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.MaxValidators = int(value) },
+        valid: atLeastOne,
+    },
+    "min_lock_period": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.MinLockPeriod) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.MinLockPeriod = int64(value) },
+        valid: atLeastZero,
+    },
+    "max_lock_period": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.MaxLockPeriod) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.MaxLockPeriod = int64(value) },
+        valid: atLeastOne,
+    },
+    "unlock_delay": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.UnlockDelay) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.UnlockDelay = int64(value) },
+        valid: atLeastZero,
+    },
+    "min_validators": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.MinValidators) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.MinValidators = int(value) },
+        valid: atLeastOne,
+    },
+    "finality_threshold": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.FinalityThreshold) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.FinalityThreshold = int(value) },
+        valid: func(value float64) bool { return wholeNumber(value) && value >= 1 && value <= 100 },
+    },
+    "slash_fraction": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.SlashFraction },
+        set:   func(pop *ProofOfPlay, value float64) { pop.SlashFraction = value },
+        valid: fraction,
+    },
+    "unbonding_period": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.UnbondingPeriod) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.UnbondingPeriod = int64(value) },
+        valid: atLeastZero,
+    },
+    "round_timeout": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.RoundTimeout) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.RoundTimeout = int64(value) },
+        valid: atLeastOne,
+    },
+    "downtime_threshold": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.DowntimeThreshold) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.DowntimeThreshold = int(value) },
+        valid: atLeastZero,
+    },
+    "downtime_jail": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.DowntimeJail) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.DowntimeJail = int64(value) },
+        valid: atLeastZero,
+    },
+    "min_validator_stake": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.MinValidatorStake.Float() },
+        set:   func(pop *ProofOfPlay, value float64) { pop.MinValidatorStake = token.AmountFromFloat(value) },
+        valid: nonNegative,
+    },
+    "epoch_length": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.EpochLength) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.EpochLength = int64(value) },
+        valid: atLeastOne,
+    },
+    "max_evidence_age": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.MaxEvidenceAge) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.MaxEvidenceAge = int64(value) },
+        valid: atLeastOne,
+    },
+    "max_commission_change": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.MaxCommissionChange },
+        set:   func(pop *ProofOfPlay, value float64) { pop.MaxCommissionChange = value },
+        valid: fraction,
+    },
+    "gov_min_deposit": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.MinDeposit.Float() },
+        set:   func(pop *ProofOfPlay, value float64) { pop.MinDeposit = token.AmountFromFloat(value) },
+        valid: nonNegative,
+    },
+    "gov_voting_period": {
+        get:   func(pop *ProofOfPlay) float64 { return float64(pop.VotingPeriod) },
+        set:   func(pop *ProofOfPlay, value float64) { pop.VotingPeriod = int64(value) },
+        valid: atLeastOne,
+    },
+    "gov_quorum": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Quorum },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Quorum = value },
+        valid: fraction,
+    },
+    "gov_pass_threshold": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.PassThreshold },
+        set:   func(pop *ProofOfPlay, value float64) { pop.PassThreshold = value },
+        valid: fraction,
+    },
+    "fee_master_share": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.FeeSharing.MasterShare },
+        set:   func(pop *ProofOfPlay, value float64) { pop.FeeSharing.MasterShare = value },
+        valid: fraction,
+    },
+    "fee_staking_share": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.FeeSharing.StakingShare },
+        set:   func(pop *ProofOfPlay, value float64) { pop.FeeSharing.StakingShare = value },
+        valid: fraction,
+    },
+    "fee_treasury_share": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.FeeSharing.TreasuryShare },
+        set:   func(pop *ProofOfPlay, value float64) { pop.FeeSharing.TreasuryShare = value },
+        valid: fraction,
+    },
+    "fee_burn_share": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.FeeSharing.BurnShare },
+        set:   func(pop *ProofOfPlay, value float64) { pop.FeeSharing.BurnShare = value },
+        valid: fraction,
+    },
+    "treasury_reward_share": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.FeeSharing.TreasuryRewardShare },
+        set:   func(pop *ProofOfPlay, value float64) { pop.FeeSharing.TreasuryRewardShare = value },
+        valid: fraction,
+    },
+    "fee_rate": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.TransactionFeeRate },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.TransactionFeeRate = value },
+        valid: fraction,
+    },
+    // Setting the yield rate pins the yield curve to it, so the rate holds from epoch to epoch
+    "yield_rate": {
+        get: func(pop *ProofOfPlay) float64 { return pop.Economy.YieldRate },
+        set: func(pop *ProofOfPlay, value float64) {
+            pop.Economy.YieldRate = value
+            pop.Economy.YieldCurve.Floor = value
+            pop.Economy.YieldCurve.Ceiling = value
+        },
+        valid: nonNegative,
+    },
+    "yield_floor": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.YieldCurve.Floor },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.YieldCurve.Floor = value },
+        valid: nonNegative,
+    },
+    "yield_ceiling": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.YieldCurve.Ceiling },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.YieldCurve.Ceiling = value },
+        valid: nonNegative,
+    },
+    "yield_target_ratio": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.YieldCurve.TargetRatio },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.YieldCurve.TargetRatio = value },
+        valid: func(value float64) bool { return value > 0 && value <= 1 },
+    },
+    "base_game_reward": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.BaseGameReward.Float() },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.BaseGameReward = token.AmountFromFloat(value) },
+        valid: nonNegative,
+    },
+    "short_match_factor": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.ShortMatchFactor },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.ShortMatchFactor = value },
+        valid: nonNegative,
+    },
+    "medium_match_factor": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.MediumMatchFactor },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.MediumMatchFactor = value },
+        valid: nonNegative,
+    },
+    "long_match_factor": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.LongMatchFactor },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.LongMatchFactor = value },
+        valid: nonNegative,
+    },
+    "marathon_match_factor": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.MarathonMatchFactor },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.MarathonMatchFactor = value },
+        valid: nonNegative,
+    },
+    "rank_factor_base": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.RankFactorBase },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.RankFactorBase = value },
+        valid: nonNegative,
+    },
+    "rank_factor_step": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.RankFactorStep },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.RankFactorStep = value },
+        valid: nonNegative,
+    },
+    "rank_factor_max": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.RankFactorMax },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.RankFactorMax = value },
+        valid: nonNegative,
+    },
+    "min_performance_factor": {
+        get:   func(pop *ProofOfPlay) float64 { return pop.Economy.MinPerformanceFactor },
+        set:   func(pop *ProofOfPlay, value float64) { pop.Economy.MinPerformanceFactor = value },
+        valid: fraction,
+    },
+}
+
+// GetProposals returns every proposal, oldest first
+func (pop *ProofOfPlay) GetProposals() []Proposal {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    proposals := []Proposal{}
+    for _, proposal := range pop.sortedProposals() {
+        proposals = append(proposals, proposal.clone())
+    }
+
+    return proposals
+}
+
+// GetProposal returns a proposal by ID
+func (pop *ProofOfPlay) GetProposal(id string) (Proposal, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    proposal, exists := pop.proposals[id]
+    if !exists {
+        return Proposal{}, errors.New("proposal not found")
+    }
+
+    return proposal.clone(), nil
+}
+
+// GetTreasurySpends returns the proposals that spend from the treasury, oldest first
+func (pop *ProofOfPlay) GetTreasurySpends() []Proposal {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    proposals := []Proposal{}
+    for _, proposal := range pop.sortedProposals() {
+        if proposal.Spend != nil {
+            proposals = append(proposals, proposal.clone())
+        }
+    }
+
+    return proposals
+}
+
+// FeePolicy returns the policy the next block's fees are split by
+func (pop *ProofOfPlay) FeePolicy() core.FeePolicy {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.FeeSharing
+}
+
+// EconomicParams returns the fee rate, yield rate and game reward settings in effect
+func (pop *ProofOfPlay) EconomicParams() token.EconomicParams {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.Economy
+}
+
+// GetGovernedParams returns the current value of every setting governance can change, by name
+func (pop *ProofOfPlay) GetGovernedParams() map[string]float64 {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.governedValues()
+}
+
+// GovernanceWeight returns an address's weight in governance votes: its own stake raised by its play score
+// if it is a validator, plus the stake it delegates
+func (pop *ProofOfPlay) GovernanceWeight(address string) float64 {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.governanceWeight(address)
+}
+
+// checkGovernance validates a proposal or governance vote transaction
+// Balances are only known when the transaction is applied, so a deposit the sender can't afford passes here
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkGovernance(tx core.Transaction) error {
+    data, _ := tx.Data.(map[string]interface{})
+
+    switch tx.Type {
+    case TxTypeProposeParams:
+        if tx.Amount < pop.MinDeposit {
+            return fmt.Errorf("deposit must be at least %s", pop.MinDeposit)
+        }
+        if title, _ := data["title"].(string); title == "" {
+            return errors.New("proposal title is required")
+        }
+        if _, exists := pop.proposals[tx.ID]; exists {
+            return errors.New("proposal has already been submitted")
+        }
+
+        changes, err := proposalChanges(tx)
+        if err != nil {
+            return err
+        }
+        spend, err := proposalSpend(tx)
+        if err != nil {
+            return err
+        }
+        season, err := proposalSeason(tx)
+        if err != nil {
+            return err
+        }
+        servers, err := proposalGameServers(tx)
+        if err != nil {
+            return err
+        }
+        settings, err := proposalSettings(tx)
+        if err != nil {
+            return err
+        }
+        if len(changes) == 0 && spend == nil && season == nil && servers == nil && len(settings) == 0 {
+            return errors.New("proposal must change at least one parameter, game server or module's settings, add a season or spend from the treasury")
+        }
+        for name, value := range changes {
+            param, governed := governedParams[name]
+            if !governed {
+                return fmt.Errorf("unknown parameter %q", name)
+            }
+            if !param.valid(value) {
+                return fmt.Errorf("invalid value %v for parameter %q", value, name)
+            }
+        }
+        if err := pop.feeSharingWith(changes).Validate(); err != nil {
+            return err
+        }
+        if err := pop.yieldCurveWith(changes).Validate(); err != nil {
+            return err
+        }
+        minLock, maxLock := pop.MinLockPeriod, pop.MaxLockPeriod
+        if value, changed := changes["min_lock_period"]; changed {
+            minLock = int64(value)
+        }
+        if value, changed := changes["max_lock_period"]; changed {
+            maxLock = int64(value)
+        }
+        if minLock > maxLock {
+            return errors.New("shortest lock period can't be longer than the longest")
+        }
+        if err := pop.checkProposalSeason(season); err != nil {
+            return err
+        }
+        for name, value := range settings {
+            module := pop.configurable(name)
+            if module == nil {
+                return fmt.Errorf("no module keeps settings under %q", name)
+            }
+            if err := module.ValidateSettings(value); err != nil {
+                return err
+            }
+        }
+        if value, exists := settings[SettingsName]; exists {
+            decoded, _ := decodeSettings(value)
+            if len(decoded.Params) > 0 || decoded.Seasons != nil {
+                return errors.New("proposals change parameters and seasons through their changes and season, not the engine's settings")
+            }
+        }
+
+        // Voting ends at the earliest a period after the next block
+        if activation, exists := data["activation"]; exists {
+            height, ok := activation.(float64)
+            if votingEnd := pop.blockHeight + 1 + pop.VotingPeriod; !ok || !wholeNumber(height) || int64(height) <= votingEnd {
+                return fmt.Errorf("activation height must be after voting ends at %d", votingEnd)
+            }
+        }
+
+    case TxTypeGovVote:
+        id, _ := data["proposal"].(string)
+        proposal, exists := pop.proposals[id]
+        if !exists {
+            return errors.New("proposal not found")
+        }
+        if proposal.Status != ProposalVoting {
+            return errors.New("proposal is no longer open for votes")
+        }
+
+        option, _ := data["option"].(string)
+        if option != OptionYes && option != OptionNo && option != OptionAbstain {
+            return errors.New("option must be yes, no or abstain")
+        }
+        if pop.governanceWeight(tx.Sender) <= 0 {
+            return errors.New("voter has no stake")
+        }
+    }
+
+    return nil
+}
+
+// applyGovernance executes a checked proposal or governance vote transaction
+// A voter who votes again replaces its earlier vote; weights are only counted when voting ends
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyGovernance(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    data, _ := tx.Data.(map[string]interface{})
+
+    switch tx.Type {
+    case TxTypeProposeParams:
+        // A proposer who can't cover the deposit proposes nothing
+        if state.Transfer(tx.Sender, DepositEscrowAddress, tx.Amount) != nil {
+            return
+        }
+
+        title, _ := data["title"].(string)
+        description, _ := data["description"].(string)
+        changes, _ := proposalChanges(tx)
+        spend, _ := proposalSpend(tx)
+        season, _ := proposalSeason(tx)
+        servers, _ := proposalGameServers(tx)
+        settings, _ := proposalSettings(tx)
+
+        // A proposal included later than expected may ask to activate before its voting ends, and activates once it passes
+        activation, _ := data["activation"].(float64)
+        if int64(activation) <= header.Index+pop.VotingPeriod {
+            activation = 0
+        }
+
+        pop.proposals[tx.ID] = &Proposal{
+            ID:               tx.ID,
+            Proposer:         tx.Sender,
+            Title:            title,
+            Description:      description,
+            Changes:          changes,
+            Spend:            spend,
+            Season:           season,
+            GameServers:      servers,
+            Settings:         settings,
+            Deposit:          tx.Amount,
+            SubmitHeight:     header.Index,
+            VotingEndHeight:  header.Index + pop.VotingPeriod,
+            Status:           ProposalVoting,
+            Votes:            make(map[string]string),
+            ActivationHeight: int64(activation),
+        }
+
+    case TxTypeGovVote:
+        id, _ := data["proposal"].(string)
+        option, _ := data["option"].(string)
+        pop.proposals[id].Votes[tx.Sender] = option
+    }
+}
+
+// endVoting tallies the proposals whose voting ends with a block, scheduling those that pass to activate
+// with the next block unless they asked for a later one
+// Proposals that reach quorum get their deposit back out of the escrow; those that don't have it burned
+// The caller must hold the lock
+func (pop *ProofOfPlay) endVoting(height int64, state *core.State) {
+    for _, proposal := range pop.sortedProposals() {
+        if proposal.Status != ProposalVoting || proposal.VotingEndHeight > height {
+            continue
+        }
+
+        tally := pop.tallyProposal(proposal)
+        proposal.Tally = &tally
+
+        voted := tally.Yes + tally.No + tally.Abstain
+        switch {
+        case tally.TotalWeight <= 0 || voted < tally.TotalWeight*pop.Quorum:
+            proposal.Status = ProposalExpired
+            state.Burn(DepositEscrowAddress, proposal.Deposit)
+            continue
+        case tally.Yes+tally.No > 0 && tally.Yes > (tally.Yes+tally.No)*pop.PassThreshold:
+            proposal.Status = ProposalPassed
+            if proposal.ActivationHeight == 0 {
+                proposal.ActivationHeight = height + 1
+            }
+        default:
+            proposal.Status = ProposalRejected
+        }
+
+        proposal.DepositRefunded = state.Transfer(DepositEscrowAddress, proposal.Proposer, proposal.Deposit) == nil
+    }
+}
+
+// activateProposals applies the changes, adds the seasons, changes the game servers and module settings and pays the
+// treasury spends of the passed proposals that activate with the block after a height, in submission order;
+// a spend the treasury can't cover then is never paid
+// Game servers and module settings are changed in the state, which each module's settings are applied from once the block ends
+// Each proposal that changes anything but the treasury adds a version taking effect from its activation height
+// The caller must hold the lock
+func (pop *ProofOfPlay) activateProposals(height int64, state *core.State) {
+    for _, proposal := range pop.sortedProposals() {
+        if proposal.Status != ProposalPassed || proposal.Activated || proposal.ActivationHeight > height+1 {
+            continue
+        }
+        proposal.Activated = true
+
+        if len(proposal.Changes) > 0 || proposal.Season != nil || proposal.GameServers != nil || len(proposal.Settings) > 0 {
+            pop.applyChanges(proposal.Changes)
+            if proposal.Season != nil {
+                pop.applySeason(*proposal.Season)
+            }
+            if proposal.GameServers != nil {
+                for _, server := range proposal.GameServers.Add {
+                    state.SetGameServer(server, true)
+                }
+                for _, server := range proposal.GameServers.Remove {
+                    state.SetGameServer(server, false)
+                }
+            }
+            for _, name := range sortedSettings(proposal.Settings) {
+                state.SetModuleSettings(name, proposal.Settings[name])
+            }
+            pop.recordParamVersion(height, proposal.ID)
+        }
+        if proposal.Spend != nil {
+            proposal.Spend.Paid = state.SpendTreasury(proposal.Spend.Recipient, proposal.Spend.Amount) == nil
+        }
+    }
+}
+
+// tallyProposal weighs the votes on a proposal by the voters' current stake and play scores
+// The caller must hold the lock
+func (pop *ProofOfPlay) tallyProposal(proposal *Proposal) ProposalTally {
+    tally := ProposalTally{}
+
+    voters := make([]string, 0, len(proposal.Votes))
+    for voter := range proposal.Votes {
+        voters = append(voters, voter)
+    }
+    sort.Strings(voters)
+
+    for _, voter := range voters {
+        weight := pop.governanceWeight(voter)
+        switch proposal.Votes[voter] {
+        case OptionYes:
+            tally.Yes += weight
+        case OptionNo:
+            tally.No += weight
+        case OptionAbstain:
+            tally.Abstain += weight
+        }
+    }
+
+    // Every validator's own weight and every delegation could have voted
+    for _, validator := range pop.validators {
+        if validator.Active() {
+            tally.TotalWeight += validator.Stake.Float() * (1 + validator.PlayScore)
+        }
+    }
+    for _, delegation := range pop.sortedDelegations() {
+        tally.TotalWeight += delegation.Amount.Float()
+    }
+
+    return tally
+}
+
+// applyChanges sets the parameters an accepted proposal changes, in name order
+// The values configured at startup are kept first, so a rebuild from genesis starts from them again
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyChanges(changes map[string]float64) {
+    pop.keepConfigured()
+
+    names := make([]string, 0, len(changes))
+    for name := range changes {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    for _, name := range names {
+        governedParams[name].set(pop, changes[name])
+    }
+}
+
+// keepConfigured keeps the settings and season calendar configured at startup before governance first changes them
+// The caller must hold the lock
+func (pop *ProofOfPlay) keepConfigured() {
+    if pop.configured == nil {
+        pop.configured = pop.governedValues()
+        pop.configuredSeasons = pop.Economy.Seasons
+    }
+}
+
+// restoreConfigured sets the parameters governance changed back to the values configured at startup,
+// dropping the versions proposals added
+// The caller must hold the lock
+func (pop *ProofOfPlay) restoreConfigured() {
+    for name, value := range pop.configured {
+        governedParams[name].set(pop, value)
+    }
+    if pop.configured != nil {
+        pop.Economy.Seasons = pop.configuredSeasons
+    }
+    pop.configured = nil
+    pop.configuredSeasons = nil
+    pop.paramVersions = nil
+}
+
+// feeSharingWith returns the fee policy as it would be with a proposal's changes applied
+// The caller must hold the lock
+func (pop *ProofOfPlay) feeSharingWith(changes map[string]float64) core.FeePolicy {
+    sharing := pop.FeeSharing
+    for name, value := range changes {
+        switch name {
+        case "fee_master_share":
+            sharing.MasterShare = value
+        case "fee_staking_share":
+            sharing.StakingShare = value
+        case "fee_treasury_share":
+            sharing.TreasuryShare = value
+        case "fee_burn_share":
+            sharing.BurnShare = value
+        case "treasury_reward_share":
+            sharing.TreasuryRewardShare = value
+        }
+    }
+
+    return sharing
+}
+
+// governanceWeight returns an address's weight in governance votes
+// The caller must hold the lock
+func (pop *ProofOfPlay) governanceWeight(address string) float64 {
+    weight := 0.0
+    if validator := pop.validator(address); validator != nil && validator.Active() {
+        weight += validator.Stake.Float() * (1 + validator.PlayScore)
+    }
+    for _, delegation := range pop.sortedDelegations() {
+        if delegation.Delegator == address {
+            weight += delegation.Amount.Float()
+        }
+    }
+
+    return weight
+}
+
+// sortedProposals returns the proposals ordered by submission height, then ID
+// The caller must hold the lock
+func (pop *ProofOfPlay) sortedProposals() []*Proposal {
+    proposals := make([]*Proposal, 0, len(pop.proposals))
+    for _, proposal := range pop.proposals {
+        proposals = append(proposals, proposal)
+    }
+
+    sort.Slice(proposals, func(i, j int) bool {
+        if proposals[i].SubmitHeight != proposals[j].SubmitHeight {
+            return proposals[i].SubmitHeight < proposals[j].SubmitHeight
+        }
+        return proposals[i].ID < proposals[j].ID
+    })
+
+    return proposals
+}
+
+// sortedDelegations returns every delegation ordered by delegator, then validator, so sums over them agree on every node
+// The caller must hold the lock
+func (pop *ProofOfPlay) sortedDelegations() []*Delegation {
+    delegations := make([]*Delegation, 0, len(pop.delegations))
+    for _, delegation := range pop.delegations {
+        delegations = append(delegations, delegation)
+    }
+
+    sort.Slice(delegations, func(i, j int) bool {
+        if delegations[i].Delegator != delegations[j].Delegator {
+            return delegations[i].Delegator < delegations[j].Delegator
+        }
+        return delegations[i].Validator < delegations[j].Validator
+    })
+
+    return delegations
+}
+
+// clone returns a copy of a proposal that shares no memory with it
+func (p *Proposal) clone() Proposal {
+    copied := *p
+    copied.Changes = make(map[string]float64, len(p.Changes))
+    for name, value := range p.Changes {
+        copied.Changes[name] = value
+    }
+    copied.Votes = make(map[string]string, len(p.Votes))
+    for voter, option := range p.Votes {
+        copied.Votes[voter] = option
+    }
+    if p.Spend != nil {
+        spend := *p.Spend
+        copied.Spend = &spend
+    }
+    if p.Season != nil {
+        season := *p.Season
+        season.Modes = append([]string(nil), p.Season.Modes...)
+        copied.Season = &season
+    }
+    if p.GameServers != nil {
+        copied.GameServers = &GameServerChange{
+            Add:    append([]string(nil), p.GameServers.Add...),
+            Remove: append([]string(nil), p.GameServers.Remove...),
+        }
+    }
+    if p.Settings != nil {
+        copied.Settings = make(map[string]json.RawMessage, len(p.Settings))
+        for name, settings := range p.Settings {
+            copied.Settings[name] = append(json.RawMessage(nil), settings...)
+        }
+    }
+    if p.Tally != nil {
+        tally := *p.Tally
+        copied.Tally = &tally
+    }
+
+    return copied
+}
+
+// proposalChanges reads the parameter changes a proposal transaction asks for
+func proposalChanges(tx core.Transaction) (map[string]float64, error) {
+    data, _ := tx.Data.(map[string]interface{})
+    raw, _ := data["changes"].(map[string]interface{})
+
+    changes := make(map[string]float64, len(raw))
+    for name, value := range raw {
+        number, ok := value.(float64)
+        if !ok {
+            return nil, fmt.Errorf("value of parameter %q must be a number", name)
+        }
+        changes[name] = number
+    }
+
+    return changes, nil
+}
+
+// proposalSpend reads the treasury spend a proposal transaction asks for, nil if it asks for none
+func proposalSpend(tx core.Transaction) (*TreasurySpend, error) {
+    data, _ := tx.Data.(map[string]interface{})
+    raw, exists := data["spend"].(map[string]interface{})
+    if !exists {
+        return nil, nil
+    }
+
+    recipient, _ := raw["recipient"].(string)
+    if recipient == "" {
+        return nil, errors.New("treasury spend needs a recipient")
+    }
+    amount, ok := token.DataAmount(raw["amount"])
+    if !ok || amount <= 0 {
+        return nil, errors.New("treasury spend amount must be positive")
+    }
+
+    return &TreasurySpend{Recipient: recipient, Amount: amount}, nil
+}
+
+// proposalGameServers reads the game servers a proposal transaction allows and stops, nil if it changes none
+func proposalGameServers(tx core.Transaction) (*GameServerChange, error) {
+    data, _ := tx.Data.(map[string]interface{})
+    raw, exists := data["gameServers"].(map[string]interface{})
+    if !exists {
+        return nil, nil
+    }
+
+    change := &GameServerChange{}
+    listed := make(map[string]bool)
+    for _, list := range []struct {
+        key       string
+        addresses *[]string
+    }{{"add", &change.Add}, {"remove", &change.Remove}} {
+        values, _ := raw[list.key].([]interface{})
+        for _, value := range values {
+            address, _ := value.(string)
+            if address == "" || listed[address] {
+                return nil, errors.New("game servers must be distinct addresses, each added or removed once")
+            }
+            listed[address] = true
+            *list.addresses = append(*list.addresses, address)
+        }
+    }
+    if len(listed) == 0 {
+        return nil, errors.New("game server change must add or remove at least one address")
+    }
+
+    return change, nil
+}
+
+// proposalSettings reads the module settings a proposal transaction replaces, by settings name
+func proposalSettings(tx core.Transaction) (map[string]json.RawMessage, error) {
+    data, _ := tx.Data.(map[string]interface{})
+    raw, _ := data["settings"].(map[string]interface{})
+
+    settings := make(map[string]json.RawMessage, len(raw))
+    for name, value := range raw {
+        encoded, err := json.Marshal(value)
+        if err != nil {
+            return nil, fmt.Errorf("invalid settings for %q: %w", name, err)
+        }
+        settings[name] = encoded
+    }
+
+    return settings, nil
+}
+
+// sortedSettings returns the names of the modules whose settings a proposal replaces, in order
+func sortedSettings(settings map[string]json.RawMessage) []string {
+    names := make([]string, 0, len(settings))
+    for name := range settings {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    return names
+}
+
+// fraction reports whether a value is a share between 0 and 1
+func fraction(value float64) bool {
+    return value >= 0 && value <= 1
+}
+
+// nonNegative reports whether a value is a finite amount of at least 0
+func nonNegative(value float64) bool {
+    return value >= 0 && !math.IsInf(value, 0)
+}
+
+// atLeastZero reports whether a value is a whole number of at least 0
+func atLeastZero(value float64) bool {
+    return wholeNumber(value) && value >= 0
+}
+
+// atLeastOne reports whether a value is a whole number of at least 1
+func atLeastOne(value float64) bool {
+    return wholeNumber(value) && value >= 1
+}
+
+// wholeNumber reports whether a value is a whole number that fits an int64
+func wholeNumber(value float64) bool {
+    return value == math.Trunc(value) && math.Abs(value) < 1<<53
+}
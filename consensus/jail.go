@@ -0,0 +1,84 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeUnjail returns a validator jailed for downtime to the active set once its jail time is served
+// Sender is the jailed validator
+const TxTypeUnjail = "validator_unjail"
+
+// DefaultDowntimeThreshold is how many finalized blocks in a row a validator can miss before it is jailed
+const DefaultDowntimeThreshold = 20
+
+// DefaultDowntimeJail is how long a first downtime jailing lasts, in seconds (10 minutes)
+const DefaultDowntimeJail = 10 * 60
+
+// maxJailDoublings caps how far repeated offenses extend the jail time (64 times the first)
+const maxJailDoublings = 6
+
+// Active reports whether a validator is in the active set: neither removed for misbehaving nor jailed for downtime,
+// and ranked among the validators chosen at the start of the epoch
+// Inactive validators have no weight: they aren't scheduled to propose, their votes don't count and they earn no rewards
+func (v Validator) Active() bool {
+    return !v.Jailed && !v.JailedForDowntime && !v.Candidate
+}
+
+// recordParticipation updates missed-block counts from the vote reward in a block
+// The block's producer and the voters the reward pays took part; every other active validator missed the block
+// Votes are only seen on chain through vote rewards, so a block without one changes nothing
+// The caller must hold the lock
+func (pop *ProofOfPlay) recordParticipation(shares map[string]token.Amount, header core.BlockHeader) {
+    for i := range pop.validators {
+        validator := &pop.validators[i]
+        if !validator.Active() {
+            continue
+        }
+
+        if _, voted := shares[validator.Address]; voted || validator.Address == header.Validator {
+            validator.MissedBlocks = 0
+            continue
+        }
+
+        validator.MissedBlocks++
+        if pop.DowntimeThreshold > 0 && validator.MissedBlocks >= pop.DowntimeThreshold {
+            pop.jailForDowntime(validator, header.Timestamp)
+        }
+    }
+}
+
+// jailForDowntime removes an offline validator from the active set until it unjails
+// Each repeated offense doubles the time before it may
+// The caller must hold the lock
+func (pop *ProofOfPlay) jailForDowntime(validator *Validator, timestamp int64) {
+    doublings := validator.DowntimeOffenses
+    if doublings > maxJailDoublings {
+        doublings = maxJailDoublings
+    }
+
+    validator.JailedForDowntime = true
+    validator.DowntimeOffenses++
+    validator.UnjailTime = timestamp + pop.DowntimeJail<<doublings
+    validator.MissedBlocks = 0
+}
+
+// checkUnjail validates an unjail transaction at a given block time
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkUnjail(tx core.Transaction, now int64) error {
+    validator := pop.validator(tx.Sender)
+    if validator == nil {
+        return errors.New("validator not registered")
+    }
+    if !validator.JailedForDowntime {
+        return errors.New("validator is not jailed for downtime")
+    }
+    if now < validator.UnjailTime {
+        return fmt.Errorf("validator can't unjail until %d", validator.UnjailTime)
+    }
+
+    return nil
+}
@@ -0,0 +1,192 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+)
+
+// TxTypeRotateKey replaces the consensus key a validator signs blocks and votes with, from the next epoch
+// Sender is the validator; its stake stays bonded throughout
+// Data: oldKey and newKey, hex public keys; oldProof and newProof, each key's signature of KeyRotationPayload
+const TxTypeRotateKey = "validator_rotate_key"
+
+// KeyChange is a consensus key a validator rotated to
+type KeyChange struct {
+    Key    string `json:"key"`    // Hex public key
+    Height int64  `json:"height"` // First block the key signs for, the start of an epoch
+}
+
+// KeyRotationPayload returns the bytes both the old and the new consensus key sign to rotate a validator's key
+func KeyRotationPayload(address string, oldKey string, newKey string) ([]byte, error) {
+    return core.CanonicalEncode(struct {
+        Purpose string `json:"purpose"`
+        Address string `json:"address"`
+        OldKey  string `json:"oldKey"`
+        NewKey  string `json:"newKey"`
+    }{
+        Purpose: TxTypeRotateKey,
+        Address: address,
+        OldKey:  oldKey,
+        NewKey:  newKey,
+    })
+}
+
+// KeyAt returns the hex consensus key a validator signs with at a height, or "" if it never rotated its key by then,
+// in which case it signs with the key its address is derived from
+func (v Validator) KeyAt(height int64) string {
+    key := ""
+    for _, change := range v.KeyHistory {
+        if change.Height <= height {
+            key = change.Key
+        }
+    }
+
+    return key
+}
+
+// CheckConsensusKey reports whether a hex public key is one a validator signs with from the next block,
+// or will once a pending rotation takes effect
+func (pop *ProofOfPlay) CheckConsensusKey(address string, publicKey string) error {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    err := pop.checkSigner(address, publicKey, pop.blockHeight+1)
+    if err == nil {
+        return nil
+    }
+
+    if validator := pop.validator(address); validator != nil {
+        for _, change := range validator.KeyHistory {
+            if change.Key == publicKey && change.Height > pop.blockHeight+1 {
+                return nil
+            }
+        }
+    }
+
+    return err
+}
+
+// checkKeyRotation validates a key rotation transaction against the validator set
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkKeyRotation(tx core.Transaction) error {
+    validator := pop.validator(tx.Sender)
+    if validator == nil {
+        return errors.New("validator not registered")
+    }
+    if validator.Jailed {
+        return errors.New("validator has been removed from the active set")
+    }
+
+    history := validator.KeyHistory
+    if len(history) > 0 && history[len(history)-1].Height > pop.blockHeight+1 {
+        return errors.New("a key rotation is already pending")
+    }
+
+    data, _ := tx.Data.(map[string]interface{})
+    oldKey, _ := data["oldKey"].(string)
+    newKey, _ := data["newKey"].(string)
+    oldProof, _ := data["oldProof"].(string)
+    newProof, _ := data["newProof"].(string)
+    if oldKey == "" || newKey == "" || oldProof == "" || newProof == "" {
+        return errors.New("old and new keys and both proofs are required")
+    }
+    if oldKey == newKey {
+        return errors.New("new key is the current key")
+    }
+    if err := pop.checkSigner(tx.Sender, oldKey, pop.blockHeight+1); err != nil {
+        return errors.New("old key is not the validator's current key")
+    }
+    if pop.keyInUse(newKey) {
+        return errors.New("new key is already used by a validator")
+    }
+
+    payload, err := KeyRotationPayload(tx.Sender, oldKey, newKey)
+    if err != nil {
+        return err
+    }
+
+    for _, signed := range []struct{ key, proof string }{{oldKey, oldProof}, {newKey, newProof}} {
+        publicKey, err := crypto.HexToPublicKey(signed.key)
+        if err != nil {
+            return fmt.Errorf("invalid consensus key: %w", err)
+        }
+        valid, err := crypto.Verify(payload, signed.proof, publicKey)
+        if err != nil || !valid {
+            return errors.New("invalid key rotation proof")
+        }
+    }
+
+    return nil
+}
+
+// applyKeyRotation executes a checked key rotation transaction, scheduling the new key for the next epoch
+// Earlier keys stay in the history, so blocks and votes they signed still verify
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyKeyRotation(tx core.Transaction, header core.BlockHeader) {
+    data, _ := tx.Data.(map[string]interface{})
+    newKey, _ := data["newKey"].(string)
+
+    validator := pop.validator(tx.Sender)
+    validator.KeyHistory = append(validator.KeyHistory, KeyChange{Key: newKey, Height: pop.nextEpoch(header.Index)})
+}
+
+// checkSigner reports whether a hex public key is the one a validator signs with at a height
+// Validators that never rotated their key, and addresses that aren't validators, sign with the key their address is derived from
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkSigner(address string, publicKey string, height int64) error {
+    if validator := pop.validator(address); validator != nil {
+        if key := validator.KeyAt(height); key != "" {
+            if key != publicKey {
+                return fmt.Errorf("key is not the consensus key of %s at height %d", address, height)
+            }
+            return nil
+        }
+    }
+
+    key, err := crypto.HexToPublicKey(publicKey)
+    if err != nil {
+        return fmt.Errorf("invalid public key: %w", err)
+    }
+    if crypto.GetAddressFromPublicKey(key) != address {
+        return fmt.Errorf("key is not the consensus key of %s at height %d", address, height)
+    }
+
+    return nil
+}
+
+// signer returns the address of the validator a key pair signs for at a height
+// The caller must hold the lock
+func (pop *ProofOfPlay) signer(keyPair *crypto.KeyPair, height int64) string {
+    publicKey := crypto.PublicKeyToHex(keyPair.PublicKey)
+    for _, validator := range pop.validators {
+        if validator.KeyAt(height) == publicKey {
+            return validator.Address
+        }
+    }
+
+    return crypto.GetAddressFromPublicKey(keyPair.PublicKey)
+}
+
+// keyInUse reports whether a hex public key signs, signed or will sign for any validator
+// The caller must hold the lock
+func (pop *ProofOfPlay) keyInUse(publicKey string) bool {
+    if key, err := crypto.HexToPublicKey(publicKey); err == nil {
+        address := crypto.GetAddressFromPublicKey(key)
+        if pop.validator(address) != nil || pop.pendingValidator(address) != nil {
+            return true
+        }
+    }
+
+    for _, validator := range pop.validators {
+        for _, change := range validator.KeyHistory {
+            if change.Key == publicKey {
+                return true
+            }
+        }
+    }
+
+    return false
+}
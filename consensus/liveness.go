@@ -0,0 +1,255 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+)
+
+// UptimeWindow is how many recent blocks a validator's uptime is measured over
+const UptimeWindow = 100
+
+// MaxHeartbeatAge is how old a heartbeat can be when it arrives, in seconds, so old heartbeats can't be replayed
+const MaxHeartbeatAge = 120
+
+// Heartbeat is a validator's signed statement that it is online, gossiped with network heartbeats
+type Heartbeat struct {
+    Validator string `json:"validator"`
+    Timestamp int64  `json:"timestamp"`
+    PublicKey string `json:"publicKey,omitempty"` // Validator's hex public key, needed to verify the signature
+    Signature string `json:"signature,omitempty"`
+}
+
+// Liveness is a validator's recent participation as seen by this node
+type Liveness struct {
+    Validator     string  `json:"validator"`
+    LastHeartbeat int64   `json:"lastHeartbeat"`
+    LastVote      int64   `json:"lastVote"`
+    LastProposal  int64   `json:"lastProposal"`
+    Heartbeats    int64   `json:"heartbeats"`
+    Votes         int64   `json:"votes"`
+    Proposals     int64   `json:"proposals"`
+    WindowBlocks  int     `json:"windowBlocks"` // Recent blocks in the uptime window
+    SignedBlocks  int     `json:"signedBlocks"` // Blocks in the window the validator voted on or proposed
+    Uptime        float64 `json:"uptime"`       // Share of the window's blocks the validator signed
+}
+
+// livenessRecord is the participation tracked for one validator
+type livenessRecord struct {
+    Liveness
+    signed map[int64]bool // Heights voted on or proposed, pruned as they leave the window
+}
+
+// HeartbeatSigningPayload returns the bytes a validator signs for a heartbeat
+func HeartbeatSigningPayload(validator string, timestamp int64) ([]byte, error) {
+    return core.CanonicalEncode(struct {
+        Validator string `json:"validator"`
+        Timestamp int64  `json:"timestamp"`
+    }{
+        Validator: validator,
+        Timestamp: timestamp,
+    })
+}
+
+// NewHeartbeat returns a heartbeat for the current time signed with a validator's consensus key pair
+func NewHeartbeat(validator string, keyPair *crypto.KeyPair) (Heartbeat, error) {
+    heartbeat := Heartbeat{
+        Validator: validator,
+        Timestamp: time.Now().Unix(),
+        PublicKey: crypto.PublicKeyToHex(keyPair.PublicKey),
+    }
+
+    payload, err := HeartbeatSigningPayload(heartbeat.Validator, heartbeat.Timestamp)
+    if err != nil {
+        return Heartbeat{}, err
+    }
+
+    heartbeat.Signature, err = keyPair.Sign(payload)
+    if err != nil {
+        return Heartbeat{}, err
+    }
+
+    return heartbeat, nil
+}
+
+// Verify checks that a heartbeat was signed by its validator, with the key its address is derived from
+func (h Heartbeat) Verify() error {
+    if err := h.verifySignature(); err != nil {
+        return err
+    }
+
+    publicKey, _ := crypto.HexToPublicKey(h.PublicKey)
+    if crypto.GetAddressFromPublicKey(publicKey) != h.Validator {
+        return errors.New("heartbeat public key does not match its validator")
+    }
+
+    return nil
+}
+
+// verifySignature checks that a heartbeat was signed by the public key it carries
+func (h Heartbeat) verifySignature() error {
+    if h.Signature == "" || h.PublicKey == "" {
+        return errors.New("heartbeat is not signed")
+    }
+
+    publicKey, err := crypto.HexToPublicKey(h.PublicKey)
+    if err != nil {
+        return fmt.Errorf("invalid heartbeat public key: %w", err)
+    }
+
+    payload, err := HeartbeatSigningPayload(h.Validator, h.Timestamp)
+    if err != nil {
+        return err
+    }
+
+    valid, err := crypto.Verify(payload, h.Signature, publicKey)
+    if err != nil || !valid {
+        return errors.New("invalid heartbeat signature")
+    }
+
+    return nil
+}
+
+// RecordHeartbeat refreshes a validator's liveness from a signed heartbeat
+// Heartbeats older than the last one seen, or than MaxHeartbeatAge, are rejected
+func (pop *ProofOfPlay) RecordHeartbeat(heartbeat Heartbeat) error {
+    if err := heartbeat.verifySignature(); err != nil {
+        return err
+    }
+
+    now := pop.now()
+    if heartbeat.Timestamp < now-MaxHeartbeatAge || heartbeat.Timestamp > now+MaxHeartbeatAge {
+        return errors.New("heartbeat is stale")
+    }
+
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if pop.validator(heartbeat.Validator) == nil {
+        return errors.New("validator not registered")
+    }
+    if err := pop.checkSigner(heartbeat.Validator, heartbeat.PublicKey, pop.blockHeight+1); err != nil {
+        return err
+    }
+
+    record := pop.touch(heartbeat.Validator, now)
+    if heartbeat.Timestamp <= record.LastHeartbeat {
+        return errors.New("heartbeat is stale")
+    }
+
+    record.LastHeartbeat = heartbeat.Timestamp
+    record.Heartbeats++
+
+    return nil
+}
+
+// RecordBlock counts a block added to the chain towards the uptime window, crediting its producer
+func (pop *ProofOfPlay) RecordBlock(header core.BlockHeader) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if len(pop.window) == 0 || pop.window[len(pop.window)-1] < header.Index {
+        pop.window = append(pop.window, header.Index)
+    }
+    if len(pop.window) > UptimeWindow {
+        pop.window = pop.window[len(pop.window)-UptimeWindow:]
+
+        for _, record := range pop.liveness {
+            for height := range record.signed {
+                if height < pop.window[0] {
+                    delete(record.signed, height)
+                }
+            }
+        }
+    }
+
+    if pop.validator(header.Validator) == nil {
+        return
+    }
+
+    record := pop.touch(header.Validator, pop.now())
+    record.LastProposal = header.Timestamp
+    record.Proposals++
+    record.signed[header.Index] = true
+}
+
+// GetLiveness returns a validator's participation and uptime over the recent blocks
+func (pop *ProofOfPlay) GetLiveness(address string) (Liveness, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if pop.validator(address) == nil {
+        return Liveness{}, errors.New("validator not registered")
+    }
+
+    return pop.livenessOf(address), nil
+}
+
+// GetAllLiveness returns every registered validator's participation and uptime, ordered by address
+func (pop *ProofOfPlay) GetAllLiveness() []Liveness {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    all := []Liveness{}
+    for _, validator := range pop.validators {
+        all = append(all, pop.livenessOf(validator.Address))
+    }
+
+    sort.Slice(all, func(i, j int) bool {
+        return all[i].Validator < all[j].Validator
+    })
+
+    return all
+}
+
+// recordVote credits a validator's liveness with a vote it cast
+// The caller must hold the lock
+func (pop *ProofOfPlay) recordVote(vote Vote) {
+    record := pop.touch(vote.Validator, pop.now())
+    record.LastVote = vote.Timestamp
+    record.Votes++
+    record.signed[vote.Height] = true
+}
+
+// touch returns a validator's liveness record, creating it if needed, and marks the validator active
+// The caller must hold the lock
+func (pop *ProofOfPlay) touch(address string, now int64) *livenessRecord {
+    if validator := pop.validator(address); validator != nil {
+        validator.LastActivity = now
+    }
+
+    record, exists := pop.liveness[address]
+    if !exists {
+        record = &livenessRecord{Liveness: Liveness{Validator: address}, signed: make(map[int64]bool)}
+        pop.liveness[address] = record
+    }
+
+    return record
+}
+
+// livenessOf returns a validator's liveness with its uptime over the current window
+// The caller must hold the lock
+func (pop *ProofOfPlay) livenessOf(address string) Liveness {
+    liveness := Liveness{Validator: address}
+    record, exists := pop.liveness[address]
+    if exists {
+        liveness = record.Liveness
+    }
+
+    liveness.WindowBlocks = len(pop.window)
+    liveness.SignedBlocks = 0
+    for _, height := range pop.window {
+        if exists && record.signed[height] {
+            liveness.SignedBlocks++
+        }
+    }
+    if liveness.WindowBlocks > 0 {
+        liveness.Uptime = float64(liveness.SignedBlocks) / float64(liveness.WindowBlocks)
+    }
+
+    return liveness
+}
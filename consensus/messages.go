@@ -0,0 +1,58 @@
+package consensus
+
+import (
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// Network message types consensus messages are gossiped as
+const (
+    TopicProposal = "consensus_proposal"
+    TopicVote     = "consensus_vote"
+    TopicEvidence = "consensus_evidence"
+
+    TopicStatus       = "consensus_status"
+    TopicSyncRequest  = "consensus_sync_request"
+    TopicSyncResponse = "consensus_sync_response"
+)
+
+// MaxSyncBlocks is the most blocks a sync response carries
+const MaxSyncBlocks = 10
+
+// ProposalMessage is a signed block proposed for its height, and the voting round it is proposed in
+type ProposalMessage struct {
+    Block core.Block `json:"block"`
+    Round int64      `json:"round"`
+}
+
+// VoteMessage carries a signed prevote, precommit or approval vote
+type VoteMessage struct {
+    Vote Vote `json:"vote"`
+}
+
+// EvidenceMessage carries evidence of a validator's misbehavior, for peers to verify, pool and gossip on
+// until a block commits it
+type EvidenceMessage struct {
+    Evidence Evidence `json:"evidence"`
+}
+
+// StatusMessage announces a node's chain tip and the highest block it has seen finalized,
+// so peers that fell behind can catch up
+type StatusMessage struct {
+    NodeID          string `json:"nodeId"`
+    Height          int64  `json:"height"`
+    FinalizedHeight int64  `json:"finalizedHeight"` // -1 if the node has seen no block finalized
+}
+
+// SyncRequest asks a peer for the blocks of its chain from From to To
+type SyncRequest struct {
+    NodeID string `json:"nodeId"` // Node to send the blocks to
+    From   int64  `json:"from"`
+    To     int64  `json:"to"`
+}
+
+// SyncResponse carries consecutive blocks of a peer's chain, lowest first, and the peer's chain tip
+type SyncResponse struct {
+    NodeID string       `json:"nodeId"`
+    Height int64        `json:"height"`
+    Blocks []core.Block `json:"blocks"`
+}
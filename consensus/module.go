@@ -0,0 +1,437 @@
+package consensus
+
+import (
+    "errors"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// signedTypes are the transaction types acting for their sender, which must carry the sender's signature
+// Blocks from peers are applied without the admission checks, so the signature is verified again when they are applied
+var signedTypes = map[string]bool{
+    TxTypeRegisterValidator: true,
+    TxTypeBond:              true,
+    TxTypeUnbond:            true,
+    TxTypeDelegate:          true,
+    TxTypeUndelegate:        true,
+    TxTypeRedelegate:        true,
+    TxTypeWithdrawRewards:   true,
+    TxTypeSetCommission:     true,
+    TxTypeUnjail:            true,
+    TxTypeRotateKey:         true,
+    TxTypeProposeParams:     true,
+    TxTypeGovVote:           true,
+    TxTypeStakeLock:         true,
+    TxTypeStakeUnlock:       true,
+    core.TxTypeGameResult:   true,
+    core.TxTypeMatchVoid:    true,
+}
+
+// CheckTransaction reports whether an evidence, attestation, registration, bonding, delegation, commission, unjail,
+// key rotation, governance or staking transaction would succeed, and that game results and voids come from game servers
+// Other transaction types are not the consensus engine's and always pass
+func (pop *ProofOfPlay) CheckTransaction(tx core.Transaction) error {
+    switch tx.Type {
+    case TxTypeEvidence:
+        evidence, err := decodeEvidence(tx)
+        if err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkEvidence(evidence, pop.blockHeight+1)
+
+    case TxTypeAttestation:
+        attestation, err := decodeAttestation(tx)
+        if err != nil {
+            return err
+        }
+        if err := attestation.Verify(); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkAttestation(attestation)
+
+    case TxTypeRegisterValidator:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkRegistration(tx)
+
+    case TxTypeBond, TxTypeUnbond:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkBonding(tx)
+
+    case TxTypeDelegate, TxTypeUndelegate, TxTypeRedelegate, TxTypeWithdrawRewards:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkDelegation(tx)
+
+    case TxTypeSetCommission:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkCommission(tx, pop.blockTime)
+
+    case TxTypeUnjail:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkUnjail(tx, pop.blockTime)
+
+    case TxTypeRotateKey:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkKeyRotation(tx)
+
+    case TxTypeProposeParams, TxTypeGovVote:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkGovernance(tx)
+
+    case TxTypeStakeLock, TxTypeStakeUnlock:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        return pop.checkStaking(tx, pop.blockTime)
+
+    case core.TxTypeGameResult, core.TxTypeMatchVoid:
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if !pop.gameServers[tx.Sender] {
+            return errors.New("match results are only accepted from game servers genesis or governance allowed")
+        }
+    }
+
+    return nil
+}
+
+// ApplyTransaction executes a confirmed evidence, attestation, registration, bonding, delegation, commission, unjail,
+// key rotation, governance or staking transaction,
+// shares coinbases and vote rewards with the delegators of the validators paid,
+// and counts the blocks validators missed from the voters in each vote reward
+// A transaction not signed by the sender it acts for does nothing
+func (pop *ProofOfPlay) ApplyTransaction(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    if signedTypes[tx.Type] && core.VerifyTransactionSignature(tx) != nil {
+        return
+    }
+
+    switch tx.Type {
+    case core.TxTypeCoinbase:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        pop.shareReward(tx.Recipient, tx.Amount, state)
+
+    case core.TxTypeVoteReward:
+        shares, _ := core.VoteRewardShares(tx)
+        voters := make([]string, 0, len(shares))
+        for voter := range shares {
+            voters = append(voters, voter)
+        }
+        sort.Strings(voters)
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        for _, voter := range voters {
+            pop.shareReward(voter, shares[voter], state)
+        }
+        pop.recordParticipation(shares, header)
+
+    case TxTypeEvidence:
+        evidence, err := decodeEvidence(tx)
+        if err != nil {
+            return
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkEvidence(evidence, header.Index) != nil {
+            return
+        }
+
+        pop.slash(evidence, header.Index, state)
+
+    case TxTypeAttestation:
+        attestation, err := decodeAttestation(tx)
+        if err != nil || attestation.Verify() != nil {
+            return
+        }
+
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkAttestation(attestation) != nil {
+            return
+        }
+
+        pop.applyAttestation(attestation, header)
+
+    case TxTypeRegisterValidator:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkRegistration(tx) != nil {
+            return
+        }
+
+        pop.applyRegistration(tx, header, state)
+
+    case TxTypeBond, TxTypeUnbond:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkBonding(tx) != nil {
+            return
+        }
+
+        pop.applyBonding(tx, header, state)
+
+    case TxTypeDelegate, TxTypeUndelegate, TxTypeRedelegate, TxTypeWithdrawRewards:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkDelegation(tx) != nil {
+            return
+        }
+
+        pop.applyDelegation(tx, header, state)
+
+    case TxTypeSetCommission:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkCommission(tx, header.Timestamp) != nil {
+            return
+        }
+
+        pop.applyCommission(tx, header)
+
+    case TxTypeRotateKey:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkKeyRotation(tx) != nil {
+            return
+        }
+
+        pop.applyKeyRotation(tx, header)
+
+    case TxTypeUnjail:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkUnjail(tx, header.Timestamp) != nil {
+            return
+        }
+
+        pop.validator(tx.Sender).JailedForDowntime = false
+
+    case TxTypeProposeParams, TxTypeGovVote:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkGovernance(tx) != nil {
+            return
+        }
+
+        pop.applyGovernance(tx, header, state)
+
+    case TxTypeStakeLock, TxTypeStakeUnlock:
+        pop.mutex.Lock()
+        defer pop.mutex.Unlock()
+
+        if pop.checkStaking(tx, header.Timestamp) != nil {
+            return
+        }
+
+        pop.applyStaking(tx, header, state)
+    }
+}
+
+// EndBlock records the seasons that started or ended, pays out the staking reward pool, returns unbonded, undelegated and unlocked stake whose waiting period is over to its owners' wallets,
+// adds the validators registered on chain whose epoch starts with the next block and chooses that epoch's active set and yield rate, tallies the proposals whose voting ends,
+// takes up the game servers the state allows, and drops pooled evidence the block committed or that can no longer be
+func (pop *ProofOfPlay) EndBlock(header core.BlockHeader, state *core.State) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    previous := pop.blockTime
+    pop.blockTime = header.Timestamp
+    pop.blockHeight = header.Index
+
+    pop.recordSeasonTransitions(previous, header)
+    pop.payStakingPool(state)
+    pop.releaseUnbonded(header.Timestamp, state)
+    pop.releaseUndelegated(header.Timestamp, state)
+    pop.releaseUnlocked(header.Timestamp, state)
+    pop.activatePending(header.Index)
+    if pop.nextEpoch(header.Index) == header.Index+1 {
+        pop.rotateActiveSet(header)
+        pop.recordValidatorSet(header.Index + 1)
+        pop.updateYieldRate(header, state)
+    }
+    pop.advancePriorities(header.Index)
+    pop.endVoting(header.Index, state)
+    pop.activateProposals(header.Index, state)
+    pop.gameServers = state.GameServers
+    pop.pruneEvidencePool(header.Index)
+    pop.prunePlayCredits(header.Timestamp)
+    pop.persistValidators()
+}
+
+// Reset restores every validator's registered stake, play score and active status, the configured settings and the default
+// play modes, drops the validators registered on chain and the game servers, and forgets committed evidence, attestations,
+// bonds, delegations, locks and proposals
+func (pop *ProofOfPlay) Reset() {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    configured := []Validator{}
+    for _, validator := range pop.validators {
+        if validator.RegisteredAt == 0 {
+            configured = append(configured, registered(validator))
+        }
+    }
+    pop.validators = configured
+    pop.pending = nil
+    pop.validatorHistory = nil
+    pop.recordValidatorSet(0)
+    pop.priorities = make(map[string]float64)
+    pop.priorityHeight = 1
+    pop.schedules = make(map[int64][]string)
+    pop.evidence = make(map[string]*CommittedEvidence)
+    pop.gameServers = nil
+    pop.attested = make(map[attestationKey]bool)
+    pop.playCredits = make(map[playCreditKey]float64)
+    pop.PlayModes = DefaultPlayModes()
+    pop.delegations = make(map[delegationKey]*Delegation)
+    pop.undelegations = nil
+    pop.redelegations = nil
+    pop.locks = make(map[string]*StakeLock)
+    pop.proposals = make(map[string]*Proposal)
+    pop.events = nil
+    pop.restoreConfigured()
+    pop.stakedRatio = 0
+    pop.yieldHeight = 0
+    pop.blockTime = 0
+    pop.blockHeight = 0
+}
+
+// Fork returns a consensus engine with the same configuration, as configured before governance changed it,
+// and the validators configured off chain, as registered, without votes or evidence; its play modes and game servers
+// come from the genesis block it replays
+func (pop *ProofOfPlay) Fork() core.Module {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    forked := NewProofOfPlay()
+    forked.MinValidators = pop.MinValidators
+    forked.FinalityThreshold = pop.FinalityThreshold
+    forked.SlashFraction = pop.SlashFraction
+    forked.UnbondingPeriod = pop.UnbondingPeriod
+    forked.RoundTimeout = pop.RoundTimeout
+    forked.DowntimeThreshold = pop.DowntimeThreshold
+    forked.DowntimeJail = pop.DowntimeJail
+    forked.MinValidatorStake = pop.MinValidatorStake
+    forked.EpochLength = pop.EpochLength
+    forked.MaxEvidenceAge = pop.MaxEvidenceAge
+    forked.MaxCommissionChange = pop.MaxCommissionChange
+    forked.MinDeposit = pop.MinDeposit
+    forked.VotingPeriod = pop.VotingPeriod
+    forked.Quorum = pop.Quorum
+    forked.PassThreshold = pop.PassThreshold
+    forked.MaxValidators = pop.MaxValidators
+    forked.FeeSharing = pop.FeeSharing
+    forked.Economy = pop.Economy
+    forked.MinLockPeriod = pop.MinLockPeriod
+    forked.MaxLockPeriod = pop.MaxLockPeriod
+    forked.UnlockDelay = pop.UnlockDelay
+    for name, value := range pop.configured {
+        governedParams[name].set(forked, value)
+    }
+    if pop.configured != nil {
+        forked.Economy.Seasons = pop.configuredSeasons
+    }
+    forked.Boosts = pop.Boosts
+    forked.Configurables = pop.Configurables
+    forked.Clock = pop.Clock
+    for _, validator := range pop.validators {
+        if validator.RegisteredAt == 0 {
+            forked.validators = append(forked.validators, registered(validator))
+        }
+    }
+    forked.recordValidatorSet(0)
+
+    return forked
+}
+
+// registered returns a validator as it was registered, before anything on chain changed its stake or status
+func registered(validator Validator) Validator {
+    validator.Stake = validator.RegisteredStake
+    validator.Bonded = 0
+    validator.Unbonding = nil
+    validator.Delegated = 0
+    validator.PlayScore = 0
+    validator.Commission = DefaultCommission
+    validator.CommissionUpdated = 0
+    validator.Jailed = false
+    validator.Candidate = false
+    validator.MissedBlocks = 0
+    validator.JailedForDowntime = false
+    validator.UnjailTime = 0
+    validator.DowntimeOffenses = 0
+    validator.KeyHistory = nil
+
+    return validator
+}
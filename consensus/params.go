@@ -0,0 +1,102 @@
+package consensus
+
+import (
+    "errors"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// ParamVersion is one version of the settings governance can change, recorded on chain
+// Version 0 is the settings as configured; each accepted proposal adds the next version
+type ParamVersion struct {
+    Version  int64              `json:"version"`
+    Height   int64              `json:"height"`             // First block produced under the version
+    Proposal string             `json:"proposal,omitempty"` // Proposal that set the version
+    Params   map[string]float64 `json:"params"`             // Value of every governed setting, by name
+    Seasons  []token.Season     `json:"seasons,omitempty"`  // Season calendar game rewards were scaled by
+}
+
+// ParamsVersion returns the version of the settings the next block is produced under
+func (pop *ProofOfPlay) ParamsVersion() int64 {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return int64(len(pop.paramVersions))
+}
+
+// GetParamVersions returns every version of the governed settings, oldest first
+func (pop *ProofOfPlay) GetParamVersions() []ParamVersion {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    versions := []ParamVersion{pop.baseParams()}
+    for _, version := range pop.paramVersions {
+        versions = append(versions, version.clone())
+    }
+
+    return versions
+}
+
+// GetParamVersion returns a version of the governed settings
+func (pop *ProofOfPlay) GetParamVersion(version int64) (ParamVersion, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if version < 0 || version > int64(len(pop.paramVersions)) {
+        return ParamVersion{}, errors.New("parameter version not found")
+    }
+    if version == 0 {
+        return pop.baseParams(), nil
+    }
+
+    return pop.paramVersions[version-1].clone(), nil
+}
+
+// recordParamVersion adds the current settings as the next version, taking effect with the block after a height
+// The caller must hold the lock
+func (pop *ProofOfPlay) recordParamVersion(height int64, proposal string) {
+    pop.paramVersions = append(pop.paramVersions, ParamVersion{
+        Version:  int64(len(pop.paramVersions)) + 1,
+        Height:   height + 1,
+        Proposal: proposal,
+        Params:   pop.governedValues(),
+        Seasons:  pop.Economy.Seasons,
+    })
+}
+
+// baseParams returns version 0, the settings as configured before any proposal changed them
+// The caller must hold the lock
+func (pop *ProofOfPlay) baseParams() ParamVersion {
+    base := ParamVersion{Params: make(map[string]float64, len(governedParams)), Seasons: pop.configuredSeasons}
+    if pop.configured == nil {
+        base.Params = pop.governedValues()
+        base.Seasons = pop.Economy.Seasons
+    }
+    for name, value := range pop.configured {
+        base.Params[name] = value
+    }
+
+    return base
+}
+
+// governedValues returns the current value of every governed setting, by name
+// The caller must hold the lock
+func (pop *ProofOfPlay) governedValues() map[string]float64 {
+    values := make(map[string]float64, len(governedParams))
+    for name, param := range governedParams {
+        values[name] = param.get(pop)
+    }
+
+    return values
+}
+
+// clone returns a copy of a parameter version that shares no memory with it
+func (v ParamVersion) clone() ParamVersion {
+    params := make(map[string]float64, len(v.Params))
+    for name, value := range v.Params {
+        params[name] = value
+    }
+    v.Params = params
+
+    return v
+}
@@ -0,0 +1,293 @@
+package consensus
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/storage"
+)
+
+// Store keys used by the consensus engine
+const (
+    storeKeySignState    = "consensus/signstate"
+    storeKeyValidators   = "consensus/validators"
+    storeKeyRoundsPrefix = "consensus/rounds/"
+)
+
+// SignState is the last block and vote this node's validator signed
+// It is made durable before anything signed is broadcast, so after a crash the validator can't sign a conflicting message
+type SignState struct {
+    ProposalHeight int64  `json:"proposalHeight"` // Height of the last block signed
+    ProposalHash   string `json:"proposalHash,omitempty"`
+    VoteHeight     int64  `json:"voteHeight"`     // Height, round and step of the last prevote or precommit signed
+    VoteRound      int64  `json:"voteRound"`
+    VoteStep       string `json:"voteStep,omitempty"`
+    VoteHash       string `json:"voteHash,omitempty"` // Block the last vote was for, empty for nil
+}
+
+// persistedRounds is the voting at one height as written to the store
+type persistedRounds struct {
+    Votes       []Vote    `json:"votes"`
+    LockedRound int64     `json:"lockedRound"`
+    LockedBlock string    `json:"lockedBlock,omitempty"`
+    Decision    *Decision `json:"decision,omitempty"`
+}
+
+// OpenProofOfPlay loads a consensus engine persisted in a store: the validator set, the votes at heights not yet pruned,
+// this node's locks and the last messages its validator signed
+// Stake and status are still derived from the chain, so callers rebuild the engine from it after configuring it
+func OpenProofOfPlay(store storage.Store) (*ProofOfPlay, error) {
+    pop := NewProofOfPlay()
+    pop.store = store
+
+    if data, err := store.Get(storeKeySignState); err == nil {
+        if err := json.Unmarshal(data, &pop.signState); err != nil {
+            return nil, fmt.Errorf("invalid consensus sign state: %w", err)
+        }
+    } else if !errors.Is(err, storage.ErrNotFound) {
+        return nil, err
+    }
+
+    if data, err := store.Get(storeKeyValidators); err == nil {
+        if err := json.Unmarshal(data, &pop.validators); err != nil {
+            return nil, fmt.Errorf("invalid consensus validator set: %w", err)
+        }
+    } else if !errors.Is(err, storage.ErrNotFound) {
+        return nil, err
+    }
+
+    keys, err := store.Keys(storeKeyRoundsPrefix)
+    if err != nil {
+        return nil, err
+    }
+    for _, key := range keys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        var persisted persistedRounds
+        if err := json.Unmarshal(data, &persisted); err != nil {
+            return nil, fmt.Errorf("invalid consensus rounds %s: %w", key, err)
+        }
+
+        var height int64
+        if _, err := fmt.Sscanf(strings.TrimPrefix(key, storeKeyRoundsPrefix), "%d", &height); err != nil {
+            return nil, fmt.Errorf("invalid consensus rounds key %s", key)
+        }
+
+        rounds := pop.roundsAt(height)
+        rounds.lockedRound = persisted.LockedRound
+        rounds.lockedBlock = persisted.LockedBlock
+        rounds.decision = persisted.Decision
+        for _, vote := range persisted.Votes {
+            stepKey := roundStep{round: vote.Round, step: vote.Step}
+            if rounds.votes[stepKey] == nil {
+                rounds.votes[stepKey] = make(map[string]Vote)
+            }
+            rounds.votes[stepKey][vote.Validator] = vote
+        }
+    }
+
+    return pop, nil
+}
+
+// GetSignState returns the last block and vote this node's validator signed
+func (pop *ProofOfPlay) GetSignState() SignState {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.signState
+}
+
+// CheckProposal reports whether this node's validator may sign a block at a height
+// Once it has signed a block at a height, any other block there would be a double sign
+func (pop *ProofOfPlay) CheckProposal(height int64) error {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if height <= pop.signState.ProposalHeight {
+        return fmt.Errorf("already signed a block at height %d", pop.signState.ProposalHeight)
+    }
+
+    return nil
+}
+
+// RecordProposal durably records a block this node's validator signed, before the block is broadcast
+func (pop *ProofOfPlay) RecordProposal(header core.BlockHeader) error {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if header.Index < pop.signState.ProposalHeight ||
+        (header.Index == pop.signState.ProposalHeight && header.Hash != pop.signState.ProposalHash) {
+        return fmt.Errorf("block %d conflicts with the block signed at height %d", header.Index, pop.signState.ProposalHeight)
+    }
+
+    pop.signState.ProposalHeight = header.Index
+    pop.signState.ProposalHash = header.Hash
+
+    return pop.flushSignState()
+}
+
+// Flush writes the validator set and the votes not yet pruned to the store and makes them durable,
+// returning any earlier write error
+func (pop *ProofOfPlay) Flush() error {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if pop.store == nil {
+        return nil
+    }
+
+    pop.persistValidators()
+    for height := range pop.rounds {
+        pop.persistRounds(height)
+    }
+
+    if pop.storeErr != nil {
+        return pop.storeErr
+    }
+
+    return pop.store.Flush()
+}
+
+// checkSignVote reports whether this node's validator may sign a prevote or precommit
+// Votes go forward by height, round and step; at the last one signed, only a vote for the same block may be signed again
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkSignVote(vote Vote) error {
+    last := pop.signState
+    switch {
+    case vote.Height != last.VoteHeight:
+        if vote.Height < last.VoteHeight {
+            return fmt.Errorf("already signed votes at height %d", last.VoteHeight)
+        }
+    case vote.Round != last.VoteRound:
+        if vote.Round < last.VoteRound {
+            return fmt.Errorf("already signed votes in round %d at height %d", last.VoteRound, last.VoteHeight)
+        }
+    case vote.Step != last.VoteStep:
+        if last.VoteStep == StepPrecommit {
+            return fmt.Errorf("already precommitted in round %d at height %d", last.VoteRound, last.VoteHeight)
+        }
+    case vote.BlockHash != last.VoteHash:
+        return fmt.Errorf("already signed a %s for another block in round %d at height %d", last.VoteStep, last.VoteRound, last.VoteHeight)
+    }
+
+    return nil
+}
+
+// recordSignVote durably records a prevote or precommit this node's validator signed
+// The caller must hold the lock
+func (pop *ProofOfPlay) recordSignVote(vote Vote) error {
+    pop.signState.VoteHeight = vote.Height
+    pop.signState.VoteRound = vote.Round
+    pop.signState.VoteStep = vote.Step
+    pop.signState.VoteHash = vote.BlockHash
+
+    pop.persistRounds(vote.Height)
+    return pop.flushSignState()
+}
+
+// flushSignState writes the sign state and makes the store durable
+// The caller must hold the lock
+func (pop *ProofOfPlay) flushSignState() error {
+    if pop.store == nil {
+        return nil
+    }
+
+    data, err := json.Marshal(pop.signState)
+    if err != nil {
+        return err
+    }
+    if err := pop.store.Put(storeKeySignState, data); err != nil {
+        return err
+    }
+
+    return pop.store.Flush()
+}
+
+// persistValidators writes the validator set to the store
+// The caller must hold the lock
+func (pop *ProofOfPlay) persistValidators() {
+    if pop.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(pop.validators)
+    if err != nil {
+        pop.recordStoreError(err)
+        return
+    }
+
+    pop.recordStoreError(pop.store.Put(storeKeyValidators, data))
+}
+
+// persistRounds writes the voting at a height to the store
+// The caller must hold the lock
+func (pop *ProofOfPlay) persistRounds(height int64) {
+    if pop.store == nil {
+        return
+    }
+
+    rounds, exists := pop.rounds[height]
+    if !exists {
+        return
+    }
+
+    persisted := persistedRounds{
+        Votes:       []Vote{},
+        LockedRound: rounds.lockedRound,
+        LockedBlock: rounds.lockedBlock,
+        Decision:    rounds.decision,
+    }
+    for _, votes := range rounds.votes {
+        for _, vote := range votes {
+            persisted.Votes = append(persisted.Votes, vote)
+        }
+    }
+    sort.Slice(persisted.Votes, func(i, j int) bool {
+        a, b := persisted.Votes[i], persisted.Votes[j]
+        if a.Round != b.Round {
+            return a.Round < b.Round
+        }
+        if a.Step != b.Step {
+            return a.Step == StepPrevote
+        }
+        return a.Validator < b.Validator
+    })
+
+    data, err := json.Marshal(persisted)
+    if err != nil {
+        pop.recordStoreError(err)
+        return
+    }
+
+    pop.recordStoreError(pop.store.Put(roundsKey(height), data))
+}
+
+// deleteRounds removes the voting at a pruned height from the store
+// The caller must hold the lock
+func (pop *ProofOfPlay) deleteRounds(height int64) {
+    if pop.store == nil {
+        return
+    }
+
+    pop.recordStoreError(pop.store.Delete(roundsKey(height)))
+}
+
+// recordStoreError keeps the first store write error so Flush can report it
+// The caller must hold the lock
+func (pop *ProofOfPlay) recordStoreError(err error) {
+    if err != nil && pop.storeErr == nil {
+        pop.storeErr = err
+    }
+}
+
+// roundsKey returns the store key of the voting at a height, zero-padded so keys sort by height
+func roundsKey(height int64) string {
+    return fmt.Sprintf("%s%020d", storeKeyRoundsPrefix, height)
+}
@@ -0,0 +1,111 @@
+package consensus
+
+import (
+    "errors"
+    "math"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// Game modes attestations can report activity from; attestations without a mode are casual
+const (
+    ModeRanked     = "ranked"
+    ModeCasual     = "casual"
+    ModeTournament = "tournament"
+)
+
+// secondsPerDay is the length of the days play score caps are counted over, by block time
+const secondsPerDay = 86400
+
+// PlayMode is how a game mode's raw match activity becomes play score
+// A match with activity a scores Weight * a / (a + HalfActivity), so no match scores more than Weight,
+// and a validator gains at most DailyCap from the mode each day
+type PlayMode struct {
+    Weight       float64 `json:"weight"`
+    HalfActivity float64 `json:"halfActivity"` // Activity that scores half the weight
+    DailyCap     float64 `json:"dailyCap"`
+}
+
+// playCreditKey identifies the play score a validator gained from one mode on one day
+type playCreditKey struct {
+    player string
+    mode   string
+    day    int64
+}
+
+// DefaultPlayModes returns the scoring of each game mode: tournaments weigh most and casual matches least
+func DefaultPlayModes() map[string]PlayMode {
+    return map[string]PlayMode{
+        ModeRanked:     {Weight: 1, HalfActivity: 100, DailyCap: 10},
+        ModeCasual:     {Weight: 0.5, HalfActivity: 100, DailyCap: 5},
+        ModeTournament: {Weight: 2, HalfActivity: 100, DailyCap: 20},
+    }
+}
+
+// Validate checks that a mode's curve and cap are usable
+func (m PlayMode) Validate() error {
+    if !(m.Weight >= 0) || math.IsInf(m.Weight, 0) {
+        return errors.New("weight must not be negative")
+    }
+    if !(m.HalfActivity > 0) || math.IsInf(m.HalfActivity, 0) {
+        return errors.New("half activity must be positive")
+    }
+    if !(m.DailyCap >= 0) || math.IsInf(m.DailyCap, 0) {
+        return errors.New("daily cap must not be negative")
+    }
+
+    return nil
+}
+
+// Score returns the play score a match with some raw activity is worth in the mode, before the daily cap
+func (m PlayMode) Score(activity float64) float64 {
+    return m.Weight * activity / (activity + m.HalfActivity)
+}
+
+// GetPlayModes returns how each game mode's activity is scored, by mode
+func (pop *ProofOfPlay) GetPlayModes() map[string]PlayMode {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    modes := make(map[string]PlayMode, len(pop.PlayModes))
+    for name, mode := range pop.PlayModes {
+        modes[name] = mode
+    }
+
+    return modes
+}
+
+// attestationMode returns the game mode an attestation reports activity from
+func attestationMode(attestation *ActivityAttestation) string {
+    if attestation.Mode == "" {
+        return ModeCasual
+    }
+
+    return attestation.Mode
+}
+
+// playCredit returns the play score an attestation adds once its mode's curve and daily cap are applied
+// The caller must hold the lock
+func (pop *ProofOfPlay) playCredit(attestation *ActivityAttestation, header core.BlockHeader) (playCreditKey, float64) {
+    name := attestationMode(attestation)
+    mode := pop.PlayModes[name]
+    key := playCreditKey{player: attestation.Player, mode: name, day: header.Timestamp / secondsPerDay}
+
+    credit := mode.Score(attestation.Activity)
+    if remaining := mode.DailyCap - pop.playCredits[key]; credit > remaining {
+        credit = math.Max(remaining, 0)
+    }
+
+    return key, credit
+}
+
+// prunePlayCredits drops the daily play score counts of days before a block's
+// The caller must hold the lock
+func (pop *ProofOfPlay) prunePlayCredits(timestamp int64) {
+    today := timestamp / secondsPerDay
+    for key := range pop.playCredits {
+        if key.day < today {
+            delete(pop.playCredits, key)
+        }
+    }
+}
@@ -0,0 +1,354 @@
+package consensus
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "math/rand"
+    "sync"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/storage"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// ProofOfPlay implements a custom consensus mechanism for the Nexus Legends blockchain
+// It rewards active players and validates transactions based on game participation
+// It is safe for concurrent use once configured; the exported settings must be set before then,
+// and are only changed afterwards by governance proposals, under the lock
+type ProofOfPlay struct {
+    // Minimum number of validators required for consensus
+    MinValidators int
+    
+    // Percentage of validator weight whose approval votes mark a block approved in tallies (e.g., 67 for 2/3)
+    // Finality itself takes more than 2/3 of the weight precommitting a block
+    FinalityThreshold int
+    
+    // Share of its stake a validator loses when evidence of misbehavior is committed
+    SlashFraction float64
+    
+    // Seconds unbonded stake waits, still slashable, before it returns to the validator's wallet
+    UnbondingPeriod int64
+    
+    // Seconds the first round at a height lasts before the next proposer in its schedule may propose; later rounds last longer
+    RoundTimeout int64
+    
+    // Consecutive finalized blocks a validator can miss before it is jailed, and seconds its first jailing lasts
+    DowntimeThreshold int
+    DowntimeJail      int64
+    
+    // Least stake a validator can register with on chain, and blocks per epoch; registrations take effect at the next epoch
+    MinValidatorStake token.Amount
+    EpochLength       int64
+    
+    // Blocks after the misbehavior within which evidence of it can be committed
+    MaxEvidenceAge int64
+    
+    // Most a validator's commission rate can move in one change; validators change it at most once a day
+    MaxCommissionChange float64
+    
+    // Least deposit a governance proposal needs, blocks it is open for votes, share of the weight that must vote,
+    // and share of the yes and no weight that must vote yes for it to pass
+    MinDeposit    token.Amount
+    VotingPeriod  int64
+    Quorum        float64
+    PassThreshold float64
+    
+    // How block fees are split between the master wallet, the staking reward pool, the treasury, burning and the producer;
+    // governance changes the shares
+    FeeSharing core.FeePolicy
+    
+    // Fee rate, yield rate and game reward settings the token economics use; governance changes them
+    Economy token.EconomicParams
+    
+    // Most validators in the active set; those ranked below by consensus weight at the start of an epoch wait as candidates
+    MaxValidators int
+    
+    // Shortest and longest periods tokens can be locked for, and the time unlocked tokens wait before returning, in seconds
+    MinLockPeriod int64
+    MaxLockPeriod int64
+    UnlockDelay   int64
+    
+    // How each game mode's attested activity is scored, by mode; attestations from other modes are rejected
+    // Genesis and governance set them through the engine's settings
+    PlayModes map[string]PlayMode
+    
+    // Source of the current time for votes, liveness and the evidence pool, time.Now if nil
+    Clock func() time.Time
+    
+    // Registered validators, active or not; read them through ValidatorSet
+    validators []Validator
+    
+    // Validators registered on chain waiting for their epoch to start
+    pending []Validator
+    
+    // Validator sets recorded as epochs started, oldest first, for checking blocks and evidence from past epochs
+    validatorHistory []epochValidators
+    
+    // Proposer priorities the height priorityHeight starts with, before fitting them to the validators,
+    // and the proposer schedules recorded for recent heights
+    priorities     map[string]float64
+    priorityHeight int64
+    schedules      map[int64][]string
+    
+    // Votes cast on each block not yet pruned
+    votes map[BlockRef]*BlockVotes
+    
+    // Prevotes, precommits, lock and decision at each height not yet pruned
+    rounds map[int64]*heightRounds
+    
+    // Source of the boost staked NFTs give each validator, if any
+    Boosts BoostSource
+    
+    // Modules besides this engine whose settings proposals can replace, such as the NFT registry
+    Configurables []core.Configurable
+    
+    // Signed headers seen from each validator at each height, to catch double signing
+    headers map[headerKey]core.BlockHeader
+    
+    // Evidence committed on chain by ID
+    evidence map[string]*CommittedEvidence
+    
+    // Verified evidence from peers or this node waiting to be committed, by ID
+    evidencePool map[string]*PooledEvidence
+    
+    // Stake delegated to validators, and delegated stake still moving out or between validators
+    delegations   map[delegationKey]*Delegation
+    undelegations []Undelegation
+    redelegations []Redelegation
+    
+    // Tokens locked for staking rewards, unlocking ones included, by ID
+    locks map[string]*StakeLock
+    
+    // Game servers allowed to attest to player activity as of the last block, as the state records them,
+    // and the matches each player has claimed
+    gameServers map[string]bool
+    attested    map[attestationKey]bool
+    playCredits map[playCreditKey]float64
+    
+    // Governance proposals by ID, the settings and season calendar as configured before any proposal changed them,
+    // and the versions of the settings accepted proposals added, oldest first
+    proposals         map[string]*Proposal
+    configured        map[string]float64
+    configuredSeasons []token.Season
+    paramVersions     []ParamVersion
+    
+    // Participation seen from each validator, and the recent block heights uptime is measured over
+    liveness map[string]*livenessRecord
+    window   []int64
+    
+    // Share of the supply staked when the yield rate was last set, and the block that set it
+    stakedRatio float64
+    yieldHeight int64
+    
+    // Time and height of the last block applied, which unjail and evidence transactions are checked against
+    blockTime   int64
+    blockHeight int64
+    
+    // Store the validator set, votes and sign state are persisted in, nil to keep them only in memory
+    store     storage.Store
+    storeErr  error
+    signState SignState
+    
+    // Rounds that timed out without a block being proposed or finalized
+    roundMetrics RoundMetrics
+    
+    // Validator events recorded for the blocks executed, drained by the chain
+    events []core.ChainEvent
+    
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// BoostSource reports the boost to a validator's weight from assets staked to it, such as NFTs
+type BoostSource interface {
+    // ValidatorBoost returns the fraction a validator's weight is increased by (0.25 = 25%)
+    ValidatorBoost(address string) float64
+}
+
+// Validator represents a node that can validate transactions and create blocks
+type Validator struct {
+    Address         string       `json:"address"`             // Wallet address of the validator
+    Stake           token.Amount `json:"stake"`               // Amount of ILYZ tokens staked
+    RegisteredStake token.Amount `json:"registeredStake"`     // Stake at registration, before any bonding or slashing
+    Bonded          token.Amount `json:"bonded"`              // Part of the stake bonded by transactions, which the validator may unbond
+    Unbonding       []Unbonding  `json:"unbonding,omitempty"` // Stake unbonded but not yet released, oldest first
+    Delegated       token.Amount `json:"delegated"`           // Stake delegated to the validator by token holders
+    Commission      float64      `json:"commission"`          // Share of its delegators' rewards the validator keeps
+    PlayScore       float64      `json:"playScore"`           // Score based on game participation
+    LastActivity    int64        `json:"lastActivity"`        // Timestamp of last activity
+    IsGameNode      bool         `json:"isGameNode"`          // Whether this is a game server node
+    Jailed          bool         `json:"jailed"`              // Removed from the active set for misbehaving
+    Candidate       bool         `json:"candidate,omitempty"` // Ranked outside the active set at the start of the epoch
+    
+    // Downtime jailing, from the finalized blocks the validator neither produced nor voted on
+    MissedBlocks      int   `json:"missedBlocks"`      // Consecutive finalized blocks missed
+    JailedForDowntime bool  `json:"jailedForDowntime"` // Out of the active set until it unjails
+    UnjailTime        int64 `json:"unjailTime"`        // Block time from which it may unjail
+    DowntimeOffenses  int   `json:"downtimeOffenses"`  // Times jailed for downtime, each doubling the jail time
+    
+    // Last commission change; the rate changes at most once a day
+    CommissionUpdated int64 `json:"commissionUpdated,omitempty"` // Block time the commission was last changed
+    
+    // On-chain registration; validators configured off chain have none
+    ConsensusKey     string `json:"consensusKey,omitempty"`     // Hex public key the validator proved it holds
+    RegisteredAt     int64  `json:"registeredAt,omitempty"`     // Block the registration was committed in
+    ActivationHeight int64  `json:"activationHeight,omitempty"` // First block of the epoch the validator joined in
+    
+    // Consensus keys rotated to, oldest first; each signs from its height on, and earlier keys are kept
+    // to verify what they signed
+    KeyHistory []KeyChange `json:"keyHistory,omitempty"`
+}
+
+// NewProofOfPlay creates a new Proof of Play consensus mechanism
+func NewProofOfPlay() *ProofOfPlay {
+    return &ProofOfPlay{
+        MinValidators:       3,
+        FinalityThreshold:   67,
+        SlashFraction:       DefaultSlashFraction,
+        UnbondingPeriod:     DefaultUnbondingPeriod,
+        RoundTimeout:        DefaultRoundTimeout,
+        DowntimeThreshold:   DefaultDowntimeThreshold,
+        DowntimeJail:        DefaultDowntimeJail,
+        MinValidatorStake:   DefaultMinValidatorStake,
+        EpochLength:         DefaultEpochLength,
+        MaxEvidenceAge:      DefaultMaxEvidenceAge,
+        MaxCommissionChange: DefaultMaxCommissionChange,
+        MinDeposit:          DefaultMinDeposit,
+        VotingPeriod:        DefaultVotingPeriod,
+        Quorum:              DefaultQuorum,
+        PassThreshold:       DefaultPassThreshold,
+        MaxValidators:       DefaultMaxValidators,
+        Economy:             token.DefaultEconomicParams(),
+        MinLockPeriod:       DefaultMinLockPeriod,
+        MaxLockPeriod:       DefaultMaxLockPeriod,
+        UnlockDelay:         DefaultUnlockDelay,
+        PlayModes:           DefaultPlayModes(),
+        validators:          []Validator{},
+        votes:               make(map[BlockRef]*BlockVotes),
+        rounds:              make(map[int64]*heightRounds),
+        headers:             make(map[headerKey]core.BlockHeader),
+        evidence:            make(map[string]*CommittedEvidence),
+        evidencePool:        make(map[string]*PooledEvidence),
+        delegations:         make(map[delegationKey]*Delegation),
+        locks:               make(map[string]*StakeLock),
+        attested:            make(map[attestationKey]bool),
+        playCredits:         make(map[playCreditKey]float64),
+        liveness:            make(map[string]*livenessRecord),
+        proposals:           make(map[string]*Proposal),
+        priorities:          make(map[string]float64),
+        priorityHeight:      1,
+        schedules:           make(map[int64][]string),
+    }
+}
+
+// RegisterValidator adds a validator configured off chain, such as a genesis validator, to the consensus mechanism
+// Every node must register the same ones; other validators join through registration transactions
+// A validator recovered from the store is already registered and keeps its recovered state
+func (pop *ProofOfPlay) RegisterValidator(address string, stake token.Amount, isGameNode bool) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+    
+    if pop.validator(address) != nil {
+        return
+    }
+    
+    validator := Validator{
+        Address:         address,
+        Stake:           stake,
+        RegisteredStake: stake,
+        Commission:      DefaultCommission,
+        PlayScore:       0,
+        LastActivity:    pop.now(),
+        IsGameNode:      isGameNode,
+    }
+    
+    pop.validators = append(pop.validators, validator)
+}
+
+// SelectBlockProducer selects a validator to produce the next block
+// Selection is weighted by stake and play score
+func (pop *ProofOfPlay) SelectBlockProducer() (string, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+    
+    if len(pop.validators) < pop.MinValidators {
+        return "", errors.New("not enough validators")
+    }
+    
+    // Calculate total weight (stake * play score)
+    totalWeight := 0.0
+    weights := make([]float64, len(pop.validators))
+    
+    for i, validator := range pop.validators {
+        // Inactive validators (no activity in last 24 hours) and jailed validators have zero weight
+        if pop.now()-validator.LastActivity > 86400 || !validator.Active() {
+            weights[i] = 0
+            continue
+        }
+        
+        // Game nodes have higher base weight
+        baseWeight := 1.0
+        if validator.IsGameNode {
+            baseWeight = 2.0
+        }
+        
+        // Weight = base * stake * (1 + play score) * (1 + staked NFT boost)
+        weight := baseWeight * validator.VotingWeight()
+        if pop.Boosts != nil {
+            weight *= 1 + pop.Boosts.ValidatorBoost(validator.Address)
+        }
+        weights[i] = weight
+        totalWeight += weight
+    }
+    
+    if totalWeight <= 0 {
+        return "", errors.New("no active validators with positive weight")
+    }
+    
+    // Select validator based on weight
+    rand.Seed(time.Now().UnixNano())
+    selection := rand.Float64() * totalWeight
+    
+    cumulativeWeight := 0.0
+    for i, weight := range weights {
+        cumulativeWeight += weight
+        if cumulativeWeight >= selection {
+            return pop.validators[i].Address, nil
+        }
+    }
+    
+    // Fallback to first validator (should never happen)
+    return pop.validators[0].Address, nil
+}
+
+// ValidateBlock checks if a block is valid according to consensus rules
+func (pop *ProofOfPlay) ValidateBlock(blockData []byte, producerAddress string, signature string) bool {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+    
+    // Verify the block producer is an active validator
+    if validator := pop.validator(producerAddress); validator == nil || !validator.Active() {
+        return false
+    }
+    
+    // TODO: Implement signature verification
+    // For now, we'll use a simple hash check as placeholder
+    expectedHash := sha256.Sum256(blockData)
+    expectedHashStr := hex.EncodeToString(expectedHash[:])
+    
+    return signature == expectedHashStr
+}
+
+// validator returns the registered validator with an address, or nil
+// The caller must hold the lock
+func (pop *ProofOfPlay) validator(address string) *Validator {
+    for i := range pop.validators {
+        if pop.validators[i].Address == address {
+            return &pop.validators[i]
+        }
+    }
+    
+    return nil
+}
@@ -0,0 +1,148 @@
+package consensus
+
+import (
+    "math"
+    "sort"
+)
+
+// ScheduleRetention is how many heights' proposer schedules are kept for checking blocks already applied
+const ScheduleRetention = 1000
+
+// newValidatorPriority is the priority a validator entering the active set starts at, as a multiple of the total weight,
+// so leaving and rejoining the set can't move a validator up the queue
+const newValidatorPriority = -1.125
+
+// weightedValidator is an active validator and the weight it is scheduled by
+type weightedValidator struct {
+    address string
+    weight  float64
+}
+
+// scheduledValidators returns the active validators with positive weight, by address
+func scheduledValidators(validators []Validator) []weightedValidator {
+    scheduled := []weightedValidator{}
+    for _, validator := range validators {
+        if weight := validator.ConsensusWeight(); validator.Active() && weight > 0 {
+            scheduled = append(scheduled, weightedValidator{address: validator.Address, weight: weight})
+        }
+    }
+    sort.Slice(scheduled, func(i, j int) bool {
+        return scheduled[i].address < scheduled[j].address
+    })
+
+    return scheduled
+}
+
+// normalizePriorities fits proposer priorities to the active validators: validators that left lose theirs,
+// new ones start behind, and the priorities are centered on zero and kept within twice the total weight of each other
+func normalizePriorities(priorities map[string]float64, scheduled []weightedValidator) map[string]float64 {
+    total := 0.0
+    for _, validator := range scheduled {
+        total += validator.weight
+    }
+
+    normalized := make(map[string]float64, len(scheduled))
+    if len(scheduled) == 0 {
+        return normalized
+    }
+
+    sum := 0.0
+    for _, validator := range scheduled {
+        priority, exists := priorities[validator.address]
+        if !exists {
+            priority = newValidatorPriority * total
+        }
+        normalized[validator.address] = priority
+        sum += priority
+    }
+
+    average := sum / float64(len(scheduled))
+    lowest, highest := math.Inf(1), math.Inf(-1)
+    for _, validator := range scheduled {
+        normalized[validator.address] -= average
+        lowest = math.Min(lowest, normalized[validator.address])
+        highest = math.Max(highest, normalized[validator.address])
+    }
+    if spread := highest - lowest; spread > 2*total {
+        for _, validator := range scheduled {
+            normalized[validator.address] *= 2 * total / spread
+        }
+    }
+
+    return normalized
+}
+
+// proposerStep raises every active validator's priority by its weight and picks the highest, ties going to the lowest address,
+// which then drops by the total weight
+// Over many steps each validator is picked in proportion to its weight, and never far from its share
+func proposerStep(priorities map[string]float64, scheduled []weightedValidator) string {
+    total := 0.0
+    winner := ""
+    for _, validator := range scheduled {
+        priorities[validator.address] += validator.weight
+        total += validator.weight
+        if winner == "" || priorities[validator.address] > priorities[winner] {
+            winner = validator.address
+        }
+    }
+    if winner != "" {
+        priorities[winner] -= total
+    }
+
+    return winner
+}
+
+// proposerOrder returns the order validators may propose in at a height, from the priorities the height starts with:
+// the winner of a step first, for round 0, then the others by their priority after it, so a proposer that is down
+// hands each later round to someone else
+// The priorities are left unchanged
+func proposerOrder(priorities map[string]float64, scheduled []weightedValidator) []string {
+    stepped := make(map[string]float64, len(priorities))
+    for address, priority := range priorities {
+        stepped[address] = priority
+    }
+
+    first := proposerStep(stepped, scheduled)
+    if first == "" {
+        return []string{}
+    }
+
+    order := []string{first}
+    for _, validator := range scheduled {
+        if validator.address != first {
+            order = append(order, validator.address)
+        }
+    }
+    sort.SliceStable(order[1:], func(i, j int) bool {
+        return stepped[order[1+i]] > stepped[order[1+j]]
+    })
+
+    return order
+}
+
+// advancePriorities records the proposer schedule of the height after a block and moves the priorities past it
+// Each height steps the priorities once, for its first round, whichever round its block was produced in
+// The caller must hold the lock
+func (pop *ProofOfPlay) advancePriorities(height int64) {
+    if pop.priorityHeight > height+1 {
+        return
+    }
+
+    scheduled := scheduledValidators(pop.validators)
+    for pop.priorityHeight < height+1 {
+        pop.priorities = normalizePriorities(pop.priorities, scheduled)
+        proposerStep(pop.priorities, scheduled)
+        pop.priorityHeight++
+    }
+
+    pop.priorities = normalizePriorities(pop.priorities, scheduled)
+    pop.schedules[height+1] = proposerOrder(pop.priorities, scheduled)
+    proposerStep(pop.priorities, scheduled)
+    pop.priorityHeight = height + 2
+
+    for scheduledHeight := range pop.schedules {
+        if scheduledHeight <= height+1-ScheduleRetention {
+            delete(pop.schedules, scheduledHeight)
+        }
+    }
+}
@@ -0,0 +1,189 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeRegisterValidator registers the sender as a validator from the next epoch
+// Amount: stake locked from the sender's wallet, at least the minimum validator stake
+// Data: consensusKey, the hex public key the validator signs blocks and votes with;
+// proof, that key's signature of RegistrationProofPayload, proving the sender holds it;
+// gameNode, whether the validator runs a game server; commission, the starting commission rate, 10% if left out
+const TxTypeRegisterValidator = "validator_register"
+
+// DefaultMinValidatorStake is the least stake a validator can register with on chain
+const DefaultMinValidatorStake = 1000 * token.ILYZ
+
+// DefaultEpochLength is how many blocks an epoch lasts; validators registered during one join at the start of the next
+const DefaultEpochLength = 100
+
+// RegistrationProofPayload returns the bytes a consensus key signs to prove it is held by a registering validator
+func RegistrationProofPayload(address string, consensusKey string) ([]byte, error) {
+    return core.CanonicalEncode(struct {
+        Purpose      string `json:"purpose"`
+        Address      string `json:"address"`
+        ConsensusKey string `json:"consensusKey"`
+    }{
+        Purpose:      TxTypeRegisterValidator,
+        Address:      address,
+        ConsensusKey: consensusKey,
+    })
+}
+
+// SignRegistrationProof returns a key pair's proof of possession for registering an address as a validator
+func SignRegistrationProof(address string, keyPair *crypto.KeyPair) (string, error) {
+    payload, err := RegistrationProofPayload(address, crypto.PublicKeyToHex(keyPair.PublicKey))
+    if err != nil {
+        return "", err
+    }
+
+    return keyPair.Sign(payload)
+}
+
+// GetPendingValidators returns the validators registered on chain that join at the start of the next epoch, by address
+func (pop *ProofOfPlay) GetPendingValidators() []Validator {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    pending := make([]Validator, 0, len(pop.pending))
+    for _, validator := range pop.pending {
+        pending = append(pending, validator.clone())
+    }
+    sort.Slice(pending, func(i, j int) bool {
+        return pending[i].Address < pending[j].Address
+    })
+
+    return pending
+}
+
+// NextEpoch returns the first height of the epoch after the one a height is in
+func (pop *ProofOfPlay) NextEpoch(height int64) int64 {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.nextEpoch(height)
+}
+
+// nextEpoch returns the first height of the epoch after the one a height is in
+// The caller must hold the lock
+func (pop *ProofOfPlay) nextEpoch(height int64) int64 {
+    if pop.EpochLength <= 0 {
+        return height + 1
+    }
+
+    return (height/pop.EpochLength + 1) * pop.EpochLength
+}
+
+// checkRegistration validates a registration transaction
+// Balances are only known when the transaction is applied, so a stake the sender can't afford passes here
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkRegistration(tx core.Transaction) error {
+    if pop.validator(tx.Sender) != nil || pop.pendingValidator(tx.Sender) != nil {
+        return errors.New("validator already registered")
+    }
+    if tx.Amount < pop.MinValidatorStake {
+        return fmt.Errorf("stake must be at least %s", pop.MinValidatorStake)
+    }
+
+    data, _ := tx.Data.(map[string]interface{})
+    consensusKey, _ := data["consensusKey"].(string)
+    proof, _ := data["proof"].(string)
+    if consensusKey == "" || proof == "" {
+        return errors.New("consensus key and proof of possession are required")
+    }
+    if rate, set := data["commission"]; set {
+        if rate, ok := rate.(float64); !ok || rate < 0 || rate > 1 {
+            return errors.New("commission rate must be between 0 and 1")
+        }
+    }
+
+    publicKey, err := crypto.HexToPublicKey(consensusKey)
+    if err != nil {
+        return fmt.Errorf("invalid consensus key: %w", err)
+    }
+
+    // Blocks and votes are checked against the address of the key that signed them
+    if crypto.GetAddressFromPublicKey(publicKey) != tx.Sender {
+        return errors.New("consensus key does not match the validator address")
+    }
+
+    payload, err := RegistrationProofPayload(tx.Sender, consensusKey)
+    if err != nil {
+        return err
+    }
+
+    valid, err := crypto.Verify(payload, proof, publicKey)
+    if err != nil || !valid {
+        return errors.New("invalid proof of possession")
+    }
+
+    return nil
+}
+
+// applyRegistration executes a checked registration transaction, queuing the validator for the next epoch
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyRegistration(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    // A sender who can't cover the stake registers nothing; the stake is held in the stake escrow like a bond
+    if state.Transfer(tx.Sender, StakeEscrowAddress, tx.Amount) != nil {
+        return
+    }
+
+    data, _ := tx.Data.(map[string]interface{})
+    consensusKey, _ := data["consensusKey"].(string)
+    gameNode, _ := data["gameNode"].(bool)
+    commission, set := data["commission"].(float64)
+    if !set {
+        commission = DefaultCommission
+    }
+
+    pop.pending = append(pop.pending, Validator{
+        Address:          tx.Sender,
+        Stake:            tx.Amount,
+        RegisteredStake:  tx.Amount,
+        Bonded:           tx.Amount,
+        Commission:       commission,
+        LastActivity:     header.Timestamp,
+        IsGameNode:       gameNode,
+        Candidate:        true,
+        ConsensusKey:     consensusKey,
+        RegisteredAt:     header.Index,
+        ActivationHeight: pop.nextEpoch(header.Index),
+    })
+}
+
+// activatePending moves the validators whose epoch starts with the next block into the validator set,
+// as candidates for the active set
+// The caller must hold the lock
+func (pop *ProofOfPlay) activatePending(height int64) {
+    waiting := []Validator{}
+    for _, validator := range pop.pending {
+        if validator.ActivationHeight > height+1 {
+            waiting = append(waiting, validator)
+            continue
+        }
+        pop.validators = append(pop.validators, validator)
+    }
+
+    if len(waiting) == 0 {
+        waiting = nil
+    }
+    pop.pending = waiting
+}
+
+// pendingValidator returns a validator registered on chain that hasn't joined yet, or nil
+// The caller must hold the lock
+func (pop *ProofOfPlay) pendingValidator(address string) *Validator {
+    for i := range pop.pending {
+        if pop.pending[i].Address == address {
+            return &pop.pending[i]
+        }
+    }
+
+    return nil
+}
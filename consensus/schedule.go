@@ -0,0 +1,122 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// DefaultRoundTimeout is how long the first round at a height lasts before the next proposer may propose, in seconds
+const DefaultRoundTimeout = 10
+
+// ProposerSchedule returns the order in which validators may propose the block at a height
+// The round-0 proposer is first; if it doesn't produce before its round times out, the next in line may, and so on
+// The order comes from proposer priorities that each validator's weight raises every height and proposing lowers,
+// so proposing rotates among the validators in proportion to their weight instead of in runs
+// Weights count only stake and delegations, which every node agrees on; play scores are tracked per node
+func (pop *ProofOfPlay) ProposerSchedule(height int64) []string {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.schedule(height)
+}
+
+// Proposer returns the validator allowed to propose the block at a height in a round
+// Rounds past the end of the schedule wrap around to its start
+func (pop *ProofOfPlay) Proposer(height int64, round int64) (string, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if len(pop.validators) < pop.MinValidators {
+        return "", errors.New("not enough validators")
+    }
+
+    schedule := pop.schedule(height)
+    if len(schedule) == 0 {
+        return "", fmt.Errorf("no validators scheduled to propose at height %d", height)
+    }
+
+    return schedule[round%int64(len(schedule))], nil
+}
+
+// ProposerRound returns the round reached a number of seconds after the parent block
+// Each round lasts twice as long as the one before, up to a cap, so validators with drifting clocks still meet in a round
+func (pop *ProofOfPlay) ProposerRound(parentTime int64, now int64) int64 {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.proposerRound(parentTime, now)
+}
+
+// CheckProposer reports whether a block's producer was scheduled to propose it on top of its parent
+// Any proposer of a round up to the one reached at the block's time is accepted,
+// and the block's time may run at most one round ahead of the local clock
+func (pop *ProofOfPlay) CheckProposer(header core.BlockHeader, parent core.BlockHeader, now int64) error {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if header.Timestamp < parent.Timestamp {
+        return errors.New("block is older than its parent")
+    }
+    if header.Timestamp > now+pop.RoundTimeout {
+        return errors.New("block is from the future")
+    }
+
+    schedule := pop.schedule(header.Index)
+    if len(schedule) == 0 {
+        return fmt.Errorf("no validators scheduled to propose at height %d", header.Index)
+    }
+
+    rounds := pop.proposerRound(parent.Timestamp, header.Timestamp) + 1
+    if rounds > int64(len(schedule)) {
+        rounds = int64(len(schedule))
+    }
+    for round := int64(0); round < rounds; round++ {
+        if schedule[round] == header.Validator {
+            return nil
+        }
+    }
+
+    return fmt.Errorf("%s is not scheduled to propose block %d by round %d", header.Validator, header.Index, rounds-1)
+}
+
+// schedule returns the order the active validators may propose in at a height, one round each
+// Heights already scheduled return the recorded schedule, and later ones step the priorities forward
+// with the current validators; heights whose schedule was pruned have none
+// The caller must hold the lock
+func (pop *ProofOfPlay) schedule(height int64) []string {
+    if schedule, recorded := pop.schedules[height]; recorded {
+        return append([]string(nil), schedule...)
+    }
+    if height < pop.priorityHeight {
+        return nil
+    }
+
+    scheduled := scheduledValidators(pop.validators)
+    priorities := pop.priorities
+    for h := pop.priorityHeight; h < height; h++ {
+        priorities = normalizePriorities(priorities, scheduled)
+        proposerStep(priorities, scheduled)
+    }
+
+    return proposerOrder(normalizePriorities(priorities, scheduled), scheduled)
+}
+
+// proposerRound returns the round reached a number of seconds after the parent block
+// The caller must hold the lock
+func (pop *ProofOfPlay) proposerRound(parentTime int64, now int64) int64 {
+    if now <= parentTime || pop.RoundTimeout <= 0 {
+        return 0
+    }
+
+    elapsed := now - parentTime
+    round := int64(0)
+    for round < maxRoundDoublings && elapsed >= pop.roundDuration(round) {
+        elapsed -= pop.roundDuration(round)
+        round++
+    }
+
+    // Past the cap every round lasts the same
+    return round + elapsed/pop.roundDuration(round)
+}
@@ -0,0 +1,105 @@
+package consensus
+
+import (
+    "encoding/json"
+    "errors"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Season event kinds
+const (
+    EventKindSeasonStart = "season_start"
+    EventKindSeasonEnd   = "season_end"
+)
+
+// SeasonEvent is the payload of a season chain event, published with the first block at or after the season's
+// start or end time so game backends can switch their rewards and ladders
+type SeasonEvent struct {
+    Kind   string       `json:"kind"`
+    Season token.Season `json:"season"`
+}
+
+// recordSeasonTransitions records an event for each season that started or ended between the previous block's time
+// and a block's, ends first; nothing is recorded for the first block after genesis, which has no previous time
+// The caller must hold the lock
+func (pop *ProofOfPlay) recordSeasonTransitions(previous int64, header core.BlockHeader) {
+    if previous == 0 {
+        return
+    }
+
+    for _, season := range pop.Economy.Seasons {
+        if season.End > previous && season.End <= header.Timestamp {
+            pop.recordSeasonEvent(EventKindSeasonEnd, season, header)
+        }
+    }
+    for _, season := range pop.Economy.Seasons {
+        if season.Start > previous && season.Start <= header.Timestamp {
+            pop.recordSeasonEvent(EventKindSeasonStart, season, header)
+        }
+    }
+}
+
+// recordSeasonEvent records a season chain event for a block
+// The caller must hold the lock
+func (pop *ProofOfPlay) recordSeasonEvent(kind string, season token.Season, header core.BlockHeader) {
+    pop.events = append(pop.events, core.ChainEvent{
+        Type:        core.EventSeason,
+        BlockHeight: header.Index,
+        Kind:        kind,
+        Data:        SeasonEvent{Kind: kind, Season: season},
+    })
+}
+
+// checkProposalSeason checks that a season a proposal adds is valid, hasn't ended and fits the calendar
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkProposalSeason(season *token.Season) error {
+    if season == nil {
+        return nil
+    }
+
+    if err := season.Validate(); err != nil {
+        return err
+    }
+    if season.End <= pop.blockTime {
+        return errors.New("season has already ended")
+    }
+
+    return token.ValidateSeasons(token.WithSeason(pop.Economy.Seasons, *season))
+}
+
+// applySeason adds a season an accepted proposal asks for to the calendar, replacing any with its ID,
+// unless it overlaps a season added since the proposal was submitted
+// The calendar configured at startup is kept first, so a rebuild from genesis starts from it again
+// The caller must hold the lock
+func (pop *ProofOfPlay) applySeason(season token.Season) {
+    calendar := token.WithSeason(pop.Economy.Seasons, season)
+    if token.ValidateSeasons(calendar) != nil {
+        return
+    }
+
+    pop.keepConfigured()
+    pop.Economy.Seasons = calendar
+}
+
+// proposalSeason reads the season a proposal transaction adds, nil if it adds none
+func proposalSeason(tx core.Transaction) (*token.Season, error) {
+    data, _ := tx.Data.(map[string]interface{})
+    raw, exists := data["season"]
+    if !exists {
+        return nil, nil
+    }
+
+    encoded, err := json.Marshal(raw)
+    if err != nil {
+        return nil, err
+    }
+
+    var season token.Season
+    if err := json.Unmarshal(encoded, &season); err != nil {
+        return nil, errors.New("invalid season")
+    }
+
+    return &season, nil
+}
@@ -0,0 +1,105 @@
+package consensus
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// SettingsName is the name the consensus engine's settings are kept under in genesis, the state and proposals
+const SettingsName = "consensus"
+
+// Settings are the consensus engine's settings genesis sets and governance replaces
+// Governed parameters and seasons are only set by genesis; proposals change them through their changes and seasons
+type Settings struct {
+    // How each game mode's activity is scored, overriding the defaults for ranked, casual and tournament matches
+    PlayModes map[string]PlayMode `json:"playModes,omitempty"`
+
+    // Values the governed parameters start from, by the names proposals change them by; the rest keep their defaults
+    Params map[string]float64 `json:"params,omitempty"`
+
+    // Competitive seasons multiplying the game rewards of their modes while they run
+    Seasons []token.Season `json:"seasons,omitempty"`
+}
+
+// SettingsName returns the name the engine's settings are kept under
+func (pop *ProofOfPlay) SettingsName() string {
+    return SettingsName
+}
+
+// ValidateSettings reports whether settings name and score every game mode usably and hold valid parameters and seasons
+func (pop *ProofOfPlay) ValidateSettings(settings json.RawMessage) error {
+    _, err := decodeSettings(settings)
+    return err
+}
+
+// ApplySettings scores game modes by the defaults overridden by the settings' play modes, and starts the governed
+// parameters and season calendar from the settings' values
+func (pop *ProofOfPlay) ApplySettings(settings json.RawMessage) {
+    decoded, err := decodeSettings(settings)
+    if err != nil {
+        return
+    }
+
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    pop.PlayModes = DefaultPlayModes()
+    for name, mode := range decoded.PlayModes {
+        pop.PlayModes[name] = mode
+    }
+    for name, value := range decoded.Params {
+        governedParams[name].set(pop, value)
+    }
+    if decoded.Seasons != nil {
+        pop.Economy.Seasons = decoded.Seasons
+    }
+}
+
+// decodeSettings reads and validates the consensus engine's settings
+func decodeSettings(settings json.RawMessage) (Settings, error) {
+    var decoded Settings
+    if err := json.Unmarshal(settings, &decoded); err != nil {
+        return Settings{}, fmt.Errorf("invalid consensus settings: %w", err)
+    }
+
+    for name, mode := range decoded.PlayModes {
+        if name == "" {
+            return Settings{}, errors.New("mode name is required")
+        }
+        if err := mode.Validate(); err != nil {
+            return Settings{}, fmt.Errorf("mode %q: %w", name, err)
+        }
+    }
+    for name, value := range decoded.Params {
+        param, governed := governedParams[name]
+        if !governed {
+            return Settings{}, fmt.Errorf("unknown parameter %q", name)
+        }
+        if !param.valid(value) {
+            return Settings{}, fmt.Errorf("invalid value %v for parameter %q", value, name)
+        }
+    }
+    if err := token.ValidateSeasons(decoded.Seasons); err != nil {
+        return Settings{}, fmt.Errorf("invalid seasons: %w", err)
+    }
+
+    return decoded, nil
+}
+
+// configurable returns the module whose settings are kept under a name, this engine included, nil if there is none
+func (pop *ProofOfPlay) configurable(name string) core.Configurable {
+    if name == SettingsName {
+        return pop
+    }
+    for _, module := range pop.Configurables {
+        if module.SettingsName() == name {
+            return module
+        }
+    }
+
+    return nil
+}
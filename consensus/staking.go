@@ -0,0 +1,271 @@
+package consensus
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Staking transaction types
+const (
+    TxTypeStakeLock   = "stake_lock"   // Amount: tokens locked; Data: period, seconds the lock lasts; validator, optionally, to delegate the lock to
+    TxTypeStakeUnlock = "stake_unlock" // Data: lock, the ID of a lock whose period is over; its tokens return after the unlock delay
+)
+
+// StakeLockEscrowAddress is the account holding locked tokens until their unlock delay is over
+const StakeLockEscrowAddress = "stake_locks"
+
+// Default staking settings, in seconds
+const (
+    DefaultMinLockPeriod = 7 * 24 * 60 * 60   // Shortest period tokens can be locked for
+    DefaultMaxLockPeriod = 365 * 24 * 60 * 60 // Longest period tokens can be locked for
+    DefaultUnlockDelay   = 3 * 24 * 60 * 60   // Time unlocked tokens wait before returning to the wallet
+)
+
+// StakeLock is tokens an address has locked for a period, earning from the staking reward pool in proportion
+// to the amount and the period; a lock delegated to a validator also backs it and shares in its rewards
+// like a delegation, and is slashed with it
+type StakeLock struct {
+    ID          string       `json:"id"` // ID of the transaction that made the lock
+    Owner       string       `json:"owner"`
+    Amount      token.Amount `json:"amount"`
+    Period      int64        `json:"period"`
+    Validator   string       `json:"validator,omitempty"`
+    LockedAt    int64        `json:"lockedAt"`              // Block time the lock was made
+    UnlocksAt   int64        `json:"unlocksAt"`             // Block time from which it can be unlocked
+    Rewards     token.Amount `json:"rewards"`               // Paid to the owner so far
+    ReleaseTime int64        `json:"releaseTime,omitempty"` // Block time the tokens return to the wallet, once unlocked
+}
+
+// Unlocking reports whether a lock has been unlocked and is waiting for its tokens to return
+func (l StakeLock) Unlocking() bool {
+    return l.ReleaseTime > 0
+}
+
+// weight returns a lock's share of the staking reward pool before it is divided by the total
+func (l StakeLock) weight() float64 {
+    return l.Amount.Float() * float64(l.Period)
+}
+
+// GetStakeLocks returns an address's locks, unlocking ones included, oldest first
+func (pop *ProofOfPlay) GetStakeLocks(owner string) []StakeLock {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    locks := []StakeLock{}
+    for _, lock := range pop.sortedLocks() {
+        if lock.Owner == owner {
+            locks = append(locks, *lock)
+        }
+    }
+
+    return locks
+}
+
+// TotalStaked returns the tokens staked with the engine: validator stakes, the stake delegated to them
+// and locks delegated to none, leaving out tokens on their way back to wallets
+func (pop *ProofOfPlay) TotalStaked() token.Amount {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.totalStaked()
+}
+
+// totalStaked returns the tokens staked with the engine
+// The caller must hold the lock
+func (pop *ProofOfPlay) totalStaked() token.Amount {
+    staked := token.Amount(0)
+    for _, validator := range pop.validators {
+        staked += validator.Stake + validator.Delegated
+    }
+    for _, lock := range pop.locks {
+        if lock.Validator == "" && !lock.Unlocking() {
+            staked += lock.Amount
+        }
+    }
+
+    return staked
+}
+
+// checkStaking validates a lock or unlock transaction
+// Balances are only known when the transaction is applied, so a lock the sender can't afford passes here
+// The caller must hold the lock
+func (pop *ProofOfPlay) checkStaking(tx core.Transaction, now int64) error {
+    data, _ := tx.Data.(map[string]interface{})
+
+    switch tx.Type {
+    case TxTypeStakeLock:
+        if tx.Amount <= 0 {
+            return errors.New("amount must be positive")
+        }
+        period, _ := data["period"].(float64)
+        if !wholeNumber(period) || int64(period) < pop.MinLockPeriod || int64(period) > pop.MaxLockPeriod {
+            return fmt.Errorf("lock period must be between %d and %d seconds", pop.MinLockPeriod, pop.MaxLockPeriod)
+        }
+        if _, exists := pop.locks[tx.ID]; exists {
+            return errors.New("lock has already been made")
+        }
+
+        if address, _ := data["validator"].(string); address != "" {
+            validator := pop.validator(address)
+            if validator == nil {
+                return errors.New("validator not registered")
+            }
+            if address == tx.Sender {
+                return errors.New("validators bond their own stake rather than delegate it")
+            }
+            if !validator.Active() {
+                return errors.New("validator has been removed from the active set")
+            }
+        }
+
+    case TxTypeStakeUnlock:
+        id, _ := data["lock"].(string)
+        lock, exists := pop.locks[id]
+        if !exists || lock.Owner != tx.Sender {
+            return errors.New("lock not found")
+        }
+        if lock.Unlocking() {
+            return errors.New("lock is already unlocking")
+        }
+        if now < lock.UnlocksAt {
+            return fmt.Errorf("lock can't be unlocked until %d", lock.UnlocksAt)
+        }
+    }
+
+    return nil
+}
+
+// applyStaking executes a checked lock or unlock transaction
+// The caller must hold the lock
+func (pop *ProofOfPlay) applyStaking(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    data, _ := tx.Data.(map[string]interface{})
+
+    switch tx.Type {
+    case TxTypeStakeLock:
+        // An owner who can't cover the lock locks nothing
+        if state.Transfer(tx.Sender, StakeLockEscrowAddress, tx.Amount) != nil {
+            return
+        }
+
+        period, _ := data["period"].(float64)
+        address, _ := data["validator"].(string)
+        pop.locks[tx.ID] = &StakeLock{
+            ID:        tx.ID,
+            Owner:     tx.Sender,
+            Amount:    tx.Amount,
+            Period:    int64(period),
+            Validator: address,
+            LockedAt:  header.Timestamp,
+            UnlocksAt: header.Timestamp + int64(period),
+        }
+        if validator := pop.validator(address); validator != nil {
+            validator.Delegated += tx.Amount
+        }
+
+    case TxTypeStakeUnlock:
+        id, _ := data["lock"].(string)
+        lock := pop.locks[id]
+        lock.ReleaseTime = header.Timestamp + pop.UnlockDelay
+        if validator := pop.validator(lock.Validator); validator != nil {
+            validator.Delegated -= lock.Amount
+        }
+    }
+}
+
+// payStakingPool pays the fees waiting in the staking reward pool to the locks that aren't unlocking,
+// in proportion to their amount and period; the pool keeps what rounding leaves, and everything while nothing is locked
+// The caller must hold the lock
+func (pop *ProofOfPlay) payStakingPool(state *core.State) {
+    pool := state.Balances[core.StakingPoolAddress]
+    if pool <= 0 {
+        return
+    }
+
+    earning := []*StakeLock{}
+    total := 0.0
+    for _, lock := range pop.sortedLocks() {
+        if !lock.Unlocking() && lock.Amount > 0 {
+            earning = append(earning, lock)
+            total += lock.weight()
+        }
+    }
+    if total <= 0 {
+        return
+    }
+
+    for _, lock := range earning {
+        reward := pool.MulRate(lock.weight() / total)
+        if reward <= 0 || state.Transfer(core.StakingPoolAddress, lock.Owner, reward) != nil {
+            continue
+        }
+        lock.Rewards += reward
+    }
+}
+
+// releaseUnlocked returns the tokens of locks whose unlock delay is over by a block time from the escrow
+// to their owners' wallets; a lock the escrow can't cover stays until it can
+// The caller must hold the lock
+func (pop *ProofOfPlay) releaseUnlocked(timestamp int64, state *core.State) {
+    for _, lock := range pop.sortedLocks() {
+        if !lock.Unlocking() || lock.ReleaseTime > timestamp {
+            continue
+        }
+        if lock.Amount > 0 && state.Transfer(StakeLockEscrowAddress, lock.Owner, lock.Amount) != nil {
+            continue
+        }
+        delete(pop.locks, lock.ID)
+    }
+}
+
+// slashLocks takes the slash fraction of the locks delegated to a misbehaving validator, unlocking ones included,
+// and returns the total taken
+// The caller must hold the lock
+func (pop *ProofOfPlay) slashLocks(validator *Validator) token.Amount {
+    slashed := token.Amount(0)
+
+    for _, lock := range pop.sortedLocks() {
+        if lock.Validator != validator.Address {
+            continue
+        }
+
+        cut := lock.Amount.MulRate(pop.SlashFraction)
+        lock.Amount -= cut
+        if !lock.Unlocking() {
+            validator.Delegated -= cut
+        }
+        slashed += cut
+    }
+
+    return slashed
+}
+
+// locksWith returns the locks delegated to a validator that aren't unlocking, by ID
+// The caller must hold the lock
+func (pop *ProofOfPlay) locksWith(validator string) []*StakeLock {
+    locks := []*StakeLock{}
+    for _, lock := range pop.sortedLocks() {
+        if lock.Validator == validator && !lock.Unlocking() {
+            locks = append(locks, lock)
+        }
+    }
+
+    return locks
+}
+
+// sortedLocks returns every lock by ID, so sums and payouts over them agree on every node
+// The caller must hold the lock
+func (pop *ProofOfPlay) sortedLocks() []*StakeLock {
+    locks := make([]*StakeLock, 0, len(pop.locks))
+    for _, lock := range pop.locks {
+        locks = append(locks, lock)
+    }
+    sort.Slice(locks, func(i, j int) bool {
+        return locks[i].ID < locks[j].ID
+    })
+
+    return locks
+}
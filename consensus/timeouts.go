@@ -0,0 +1,94 @@
+package consensus
+
+import "time"
+
+// maxRoundDoublings caps how far round timeouts grow (64 times the first)
+const maxRoundDoublings = 6
+
+// RoundMetrics counts the rounds that timed out without a block being proposed or finalized
+type RoundMetrics struct {
+    SkippedRounds     int64 `json:"skippedRounds"`
+    LastSkippedHeight int64 `json:"lastSkippedHeight"`
+    LastSkippedRound  int64 `json:"lastSkippedRound"`
+    MaxRound          int64 `json:"maxRound"` // Highest round any height has been skipped to
+}
+
+// RoundDuration returns how long a round lasts in seconds: the round timeout, doubled each round up to a cap
+func (pop *ProofOfPlay) RoundDuration(round int64) int64 {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.roundDuration(round)
+}
+
+// roundDuration returns how long a round lasts in seconds
+// The caller must hold the lock
+func (pop *ProofOfPlay) roundDuration(round int64) int64 {
+    if round > maxRoundDoublings {
+        round = maxRoundDoublings
+    }
+
+    return pop.RoundTimeout << round
+}
+
+// RecordSkippedRound counts a round that timed out at a height, moving voting on to the next round
+func (pop *ProofOfPlay) RecordSkippedRound(height int64, round int64) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    pop.roundMetrics.SkippedRounds++
+    pop.roundMetrics.LastSkippedHeight = height
+    pop.roundMetrics.LastSkippedRound = round
+    if round+1 > pop.roundMetrics.MaxRound {
+        pop.roundMetrics.MaxRound = round + 1
+    }
+}
+
+// GetRoundMetrics returns the counts of rounds skipped since the node started
+func (pop *ProofOfPlay) GetRoundMetrics() RoundMetrics {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.roundMetrics
+}
+
+// RoundAhead reports whether more than 1/3 of the weight has voted in a round at a height
+// At least one honest validator is then in that round, so a validator behind can safely skip to it
+func (pop *ProofOfPlay) RoundAhead(height int64, round int64) bool {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    rounds, exists := pop.rounds[height]
+    if !exists {
+        return false
+    }
+
+    voted := make(map[string]bool)
+    for _, step := range []string{StepPrevote, StepPrecommit} {
+        for address := range rounds.votes[roundStep{round: round, step: step}] {
+            voted[address] = true
+        }
+    }
+
+    weight, total := 0.0, 0.0
+    for _, validator := range pop.validators {
+        if !validator.Active() {
+            continue
+        }
+        total += validator.ConsensusWeight()
+        if voted[validator.Address] {
+            weight += validator.ConsensusWeight()
+        }
+    }
+
+    return total > 0 && weight*3 > total
+}
+
+// now returns the current Unix time by the engine's clock
+func (pop *ProofOfPlay) now() int64 {
+    if pop.Clock != nil {
+        return pop.Clock().Unix()
+    }
+
+    return time.Now().Unix()
+}
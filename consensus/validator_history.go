@@ -0,0 +1,100 @@
+package consensus
+
+import (
+    "fmt"
+    "reflect"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// ValidatorSetRetention is how many epochs' validator sets are kept for queries by height
+const ValidatorSetRetention = 1000
+
+// epochValidators is the validator set as an epoch started
+type epochValidators struct {
+    height     int64 // First block of the epoch
+    validators []Validator
+}
+
+// GetValidatorSetAtHeight returns the validators as they stood when the epoch containing a height started,
+// or the current validators for the next block
+// Heights older than the retained epochs return core.ErrStatePruned, heights beyond the next block core.ErrStateNotAvailable
+func (pop *ProofOfPlay) GetValidatorSetAtHeight(height int64) (ValidatorSet, error) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if height > pop.blockHeight+1 {
+        return ValidatorSet{}, fmt.Errorf("%w: height %d is beyond the next block %d", core.ErrStateNotAvailable, height, pop.blockHeight+1)
+    }
+
+    validators, found := pop.validatorsAt(height)
+    if !found {
+        return ValidatorSet{}, fmt.Errorf("%w: validator set at height %d is older than the retained epochs", core.ErrStatePruned, height)
+    }
+
+    set := newValidatorSet(validators, nil)
+    if height == pop.blockHeight+1 {
+        set = newValidatorSet(validators, pop.pending)
+    }
+
+    return set, nil
+}
+
+// validatorsAt returns the validators a block at a height is checked against: the current ones for blocks
+// after the last applied, and the set its epoch started with for earlier blocks
+// It reports false if that epoch's set has been pruned
+// The caller must hold the lock
+func (pop *ProofOfPlay) validatorsAt(height int64) ([]Validator, bool) {
+    if height > pop.blockHeight {
+        return pop.validators, true
+    }
+
+    history := pop.validatorHistory
+    i := sort.Search(len(history), func(i int) bool {
+        return history[i].height > height
+    })
+    if i == 0 {
+        // Before any epoch was recorded, the engine hasn't replayed a block since it was created
+        return pop.validators, len(history) == 0
+    }
+
+    return history[i-1].validators, true
+}
+
+// recordValidatorSet records the validators an epoch starting at a height begins with,
+// unless they are unchanged since the last epoch, and drops the oldest beyond the retention
+// The caller must hold the lock
+func (pop *ProofOfPlay) recordValidatorSet(height int64) {
+    validators := make([]Validator, len(pop.validators))
+    for i, validator := range pop.validators {
+        validators[i] = validator.clone()
+    }
+
+    if n := len(pop.validatorHistory); n > 0 && reflect.DeepEqual(pop.validatorHistory[n-1].validators, validators) {
+        return
+    }
+
+    pop.validatorHistory = append(pop.validatorHistory, epochValidators{height: height, validators: validators})
+    if len(pop.validatorHistory) > ValidatorSetRetention {
+        pop.validatorHistory = pop.validatorHistory[len(pop.validatorHistory)-ValidatorSetRetention:]
+    }
+}
+
+// wasValidator reports whether an address was in the validator set at a height,
+// or is in the current one if that height's set has been pruned
+// The caller must hold the lock
+func (pop *ProofOfPlay) wasValidator(address string, height int64) bool {
+    validators, found := pop.validatorsAt(height)
+    if !found {
+        return pop.validator(address) != nil
+    }
+
+    for _, validator := range validators {
+        if validator.Address == address {
+            return true
+        }
+    }
+
+    return false
+}
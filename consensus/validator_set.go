@@ -0,0 +1,78 @@
+package consensus
+
+import "sort"
+
+// ValidatorSet is a read-only snapshot of the validators, taken at one moment
+// It shares nothing with the consensus engine, so it can be read while the engine keeps changing
+type ValidatorSet struct {
+    Validators  []Validator `json:"validators"`  // Registered validators, by address
+    Pending     []Validator `json:"pending"`     // Validators registered on chain that join at the next epoch, by address
+    ActiveCount int         `json:"activeCount"` // Validators in the active set
+    TotalWeight float64     `json:"totalWeight"` // Consensus weight of the active set
+}
+
+// ValidatorSet returns a snapshot of the registered and pending validators
+func (pop *ProofOfPlay) ValidatorSet() ValidatorSet {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return newValidatorSet(pop.validators, pop.pending)
+}
+
+// newValidatorSet returns a snapshot of validators and pending validators, sharing no memory with them
+func newValidatorSet(validators []Validator, pending []Validator) ValidatorSet {
+    set := ValidatorSet{
+        Validators: make([]Validator, 0, len(validators)),
+        Pending:    make([]Validator, 0, len(pending)),
+    }
+    for _, validator := range validators {
+        set.Validators = append(set.Validators, validator.clone())
+        if validator.Active() {
+            set.ActiveCount++
+            set.TotalWeight += validator.ConsensusWeight()
+        }
+    }
+    for _, validator := range pending {
+        set.Pending = append(set.Pending, validator.clone())
+    }
+
+    sort.Slice(set.Validators, func(i, j int) bool {
+        return set.Validators[i].Address < set.Validators[j].Address
+    })
+    sort.Slice(set.Pending, func(i, j int) bool {
+        return set.Pending[i].Address < set.Pending[j].Address
+    })
+
+    return set
+}
+
+// Get returns the validator in the set with an address
+func (s ValidatorSet) Get(address string) (Validator, bool) {
+    i := sort.Search(len(s.Validators), func(i int) bool {
+        return s.Validators[i].Address >= address
+    })
+    if i == len(s.Validators) || s.Validators[i].Address != address {
+        return Validator{}, false
+    }
+
+    return s.Validators[i], true
+}
+
+// Active returns the validators in the set's active set, by address
+func (s ValidatorSet) Active() []Validator {
+    active := []Validator{}
+    for _, validator := range s.Validators {
+        if validator.Active() {
+            active = append(active, validator)
+        }
+    }
+
+    return active
+}
+
+// clone returns a copy of a validator that shares no memory with it
+func (v Validator) clone() Validator {
+    v.Unbonding = append([]Unbonding(nil), v.Unbonding...)
+    v.KeyHistory = append([]KeyChange(nil), v.KeyHistory...)
+    return v
+}
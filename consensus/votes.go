@@ -0,0 +1,338 @@
+package consensus
+
+import (
+    "errors"
+    "sort"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// ErrDuplicateVote is returned for a vote the validator has already cast, which has nothing new to count or gossip
+var ErrDuplicateVote = errors.New("vote already recorded")
+
+// BlockRef identifies a block by height and hash, so competing blocks at one height are told apart
+type BlockRef struct {
+    Height int64  `json:"height"`
+    Hash   string `json:"hash"`
+}
+
+// Vote is one validator's vote on a block
+// Votes cast through CastVote are signed, so conflicting votes can be proven against the validator
+// A vote with a step is a prevote or precommit in a round of the two-phase vote that finalizes a height;
+// one without is an approval vote, tallied but not deciding finality
+type Vote struct {
+    Validator string `json:"validator"`
+    Height    int64  `json:"height"`
+    Round     int64  `json:"round,omitempty"`
+    Step      string `json:"step,omitempty"`      // StepPrevote, StepPrecommit or empty for an approval vote
+    BlockHash string `json:"blockHash"`           // Empty for a prevote or precommit of nil
+    Approve   bool   `json:"approve"`
+    Timestamp int64  `json:"timestamp"`
+    PublicKey string `json:"publicKey,omitempty"` // Validator's hex public key, needed to verify the signature
+    Signature string `json:"signature,omitempty"`
+}
+
+// BlockVotes holds the votes cast on one block, one per validator
+type BlockVotes struct {
+    Block BlockRef        `json:"block"`
+    Votes map[string]Vote `json:"votes"` // By validator address
+}
+
+// Tally is the stake-weighted result of the votes on a block
+// Weights are those of the validators at the time of the tally; votes by validators no longer active don't count
+type Tally struct {
+    Block         BlockRef `json:"block"`
+    Approvals     int      `json:"approvals"`
+    Rejections    int      `json:"rejections"`
+    ApproveWeight float64  `json:"approveWeight"`
+    RejectWeight  float64  `json:"rejectWeight"`
+    TotalWeight   float64  `json:"totalWeight"` // Weight of every active validator, voting or not
+    Approval      float64  `json:"approval"`    // Percentage of the total weight that approves
+    Approved      bool     `json:"approved"`    // Whether the approval meets the finality threshold
+    Final         bool     `json:"final"`       // Whether the block was decided by more than 2/3 of the weight precommitting it
+}
+
+// VotingWeight returns a validator's weight in votes, its own and delegated stake scaled by its play score
+func (v Validator) VotingWeight() float64 {
+    return (v.Stake + v.Delegated).Float() * (1 + v.PlayScore)
+}
+
+// VoteForBlock records a validator's vote for the block with a given height and hash
+// A validator votes once per height and round; a later vote at the same height is rejected
+func (pop *ProofOfPlay) VoteForBlock(height int64, blockHash string, validatorAddress string, approve bool) error {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    validator := pop.validator(validatorAddress)
+    if validator == nil {
+        return errors.New("validator not registered")
+    }
+    if validator.Jailed {
+        return errors.New("validator has been removed from the active set")
+    }
+    if blockHash == "" {
+        return errors.New("block hash is required")
+    }
+
+    if earlier, voted := pop.approvalVote(validatorAddress, height, 0); voted {
+        if earlier.BlockHash == blockHash && earlier.Approve == approve {
+            return ErrDuplicateVote
+        }
+        return errors.New("validator has already voted at this height")
+    }
+
+    vote := Vote{
+        Validator: validatorAddress,
+        Height:    height,
+        BlockHash: blockHash,
+        Approve:   approve,
+        Timestamp: time.Now().Unix(),
+    }
+    pop.addApprovalVote(vote)
+
+    return nil
+}
+
+// CastVote records a signed vote, one per validator for each height, round and step
+// A vote that conflicts with one the validator signed earlier in the same round is not counted;
+// instead the evidence against the validator is returned, to be committed on chain
+// A repeat of a vote already counted returns ErrDuplicateVote, and any other second vote in the round is rejected
+// Prevotes and precommits only count from active validators, and a precommit may decide its height
+func (pop *ProofOfPlay) CastVote(vote Vote) (*Evidence, error) {
+    if err := vote.verifySignature(); err != nil {
+        return nil, err
+    }
+
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if err := pop.checkSigner(vote.Validator, vote.PublicKey, vote.Height); err != nil {
+        return nil, err
+    }
+
+    validator := pop.validator(vote.Validator)
+    if validator == nil {
+        return nil, errors.New("validator not registered")
+    }
+    if validator.Jailed {
+        return nil, errors.New("validator has been removed from the active set")
+    }
+    if vote.Step != "" {
+        if !validator.Active() {
+            return nil, errors.New("validator is not in the active set")
+        }
+        if earlier, voted := pop.rounds[vote.Height].stepVote(vote); voted && earlier.BlockHash == vote.BlockHash {
+            return nil, ErrDuplicateVote
+        }
+        return pop.castStepVote(vote), nil
+    }
+
+    if earlier, voted := pop.approvalVote(vote.Validator, vote.Height, vote.Round); voted {
+        switch {
+        case earlier.Signature != "" && votesConflict(earlier, vote):
+            return newVoteEvidence(earlier, vote), nil
+        case earlier.BlockHash == vote.BlockHash && earlier.Approve == vote.Approve:
+            return nil, ErrDuplicateVote
+        }
+        return nil, errors.New("validator has already voted in this round")
+    }
+
+    pop.addApprovalVote(vote)
+
+    return nil, nil
+}
+
+// approvalVote returns the approval vote a validator cast in a round at a height, if any
+// The caller must hold the lock
+func (pop *ProofOfPlay) approvalVote(address string, height int64, round int64) (Vote, bool) {
+    for block, votes := range pop.votes {
+        if block.Height != height {
+            continue
+        }
+        if vote, voted := votes.Votes[address]; voted && vote.Round == round {
+            return vote, true
+        }
+    }
+
+    return Vote{}, false
+}
+
+// addApprovalVote records an approval vote on its block
+// The caller must hold the lock
+func (pop *ProofOfPlay) addApprovalVote(vote Vote) {
+    block := BlockRef{Height: vote.Height, Hash: vote.BlockHash}
+    votes, exists := pop.votes[block]
+    if !exists {
+        votes = &BlockVotes{Block: block, Votes: make(map[string]Vote)}
+        pop.votes[block] = votes
+    }
+    votes.Votes[vote.Validator] = vote
+    pop.recordVote(vote)
+}
+
+// HasConsensus checks if a block has been finalized, returning the percentage of validator weight that approves it
+func (pop *ProofOfPlay) HasConsensus(height int64, blockHash string) (bool, float64) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    tally := pop.tally(BlockRef{Height: height, Hash: blockHash})
+    return tally.Final, tally.Approval
+}
+
+// GetTally returns the weighted tally of the votes on a block
+func (pop *ProofOfPlay) GetTally(height int64, blockHash string) Tally {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return pop.tally(BlockRef{Height: height, Hash: blockHash})
+}
+
+// GetTallies returns the tallies of every block voted on at a height, most approved first
+func (pop *ProofOfPlay) GetTallies(height int64) []Tally {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    tallies := []Tally{}
+    for block := range pop.votes {
+        if block.Height == height {
+            tallies = append(tallies, pop.tally(block))
+        }
+    }
+
+    sort.Slice(tallies, func(i, j int) bool {
+        if tallies[i].ApproveWeight != tallies[j].ApproveWeight {
+            return tallies[i].ApproveWeight > tallies[j].ApproveWeight
+        }
+        return tallies[i].Block.Hash < tallies[j].Block.Hash
+    })
+
+    return tallies
+}
+
+// GetVotes returns the votes cast on a block, ordered by validator
+func (pop *ProofOfPlay) GetVotes(height int64, blockHash string) []Vote {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    votes := []Vote{}
+    if blockVotes, exists := pop.votes[BlockRef{Height: height, Hash: blockHash}]; exists {
+        for _, vote := range blockVotes.Votes {
+            votes = append(votes, vote)
+        }
+    }
+
+    sort.Slice(votes, func(i, j int) bool {
+        return votes[i].Validator < votes[j].Validator
+    })
+
+    return votes
+}
+
+// VoteRewards splits a reward pool among the validators whose precommits finalized a block, in proportion to their weight
+// It returns nothing for a block that has not reached finality
+func (pop *ProofOfPlay) VoteRewards(height int64, blockHash string, pool token.Amount) map[string]token.Amount {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    if !pop.finalized(BlockRef{Height: height, Hash: blockHash}) || pool <= 0 {
+        return nil
+    }
+
+    voters := []*Validator{}
+    total := 0.0
+    for _, precommit := range pop.rounds[height].decision.Precommits {
+        validator := pop.validator(precommit.Validator)
+        if validator == nil || !validator.Active() {
+            continue
+        }
+        voters = append(voters, validator)
+        total += validator.VotingWeight()
+    }
+    if total <= 0 {
+        return nil
+    }
+
+    rewards := make(map[string]token.Amount)
+    for _, validator := range voters {
+        rewards[validator.Address] = pool.MulRate(validator.VotingWeight() / total)
+    }
+
+    return rewards
+}
+
+// ResetVotes clears all votes
+func (pop *ProofOfPlay) ResetVotes() {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    pop.votes = make(map[BlockRef]*BlockVotes)
+    for height := range pop.rounds {
+        pop.deleteRounds(height)
+    }
+    pop.rounds = make(map[int64]*heightRounds)
+}
+
+// PruneVotes drops the votes, rounds and signed headers seen below a height, once those heights are final
+func (pop *ProofOfPlay) PruneVotes(height int64) {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    for block := range pop.votes {
+        if block.Height < height {
+            delete(pop.votes, block)
+        }
+    }
+    for at := range pop.rounds {
+        if at < height {
+            delete(pop.rounds, at)
+            pop.deleteRounds(at)
+        }
+    }
+    for key := range pop.headers {
+        if key.height < height {
+            delete(pop.headers, key)
+        }
+    }
+}
+
+// tally weighs the votes on a block by the current validator set
+// The caller must hold the lock
+func (pop *ProofOfPlay) tally(block BlockRef) Tally {
+    tally := Tally{Block: block}
+
+    weights := make(map[string]float64, len(pop.validators))
+    for _, validator := range pop.validators {
+        if !validator.Active() {
+            continue
+        }
+        weight := validator.VotingWeight()
+        weights[validator.Address] = weight
+        tally.TotalWeight += weight
+    }
+
+    if votes, exists := pop.votes[block]; exists {
+        for address, vote := range votes.Votes {
+            weight, registered := weights[address]
+            if !registered {
+                continue
+            }
+
+            if vote.Approve {
+                tally.Approvals++
+                tally.ApproveWeight += weight
+            } else {
+                tally.Rejections++
+                tally.RejectWeight += weight
+            }
+        }
+    }
+
+    if tally.TotalWeight > 0 {
+        tally.Approval = tally.ApproveWeight * 100 / tally.TotalWeight
+    }
+    tally.Approved = tally.ApproveWeight > 0 && tally.Approval >= float64(pop.FinalityThreshold)
+    tally.Final = pop.finalized(block)
+
+    return tally
+}
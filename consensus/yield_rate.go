@@ -0,0 +1,59 @@
+package consensus
+
+import (
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// YieldRateStatus is the yearly yield rate in effect, the staked share of the supply it was set from and the curve that set it
+type YieldRateStatus struct {
+    Rate        float64          `json:"rate"`
+    StakedRatio float64          `json:"stakedRatio"`
+    Curve       token.YieldCurve `json:"curve"`
+    Height      int64            `json:"height,omitempty"` // Block that started the epoch the rate was set for, 0 until one has
+}
+
+// GetYieldRate returns the yearly yield rate in effect and the staked share of the supply it was set from
+func (pop *ProofOfPlay) GetYieldRate() YieldRateStatus {
+    pop.mutex.Lock()
+    defer pop.mutex.Unlock()
+
+    return YieldRateStatus{
+        Rate:        pop.Economy.YieldRate,
+        StakedRatio: pop.stakedRatio,
+        Curve:       pop.Economy.YieldCurve,
+        Height:      pop.yieldHeight,
+    }
+}
+
+// updateYieldRate sets the yield rate for the epoch starting after a block from the share of the supply staked
+// or locked once the block is applied, by the yield curve
+// The rate configured at startup is kept first, so a rebuild from genesis starts from it again
+// The caller must hold the lock
+func (pop *ProofOfPlay) updateYieldRate(header core.BlockHeader, state *core.State) {
+    ratio := 0.0
+    if state.Supply > 0 {
+        ratio = pop.totalStaked().Float() / state.Supply.Float()
+    }
+    if ratio > 1 {
+        ratio = 1
+    }
+
+    pop.keepConfigured()
+    pop.stakedRatio = ratio
+    pop.yieldHeight = header.Index
+    pop.Economy.YieldRate = pop.Economy.YieldCurve.Rate(ratio)
+}
+
+// yieldCurveWith returns the yield curve as it would be with a proposal's changes applied, in name order
+// The caller must hold the lock
+func (pop *ProofOfPlay) yieldCurveWith(changes map[string]float64) token.YieldCurve {
+    proposed := &ProofOfPlay{Economy: pop.Economy}
+    for _, name := range []string{"yield_ceiling", "yield_floor", "yield_rate", "yield_target_ratio"} {
+        if value, changed := changes[name]; changed {
+            governedParams[name].set(proposed, value)
+        }
+    }
+
+    return proposed.Economy.YieldCurve
+}
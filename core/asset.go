@@ -0,0 +1,190 @@
+package core
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Asset transaction types; fees are paid in ILYZ whatever the asset
+const (
+    TxTypeGenesisAsset  = "genesis_asset"  // Genesis only; Data: asset, the token.Asset registered
+    TxTypeAssetMint     = "asset_mint"     // Sent by a minter; Amount: minted to the recipient; Data: asset, its ID
+    TxTypeAssetTransfer = "asset_transfer" // Amount: sent to the recipient; Data: asset, the ID of a transferable asset
+    TxTypeAssetBurn     = "asset_burn"     // Amount: destroyed from the sender's balance, such as when spent in game; Data: asset
+)
+
+// AssetInfo is an asset's definition and its supply as of a height
+type AssetInfo struct {
+    token.Asset
+    Supply token.Amount `json:"supply"`
+}
+
+// applyAsset registers, mints, transfers or burns an asset other than ILYZ by the rules genesis set for it
+// A transaction breaking the asset's rules, one the sender can't cover, or one the sender didn't sign does nothing,
+// since blocks from peers are applied without the admission checks
+func (s *State) applyAsset(tx Transaction) {
+    if tx.Type == TxTypeGenesisAsset {
+        asset, err := decodeAsset(tx)
+        if err != nil {
+            return
+        }
+        if s.Assets == nil {
+            s.Assets = make(map[string]token.Asset)
+        }
+        s.Assets[asset.ID] = asset
+        return
+    }
+
+    id := txAsset(tx)
+    asset, exists := s.Assets[id]
+    if !exists || tx.Amount <= 0 || VerifyTransactionSignature(tx) != nil {
+        return
+    }
+
+    switch tx.Type {
+    case TxTypeAssetMint:
+        if asset.CanMint(tx.Sender) && asset.CanSupply(s.AssetSupply[id], tx.Amount) {
+            s.MintAsset(id, tx.Recipient, tx.Amount)
+        }
+
+    case TxTypeAssetTransfer:
+        if asset.Transferable {
+            s.TransferAsset(id, tx.Sender, tx.Recipient, tx.Amount)
+        }
+
+    case TxTypeAssetBurn:
+        s.BurnAsset(id, tx.Sender, tx.Amount)
+    }
+}
+
+// GetAssets returns every asset registered beside ILYZ with its supply as of the latest block, by ID
+func (bc *Blockchain) GetAssets() []AssetInfo {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    state := bc.States.Latest()
+    ids := make([]string, 0, len(state.Assets))
+    for id := range state.Assets {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    assets := make([]AssetInfo, 0, len(ids))
+    for _, id := range ids {
+        assets = append(assets, AssetInfo{Asset: state.Assets[id], Supply: state.AssetSupply[id]})
+    }
+
+    return assets
+}
+
+// GetAsset returns an asset registered beside ILYZ with its supply as of the latest block
+func (bc *Blockchain) GetAsset(id string) (AssetInfo, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    state := bc.States.Latest()
+    asset, exists := state.Assets[id]
+    if !exists {
+        return AssetInfo{}, fmt.Errorf("asset %q not found", id)
+    }
+
+    return AssetInfo{Asset: asset, Supply: state.AssetSupply[id]}, nil
+}
+
+// GetAssetHoldingsAt returns an address's balance of every asset other than ILYZ as of the given height, by asset
+// Heights outside the retained state range return ErrStatePruned or ErrStateNotAvailable
+func (bc *Blockchain) GetAssetHoldingsAt(address string, height int64) (map[string]token.Amount, error) {
+    state, err := bc.States.StateAt(height)
+    if err != nil {
+        return nil, err
+    }
+
+    return state.AssetHoldings(address), nil
+}
+
+// checkAsset rejects an asset transaction its sender didn't sign or that breaks the asset's rules as of
+// the latest block; genesis registers every asset, so none can be registered afterwards
+// Balances are only known when the transaction is applied, so a transfer or burn the sender can't cover passes here
+// The caller must hold the lock
+func (bc *Blockchain) checkAsset(tx Transaction) error {
+    switch tx.Type {
+    case TxTypeGenesisAsset:
+        return errors.New("assets are registered at genesis")
+
+    case TxTypeAssetMint, TxTypeAssetTransfer, TxTypeAssetBurn:
+    default:
+        return nil
+    }
+
+    if err := VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+    if tx.Amount <= 0 {
+        return errors.New("amount must be positive")
+    }
+
+    state := bc.States.Latest()
+    id := txAsset(tx)
+    asset, exists := state.Assets[id]
+    if !exists {
+        return fmt.Errorf("asset %q not found", id)
+    }
+
+    switch tx.Type {
+    case TxTypeAssetMint:
+        if !asset.CanMint(tx.Sender) {
+            return fmt.Errorf("%s is not a minter of %s", tx.Sender, id)
+        }
+        if !asset.CanSupply(state.AssetSupply[id], tx.Amount) {
+            return fmt.Errorf("minting %s would take %s past its supply limit of %s", tx.Amount, id, asset.MaxSupply)
+        }
+
+    case TxTypeAssetTransfer:
+        if !asset.Transferable {
+            return fmt.Errorf("%s can't be transferred", id)
+        }
+    }
+
+    return nil
+}
+
+// assetTransaction builds the genesis transaction registering an asset
+func assetTransaction(asset token.Asset, timestamp int64) Transaction {
+    tx := Transaction{
+        Type:      TxTypeGenesisAsset,
+        Data:      map[string]interface{}{"asset": asset},
+        Timestamp: timestamp,
+    }
+    tx.ID = ComputeTransactionID(tx)
+
+    return tx
+}
+
+// decodeAsset reads the asset a genesis asset transaction registers
+func decodeAsset(tx Transaction) (token.Asset, error) {
+    data, _ := tx.Data.(map[string]interface{})
+
+    encoded, err := json.Marshal(data["asset"])
+    if err != nil {
+        return token.Asset{}, err
+    }
+
+    var asset token.Asset
+    if err := json.Unmarshal(encoded, &asset); err != nil {
+        return token.Asset{}, err
+    }
+
+    return asset, asset.Validate()
+}
+
+// txAsset returns the ID of the asset an asset transaction mints, transfers or burns
+func txAsset(tx Transaction) string {
+    data, _ := tx.Data.(map[string]interface{})
+    id, _ := data["asset"].(string)
+
+    return id
+}
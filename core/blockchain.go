@@ -0,0 +1,711 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// MaxHeadersPerRequest is the largest header range returned by a single GetHeadersRange call
+const MaxHeadersPerRequest = 2000
+
+// BlockHeader holds the fields of a block that are hashed and linked into the chain
+// Headers commit to their transactions through TxRoot, so they can be synced and verified without bodies
+type BlockHeader struct {
+    Index     int64  `json:"index"`
+    Timestamp int64  `json:"timestamp"`
+    Hash      string `json:"hash"`
+    PrevHash  string `json:"prevHash"`
+    TxRoot    string `json:"txRoot"`
+    Validator string `json:"validator"`
+    PublicKey string `json:"publicKey,omitempty"` // Producer's hex public key, needed to verify the signature
+    Signature string `json:"signature"`           // Producer's signature over the hash
+
+    // Version of the consensus parameters the block was produced under
+    ParamsVersion int64 `json:"paramsVersion,omitempty"`
+
+    // Least fee each of the block's transactions pays, set by the fee market from its parent's fullness
+    BaseFee token.Amount `json:"baseFee,omitempty"`
+}
+
+// BlockBody holds the transactions of a block
+type BlockBody struct {
+    Transactions []Transaction `json:"transactions"`
+}
+
+// Block represents a single block in the blockchain
+type Block struct {
+    BlockHeader
+    BlockBody
+}
+
+// Transaction represents a transaction in the blockchain
+type Transaction struct {
+    ID        string       `json:"id"`
+    Type      string       `json:"type"`
+    Sender    string       `json:"sender"`
+    Recipient string       `json:"recipient"`
+    Amount    token.Amount `json:"amount"`
+    Fee       token.Amount `json:"fee,omitempty"` // Paid by the sender to the block producer
+    Data      interface{}  `json:"data"`
+    Timestamp int64        `json:"timestamp"`
+    PublicKey string       `json:"publicKey,omitempty"` // Sender's hex public key, needed to verify the signature
+    Signature string       `json:"signature"`
+}
+
+// Blockchain represents the entire blockchain
+type Blockchain struct {
+    // Block headers indexed by height
+    Headers []BlockHeader `json:"headers"`
+
+    // Block bodies keyed by block hash, stored apart from headers
+    Bodies map[string]BlockBody `json:"bodies"`
+
+    PendingTransactions []Transaction
+    Difficulty          int
+    MiningReward        token.Amount
+    Nodes               []string
+
+    // Most minted each block for the validators who voted to finalize its parent
+    VoteRewardPool token.Amount
+
+    // How each block's fees are split when no fee policy source is set
+    FeePolicy FeePolicy
+
+    // Splits the vote reward pool among voters; when nil no vote rewards are paid
+    Rewarder VoteRewarder `json:"-"`
+
+    // Picks the canonical branch among competing ones; when nil the longest branch wins
+    ForkChoice ForkChoice `json:"-"`
+
+    // Reports which blocks are finalized, so match rewards are only paid for final results; when nil a result
+    // is paid in the block after it
+    Finality FinalitySource `json:"-"`
+
+    // Reports the consensus parameter version new blocks reference; when nil they reference none
+    Params ParamsSource `json:"-"`
+
+    // Reports the fee policy new blocks split their fees by; when nil FeePolicy applies
+    Fees FeePolicySource `json:"-"`
+
+    // Sets the base fee blocks' transactions must pay; when nil blocks have no base fee
+    FeeMarket *token.FeeMarket `json:"-"`
+
+    // Identifier of the network this chain belongs to
+    ChainID string `json:"chainId,omitempty"`
+
+    // Event hub notified of new pending transactions and blocks
+    Events *EventHub `json:"-"`
+
+    // State versions by height, retained according to the storage mode
+    States *StateStore `json:"-"`
+
+    // Runtime metrics updated as blocks are added and reverted
+    Metrics *ChainMetrics `json:"-"`
+
+    // Token economics used to cap block rewards; when nil the full MiningReward is paid
+    Economics *token.TokenEconomics `json:"-"`
+
+    // Reads the timestamp of the block being executed, so economics sharing it run on chain time during execution
+    Clock *token.BlockClock `json:"-"`
+
+    // Modules applied alongside the core state as blocks are executed
+    modules []Module
+
+    // Mutex for thread safety
+    mutex sync.RWMutex
+}
+
+// ChainConfig holds the settings chosen when a node is initialized
+type ChainConfig struct {
+    // Storage mode ("archive" or "full")
+    StorageMode string `json:"storageMode"`
+
+    // Number of recent state versions kept in full mode
+    StateRetention int64 `json:"stateRetention"`
+
+    // Genesis definition; when nil a throwaway genesis block is created
+    Genesis *Genesis `json:"-"`
+}
+
+// DefaultChainConfig returns the configuration used by NewBlockchain
+func DefaultChainConfig() ChainConfig {
+    return ChainConfig{
+        StorageMode:    StorageModeFull,
+        StateRetention: DefaultStateRetention,
+    }
+}
+
+// NewBlockchain creates a new blockchain with a genesis block
+func NewBlockchain() *Blockchain {
+    blockchain, _ := NewBlockchainWithConfig(DefaultChainConfig())
+    return blockchain
+}
+
+// NewBlockchainWithConfig creates a new blockchain with a genesis block using the given configuration
+func NewBlockchainWithConfig(config ChainConfig) (*Blockchain, error) {
+    states, err := NewStateStore(config.StorageMode, config.StateRetention)
+    if err != nil {
+        return nil, err
+    }
+
+    blockchain := &Blockchain{
+        Headers:             []BlockHeader{},
+        Bodies:              make(map[string]BlockBody),
+        PendingTransactions: []Transaction{},
+        Difficulty:          4,
+        MiningReward:        5 * token.ILYZ,
+        VoteRewardPool:      DefaultVoteRewardPool,
+        Nodes:               []string{},
+        Events:              NewEventHub(),
+        States:              states,
+        Metrics:             NewChainMetrics(),
+        Clock:               token.NewBlockClock(),
+    }
+
+    // Create genesis block
+    var genesisBlock Block
+    if config.Genesis != nil {
+        blockchain.ChainID = config.Genesis.ChainID
+        if config.Genesis.FeePolicy != nil {
+            blockchain.FeePolicy = *config.Genesis.FeePolicy
+        }
+        blockchain.FeeMarket = config.Genesis.FeeMarket
+        genesisBlock = config.Genesis.Block()
+    } else {
+        genesisBlock = Block{
+            BlockHeader: BlockHeader{
+                Index:     0,
+                Timestamp: time.Now().Unix(),
+                Hash:      "",
+                PrevHash:  "0",
+                TxRoot:    ComputeTxRoot([]Transaction{}),
+                Validator: "genesis",
+                Signature: "",
+            },
+            BlockBody: BlockBody{
+                Transactions: []Transaction{},
+            },
+        }
+        genesisBlock.Hash = blockchain.CalculateHash(genesisBlock)
+    }
+
+    genesisState := NewState()
+    executeBlock(genesisBlock, genesisState, nil)
+
+    blockchain.storeBlock(genesisBlock)
+    blockchain.States.Commit(genesisBlock.Index, genesisState)
+
+    return blockchain, nil
+}
+
+// CalculateHash calculates the hash of a block
+func (bc *Blockchain) CalculateHash(block Block) string {
+    return CalculateHeaderHash(block.BlockHeader)
+}
+
+// CalculateHeaderHash calculates the hash of a block header
+// Transactions are covered through the header's TxRoot
+func CalculateHeaderHash(header BlockHeader) string {
+    hash, _ := CanonicalHash(struct {
+        Index         int64        `json:"index"`
+        Timestamp     int64        `json:"timestamp"`
+        PrevHash      string       `json:"prevHash"`
+        TxRoot        string       `json:"txRoot"`
+        Validator     string       `json:"validator"`
+        ParamsVersion int64        `json:"paramsVersion,omitempty"`
+        BaseFee       token.Amount `json:"baseFee,omitempty"`
+    }{
+        Index:         header.Index,
+        Timestamp:     header.Timestamp,
+        PrevHash:      header.PrevHash,
+        TxRoot:        header.TxRoot,
+        Validator:     header.Validator,
+        ParamsVersion: header.ParamsVersion,
+        BaseFee:       header.BaseFee,
+    })
+
+    return hash
+}
+
+// ValidateBlock checks that a block's hash covers its header and its TxRoot covers its body
+func (bc *Blockchain) ValidateBlock(block Block) error {
+    if block.TxRoot != ComputeTxRoot(block.Transactions) {
+        return fmt.Errorf("block %d has a transaction root that does not match its body", block.Index)
+    }
+
+    if block.Hash != bc.CalculateHash(block) {
+        return fmt.Errorf("block %d has an invalid hash", block.Index)
+    }
+
+    for _, tx := range block.Transactions {
+        if err := ValidateTransaction(tx); err != nil {
+            return fmt.Errorf("block %d: %w", block.Index, err)
+        }
+    }
+
+    if block.Index > 0 {
+        if err := bc.validateCoinbase(block); err != nil {
+            return fmt.Errorf("block %d: %w", block.Index, err)
+        }
+        if err := bc.validateVoteReward(block); err != nil {
+            return fmt.Errorf("block %d: %w", block.Index, err)
+        }
+    }
+
+    return nil
+}
+
+// GetLatestBlock returns the latest block in the blockchain
+func (bc *Blockchain) GetLatestBlock() Block {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    return bc.blockAt(int64(len(bc.Headers) - 1))
+}
+
+// CreateTransaction creates a new transaction
+func (bc *Blockchain) CreateTransaction(transaction Transaction) error {
+    if err := ValidateTransaction(transaction); err != nil {
+        return err
+    }
+
+    if IsProducerTransaction(transaction) {
+        return errors.New("coinbase, vote reward and match reward transactions are created by block producers")
+    }
+
+    if IsGenesisTransaction(transaction) {
+        return errors.New("genesis transactions only appear in the genesis block")
+    }
+
+    if transaction.Fee < 0 {
+        return errors.New("transaction fee cannot be negative")
+    }
+
+    bc.mutex.Lock()
+    defer bc.mutex.Unlock()
+
+    if err := bc.checkVesting(transaction); err != nil {
+        return err
+    }
+
+    if err := bc.checkMatch(transaction); err != nil {
+        return err
+    }
+
+    if err := bc.checkAsset(transaction); err != nil {
+        return err
+    }
+
+    if err := bc.checkReferral(transaction); err != nil {
+        return err
+    }
+
+    if err := bc.checkTournament(transaction); err != nil {
+        return err
+    }
+
+    if baseFee := bc.nextBaseFee(); transaction.Fee < baseFee {
+        return fmt.Errorf("transaction fee %s is below the base fee of %s", transaction.Fee, baseFee)
+    }
+
+    if err := bc.authenticate(transaction); err != nil {
+        return err
+    }
+
+    if err := bc.checkFunds(transaction); err != nil {
+        return err
+    }
+
+    if err := bc.checkModules(transaction); err != nil {
+        return err
+    }
+
+    bc.PendingTransactions = append(bc.PendingTransactions, transaction)
+
+    bc.Events.Publish(transactionEvent(EventPendingTx, transaction, 0))
+
+    return nil
+}
+
+// CreateBlock creates a new block with pending transactions, signed with the producer's key pair if one is given
+func (bc *Blockchain) CreateBlock(validator string, keyPair *crypto.KeyPair) Block {
+    bc.mutex.Lock()
+    defer bc.mutex.Unlock()
+
+    latestHeader := bc.Headers[len(bc.Headers)-1]
+    height := latestHeader.Index + 1
+    timestamp := time.Now().Unix()
+
+    // Transactions admitted while the base fee was lower wait in the mempool until it falls back,
+    // and those their senders can no longer pay for are dropped, so the coinbase only counts fees that are burned,
+    // as are match reports from servers governance has since stopped
+    baseFee := bc.nextBaseFee()
+    included, waiting := splitByFee(bc.PendingTransactions, baseFee)
+    included = bc.fundedOnly(bc.reportedOnly(included))
+
+    // Pay the producer first, ahead of the transactions whose fees it collects, then the voters on the parent
+    // and the players of matches whose results are final
+    coinbase := bc.createCoinbase(validator, height, timestamp, included, baseFee)
+    transactions := []Transaction{coinbase}
+    if reward, ok := bc.createVoteReward(height, timestamp, coinbaseReward(coinbase)); ok {
+        transactions = append(transactions, reward)
+    }
+    transactions = append(transactions, bc.createMatchRewards(height, timestamp)...)
+    transactions = append(transactions, included...)
+
+    newBlock := Block{
+        BlockHeader: BlockHeader{
+            Index:         height,
+            Timestamp:     timestamp,
+            PrevHash:      latestHeader.Hash,
+            TxRoot:        ComputeTxRoot(transactions),
+            Validator:     validator,
+            ParamsVersion: bc.paramsVersion(),
+            BaseFee:       baseFee,
+        },
+        BlockBody: BlockBody{
+            Transactions: transactions,
+        },
+    }
+
+    newBlock.Hash = bc.CalculateHash(newBlock)
+    if keyPair != nil {
+        SignHeader(&newBlock.BlockHeader, keyPair)
+    }
+    bc.appendBlock(newBlock)
+    bc.PendingTransactions = waiting
+
+    return newBlock
+}
+
+// AddBlock validates a block received from a peer and appends it to the chain
+func (bc *Blockchain) AddBlock(block Block) error {
+    bc.mutex.Lock()
+    defer bc.mutex.Unlock()
+
+    if err := bc.validateNextBlock(block); err != nil {
+        return err
+    }
+
+    bc.appendBlock(block)
+
+    return nil
+}
+
+// validateNextBlock checks that a block is valid on its own, continues the local chain and agrees with the chain's
+// parameters, fees, rewards and balances, so blocks from peers and from exports are held to the same rules
+// The caller must hold the lock
+func (bc *Blockchain) validateNextBlock(block Block) error {
+    if err := bc.ValidateBlock(block); err != nil {
+        return err
+    }
+
+    latestHeader := bc.Headers[len(bc.Headers)-1]
+    if block.Index != latestHeader.Index+1 {
+        return fmt.Errorf("block %d does not continue the local chain at height %d", block.Index, latestHeader.Index)
+    }
+
+    if block.PrevHash != latestHeader.Hash {
+        return fmt.Errorf("block %d does not link to the local chain tip", block.Index)
+    }
+
+    if err := checkGenesisOnly(block); err != nil {
+        return err
+    }
+
+    if err := bc.checkParamsVersion(block); err != nil {
+        return err
+    }
+
+    if err := bc.checkBaseFee(block); err != nil {
+        return err
+    }
+
+    if err := bc.checkFeeSplit(block); err != nil {
+        return err
+    }
+
+    if err := bc.checkBlockReward(block); err != nil {
+        return err
+    }
+
+    if err := bc.checkMatchRewards(block); err != nil {
+        return err
+    }
+
+    if err := bc.checkMatchReports(block); err != nil {
+        return err
+    }
+
+    if err := bc.checkSenders(block); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+// GetPendingTransactionCount returns the number of transactions waiting in the mempool
+func (bc *Blockchain) GetPendingTransactionCount() int {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    return len(bc.PendingTransactions)
+}
+
+// appendBlock appends a block to the chain, executes it and notifies subscribers
+// The caller must hold the write lock
+func (bc *Blockchain) appendBlock(block Block) {
+    bc.storeBlock(block)
+
+    // Execute the block against the latest state and commit the new version, with economics on the block's time
+    bc.Clock.Enter(block.Timestamp)
+    state := bc.States.Latest().Copy()
+    moduleEvents := executeBlock(block, state, bc.modules)
+    bc.States.Commit(block.Index, state)
+    bc.syncSupply()
+    var yearTransitions []token.YearTransition
+    if bc.Economics != nil {
+        yearTransitions = bc.Economics.AdvanceYear(block.Timestamp)
+        bc.Economics.RecordBlockRewards(BlockRewards(block))
+    }
+    bc.settleMatchEscrows(block)
+    bc.syncBaseFee()
+    bc.Clock.Leave()
+    bc.Metrics.RecordBlock(block)
+
+    // Notify subscribers of the new head, every transaction it confirmed and what modules did
+    bc.Events.Publish(ChainEvent{
+        Type:        EventNewHead,
+        BlockHeight: block.Index,
+        Data:        block,
+    })
+    for _, tx := range block.Transactions {
+        bc.Events.Publish(transactionEvent(EventTx, tx, block.Index))
+    }
+    for _, event := range moduleEvents {
+        bc.Events.Publish(event)
+    }
+    for _, transition := range yearTransitions {
+        bc.Events.Publish(ChainEvent{
+            Type:        EventYear,
+            BlockHeight: block.Index,
+            Data:        transition,
+        })
+    }
+}
+
+// SetEconomics sets the token economics that cap block rewards and report the chain's supply,
+// reconciling economics restored from a store with the chain: the supply is the chain's, and the yearly minted count
+// covers at least the block rewards of the blocks since the year started
+func (bc *Blockchain) SetEconomics(economics *token.TokenEconomics) {
+    bc.mutex.Lock()
+    defer bc.mutex.Unlock()
+
+    bc.Economics = economics
+    bc.syncSupply()
+    bc.syncBaseFee()
+
+    yearStart := economics.GetYearStartTime()
+    rewards := token.Amount(0)
+    for _, header := range bc.Headers {
+        if header.Timestamp >= yearStart {
+            rewards += BlockRewards(bc.blockAt(header.Index))
+        }
+    }
+    economics.ReconcileBlockRewards(rewards)
+}
+
+// syncSupply reports the supply of the latest state's ledger to the token economics
+// The caller must hold the write lock
+func (bc *Blockchain) syncSupply() {
+    if bc.Economics != nil {
+        state := bc.States.Latest()
+        bc.Economics.SyncSupply(state.Supply, state.FeesBurned)
+    }
+}
+
+// storeBlock stores a block's header and body separately
+// The caller must hold the write lock
+func (bc *Blockchain) storeBlock(block Block) {
+    bc.Headers = append(bc.Headers, block.BlockHeader)
+    bc.Bodies[block.Hash] = block.BlockBody
+}
+
+// blockAt reassembles the block at a height from its header and body
+// The caller must hold the lock and check the height is in range
+func (bc *Blockchain) blockAt(height int64) Block {
+    header := bc.Headers[height]
+
+    return Block{
+        BlockHeader: header,
+        BlockBody:   bc.Bodies[header.Hash],
+    }
+}
+
+// IsChainValid checks if the blockchain is valid
+func (bc *Blockchain) IsChainValid() bool {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    for i := 1; i < len(bc.Headers); i++ {
+        currentBlock := bc.blockAt(int64(i))
+        prevBlock := bc.Headers[i-1]
+
+        // Check if the hash and transaction root are correct
+        if bc.ValidateBlock(currentBlock) != nil {
+            return false
+        }
+
+        // Check if the previous hash reference is correct
+        if currentBlock.PrevHash != prevBlock.Hash {
+            return false
+        }
+    }
+    return true
+}
+
+// GetBlockByHeight returns the block at the given height
+func (bc *Blockchain) GetBlockByHeight(height int64) (Block, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    if height < 0 || height >= int64(len(bc.Headers)) {
+        return Block{}, errors.New("block not found")
+    }
+
+    return bc.blockAt(height), nil
+}
+
+// GetHeaderByHeight returns the header of the block at the given height
+func (bc *Blockchain) GetHeaderByHeight(height int64) (BlockHeader, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    if height < 0 || height >= int64(len(bc.Headers)) {
+        return BlockHeader{}, errors.New("block not found")
+    }
+
+    return bc.Headers[height], nil
+}
+
+// GetHeadersRange returns the headers in [fromHeight, toHeight] for header-only sync
+// Ranges longer than MaxHeadersPerRequest and ranges past the tip are truncated
+func (bc *Blockchain) GetHeadersRange(fromHeight int64, toHeight int64) ([]BlockHeader, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    latestHeight := int64(len(bc.Headers) - 1)
+    if fromHeight < 0 || fromHeight > latestHeight || toHeight < fromHeight {
+        return nil, fmt.Errorf("invalid header range %d-%d (chain height is %d)", fromHeight, toHeight, latestHeight)
+    }
+
+    if toHeight > latestHeight {
+        toHeight = latestHeight
+    }
+    if toHeight-fromHeight+1 > MaxHeadersPerRequest {
+        toHeight = fromHeight + MaxHeadersPerRequest - 1
+    }
+
+    headers := make([]BlockHeader, toHeight-fromHeight+1)
+    copy(headers, bc.Headers[fromHeight:toHeight+1])
+
+    return headers, nil
+}
+
+// GetBody returns the body of the block with the given hash
+func (bc *Blockchain) GetBody(hash string) (BlockBody, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    body, exists := bc.Bodies[hash]
+    if !exists {
+        return BlockBody{}, errors.New("block body not found")
+    }
+
+    return body, nil
+}
+
+// GetTransactionByID returns a confirmed transaction and the height of the block containing it
+func (bc *Blockchain) GetTransactionByID(id string) (Transaction, int64, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    for _, header := range bc.Headers {
+        for _, tx := range bc.Bodies[header.Hash].Transactions {
+            if tx.ID == id {
+                return tx, header.Index, nil
+            }
+        }
+    }
+
+    return Transaction{}, 0, errors.New("transaction not found")
+}
+
+// GetTransactionsByAddress returns all confirmed transactions sent or received by an address, oldest first
+func (bc *Blockchain) GetTransactionsByAddress(address string) []Transaction {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    transactions := []Transaction{}
+
+    for _, header := range bc.Headers {
+        for _, tx := range bc.Bodies[header.Hash].Transactions {
+            if tx.Sender == address || tx.Recipient == address {
+                transactions = append(transactions, tx)
+            }
+        }
+    }
+
+    return transactions
+}
+
+// GetHeight returns the height of the latest block
+func (bc *Blockchain) GetHeight() int64 {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    return bc.Headers[len(bc.Headers)-1].Index
+}
+
+// GetBalanceAt returns an address's balance as of the given height
+// Heights outside the retained state range return ErrStatePruned or ErrStateNotAvailable
+func (bc *Blockchain) GetBalanceAt(address string, height int64) (token.Amount, error) {
+    state, err := bc.States.StateAt(height)
+    if err != nil {
+        return 0, err
+    }
+
+    return state.Balances[address], nil
+}
+
+// GetSupplyAt returns the token supply as of the given height
+// Heights outside the retained state range return ErrStatePruned or ErrStateNotAvailable
+func (bc *Blockchain) GetSupplyAt(height int64) (Supply, error) {
+    state, err := bc.States.StateAt(height)
+    if err != nil {
+        return Supply{}, err
+    }
+
+    return Supply{Height: height, Total: state.Supply, FeesBurned: state.FeesBurned}, nil
+}
+
+// GetStateCommitment returns the roots composing the state root at a height
+// Heights outside the retained state range return ErrStatePruned or ErrStateNotAvailable
+func (bc *Blockchain) GetStateCommitment(height int64) (StateCommitment, error) {
+    state, err := bc.States.StateAt(height)
+    if err != nil {
+        return StateCommitment{}, err
+    }
+
+    return state.Commitment(height), nil
+}
+
+// RegisterNode registers a new node in the network
+func (bc *Blockchain) RegisterNode(address string) {
+    bc.Nodes = append(bc.Nodes, address)
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+    "path/filepath"
+    "testing"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TestImportRejectsBlocksPeersWouldRefuse checks that an export holding a block a peer would refuse, here a transfer
+// its sender never signed, is not loaded even though every block hashes correctly
+func TestImportRejectsBlocksPeersWouldRefuse(t *testing.T) {
+    victim := newTestKey(t)
+    thief := newTestKey(t)
+    genesis := fundedGenesis(map[string]token.Amount{victim.address: 10 * token.ILYZ})
+
+    // The forger appends the block without validating it, as a tampered store would hold it
+    forger := newFundedChain(t, genesis)
+    theft := thief.transfer(t, thief.address, 9*token.ILYZ, token.ILYZ)
+    theft.Sender = victim.address
+    theft.ID = ComputeTransactionID(theft)
+    forger.appendBlock(unfilteredBlock(forger, "producer", []Transaction{theft}, []Transaction{theft}))
+    if forger.States.Latest().Balances[thief.address] != 9*token.ILYZ {
+        t.Fatal("forged transfer was not applied on the forging chain")
+    }
+
+    path := filepath.Join(t.TempDir(), "chain.export")
+    if err := forger.ExportChain(path, 0, forger.GetHeight()); err != nil {
+        t.Fatal(err)
+    }
+
+    bc := newFundedChain(t, genesis)
+    if _, err := bc.ImportChain(path); err == nil {
+        t.Fatal("export with an unsigned transfer was imported")
+    }
+    if bc.GetHeight() != 0 || bc.States.Latest().Balances[victim.address] != 10*token.ILYZ {
+        t.Fatalf("chain at height %d with the victim holding %s after the refused import",
+            bc.GetHeight(), bc.States.Latest().Balances[victim.address])
+    }
+}
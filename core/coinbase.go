@@ -0,0 +1,180 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeCoinbase is the transaction type that pays a block producer its reward and its share of the collected fees,
+// recording the shares paid to the master wallet, the staking reward pool and the treasury and burned
+const TxTypeCoinbase = "coinbase"
+
+// IsProducerTransaction reports whether a transaction is created by a block producer, a coinbase or vote reward,
+// rather than submitted by a user
+func IsProducerTransaction(tx Transaction) bool {
+    return tx.Type == TxTypeCoinbase || tx.Type == TxTypeVoteReward || tx.Type == TxTypeMatchReward
+}
+
+// BlockFees returns the total fees paid by a list of transactions
+// Blocks only include transactions their senders can pay for, so a block's fees are exactly those its execution burns
+func BlockFees(transactions []Transaction) token.Amount {
+    fees := token.Amount(0)
+    for _, tx := range transactions {
+        if !IsProducerTransaction(tx) {
+            fees += tx.Fee
+        }
+    }
+
+    return fees
+}
+
+// BlockRewards returns the new tokens a block's coinbase and vote reward mint, fees aside
+func BlockRewards(block Block) token.Amount {
+    rewards := token.Amount(0)
+    for _, tx := range block.Transactions {
+        switch tx.Type {
+        case TxTypeCoinbase:
+            rewards += coinbaseReward(tx)
+        case TxTypeVoteReward, TxTypeMatchReward:
+            rewards += tx.Amount
+        }
+    }
+
+    return rewards
+}
+
+// createCoinbase builds the coinbase for the next block from the transactions it will include and its base fee
+// The caller must hold the lock
+func (bc *Blockchain) createCoinbase(validator string, height int64, timestamp int64, transactions []Transaction, baseFee token.Amount) Transaction {
+    // Mint the block reward within the yearly supply cap
+    reward := bc.MiningReward
+    if bc.Economics != nil {
+        reward = bc.Economics.BlockRewardAllowance(reward, 0)
+    }
+
+    fees := BlockFees(transactions)
+    policy := bc.feePolicy()
+    split := bc.splitFees(transactions, baseFee)
+    treasuryReward := policy.TreasuryReward(reward)
+
+    coinbase := Transaction{
+        Type:      TxTypeCoinbase,
+        Recipient: validator,
+        Amount:    reward - treasuryReward + split.Producer,
+        Data: map[string]interface{}{
+            "height":         height,
+            "reward":         reward,
+            "fees":           fees,
+            "master":         split.Master,
+            "masterWallet":   policy.MasterWallet,
+            "staking":        split.Staking,
+            "treasury":       split.Treasury,
+            "treasuryReward": treasuryReward,
+            "burned":         split.Burned,
+        },
+        Timestamp: timestamp,
+    }
+    coinbase.ID = ComputeTransactionID(coinbase)
+
+    return coinbase
+}
+
+// validateCoinbase checks that a block opens with a single coinbase paying exactly the reward it records, at most
+// the block reward, plus the fees its recorded split leaves to its producer
+// Whether the split follows the fee policy and the reward fits the yearly cap depend on the chain the block continues,
+// so AddBlock checks those
+func (bc *Blockchain) validateCoinbase(block Block) error {
+    if len(block.Transactions) == 0 || block.Transactions[0].Type != TxTypeCoinbase {
+        return errors.New("block does not start with a coinbase transaction")
+    }
+
+    for _, tx := range block.Transactions[1:] {
+        if tx.Type == TxTypeCoinbase {
+            return errors.New("block has more than one coinbase transaction")
+        }
+    }
+
+    coinbase := block.Transactions[0]
+    if coinbase.Recipient != block.Validator {
+        return errors.New("coinbase does not pay the block producer")
+    }
+
+    if height, ok := txHeight(coinbase); !ok || height != block.Index {
+        return errors.New("coinbase is not bound to the block height")
+    }
+
+    fees := BlockFees(block.Transactions)
+    split, masterWallet := coinbaseSplit(coinbase)
+    if split.Master < 0 || split.Staking < 0 || split.Treasury < 0 || split.Burned < 0 {
+        return errors.New("coinbase records a negative share of the fees")
+    }
+    if split.Master > 0 && masterWallet == "" {
+        return errors.New("coinbase pays the master wallet's fees to no address")
+    }
+
+    producerFees := fees - split.Master - split.Staking - split.Treasury - split.Burned
+    if producerFees < 0 {
+        return fmt.Errorf("coinbase splits more than the fees of %v", fees)
+    }
+
+    // The treasury's share of the reward is minted alongside the coinbase amount, so both count toward the reward
+    treasuryReward := coinbaseTreasuryReward(coinbase)
+    if treasuryReward < 0 {
+        return errors.New("coinbase records a negative treasury share of the reward")
+    }
+
+    // The recorded reward is what the economics count as minted, so it must be what the coinbase actually mints
+    reward := coinbaseReward(coinbase)
+    if reward < 0 || reward > bc.MiningReward {
+        return fmt.Errorf("coinbase records a reward of %v, outside the block reward of %v", reward, bc.MiningReward)
+    }
+    if coinbase.Amount < 0 || coinbase.Amount+treasuryReward != reward+producerFees {
+        return fmt.Errorf("coinbase pays %v, not its recorded reward plus the producer's fees of %v", coinbase.Amount+treasuryReward, reward+producerFees)
+    }
+
+    return nil
+}
+
+// checkBlockReward reports whether the reward a block's coinbase records fits within what the yearly supply cap
+// still allows, as counted by the token economics
+// The caller must hold the lock
+func (bc *Blockchain) checkBlockReward(block Block) error {
+    if bc.Economics == nil {
+        return nil
+    }
+
+    reward := coinbaseReward(block.Transactions[0])
+    if ceiling := bc.Economics.BlockRewardCeiling(); reward > ceiling {
+        return fmt.Errorf("block %d mints a reward of %s, more than the %s the yearly supply cap allows", block.Index, reward, ceiling)
+    }
+
+    return nil
+}
+
+// coinbaseReward returns the block reward a coinbase records minting, fees aside
+func coinbaseReward(tx Transaction) token.Amount {
+    data, _ := tx.Data.(map[string]interface{})
+    reward, _ := token.DataAmount(data["reward"])
+
+    return reward
+}
+
+// txHeight returns the block height recorded in a coinbase or vote reward
+// Heights are int64 when built locally and float64 once decoded from JSON
+func txHeight(tx Transaction) (int64, bool) {
+    data, ok := tx.Data.(map[string]interface{})
+    if !ok {
+        return 0, false
+    }
+
+    switch height := data["height"].(type) {
+    case int64:
+        return height, true
+    case float64:
+        return int64(height), true
+    }
+
+    return 0, false
+}
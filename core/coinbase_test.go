@@ -0,0 +1,48 @@
+package core
+
+import (
+    "testing"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TestCoinbaseRewardMustMatchWhatItMints checks that a peer's block is refused when its coinbase records a reward
+// other than the one it mints, or one the yearly supply cap no longer allows
+func TestCoinbaseRewardMustMatchWhatItMints(t *testing.T) {
+    bc := newFundedChain(t, fundedGenesis(nil))
+
+    for _, recorded := range []token.Amount{0, bc.MiningReward + token.ILYZ} {
+        block := unfilteredBlock(bc, "producer", nil, nil)
+        block.Transactions[0].Data.(map[string]interface{})["reward"] = recorded
+        if err := bc.AddBlock(resealed(bc, block)); err == nil {
+            t.Fatalf("block minting %s while recording a reward of %s was accepted", bc.MiningReward, recorded)
+        }
+    }
+
+    economics := token.NewTokenEconomics("master")
+    bc.SetEconomics(economics)
+    block := unfilteredBlock(bc, "producer", nil, nil)
+
+    // Only one ILYZ is left under the cap by the time the block arrives
+    economics.YearlyMinted = economics.GetYearlySupplyCap() - token.ILYZ
+    if err := bc.AddBlock(block); err == nil {
+        t.Fatal("block minting past the yearly supply cap was accepted")
+    }
+
+    created := bc.CreateBlock("producer", nil)
+    if reward := coinbaseReward(created.Transactions[0]); reward != token.ILYZ {
+        t.Fatalf("producer minted %s, want the 1 ILYZ left under the cap", reward)
+    }
+    if bc.GetHeight() != 1 {
+        t.Fatalf("chain is at height %d, want the block within the cap appended", bc.GetHeight())
+    }
+}
+
+// resealed recomputes a block's coinbase ID, transaction root and hash after a test changed its transactions
+func resealed(bc *Blockchain, block Block) Block {
+    block.Transactions[0].ID = ComputeTransactionID(block.Transactions[0])
+    block.TxRoot = ComputeTxRoot(block.Transactions)
+    block.Hash = bc.CalculateHash(block)
+
+    return block
+}
@@ -0,0 +1,247 @@
+package core
+
+import (
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// RunRateWindow is the number of seconds of recent emission the year-end projection extrapolates
+const RunRateWindow = 30 * 24 * 60 * 60
+
+// Granularities emission can be reported by, each a calendar period of the block times in UTC
+const (
+    PeriodDay   = "day"
+    PeriodMonth = "month"
+    PeriodYear  = "year"
+)
+
+// periodLayouts names each reporting period by formatting a block time with its layout
+var periodLayouts = map[string]string{
+    PeriodDay:   "2006-01-02",
+    PeriodMonth: "2006-01",
+    PeriodYear:  "2006",
+}
+
+// StakeSource reports the tokens staked, such as with the consensus engine
+type StakeSource interface {
+    // TotalStaked returns the tokens currently staked
+    TotalStaked() token.Amount
+}
+
+// EmissionPeriod is what blocks in one calendar period minted and burned
+// Minted covers block rewards and any other tokens entering the supply; fees paid back out by coinbases aren't counted
+type EmissionPeriod struct {
+    Period string       `json:"period"` // Such as "2025-03-14", "2025-03" or "2025"
+    Minted token.Amount `json:"minted"`
+    Burned token.Amount `json:"burned"`
+}
+
+// SupplyReport is the health of the ILYZ supply as of the latest block
+type SupplyReport struct {
+    Height       int64        `json:"height"`
+    Supply       token.Amount `json:"supply"`
+    FeesBurned   token.Amount `json:"feesBurned"` // In total
+    Staked       token.Amount `json:"staked"`
+    StakingRatio float64      `json:"stakingRatio"` // Share of the supply staked
+
+    // Emission of the day, month and year the latest block falls in
+    Today     EmissionPeriod `json:"today"`
+    ThisMonth EmissionPeriod `json:"thisMonth"`
+    ThisYear  EmissionPeriod `json:"thisYear"`
+
+    // Net tokens entering the supply per day over the run-rate window, and the supply that rate reaches by the
+    // end of the calendar year
+    DailyRunRate           token.Amount `json:"dailyRunRate"`
+    ProjectedYearEndSupply token.Amount `json:"projectedYearEndSupply"`
+}
+
+// emissionSample is the net emission of one block, kept for the run-rate window
+type emissionSample struct {
+    timestamp int64
+    net       token.Amount
+}
+
+// EmissionReport is a module tallying what each block mints and burns by calendar period, so the supply's
+// growth can be monitored; being derived from the chain, it is rebuilt on rollbacks like any module
+type EmissionReport struct {
+    // Source of the staked total, nil to report nothing staked
+    Stakes StakeSource
+
+    periods map[string]map[string]*EmissionPeriod // By granularity, then period name
+    recent  []emissionSample
+
+    height     int64
+    timestamp  int64
+    first      int64 // Time of the first block after genesis
+    supply     token.Amount
+    feesBurned token.Amount
+
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// NewEmissionReport creates an empty emission report
+func NewEmissionReport() *EmissionReport {
+    report := &EmissionReport{}
+    report.reset()
+
+    return report
+}
+
+// CheckTransaction accepts every transaction; the report only observes blocks
+func (er *EmissionReport) CheckTransaction(tx Transaction) error {
+    return nil
+}
+
+// ApplyTransaction does nothing; a block's emission is measured from the state once it ends
+func (er *EmissionReport) ApplyTransaction(tx Transaction, header BlockHeader, state *State) {}
+
+// EndBlock tallies what a block minted and burned from how the supply and fees burned moved
+// The genesis block's allocations make up the starting supply rather than emission
+func (er *EmissionReport) EndBlock(header BlockHeader, state *State) {
+    er.mutex.Lock()
+    defer er.mutex.Unlock()
+
+    burned := state.FeesBurned - er.feesBurned
+    minted := state.Supply - er.supply + burned
+    er.height = header.Index
+    er.timestamp = header.Timestamp
+    er.supply = state.Supply
+    er.feesBurned = state.FeesBurned
+    if header.Index == 0 {
+        return
+    }
+    if er.first == 0 {
+        er.first = header.Timestamp
+    }
+
+    when := time.Unix(header.Timestamp, 0).UTC()
+    for granularity, layout := range periodLayouts {
+        name := when.Format(layout)
+        period, exists := er.periods[granularity][name]
+        if !exists {
+            period = &EmissionPeriod{Period: name}
+            er.periods[granularity][name] = period
+        }
+        period.Minted += minted
+        period.Burned += burned
+    }
+
+    er.recent = append(er.recent, emissionSample{timestamp: header.Timestamp, net: minted - burned})
+    expired := 0
+    for expired < len(er.recent) && er.recent[expired].timestamp <= header.Timestamp-RunRateWindow {
+        expired++
+    }
+    er.recent = er.recent[expired:]
+}
+
+// Reset clears the report before the chain replays blocks into it
+func (er *EmissionReport) Reset() {
+    er.mutex.Lock()
+    defer er.mutex.Unlock()
+
+    er.reset()
+}
+
+// Fork returns an empty report with the same stake source
+func (er *EmissionReport) Fork() Module {
+    forked := NewEmissionReport()
+    forked.Stakes = er.Stakes
+
+    return forked
+}
+
+// GetReport returns the health of the supply as of the latest block
+func (er *EmissionReport) GetReport() SupplyReport {
+    er.mutex.Lock()
+    report := SupplyReport{
+        Height:     er.height,
+        Supply:     er.supply,
+        FeesBurned: er.feesBurned,
+        Today:      er.current(PeriodDay),
+        ThisMonth:  er.current(PeriodMonth),
+        ThisYear:   er.current(PeriodYear),
+    }
+
+    // Extrapolate the window's net emission, or what there is of it on a young chain, to the end of the year
+    net := token.Amount(0)
+    for _, sample := range er.recent {
+        net += sample.net
+    }
+    span := int64(RunRateWindow)
+    if elapsed := er.timestamp - er.first; elapsed < span {
+        span = elapsed
+    }
+    if span > 0 {
+        year := time.Unix(er.timestamp, 0).UTC().Year()
+        yearEnd := time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+        report.DailyRunRate = net.Scale(24*60*60, token.Amount(span))
+        report.ProjectedYearEndSupply = er.supply + net.Scale(token.Amount(yearEnd-er.timestamp), token.Amount(span))
+    } else {
+        report.ProjectedYearEndSupply = er.supply
+    }
+    er.mutex.Unlock()
+
+    // The stake source has its own lock, so it is asked outside the report's
+    if er.Stakes != nil {
+        report.Staked = er.Stakes.TotalStaked()
+    }
+    if report.Supply > 0 {
+        report.StakingRatio = report.Staked.Float() / report.Supply.Float()
+    }
+
+    return report
+}
+
+// GetEmission returns what every day, month or year minted and burned, oldest first
+func (er *EmissionReport) GetEmission(granularity string) ([]EmissionPeriod, bool) {
+    er.mutex.Lock()
+    defer er.mutex.Unlock()
+
+    periods, known := er.periods[granularity]
+    if !known {
+        return nil, false
+    }
+
+    names := make([]string, 0, len(periods))
+    for name := range periods {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    emission := make([]EmissionPeriod, 0, len(names))
+    for _, name := range names {
+        emission = append(emission, *periods[name])
+    }
+
+    return emission, true
+}
+
+// current returns the emission of the period of a granularity the latest block falls in
+// The caller must hold the lock
+func (er *EmissionReport) current(granularity string) EmissionPeriod {
+    name := time.Unix(er.timestamp, 0).UTC().Format(periodLayouts[granularity])
+    if period, exists := er.periods[granularity][name]; exists {
+        return *period
+    }
+
+    return EmissionPeriod{Period: name}
+}
+
+// reset empties the report
+// The caller must hold the lock
+func (er *EmissionReport) reset() {
+    er.periods = make(map[string]map[string]*EmissionPeriod, len(periodLayouts))
+    for granularity := range periodLayouts {
+        er.periods[granularity] = make(map[string]*EmissionPeriod)
+    }
+    er.recent = nil
+    er.height = 0
+    er.timestamp = 0
+    er.first = 0
+    er.supply = 0
+    er.feesBurned = 0
+}
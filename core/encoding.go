@@ -0,0 +1,230 @@
+package core
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+)
+
+// CanonicalEncode returns the canonical encoding of a value, used for every hash and signature payload
+// The encoding is JSON with object keys sorted bytewise, no insignificant whitespace, no HTML escaping,
+// and numbers written exactly as encoding/json renders them, so equal values always encode to equal bytes
+func CanonicalEncode(v interface{}) ([]byte, error) {
+    data, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+
+    // Decode into generic values so struct field order and map ordering no longer matter
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.UseNumber()
+
+    var value interface{}
+    if err := decoder.Decode(&value); err != nil {
+        return nil, err
+    }
+
+    var buffer bytes.Buffer
+    if err := writeCanonical(&buffer, value); err != nil {
+        return nil, err
+    }
+
+    return buffer.Bytes(), nil
+}
+
+// CanonicalHash returns the hex SHA-256 hash of a value's canonical encoding
+func CanonicalHash(v interface{}) (string, error) {
+    data, err := CanonicalEncode(v)
+    if err != nil {
+        return "", err
+    }
+
+    hash := sha256.Sum256(data)
+    return hex.EncodeToString(hash[:]), nil
+}
+
+// TransactionSigningPayload returns the bytes a sender signs for a transaction
+// The ID and signature are cleared, since both are derived after signing
+func TransactionSigningPayload(tx Transaction) ([]byte, error) {
+    tx.ID = ""
+    tx.Signature = ""
+    return CanonicalEncode(tx)
+}
+
+// ComputeTransactionID returns the content-derived ID of a transaction
+// Clients set Transaction.ID to this value; it covers everything except the ID and signature
+func ComputeTransactionID(tx Transaction) string {
+    payload, _ := TransactionSigningPayload(tx)
+    hash := sha256.Sum256(payload)
+    return hex.EncodeToString(hash[:])
+}
+
+// ValidateTransaction checks that a transaction's ID matches its content
+func ValidateTransaction(tx Transaction) error {
+    if tx.ID != ComputeTransactionID(tx) {
+        return fmt.Errorf("transaction %q has an ID that does not match its content", tx.ID)
+    }
+
+    return nil
+}
+
+// VerifyTransactionSignature checks that a transaction was signed by its sender
+// The public key must hash to the sender's address and the signature must cover the signing payload
+func VerifyTransactionSignature(tx Transaction) error {
+    if tx.Signature == "" || tx.PublicKey == "" {
+        return errors.New("transaction is not signed")
+    }
+
+    publicKey, err := crypto.HexToPublicKey(tx.PublicKey)
+    if err != nil {
+        return fmt.Errorf("invalid transaction public key: %w", err)
+    }
+
+    if crypto.GetAddressFromPublicKey(publicKey) != tx.Sender {
+        return errors.New("transaction public key does not match its sender")
+    }
+
+    payload, err := TransactionSigningPayload(tx)
+    if err != nil {
+        return err
+    }
+
+    valid, err := crypto.Verify(payload, tx.Signature, publicKey)
+    if err != nil || !valid {
+        return errors.New("invalid transaction signature")
+    }
+
+    return nil
+}
+
+// SignHeader signs a header's hash with the producer's key pair, filling in its public key and signature
+// The signature is not covered by the hash, so signing never changes it
+func SignHeader(header *BlockHeader, keyPair *crypto.KeyPair) error {
+    signature, err := keyPair.Sign([]byte(header.Hash))
+    if err != nil {
+        return err
+    }
+
+    header.PublicKey = crypto.PublicKeyToHex(keyPair.PublicKey)
+    header.Signature = signature
+    return nil
+}
+
+// VerifyHeaderSignature checks that a header's hash covers its fields and was signed by its producer,
+// with the key the producer's address is derived from
+func VerifyHeaderSignature(header BlockHeader) error {
+    if err := VerifySignedHeader(header); err != nil {
+        return err
+    }
+
+    publicKey, _ := crypto.HexToPublicKey(header.PublicKey)
+    if crypto.GetAddressFromPublicKey(publicKey) != header.Validator {
+        return errors.New("block public key does not match its producer")
+    }
+
+    return nil
+}
+
+// VerifySignedHeader checks that a header's hash covers its fields and was signed by the public key it carries
+// Whether that key is the producer's is left to the caller, for producers that rotated their consensus key
+func VerifySignedHeader(header BlockHeader) error {
+    if header.Signature == "" || header.PublicKey == "" {
+        return errors.New("block header is not signed")
+    }
+
+    if header.Hash != CalculateHeaderHash(header) {
+        return fmt.Errorf("block %d has an invalid hash", header.Index)
+    }
+
+    publicKey, err := crypto.HexToPublicKey(header.PublicKey)
+    if err != nil {
+        return fmt.Errorf("invalid block public key: %w", err)
+    }
+
+    valid, err := crypto.Verify([]byte(header.Hash), header.Signature, publicKey)
+    if err != nil || !valid {
+        return errors.New("invalid block signature")
+    }
+
+    return nil
+}
+
+// writeCanonical writes a decoded JSON value in canonical form
+func writeCanonical(buffer *bytes.Buffer, value interface{}) error {
+    switch v := value.(type) {
+    case nil:
+        buffer.WriteString("null")
+
+    case bool:
+        if v {
+            buffer.WriteString("true")
+        } else {
+            buffer.WriteString("false")
+        }
+
+    case json.Number:
+        buffer.WriteString(v.String())
+
+    case string:
+        return writeCanonicalString(buffer, v)
+
+    case []interface{}:
+        buffer.WriteByte('[')
+        for i, element := range v {
+            if i > 0 {
+                buffer.WriteByte(',')
+            }
+            if err := writeCanonical(buffer, element); err != nil {
+                return err
+            }
+        }
+        buffer.WriteByte(']')
+
+    case map[string]interface{}:
+        keys := make([]string, 0, len(v))
+        for key := range v {
+            keys = append(keys, key)
+        }
+        sort.Strings(keys)
+
+        buffer.WriteByte('{')
+        for i, key := range keys {
+            if i > 0 {
+                buffer.WriteByte(',')
+            }
+            if err := writeCanonicalString(buffer, key); err != nil {
+                return err
+            }
+            buffer.WriteByte(':')
+            if err := writeCanonical(buffer, v[key]); err != nil {
+                return err
+            }
+        }
+        buffer.WriteByte('}')
+
+    default:
+        return fmt.Errorf("cannot canonically encode value of type %T", value)
+    }
+
+    return nil
+}
+
+// writeCanonicalString writes a JSON string without HTML escaping
+func writeCanonicalString(buffer *bytes.Buffer, s string) error {
+    var encoded bytes.Buffer
+    encoder := json.NewEncoder(&encoded)
+    encoder.SetEscapeHTML(false)
+    if err := encoder.Encode(s); err != nil {
+        return err
+    }
+
+    // Encode appends a newline
+    buffer.Write(bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))
+    return nil
+}
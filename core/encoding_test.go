@@ -0,0 +1,122 @@
+package core
+
+import (
+    "testing"
+)
+
+// TestCanonicalEncodeGoldenVectors pins the canonical encoding byte for byte
+func TestCanonicalEncodeGoldenVectors(t *testing.T) {
+    vectors := []struct {
+        name     string
+        value    interface{}
+        expected string
+    }{
+        {"null", nil, `null`},
+        {"sorted keys", map[string]interface{}{"b": 1, "a": true, "c": nil}, `{"a":true,"b":1,"c":null}`},
+        {"nested", map[string]interface{}{"z": []interface{}{map[string]interface{}{"y": "1", "x": "2"}}, "m": 1.5}, `{"m":1.5,"z":[{"x":"2","y":"1"}]}`},
+        {"no html escaping", "<a&b>", `"<a&b>"`},
+        {"unicode", "héllo\n", `"héllo\n"`},
+        {"struct field order", struct {
+            B string `json:"b"`
+            A int64  `json:"a"`
+        }{"x", 7}, `{"a":7,"b":"x"}`},
+        {"transaction", goldenTransaction(), `{"amount":12.5,"data":{"nftId":"nft_1","note":"gg"},"id":"tx1","recipient":"bob","sender":"alice","signature":"sig","timestamp":1700000000,"type":"token_transfer"}`},
+    }
+
+    for _, vector := range vectors {
+        encoded, err := CanonicalEncode(vector.value)
+        if err != nil {
+            t.Fatalf("%s: %v", vector.name, err)
+        }
+        if string(encoded) != vector.expected {
+            t.Errorf("%s: got %s, want %s", vector.name, encoded, vector.expected)
+        }
+    }
+}
+
+// TestCanonicalHashGoldenVectors pins the hashes that blocks and transactions commit to
+func TestCanonicalHashGoldenVectors(t *testing.T) {
+    header := BlockHeader{
+        Index:     1,
+        Timestamp: 1700000000,
+        PrevHash:  "prev",
+        TxRoot:    "root",
+        Validator: "validator",
+        Hash:      "ignored",
+        Signature: "ignored",
+    }
+
+    payload, err := TransactionSigningPayload(goldenTransaction())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    checks := []struct {
+        name     string
+        got      string
+        expected string
+    }{
+        {"header hash", CalculateHeaderHash(header), "a494984786649bc1f0657764bca41e29f173065295f0986a950561ed33fcd852"},
+        {"transaction hash", HashTransaction(goldenTransaction()), "4ae80beeda92825c0d4f0d803cb3206f48192eb0555cbe6c6e2ca231aee1c942"},
+        {"transaction ID", ComputeTransactionID(goldenTransaction()), "464c664ceb97e25db1db51e7e7fec52d227dbc77f3ca4c10d85e24eb8b212866"},
+        {"signing payload", string(payload), `{"amount":12.5,"data":{"nftId":"nft_1","note":"gg"},"id":"","recipient":"bob","sender":"alice","signature":"","timestamp":1700000000,"type":"token_transfer"}`},
+    }
+
+    for _, check := range checks {
+        if check.got != check.expected {
+            t.Errorf("%s: got %s, want %s", check.name, check.got, check.expected)
+        }
+    }
+}
+
+// TestCanonicalEncodeMapOrdering checks that map iteration order never changes the encoding
+func TestCanonicalEncodeMapOrdering(t *testing.T) {
+    first, err := CanonicalEncode(map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": 4})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for i := 0; i < 50; i++ {
+        again, err := CanonicalEncode(map[string]interface{}{"d": 4, "c": 3, "b": 2, "a": 1})
+        if err != nil {
+            t.Fatal(err)
+        }
+        if string(again) != string(first) {
+            t.Fatalf("encoding changed between runs: %s vs %s", again, first)
+        }
+    }
+}
+
+// goldenTransaction returns a fixed transaction for golden vectors
+func goldenTransaction() Transaction {
+    return Transaction{
+        ID:        "tx1",
+        Type:      "token_transfer",
+        Sender:    "alice",
+        Recipient: "bob",
+        Amount:    1_250_000_000, // 12.5 ILYZ
+        Data:      map[string]interface{}{"note": "gg", "nftId": "nft_1"},
+        Timestamp: 1700000000,
+        Signature: "sig",
+    }
+}
+
+// TestValidateTransactionRejectsMismatchedID checks that IDs are enforced against content
+func TestValidateTransactionRejectsMismatchedID(t *testing.T) {
+    tx := goldenTransaction()
+    tx.ID = ComputeTransactionID(tx)
+    if err := ValidateTransaction(tx); err != nil {
+        t.Fatalf("valid transaction rejected: %v", err)
+    }
+
+    // The signature is not part of the ID
+    tx.Signature = "other"
+    if err := ValidateTransaction(tx); err != nil {
+        t.Fatalf("signature change rejected: %v", err)
+    }
+
+    tx.Amount = 13
+    if err := ValidateTransaction(tx); err == nil {
+        t.Fatal("transaction with tampered amount was accepted")
+    }
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+    "sync"
+)
+
+// Chain event types published by the block execution pipeline
+const (
+    EventNewHead   = "newHead"
+    EventPendingTx = "pendingTx"
+    EventTx        = "tx"
+    EventNFT       = "nft"
+    EventValidator = "validator"
+    EventSeason    = "season"
+    EventYear      = "year"
+)
+
+// ChainEvent is a single notification published by the blockchain
+type ChainEvent struct {
+    Type        string      `json:"type"`
+    BlockHeight int64       `json:"blockHeight,omitempty"`
+    Addresses   []string    `json:"addresses,omitempty"`  // Addresses touched by the event
+    NFTID       string      `json:"nftId,omitempty"`      // NFT touched by the event, if any
+    Collection  string      `json:"collection,omitempty"` // Collection of the NFT, if any
+    Kind        string      `json:"kind,omitempty"`       // What happened, for module events such as NFT events
+    Data        interface{} `json:"data"`
+}
+
+// EventHub fans chain events out to any number of subscribers
+type EventHub struct {
+    // Map of subscriber ID to delivery channel
+    subscribers map[int]chan ChainEvent
+
+    // Next subscriber ID
+    nextID int
+
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// NewEventHub creates a new event hub
+func NewEventHub() *EventHub {
+    return &EventHub{
+        subscribers: make(map[int]chan ChainEvent),
+        nextID:      1,
+    }
+}
+
+// Subscribe registers a new subscriber and returns its ID and delivery channel
+func (h *EventHub) Subscribe(bufferSize int) (int, <-chan ChainEvent) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    id := h.nextID
+    h.nextID++
+
+    ch := make(chan ChainEvent, bufferSize)
+    h.subscribers[id] = ch
+
+    return id, ch
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (h *EventHub) Unsubscribe(id int) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    if ch, exists := h.subscribers[id]; exists {
+        close(ch)
+        delete(h.subscribers, id)
+    }
+}
+
+// Publish delivers an event to every subscriber
+// Slow subscribers whose buffers are full miss the event rather than stalling block execution
+func (h *EventHub) Publish(event ChainEvent) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    for _, ch := range h.subscribers {
+        select {
+        case ch <- event:
+        default:
+        }
+    }
+}
+
+// transactionEvent builds the event published for a transaction
+func transactionEvent(eventType string, tx Transaction, height int64) ChainEvent {
+    event := ChainEvent{
+        Type:        eventType,
+        BlockHeight: height,
+        Addresses:   []string{},
+        Data:        tx,
+    }
+
+    if tx.Sender != "" {
+        event.Addresses = append(event.Addresses, tx.Sender)
+    }
+    if tx.Recipient != "" && tx.Recipient != tx.Sender {
+        event.Addresses = append(event.Addresses, tx.Recipient)
+    }
+
+    // NFT transactions carry the NFT ID in their data payload
+    if data, ok := tx.Data.(map[string]interface{}); ok {
+        if nftID, ok := data["nftId"].(string); ok {
+            event.NFTID = nftID
+        }
+    }
+
+    return event
+}
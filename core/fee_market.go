@@ -0,0 +1,108 @@
+package core
+
+import (
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// GetBaseFee returns the least fee the next block's transactions must pay, and whether the chain has a fee market
+func (bc *Blockchain) GetBaseFee() (token.Amount, token.FeeMarket, bool) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    if bc.FeeMarket == nil {
+        return 0, token.FeeMarket{}, false
+    }
+
+    return bc.nextBaseFee(), *bc.FeeMarket, true
+}
+
+// nextBaseFee returns the base fee the next block must carry, from the chain tip's base fee and fullness;
+// 0 without a fee market
+// The caller must hold the lock
+func (bc *Blockchain) nextBaseFee() token.Amount {
+    if bc.FeeMarket == nil {
+        return 0
+    }
+
+    tip := bc.Headers[len(bc.Headers)-1]
+    if tip.Index == 0 {
+        return bc.FeeMarket.NextBaseFee(0, 0)
+    }
+
+    return bc.FeeMarket.NextBaseFee(tip.BaseFee, len(paidTransactions(bc.Bodies[tip.Hash].Transactions)))
+}
+
+// checkBaseFee reports whether a block continuing the chain carries the base fee its parent sets and every
+// transaction in it pays at least that
+// The caller must hold the lock
+func (bc *Blockchain) checkBaseFee(block Block) error {
+    expected := bc.nextBaseFee()
+    if block.BaseFee != expected {
+        return fmt.Errorf("block %d carries a base fee of %s, expected %s", block.Index, block.BaseFee, expected)
+    }
+
+    for _, tx := range paidTransactions(block.Transactions) {
+        if tx.Fee < block.BaseFee {
+            return fmt.Errorf("block %d: transaction %s pays a fee of %s, below the base fee of %s", block.Index, tx.ID, tx.Fee, block.BaseFee)
+        }
+    }
+
+    return nil
+}
+
+// splitFees splits the fees of a block's transactions by the fee policy in effect, once the fee market's surplus,
+// the base fee above its floor for each transaction, has been burned
+// The caller must hold the lock
+func (bc *Blockchain) splitFees(transactions []Transaction, baseFee token.Amount) FeeSplit {
+    fees := BlockFees(transactions)
+
+    surplus := token.Amount(0)
+    if bc.FeeMarket != nil {
+        surplus = bc.FeeMarket.Surplus(baseFee) * token.Amount(len(paidTransactions(transactions)))
+        if surplus > fees {
+            surplus = fees
+        }
+    }
+
+    split := bc.feePolicy().Split(fees - surplus)
+    split.Burned += surplus
+
+    return split
+}
+
+// syncBaseFee reports the base fee the next block must carry to the token economics
+// The caller must hold the write lock
+func (bc *Blockchain) syncBaseFee() {
+    if bc.Economics != nil {
+        bc.Economics.SyncBaseFee(bc.nextBaseFee())
+    }
+}
+
+// splitByFee separates the transactions paying at least a base fee from those paying less, keeping their order
+func splitByFee(transactions []Transaction, baseFee token.Amount) ([]Transaction, []Transaction) {
+    paying := []Transaction{}
+    below := []Transaction{}
+    for _, tx := range transactions {
+        if tx.Fee >= baseFee {
+            paying = append(paying, tx)
+        } else {
+            below = append(below, tx)
+        }
+    }
+
+    return paying, below
+}
+
+// paidTransactions returns a block's transactions other than those its producer creates, which pay no fee
+func paidTransactions(transactions []Transaction) []Transaction {
+    paid := []Transaction{}
+    for _, tx := range transactions {
+        if !IsProducerTransaction(tx) {
+            paid = append(paid, tx)
+        }
+    }
+
+    return paid
+}
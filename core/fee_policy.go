@@ -0,0 +1,168 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// StakingPoolAddress is the account holding the fees set aside for stakers until the consensus engine pays them out
+const StakingPoolAddress = "staking_reward_pool"
+
+// TreasuryAddress is the community treasury's account; only proposals passed by governance spend from it
+const TreasuryAddress = "community_treasury"
+
+// FeePolicy splits each block's fees between the master wallet, the staking reward pool, the community treasury
+// and burning; the block producer keeps the rest
+// The treasury also takes a share of each block reward before the producer is paid
+type FeePolicy struct {
+    MasterWallet        string  `json:"masterWallet,omitempty"` // Without one the producer keeps the master wallet's share
+    MasterShare         float64 `json:"masterShare"`
+    StakingShare        float64 `json:"stakingShare"`
+    TreasuryShare       float64 `json:"treasuryShare"`
+    BurnShare           float64 `json:"burnShare"`
+    TreasuryRewardShare float64 `json:"treasuryRewardShare"` // Share of the block reward, rather than the fees
+}
+
+// FeeSplit is how a block's fees are divided
+type FeeSplit struct {
+    Producer token.Amount `json:"producer"`
+    Master   token.Amount `json:"master"`
+    Staking  token.Amount `json:"staking"`
+    Treasury token.Amount `json:"treasury"`
+    Burned   token.Amount `json:"burned"`
+}
+
+// FeePolicySource reports the fee policy in effect, such as one governance adjusts
+type FeePolicySource interface {
+    // FeePolicy returns the policy the next block's fees are split by
+    FeePolicy() FeePolicy
+}
+
+// Validate checks that no share is negative, that together the fee shares don't exceed the whole
+// and that the treasury takes no more than the whole block reward
+func (p FeePolicy) Validate() error {
+    if p.MasterShare < 0 || p.StakingShare < 0 || p.TreasuryShare < 0 || p.BurnShare < 0 {
+        return errors.New("fee shares cannot be negative")
+    }
+
+    if p.MasterShare+p.StakingShare+p.TreasuryShare+p.BurnShare > 1 {
+        return errors.New("fee shares add up to more than 1")
+    }
+
+    if p.TreasuryRewardShare < 0 || p.TreasuryRewardShare > 1 {
+        return errors.New("treasury reward share must be between 0 and 1")
+    }
+
+    return nil
+}
+
+// Split divides a block's fees by the policy, rounding each share down so the producer keeps what rounding leaves
+// Shares adding up past the whole, as governance changes passed together can leave them, are cut back in the order
+// burn, staking, treasury, master
+func (p FeePolicy) Split(fees token.Amount) FeeSplit {
+    remaining := fees
+    take := func(share float64) token.Amount {
+        amount := fees.MulRate(share)
+        if amount > remaining {
+            amount = remaining
+        }
+        if amount < 0 {
+            amount = 0
+        }
+        remaining -= amount
+        return amount
+    }
+
+    split := FeeSplit{}
+    split.Burned = take(p.BurnShare)
+    split.Staking = take(p.StakingShare)
+    split.Treasury = take(p.TreasuryShare)
+    if p.MasterWallet != "" {
+        split.Master = take(p.MasterShare)
+    }
+    split.Producer = remaining
+
+    return split
+}
+
+// TreasuryReward returns the share of a block reward the treasury takes, rounded down
+func (p FeePolicy) TreasuryReward(reward token.Amount) token.Amount {
+    if p.TreasuryRewardShare <= 0 || reward <= 0 {
+        return 0
+    }
+
+    share := reward.MulRate(p.TreasuryRewardShare)
+    if share > reward {
+        share = reward
+    }
+
+    return share
+}
+
+// GetFeePolicy returns the policy the next block's fees are split by
+func (bc *Blockchain) GetFeePolicy() FeePolicy {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    return bc.feePolicy()
+}
+
+// feePolicy returns the policy the next block's fees are split by, the genesis policy without a fee policy source
+// The caller must hold the lock
+func (bc *Blockchain) feePolicy() FeePolicy {
+    if bc.Fees == nil {
+        return bc.FeePolicy
+    }
+
+    return bc.Fees.FeePolicy()
+}
+
+// checkFeeSplit reports whether a block continuing the chain splits its fees, and the treasury's share
+// of its reward, by the policy in effect
+// The caller must hold the lock
+func (bc *Blockchain) checkFeeSplit(block Block) error {
+    policy := bc.feePolicy()
+    expected := bc.splitFees(block.Transactions, block.BaseFee)
+
+    coinbase := block.Transactions[0]
+    treasuryReward := coinbaseTreasuryReward(coinbase)
+    if expectedReward := policy.TreasuryReward(coinbaseReward(coinbase)); treasuryReward != expectedReward {
+        return fmt.Errorf("block %d pays the treasury %s of its reward, expected %s", block.Index, treasuryReward, expectedReward)
+    }
+
+    split, masterWallet := coinbaseSplit(coinbase)
+    split.Producer = expected.Producer
+    if split != expected {
+        return fmt.Errorf("block %d splits its fees %+v, expected %+v", block.Index, split, expected)
+    }
+    if split.Master > 0 && masterWallet != policy.MasterWallet {
+        return fmt.Errorf("block %d pays the master wallet's fees to %q, expected %q", block.Index, masterWallet, policy.MasterWallet)
+    }
+
+    return nil
+}
+
+// coinbaseSplit returns the shares of the fees a coinbase records for the master wallet, the staking reward pool,
+// the treasury and burning, and the master wallet it pays; the producer's share is part of the coinbase amount
+func coinbaseSplit(tx Transaction) (FeeSplit, string) {
+    data, _ := tx.Data.(map[string]interface{})
+
+    split := FeeSplit{}
+    split.Master, _ = token.DataAmount(data["master"])
+    split.Staking, _ = token.DataAmount(data["staking"])
+    split.Treasury, _ = token.DataAmount(data["treasury"])
+    split.Burned, _ = token.DataAmount(data["burned"])
+    masterWallet, _ := data["masterWallet"].(string)
+
+    return split, masterWallet
+}
+
+// coinbaseTreasuryReward returns the share of the block reward a coinbase records paying the treasury
+func coinbaseTreasuryReward(tx Transaction) token.Amount {
+    data, _ := tx.Data.(map[string]interface{})
+    reward, _ := token.DataAmount(data["treasuryReward"])
+
+    return reward
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ForkChoice picks the canonical chain among competing branches
+type ForkChoice interface {
+    // ChooseBranch returns the index of the canonical branch
+    // Each branch is the headers above a common ancestor, lowest first; the choice must not depend on their order
+    ChooseBranch(branches [][]BlockHeader) (int, error)
+}
+
+// PreferBranch reports whether a competing branch should replace the local chain above its fork point
+// The branch is headers lowest first, the first linking to a block on the local chain
+// Without a fork choice the longer branch wins, and the local chain is kept at equal length
+func (bc *Blockchain) PreferBranch(branch []BlockHeader) (bool, error) {
+    if len(branch) == 0 {
+        return false, errors.New("branch is empty")
+    }
+
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    fork := branch[0].Index - 1
+    if fork < 0 || fork >= int64(len(bc.Headers)) {
+        return false, fmt.Errorf("branch does not fork from the local chain at height %d", fork)
+    }
+    if branch[0].PrevHash != bc.Headers[fork].Hash {
+        return false, fmt.Errorf("branch does not link to local block %d", fork)
+    }
+
+    local := append([]BlockHeader{}, bc.Headers[fork+1:]...)
+    if len(local) > 0 && local[0].Hash == branch[0].Hash {
+        return false, errors.New("branch does not diverge from the local chain at its first block")
+    }
+
+    if bc.ForkChoice == nil {
+        return len(branch) > len(local), nil
+    }
+
+    chosen, err := bc.ForkChoice.ChooseBranch([][]BlockHeader{local, branch})
+    if err != nil {
+        return false, err
+    }
+
+    return chosen == 1, nil
+}
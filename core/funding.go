@@ -0,0 +1,146 @@
+package core
+
+import (
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Authenticator is implemented by modules owning transactions whose sender is proven by something other than
+// the transaction's signature, such as an attestation signed by the game server that pays its fee
+type Authenticator interface {
+    // AuthenticateTransaction reports whether the module vouches for a transaction's sender and, if it does,
+    // whether the proof holds
+    AuthenticateTransaction(tx Transaction) (bool, error)
+}
+
+// SenderDebit returns what a transaction takes from its sender's balance before it is applied: its fee,
+// plus the amount of a token transfer
+// Amounts modules move when they apply a transaction, such as bonds or purchases, are checked by the modules
+func SenderDebit(tx Transaction) token.Amount {
+    if IsProducerTransaction(tx) {
+        return 0
+    }
+
+    debit := tx.Fee
+    if tx.Type == "token_transfer" {
+        debit += tx.Amount
+    }
+
+    return debit
+}
+
+// fundedTransactions reports, for each transaction of a block in order, whether its sender can pay for it from
+// the balance held before the block, less what the sender's earlier transactions in the block take
+// Credits within the block don't count, so the result depends only on the parent state and the block
+func fundedTransactions(state *State, transactions []Transaction) []bool {
+    funded := make([]bool, len(transactions))
+    spent := make(map[string]token.Amount)
+    for i, tx := range transactions {
+        debit := SenderDebit(tx)
+        if tx.Fee < 0 || debit < tx.Fee {
+            continue
+        }
+        if debit > 0 && state.Balances[tx.Sender]-spent[tx.Sender] < debit {
+            continue
+        }
+
+        spent[tx.Sender] += debit
+        funded[i] = true
+    }
+
+    return funded
+}
+
+// collectFees burns the fee of every transaction of a block its sender can pay for, before any is applied,
+// and reports which are; the coinbase mints back exactly the fees burned, as blocks may only include those
+func (s *State) collectFees(transactions []Transaction) []bool {
+    funded := fundedTransactions(s, transactions)
+    for i, tx := range transactions {
+        if funded[i] && tx.Fee > 0 {
+            s.Burn(tx.Sender, tx.Fee)
+        }
+    }
+
+    return funded
+}
+
+// authenticate checks that a transaction taking from its sender's balance was signed by the sender,
+// or that a module proves the sender another way
+// The caller must hold the lock
+func (bc *Blockchain) authenticate(tx Transaction) error {
+    if SenderDebit(tx) == 0 {
+        return nil
+    }
+
+    for _, module := range bc.modules {
+        if authenticator, ok := module.(Authenticator); ok {
+            if handled, err := authenticator.AuthenticateTransaction(tx); handled {
+                return err
+            }
+        }
+    }
+
+    return VerifyTransactionSignature(tx)
+}
+
+// checkFunds checks that a transaction's sender can pay for it on top of their transactions already in the mempool
+// The caller must hold the lock
+func (bc *Blockchain) checkFunds(tx Transaction) error {
+    debit := SenderDebit(tx)
+    if debit < tx.Fee {
+        return fmt.Errorf("transfer amount %s cannot be negative", tx.Amount)
+    }
+    if debit == 0 {
+        return nil
+    }
+
+    for _, pending := range bc.PendingTransactions {
+        if pending.Sender == tx.Sender {
+            debit += SenderDebit(pending)
+        }
+    }
+
+    if balance := bc.States.Latest().Balances[tx.Sender]; debit > balance {
+        return fmt.Errorf("%w: %s needs %s for its pending transactions and has %s",
+            token.ErrInsufficientBalance, tx.Sender, debit, balance)
+    }
+
+    return nil
+}
+
+// checkSenders checks that every transaction of a block its producer didn't create was authenticated by its sender
+// and can be paid for, so the coinbase only mints back fees that are burned
+// The caller must hold the lock
+func (bc *Blockchain) checkSenders(block Block) error {
+    funded := fundedTransactions(bc.States.Latest(), block.Transactions)
+    for i, tx := range block.Transactions {
+        if IsProducerTransaction(tx) {
+            continue
+        }
+
+        if err := bc.authenticate(tx); err != nil {
+            return fmt.Errorf("block %d: transaction %s: %w", block.Index, tx.ID, err)
+        }
+        if !funded[i] {
+            return fmt.Errorf("block %d: %w: %s can't pay for transaction %s",
+                block.Index, token.ErrInsufficientBalance, tx.Sender, tx.ID)
+        }
+    }
+
+    return nil
+}
+
+// fundedOnly returns the transactions their senders can pay for in a block built on the latest state, keeping their order
+// The caller must hold the lock
+func (bc *Blockchain) fundedOnly(transactions []Transaction) []Transaction {
+    funded := fundedTransactions(bc.States.Latest(), transactions)
+    kept := []Transaction{}
+    for i, tx := range transactions {
+        if funded[i] {
+            kept = append(kept, tx)
+        }
+    }
+
+    return kept
+}
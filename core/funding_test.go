@@ -0,0 +1,194 @@
+package core
+
+import (
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TestUnfundedSenderIsRejected checks that a sender who can't cover a fee and transfer is refused at admission,
+// in blocks from peers and at execution, and that the supply never grows by the fee they declared
+func TestUnfundedSenderIsRejected(t *testing.T) {
+    funded := newTestKey(t)
+    unfunded := newTestKey(t)
+    bc := newFundedChain(t, fundedGenesis(map[string]token.Amount{funded.address: 10 * token.ILYZ}))
+
+    transfer := funded.transfer(t, "bob", 4*token.ILYZ, token.ILYZ)
+    if err := bc.CreateTransaction(transfer); err != nil {
+        t.Fatalf("funded transfer rejected: %v", err)
+    }
+
+    // The first transfer's 5 ILYZ is already spoken for, so another 6 can't be paid
+    if err := bc.CreateTransaction(funded.transfer(t, "bob", 5*token.ILYZ, token.ILYZ)); !errors.Is(err, token.ErrInsufficientBalance) {
+        t.Fatalf("transfer beyond the pending balance: got %v, want insufficient balance", err)
+    }
+
+    broke := unfunded.transfer(t, "bob", 0, token.ILYZ)
+    if err := bc.CreateTransaction(broke); !errors.Is(err, token.ErrInsufficientBalance) {
+        t.Fatalf("unfunded sender: got %v, want insufficient balance", err)
+    }
+
+    unsigned := funded.transfer(t, "bob", token.ILYZ, token.ILYZ)
+    unsigned.Signature = ""
+    if err := bc.CreateTransaction(unsigned); err == nil {
+        t.Fatal("unsigned transfer was admitted")
+    }
+
+    // A peer's block including the unfunded transaction is refused before it touches the state
+    supply := bc.States.Latest().Supply
+    if err := bc.AddBlock(unfilteredBlock(bc, "producer", []Transaction{broke}, []Transaction{broke})); !errors.Is(err, token.ErrInsufficientBalance) {
+        t.Fatalf("block with an unfunded transaction: got %v, want insufficient balance", err)
+    }
+    if bc.GetHeight() != 0 || bc.States.Latest().Supply != supply {
+        t.Fatal("block with an unfunded transaction changed the chain")
+    }
+
+    // Executing it anyway, behind a coinbase counting no fees, collects nothing and applies nothing
+    state := bc.States.Latest().Copy()
+    block := unfilteredBlock(bc, "producer", nil, []Transaction{broke})
+    executeBlock(block, state, nil)
+    if minted := state.Supply - supply; minted != BlockRewards(block) {
+        t.Fatalf("supply grew by %s, want only the block reward of %s", minted, BlockRewards(block))
+    }
+    if state.Balances[unfunded.address] != 0 {
+        t.Fatalf("unfunded sender holds %s", state.Balances[unfunded.address])
+    }
+
+    // The funded transfer is included and its fee burned, with the coinbase minting back only what was burned
+    created := bc.CreateBlock("producer", nil)
+    if len(created.Transactions) != 2 {
+        t.Fatalf("block has %d transactions, want the coinbase and the transfer", len(created.Transactions))
+    }
+    latest := bc.States.Latest()
+    if latest.Balances[funded.address] != 5*token.ILYZ || latest.Balances["bob"] != 4*token.ILYZ {
+        t.Fatalf("sender holds %s and recipient %s after the transfer", latest.Balances[funded.address], latest.Balances["bob"])
+    }
+    if minted := latest.Supply - supply; minted != BlockRewards(created)-latest.FeesBurned {
+        t.Fatalf("supply grew by %s, want the reward less the fees burned", minted)
+    }
+}
+
+// testKey is a key pair and the address it controls
+type testKey struct {
+    keyPair *crypto.KeyPair
+    address string
+}
+
+// newTestKey generates a key pair for a test
+func newTestKey(t *testing.T) testKey {
+    keyPair, err := crypto.GenerateKeyPair()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return testKey{keyPair: keyPair, address: crypto.GetAddressFromPublicKey(keyPair.PublicKey)}
+}
+
+// transfer returns a token transfer signed by the key
+func (k testKey) transfer(t *testing.T, recipient string, amount token.Amount, fee token.Amount) Transaction {
+    return k.sign(t, Transaction{
+        Type:      "token_transfer",
+        Sender:    k.address,
+        Recipient: recipient,
+        Amount:    amount,
+        Fee:       fee,
+        Timestamp: time.Now().UnixNano(),
+    })
+}
+
+// sign returns a transaction signed by the key
+func (k testKey) sign(t *testing.T, tx Transaction) Transaction {
+    tx.PublicKey = crypto.PublicKeyToHex(k.keyPair.PublicKey)
+    tx.ID = ComputeTransactionID(tx)
+
+    payload, err := TransactionSigningPayload(tx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if tx.Signature, err = k.keyPair.Sign(payload); err != nil {
+        t.Fatal(err)
+    }
+
+    return tx
+}
+
+// fundedGenesis returns a genesis allocating balances
+func fundedGenesis(alloc map[string]token.Amount) *Genesis {
+    return &Genesis{
+        ChainID:   "test",
+        Timestamp: time.Now().Unix() - 60,
+        Alloc:     alloc,
+    }
+}
+
+// newFundedChain creates a chain from a genesis
+func newFundedChain(t *testing.T, genesis *Genesis) *Blockchain {
+    config := DefaultChainConfig()
+    config.Genesis = genesis
+
+    bc, err := NewBlockchainWithConfig(config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return bc
+}
+
+// unfilteredBlock builds the next block including transactions behind a coinbase counting the fees of others,
+// as a producer skipping the funding checks would
+func unfilteredBlock(bc *Blockchain, validator string, counted []Transaction, transactions []Transaction) Block {
+    latest := bc.Headers[len(bc.Headers)-1]
+    height := latest.Index + 1
+    timestamp := time.Now().Unix()
+    baseFee := bc.nextBaseFee()
+
+    transactions = append([]Transaction{bc.createCoinbase(validator, height, timestamp, counted, baseFee)}, transactions...)
+    block := Block{
+        BlockHeader: BlockHeader{
+            Index:         height,
+            Timestamp:     timestamp,
+            PrevHash:      latest.Hash,
+            TxRoot:        ComputeTxRoot(transactions),
+            Validator:     validator,
+            ParamsVersion: bc.paramsVersion(),
+            BaseFee:       baseFee,
+        },
+        BlockBody: BlockBody{
+            Transactions: transactions,
+        },
+    }
+    block.Hash = bc.CalculateHash(block)
+
+    return block
+}
+
+// TestCreateBlockDropsUnfundedTransactions checks that a producer leaves out transactions their senders can no longer
+// pay for, so its coinbase counts only the fees burned and peers accept its block
+func TestCreateBlockDropsUnfundedTransactions(t *testing.T) {
+    funded := newTestKey(t)
+    genesis := fundedGenesis(map[string]token.Amount{funded.address: 10 * token.ILYZ})
+    producer := newFundedChain(t, genesis)
+    peer := newFundedChain(t, genesis)
+
+    // Admitted while funded, the second transfer can't be paid once the first is
+    first := funded.transfer(t, "bob", 8*token.ILYZ, token.ILYZ)
+    second := funded.transfer(t, "bob", token.ILYZ, token.ILYZ)
+    producer.PendingTransactions = append(producer.PendingTransactions, first, second)
+
+    block := producer.CreateBlock("producer", nil)
+    if len(block.Transactions) != 2 || block.Transactions[1].ID != first.ID {
+        t.Fatalf("block has %d transactions, want the coinbase and the first transfer", len(block.Transactions))
+    }
+    if fees := BlockFees(block.Transactions); fees != token.ILYZ {
+        t.Fatalf("block counts %s of fees, want only the first transfer's", fees)
+    }
+    if len(producer.PendingTransactions) != 0 {
+        t.Fatalf("%d transactions left in the mempool", len(producer.PendingTransactions))
+    }
+    if err := peer.AddBlock(block); err != nil {
+        t.Fatalf("peer rejected the block: %v", err)
+    }
+}
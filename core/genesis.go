@@ -0,0 +1,307 @@
+package core
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// ChainIdentityFile is the file in a node's data directory recording which network it belongs to
+const ChainIdentityFile = "chain_identity.json"
+
+// Genesis describes the initial state of a network
+type Genesis struct {
+    ChainID   string                  `json:"chainId"`
+    Timestamp int64                   `json:"timestamp"`
+    Alloc     map[string]token.Amount `json:"alloc,omitempty"` // Initial ILYZ balances
+
+    // Allocations held in the vesting escrow and released to each address by its schedule, such as the team's
+    Vesting map[string]VestingSchedule `json:"vesting,omitempty"`
+
+    // How each block's fees are split until governance changes it; without one the producer keeps them all
+    FeePolicy *FeePolicy `json:"feePolicy,omitempty"`
+
+    // Most that may be minted each year, for every year of the chain; without one the default schedule applies
+    // It is part of the genesis hash, so nodes minting by different schedules never share a chain
+    Emission *token.SupplySchedule `json:"emission,omitempty"`
+
+    // Base fee market transactions pay into; without one any fee is accepted
+    FeeMarket *token.FeeMarket `json:"feeMarket,omitempty"`
+
+    // Currencies the chain holds beside ILYZ, such as a cosmetic currency; fees and stakes stay in ILYZ
+    Assets []token.Asset `json:"assets,omitempty"`
+
+    // Share of referred players' game rewards paid to their referrers, for how many days and from what yearly budget;
+    // without one the default program applies
+    Referral *token.ReferralProgram `json:"referral,omitempty"`
+
+    // Most game rewards and yield may mint in a clock hour and in a UTC day; the default ceilings apply to those left unset
+    HourlyEmissionCeiling token.Amount `json:"hourlyEmissionCeiling,omitempty"`
+    DailyEmissionCeiling  token.Amount `json:"dailyEmissionCeiling,omitempty"`
+
+    // Game servers allowed to report match results and attest to player activity until governance changes them
+    GameServers []string `json:"gameServers,omitempty"`
+
+    // Settings of the modules blocks are applied by, by module, such as the play modes scoring attestations, the governed
+    // parameters and seasons consensus starts from and the NFT mint and fee policies; governance replaces them,
+    // and modules use their defaults for settings left out
+    Settings map[string]json.RawMessage `json:"settings,omitempty"`
+}
+
+// ChainIdentity is what a data directory records about the network it was initialized for
+type ChainIdentity struct {
+    ChainID          string `json:"chainId"`
+    GenesisHash      string `json:"genesisHash"`      // Hash of the genesis file
+    GenesisBlockHash string `json:"genesisBlockHash"` // Hash of the genesis block built from it
+
+    // Hash of the emission schedule the network mints by
+    EmissionScheduleHash string `json:"emissionScheduleHash,omitempty"`
+}
+
+// LoadGenesis reads a genesis file
+func LoadGenesis(path string) (*Genesis, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var genesis Genesis
+    if err := json.Unmarshal(data, &genesis); err != nil {
+        return nil, fmt.Errorf("invalid genesis file %s: %w", path, err)
+    }
+
+    if genesis.ChainID == "" {
+        return nil, fmt.Errorf("genesis file %s has no chain ID", path)
+    }
+
+    if genesis.FeePolicy != nil {
+        if err := genesis.FeePolicy.Validate(); err != nil {
+            return nil, fmt.Errorf("genesis file %s has an invalid fee policy: %w", path, err)
+        }
+    }
+
+    if genesis.Emission != nil {
+        if err := genesis.Emission.Validate(); err != nil {
+            return nil, fmt.Errorf("genesis file %s has an invalid emission schedule: %w", path, err)
+        }
+    }
+
+    if genesis.FeeMarket != nil {
+        if err := genesis.FeeMarket.Validate(); err != nil {
+            return nil, fmt.Errorf("genesis file %s has an invalid fee market: %w", path, err)
+        }
+    }
+
+    if genesis.Referral != nil {
+        if err := genesis.Referral.Validate(); err != nil {
+            return nil, fmt.Errorf("genesis file %s has an invalid referral program: %w", path, err)
+        }
+    }
+
+    if genesis.HourlyEmissionCeiling < 0 || genesis.DailyEmissionCeiling < 0 {
+        return nil, fmt.Errorf("genesis file %s has a negative emission ceiling", path)
+    }
+
+    registered := make(map[string]bool, len(genesis.Assets))
+    for _, asset := range genesis.Assets {
+        if err := asset.Validate(); err != nil {
+            return nil, fmt.Errorf("genesis file %s has an invalid asset %q: %w", path, asset.ID, err)
+        }
+        if registered[asset.ID] {
+            return nil, fmt.Errorf("genesis file %s registers asset %q twice", path, asset.ID)
+        }
+        registered[asset.ID] = true
+    }
+
+    servers := make(map[string]bool, len(genesis.GameServers))
+    for _, server := range genesis.GameServers {
+        if server == "" || servers[server] {
+            return nil, fmt.Errorf("genesis file %s lists game server %q more than once or without an address", path, server)
+        }
+        servers[server] = true
+    }
+
+    for address, schedule := range genesis.Vesting {
+        if err := schedule.Validate(); err != nil {
+            return nil, fmt.Errorf("genesis file %s has an invalid vesting schedule for %s: %w", path, address, err)
+        }
+    }
+
+    return &genesis, nil
+}
+
+// SaveGenesis writes a genesis file
+func SaveGenesis(genesis *Genesis, path string) error {
+    data, err := json.MarshalIndent(genesis, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    return os.WriteFile(path, data, 0644)
+}
+
+// Hash returns the hash of the genesis definition
+func (g *Genesis) Hash() string {
+    hash, _ := CanonicalHash(g)
+    return hash
+}
+
+// SupplySchedule returns the emission schedule the network mints by, the default one if the genesis sets none
+func (g *Genesis) SupplySchedule() token.SupplySchedule {
+    if g.Emission == nil {
+        return token.DefaultSupplySchedule()
+    }
+
+    return *g.Emission
+}
+
+// EmissionScheduleHash returns the hash of the emission schedule the network mints by
+// Peers compare it when they connect, since minting by different schedules would split the chain
+func (g *Genesis) EmissionScheduleHash() string {
+    hash, _ := CanonicalHash(g.SupplySchedule())
+    return hash
+}
+
+// Block builds the deterministic genesis block for this network
+// The block links to the genesis file hash in place of a previous block, so it commits to the chain ID
+func (g *Genesis) Block() Block {
+    addresses := make([]string, 0, len(g.Alloc))
+    for address := range g.Alloc {
+        addresses = append(addresses, address)
+    }
+    sort.Strings(addresses)
+
+    transactions := []Transaction{}
+    for _, address := range addresses {
+        tx := Transaction{
+            Type:      "genesis_alloc",
+            Recipient: address,
+            Amount:    g.Alloc[address],
+            Timestamp: g.Timestamp,
+        }
+        tx.ID = ComputeTransactionID(tx)
+        transactions = append(transactions, tx)
+    }
+
+    vesting := make([]string, 0, len(g.Vesting))
+    for address := range g.Vesting {
+        vesting = append(vesting, address)
+    }
+    sort.Strings(vesting)
+
+    for _, address := range vesting {
+        schedule := g.Vesting[address]
+        if schedule.Start == 0 {
+            schedule.Start = g.Timestamp
+        }
+        transactions = append(transactions, vestingTransaction(address, schedule, g.Timestamp))
+    }
+
+    assets := append([]token.Asset{}, g.Assets...)
+    sort.Slice(assets, func(i, j int) bool {
+        return assets[i].ID < assets[j].ID
+    })
+    for _, asset := range assets {
+        transactions = append(transactions, assetTransaction(asset, g.Timestamp))
+    }
+
+    servers := append([]string{}, g.GameServers...)
+    sort.Strings(servers)
+    for _, server := range servers {
+        transactions = append(transactions, gameServerTransaction(server, g.Timestamp))
+    }
+    transactions = append(transactions, settingsTransactions(g.Settings, g.Timestamp)...)
+
+    block := Block{
+        BlockHeader: BlockHeader{
+            Index:     0,
+            Timestamp: g.Timestamp,
+            PrevHash:  g.Hash(),
+            TxRoot:    ComputeTxRoot(transactions),
+            Validator: "genesis",
+        },
+        BlockBody: BlockBody{
+            Transactions: transactions,
+        },
+    }
+    block.Hash = CalculateHeaderHash(block.BlockHeader)
+
+    return block
+}
+
+// Identity returns the chain identity a data directory should record for this genesis
+func (g *Genesis) Identity() ChainIdentity {
+    return ChainIdentity{
+        ChainID:              g.ChainID,
+        GenesisHash:          g.Hash(),
+        GenesisBlockHash:     g.Block().Hash,
+        EmissionScheduleHash: g.EmissionScheduleHash(),
+    }
+}
+
+// CheckChainIdentity verifies that a data directory belongs to the network described by the genesis
+// A directory without an identity file is claimed for this network; one initialized for a
+// different network is rejected so its data is never mixed with another chain's
+func CheckChainIdentity(dataDir string, genesis *Genesis) error {
+    expected := genesis.Identity()
+    path := filepath.Join(dataDir, ChainIdentityFile)
+
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        if err := os.MkdirAll(dataDir, 0755); err != nil {
+            return err
+        }
+
+        data, err := json.MarshalIndent(expected, "", "  ")
+        if err != nil {
+            return err
+        }
+
+        return os.WriteFile(path, data, 0644)
+    }
+    if err != nil {
+        return err
+    }
+
+    var stored ChainIdentity
+    if err := json.Unmarshal(data, &stored); err != nil {
+        return fmt.Errorf("corrupt chain identity file %s: %w", path, err)
+    }
+
+    if stored.ChainID != expected.ChainID {
+        return fmt.Errorf("data directory %s belongs to chain %q but the configured genesis is for chain %q; refusing to start", dataDir, stored.ChainID, expected.ChainID)
+    }
+
+    // Directories initialized before the schedule was recorded are checked by the genesis hash alone
+    if stored.EmissionScheduleHash != "" && stored.EmissionScheduleHash != expected.EmissionScheduleHash {
+        return fmt.Errorf("data directory %s was initialized with emission schedule %s but the configured genesis mints by %s; refusing to start", dataDir, stored.EmissionScheduleHash, expected.EmissionScheduleHash)
+    }
+
+    if stored.GenesisHash != expected.GenesisHash || stored.GenesisBlockHash != expected.GenesisBlockHash {
+        return fmt.Errorf("data directory %s was initialized with genesis %s but the configured genesis hashes to %s; refusing to start", dataDir, stored.GenesisHash, expected.GenesisHash)
+    }
+
+    return nil
+}
+
+// VerifyGenesis checks that the chain's genesis block matches the given genesis definition
+func (bc *Blockchain) VerifyGenesis(genesis *Genesis) error {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    if bc.ChainID != "" && bc.ChainID != genesis.ChainID {
+        return fmt.Errorf("chain ID mismatch: chain is %q, genesis is %q", bc.ChainID, genesis.ChainID)
+    }
+
+    expected := genesis.Block()
+    if bc.Headers[0].Hash != expected.Hash {
+        return fmt.Errorf("genesis block mismatch: stored %s, expected %s for chain %q", bc.Headers[0].Hash, expected.Hash, genesis.ChainID)
+    }
+
+    return nil
+}
@@ -0,0 +1,505 @@
+package core
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Match transaction types
+const (
+    TxTypeGameResult  = "game_result"  // Sender: the game server that hosted the match; Data: match, the match ID; rewards, each player's escrowed reward; breakdowns, optionally, how each was calculated; referrals, optionally, each referrer's escrowed share
+    TxTypeMatchVoid   = "match_void"   // Sender: the game server that hosted the match; Data: match, the match ID
+    TxTypeMatchReward = "match_reward" // Created by block producers once a result is final; Data: match, the match ID; rewards, each player's reward; referrals, if any, each referrer's share
+)
+
+// Match result statuses
+const (
+    MatchPending = "pending" // Reported, waiting for the block holding the result to be finalized
+    MatchPaid    = "paid"
+    MatchVoided  = "voided"
+)
+
+// MatchResult is a game server's report of a match and what became of its rewards
+type MatchResult struct {
+    MatchID   string                  `json:"matchId"`
+    Server    string                  `json:"server"`
+    Height    int64                   `json:"height"` // Block holding the result, or the void if the match was voided unreported
+    BlockHash string                  `json:"blockHash"`
+    Rewards   map[string]token.Amount `json:"rewards,omitempty"` // By player
+    Status    string                  `json:"status"`
+    SettledAt int64                   `json:"settledAt,omitempty"` // Height the rewards were paid or voided at
+
+    // How each player's reward was calculated, by player, for the rewards the server reported one for
+    Breakdowns map[string]token.RewardBreakdown `json:"breakdowns,omitempty"`
+
+    // Shares of the players' rewards paid to whoever referred them, by referrer
+    Referrals map[string]token.Amount `json:"referrals,omitempty"`
+}
+
+// RewardReceipt is what a match reward transaction paid each player and how each reward was calculated
+type RewardReceipt struct {
+    TxID        string                           `json:"txId"`
+    BlockHeight int64                            `json:"blockHeight"`
+    MatchID     string                           `json:"matchId"`
+    Server      string                           `json:"server"`
+    Rewards     map[string]token.Amount          `json:"rewards"`
+    Breakdowns  map[string]token.RewardBreakdown `json:"breakdowns,omitempty"`
+    Referrals   map[string]token.Amount          `json:"referrals,omitempty"` // By referrer
+}
+
+// FinalitySource reports which blocks consensus has decided
+type FinalitySource interface {
+    // IsFinalized reports whether a block has been decided at its height
+    IsFinalized(height int64, blockHash string) bool
+}
+
+// GameResultTransaction returns an unsigned transaction reporting a match's result with the rewards its game server's
+// node holds in escrow for it, sent by the game server
+func GameResultTransaction(server string, escrow token.MatchEscrow, timestamp int64) Transaction {
+    rewards := make(map[string]interface{}, len(escrow.Rewards))
+    for player, reward := range escrow.Rewards {
+        rewards[player] = reward
+    }
+
+    data := map[string]interface{}{
+        "match":   escrow.MatchID,
+        "rewards": rewards,
+    }
+    if len(escrow.Breakdowns) > 0 {
+        data["breakdowns"] = escrow.Breakdowns
+    }
+    if len(escrow.Referrals) > 0 {
+        referrals := make(map[string]interface{}, len(escrow.Referrals))
+        for referrer, share := range escrow.Referrals {
+            referrals[referrer] = share
+        }
+        data["referrals"] = referrals
+    }
+
+    return Transaction{
+        Type:      TxTypeGameResult,
+        Sender:    server,
+        Data:      data,
+        Timestamp: timestamp,
+    }
+}
+
+// MatchRewards returns the reward each player is paid by a game result or match reward
+// Rewards are amounts when built locally and float64 once decoded from JSON
+func MatchRewards(tx Transaction) (map[string]token.Amount, bool) {
+    return rewardShares(tx)
+}
+
+// MatchReferrals returns the share each referrer is paid by a game result or match reward, none if it pays none
+func MatchReferrals(tx Transaction) (map[string]token.Amount, bool) {
+    data, _ := tx.Data.(map[string]interface{})
+    if data["referrals"] == nil {
+        return map[string]token.Amount{}, true
+    }
+
+    return dataShares(data["referrals"])
+}
+
+// GetMatchResult returns a match's result and what became of its rewards as of the latest block
+func (bc *Blockchain) GetMatchResult(matchID string) (MatchResult, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    result, exists := bc.States.Latest().Matches[matchID]
+    if !exists {
+        return MatchResult{}, fmt.Errorf("match %s has no result", matchID)
+    }
+
+    return result, nil
+}
+
+// GetRewardReceipt returns the receipt of a confirmed match reward transaction
+func (bc *Blockchain) GetRewardReceipt(txID string) (RewardReceipt, error) {
+    tx, height, err := bc.GetTransactionByID(txID)
+    if err != nil {
+        return RewardReceipt{}, err
+    }
+    if tx.Type != TxTypeMatchReward {
+        return RewardReceipt{}, fmt.Errorf("transaction %s is not a match reward", txID)
+    }
+
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    // Results are never removed once recorded, so the latest state holds the one the reward paid
+    result := bc.States.Latest().Matches[matchID(tx)]
+    rewards, _ := MatchRewards(tx)
+
+    return RewardReceipt{
+        TxID:        tx.ID,
+        BlockHeight: height,
+        MatchID:     result.MatchID,
+        Server:      result.Server,
+        Rewards:     rewards,
+        Breakdowns:  result.Breakdowns,
+        Referrals:   result.Referrals,
+    }, nil
+}
+
+// applyMatchTransaction records a game result or void, and pays a match reward, in the block with a header
+// A match is reported once, by a game server that signed its report; a void only settles a result still pending
+// from the same server, and a reward only pays one still pending
+func (s *State) applyMatchTransaction(tx Transaction, header BlockHeader) {
+    id := matchID(tx)
+    if id == "" || checkMatchReport(s, tx) != nil {
+        return
+    }
+    result, exists := s.Matches[id]
+
+    switch tx.Type {
+    case TxTypeGameResult:
+        rewards, ok := MatchRewards(tx)
+        referrals, valid := MatchReferrals(tx)
+        if exists || !ok || !valid || checkMatchReferrals(s, rewards, referrals) != nil {
+            return
+        }
+        result = MatchResult{
+            MatchID:    id,
+            Server:     tx.Sender,
+            Height:     header.Index,
+            BlockHash:  header.Hash,
+            Rewards:    rewards,
+            Status:     MatchPending,
+            Breakdowns: matchBreakdowns(tx, rewards),
+        }
+        if len(referrals) > 0 {
+            result.Referrals = referrals
+        }
+
+    case TxTypeMatchVoid:
+        if exists && (result.Status != MatchPending || result.Server != tx.Sender) {
+            return
+        }
+        if !exists {
+            result = MatchResult{MatchID: id, Server: tx.Sender, Height: header.Index, BlockHash: header.Hash}
+        }
+        result.Status = MatchVoided
+        result.SettledAt = header.Index
+
+    case TxTypeMatchReward:
+        if !exists || result.Status != MatchPending {
+            return
+        }
+        for _, player := range sortedPlayers(result.Rewards) {
+            if reward := result.Rewards[player]; reward > 0 {
+                s.Mint(player, reward)
+            }
+        }
+        for _, referrer := range sortedPlayers(result.Referrals) {
+            if share := result.Referrals[referrer]; share > 0 {
+                s.Mint(referrer, share)
+            }
+        }
+        result.Status = MatchPaid
+        result.SettledAt = header.Index
+
+    default:
+        return
+    }
+
+    if s.Matches == nil {
+        s.Matches = make(map[string]MatchResult)
+    }
+    s.Matches[id] = result
+}
+
+// checkMatchReport reports whether a game result or void was signed by a game server the state allows to report matches
+// Other transactions always pass
+func checkMatchReport(state *State, tx Transaction) error {
+    if tx.Type != TxTypeGameResult && tx.Type != TxTypeMatchVoid {
+        return nil
+    }
+    if err := VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+    if !state.IsGameServer(tx.Sender) {
+        return fmt.Errorf("%s is not a game server genesis or governance allowed to report matches", tx.Sender)
+    }
+
+    return nil
+}
+
+// checkMatchReports reports whether every game result and void in a block continuing the chain was signed by
+// a game server, since recording a forged result would have its rewards minted
+// The caller must hold the lock
+func (bc *Blockchain) checkMatchReports(block Block) error {
+    state := bc.States.Latest()
+    for _, tx := range block.Transactions {
+        if err := checkMatchReport(state, tx); err != nil {
+            return fmt.Errorf("block %d: transaction %s: %w", block.Index, tx.ID, err)
+        }
+    }
+
+    return nil
+}
+
+// reportedOnly returns the transactions leaving out game results and voids no game server signed, keeping their order
+// The caller must hold the lock
+func (bc *Blockchain) reportedOnly(transactions []Transaction) []Transaction {
+    state := bc.States.Latest()
+    kept := []Transaction{}
+    for _, tx := range transactions {
+        if checkMatchReport(state, tx) == nil {
+            kept = append(kept, tx)
+        }
+    }
+
+    return kept
+}
+
+// checkMatch rejects a game result or void that could not be recorded: a result must report a match that has none
+// yet, with the rewards this node holds in escrow for it if any, and a void must be for a result still pending
+// from the sender
+// The caller must hold the lock
+func (bc *Blockchain) checkMatch(tx Transaction) error {
+    if tx.Type != TxTypeGameResult && tx.Type != TxTypeMatchVoid {
+        return nil
+    }
+    if err := checkMatchReport(bc.States.Latest(), tx); err != nil {
+        return err
+    }
+
+    id := matchID(tx)
+    if id == "" {
+        return errors.New("match ID is required")
+    }
+    result, exists := bc.States.Latest().Matches[id]
+
+    if tx.Type == TxTypeMatchVoid {
+        if exists && result.Status != MatchPending {
+            return fmt.Errorf("match %s has already been %s", id, result.Status)
+        }
+        if exists && result.Server != tx.Sender {
+            return errors.New("only the game server that reported a match can void it")
+        }
+        return nil
+    }
+
+    if exists {
+        return fmt.Errorf("match %s has already been reported", id)
+    }
+    rewards, ok := MatchRewards(tx)
+    if !ok || len(rewards) == 0 {
+        return errors.New("game result has invalid rewards")
+    }
+    referrals, ok := MatchReferrals(tx)
+    if !ok {
+        return errors.New("game result has invalid referral rewards")
+    }
+    if err := checkMatchReferrals(bc.States.Latest(), rewards, referrals); err != nil {
+        return err
+    }
+    if data, _ := tx.Data.(map[string]interface{}); data["breakdowns"] != nil {
+        if breakdowns := matchBreakdowns(tx, rewards); len(breakdowns) != len(rewards) {
+            return errors.New("game result breakdowns must explain every player's reward")
+        }
+    }
+
+    if bc.Economics != nil {
+        escrow, err := bc.Economics.GetMatchEscrow(id)
+        if err == nil && (!sameRewards(rewards, escrow.Rewards) || !sameRewards(referrals, escrow.Referrals)) {
+            return fmt.Errorf("game result rewards differ from the %s held in escrow for match %s", escrow.Total(), id)
+        }
+    }
+
+    return nil
+}
+
+// createMatchRewards builds the rewards for every pending match whose result is in a finalized block,
+// or in any earlier block without a finality source, by match ID
+// The caller must hold the lock
+func (bc *Blockchain) createMatchRewards(height int64, timestamp int64) []Transaction {
+    matches := bc.States.Latest().Matches
+
+    ids := make([]string, 0, len(matches))
+    for id, result := range matches {
+        if result.Status != MatchPending || result.Height >= height {
+            continue
+        }
+        if bc.Finality != nil && !bc.Finality.IsFinalized(result.Height, result.BlockHash) {
+            continue
+        }
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    rewards := make([]Transaction, 0, len(ids))
+    for _, id := range ids {
+        result := matches[id]
+
+        shares := make(map[string]interface{}, len(result.Rewards))
+        total := token.Amount(0)
+        for player, reward := range result.Rewards {
+            shares[player] = reward
+            total += reward
+        }
+
+        data := map[string]interface{}{
+            "match":   id,
+            "rewards": shares,
+        }
+        if len(result.Referrals) > 0 {
+            referrals := make(map[string]interface{}, len(result.Referrals))
+            for referrer, share := range result.Referrals {
+                referrals[referrer] = share
+                total += share
+            }
+            data["referrals"] = referrals
+        }
+
+        reward := Transaction{
+            Type:      TxTypeMatchReward,
+            Amount:    total,
+            Data:      data,
+            Timestamp: timestamp,
+        }
+        reward.ID = ComputeTransactionID(reward)
+        rewards = append(rewards, reward)
+    }
+
+    return rewards
+}
+
+// checkMatchRewards reports whether every match reward in a block continuing the chain pays a pending result
+// from an earlier block, once and exactly as reported
+// Whether the result's block has been finalized is only known to each node, so that is not checked
+// The caller must hold the lock
+func (bc *Blockchain) checkMatchRewards(block Block) error {
+    matches := bc.States.Latest().Matches
+    paid := make(map[string]bool)
+
+    for _, tx := range block.Transactions {
+        if tx.Type != TxTypeMatchReward {
+            continue
+        }
+
+        id := matchID(tx)
+        result, exists := matches[id]
+        if !exists || result.Status != MatchPending || result.Height >= block.Index {
+            return fmt.Errorf("block %d pays match %q, which has no pending result", block.Index, id)
+        }
+        if paid[id] {
+            return fmt.Errorf("block %d pays match %s more than once", block.Index, id)
+        }
+        paid[id] = true
+
+        rewards, ok := MatchRewards(tx)
+        referrals, valid := MatchReferrals(tx)
+        if !ok || !valid || !sameRewards(rewards, result.Rewards) || !sameRewards(referrals, result.Referrals) {
+            return fmt.Errorf("block %d pays match %s other rewards than its result reported", block.Index, id)
+        }
+        total := token.Amount(0)
+        for _, reward := range rewards {
+            total += reward
+        }
+        for _, share := range referrals {
+            total += share
+        }
+        if tx.Amount != total {
+            return fmt.Errorf("block %d pays match %s an amount that does not match its rewards", block.Index, id)
+        }
+    }
+
+    return nil
+}
+
+// settleMatchEscrows takes the rewards of matches an applied block paid or voided out of the token economics' escrow,
+// and forgets the escrows settled by blocks the chain can no longer be rolled back past
+// The caller must hold the write lock
+func (bc *Blockchain) settleMatchEscrows(block Block) {
+    if bc.Economics == nil {
+        return
+    }
+
+    matches := bc.States.Latest().Matches
+    for _, tx := range block.Transactions {
+        if tx.Type != TxTypeMatchReward && tx.Type != TxTypeMatchVoid {
+            continue
+        }
+
+        // Escrows are only held by the node of the server that hosted the match, so other nodes find none
+        result, exists := matches[matchID(tx)]
+        if !exists || result.SettledAt != block.Index {
+            continue
+        }
+        switch result.Status {
+        case MatchPaid, MatchVoided:
+            bc.Economics.SettleMatchRewards(result.MatchID, block.Index, result.Status == MatchVoided)
+        }
+    }
+
+    oldest, _ := bc.States.RetainedRange()
+    bc.Economics.ForgetSettledEscrows(oldest)
+}
+
+// matchID returns the match a game result, void or match reward is for
+func matchID(tx Transaction) string {
+    data, _ := tx.Data.(map[string]interface{})
+    id, _ := data["match"].(string)
+
+    return id
+}
+
+// matchBreakdowns returns the breakdowns a game result records for the rewards it reports, leaving out any
+// that don't arrive at the player's reward
+func matchBreakdowns(tx Transaction, rewards map[string]token.Amount) map[string]token.RewardBreakdown {
+    data, _ := tx.Data.(map[string]interface{})
+    if data["breakdowns"] == nil {
+        return nil
+    }
+
+    encoded, err := json.Marshal(data["breakdowns"])
+    if err != nil {
+        return nil
+    }
+    var recorded map[string]token.RewardBreakdown
+    if err := json.Unmarshal(encoded, &recorded); err != nil {
+        return nil
+    }
+
+    breakdowns := make(map[string]token.RewardBreakdown, len(recorded))
+    for player, breakdown := range recorded {
+        if reward, exists := rewards[player]; exists && breakdown.Reward == reward {
+            breakdowns[player] = breakdown
+        }
+    }
+    if len(breakdowns) == 0 {
+        return nil
+    }
+
+    return breakdowns
+}
+
+// sameRewards reports whether two sets of rewards pay the same players the same amounts
+func sameRewards(a map[string]token.Amount, b map[string]token.Amount) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for player, reward := range a {
+        if other, exists := b[player]; !exists || other != reward {
+            return false
+        }
+    }
+
+    return true
+}
+
+// sortedPlayers returns the players of a set of rewards in order, so they are paid alike on every node
+func sortedPlayers(rewards map[string]token.Amount) []string {
+    players := make([]string, 0, len(rewards))
+    for player := range rewards {
+        players = append(players, player)
+    }
+    sort.Strings(players)
+
+    return players
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+    "testing"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// matchReport returns an unsigned game result paying one player for a match
+func matchReport(server string, match string, player string, reward token.Amount) Transaction {
+    escrow := token.MatchEscrow{MatchID: match, Rewards: map[string]token.Amount{player: reward}}
+    tx := GameResultTransaction(server, escrow, time.Now().UnixNano())
+    tx.ID = ComputeTransactionID(tx)
+
+    return tx
+}
+
+// TestGameResultsMustComeFromGameServers checks that a game result is only recorded, and so only paid, when a game
+// server genesis allowed signed it, whether it arrives as a transaction, in a peer's block or applied unchecked
+func TestGameResultsMustComeFromGameServers(t *testing.T) {
+    server := newTestKey(t)
+    impostor := newTestKey(t)
+    genesis := fundedGenesis(map[string]token.Amount{server.address: 10 * token.ILYZ})
+    genesis.GameServers = []string{server.address}
+
+    bc := newFundedChain(t, genesis)
+    forged := map[string]Transaction{
+        "result from an address that is no game server": impostor.sign(t, matchReport(impostor.address, "forged", impostor.address, 1000*token.ILYZ)),
+        "unsigned result in a game server's name":       matchReport(server.address, "unsigned", impostor.address, 1000*token.ILYZ),
+    }
+    for name, tx := range forged {
+        if err := bc.CreateTransaction(tx); err == nil {
+            t.Fatalf("%s was admitted to the mempool", name)
+        }
+        if err := bc.AddBlock(unfilteredBlock(bc, "producer", nil, []Transaction{tx})); err == nil {
+            t.Fatalf("block holding a %s was accepted", name)
+        }
+    }
+
+    // A block applied without validation, as a tampered store would hold it, records neither
+    unchecked := newFundedChain(t, genesis)
+    unchecked.appendBlock(unfilteredBlock(unchecked, "producer", nil, []Transaction{forged["result from an address that is no game server"], forged["unsigned result in a game server's name"]}))
+    if matches := unchecked.States.Latest().Matches; len(matches) != 0 {
+        t.Fatalf("forged results recorded: %v", matches)
+    }
+
+    // The game server's signed result is recorded and accepted by peers
+    honest := server.sign(t, matchReport(server.address, "honest", "player", token.ILYZ))
+    if err := bc.CreateTransaction(honest); err != nil {
+        t.Fatal(err)
+    }
+    block := bc.CreateBlock("producer", nil)
+    if result, err := bc.GetMatchResult("honest"); err != nil || result.Status != MatchPending {
+        t.Fatalf("game server's result not recorded as pending: %v", err)
+    }
+    peer := newFundedChain(t, genesis)
+    if err := peer.AddBlock(block); err != nil {
+        t.Fatalf("peer rejected the game server's result: %v", err)
+    }
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+)
+
+// MerkleProofStep is one sibling hash on the path from a leaf, such as a transaction, to the Merkle root
+type MerkleProofStep struct {
+    Hash    string `json:"hash"`
+    IsRight bool   `json:"isRight"` // Whether the sibling sits to the right of the running hash
+}
+
+// TxInclusionProof lets a header-only client check that a transaction is in a block
+type TxInclusionProof struct {
+    TxHash      string            `json:"txHash"`
+    BlockHeight int64             `json:"blockHeight"`
+    TxRoot      string            `json:"txRoot"`
+    Steps       []MerkleProofStep `json:"steps"`
+}
+
+// HashTransaction returns the hash of a transaction used as a Merkle leaf
+func HashTransaction(tx Transaction) string {
+    hash, _ := CanonicalHash(tx)
+    return hash
+}
+
+// ComputeTxRoot returns the Merkle root of a block's transactions
+// An odd node at any level is paired with itself
+func ComputeTxRoot(transactions []Transaction) string {
+    return ComputeMerkleRoot(transactionLeaves(transactions))
+}
+
+// BuildTxProof builds the Merkle path for the transaction at the given index
+func BuildTxProof(transactions []Transaction, index int) ([]MerkleProofStep, error) {
+    if index < 0 || index >= len(transactions) {
+        return nil, errors.New("transaction index out of range")
+    }
+
+    return BuildMerkleProof(transactionLeaves(transactions), index)
+}
+
+// VerifyTxProof checks a Merkle path from a transaction hash to a transaction root
+func VerifyTxProof(txHash string, steps []MerkleProofStep, txRoot string) bool {
+    return VerifyMerkleProof(txHash, steps, txRoot)
+}
+
+// ComputeMerkleRoot returns the Merkle root of a list of leaf hashes
+// An empty list has the hash of no data as its root, and an odd node at any level is paired with itself
+func ComputeMerkleRoot(leaves []string) string {
+    if len(leaves) == 0 {
+        hash := sha256.Sum256([]byte{})
+        return hex.EncodeToString(hash[:])
+    }
+
+    level := leaves
+    for len(level) > 1 {
+        level = merkleParentLevel(level)
+    }
+
+    return level[0]
+}
+
+// BuildMerkleProof builds the Merkle path for the leaf at the given index
+func BuildMerkleProof(leaves []string, index int) ([]MerkleProofStep, error) {
+    if index < 0 || index >= len(leaves) {
+        return nil, errors.New("leaf index out of range")
+    }
+
+    level := leaves
+    steps := []MerkleProofStep{}
+    for len(level) > 1 {
+        sibling := index ^ 1
+        if sibling >= len(level) {
+            sibling = index
+        }
+
+        steps = append(steps, MerkleProofStep{
+            Hash:    level[sibling],
+            IsRight: sibling >= index,
+        })
+
+        level = merkleParentLevel(level)
+        index /= 2
+    }
+
+    return steps, nil
+}
+
+// VerifyMerkleProof checks a Merkle path from a leaf hash to a root
+func VerifyMerkleProof(leaf string, steps []MerkleProofStep, root string) bool {
+    current := leaf
+    for _, step := range steps {
+        if step.IsRight {
+            current = hashPair(current, step.Hash)
+        } else {
+            current = hashPair(step.Hash, current)
+        }
+    }
+
+    return current == root
+}
+
+// VerifyHeaderChain checks that headers are correctly hashed and link onto a trusted header
+// Header-only clients use it to follow the chain without downloading bodies
+func VerifyHeaderChain(trusted BlockHeader, headers []BlockHeader) error {
+    previous := trusted
+    for _, header := range headers {
+        if header.Hash != CalculateHeaderHash(header) {
+            return errors.New("header has an invalid hash")
+        }
+
+        if header.Index != previous.Index+1 || header.PrevHash != previous.Hash {
+            return errors.New("header does not link to the previous header")
+        }
+
+        previous = header
+    }
+
+    return nil
+}
+
+// transactionLeaves returns the Merkle leaves of a list of transactions
+func transactionLeaves(transactions []Transaction) []string {
+    leaves := make([]string, len(transactions))
+    for i, tx := range transactions {
+        leaves[i] = HashTransaction(tx)
+    }
+
+    return leaves
+}
+
+// merkleParentLevel hashes adjacent pairs of a Merkle tree level
+func merkleParentLevel(level []string) []string {
+    parents := []string{}
+    for i := 0; i < len(level); i += 2 {
+        if i+1 < len(level) {
+            parents = append(parents, hashPair(level[i], level[i+1]))
+        } else {
+            parents = append(parents, hashPair(level[i], level[i]))
+        }
+    }
+
+    return parents
+}
+
+// hashPair hashes two child nodes into their parent
+func hashPair(left string, right string) string {
+    hash := sha256.Sum256([]byte(left + right))
+    return hex.EncodeToString(hash[:])
+}
+
+// GetTxInclusionProof builds an inclusion proof for a confirmed transaction
+func (bc *Blockchain) GetTxInclusionProof(id string) (*TxInclusionProof, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    for _, header := range bc.Headers {
+        transactions := bc.Bodies[header.Hash].Transactions
+        for i, tx := range transactions {
+            if tx.ID != id {
+                continue
+            }
+
+            steps, err := BuildTxProof(transactions, i)
+            if err != nil {
+                return nil, err
+            }
+
+            return &TxInclusionProof{
+                TxHash:      HashTransaction(tx),
+                BlockHeight: header.Index,
+                TxRoot:      header.TxRoot,
+                Steps:       steps,
+            }, nil
+        }
+    }
+
+    return nil, errors.New("transaction not found")
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+    "sync"
+)
+
+// MetricsWindow is the number of recent blocks used for block interval and throughput averages
+const MetricsWindow = 100
+
+// ChainStats is a snapshot of chain runtime metrics
+type ChainStats struct {
+    Height               int64   `json:"height"`
+    AverageBlockInterval float64 `json:"averageBlockInterval"` // Seconds between recent blocks
+    TxPerSecond          float64 `json:"txPerSecond"`          // Confirmed transactions per second over recent blocks
+    MempoolDepth         int     `json:"mempoolDepth"`
+    TotalTransactions    int64   `json:"totalTransactions"`
+    Reorgs               int64   `json:"reorgs"`
+    MaxReorgDepth        int64   `json:"maxReorgDepth"`
+}
+
+// blockSample is the part of a block the metrics window keeps
+type blockSample struct {
+    Timestamp int64
+    TxCount   int
+}
+
+// ChainMetrics collects runtime metrics as blocks are added and reverted
+type ChainMetrics struct {
+    // Most recent blocks, oldest first
+    recent []blockSample
+
+    totalTransactions int64
+    reorgs            int64
+    maxReorgDepth     int64
+
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// NewChainMetrics creates an empty metrics collector
+func NewChainMetrics() *ChainMetrics {
+    return &ChainMetrics{
+        recent: []blockSample{},
+    }
+}
+
+// RecordBlock records a block added to the chain
+func (m *ChainMetrics) RecordBlock(block Block) {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+
+    // Coinbases and vote rewards are not user transactions
+    txCount := 0
+    for _, tx := range block.Transactions {
+        if !IsProducerTransaction(tx) {
+            txCount++
+        }
+    }
+
+    m.recent = append(m.recent, blockSample{Timestamp: block.Timestamp, TxCount: txCount})
+    if len(m.recent) > MetricsWindow {
+        m.recent = m.recent[len(m.recent)-MetricsWindow:]
+    }
+
+    m.totalTransactions += int64(txCount)
+}
+
+// RecordReorg records blocks removed from the tip of the chain
+func (m *ChainMetrics) RecordReorg(removed []Block) {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+
+    depth := int64(len(removed))
+    if depth == 0 {
+        return
+    }
+
+    m.reorgs++
+    if depth > m.maxReorgDepth {
+        m.maxReorgDepth = depth
+    }
+
+    // Drop reverted blocks from the window and the totals
+    for _, block := range removed {
+        for _, tx := range block.Transactions {
+            if !IsProducerTransaction(tx) {
+                m.totalTransactions--
+            }
+        }
+    }
+
+    if depth >= int64(len(m.recent)) {
+        m.recent = []blockSample{}
+    } else {
+        m.recent = m.recent[:int64(len(m.recent))-depth]
+    }
+}
+
+// Snapshot returns the collected metrics
+// Height and mempool depth are owned by the chain and left for the caller to fill in
+func (m *ChainMetrics) Snapshot() ChainStats {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+
+    stats := ChainStats{
+        TotalTransactions: m.totalTransactions,
+        Reorgs:            m.reorgs,
+        MaxReorgDepth:     m.maxReorgDepth,
+    }
+
+    // Averages need at least two blocks to span a time interval
+    if len(m.recent) < 2 {
+        return stats
+    }
+
+    first := m.recent[0]
+    last := m.recent[len(m.recent)-1]
+    elapsed := float64(last.Timestamp - first.Timestamp)
+    if elapsed <= 0 {
+        return stats
+    }
+
+    // The first block's transactions were confirmed before the window started
+    txCount := 0
+    for _, sample := range m.recent[1:] {
+        txCount += sample.TxCount
+    }
+
+    stats.AverageBlockInterval = elapsed / float64(len(m.recent)-1)
+    stats.TxPerSecond = float64(txCount) / elapsed
+
+    return stats
+}
+
+// Stats returns the chain's current runtime metrics
+func (bc *Blockchain) Stats() ChainStats {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    stats := bc.Metrics.Snapshot()
+    stats.Height = bc.Headers[len(bc.Headers)-1].Index
+    stats.MempoolDepth = len(bc.PendingTransactions)
+
+    return stats
+}
@@ -0,0 +1,141 @@
+package core
+
+// Module keeps state for transaction types owned by another package, such as the NFT registry
+// Modules see exactly the confirmed blocks, so their state can always be rebuilt by replaying the chain
+type Module interface {
+    // CheckTransaction reports whether a transaction would succeed against the module's current state
+    // Transactions of types the module doesn't own must return nil
+    CheckTransaction(tx Transaction) error
+
+    // ApplyTransaction executes a confirmed transaction after the core state has applied it
+    // Modules may move balances in state; a transaction that fails has no effect on the module
+    ApplyTransaction(tx Transaction, header BlockHeader, state *State)
+
+    // EndBlock runs once all of a block's transactions have been applied
+    EndBlock(header BlockHeader, state *State)
+
+    // Reset clears all module state before the chain replays blocks into it
+    Reset()
+
+    // Fork returns an empty module with the same configuration, used to replay the chain without touching this one
+    Fork() Module
+}
+
+// EventSource is implemented by modules that report events for the blocks they execute
+// Events are only published for blocks appended to the chain, never for rebuilds or replays
+type EventSource interface {
+    // DrainEvents returns and clears the events recorded since it was last called
+    DrainEvents() []ChainEvent
+}
+
+// StateCommitter is implemented by modules whose state is committed to in the block state root
+type StateCommitter interface {
+    // StateCommitment returns the name the module's root is recorded under and a deterministic root of its state
+    StateCommitment() (string, string)
+}
+
+// RegisterModule adds a module that is applied to every new block
+// The module must already reflect the current chain; use RebuildModules once every module is registered if it may not
+func (bc *Blockchain) RegisterModule(module Module) {
+    bc.mutex.Lock()
+    defer bc.mutex.Unlock()
+
+    bc.modules = append(bc.modules, module)
+}
+
+// RebuildModules resets every registered module and re-executes the chain into them together, replacing the states
+// the chain recorded with the ones this produces
+// Modules move balances as they apply blocks, so the states are only right once every module has seen every block
+func (bc *Blockchain) RebuildModules() {
+    bc.mutex.Lock()
+    defer bc.mutex.Unlock()
+
+    bc.rebuildModules(bc.Headers[len(bc.Headers)-1].Index)
+}
+
+// rebuildModules resets every registered module and re-executes blocks up to a height into them together,
+// committing each state to a fresh store that replaces the chain's
+// The caller must hold the write lock
+func (bc *Blockchain) rebuildModules(height int64) {
+    for _, module := range bc.modules {
+        module.Reset()
+    }
+
+    // The store's settings were validated when the chain was created
+    states, _ := NewStateStore(bc.States.Mode, bc.States.Retention)
+    state := NewState()
+    for h := int64(0); h <= height; h++ {
+        block := bc.blockAt(h)
+        state = state.Copy()
+
+        bc.Clock.Enter(block.Timestamp)
+        executeBlock(block, state, bc.modules)
+        bc.Clock.Leave()
+
+        states.Commit(h, state)
+    }
+
+    bc.States = states
+    bc.syncSupply()
+}
+
+// checkModules runs a transaction past every module's admission checks
+// The caller must hold the lock
+func (bc *Blockchain) checkModules(tx Transaction) error {
+    for _, module := range bc.modules {
+        if err := module.CheckTransaction(tx); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// executeBlock collects the fees of a block's transactions and applies those their senders could pay for in order
+// to a state and a set of modules, then releases what has vested by the block's time so modules ending the block see it
+// A transaction its sender can't pay for is left out entirely, as the coinbase doesn't count its fee
+// Modules whose settings the block changed apply them, and modules that commit to their state have their roots
+// recorded in the state, once the block ends
+// It returns the events the modules recorded for the block
+func executeBlock(block Block, state *State, modules []Module) []ChainEvent {
+    settings := state.Settings
+    funded := state.collectFees(block.Transactions)
+    for i, tx := range block.Transactions {
+        if !funded[i] {
+            continue
+        }
+
+        state.ApplyTransaction(tx)
+        state.applyMatchTransaction(tx, block.BlockHeader)
+        state.applyReferral(tx, block.BlockHeader)
+        state.applyTournament(tx, block.BlockHeader)
+        for _, module := range modules {
+            module.ApplyTransaction(tx, block.BlockHeader, state)
+        }
+    }
+    state.ReleaseVested(block.Timestamp)
+
+    events := []ChainEvent{}
+    for _, module := range modules {
+        module.EndBlock(block.BlockHeader, state)
+
+        if source, ok := module.(EventSource); ok {
+            events = append(events, source.DrainEvents()...)
+        }
+    }
+
+    // Settings genesis or governance changed take effect from the next block
+    applySettingsChanges(settings, state, modules)
+
+    for _, module := range modules {
+        if committer, ok := module.(StateCommitter); ok {
+            name, root := committer.StateCommitment()
+            if state.ModuleRoots == nil {
+                state.ModuleRoots = make(map[string]string)
+            }
+            state.ModuleRoots[name] = root
+        }
+    }
+
+    return events
+}
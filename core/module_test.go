@@ -0,0 +1,124 @@
+package core
+
+import (
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Transaction type and escrow account of lockModule
+const (
+    txTypeTestLock = "test_lock"
+    testLockEscrow = "test_locks"
+)
+
+// lockModule holds what senders lock in an escrow account, as the modules holding stakes, deposits or sales do
+type lockModule struct {
+    locked map[string]token.Amount
+}
+
+// newLockModule creates a lock module holding nothing
+func newLockModule() *lockModule {
+    return &lockModule{locked: make(map[string]token.Amount)}
+}
+
+// CheckTransaction accepts every transaction
+func (m *lockModule) CheckTransaction(tx Transaction) error {
+    return nil
+}
+
+// ApplyTransaction moves a signed lock's amount from its sender to the escrow
+func (m *lockModule) ApplyTransaction(tx Transaction, header BlockHeader, state *State) {
+    if tx.Type != txTypeTestLock || VerifyTransactionSignature(tx) != nil {
+        return
+    }
+    if state.Transfer(tx.Sender, testLockEscrow, tx.Amount) != nil {
+        return
+    }
+    m.locked[tx.Sender] += tx.Amount
+}
+
+// EndBlock does nothing
+func (m *lockModule) EndBlock(header BlockHeader, state *State) {}
+
+// Reset forgets every lock
+func (m *lockModule) Reset() {
+    m.locked = make(map[string]token.Amount)
+}
+
+// Fork returns an empty lock module
+func (m *lockModule) Fork() Module {
+    return newLockModule()
+}
+
+// TestRestartKeepsModuleBalances checks that a chain restored from an export records the balances its modules moved,
+// whether the modules were registered before the blocks were loaded or rebuilt after
+func TestRestartKeepsModuleBalances(t *testing.T) {
+    owner := newTestKey(t)
+    genesis := fundedGenesis(map[string]token.Amount{owner.address: 10 * token.ILYZ})
+
+    live := newFundedChain(t, genesis)
+    live.RegisterModule(newLockModule())
+    live.RebuildModules()
+    lock := owner.sign(t, Transaction{
+        Type:      txTypeTestLock,
+        Sender:    owner.address,
+        Amount:    4 * token.ILYZ,
+        Fee:       token.ILYZ,
+        Timestamp: time.Now().UnixNano(),
+    })
+    if err := live.CreateTransaction(lock); err != nil {
+        t.Fatal(err)
+    }
+    live.CreateBlock("producer", nil)
+
+    want := live.States.Latest()
+    if want.Balances[owner.address] != 5*token.ILYZ || want.Balances[testLockEscrow] != 4*token.ILYZ {
+        t.Fatalf("owner holds %s and escrow %s before the restart", want.Balances[owner.address], want.Balances[testLockEscrow])
+    }
+
+    path := filepath.Join(t.TempDir(), "chain.export")
+    if err := live.ExportChain(path, 0, live.GetHeight()); err != nil {
+        t.Fatal(err)
+    }
+
+    // A node registers its modules before loading its saved blocks
+    restarted := newFundedChain(t, genesis)
+    module := newLockModule()
+    restarted.RegisterModule(module)
+    restarted.RebuildModules()
+    if _, err := restarted.ImportChain(path); err != nil {
+        t.Fatal(err)
+    }
+    checkRestartedState(t, "loaded with the module registered", restarted, module, owner.address, want)
+
+    // Rebuilding a module registered after the blocks were loaded recomputes the states they recorded
+    late := newFundedChain(t, genesis)
+    if _, err := late.ImportChain(path); err != nil {
+        t.Fatal(err)
+    }
+    lateModule := newLockModule()
+    late.RegisterModule(lateModule)
+    late.RebuildModules()
+    checkRestartedState(t, "module rebuilt after loading", late, lateModule, owner.address, want)
+}
+
+// checkRestartedState compares the latest state of a restarted chain and what its lock module holds with the state
+// before the restart
+func checkRestartedState(t *testing.T, name string, bc *Blockchain, module *lockModule, owner string, want *State) {
+    t.Helper()
+
+    got := bc.States.Latest()
+    if got.Balances[owner] != want.Balances[owner] || got.Balances[testLockEscrow] != want.Balances[testLockEscrow] {
+        t.Fatalf("%s: owner holds %s and escrow %s, want %s and %s", name, got.Balances[owner],
+            got.Balances[testLockEscrow], want.Balances[owner], want.Balances[testLockEscrow])
+    }
+    if got.Root() != want.Root() {
+        t.Fatalf("%s: state root %s, want %s", name, got.Root(), want.Root())
+    }
+    if module.locked[owner] != want.Balances[testLockEscrow] {
+        t.Fatalf("%s: module holds %s for the owner, want %s", name, module.locked[owner], want.Balances[testLockEscrow])
+    }
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+    "fmt"
+)
+
+// ParamsSource reports which version of the consensus parameters is in effect
+// Blocks reference the version they were produced under, so nodes running different parameters reject each other's blocks
+type ParamsSource interface {
+    // ParamsVersion returns the version of the parameters the next block is produced under
+    ParamsVersion() int64
+}
+
+// paramsVersion returns the parameter version the next block must reference, 0 without a parameter source
+// The caller must hold the lock
+func (bc *Blockchain) paramsVersion() int64 {
+    if bc.Params == nil {
+        return 0
+    }
+
+    return bc.Params.ParamsVersion()
+}
+
+// checkParamsVersion reports whether a block continuing the chain references the parameter version in effect
+// The caller must hold the lock
+func (bc *Blockchain) checkParamsVersion(block Block) error {
+    if expected := bc.paramsVersion(); block.ParamsVersion != expected {
+        return fmt.Errorf("block %d references parameter version %d, expected %d", block.Index, block.ParamsVersion, expected)
+    }
+
+    return nil
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeReferral binds a new player to whoever referred them, as the first transaction of the player's wallet
+// Sender: the new player; Data: referrer, the referrer's address
+const TxTypeReferral = "referral"
+
+// Referral is who referred a player and when the referral was bound on chain
+type Referral struct {
+    Player   string `json:"player"`
+    Referrer string `json:"referrer"`
+    BoundAt  int64  `json:"boundAt"` // Time of the block that bound it; referral rewards are paid for a period from it
+    Height   int64  `json:"height"`
+}
+
+// ReferralTransaction returns an unsigned transaction binding a new player's wallet to its referrer, sent by the player
+func ReferralTransaction(player string, referrer string, timestamp int64) Transaction {
+    return Transaction{
+        Type:      TxTypeReferral,
+        Sender:    player,
+        Data:      map[string]interface{}{"referrer": referrer},
+        Timestamp: timestamp,
+    }
+}
+
+// GetReferral returns who referred a player as of the latest block
+func (bc *Blockchain) GetReferral(player string) (Referral, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    referral, exists := bc.States.Latest().Referrals[player]
+    if !exists {
+        return Referral{}, fmt.Errorf("%s was not referred", player)
+    }
+
+    return referral, nil
+}
+
+// GetReferredPlayers returns the players an address referred as of the latest block, earliest bound first
+func (bc *Blockchain) GetReferredPlayers(referrer string) []Referral {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    referred := []Referral{}
+    for _, referral := range bc.States.Latest().Referrals {
+        if referral.Referrer == referrer {
+            referred = append(referred, referral)
+        }
+    }
+    sort.Slice(referred, func(i, j int) bool {
+        if referred[i].Height != referred[j].Height {
+            return referred[i].Height < referred[j].Height
+        }
+        return referred[i].Player < referred[j].Player
+    })
+
+    return referred
+}
+
+// applyReferral binds a player to their referrer in the block with a header
+// A player is bound once, only to someone else, and only by a referral they signed, since blocks from peers are applied
+// without the admission checks
+func (s *State) applyReferral(tx Transaction, header BlockHeader) {
+    if tx.Type != TxTypeReferral || VerifyTransactionSignature(tx) != nil {
+        return
+    }
+
+    referrer := txReferrer(tx)
+    if referrer == "" || referrer == tx.Sender {
+        return
+    }
+    if _, exists := s.Referrals[tx.Sender]; exists {
+        return
+    }
+
+    if s.Referrals == nil {
+        s.Referrals = make(map[string]Referral)
+    }
+    s.Referrals[tx.Sender] = Referral{
+        Player:   tx.Sender,
+        Referrer: referrer,
+        BoundAt:  header.Timestamp,
+        Height:   header.Index,
+    }
+}
+
+// checkReferral rejects a referral the player didn't sign, that names no one else, or that comes after the player's
+// wallet was bound or started holding ILYZ, since a referral is made as the wallet is created
+// The caller must hold the lock
+func (bc *Blockchain) checkReferral(tx Transaction) error {
+    if tx.Type != TxTypeReferral {
+        return nil
+    }
+
+    if err := VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+
+    referrer := txReferrer(tx)
+    if referrer == "" {
+        return errors.New("referrer is required")
+    }
+    if referrer == tx.Sender {
+        return errors.New("players can't refer themselves")
+    }
+
+    state := bc.States.Latest()
+    if referral, exists := state.Referrals[tx.Sender]; exists {
+        return fmt.Errorf("%s was already referred by %s", tx.Sender, referral.Referrer)
+    }
+    if state.Balances[tx.Sender] > 0 {
+        return errors.New("referrals are bound when a wallet is created, before it holds any ILYZ")
+    }
+
+    return nil
+}
+
+// checkMatchReferrals rejects referral rewards paid to anyone who didn't refer one of a match's players
+func checkMatchReferrals(state *State, rewards map[string]token.Amount, referrals map[string]token.Amount) error {
+    referrers := make(map[string]bool)
+    for player := range rewards {
+        if referral, exists := state.Referrals[player]; exists {
+            referrers[referral.Referrer] = true
+        }
+    }
+
+    for referrer := range referrals {
+        if !referrers[referrer] {
+            return fmt.Errorf("%s referred none of the match's players", referrer)
+        }
+    }
+
+    return nil
+}
+
+// txReferrer returns the referrer a referral names
+func txReferrer(tx Transaction) string {
+    data, _ := tx.Data.(map[string]interface{})
+    referrer, _ := data["referrer"].(string)
+
+    return referrer
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+    "fmt"
+)
+
+// ReplayMismatch records a height where re-execution produced a different state root
+type ReplayMismatch struct {
+    Height       int64  `json:"height"`
+    StoredRoot   string `json:"storedRoot"`
+    ReplayedRoot string `json:"replayedRoot"`
+}
+
+// ReplayReport summarizes a replay from genesis
+type ReplayReport struct {
+    BlocksReplayed int64            `json:"blocksReplayed"`
+    FinalRoot      string           `json:"finalRoot"`
+    Mismatches     []ReplayMismatch `json:"mismatches"`
+}
+
+// Replay re-executes every block from genesis against a fresh state store and compares the
+// resulting state root at each height with the one recorded when the block was first executed
+// The fresh store is an archive store, so every replayed version can be inspected afterwards
+// When stopOnMismatch is set, replay stops at the first divergence
+func (bc *Blockchain) Replay(stopOnMismatch bool) (*ReplayReport, *StateStore, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    replayed, err := NewStateStore(StorageModeArchive, 0)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    report := &ReplayReport{
+        Mismatches: []ReplayMismatch{},
+    }
+
+    // Modules move balances too, so replay them on empty forks that leave the live modules untouched
+    modules := make([]Module, len(bc.modules))
+    for i, module := range bc.modules {
+        modules[i] = module.Fork()
+    }
+
+    state := NewState()
+    for i := range bc.Headers {
+        block := bc.blockAt(int64(i))
+
+        // The chain itself must be intact before its execution can be compared
+        if err := bc.ValidateBlock(block); err != nil {
+            return report, replayed, err
+        }
+        if i > 0 && block.PrevHash != bc.Headers[i-1].Hash {
+            return report, replayed, fmt.Errorf("block %d does not link to block %d", block.Index, bc.Headers[i-1].Index)
+        }
+
+        state = state.Copy()
+        executeBlock(block, state, modules)
+        replayed.Commit(block.Index, state)
+
+        root := state.Root()
+        report.BlocksReplayed++
+        report.FinalRoot = root
+
+        if storedRoot, exists := bc.States.RootAt(block.Index); exists && storedRoot != root {
+            report.Mismatches = append(report.Mismatches, ReplayMismatch{
+                Height:       block.Index,
+                StoredRoot:   storedRoot,
+                ReplayedRoot: root,
+            })
+
+            if stopOnMismatch {
+                break
+            }
+        }
+    }
+
+    return report, replayed, nil
+}
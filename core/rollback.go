@@ -0,0 +1,78 @@
+package core
+
+import (
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// RollbackTo reverts the chain to the given height
+// Blocks above the height are removed, the state is reverted to the version at that height,
+// the token economics take off the rewards the removed blocks minted this year and hold the match rewards they settled
+// in escrow again, and the transactions from removed blocks are returned to the mempool ahead of pending ones
+func (bc *Blockchain) RollbackTo(height int64) ([]Block, error) {
+    bc.mutex.Lock()
+    defer bc.mutex.Unlock()
+
+    latestHeight := bc.Headers[len(bc.Headers)-1].Index
+    if height < 0 || height > latestHeight {
+        return nil, fmt.Errorf("invalid rollback height %d (chain height is %d)", height, latestHeight)
+    }
+
+    if height == latestHeight {
+        return []Block{}, nil
+    }
+
+    // Revert state first so a pruned target leaves the chain untouched
+    if err := bc.States.Truncate(height); err != nil {
+        return nil, err
+    }
+
+    removed := []Block{}
+    for h := height + 1; h <= latestHeight; h++ {
+        block := bc.blockAt(h)
+        removed = append(removed, block)
+        delete(bc.Bodies, block.Hash)
+    }
+    bc.Headers = bc.Headers[:height+1]
+    bc.revertEconomics(height, removed)
+
+    // Modules don't keep history, so rebuild them together from the remaining blocks
+    bc.rebuildModules(height)
+    bc.syncBaseFee()
+
+    // Re-open the mempool for reverted transactions, keeping their original order
+    reverted := []Transaction{}
+    for _, block := range removed {
+        for _, tx := range block.Transactions {
+            // Coinbases and vote rewards belong to the reverted block and are not replayed
+            if !IsProducerTransaction(tx) {
+                reverted = append(reverted, tx)
+            }
+        }
+    }
+    bc.PendingTransactions = append(reverted, bc.PendingTransactions...)
+    bc.Metrics.RecordReorg(removed)
+
+    return removed, nil
+}
+
+// revertEconomics takes the rewards removed blocks minted since the year started off the economics' yearly minted count
+// and holds the match rewards they paid or voided in escrow again
+// A rollback across a year transition leaves the new year in place
+// The caller must hold the write lock
+func (bc *Blockchain) revertEconomics(height int64, removed []Block) {
+    if bc.Economics == nil {
+        return
+    }
+
+    yearStart := bc.Economics.GetYearStartTime()
+    rewards := token.Amount(0)
+    for _, block := range removed {
+        if block.Timestamp >= yearStart {
+            rewards += BlockRewards(block)
+        }
+    }
+    bc.Economics.RevertBlockRewards(rewards)
+    bc.Economics.RestoreMatchRewards(height)
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+    "testing"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TestRollbackAcrossMatchSettlement checks that rolling back the block paying a match takes its rewards off the
+// yearly minted count and holds them in escrow again, so producing the block again pays and counts them once
+func TestRollbackAcrossMatchSettlement(t *testing.T) {
+    server := newTestKey(t)
+    genesis := fundedGenesis(map[string]token.Amount{server.address: 10 * token.ILYZ})
+    genesis.GameServers = []string{server.address}
+    bc := newFundedChain(t, genesis)
+
+    reward := 5 * token.ILYZ
+    economics := token.NewTokenEconomics("master")
+    economics.Escrows["match"] = &token.MatchEscrow{MatchID: "match", Rewards: map[string]token.Amount{"player": reward}}
+    economics.Reserved = reward
+    bc.SetEconomics(economics)
+
+    if err := bc.CreateTransaction(server.sign(t, matchReport(server.address, "match", "player", reward))); err != nil {
+        t.Fatal(err)
+    }
+    bc.CreateBlock("producer", nil)
+    minted := economics.YearlyMinted
+
+    paid := bc.CreateBlock("producer", nil)
+    if result, err := bc.GetMatchResult("match"); err != nil || result.Status != MatchPaid {
+        t.Fatalf("match not paid by block %d: %v", paid.Index, err)
+    }
+    if _, err := economics.GetMatchEscrow("match"); err == nil || economics.Reserved != 0 {
+        t.Fatalf("escrow still held after the match was paid, %s reserved", economics.Reserved)
+    }
+    if economics.YearlyMinted != minted+BlockRewards(paid) {
+        t.Fatalf("yearly minted %s, want %s", economics.YearlyMinted, minted+BlockRewards(paid))
+    }
+
+    if _, err := bc.RollbackTo(1); err != nil {
+        t.Fatal(err)
+    }
+    if economics.YearlyMinted != minted {
+        t.Fatalf("yearly minted %s after rolling back, want %s", economics.YearlyMinted, minted)
+    }
+    escrow, err := economics.GetMatchEscrow("match")
+    if err != nil || escrow.Total() != reward || economics.Reserved != reward {
+        t.Fatalf("escrow not held again after rolling back: %v, %s reserved", err, economics.Reserved)
+    }
+    if result, err := bc.GetMatchResult("match"); err != nil || result.Status != MatchPending {
+        t.Fatalf("match not pending after rolling back: %v", err)
+    }
+
+    // Producing the block again pays the match and counts its rewards once
+    again := bc.CreateBlock("producer", nil)
+    if economics.YearlyMinted != minted+BlockRewards(again) || economics.Reserved != 0 {
+        t.Fatalf("yearly minted %s with %s reserved, want %s with none", economics.YearlyMinted, economics.Reserved, minted+BlockRewards(again))
+    }
+    if _, err := economics.GetMatchEscrow("match"); err == nil {
+        t.Fatal("escrow still held after the match was paid again")
+    }
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "sort"
+)
+
+// Genesis transaction types recording the consensus-critical settings a network starts with
+const (
+    TxTypeGenesisGameServer = "genesis_game_server" // Genesis only; Recipient: a game server allowed to report matches and attest to activity
+    TxTypeGenesisSettings   = "genesis_settings"    // Genesis only; Data: module, the name its settings are kept under; settings, the settings
+)
+
+// Configurable is implemented by modules whose consensus-critical settings are set by genesis and replaced by governance,
+// so every node applies blocks by the same settings whatever its local configuration
+type Configurable interface {
+    // SettingsName returns the name the module's settings are kept under in genesis, the state and proposals
+    SettingsName() string
+
+    // ValidateSettings reports whether settings could be applied
+    ValidateSettings(settings json.RawMessage) error
+
+    // ApplySettings replaces the module's settings, taking effect from the next block
+    // Settings that don't validate leave the module's settings as they were
+    ApplySettings(settings json.RawMessage)
+}
+
+// IsGenesisTransaction reports whether a transaction type may only appear in the genesis block
+func IsGenesisTransaction(tx Transaction) bool {
+    switch tx.Type {
+    case "genesis_alloc", TxTypeGenesisVesting, TxTypeGenesisAsset, TxTypeGenesisGameServer, TxTypeGenesisSettings:
+        return true
+    }
+
+    return false
+}
+
+// IsGameServer reports whether an address is a game server genesis or governance allowed to report matches
+func (s *State) IsGameServer(address string) bool {
+    return s.GameServers[address]
+}
+
+// SetGameServer allows or stops an address reporting matches as a game server
+// The set is replaced rather than changed in place, so state copies can share it
+func (s *State) SetGameServer(address string, allowed bool) {
+    servers := make(map[string]bool, len(s.GameServers)+1)
+    for server := range s.GameServers {
+        servers[server] = true
+    }
+    if allowed {
+        servers[address] = true
+    } else {
+        delete(servers, address)
+    }
+
+    s.GameServers = servers
+}
+
+// ModuleSettings returns the settings in force for a module, nil if neither genesis nor governance set any
+func (s *State) ModuleSettings(name string) json.RawMessage {
+    return s.Settings[name]
+}
+
+// SetModuleSettings replaces a module's settings; the module applies them once the block ends
+// The settings are replaced rather than changed in place, so state copies can share them
+func (s *State) SetModuleSettings(name string, settings json.RawMessage) {
+    replaced := make(map[string]json.RawMessage, len(s.Settings)+1)
+    for module, current := range s.Settings {
+        replaced[module] = current
+    }
+    replaced[name] = append(json.RawMessage(nil), settings...)
+
+    s.Settings = replaced
+}
+
+// CheckSettings reports whether a module keeps each of the genesis settings and would apply them
+func (g *Genesis) CheckSettings(modules []Configurable) error {
+    for name, settings := range g.Settings {
+        var module Configurable
+        for _, candidate := range modules {
+            if candidate.SettingsName() == name {
+                module = candidate
+            }
+        }
+        if module == nil {
+            return fmt.Errorf("genesis has settings for %q, which no module keeps", name)
+        }
+        if err := module.ValidateSettings(settings); err != nil {
+            return fmt.Errorf("genesis has invalid %s settings: %w", name, err)
+        }
+    }
+
+    return nil
+}
+
+// applyGenesisSettings records a game server or a module's settings from a genesis transaction
+func (s *State) applyGenesisSettings(tx Transaction) {
+    switch tx.Type {
+    case TxTypeGenesisGameServer:
+        if tx.Recipient != "" {
+            s.SetGameServer(tx.Recipient, true)
+        }
+
+    case TxTypeGenesisSettings:
+        data, _ := tx.Data.(map[string]interface{})
+        name, _ := data["module"].(string)
+        settings, err := json.Marshal(data["settings"])
+        if name != "" && err == nil {
+            s.SetModuleSettings(name, settings)
+        }
+    }
+}
+
+// applySettingsChanges hands each configurable module the settings a block gave it, whether from genesis or from
+// an accepted proposal, comparing them with those in force before the block
+func applySettingsChanges(previous map[string]json.RawMessage, state *State, modules []Module) {
+    for _, module := range modules {
+        configurable, ok := module.(Configurable)
+        if !ok {
+            continue
+        }
+
+        name := configurable.SettingsName()
+        if settings, exists := state.Settings[name]; exists && !bytes.Equal(settings, previous[name]) {
+            configurable.ApplySettings(settings)
+        }
+    }
+}
+
+// checkGenesisOnly rejects a block after genesis that holds a transaction only genesis may hold
+func checkGenesisOnly(block Block) error {
+    for _, tx := range block.Transactions {
+        if IsGenesisTransaction(tx) {
+            return fmt.Errorf("block %d: %s transaction %s may only appear in the genesis block", block.Index, tx.Type, tx.ID)
+        }
+    }
+
+    return nil
+}
+
+// gameServerTransaction builds the genesis transaction allowing a game server to report matches
+func gameServerTransaction(server string, timestamp int64) Transaction {
+    tx := Transaction{
+        Type:      TxTypeGenesisGameServer,
+        Recipient: server,
+        Timestamp: timestamp,
+    }
+    tx.ID = ComputeTransactionID(tx)
+
+    return tx
+}
+
+// settingsTransactions builds the genesis transactions setting each module's settings, in module name order
+// Settings are decoded into generic values, so the transactions read the same once decoded from a peer or an export
+func settingsTransactions(settings map[string]json.RawMessage, timestamp int64) []Transaction {
+    names := make([]string, 0, len(settings))
+    for name := range settings {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    transactions := make([]Transaction, 0, len(names))
+    for _, name := range names {
+        var value interface{}
+        if err := json.Unmarshal(settings[name], &value); err != nil {
+            continue
+        }
+
+        tx := Transaction{
+            Type:      TxTypeGenesisSettings,
+            Data:      map[string]interface{}{"module": name, "settings": value},
+            Timestamp: timestamp,
+        }
+        tx.ID = ComputeTransactionID(tx)
+        transactions = append(transactions, tx)
+    }
+
+    return transactions
+}
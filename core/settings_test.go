@@ -0,0 +1,97 @@
+package core
+
+import (
+    "encoding/json"
+    "testing"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// settingsModule keeps the settings genesis and governance give it, as the consensus engine and NFT registry do
+type settingsModule struct {
+    applied []string
+}
+
+// SettingsName returns the name the module's settings are kept under
+func (m *settingsModule) SettingsName() string {
+    return "test"
+}
+
+// ValidateSettings accepts any JSON object
+func (m *settingsModule) ValidateSettings(settings json.RawMessage) error {
+    var decoded map[string]interface{}
+    return json.Unmarshal(settings, &decoded)
+}
+
+// ApplySettings records the settings applied
+func (m *settingsModule) ApplySettings(settings json.RawMessage) {
+    m.applied = append(m.applied, string(settings))
+}
+
+// CheckTransaction accepts every transaction
+func (m *settingsModule) CheckTransaction(tx Transaction) error {
+    return nil
+}
+
+// ApplyTransaction does nothing
+func (m *settingsModule) ApplyTransaction(tx Transaction, header BlockHeader, state *State) {}
+
+// EndBlock does nothing
+func (m *settingsModule) EndBlock(header BlockHeader, state *State) {}
+
+// Reset forgets the settings applied
+func (m *settingsModule) Reset() {
+    m.applied = nil
+}
+
+// Fork returns a module with no settings applied
+func (m *settingsModule) Fork() Module {
+    return &settingsModule{}
+}
+
+// TestGenesisSettingsReachModules checks that the game servers and module settings a genesis sets are recorded in the
+// state and applied to the modules as the genesis block is replayed, and that no later block can set them
+func TestGenesisSettingsReachModules(t *testing.T) {
+    owner := newTestKey(t)
+    genesis := fundedGenesis(map[string]token.Amount{owner.address: 10 * token.ILYZ})
+    genesis.GameServers = []string{"server"}
+    genesis.Settings = map[string]json.RawMessage{"test": json.RawMessage(`{"level": 2}`)}
+
+    module := &settingsModule{}
+    if err := genesis.CheckSettings([]Configurable{module}); err != nil {
+        t.Fatal(err)
+    }
+    if err := genesis.CheckSettings(nil); err == nil {
+        t.Fatal("genesis settings no module keeps were accepted")
+    }
+
+    bc := newFundedChain(t, genesis)
+    bc.RegisterModule(module)
+    bc.RebuildModules()
+
+    state := bc.States.Latest()
+    if !state.IsGameServer("server") || state.IsGameServer(owner.address) {
+        t.Fatalf("game servers %v, want only the genesis one", state.GameServers)
+    }
+    if len(module.applied) != 1 || module.applied[0] != `{"level":2}` {
+        t.Fatalf("module applied %v, want the genesis settings once", module.applied)
+    }
+
+    // Rebuilding starts the module over from the genesis settings
+    bc.RebuildModules()
+    if len(module.applied) != 1 {
+        t.Fatalf("module applied %v after rebuilding, want the genesis settings once", module.applied)
+    }
+
+    intruder := gameServerTransaction(owner.address, time.Now().Unix())
+    if err := bc.CreateTransaction(intruder); err == nil {
+        t.Fatal("genesis game server transaction was admitted to the mempool")
+    }
+    if err := bc.AddBlock(unfilteredBlock(bc, "producer", nil, []Transaction{intruder})); err == nil {
+        t.Fatal("block after genesis adding a game server was accepted")
+    }
+    if bc.GetHeight() != 0 || bc.States.Latest().IsGameServer(owner.address) {
+        t.Fatal("refused block changed the game servers")
+    }
+}
@@ -0,0 +1,419 @@
+package core
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Node storage modes
+const (
+    // StorageModeArchive keeps every historical state version so any height can be queried
+    StorageModeArchive = "archive"
+
+    // StorageModeFull keeps only the most recent state versions
+    StorageModeFull = "full"
+)
+
+// DefaultStateRetention is the number of recent state versions kept by a full node
+const DefaultStateRetention = 128
+
+// Errors returned for state queries outside the retained range
+var (
+    ErrStatePruned       = errors.New("state has been pruned")
+    ErrStateNotAvailable = errors.New("state not available")
+)
+
+// State is the account state produced by executing the chain up to a given height
+// NFT state is kept by the NFT registry module rather than here; the state only records its root
+// Balances and the supply are kept by the state's ledger; the state root commits to the balances
+type State struct {
+    token.Ledger
+
+    // Fees destroyed rather than paid to block producers, in total
+    FeesBurned token.Amount `json:"feesBurned,omitempty"`
+
+    // What has entered and left the community treasury, in total
+    TreasuryFlows TreasuryFlows `json:"treasuryFlows"`
+
+    // Map of beneficiary to the allocation genesis placed in the vesting escrow for them
+    Vesting map[string]VestingAccount `json:"vesting,omitempty"`
+
+    // Map of match ID to the result its game server reported
+    Matches map[string]MatchResult `json:"matches,omitempty"`
+
+    // Map of player to who referred them
+    Referrals map[string]Referral `json:"referrals,omitempty"`
+
+    // Map of tournament ID to the tournament and the entry stakes locked for it
+    Tournaments map[string]Tournament `json:"tournaments,omitempty"`
+
+    // Map of asset ID to the asset genesis registered beside ILYZ
+    Assets map[string]token.Asset `json:"assets,omitempty"`
+
+    // Set of the game servers genesis or governance allowed to report matches
+    GameServers map[string]bool `json:"gameServers,omitempty"`
+
+    // Map of module name to the settings genesis or governance set for the module
+    Settings map[string]json.RawMessage `json:"settings,omitempty"`
+
+    // Map of module name to the root of the module's state, set for modules that commit to their state
+    ModuleRoots map[string]string `json:"moduleRoots,omitempty"`
+}
+
+// Supply is the token supply as of a height
+type Supply struct {
+    Height     int64        `json:"height"`
+    Total      token.Amount `json:"total"`      // Tokens held by accounts or locked by modules
+    FeesBurned token.Amount `json:"feesBurned"` // Fees destroyed so far rather than paid to block producers
+}
+
+// StateCommitment breaks a state root into the roots it commits to
+// Clients holding a module root, such as the NFT registry root, check it is part of a state root with Verify
+type StateCommitment struct {
+    Height       int64             `json:"height"`
+    Root         string            `json:"root"`
+    BalancesRoot string            `json:"balancesRoot"`
+    ModuleRoots  map[string]string `json:"moduleRoots,omitempty"`
+}
+
+// StateStore keeps state versions by height according to the node storage mode
+type StateStore struct {
+    // Storage mode ("archive" or "full")
+    Mode string
+
+    // Number of recent versions kept in full mode
+    Retention int64
+
+    // Map of block height to state version
+    versions map[int64]*State
+
+    // Map of block height to state root, kept for every height even when versions are pruned
+    roots map[int64]string
+
+    // Oldest and latest retained heights
+    oldest int64
+    latest int64
+
+    // Mutex for thread safety
+    mutex sync.RWMutex
+}
+
+// NewState creates an empty state
+func NewState() *State {
+    return &State{
+        Ledger: token.NewLedger(),
+    }
+}
+
+// Copy returns a deep copy of the state
+func (s *State) Copy() *State {
+    copied := &State{
+        Ledger:        s.Ledger.Copy(),
+        FeesBurned:    s.FeesBurned,
+        TreasuryFlows: s.TreasuryFlows,
+    }
+
+    if s.Vesting != nil {
+        copied.Vesting = make(map[string]VestingAccount, len(s.Vesting))
+        for address, account := range s.Vesting {
+            copied.Vesting[address] = account
+        }
+    }
+
+    // Results are replaced rather than changed in place, so their rewards can be shared
+    if s.Matches != nil {
+        copied.Matches = make(map[string]MatchResult, len(s.Matches))
+        for id, result := range s.Matches {
+            copied.Matches[id] = result
+        }
+    }
+
+    if s.Referrals != nil {
+        copied.Referrals = make(map[string]Referral, len(s.Referrals))
+        for player, referral := range s.Referrals {
+            copied.Referrals[player] = referral
+        }
+    }
+
+    // Tournaments are replaced rather than changed in place, so their entrants can be shared
+    if s.Tournaments != nil {
+        copied.Tournaments = make(map[string]Tournament, len(s.Tournaments))
+        for id, tournament := range s.Tournaments {
+            copied.Tournaments[id] = tournament
+        }
+    }
+
+    // Assets never change once registered, so their definitions can be shared
+    if s.Assets != nil {
+        copied.Assets = make(map[string]token.Asset, len(s.Assets))
+        for id, asset := range s.Assets {
+            copied.Assets[id] = asset
+        }
+    }
+
+    // Game servers and settings are replaced rather than changed in place, so they can be shared
+    copied.GameServers = s.GameServers
+    copied.Settings = s.Settings
+
+    if s.ModuleRoots != nil {
+        copied.ModuleRoots = make(map[string]string, len(s.ModuleRoots))
+        for name, root := range s.ModuleRoots {
+            copied.ModuleRoots[name] = root
+        }
+    }
+
+    return copied
+}
+
+// ApplyTransaction records the effects of a confirmed transaction through the ledger, once its fee has been collected
+// Fees leave the supply with the sender and the block's coinbase mints them back to the producer, the master wallet,
+// the staking reward pool and the treasury, less the share burned, so the supply grows by the reward less the fees burned
+func (s *State) ApplyTransaction(tx Transaction) {
+    switch tx.Type {
+    case "genesis_alloc":
+        s.Mint(tx.Recipient, tx.Amount)
+
+    case TxTypeGenesisVesting:
+        if s.Vesting == nil {
+            s.Vesting = make(map[string]VestingAccount)
+        }
+        s.Mint(VestingEscrowAddress, tx.Amount)
+        s.Vesting[tx.Recipient] = VestingAccount{VestingSchedule: vestingSchedule(tx)}
+
+    case TxTypeCoinbase:
+        s.Mint(tx.Recipient, tx.Amount)
+
+        // Only shares actually paid are minted, so accounts that receive nothing don't enter the balances
+        split, masterWallet := coinbaseSplit(tx)
+        if split.Master > 0 {
+            s.Mint(masterWallet, split.Master)
+        }
+        if split.Staking > 0 {
+            s.Mint(StakingPoolAddress, split.Staking)
+        }
+        if treasuryReward := coinbaseTreasuryReward(tx); split.Treasury > 0 || treasuryReward > 0 {
+            s.Mint(TreasuryAddress, split.Treasury+treasuryReward)
+            s.TreasuryFlows.Fees += split.Treasury
+            s.TreasuryFlows.Rewards += treasuryReward
+        }
+        s.FeesBurned += split.Burned
+
+    case TxTypeVoteReward:
+        shares, _ := VoteRewardShares(tx)
+        for voter, share := range shares {
+            s.Mint(voter, share)
+        }
+
+    case "token_transfer":
+        s.Transfer(tx.Sender, tx.Recipient, tx.Amount)
+
+    case TxTypeGenesisAsset, TxTypeAssetMint, TxTypeAssetTransfer, TxTypeAssetBurn:
+        s.applyAsset(tx)
+
+    case TxTypeGenesisGameServer, TxTypeGenesisSettings:
+        s.applyGenesisSettings(tx)
+    }
+}
+
+// Root returns a deterministic hash of the state, covering the balances and every module root
+func (s *State) Root() string {
+    return ComposeStateRoot(s.BalancesRoot(), s.ModuleRoots)
+}
+
+// BalancesRoot returns a deterministic hash of the account balances, covering those of other assets once any are held
+func (s *State) BalancesRoot() string {
+    addresses := make([]string, 0, len(s.Balances))
+    for address := range s.Balances {
+        addresses = append(addresses, address)
+    }
+    sort.Strings(addresses)
+
+    // Encode entries as sorted pairs so the hash doesn't depend on map ordering
+    entries := [][2]interface{}{}
+    for _, address := range addresses {
+        entries = append(entries, [2]interface{}{address, s.Balances[address]})
+    }
+
+    data, _ := json.Marshal(entries)
+    if len(s.AssetBalances) > 0 {
+        data, _ = json.Marshal([]interface{}{entries, s.assetEntries()})
+    }
+    hash := sha256.Sum256(data)
+    return hex.EncodeToString(hash[:])
+}
+
+// assetEntries returns the balances of assets other than ILYZ as sorted asset, address and balance triples
+func (s *State) assetEntries() [][3]interface{} {
+    assets := make([]string, 0, len(s.AssetBalances))
+    for asset := range s.AssetBalances {
+        assets = append(assets, asset)
+    }
+    sort.Strings(assets)
+
+    entries := [][3]interface{}{}
+    for _, asset := range assets {
+        balances := s.AssetBalances[asset]
+        addresses := make([]string, 0, len(balances))
+        for address := range balances {
+            addresses = append(addresses, address)
+        }
+        sort.Strings(addresses)
+
+        for _, address := range addresses {
+            entries = append(entries, [3]interface{}{asset, address, balances[address]})
+        }
+    }
+
+    return entries
+}
+
+// ComposeStateRoot combines the balances root with module roots into a state root
+// A state without module roots has the balances root as its state root
+func ComposeStateRoot(balancesRoot string, moduleRoots map[string]string) string {
+    if len(moduleRoots) == 0 {
+        return balancesRoot
+    }
+
+    names := make([]string, 0, len(moduleRoots))
+    for name := range moduleRoots {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    entries := [][2]string{{"balances", balancesRoot}}
+    for _, name := range names {
+        entries = append(entries, [2]string{name, moduleRoots[name]})
+    }
+
+    data, _ := json.Marshal(entries)
+    hash := sha256.Sum256(data)
+    return hex.EncodeToString(hash[:])
+}
+
+// Commitment returns the roots the state root is composed of
+func (s *State) Commitment(height int64) StateCommitment {
+    moduleRoots := make(map[string]string, len(s.ModuleRoots))
+    for name, root := range s.ModuleRoots {
+        moduleRoots[name] = root
+    }
+
+    return StateCommitment{
+        Height:       height,
+        Root:         s.Root(),
+        BalancesRoot: s.BalancesRoot(),
+        ModuleRoots:  moduleRoots,
+    }
+}
+
+// Verify checks that the commitment's roots compose to its state root
+func (c StateCommitment) Verify() bool {
+    return ComposeStateRoot(c.BalancesRoot, c.ModuleRoots) == c.Root
+}
+
+// NewStateStore creates a state store for the given storage mode
+func NewStateStore(mode string, retention int64) (*StateStore, error) {
+    if mode != StorageModeArchive && mode != StorageModeFull {
+        return nil, fmt.Errorf("unknown storage mode %q", mode)
+    }
+
+    if mode == StorageModeFull && retention < 1 {
+        return nil, errors.New("full storage mode requires a positive state retention")
+    }
+
+    return &StateStore{
+        Mode:      mode,
+        Retention: retention,
+        versions:  make(map[int64]*State),
+        roots:     make(map[int64]string),
+        oldest:    0,
+        latest:    -1,
+    }, nil
+}
+
+// Commit stores the state produced at a height and prunes versions outside the retained range
+func (ss *StateStore) Commit(height int64, state *State) {
+    ss.mutex.Lock()
+    defer ss.mutex.Unlock()
+
+    ss.versions[height] = state
+    ss.roots[height] = state.Root()
+    if height > ss.latest {
+        ss.latest = height
+    }
+
+    if ss.Mode == StorageModeFull {
+        for ss.latest-ss.oldest >= ss.Retention {
+            delete(ss.versions, ss.oldest)
+            ss.oldest++
+        }
+    }
+}
+
+// Latest returns the most recent state version
+func (ss *StateStore) Latest() *State {
+    ss.mutex.RLock()
+    defer ss.mutex.RUnlock()
+
+    return ss.versions[ss.latest]
+}
+
+// StateAt returns the state version at a height
+func (ss *StateStore) StateAt(height int64) (*State, error) {
+    ss.mutex.RLock()
+    defer ss.mutex.RUnlock()
+
+    if height > ss.latest || height < 0 {
+        return nil, fmt.Errorf("%w: height %d is beyond the latest height %d", ErrStateNotAvailable, height, ss.latest)
+    }
+
+    if height < ss.oldest {
+        return nil, fmt.Errorf("%w: height %d is older than the retained range %d-%d (%s node)", ErrStatePruned, height, ss.oldest, ss.latest, ss.Mode)
+    }
+
+    return ss.versions[height], nil
+}
+
+// Truncate discards every state version above a height
+// Rolling back into the pruned range is not possible, since the target state is no longer retained
+func (ss *StateStore) Truncate(height int64) error {
+    ss.mutex.Lock()
+    defer ss.mutex.Unlock()
+
+    if height < ss.oldest {
+        return fmt.Errorf("%w: cannot roll back to height %d, oldest retained state is %d (%s node)", ErrStatePruned, height, ss.oldest, ss.Mode)
+    }
+
+    for h := height + 1; h <= ss.latest; h++ {
+        delete(ss.versions, h)
+        delete(ss.roots, h)
+    }
+    if height < ss.latest {
+        ss.latest = height
+    }
+
+    return nil
+}
+
+// RootAt returns the state root recorded at a height
+func (ss *StateStore) RootAt(height int64) (string, bool) {
+    ss.mutex.RLock()
+    defer ss.mutex.RUnlock()
+
+    root, exists := ss.roots[height]
+    return root, exists
+}
+
+// RetainedRange returns the oldest and latest heights whose state can be queried
+func (ss *StateStore) RetainedRange() (int64, int64) {
+    ss.mutex.RLock()
+    defer ss.mutex.RUnlock()
+
+    return ss.oldest, ss.latest
+}
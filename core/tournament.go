@@ -0,0 +1,280 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TournamentEscrowAddress is the account holding tournament entry stakes until their tournaments settle
+const TournamentEscrowAddress = "tournament_escrow"
+
+// Tournament transaction types
+const (
+    TxTypeTournamentCreate = "tournament_create" // Sender: the organizer, who reports the result; Data: tournament, its ID; entryFee, the stake each entrant locks
+    TxTypeTournamentEntry  = "tournament_entry"  // Sender: the entrant; Amount: the entry fee, locked until the tournament settles; Data: tournament
+    TxTypeTournamentResult = "tournament_result" // Sender: the organizer; Data: tournament; payouts, optionally, each entrant's share of the locked stakes
+)
+
+// Tournament statuses
+const (
+    TournamentOpen     = "open"     // Taking entries; the stakes are locked
+    TournamentReleased = "released" // Settled by returning each entrant's stake
+    TournamentPaid     = "paid"     // Settled by paying the stakes out to the entrants by the result
+)
+
+// Tournament is an event entrants lock a stake to enter, held until its organizer reports the result
+type Tournament struct {
+    ID        string                  `json:"id"`
+    Organizer string                  `json:"organizer"`
+    EntryFee  token.Amount            `json:"entryFee"`
+    Entrants  map[string]token.Amount `json:"entrants"` // Stake locked by each entrant
+    Pool      token.Amount            `json:"pool"`     // Stakes locked in total
+    Status    string                  `json:"status"`
+    Height    int64                   `json:"height"`              // Block that created it
+    SettledAt int64                   `json:"settledAt,omitempty"` // Height the stakes were released or paid out at
+    Payouts   map[string]token.Amount `json:"payouts,omitempty"`   // Paid to each entrant once settled; an entrant missing was not paid
+}
+
+// TournamentResultTransaction returns an unsigned transaction settling a tournament, sent by its organizer
+// Without payouts each entrant's stake is returned; with them, the stakes are paid out by them
+func TournamentResultTransaction(organizer string, id string, payouts map[string]token.Amount, timestamp int64) Transaction {
+    data := map[string]interface{}{"tournament": id}
+    if payouts != nil {
+        shares := make(map[string]interface{}, len(payouts))
+        for entrant, payout := range payouts {
+            shares[entrant] = payout
+        }
+        data["payouts"] = shares
+    }
+
+    return Transaction{
+        Type:      TxTypeTournamentResult,
+        Sender:    organizer,
+        Data:      data,
+        Timestamp: timestamp,
+    }
+}
+
+// GetTournament returns a tournament and its entrants as of the latest block
+func (bc *Blockchain) GetTournament(id string) (Tournament, error) {
+    bc.mutex.RLock()
+    defer bc.mutex.RUnlock()
+
+    tournament, exists := bc.States.Latest().Tournaments[id]
+    if !exists {
+        return Tournament{}, fmt.Errorf("tournament %s not found", id)
+    }
+
+    return tournament, nil
+}
+
+// GetTournamentStakesAt returns what an address has locked in tournaments still open as of the given height,
+// which is not part of its spendable balance
+// Heights outside the retained state range return ErrStatePruned or ErrStateNotAvailable
+func (bc *Blockchain) GetTournamentStakesAt(address string, height int64) (token.Amount, error) {
+    state, err := bc.States.StateAt(height)
+    if err != nil {
+        return 0, err
+    }
+
+    return state.TournamentStakes(address), nil
+}
+
+// TournamentStakes returns what an address has locked in tournaments still open
+func (s *State) TournamentStakes(address string) token.Amount {
+    locked := token.Amount(0)
+    for _, tournament := range s.Tournaments {
+        if tournament.Status == TournamentOpen {
+            locked += tournament.Entrants[address]
+        }
+    }
+
+    return locked
+}
+
+// applyTournament creates a tournament, locks an entrant's stake in the tournament escrow, or settles a tournament
+// by its result, in the block with a header
+// Tournaments are replaced rather than changed in place, so state copies can share their entrants
+// A transaction breaking the tournament's rules, an entry the entrant can't cover, or a transaction its sender didn't sign
+// does nothing, since blocks from peers are applied without the admission checks
+func (s *State) applyTournament(tx Transaction, header BlockHeader) {
+    switch tx.Type {
+    case TxTypeTournamentCreate, TxTypeTournamentEntry, TxTypeTournamentResult:
+        if VerifyTransactionSignature(tx) != nil {
+            return
+        }
+    default:
+        return
+    }
+
+    id := txTournament(tx)
+    tournament, exists := s.Tournaments[id]
+
+    switch tx.Type {
+    case TxTypeTournamentCreate:
+        fee, ok := tournamentEntryFee(tx)
+        if id == "" || exists || !ok {
+            return
+        }
+        tournament = Tournament{
+            ID:        id,
+            Organizer: tx.Sender,
+            EntryFee:  fee,
+            Entrants:  map[string]token.Amount{},
+            Status:    TournamentOpen,
+            Height:    header.Index,
+        }
+
+    case TxTypeTournamentEntry:
+        if !exists || tournament.Status != TournamentOpen || tournament.Entrants[tx.Sender] > 0 || tx.Amount != tournament.EntryFee {
+            return
+        }
+        if s.Transfer(tx.Sender, TournamentEscrowAddress, tx.Amount) != nil {
+            return
+        }
+        entrants := make(map[string]token.Amount, len(tournament.Entrants)+1)
+        for entrant, stake := range tournament.Entrants {
+            entrants[entrant] = stake
+        }
+        entrants[tx.Sender] = tx.Amount
+        tournament.Entrants = entrants
+        tournament.Pool += tx.Amount
+
+    case TxTypeTournamentResult:
+        if !exists || tournament.Status != TournamentOpen || tournament.Organizer != tx.Sender {
+            return
+        }
+        payouts, status, err := tournamentPayouts(tx, tournament)
+        if err != nil {
+            return
+        }
+        // An escrow that can't cover the stakes leaves the tournament open rather than settling it unpaid
+        if s.Balances[TournamentEscrowAddress] < tournament.Pool {
+            return
+        }
+        paid := make(map[string]token.Amount, len(payouts))
+        for _, entrant := range sortedPlayers(payouts) {
+            payout := payouts[entrant]
+            if payout > 0 && s.Transfer(TournamentEscrowAddress, entrant, payout) != nil {
+                continue
+            }
+            paid[entrant] = payout
+        }
+        tournament.Payouts = paid
+        tournament.Status = status
+        tournament.SettledAt = header.Index
+
+    default:
+        return
+    }
+
+    if s.Tournaments == nil {
+        s.Tournaments = make(map[string]Tournament)
+    }
+    s.Tournaments[id] = tournament
+}
+
+// checkTournament rejects a tournament transaction its sender didn't sign or that breaks the tournament's rules
+// as of the latest block
+// Balances are only known when the transaction is applied, so an entry the entrant can't cover passes here
+// The caller must hold the lock
+func (bc *Blockchain) checkTournament(tx Transaction) error {
+    switch tx.Type {
+    case TxTypeTournamentCreate, TxTypeTournamentEntry, TxTypeTournamentResult:
+    default:
+        return nil
+    }
+
+    if err := VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+
+    id := txTournament(tx)
+    if id == "" {
+        return errors.New("tournament ID is required")
+    }
+    tournament, exists := bc.States.Latest().Tournaments[id]
+
+    if tx.Type == TxTypeTournamentCreate {
+        if exists {
+            return fmt.Errorf("tournament %s already exists", id)
+        }
+        if _, ok := tournamentEntryFee(tx); !ok {
+            return errors.New("entry fee must be positive")
+        }
+        return nil
+    }
+
+    if !exists {
+        return fmt.Errorf("tournament %s not found", id)
+    }
+    if tournament.Status != TournamentOpen {
+        return fmt.Errorf("tournament %s has already been settled", id)
+    }
+
+    if tx.Type == TxTypeTournamentEntry {
+        if tournament.Entrants[tx.Sender] > 0 {
+            return fmt.Errorf("%s has already entered tournament %s", tx.Sender, id)
+        }
+        if tx.Amount != tournament.EntryFee {
+            return fmt.Errorf("entry stake must be the entry fee of %s", tournament.EntryFee)
+        }
+        return nil
+    }
+
+    if tx.Sender != tournament.Organizer {
+        return errors.New("only the tournament's organizer can report its result")
+    }
+    _, _, err := tournamentPayouts(tx, tournament)
+
+    return err
+}
+
+// tournamentPayouts returns what a result pays each entrant and the status it settles the tournament with
+// A result without payouts returns each entrant's stake; one with payouts must pay out exactly the stakes locked,
+// and only to entrants
+func tournamentPayouts(tx Transaction, tournament Tournament) (map[string]token.Amount, string, error) {
+    data, _ := tx.Data.(map[string]interface{})
+    if data["payouts"] == nil {
+        return tournament.Entrants, TournamentReleased, nil
+    }
+
+    payouts, ok := dataShares(data["payouts"])
+    if !ok {
+        return nil, "", errors.New("tournament result has invalid payouts")
+    }
+
+    total := token.Amount(0)
+    for entrant, payout := range payouts {
+        if _, entered := tournament.Entrants[entrant]; !entered {
+            return nil, "", fmt.Errorf("%s did not enter tournament %s", entrant, tournament.ID)
+        }
+        if payout < 0 {
+            return nil, "", fmt.Errorf("tournament result pays %s a negative %s", entrant, payout)
+        }
+        total += payout
+    }
+    if total != tournament.Pool {
+        return nil, "", fmt.Errorf("tournament result pays out %s rather than the %s locked", total, tournament.Pool)
+    }
+
+    return payouts, TournamentPaid, nil
+}
+
+// tournamentEntryFee returns the entry fee a tournament creation sets, and whether it is positive
+func tournamentEntryFee(tx Transaction) (token.Amount, bool) {
+    data, _ := tx.Data.(map[string]interface{})
+    fee, ok := token.DataAmount(data["entryFee"])
+
+    return fee, ok && fee > 0
+}
+
+// txTournament returns the tournament a tournament transaction is for
+func txTournament(tx Transaction) string {
+    data, _ := tx.Data.(map[string]interface{})
+    id, _ := data["tournament"].(string)
+
+    return id
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+    "errors"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TreasuryFlows is what has entered and left the community treasury
+type TreasuryFlows struct {
+    Fees     token.Amount `json:"fees"`               // Shares of block fees received
+    Rewards  token.Amount `json:"rewards"`            // Shares of block rewards received
+    Spent    token.Amount `json:"spent"`              // Paid out by proposals governance passed
+    Returned token.Amount `json:"returned,omitempty"` // Unclaimed funds modules returned, such as expired airdrops
+}
+
+// Treasury is the community treasury's balance and flows as of a height
+type Treasury struct {
+    Height  int64         `json:"height"`
+    Address string        `json:"address"`
+    Balance token.Amount  `json:"balance"`
+    Flows   TreasuryFlows `json:"flows"`
+}
+
+// SpendTreasury pays an amount out of the community treasury, recording it as spent
+// Only the consensus engine calls it, for proposals governance passed
+func (s *State) SpendTreasury(recipient string, amount token.Amount) error {
+    if recipient == "" {
+        return errors.New("treasury spend has no recipient")
+    }
+
+    if err := s.Transfer(TreasuryAddress, recipient, amount); err != nil {
+        return err
+    }
+    s.TreasuryFlows.Spent += amount
+
+    return nil
+}
+
+// ReturnToTreasury moves unclaimed funds from a module's account back into the community treasury, recording them as returned
+func (s *State) ReturnToTreasury(from string, amount token.Amount) error {
+    if err := s.Transfer(from, TreasuryAddress, amount); err != nil {
+        return err
+    }
+    s.TreasuryFlows.Returned += amount
+
+    return nil
+}
+
+// GetTreasuryAt returns the community treasury's balance and flows as of the given height
+// Heights outside the retained state range return ErrStatePruned or ErrStateNotAvailable
+func (bc *Blockchain) GetTreasuryAt(height int64) (Treasury, error) {
+    state, err := bc.States.StateAt(height)
+    if err != nil {
+        return Treasury{}, err
+    }
+
+    return Treasury{
+        Height:  height,
+        Address: TreasuryAddress,
+        Balance: state.Balances[TreasuryAddress],
+        Flows:   state.TreasuryFlows,
+    }, nil
+}
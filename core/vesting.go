@@ -0,0 +1,173 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// VestingEscrowAddress is the account holding vesting allocations until they are released to their beneficiaries
+const VestingEscrowAddress = "vesting_escrow"
+
+// TxTypeGenesisVesting is the genesis transaction that places an allocation in the vesting escrow for its recipient
+// Amount: the allocation; Data: start, cliff and duration of its schedule
+const TxTypeGenesisVesting = "genesis_vesting"
+
+// VestingSchedule releases an allocation linearly from its start until the duration has passed,
+// releasing nothing before the cliff; what the line reached by the cliff is released at once
+type VestingSchedule struct {
+    Amount   token.Amount `json:"amount"`
+    Start    int64        `json:"start,omitempty"` // Unix time vesting starts from; the genesis timestamp if unset
+    Cliff    int64        `json:"cliff"`           // Seconds after the start before anything is released
+    Duration int64        `json:"duration"`        // Seconds after the start until everything is released
+}
+
+// VestingAccount is a beneficiary's schedule and what has been released from the escrow to them so far
+type VestingAccount struct {
+    VestingSchedule
+    Released token.Amount `json:"released"`
+}
+
+// VestingStatus is how much of an address's allocation has vested and how much is still locked as of a height
+type VestingStatus struct {
+    Address  string           `json:"address"`
+    Height   int64            `json:"height"`
+    Vested   token.Amount     `json:"vested"` // Released to the address's balance and spendable
+    Locked   token.Amount     `json:"locked"` // Still held in the vesting escrow
+    Schedule *VestingSchedule `json:"schedule,omitempty"`
+}
+
+// Validate checks that a schedule allocates a positive amount and releases it no earlier than its cliff
+func (v VestingSchedule) Validate() error {
+    if v.Amount <= 0 {
+        return errors.New("vesting amount must be positive")
+    }
+
+    if v.Cliff < 0 || v.Duration < 0 {
+        return errors.New("vesting cliff and duration cannot be negative")
+    }
+
+    if v.Duration < v.Cliff {
+        return errors.New("vesting duration is shorter than its cliff")
+    }
+
+    return nil
+}
+
+// VestedAt returns how much of the allocation has vested by a time
+func (v VestingSchedule) VestedAt(timestamp int64) token.Amount {
+    elapsed := timestamp - v.Start
+    if elapsed < v.Cliff {
+        return 0
+    }
+
+    if elapsed >= v.Duration {
+        return v.Amount
+    }
+
+    return v.Amount.Scale(token.Amount(elapsed), token.Amount(v.Duration))
+}
+
+// ReleaseVested moves what has vested by a block time out of the vesting escrow to each beneficiary
+func (s *State) ReleaseVested(timestamp int64) {
+    addresses := make([]string, 0, len(s.Vesting))
+    for address := range s.Vesting {
+        addresses = append(addresses, address)
+    }
+    sort.Strings(addresses)
+
+    for _, address := range addresses {
+        account := s.Vesting[address]
+        due := account.VestedAt(timestamp) - account.Released
+        if due <= 0 {
+            continue
+        }
+
+        if err := s.Transfer(VestingEscrowAddress, address, due); err != nil {
+            continue
+        }
+        account.Released += due
+        s.Vesting[address] = account
+    }
+}
+
+// GetVestingAt returns how much of an address's allocation has vested and how much is locked as of the given height;
+// an address without one has nothing of either
+// Heights outside the retained state range return ErrStatePruned or ErrStateNotAvailable
+func (bc *Blockchain) GetVestingAt(address string, height int64) (VestingStatus, error) {
+    state, err := bc.States.StateAt(height)
+    if err != nil {
+        return VestingStatus{}, err
+    }
+
+    status := VestingStatus{Address: address, Height: height}
+    if account, exists := state.Vesting[address]; exists {
+        schedule := account.VestingSchedule
+        status.Vested = account.Released
+        status.Locked = account.Amount - account.Released
+        status.Schedule = &schedule
+    }
+
+    return status, nil
+}
+
+// checkVesting rejects a transaction whose amount and fee exceed its sender's balance while some of the sender's
+// allocation is still locked; locked tokens stay in the escrow, so they could never be spent, but this reports why
+// The caller must hold the lock
+func (bc *Blockchain) checkVesting(tx Transaction) error {
+    state := bc.States.Latest()
+    account, exists := state.Vesting[tx.Sender]
+    if !exists || account.Released >= account.Amount {
+        return nil
+    }
+
+    balance := state.Balances[tx.Sender]
+    if tx.Amount+tx.Fee > balance {
+        return fmt.Errorf("%w: %s of the sender's allocation is still vesting and only %s is spendable",
+            token.ErrInsufficientBalance, account.Amount-account.Released, balance)
+    }
+
+    return nil
+}
+
+// vestingTransaction builds the genesis transaction placing an allocation in the vesting escrow
+func vestingTransaction(address string, schedule VestingSchedule, timestamp int64) Transaction {
+    tx := Transaction{
+        Type:      TxTypeGenesisVesting,
+        Recipient: address,
+        Amount:    schedule.Amount,
+        Data: map[string]interface{}{
+            "start":    schedule.Start,
+            "cliff":    schedule.Cliff,
+            "duration": schedule.Duration,
+        },
+        Timestamp: timestamp,
+    }
+    tx.ID = ComputeTransactionID(tx)
+
+    return tx
+}
+
+// vestingSchedule reads the schedule a genesis vesting transaction records
+func vestingSchedule(tx Transaction) VestingSchedule {
+    data, _ := tx.Data.(map[string]interface{})
+
+    seconds := func(key string) int64 {
+        switch value := data[key].(type) {
+        case int64:
+            return value
+        case float64:
+            return int64(value)
+        }
+        return 0
+    }
+
+    return VestingSchedule{
+        Amount:   tx.Amount,
+        Start:    seconds("start"),
+        Cliff:    seconds("cliff"),
+        Duration: seconds("duration"),
+    }
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeVoteReward is the transaction type that pays the validators who voted to finalize the parent block
+// Data: height and blockHash of the parent, rewards mapping each voter to its share
+const TxTypeVoteReward = "vote_reward"
+
+// DefaultVoteRewardPool is the most minted for the voters on each block
+const DefaultVoteRewardPool = 2 * token.ILYZ
+
+// VoteRewarder splits a reward pool among the validators who voted for a block
+type VoteRewarder interface {
+    // VoteRewards returns each voter's share of the pool, or nothing if the block has not been finalized
+    VoteRewards(height int64, blockHash string, pool token.Amount) map[string]token.Amount
+}
+
+// createVoteReward builds the vote reward for the voters on the chain tip, if it has been finalized
+// Shares are scaled down together when the yearly supply cap is near, counting the coinbase's reward as already minted
+// The caller must hold the lock
+func (bc *Blockchain) createVoteReward(height int64, timestamp int64, coinbaseReward token.Amount) (Transaction, bool) {
+    if bc.Rewarder == nil || bc.VoteRewardPool <= 0 {
+        return Transaction{}, false
+    }
+
+    parent := bc.Headers[len(bc.Headers)-1]
+    shares := bc.Rewarder.VoteRewards(parent.Index, parent.Hash, bc.VoteRewardPool)
+
+    voters := make([]string, 0, len(shares))
+    for voter, share := range shares {
+        if share > 0 {
+            voters = append(voters, voter)
+        }
+    }
+    sort.Strings(voters)
+
+    total := token.Amount(0)
+    for _, voter := range voters {
+        total += shares[voter]
+    }
+    if total <= 0 {
+        return Transaction{}, false
+    }
+
+    minted := total
+    if bc.Economics != nil {
+        minted = bc.Economics.BlockRewardAllowance(total, coinbaseReward)
+    }
+    if minted <= 0 {
+        return Transaction{}, false
+    }
+
+    rewards := make(map[string]interface{}, len(voters))
+    paid := token.Amount(0)
+    for _, voter := range voters {
+        share := shares[voter].Scale(minted, total)
+        rewards[voter] = share
+        paid += share
+    }
+
+    reward := Transaction{
+        Type:   TxTypeVoteReward,
+        Amount: paid,
+        Data: map[string]interface{}{
+            "height":    height - 1,
+            "blockHash": parent.Hash,
+            "rewards":   rewards,
+        },
+        Timestamp: timestamp,
+    }
+    reward.ID = ComputeTransactionID(reward)
+
+    return reward, true
+}
+
+// validateVoteReward checks that a block holds at most one vote reward, right after its coinbase,
+// for its parent and paying no more than the pool
+// Which validators voted is only known to each node, so the split itself is not checked
+func (bc *Blockchain) validateVoteReward(block Block) error {
+    for i, tx := range block.Transactions {
+        if tx.Type != TxTypeVoteReward {
+            continue
+        }
+        if i != 1 {
+            return errors.New("vote reward must directly follow the coinbase")
+        }
+
+        data, _ := tx.Data.(map[string]interface{})
+        if height, ok := txHeight(tx); !ok || height != block.Index-1 {
+            return errors.New("vote reward is not bound to the parent block")
+        }
+        if hash, _ := data["blockHash"].(string); hash != block.PrevHash {
+            return errors.New("vote reward is not bound to the parent block")
+        }
+
+        rewards, ok := VoteRewardShares(tx)
+        if !ok {
+            return errors.New("vote reward has invalid shares")
+        }
+        total := token.Amount(0)
+        for _, share := range rewards {
+            total += share
+        }
+        if total != tx.Amount {
+            return errors.New("vote reward amount does not match its shares")
+        }
+        if tx.Amount <= 0 || tx.Amount > bc.VoteRewardPool {
+            return fmt.Errorf("vote reward pays %v, more than the pool of %v", tx.Amount, bc.VoteRewardPool)
+        }
+    }
+
+    return nil
+}
+
+// VoteRewardShares returns the share each voter is paid by a vote reward
+// Shares are amounts when built locally and float64 once decoded from JSON
+func VoteRewardShares(tx Transaction) (map[string]token.Amount, bool) {
+    return rewardShares(tx)
+}
+
+// rewardShares returns the amounts a vote or match reward records paying each address
+func rewardShares(tx Transaction) (map[string]token.Amount, bool) {
+    data, _ := tx.Data.(map[string]interface{})
+    return dataShares(data["rewards"])
+}
+
+// dataShares reads a map of addresses to amounts from transaction data, none of them negative
+func dataShares(value interface{}) (map[string]token.Amount, bool) {
+    rewards, ok := value.(map[string]interface{})
+    if !ok {
+        return nil, false
+    }
+
+    shares := make(map[string]token.Amount, len(rewards))
+    for voter, value := range rewards {
+        share, ok := token.DataAmount(value)
+        if !ok || share < 0 {
+            return nil, false
+        }
+        shares[voter] = share
+    }
+
+    return shares, true
+}
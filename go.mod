@@ -0,0 +1,3 @@
+module github.com/txaimhawj/chulubmeadditional-files
+
+go 1.22
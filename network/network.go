@@ -11,18 +11,23 @@ import (
 
 // Node represents a node in the blockchain network
 type Node struct {
-    ID            string
-    Address       string
-    Type          string // "full", "game", "light", "master"
-    IsValidator   bool
-    Peers         map[string]*Peer
-    MessageQueue  chan Message
-    BlockQueue    chan []byte
-    TxQueue       chan []byte
-    IsRunning     bool
-    mutex         sync.Mutex
-    listener      net.Listener
-    peerDiscovery *PeerDiscovery
+    ID               string
+    Address          string
+    Type             string // "full", "game", "light", "master"
+    IsValidator      bool
+    Peers            map[string]*Peer
+    MessageQueue     chan Message
+    BlockQueue       chan []byte
+    TxQueue          chan []byte
+    AttestationQueue chan []byte
+    HeartbeatQueue   chan []byte
+    HeartbeatContent func() interface{} // Content sent with each heartbeat, such as a validator's signed liveness; nil sends none
+    EmissionSchedule string             // Hash of the emission schedule peers must share; empty accepts any peer
+    IsRunning        bool
+    mutex            sync.Mutex
+    listener         net.Listener
+    peerDiscovery    *PeerDiscovery
+    subscriptions    map[string][]chan []byte // Channels receiving each other message type, by type
 }
 
 // Peer represents a connection to another node
@@ -54,18 +59,33 @@ type PeerDiscovery struct {
 // NewNode creates a new network node
 func NewNode(id string, address string, nodeType string, isValidator bool) *Node {
     return &Node{
-        ID:           id,
-        Address:      address,
-        Type:         nodeType,
-        IsValidator:  isValidator,
-        Peers:        make(map[string]*Peer),
-        MessageQueue: make(chan Message, 100),
-        BlockQueue:   make(chan []byte, 10),
-        TxQueue:      make(chan []byte, 100),
-        IsRunning:    false,
+        ID:               id,
+        Address:          address,
+        Type:             nodeType,
+        IsValidator:      isValidator,
+        Peers:            make(map[string]*Peer),
+        MessageQueue:     make(chan Message, 100),
+        BlockQueue:       make(chan []byte, 10),
+        TxQueue:          make(chan []byte, 100),
+        AttestationQueue: make(chan []byte, 100),
+        HeartbeatQueue:   make(chan []byte, 100),
+        IsRunning:        false,
+        subscriptions:    make(map[string][]chan []byte),
     }
 }
 
+// Subscribe returns a channel that receives the content of every peer message of a type, encoded as JSON
+// Types with a dedicated queue, such as blocks and transactions, are not delivered to subscribers
+func (n *Node) Subscribe(messageType string, buffer int) <-chan []byte {
+    n.mutex.Lock()
+    defer n.mutex.Unlock()
+    
+    subscription := make(chan []byte, buffer)
+    n.subscriptions[messageType] = append(n.subscriptions[messageType], subscription)
+    
+    return subscription
+}
+
 // Start starts the node's network services
 func (n *Node) Start(port int) error {
     n.mutex.Lock()
@@ -113,6 +133,9 @@ func (n *Node) Stop() error {
         return errors.New("node is not running")
     }
     
+    // Mark as stopped first so the accept loop exits quietly when the listener closes
+    n.IsRunning = false
+    
     // Close listener
     if n.listener != nil {
         n.listener.Close()
@@ -125,8 +148,6 @@ func (n *Node) Stop() error {
         }
     }
     
-    n.IsRunning = false
-    
     return nil
 }
 
@@ -150,8 +171,9 @@ func (n *Node) Connect(address string) error {
         Type:    "handshake",
         Sender:  n.ID,
         Content: map[string]string{
-            "address": n.Address,
-            "type":    n.Type,
+            "address":          n.Address,
+            "type":             n.Type,
+            "emissionSchedule": n.EmissionSchedule,
         },
         Time:    time.Now().Unix(),
     }
@@ -170,14 +192,14 @@ func (n *Node) Connect(address string) error {
     
     // Wait for handshake response
     buffer := make([]byte, 1024)
-    n, err := conn.Read(buffer)
+    bytesRead, err := conn.Read(buffer)
     if err != nil {
         conn.Close()
         return err
     }
     
     var response Message
-    err = json.Unmarshal(buffer[:n], &response)
+    err = json.Unmarshal(buffer[:bytesRead], &response)
     if err != nil {
         conn.Close()
         return err
@@ -207,6 +229,12 @@ func (n *Node) Connect(address string) error {
         return errors.New("invalid peer type")
     }
     
+    // A peer minting by another schedule is on another chain
+    if schedule, _ := content["emissionSchedule"].(string); !n.sharesSchedule(schedule) {
+        conn.Close()
+        return fmt.Errorf("peer mints by emission schedule %q, not %q", schedule, n.EmissionSchedule)
+    }
+    
     // Add peer
     peer := &Peer{
         ID:       peerID,
@@ -341,13 +369,19 @@ func (n *Node) handleConnection(conn net.Conn) {
         return
     }
     
+    if schedule, _ := content["emissionSchedule"].(string); !n.sharesSchedule(schedule) {
+        conn.Close()
+        return
+    }
+    
     // Send handshake acknowledgement
     response := Message{
         Type:    "handshake_ack",
         Sender:  n.ID,
         Content: map[string]string{
-            "id":   n.ID,
-            "type": n.Type,
+            "id":               n.ID,
+            "type":             n.Type,
+            "emissionSchedule": n.EmissionSchedule,
         },
         Time:    time.Now().Unix(),
     }
@@ -380,6 +414,11 @@ func (n *Node) handleConnection(conn net.Conn) {
     go n.handlePeerMessages(peer)
 }
 
+// sharesSchedule reports whether a peer's emission schedule hash matches this node's
+func (n *Node) sharesSchedule(schedule string) bool {
+    return n.EmissionSchedule == "" || schedule == n.EmissionSchedule
+}
+
 // handlePeerMessages handles messages from a peer
 func (n *Node) handlePeerMessages(peer *Peer) {
     buffer := make([]byte, 4096)
@@ -429,6 +468,14 @@ func (n *Node) processMessages() {
                 }
                 n.TxQueue <- txData
                 
+            case "attestation":
+                // Convert content to bytes and add to attestation queue
+                attestationData, err := json.Marshal(message.Content)
+                if err != nil {
+                    continue
+                }
+                n.AttestationQueue <- attestationData
+                
             case "peer_discovery":
                 // Handle peer discovery
                 n.handlePeerDiscovery(message)
@@ -438,11 +485,44 @@ func (n *Node) processMessages() {
                 if peer, exists := n.Peers[message.Sender]; exists {
                     peer.LastSeen = time.Now().Unix()
                 }
+                
+                // Pass on any content, such as a validator's signed liveness
+                if message.Content != nil {
+                    heartbeatData, err := json.Marshal(message.Content)
+                    if err != nil {
+                        continue
+                    }
+                    n.HeartbeatQueue <- heartbeatData
+                }
+                
+            default:
+                // Deliver other types, such as consensus messages, to their subscribers
+                n.publish(message)
             }
         }
     }
 }
 
+// publish delivers a message's content to the subscribers of its type
+func (n *Node) publish(message Message) {
+    n.mutex.Lock()
+    subscribers := append([]chan []byte(nil), n.subscriptions[message.Type]...)
+    n.mutex.Unlock()
+    
+    if len(subscribers) == 0 {
+        return
+    }
+    
+    data, err := json.Marshal(message.Content)
+    if err != nil {
+        return
+    }
+    
+    for _, subscriber := range subscribers {
+        subscriber <- data
+    }
+}
+
 // handlePeerDiscovery handles peer discovery messages
 func (n *Node) handlePeerDiscovery(message Message) {
     content, ok := message.Content.(map[string]interface{})
@@ -493,7 +573,7 @@ func (p *PeerDiscovery) Start() {
     }
     
     // Start discovery server
-    listener, err := net.Listen("udp", fmt.Sprintf(":%d", p.DiscoveryPort))
+    listener, err := net.ListenUDP("udp", &net.UDPAddr{Port: p.DiscoveryPort})
     if err != nil {
         fmt.Printf("Error starting peer discovery: %v\n", err)
         return
@@ -504,7 +584,7 @@ func (p *PeerDiscovery) Start() {
     // Handle discovery requests
     buffer := make([]byte, 1024)
     for {
-        n, addr, err := listener.(*net.UDPConn).ReadFromUDP(buffer)
+        n, addr, err := listener.ReadFromUDP(buffer)
         if err != nil {
             continue
         }
@@ -549,7 +629,7 @@ func (p *PeerDiscovery) Start() {
                 continue
             }
             
-            listener.(*net.UDPConn).WriteToUDP(responseData, addr)
+            listener.WriteToUDP(responseData, addr)
         }
     }
 }
@@ -561,7 +641,11 @@ func (p *PeerDiscovery) sendHeartbeats() {
     
     for range ticker.C {
         // Send heartbeat to all peers
-        p.node.Broadcast("heartbeat", nil)
+        var content interface{}
+        if p.node.HeartbeatContent != nil {
+            content = p.node.HeartbeatContent()
+        }
+        p.node.Broadcast("heartbeat", content)
         
         // Check for inactive peers
         for id, peer := range p.node.Peers {
@@ -0,0 +1,169 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// Approval transaction types
+const (
+    TxTypeApprove     = "nft_approve"      // Data: nftId, operator; an empty operator clears the approval
+    TxTypeSetOperator = "nft_set_operator" // Data: operator, approved
+)
+
+// storeKeyOperatorPrefix prefixes persisted operator sets, one record per owner
+const storeKeyOperatorPrefix = "operator/"
+
+// Approve lets an operator transfer one NFT on the owner's behalf until it next changes hands
+// An empty operator clears the approval
+func (ns *NFTSystem) Approve(id string, owner string, operator string) error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return errors.New("NFT not found")
+    }
+
+    // Check ownership
+    if nft.Owner != owner {
+        return errors.New("sender is not the owner of this NFT")
+    }
+    if operator == owner {
+        return errors.New("owner cannot approve themselves")
+    }
+
+    nft.Approved = operator
+
+    return nil
+}
+
+// SetOperatorForAll lets an operator transfer every NFT of an owner, or revokes that right
+func (ns *NFTSystem) SetOperatorForAll(owner string, operator string, approved bool) error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    if operator == "" || operator == owner {
+        return errors.New("invalid operator")
+    }
+
+    ns.setOperator(owner, operator, approved)
+
+    return nil
+}
+
+// IsOperatorForAll reports whether an operator may transfer every NFT of an owner
+func (ns *NFTSystem) IsOperatorForAll(owner string, operator string) bool {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    return ns.Operators[owner][operator]
+}
+
+// GetOperators returns the operators an owner has approved for all their NFTs
+func (ns *NFTSystem) GetOperators(owner string) []string {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    operators := []string{}
+    for operator := range ns.Operators[owner] {
+        operators = append(operators, operator)
+    }
+    sort.Strings(operators)
+
+    return operators
+}
+
+// isApprovedOrOwner reports whether an address may transfer an NFT
+// The caller must hold the lock
+func (ns *NFTSystem) isApprovedOrOwner(nft *NFT, address string) bool {
+    return nft.Owner == address || (nft.Approved != "" && nft.Approved == address) || ns.Operators[nft.Owner][address]
+}
+
+// setOperator grants or revokes an operator's right over all of an owner's NFTs
+// The caller must hold the lock
+func (ns *NFTSystem) setOperator(owner string, operator string, approved bool) {
+    if approved {
+        if ns.Operators[owner] == nil {
+            ns.Operators[owner] = make(map[string]bool)
+        }
+        ns.Operators[owner][operator] = true
+    } else {
+        delete(ns.Operators[owner], operator)
+        if len(ns.Operators[owner]) == 0 {
+            delete(ns.Operators, owner)
+        }
+    }
+}
+
+// checkApprovalTransaction validates an approval transaction
+// The caller must hold the lock
+func (ns *NFTSystem) checkApprovalTransaction(tx core.Transaction) error {
+    operator := txString(tx, "operator")
+
+    switch tx.Type {
+    case TxTypeApprove:
+        nft, exists := ns.lookupNFT(txString(tx, "nftId"))
+        if !exists {
+            return errors.New("NFT not found")
+        }
+
+        // Check ownership
+        if nft.Owner != tx.Sender {
+            return errors.New("sender is not the owner of this NFT")
+        }
+        if operator == tx.Sender {
+            return errors.New("owner cannot approve themselves")
+        }
+
+    case TxTypeSetOperator:
+        if operator == "" || operator == tx.Sender {
+            return errors.New("invalid operator")
+        }
+    }
+
+    return nil
+}
+
+// applyApprovalTransaction executes a checked approval transaction
+// The caller must hold the lock
+func (ns *NFTSystem) applyApprovalTransaction(tx core.Transaction) {
+    switch tx.Type {
+    case TxTypeApprove:
+        nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+        nft.Approved = txString(tx, "operator")
+        ns.persistNFT(nft)
+        ns.record(nftEvent(EventKindApprove, nft, tx.Sender, nft.Approved, 0))
+
+    case TxTypeSetOperator:
+        approved, _ := txValue(tx, "approved").(bool)
+        ns.setOperator(tx.Sender, txString(tx, "operator"), approved)
+        ns.persistOperators(tx.Sender)
+        ns.record(NFTEvent{Kind: EventKindOperator, From: tx.Sender, To: txString(tx, "operator")})
+    }
+}
+
+// persistOperators writes an owner's operator set to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistOperators(owner string) {
+    if ns.store == nil {
+        return
+    }
+
+    key := storeKeyOperatorPrefix + owner
+    if len(ns.Operators[owner]) == 0 {
+        ns.recordStoreError(ns.store.Delete(key))
+        return
+    }
+
+    data, err := json.Marshal(ns.Operators[owner])
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(key, data))
+}
@@ -0,0 +1,338 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Auction transaction types
+const (
+    TxTypeAuctionCreate = "nft_auction_create" // Data: nftId, auctionType, startPrice, reservePrice, duration
+    TxTypeAuctionBid    = "nft_auction_bid"    // Data: auctionId; Amount is the bid, escrowed until outbid or settled
+)
+
+// Auction types
+const (
+    // AuctionTypeEnglish sells to the highest bid at or above the reserve when the auction ends
+    AuctionTypeEnglish = "english"
+
+    // AuctionTypeDutch lowers the price from the start price to the reserve and sells to the first bid that meets it
+    AuctionTypeDutch = "dutch"
+)
+
+// Auction statuses
+const (
+    AuctionStatusActive = "active"
+    AuctionStatusSold   = "sold"
+    AuctionStatusUnsold = "unsold"
+)
+
+// MaxAuctionDuration is the longest an auction can run, in seconds
+const MaxAuctionDuration = 30 * 24 * 60 * 60
+
+// storeKeyAuctionPrefix prefixes persisted auction records
+const storeKeyAuctionPrefix = "auction/"
+
+// Auction is a timed sale of an NFT; the NFT stays with the seller but is locked until settlement
+type Auction struct {
+    ID            string       `json:"id"`
+    NFTID         string       `json:"nftId"`
+    Seller        string       `json:"seller"`
+    Type          string       `json:"type"`
+    StartPrice    token.Amount `json:"startPrice"`   // Minimum opening bid (English) or opening price (Dutch)
+    ReservePrice  token.Amount `json:"reservePrice"` // Lowest price the NFT sells for
+    StartTime     int64        `json:"startTime"`
+    EndTime       int64        `json:"endTime"`
+    HighestBidder string       `json:"highestBidder,omitempty"`
+    HighestBid    token.Amount `json:"highestBid,omitempty"`
+    Bids          []Bid        `json:"bids"`
+    Status        string       `json:"status"`
+    SettledAt     int64        `json:"settledAt,omitempty"`
+}
+
+// Bid is a bid placed in an auction
+type Bid struct {
+    Bidder    string       `json:"bidder"`
+    Amount    token.Amount `json:"amount"`
+    Timestamp int64        `json:"timestamp"`
+    TxHash    string       `json:"txHash,omitempty"`    // ID of the bid transaction, which authorizes the sale if the bid wins
+    Signature string       `json:"signature,omitempty"` // Signature of the bid transaction
+}
+
+// CurrentPrice returns the price a Dutch auction asks at a given time
+// English auctions return the lowest acceptable next bid
+func (a *Auction) CurrentPrice(timestamp int64) token.Amount {
+    if a.Type == AuctionTypeEnglish {
+        if a.HighestBidder == "" {
+            return a.StartPrice
+        }
+        return a.HighestBid
+    }
+
+    // Decline linearly from the start price to the reserve over the auction
+    if timestamp <= a.StartTime {
+        return a.StartPrice
+    }
+    if timestamp >= a.EndTime {
+        return a.ReservePrice
+    }
+
+    elapsed := float64(timestamp-a.StartTime) / float64(a.EndTime-a.StartTime)
+    return a.StartPrice - (a.StartPrice - a.ReservePrice).MulRate(elapsed)
+}
+
+// AuctionID returns the ID of the auction created by an auction transaction
+func AuctionID(tx core.Transaction) string {
+    return "auction_" + tx.ID[:16]
+}
+
+// GetAuction gets an auction by ID
+func (ns *NFTSystem) GetAuction(id string) (*Auction, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    auction, exists := ns.Auctions[id]
+    if !exists {
+        return nil, errors.New("auction not found")
+    }
+
+    return auction, nil
+}
+
+// GetActiveAuctions returns all running auctions, soonest ending first
+func (ns *NFTSystem) GetActiveAuctions() []*Auction {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    activeAuctions := []*Auction{}
+    for _, auction := range ns.Auctions {
+        if auction.Status == AuctionStatusActive {
+            activeAuctions = append(activeAuctions, auction)
+        }
+    }
+
+    sort.Slice(activeAuctions, func(i, j int) bool {
+        if activeAuctions[i].EndTime != activeAuctions[j].EndTime {
+            return activeAuctions[i].EndTime < activeAuctions[j].EndTime
+        }
+        return activeAuctions[i].ID < activeAuctions[j].ID
+    })
+
+    return activeAuctions
+}
+
+// checkAuctionTransaction validates an auction transaction at a given block time
+// The caller must hold the lock
+func (ns *NFTSystem) checkAuctionTransaction(tx core.Transaction, now int64) error {
+    switch tx.Type {
+    case TxTypeAuctionCreate:
+        nft, exists := ns.lookupNFT(txString(tx, "nftId"))
+        if !exists {
+            return errors.New("NFT not found")
+        }
+
+        // Check ownership
+        if nft.Owner != tx.Sender {
+            return errors.New("sender is not the owner of this NFT")
+        }
+        if nft.IsListed {
+            return errors.New("NFT must be unlisted before it is auctioned")
+        }
+        if nft.AuctionID != "" {
+            return errors.New("NFT is already in an auction")
+        }
+        if nft.IsRented(now) {
+            return errors.New("NFT is rented out")
+        }
+        if err := checkLocked(nft, now); err != nil {
+            return err
+        }
+        if _, exists := ns.Auctions[AuctionID(tx)]; exists {
+            return errors.New("auction already exists")
+        }
+
+        auctionType := txString(tx, "auctionType")
+        startPrice := txAmount(tx, "startPrice")
+        reservePrice := txAmount(tx, "reservePrice")
+        duration := txFloat(tx, "duration")
+
+        if auctionType != AuctionTypeEnglish && auctionType != AuctionTypeDutch {
+            return errors.New("auction type must be english or dutch")
+        }
+        if startPrice <= 0 || reservePrice < 0 {
+            return errors.New("auction prices must be positive")
+        }
+        if auctionType == AuctionTypeDutch && reservePrice > startPrice {
+            return errors.New("dutch auction reserve cannot exceed its start price")
+        }
+        if duration <= 0 || duration > MaxAuctionDuration {
+            return errors.New("auction duration is out of range")
+        }
+        return nil
+
+    case TxTypeAuctionBid:
+        auction, exists := ns.Auctions[txString(tx, "auctionId")]
+        if !exists {
+            return errors.New("auction not found")
+        }
+
+        if auction.Status != AuctionStatusActive || now >= auction.EndTime {
+            return errors.New("auction has ended")
+        }
+        if auction.Seller == tx.Sender {
+            return errors.New("seller cannot bid in their own auction")
+        }
+
+        if auction.Type == AuctionTypeEnglish {
+            if tx.Amount < auction.StartPrice {
+                return errors.New("bid is below the starting price")
+            }
+            if auction.HighestBidder != "" && tx.Amount <= auction.HighestBid {
+                return errors.New("bid does not exceed the highest bid")
+            }
+        } else if tx.Amount < auction.CurrentPrice(now) {
+            return errors.New("bid is below the current price")
+        }
+        return nil
+    }
+
+    return nil
+}
+
+// applyAuctionTransaction executes a checked auction transaction, moving escrowed funds in state
+// The caller must hold the lock
+func (ns *NFTSystem) applyAuctionTransaction(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    switch tx.Type {
+    case TxTypeAuctionCreate:
+        nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+
+        auction := &Auction{
+            ID:           AuctionID(tx),
+            NFTID:        nft.ID,
+            Seller:       tx.Sender,
+            Type:         txString(tx, "auctionType"),
+            StartPrice:   txAmount(tx, "startPrice"),
+            ReservePrice: txAmount(tx, "reservePrice"),
+            StartTime:    header.Timestamp,
+            EndTime:      header.Timestamp + int64(txFloat(tx, "duration")),
+            Bids:         []Bid{},
+            Status:       AuctionStatusActive,
+        }
+        ns.Auctions[auction.ID] = auction
+
+        // Lock the NFT until the auction settles
+        nft.AuctionID = auction.ID
+
+        ns.persistAuction(auction)
+        ns.persistNFT(nft)
+
+        ns.record(nftEvent(EventKindAuctionStart, nft, auction.Seller, "", auction.StartPrice))
+
+    case TxTypeAuctionBid:
+        auction := ns.Auctions[txString(tx, "auctionId")]
+
+        if auction.Type == AuctionTypeDutch {
+            // The first bid meeting the price wins immediately and pays only the current price
+            price := auction.CurrentPrice(header.Timestamp)
+            if !collect(state, tx.Sender, price) {
+                return
+            }
+
+            auction.Bids = append(auction.Bids, Bid{Bidder: tx.Sender, Amount: price, Timestamp: header.Timestamp, TxHash: tx.ID, Signature: tx.Signature})
+            auction.HighestBidder = tx.Sender
+            auction.HighestBid = price
+            ns.record(nftEvent(EventKindAuctionBid, ns.NFTs[auction.NFTID], tx.Sender, "", price))
+
+            ns.settleAuction(auction, header.Timestamp, state)
+            return
+        }
+
+        // Escrow the new bid and refund the bid it replaces
+        if !collect(state, tx.Sender, tx.Amount) {
+            return
+        }
+        if auction.HighestBidder != "" {
+            release(state, auction.HighestBidder, auction.HighestBid)
+        }
+
+        auction.Bids = append(auction.Bids, Bid{Bidder: tx.Sender, Amount: tx.Amount, Timestamp: header.Timestamp, TxHash: tx.ID, Signature: tx.Signature})
+        auction.HighestBidder = tx.Sender
+        auction.HighestBid = tx.Amount
+
+        ns.persistAuction(auction)
+        ns.record(nftEvent(EventKindAuctionBid, ns.NFTs[auction.NFTID], tx.Sender, "", tx.Amount))
+    }
+}
+
+// settleExpiredAuctions settles every active auction that has ended by a block time
+// Auctions are settled in ID order so balance updates are applied identically on every node
+// The caller must hold the lock
+func (ns *NFTSystem) settleExpiredAuctions(timestamp int64, state *core.State) {
+    expired := []*Auction{}
+    for _, auction := range ns.Auctions {
+        if auction.Status == AuctionStatusActive && timestamp >= auction.EndTime {
+            expired = append(expired, auction)
+        }
+    }
+
+    sort.Slice(expired, func(i, j int) bool {
+        return expired[i].ID < expired[j].ID
+    })
+
+    // The winning bid authorizes the sale, and is always the last bid placed
+    for _, auction := range expired {
+        if len(auction.Bids) > 0 {
+            bid := auction.Bids[len(auction.Bids)-1]
+            ns.authorizing = transferAuth{txHash: bid.TxHash, signature: bid.Signature, height: ns.recordHeight}
+        }
+        ns.settleAuction(auction, timestamp, state)
+        ns.authorizing = transferAuth{}
+    }
+}
+
+// settleAuction closes an auction, paying the seller and transferring the NFT if the reserve was met
+// Otherwise the escrowed bid is refunded and the NFT is released to the seller
+// The caller must hold the lock
+func (ns *NFTSystem) settleAuction(auction *Auction, timestamp int64, state *core.State) {
+    nft := ns.NFTs[auction.NFTID]
+    nft.AuctionID = ""
+
+    if auction.HighestBidder != "" && auction.HighestBid >= auction.ReservePrice {
+        // Pay out the escrowed bid and hand over the NFT
+        ns.settleSale(nft, auction.HighestBidder, auction.HighestBid, timestamp, state)
+        auction.Status = AuctionStatusSold
+    } else {
+        if auction.HighestBidder != "" {
+            release(state, auction.HighestBidder, auction.HighestBid)
+        }
+        auction.Status = AuctionStatusUnsold
+    }
+
+    auction.SettledAt = timestamp
+
+    ns.persistAuction(auction)
+    ns.persistNFT(nft)
+
+    // The sale itself, if any, was recorded by the transfer
+    ns.record(nftEvent(EventKindAuctionEnd, nft, auction.Seller, auction.HighestBidder, auction.HighestBid))
+}
+
+// persistAuction writes an auction record to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistAuction(auction *Auction) {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(auction)
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(storeKeyAuctionPrefix+auction.ID, data))
+}
@@ -0,0 +1,124 @@
+package nft
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeTransferBatch moves several NFTs from one owner to one recipient in a single transaction
+// Data: nftIds, optional bestEffort; Recipient is the new owner; Sender is the owner or an approved operator of every NFT
+// Without bestEffort the transaction fails unless every NFT can move; with it, the NFTs that can move do
+const TxTypeTransferBatch = "nft_transfer_batch"
+
+// MaxBatchTransfer is the most NFTs a batch transfer can move
+const MaxBatchTransfer = 100
+
+// BatchFailure is an NFT a batch transfer could not move, and why
+type BatchFailure struct {
+    NFTID string `json:"nftId"`
+    Error string `json:"error"`
+}
+
+// BatchTransferResult reports which NFTs a batch transfer moved
+type BatchTransferResult struct {
+    Transferred []string       `json:"transferred"`
+    Failed      []BatchFailure `json:"failed"`
+}
+
+// TransferNFTBatch moves several NFTs to a new owner
+// Unless bestEffort is set, nothing moves when any NFT can't; the result reports each NFT that could not move
+func (ns *NFTSystem) TransferNFTBatch(ids []string, fromAddress string, toAddress string, bestEffort bool) (*BatchTransferResult, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    now := token.ClockNow(ns.Clock)
+    items, result, err := ns.checkBatch(ids, fromAddress, toAddress, bestEffort, now)
+    if err != nil {
+        return result, err
+    }
+
+    for _, item := range items {
+        ns.applyTransaction(item, now)
+    }
+
+    return result, nil
+}
+
+// checkBatch splits a batch into the transfers that can execute and the NFTs that can't
+// It fails if the batch is malformed, if nothing can move, or if anything can't move and bestEffort is unset
+// The caller must hold the lock
+func (ns *NFTSystem) checkBatch(ids []string, fromAddress string, toAddress string, bestEffort bool, now int64) ([]core.Transaction, *BatchTransferResult, error) {
+    result := &BatchTransferResult{Transferred: []string{}, Failed: []BatchFailure{}}
+
+    if len(ids) == 0 {
+        return nil, result, errors.New("batch transfer names no NFTs")
+    }
+    if len(ids) > MaxBatchTransfer {
+        return nil, result, fmt.Errorf("batch transfer can move at most %d NFTs", MaxBatchTransfer)
+    }
+
+    // Each item is checked as the single transfer it stands for
+    items := []core.Transaction{}
+    seen := make(map[string]bool)
+    for _, id := range ids {
+        item := core.Transaction{
+            Type:      TxTypeTransfer,
+            Sender:    fromAddress,
+            Recipient: toAddress,
+            Data:      map[string]interface{}{"nftId": id},
+        }
+
+        err := ns.checkAuthorized(item, now)
+        if err == nil && seen[ns.resolveNFTID(id)] {
+            err = errors.New("NFT is named more than once")
+        }
+        if err != nil {
+            result.Failed = append(result.Failed, BatchFailure{NFTID: id, Error: err.Error()})
+            continue
+        }
+
+        seen[ns.resolveNFTID(id)] = true
+        items = append(items, item)
+        result.Transferred = append(result.Transferred, id)
+    }
+
+    if len(result.Failed) > 0 && !bestEffort {
+        result.Transferred = []string{}
+        return nil, result, fmt.Errorf("NFT %s can't be transferred: %s", result.Failed[0].NFTID, result.Failed[0].Error)
+    }
+    if len(items) == 0 {
+        return nil, result, errors.New("no NFT in the batch can be transferred")
+    }
+
+    return items, result, nil
+}
+
+// batchIDs returns the NFT IDs named by a batch transfer transaction
+func batchIDs(tx core.Transaction) []string {
+    return txStrings(tx, "nftIds")
+}
+
+// checkBatchTransaction validates a batch transfer transaction at a given block time
+// The caller must hold the lock
+func (ns *NFTSystem) checkBatchTransaction(tx core.Transaction, now int64) error {
+    bestEffort, _ := txValue(tx, "bestEffort").(bool)
+    _, _, err := ns.checkBatch(batchIDs(tx), tx.Sender, tx.Recipient, bestEffort, now)
+    return err
+}
+
+// applyBatchTransaction executes a checked batch transfer, moving every NFT that can move
+// The caller must hold the lock
+func (ns *NFTSystem) applyBatchTransaction(tx core.Transaction, timestamp int64) {
+    bestEffort, _ := txValue(tx, "bestEffort").(bool)
+    items, _, err := ns.checkBatch(batchIDs(tx), tx.Sender, tx.Recipient, bestEffort, timestamp)
+    if err != nil {
+        return
+    }
+
+    for _, item := range items {
+        ns.applyTransaction(item, timestamp)
+    }
+}
@@ -0,0 +1,736 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/storage"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Transaction types executed by the NFT registry
+const (
+    TxTypeMint     = "nft_mint"     // Data: nftType, metadata or metadataUri and metadataHash, yieldRate, optional collectionId; Recipient is the owner
+    TxTypeTransfer = "nft_transfer" // Data: nftId; Recipient is the new owner; Sender is the owner or an approved operator
+    TxTypeList     = "nft_list"     // Data: nftId, price, optional expiresAt; fills the best crossing bid
+    TxTypeUnlist   = "nft_unlist"   // Data: nftId
+    TxTypeBuy      = "nft_buy"      // Data: nftId; Amount, if set, is the most the buyer will pay
+    TxTypeBurn     = "nft_burn"     // Data: nftId; Sender is the owner or an approved operator
+)
+
+// Store keys used by the registry
+const (
+    storeKeyNFTPrefix = "nft/"
+    storeKeyHead      = "nftsystem/head"
+)
+
+// registryVersion is the format of the persisted registry
+// Version 2 introduced content-derived NFT IDs; version 3 links transfer records to their transactions
+const registryVersion = 3
+
+// registryHead is the persisted position of the registry in the chain
+type registryHead struct {
+    Version   int    `json:"version"`
+    Height    int64  `json:"height"`
+    BlockHash string `json:"blockHash"`
+    BlockTime int64  `json:"blockTime"`
+    NextID    int    `json:"nextId"`
+}
+
+// OpenNFTSystem loads an NFT registry persisted in a store
+// Callers should compare Height and BlockHash with the chain and rebuild the registry if they differ
+// A registry saved in an older format is not loaded, so callers rebuild it and its NFTs get content-derived IDs
+func OpenNFTSystem(masterWalletAddress string, store storage.Store) (*NFTSystem, error) {
+    ns := NewNFTSystem(masterWalletAddress)
+    ns.store = store
+
+    headData, err := store.Get(storeKeyHead)
+    if errors.Is(err, storage.ErrNotFound) {
+        return ns, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var head registryHead
+    if err := json.Unmarshal(headData, &head); err != nil {
+        return nil, fmt.Errorf("invalid NFT registry head: %w", err)
+    }
+    if head.Version != registryVersion {
+        return ns, nil
+    }
+
+    keys, err := store.Keys(storeKeyNFTPrefix)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, key := range keys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        nft, err := DeserializeNFT(string(data))
+        if err != nil {
+            return nil, fmt.Errorf("invalid NFT record %s: %w", key, err)
+        }
+        ns.NFTs[nft.ID] = nft
+    }
+
+    auctionKeys, err := store.Keys(storeKeyAuctionPrefix)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, key := range auctionKeys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        var auction Auction
+        if err := json.Unmarshal(data, &auction); err != nil {
+            return nil, fmt.Errorf("invalid auction record %s: %w", key, err)
+        }
+        ns.Auctions[auction.ID] = &auction
+    }
+
+    collectionKeys, err := store.Keys(storeKeyCollectionPrefix)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, key := range collectionKeys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        var collection Collection
+        if err := json.Unmarshal(data, &collection); err != nil {
+            return nil, fmt.Errorf("invalid collection record %s: %w", key, err)
+        }
+        ns.Collections[collection.ID] = &collection
+    }
+
+    operatorKeys, err := store.Keys(storeKeyOperatorPrefix)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, key := range operatorKeys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        var operators map[string]bool
+        if err := json.Unmarshal(data, &operators); err != nil {
+            return nil, fmt.Errorf("invalid operator record %s: %w", key, err)
+        }
+        ns.Operators[strings.TrimPrefix(key, storeKeyOperatorPrefix)] = operators
+    }
+
+    orderKeys, err := store.Keys(storeKeyOrderPrefix)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, key := range orderKeys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        var order Order
+        if err := json.Unmarshal(data, &order); err != nil {
+            return nil, fmt.Errorf("invalid order record %s: %w", key, err)
+        }
+        ns.Orders[order.ID] = &order
+    }
+
+    voucherKeys, err := store.Keys(storeKeyVoucherPrefix)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, key := range voucherKeys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        var nftID string
+        if err := json.Unmarshal(data, &nftID); err != nil {
+            return nil, fmt.Errorf("invalid voucher record %s: %w", key, err)
+        }
+        ns.redeemedVouchers[strings.TrimPrefix(key, storeKeyVoucherPrefix)] = nftID
+    }
+
+    dropKeys, err := store.Keys(storeKeyDropPrefix)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, key := range dropKeys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        var drop Drop
+        if err := json.Unmarshal(data, &drop); err != nil {
+            return nil, fmt.Errorf("invalid drop record %s: %w", key, err)
+        }
+        ns.Drops[drop.ID] = &drop
+    }
+
+    saleKeys, err := store.Keys(storeKeySalePrefix)
+    if err != nil {
+        return nil, err
+    }
+
+    history := make([]SaleRecord, 0, len(saleKeys))
+    for _, key := range saleKeys {
+        data, err := store.Get(key)
+        if err != nil {
+            return nil, err
+        }
+
+        var sale SaleRecord
+        if err := json.Unmarshal(data, &sale); err != nil {
+            return nil, fmt.Errorf("invalid sale record %s: %w", key, err)
+        }
+        history = append(history, sale)
+    }
+    ns.reindexSales(history)
+
+    ns.reindexListings()
+    ns.reindexRentals()
+    ns.reindexStakes()
+    ns.reindexLocks()
+    ns.reindexAliases()
+    ns.reindexYieldTiers()
+    ns.reindexOrders()
+    ns.reindexAttributes()
+
+    if data, err := store.Get(storeKeyFeePolicy); err == nil {
+        var policy FeePolicy
+        if err := json.Unmarshal(data, &policy); err != nil {
+            return nil, fmt.Errorf("invalid fee policy record: %w", err)
+        }
+        ns.FeePolicy = &policy
+    } else if !errors.Is(err, storage.ErrNotFound) {
+        return nil, err
+    }
+
+    ns.Height = head.Height
+    ns.BlockHash = head.BlockHash
+    ns.BlockTime = head.BlockTime
+    ns.NextID = head.NextID
+
+    return ns, nil
+}
+
+// CheckTransaction reports whether an NFT transaction would succeed against the current registry
+// Time-dependent checks use the timestamp of the last applied block
+func (ns *NFTSystem) CheckTransaction(tx core.Transaction) error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    return ns.checkTransaction(tx, ns.BlockTime)
+}
+
+// ApplyTransaction executes a confirmed NFT transaction and persists the changes
+func (ns *NFTSystem) ApplyTransaction(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    ns.beginRecording(header)
+
+    // Failed transactions leave the registry untouched
+    if ns.checkTransaction(tx, header.Timestamp) != nil {
+        return
+    }
+
+    // Link the transfers the transaction causes back to it
+    ns.authorizing = authorizationOf(tx, header.Index)
+    defer func() {
+        ns.authorizing = transferAuth{}
+    }()
+
+    switch tx.Type {
+    case TxTypeAuctionCreate, TxTypeAuctionBid:
+        ns.applyAuctionTransaction(tx, header, state)
+    case TxTypeCollectionCreate:
+        ns.applyCollectionCreate(tx, header.Timestamp)
+    case TxTypeBuy:
+        ns.applyBuy(tx, header.Timestamp, state)
+    case TxTypeRentList, TxTypeRentUnlist, TxTypeRent:
+        ns.applyRentalTransaction(tx, header, state)
+    case TxTypeApprove, TxTypeSetOperator:
+        ns.applyApprovalTransaction(tx)
+    case TxTypeBidPlace, TxTypeBidCancel:
+        ns.applyOrderTransaction(tx, header.Timestamp, state)
+    case TxTypeSetFeePolicy:
+        ns.applyFeePolicyTransaction(tx)
+    case TxTypeVoucherBuy:
+        ns.applyVoucherTransaction(tx, header.Timestamp, state)
+    case TxTypeTransferBatch:
+        ns.applyBatchTransaction(tx, header.Timestamp)
+    case TxTypeStake, TxTypeUnstake:
+        ns.applyStakingTransaction(tx, header.Timestamp)
+    case TxTypeDropCreate, TxTypeDropBuy:
+        ns.applyDropTransaction(tx, header, state)
+    case TxTypeSetGameMetadata:
+        ns.applyGameMetadataTransaction(tx)
+    case TxTypeLock, TxTypeUnlock:
+        ns.applyLockTransaction(tx, header.Timestamp)
+    case TxTypeSetCompounding:
+        ns.applyCompoundingTransaction(tx)
+    case TxTypeList:
+        ns.applyTransaction(tx, header.Timestamp)
+        nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+        ns.matchListing(nft, header.Timestamp, state)
+    default:
+        ns.applyTransaction(tx, header.Timestamp)
+    }
+}
+
+// EndBlock fills the block's drop purchases, settles auctions, rentals, orders, unstakes and match locks that have ended and records the block the registry has been applied up to
+func (ns *NFTSystem) EndBlock(header core.BlockHeader, state *core.State) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    ns.beginRecording(header)
+    ns.fillDropPurchases(header, state)
+    ns.settleExpiredAuctions(header.Timestamp, state)
+    ns.expireRentals(header.Timestamp)
+    ns.expireOrders(header.Timestamp, state)
+    ns.releaseUnstaked(header.Timestamp)
+    ns.releaseLocks(header.Timestamp)
+    ns.recording = false
+    ns.blockProducer = ""
+
+    ns.Height = header.Index
+    ns.BlockHash = header.Hash
+    ns.BlockTime = header.Timestamp
+    ns.persistHead()
+}
+
+// Fork returns an empty registry with the same schemas and yield tiers and no store
+// Its fee policy, mint policies, game namespaces and stake boosts come from the genesis block it replays
+func (ns *NFTSystem) Fork() core.Module {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    forked := NewNFTSystem(ns.MasterWalletAddress)
+    for nftType, schema := range ns.Schemas {
+        forked.Schemas[nftType] = schema
+    }
+    forked.reindexAttributes()
+    for number, tier := range ns.YieldTiers {
+        forked.YieldTiers[number] = tier
+    }
+    forked.StakeUnlockDelay = ns.StakeUnlockDelay
+    return forked
+}
+
+// Reset clears the registry and its persisted records and restores the default settings, which genesis changes as it is replayed
+func (ns *NFTSystem) Reset() {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    ns.NFTs = make(map[string]*NFT)
+    ns.NextID = 1
+    ns.Auctions = make(map[string]*Auction)
+    ns.Collections = make(map[string]*Collection)
+    ns.Orders = make(map[string]*Order)
+    ns.Drops = make(map[string]*Drop)
+    ns.dropQueue = nil
+    ns.openBids = make(map[string]*Order)
+    ns.FeePolicy = DefaultFeePolicy(DefaultFeeRate)
+    ns.MintPolicies = defaultMintPolicies(ns.MasterWalletAddress)
+    ns.GameNamespaces = make(map[string]*GameNamespace)
+    ns.StakeBoosts = DefaultStakeBoosts()
+    ns.Operators = make(map[string]map[string]bool)
+    ns.reindexSales(nil)
+    ns.listings = newListingIndex()
+    ns.rented = make(map[string]*NFT)
+    ns.aliases = make(map[string]string)
+    ns.tierSupply = make(map[int]int64)
+    ns.redeemedVouchers = make(map[string]string)
+    ns.staked = make(map[string]*NFT)
+    ns.locked = make(map[string]*NFT)
+    ns.reindexAttributes()
+    ns.Height = -1
+    ns.BlockHash = ""
+    ns.BlockTime = 0
+
+    if ns.store == nil {
+        return
+    }
+
+    for _, prefix := range []string{storeKeyNFTPrefix, storeKeyAuctionPrefix, storeKeyCollectionPrefix, storeKeyOperatorPrefix, storeKeySalePrefix, storeKeyOrderPrefix, storeKeyVoucherPrefix, storeKeyDropPrefix} {
+        keys, err := ns.store.Keys(prefix)
+        if err != nil {
+            ns.recordStoreError(err)
+            return
+        }
+        for _, key := range keys {
+            ns.recordStoreError(ns.store.Delete(key))
+        }
+    }
+    ns.recordStoreError(ns.store.Delete(storeKeyFeePolicy))
+    ns.recordStoreError(ns.store.Delete(storeKeyHead))
+}
+
+// Flush makes the persisted registry durable, returning any earlier write error
+func (ns *NFTSystem) Flush() error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    if ns.store == nil {
+        return nil
+    }
+
+    if ns.storeErr != nil {
+        return ns.storeErr
+    }
+
+    return ns.store.Flush()
+}
+
+// checkTransaction validates an NFT transaction against the registry at a given block time
+// Every NFT transaction acts on its sender's NFTs or balance, so it must be signed by the sender
+// The caller must hold the lock
+func (ns *NFTSystem) checkTransaction(tx core.Transaction, now int64) error {
+    if strings.HasPrefix(tx.Type, "nft_") {
+        if err := core.VerifyTransactionSignature(tx); err != nil {
+            return err
+        }
+    }
+
+    return ns.checkAuthorized(tx, now)
+}
+
+// checkAuthorized validates an NFT transaction whose sender is already authenticated, such as one item of a signed batch
+// The caller must hold the lock
+func (ns *NFTSystem) checkAuthorized(tx core.Transaction, now int64) error {
+    switch tx.Type {
+    case TxTypeAuctionCreate, TxTypeAuctionBid:
+        return ns.checkAuctionTransaction(tx, now)
+
+    case TxTypeCollectionCreate:
+        return ns.checkCollectionTransaction(tx)
+
+    case TxTypeRentList, TxTypeRentUnlist, TxTypeRent:
+        return ns.checkRentalTransaction(tx, now)
+
+    case TxTypeApprove, TxTypeSetOperator:
+        return ns.checkApprovalTransaction(tx)
+
+    case TxTypeBidPlace, TxTypeBidCancel:
+        return ns.checkOrderTransaction(tx, now)
+
+    case TxTypeSetFeePolicy:
+        return ns.checkFeePolicyTransaction(tx)
+
+    case TxTypeVoucherBuy:
+        return ns.checkVoucherTransaction(tx)
+
+    case TxTypeTransferBatch:
+        return ns.checkBatchTransaction(tx, now)
+
+    case TxTypeStake, TxTypeUnstake:
+        return ns.checkStakingTransaction(tx)
+
+    case TxTypeDropCreate, TxTypeDropBuy:
+        return ns.checkDropTransaction(tx, now)
+
+    case TxTypeSetGameMetadata:
+        return ns.checkGameMetadataTransaction(tx)
+
+    case TxTypeLock, TxTypeUnlock:
+        return ns.checkLockTransaction(tx, now)
+
+    case TxTypeSetCompounding:
+        return ns.checkCompoundingTransaction(tx)
+
+    case TxTypeMint:
+        if txString(tx, "nftType") == "" {
+            return errors.New("NFT type is required")
+        }
+        if _, exists := ns.NFTs[MintedNFTID(tx)]; exists {
+            return errors.New("NFT already exists")
+        }
+        if err := ns.checkMintAuthorization(tx); err != nil {
+            return err
+        }
+
+        // Off-chain metadata is validated against the schema when it is fetched
+        metadataURI := txString(tx, "metadataUri")
+        if err := checkMetadataURI(metadataURI, txString(tx, "metadataHash")); err != nil {
+            return err
+        }
+        metadata, _ := txValue(tx, "metadata").(map[string]interface{})
+        if metadataURI == "" {
+            if err := ns.validateMetadata(txString(tx, "nftType"), metadata); err != nil {
+                return err
+            }
+        }
+
+        // Yield generators carry their tier on chain, since it sets their APY
+        if err := ns.checkYieldTier(txString(tx, "nftType"), metadata); err != nil {
+            return err
+        }
+        return ns.checkMintIntoCollection(tx)
+
+    case TxTypeTransfer, TxTypeList, TxTypeUnlist, TxTypeBuy, TxTypeBurn:
+        nft, exists := ns.lookupNFT(txString(tx, "nftId"))
+        if !exists {
+            return errors.New("NFT not found")
+        }
+
+        // Auctioned NFTs are locked until the auction settles
+        if nft.AuctionID != "" {
+            return errors.New("NFT is in an auction")
+        }
+
+        // Rented NFTs can't change hands until the rental ends
+        if nft.IsRented(now) && (tx.Type == TxTypeTransfer || tx.Type == TxTypeBuy || tx.Type == TxTypeBurn) {
+            return errors.New("NFT is rented out")
+        }
+
+        // NFTs in a match can't be sold or given away, though they can be unlisted
+        if tx.Type != TxTypeUnlist {
+            if err := checkLocked(nft, now); err != nil {
+                return err
+            }
+        }
+
+        switch tx.Type {
+        case TxTypeTransfer:
+            if !ns.isApprovedOrOwner(nft, tx.Sender) {
+                return errors.New("sender is not the owner of this NFT or approved to transfer it")
+            }
+            if tx.Recipient == "" {
+                return errors.New("recipient is required")
+            }
+
+        case TxTypeBurn:
+            if !ns.isApprovedOrOwner(nft, tx.Sender) {
+                return errors.New("sender is not the owner of this NFT or approved to burn it")
+            }
+
+        case TxTypeList:
+            if nft.Owner != tx.Sender {
+                return errors.New("sender is not the owner of this NFT")
+            }
+            if txAmount(tx, "price") <= 0 {
+                return errors.New("list price must be positive")
+            }
+            if expiresAt := int64(txFloat(tx, "expiresAt")); expiresAt != 0 && expiresAt <= now {
+                return errors.New("listing expiry must be in the future")
+            }
+
+        case TxTypeUnlist:
+            if nft.Owner != tx.Sender {
+                return errors.New("sender is not the owner of this NFT")
+            }
+            if !nft.IsListed {
+                return errors.New("NFT is not listed for sale")
+            }
+
+        case TxTypeBuy:
+            if !nft.listingLive(now) {
+                return errors.New("NFT is not listed for sale")
+            }
+            if nft.Owner == tx.Sender {
+                return errors.New("buyer is already the owner")
+            }
+            if tx.Amount > 0 && nft.ListPrice > tx.Amount {
+                return errors.New("list price exceeds the buyer's maximum")
+            }
+        }
+        return nil
+    }
+
+    return nil
+}
+
+// applyTransaction executes a checked NFT transaction and persists the NFT it changed
+// The caller must hold the lock
+func (ns *NFTSystem) applyTransaction(tx core.Transaction, timestamp int64) {
+    if nft := ns.executeTransaction(tx, timestamp); nft != nil {
+        ns.persistNFT(nft)
+    }
+}
+
+// executeTransaction executes a checked NFT transaction and returns the NFT it changed, or nil if none remains
+// The caller must hold the lock
+func (ns *NFTSystem) executeTransaction(tx core.Transaction, timestamp int64) *NFT {
+    switch tx.Type {
+    case TxTypeMint:
+        owner := tx.Recipient
+        if owner == "" {
+            owner = tx.Sender
+        }
+
+        metadata, _ := txValue(tx, "metadata").(map[string]interface{})
+        if metadata == nil {
+            metadata = map[string]interface{}{}
+        }
+
+        ns.NextID++
+        nft := ns.mint(MintedNFTID(tx), txString(tx, "nftType"), owner, tx.Sender, metadata, txFloat(tx, "yieldRate"), timestamp)
+        nft.MetadataURI = txString(tx, "metadataUri")
+        nft.MetadataHash = txString(tx, "metadataHash")
+        ns.assignYieldTier(nft)
+
+        // Earlier transactions named NFTs by the legacy ID, so replayed chains still resolve it
+        nft.LegacyID = legacyMintedNFTID(tx)
+        ns.aliases[nft.LegacyID] = nft.ID
+
+        // Count the NFT against its collection's supply
+        if collection, exists := ns.Collections[txString(tx, "collectionId")]; exists {
+            nft.CollectionID = collection.ID
+            collection.Minted++
+            ns.persistCollection(collection)
+        }
+
+        ns.record(nftEvent(EventKindMint, nft, "", owner, 0))
+        return nft
+
+    case TxTypeTransfer:
+        nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+        ns.transfer(nft, tx.Recipient, 0, timestamp)
+        return nft
+
+    case TxTypeList:
+        nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+        ns.list(nft, txAmount(tx, "price"), timestamp)
+        nft.ListExpires = int64(txFloat(tx, "expiresAt"))
+        return nft
+
+    case TxTypeUnlist:
+        nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+        ns.unlist(nft)
+        return nft
+
+    case TxTypeBurn:
+        nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+        ns.burn(nft)
+        return nil
+    }
+
+    return nil
+}
+
+// persistNFT writes an NFT record to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistNFT(nft *NFT) {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(nft)
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(storeKeyNFTPrefix+nft.ID, data))
+}
+
+// deleteNFT removes a burned NFT's record from the store
+// The caller must hold the lock
+func (ns *NFTSystem) deleteNFT(id string) {
+    if ns.store == nil {
+        return
+    }
+
+    ns.recordStoreError(ns.store.Delete(storeKeyNFTPrefix + id))
+}
+
+// persistHead writes the registry's chain position to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistHead() {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(registryHead{
+        Version:   registryVersion,
+        Height:    ns.Height,
+        BlockHash: ns.BlockHash,
+        BlockTime: ns.BlockTime,
+        NextID:    ns.NextID,
+    })
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(storeKeyHead, data))
+}
+
+// recordStoreError keeps the first store write error so Flush can report it
+// The caller must hold the lock
+func (ns *NFTSystem) recordStoreError(err error) {
+    if err != nil && ns.storeErr == nil {
+        ns.storeErr = err
+    }
+}
+
+// txValue returns a field of a transaction's data map
+func txValue(tx core.Transaction, key string) interface{} {
+    data, ok := tx.Data.(map[string]interface{})
+    if !ok {
+        return nil
+    }
+
+    return data[key]
+}
+
+// txString returns a string field of a transaction's data map
+func txString(tx core.Transaction, key string) string {
+    value, _ := txValue(tx, key).(string)
+    return value
+}
+
+// txStrings returns a list-of-strings field of a transaction's data map
+// Lists are []interface{} once decoded from JSON but may be []string in locally built transactions
+func txStrings(tx core.Transaction, key string) []string {
+    if list, ok := txValue(tx, key).([]string); ok {
+        return append([]string{}, list...)
+    }
+
+    values, _ := txValue(tx, key).([]interface{})
+
+    list := []string{}
+    for _, value := range values {
+        item, _ := value.(string)
+        list = append(list, item)
+    }
+
+    return list
+}
+
+// txFloat returns a numeric field of a transaction's data map
+// Numbers are float64 once decoded from JSON but may be integers in locally built transactions
+func txFloat(tx core.Transaction, key string) float64 {
+    value, _ := toFloat(txValue(tx, key))
+    return value
+}
+
+// txAmount returns an ILYZ amount field of a transaction's data map
+// Amounts are float64 once decoded from JSON but may be amounts or whole ILYZ integers in locally built transactions
+func txAmount(tx core.Transaction, key string) token.Amount {
+    amount, _ := token.DataAmount(txValue(tx, key))
+    return amount
+}
@@ -0,0 +1,225 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeCollectionCreate creates a collection
+// Data: name, maxSupply, metadata, royaltyRate, royaltyRecipient
+const TxTypeCollectionCreate = "nft_collection_create"
+
+// MaxRoyaltyRate is the highest royalty a collection can charge on sales (25%)
+const MaxRoyaltyRate = 0.25
+
+// storeKeyCollectionPrefix prefixes persisted collection records
+const storeKeyCollectionPrefix = "collection/"
+
+// Collection groups NFTs minted by one creator under a shared supply cap and royalty
+type Collection struct {
+    ID               string                 `json:"id"`
+    Name             string                 `json:"name"`
+    Creator          string                 `json:"creator"`
+    MaxSupply        int64                  `json:"maxSupply"` // 0 means uncapped
+    Minted           int64                  `json:"minted"`
+    Metadata         map[string]interface{} `json:"metadata"`
+    RoyaltyRate      float64                `json:"royaltyRate"`
+    RoyaltyRecipient string                 `json:"royaltyRecipient"`
+    CreatedAt        int64                  `json:"createdAt"`
+    Volume           token.Amount           `json:"volume"` // Total sale value of the collection's NFTs
+    Sales            int64                  `json:"sales"`
+}
+
+// CollectionStats summarizes a collection's market
+type CollectionStats struct {
+    CollectionID string       `json:"collectionId"`
+    Minted       int64        `json:"minted"`
+    MaxSupply    int64        `json:"maxSupply"`
+    Owners       int          `json:"owners"`
+    Listed       int          `json:"listed"`
+    FloorPrice   token.Amount `json:"floorPrice"` // Lowest list price, 0 when nothing is listed
+    Volume       token.Amount `json:"volume"`
+    Sales        int64        `json:"sales"`
+}
+
+// CollectionID returns the ID of the collection created by a collection transaction
+func CollectionID(tx core.Transaction) string {
+    return "collection_" + tx.ID[:16]
+}
+
+// GetCollection gets a collection by ID
+func (ns *NFTSystem) GetCollection(id string) (*Collection, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    collection, exists := ns.Collections[id]
+    if !exists {
+        return nil, errors.New("collection not found")
+    }
+
+    return collection, nil
+}
+
+// GetNFTsByCollection returns all NFTs minted into a collection
+func (ns *NFTSystem) GetNFTsByCollection(collectionID string) []*NFT {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    collectionNFTs := []*NFT{}
+
+    for _, nft := range ns.NFTs {
+        if nft.CollectionID == collectionID {
+            collectionNFTs = append(collectionNFTs, nft)
+        }
+    }
+
+    return collectionNFTs
+}
+
+// GetCollectionStats returns supply, ownership and market stats for a collection
+func (ns *NFTSystem) GetCollectionStats(collectionID string) (CollectionStats, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    collection, exists := ns.Collections[collectionID]
+    if !exists {
+        return CollectionStats{}, errors.New("collection not found")
+    }
+
+    stats := CollectionStats{
+        CollectionID: collection.ID,
+        Minted:       collection.Minted,
+        MaxSupply:    collection.MaxSupply,
+        Volume:       collection.Volume,
+        Sales:        collection.Sales,
+    }
+
+    owners := make(map[string]bool)
+    for _, nft := range ns.NFTs {
+        if nft.CollectionID != collectionID {
+            continue
+        }
+
+        owners[nft.Owner] = true
+
+        if nft.IsListed {
+            stats.Listed++
+            if stats.FloorPrice == 0 || nft.ListPrice < stats.FloorPrice {
+                stats.FloorPrice = nft.ListPrice
+            }
+        }
+    }
+    stats.Owners = len(owners)
+
+    return stats, nil
+}
+
+// checkCollectionTransaction validates a collection transaction
+// The caller must hold the lock
+func (ns *NFTSystem) checkCollectionTransaction(tx core.Transaction) error {
+    if _, exists := ns.Collections[CollectionID(tx)]; exists {
+        return errors.New("collection already exists")
+    }
+
+    if txString(tx, "name") == "" {
+        return errors.New("collection name is required")
+    }
+
+    if txFloat(tx, "maxSupply") < 0 {
+        return errors.New("max supply cannot be negative")
+    }
+
+    royaltyRate := txFloat(tx, "royaltyRate")
+    if royaltyRate < 0 || royaltyRate > MaxRoyaltyRate {
+        return errors.New("royalty rate is out of range")
+    }
+
+    return nil
+}
+
+// checkMintIntoCollection validates minting into the collection named by a mint transaction, if any
+// The caller must hold the lock
+func (ns *NFTSystem) checkMintIntoCollection(tx core.Transaction) error {
+    collectionID := txString(tx, "collectionId")
+    if collectionID == "" {
+        return nil
+    }
+
+    collection, exists := ns.Collections[collectionID]
+    if !exists {
+        return errors.New("collection not found")
+    }
+
+    if collection.Creator != tx.Sender {
+        return errors.New("only the collection creator can mint into it")
+    }
+
+    if collection.MaxSupply > 0 && collection.Minted >= collection.MaxSupply {
+        return errors.New("collection supply cap reached")
+    }
+
+    return nil
+}
+
+// applyCollectionCreate executes a checked collection transaction
+// The caller must hold the lock
+func (ns *NFTSystem) applyCollectionCreate(tx core.Transaction, timestamp int64) {
+    metadata, _ := txValue(tx, "metadata").(map[string]interface{})
+    if metadata == nil {
+        metadata = map[string]interface{}{}
+    }
+
+    // Royalties go to the creator unless another recipient is named
+    royaltyRecipient := txString(tx, "royaltyRecipient")
+    if royaltyRecipient == "" {
+        royaltyRecipient = tx.Sender
+    }
+
+    collection := &Collection{
+        ID:               CollectionID(tx),
+        Name:             txString(tx, "name"),
+        Creator:          tx.Sender,
+        MaxSupply:        int64(txFloat(tx, "maxSupply")),
+        Metadata:         metadata,
+        RoyaltyRate:      txFloat(tx, "royaltyRate"),
+        RoyaltyRecipient: royaltyRecipient,
+        CreatedAt:        timestamp,
+    }
+    ns.Collections[collection.ID] = collection
+
+    ns.persistCollection(collection)
+    ns.record(NFTEvent{Kind: EventKindCollectionCreate, Collection: collection.ID, From: collection.Creator})
+}
+
+// recordSale adds a sale to the stats of the NFT's collection
+// The caller must hold the lock
+func (ns *NFTSystem) recordSale(nft *NFT, price token.Amount) {
+    collection, exists := ns.Collections[nft.CollectionID]
+    if !exists || price <= 0 {
+        return
+    }
+
+    collection.Volume += price
+    collection.Sales++
+
+    ns.persistCollection(collection)
+}
+
+// persistCollection writes a collection record to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistCollection(collection *Collection) {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(collection)
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(storeKeyCollectionPrefix+collection.ID, data))
+}
@@ -0,0 +1,147 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// StateRootName is the name the registry's root is recorded under in the block state root
+const StateRootName = "nft"
+
+// NFTProof lets a client check an NFT record against the registry root without trusting the node serving it
+type NFTProof struct {
+    NFT   *NFT                   `json:"nft"`
+    Leaf  string                 `json:"leaf"` // Canonical hash of the NFT record
+    Steps []core.MerkleProofStep `json:"steps"`
+}
+
+// RegistrySnapshot is the whole NFT registry at a block, with an inclusion proof for every NFT
+type RegistrySnapshot struct {
+    Height    int64      `json:"height"`
+    BlockHash string     `json:"blockHash"`
+    Root      string     `json:"root"`
+    NFTs      []NFTProof `json:"nfts"`
+}
+
+// HashNFT returns the Merkle leaf of an NFT record
+func HashNFT(nft *NFT) string {
+    hash, _ := core.CanonicalHash(nft)
+    return hash
+}
+
+// VerifyNFTProof checks that a proof's NFT record is committed to by a registry root
+func VerifyNFTProof(proof NFTProof, root string) bool {
+    if proof.NFT == nil || HashNFT(proof.NFT) != proof.Leaf {
+        return false
+    }
+
+    return core.VerifyMerkleProof(proof.Leaf, proof.Steps, root)
+}
+
+// Verify checks every NFT in a snapshot against its root
+func (s *RegistrySnapshot) Verify() error {
+    for _, proof := range s.NFTs {
+        if !VerifyNFTProof(proof, s.Root) {
+            id := ""
+            if proof.NFT != nil {
+                id = proof.NFT.ID
+            }
+            return fmt.Errorf("NFT %q is not committed to by the snapshot root", id)
+        }
+    }
+
+    return nil
+}
+
+// StateCommitment returns the registry's Merkle root for the block state root
+func (ns *NFTSystem) StateCommitment() (string, string) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    _, leaves := ns.merkleLeaves()
+    return StateRootName, core.ComputeMerkleRoot(leaves)
+}
+
+// GetNFTProof returns an inclusion proof for an NFT against the current registry root
+func (ns *NFTSystem) GetNFTProof(id string) (*NFTProof, string, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return nil, "", errors.New("NFT not found")
+    }
+
+    ids, leaves := ns.merkleLeaves()
+    index := sort.SearchStrings(ids, nft.ID)
+
+    steps, err := core.BuildMerkleProof(leaves, index)
+    if err != nil {
+        return nil, "", err
+    }
+
+    return &NFTProof{NFT: nft, Leaf: leaves[index], Steps: steps}, core.ComputeMerkleRoot(leaves), nil
+}
+
+// Snapshot returns the registry at the last applied block with an inclusion proof for every NFT
+func (ns *NFTSystem) Snapshot() (*RegistrySnapshot, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    ids, leaves := ns.merkleLeaves()
+
+    snapshot := &RegistrySnapshot{
+        Height:    ns.Height,
+        BlockHash: ns.BlockHash,
+        Root:      core.ComputeMerkleRoot(leaves),
+        NFTs:      make([]NFTProof, 0, len(ids)),
+    }
+
+    for i, id := range ids {
+        steps, err := core.BuildMerkleProof(leaves, i)
+        if err != nil {
+            return nil, err
+        }
+
+        snapshot.NFTs = append(snapshot.NFTs, NFTProof{NFT: ns.NFTs[id], Leaf: leaves[i], Steps: steps})
+    }
+
+    return snapshot, nil
+}
+
+// ExportSnapshot writes a registry snapshot to a JSON file
+func (ns *NFTSystem) ExportSnapshot(path string) error {
+    snapshot, err := ns.Snapshot()
+    if err != nil {
+        return err
+    }
+
+    data, err := json.MarshalIndent(snapshot, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    return os.WriteFile(path, data, 0644)
+}
+
+// merkleLeaves returns the IDs of every NFT in ascending order with the matching leaf hashes
+// The caller must hold the lock
+func (ns *NFTSystem) merkleLeaves() ([]string, []string) {
+    ids := make([]string, 0, len(ns.NFTs))
+    for id := range ns.NFTs {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    leaves := make([]string, len(ids))
+    for i, id := range ids {
+        leaves[i] = HashNFT(ns.NFTs[id])
+    }
+
+    return ids, leaves
+}
@@ -0,0 +1,323 @@
+package nft
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Drop transaction types
+const (
+    TxTypeDropCreate = "nft_drop_create" // Data: nftType, metadata, price, supply, startTime, endTime, optional allowlist, maxPerAddress and collectionId
+    TxTypeDropBuy    = "nft_drop_buy"    // Data: dropId, optional quantity (default 1); the price is escrowed until the block ends
+)
+
+// storeKeyDropPrefix prefixes persisted drop records
+const storeKeyDropPrefix = "drop/"
+
+// Drop is a creator's scheduled mint window selling a fixed supply of NFTs at a fixed price
+// Purchases made in a block are filled when the block ends, in an order derived from the block hash,
+// so the order a producer puts purchases in doesn't decide who gets the last of the supply
+type Drop struct {
+    ID            string                 `json:"id"`
+    Creator       string                 `json:"creator"`
+    NFTType       string                 `json:"nftType"`
+    Metadata      map[string]interface{} `json:"metadata"` // Given to every NFT the drop mints
+    CollectionID  string                 `json:"collectionId,omitempty"`
+    Price         token.Amount           `json:"price"`
+    Supply        int64                  `json:"supply"`
+    Minted        int64                  `json:"minted"`
+    StartTime     int64                  `json:"startTime"`
+    EndTime       int64                  `json:"endTime"`                 // Exclusive
+    Allowlist     []string               `json:"allowlist,omitempty"`     // Addresses allowed to buy; empty allows everyone
+    MaxPerAddress int64                  `json:"maxPerAddress,omitempty"` // 0 means no limit
+    Purchases     map[string]int64       `json:"purchases"`               // NFTs minted to each buyer
+    CreatedAt     int64                  `json:"createdAt"`
+}
+
+// dropPurchase is a purchase waiting to be filled at the end of the block
+type dropPurchase struct {
+    tx       core.Transaction
+    drop     *Drop
+    quantity int64
+    height   int64
+}
+
+// DropID returns the ID of the drop created by a drop transaction
+func DropID(tx core.Transaction) string {
+    return "drop_" + tx.ID[:16]
+}
+
+// IsOpen reports whether a drop is selling at a given time
+func (d *Drop) IsOpen(now int64) bool {
+    return now >= d.StartTime && now < d.EndTime && d.Minted < d.Supply
+}
+
+// allows reports whether an address is on the drop's allowlist
+func (d *Drop) allows(address string) bool {
+    return len(d.Allowlist) == 0 || containsString(d.Allowlist, address)
+}
+
+// GetDrop gets a drop by ID
+func (ns *NFTSystem) GetDrop(id string) (*Drop, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    drop, exists := ns.Drops[id]
+    if !exists {
+        return nil, errors.New("drop not found")
+    }
+
+    return drop, nil
+}
+
+// GetOpenDrops returns the drops selling as of the last applied block, soonest ending first
+func (ns *NFTSystem) GetOpenDrops() []*Drop {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    openDrops := []*Drop{}
+    for _, drop := range ns.Drops {
+        if drop.IsOpen(ns.BlockTime) {
+            openDrops = append(openDrops, drop)
+        }
+    }
+
+    sort.Slice(openDrops, func(i, j int) bool {
+        if openDrops[i].EndTime != openDrops[j].EndTime {
+            return openDrops[i].EndTime < openDrops[j].EndTime
+        }
+        return openDrops[i].ID < openDrops[j].ID
+    })
+
+    return openDrops
+}
+
+// checkDropTransaction validates a drop transaction at a given block time
+// The caller must hold the lock
+func (ns *NFTSystem) checkDropTransaction(tx core.Transaction, now int64) error {
+    switch tx.Type {
+    case TxTypeDropCreate:
+        if _, exists := ns.Drops[DropID(tx)]; exists {
+            return errors.New("drop already exists")
+        }
+
+        nftType := txString(tx, "nftType")
+        if nftType == "" {
+            return errors.New("NFT type is required")
+        }
+        if err := ns.checkMinter(nftType, tx.Sender); err != nil {
+            return err
+        }
+        metadata, _ := txValue(tx, "metadata").(map[string]interface{})
+        if err := ns.validateMetadata(nftType, metadata); err != nil {
+            return err
+        }
+
+        if txAmount(tx, "price") <= 0 {
+            return errors.New("drop price must be positive")
+        }
+        if txFloat(tx, "supply") < 1 {
+            return errors.New("drop supply must be positive")
+        }
+        if txFloat(tx, "maxPerAddress") < 0 {
+            return errors.New("purchase limit cannot be negative")
+        }
+
+        startTime, endTime := int64(txFloat(tx, "startTime")), int64(txFloat(tx, "endTime"))
+        if endTime <= startTime {
+            return errors.New("drop must end after it starts")
+        }
+        if endTime <= now {
+            return errors.New("drop must end in the future")
+        }
+
+        return ns.checkMintIntoCollection(tx)
+
+    case TxTypeDropBuy:
+        drop, exists := ns.Drops[txString(tx, "dropId")]
+        if !exists {
+            return errors.New("drop not found")
+        }
+        if !drop.IsOpen(now) {
+            return errors.New("drop is not open")
+        }
+        if !drop.allows(tx.Sender) {
+            return errors.New("sender is not on the drop allowlist")
+        }
+
+        quantity := dropQuantity(tx)
+        if quantity < 1 {
+            return errors.New("quantity must be positive")
+        }
+        if drop.Minted+quantity > drop.Supply {
+            return errors.New("not enough of the drop's supply remains")
+        }
+
+        // Purchases waiting in this block count towards the limit
+        if drop.MaxPerAddress > 0 {
+            bought := drop.Purchases[tx.Sender]
+            for _, pending := range ns.dropQueue {
+                if pending.drop == drop && pending.tx.Sender == tx.Sender {
+                    bought += pending.quantity
+                }
+            }
+            if bought+quantity > drop.MaxPerAddress {
+                return errors.New("purchase exceeds the drop's per-address limit")
+            }
+        }
+    }
+
+    return nil
+}
+
+// applyDropTransaction executes a checked drop transaction
+// Purchases escrow their price and wait for the end of the block to be filled
+// The caller must hold the lock
+func (ns *NFTSystem) applyDropTransaction(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    switch tx.Type {
+    case TxTypeDropCreate:
+        metadata, _ := txValue(tx, "metadata").(map[string]interface{})
+        if metadata == nil {
+            metadata = map[string]interface{}{}
+        }
+
+        drop := &Drop{
+            ID:            DropID(tx),
+            Creator:       tx.Sender,
+            NFTType:       txString(tx, "nftType"),
+            Metadata:      metadata,
+            CollectionID:  txString(tx, "collectionId"),
+            Price:         txAmount(tx, "price"),
+            Supply:        int64(txFloat(tx, "supply")),
+            StartTime:     int64(txFloat(tx, "startTime")),
+            EndTime:       int64(txFloat(tx, "endTime")),
+            Allowlist:     txStrings(tx, "allowlist"),
+            MaxPerAddress: int64(txFloat(tx, "maxPerAddress")),
+            Purchases:     make(map[string]int64),
+            CreatedAt:     header.Timestamp,
+        }
+        ns.Drops[drop.ID] = drop
+
+        ns.persistDrop(drop)
+        ns.record(NFTEvent{Kind: EventKindDropCreate, Collection: drop.CollectionID, From: drop.Creator, Price: drop.Price})
+
+    case TxTypeDropBuy:
+        drop := ns.Drops[txString(tx, "dropId")]
+        quantity := dropQuantity(tx)
+
+        // A buyer who can't cover the escrow buys nothing
+        cost := drop.Price * token.Amount(quantity)
+        if !collect(state, tx.Sender, cost) {
+            return
+        }
+
+        ns.dropQueue = append(ns.dropQueue, dropPurchase{tx: tx, drop: drop, quantity: quantity, height: header.Index})
+    }
+}
+
+// fillDropPurchases fills the purchases made in a block in an order derived from the block hash
+// Units that can no longer be minted, because supply ran out, are refunded
+// The caller must hold the lock
+func (ns *NFTSystem) fillDropPurchases(header core.BlockHeader, state *core.State) {
+    queue := ns.dropQueue
+    ns.dropQueue = nil
+
+    keys := make(map[string]string, len(queue))
+    for _, purchase := range queue {
+        hash := sha256.Sum256([]byte(header.Hash + purchase.tx.ID))
+        keys[purchase.tx.ID] = hex.EncodeToString(hash[:])
+    }
+    sort.SliceStable(queue, func(i, j int) bool {
+        return keys[queue[i].tx.ID] < keys[queue[j].tx.ID]
+    })
+
+    for _, purchase := range queue {
+        ns.authorizing = authorizationOf(purchase.tx, purchase.height)
+        ns.fillDropPurchase(purchase, header.Timestamp, state)
+        ns.authorizing = transferAuth{}
+    }
+}
+
+// fillDropPurchase mints as many units of a purchase as the drop can still supply and refunds the rest
+// The caller must hold the lock
+func (ns *NFTSystem) fillDropPurchase(purchase dropPurchase, timestamp int64, state *core.State) {
+    drop, buyer := purchase.drop, purchase.tx.Sender
+    collection := ns.Collections[drop.CollectionID]
+
+    for i := int64(0); i < purchase.quantity; i++ {
+        full := drop.Minted >= drop.Supply ||
+            (collection != nil && collection.MaxSupply > 0 && collection.Minted >= collection.MaxSupply) ||
+            ns.checkYieldTier(drop.NFTType, drop.Metadata) != nil
+        if full {
+            release(state, buyer, drop.Price)
+            continue
+        }
+
+        metadata := make(map[string]interface{}, len(drop.Metadata))
+        for key, value := range drop.Metadata {
+            metadata[key] = value
+        }
+
+        nft := ns.mint(DropNFTID(drop, purchase.tx, i), drop.NFTType, buyer, drop.Creator, metadata, 0, timestamp)
+        nft.TransferLog[0].Price = drop.Price
+        ns.assignYieldTier(nft)
+
+        if collection != nil {
+            nft.CollectionID = collection.ID
+            collection.Minted++
+            ns.persistCollection(collection)
+        }
+
+        // Pay the creator, less the marketplace fee
+        split := ns.primarySplit(nft, drop.Price, timestamp)
+        ns.payFee(split.FeeShares, state)
+        release(state, drop.Creator, split.SellerAmount)
+
+        drop.Minted++
+        drop.Purchases[buyer]++
+
+        ns.persistNFT(nft)
+        ns.record(nftEvent(EventKindMint, nft, drop.Creator, buyer, drop.Price))
+        ns.settling = &split
+        ns.recordSaleHistory(nft, drop.Creator, buyer, drop.Price, timestamp)
+        ns.settling = nil
+    }
+
+    ns.persistDrop(drop)
+}
+
+// DropNFTID returns the ID of the NFT minted for one unit of a drop purchase
+func DropNFTID(drop *Drop, tx core.Transaction, unit int64) string {
+    return DeriveNFTID(drop.Creator, drop.CollectionID, tx.ID, unit)
+}
+
+// dropQuantity returns the number of NFTs a drop purchase asks for
+func dropQuantity(tx core.Transaction) int64 {
+    if txValue(tx, "quantity") == nil {
+        return 1
+    }
+
+    return int64(txFloat(tx, "quantity"))
+}
+
+// persistDrop writes a drop record to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistDrop(drop *Drop) {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(drop)
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(storeKeyDropPrefix+drop.ID, data))
+}
@@ -0,0 +1,111 @@
+package nft
+
+import (
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// NFT event kinds
+const (
+    EventKindMint             = "mint"
+    EventKindTransfer         = "transfer"
+    EventKindList             = "list"
+    EventKindUnlist           = "unlist"
+    EventKindListExpire       = "list_expire"
+    EventKindSale             = "sale"
+    EventKindBurn             = "burn"
+    EventKindAuctionStart     = "auction_start"
+    EventKindAuctionBid       = "auction_bid"
+    EventKindAuctionEnd       = "auction_end"
+    EventKindRentList         = "rent_list"
+    EventKindRentUnlist       = "rent_unlist"
+    EventKindRent             = "rent"
+    EventKindRentEnd          = "rent_end"
+    EventKindApprove          = "approve"
+    EventKindOperator         = "operator"
+    EventKindCollectionCreate = "collection_create"
+    EventKindBidPlace         = "bid_place"
+    EventKindBidCancel        = "bid_cancel"
+    EventKindBidExpire        = "bid_expire"
+    EventKindFeePolicy        = "fee_policy"
+    EventKindStake            = "stake"
+    EventKindUnstake          = "unstake"
+    EventKindStakeEnd         = "stake_end"
+    EventKindDropCreate       = "drop_create"
+    EventKindGameMetadata     = "game_metadata"
+    EventKindLock             = "lock"
+    EventKindUnlock           = "unlock"
+)
+
+// NFTEvent is the payload of an NFT chain event
+type NFTEvent struct {
+    Kind       string       `json:"kind"`
+    NFTID      string       `json:"nftId,omitempty"`
+    Collection string       `json:"collection,omitempty"`
+    From       string       `json:"from,omitempty"`
+    To         string       `json:"to,omitempty"`
+    Price      token.Amount `json:"price,omitempty"`
+    Game       string       `json:"game,omitempty"`
+    Timestamp  int64        `json:"timestamp"`
+}
+
+// DrainEvents returns and clears the events recorded for the blocks executed since the last call
+func (ns *NFTSystem) DrainEvents() []core.ChainEvent {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    events := ns.events
+    ns.events = nil
+
+    return events
+}
+
+// beginRecording starts recording events for a block
+// Mutations made outside block execution, through the direct API, are not recorded
+// The caller must hold the lock
+func (ns *NFTSystem) beginRecording(header core.BlockHeader) {
+    ns.recording = true
+    ns.recordHeight = header.Index
+    ns.recordTime = header.Timestamp
+    ns.blockProducer = header.Validator
+}
+
+// nftEvent builds an event about an NFT
+func nftEvent(kind string, nft *NFT, from string, to string, price token.Amount) NFTEvent {
+    return NFTEvent{
+        Kind:       kind,
+        NFTID:      nft.ID,
+        Collection: nft.CollectionID,
+        From:       from,
+        To:         to,
+        Price:      price,
+    }
+}
+
+// record adds an event to those reported for the block being executed
+// The caller must hold the lock
+func (ns *NFTSystem) record(event NFTEvent) {
+    if !ns.recording {
+        return
+    }
+
+    event.Timestamp = ns.recordTime
+
+    addresses := []string{}
+    if event.From != "" {
+        addresses = append(addresses, event.From)
+    }
+    if event.To != "" && event.To != event.From {
+        addresses = append(addresses, event.To)
+    }
+
+    ns.events = append(ns.events, core.ChainEvent{
+        Type:        core.EventNFT,
+        BlockHeight: ns.recordHeight,
+        Addresses:   addresses,
+        NFTID:       event.NFTID,
+        Collection:  event.Collection,
+        Kind:        event.Kind,
+        Data:        event,
+    })
+}
@@ -0,0 +1,287 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeSetFeePolicy replaces the marketplace fee policy; only the master wallet may send it
+// Data: policy, a FeePolicy object
+const TxTypeSetFeePolicy = "nft_set_fee_policy"
+
+// DefaultFeeRate is the marketplace fee rate charged when genesis sets no fee policy (0.5%)
+const DefaultFeeRate = 0.005
+
+// MaxFeeRate is the highest marketplace fee rate a policy can set (10%)
+const MaxFeeRate = 0.10
+
+// storeKeyFeePolicy is the store key of the fee policy in force
+const storeKeyFeePolicy = "nftsystem/feepolicy"
+
+// FeePolicy decides the marketplace fee charged on sales and rentals
+// A collection rate takes precedence over a type rate, which takes precedence over the default rate
+type FeePolicy struct {
+    DefaultRate     float64            `json:"defaultRate"`
+    TypeRates       map[string]float64 `json:"typeRates,omitempty"`       // Rate by NFT type
+    CollectionRates map[string]float64 `json:"collectionRates,omitempty"` // Rate by collection ID
+    MinFee          token.Amount       `json:"minFee"`                    // Smallest fee charged, capped at the price
+    Promotions      []FeePromotion     `json:"promotions,omitempty"`
+    Split           *FeeSplit          `json:"split,omitempty"` // How fees are shared out; nil pays the whole fee to the master wallet
+}
+
+// FeeSplit divides the marketplace fee between the master wallet, the producer of the block that includes the sale and a community pool
+// Shares are fractions of the fee and must add up to 1
+type FeeSplit struct {
+    MasterShare   float64 `json:"masterShare"`
+    ProducerShare float64 `json:"producerShare"`
+    PoolShare     float64 `json:"poolShare"`
+    PoolAddress   string  `json:"poolAddress,omitempty"`
+}
+
+// FeeShares is how one marketplace fee was paid out
+type FeeShares struct {
+    Master   token.Amount `json:"master"`
+    Producer token.Amount `json:"producer,omitempty"`
+    Pool     token.Amount `json:"pool,omitempty"`
+
+    ProducerAddress string `json:"producerAddress,omitempty"`
+    PoolAddress     string `json:"poolAddress,omitempty"`
+}
+
+// FeePromotion waives the marketplace fee for a time window
+// An empty NFT type or collection matches every NFT
+type FeePromotion struct {
+    Start        int64  `json:"start"`
+    End          int64  `json:"end"` // Exclusive
+    NFTType      string `json:"nftType,omitempty"`
+    CollectionID string `json:"collectionId,omitempty"`
+}
+
+// DefaultFeePolicy returns a policy charging one rate on everything
+func DefaultFeePolicy(rate float64) *FeePolicy {
+    return &FeePolicy{DefaultRate: rate}
+}
+
+// Validate checks that the policy's rates, floor and promotions are in range
+func (p *FeePolicy) Validate() error {
+    if p.DefaultRate < 0 || p.DefaultRate > MaxFeeRate {
+        return errors.New("default fee rate is out of range")
+    }
+    for nftType, rate := range p.TypeRates {
+        if rate < 0 || rate > MaxFeeRate {
+            return fmt.Errorf("fee rate for type %q is out of range", nftType)
+        }
+    }
+    for collectionID, rate := range p.CollectionRates {
+        if rate < 0 || rate > MaxFeeRate {
+            return fmt.Errorf("fee rate for collection %q is out of range", collectionID)
+        }
+    }
+    if p.MinFee < 0 {
+        return errors.New("minimum fee cannot be negative")
+    }
+    for _, promotion := range p.Promotions {
+        if promotion.End <= promotion.Start {
+            return errors.New("fee promotion must end after it starts")
+        }
+    }
+    if p.Split != nil {
+        return p.Split.Validate()
+    }
+
+    return nil
+}
+
+// Validate checks that the split's shares are in range, add up to the whole fee and name a pool to pay
+func (s *FeeSplit) Validate() error {
+    for _, share := range []float64{s.MasterShare, s.ProducerShare, s.PoolShare} {
+        if share < 0 || share > 1 {
+            return errors.New("fee share is out of range")
+        }
+    }
+    if math.Abs(s.MasterShare+s.ProducerShare+s.PoolShare-1) > 1e-9 {
+        return errors.New("fee shares must add up to 1")
+    }
+    if s.PoolShare > 0 && s.PoolAddress == "" {
+        return errors.New("pool address is required for a pool share")
+    }
+
+    return nil
+}
+
+// Shares divides a fee under the policy's split
+// The producer's share goes to the master wallet when no block producer is known, and rounding is left with the master wallet
+func (p *FeePolicy) Shares(fee token.Amount, producer string) FeeShares {
+    shares := FeeShares{Master: fee}
+    if p.Split == nil || fee <= 0 {
+        return shares
+    }
+
+    if producer != "" && p.Split.ProducerShare > 0 {
+        shares.Producer = fee.MulRate(p.Split.ProducerShare)
+        shares.ProducerAddress = producer
+    }
+    if p.Split.PoolShare > 0 {
+        shares.Pool = fee.MulRate(p.Split.PoolShare)
+        shares.PoolAddress = p.Split.PoolAddress
+    }
+    shares.Master = fee - shares.Producer - shares.Pool
+
+    return shares
+}
+
+// Rate returns the fee rate for an NFT, ignoring promotions and the floor
+func (p *FeePolicy) Rate(nft *NFT) float64 {
+    if rate, exists := p.CollectionRates[nft.CollectionID]; exists && nft.CollectionID != "" {
+        return rate
+    }
+    if rate, exists := p.TypeRates[nft.Type]; exists {
+        return rate
+    }
+
+    return p.DefaultRate
+}
+
+// Fee returns the marketplace fee on a payment for an NFT at a given time
+func (p *FeePolicy) Fee(nft *NFT, amount token.Amount, now int64) token.Amount {
+    if amount <= 0 || p.promoted(nft, now) {
+        return 0
+    }
+
+    fee := amount.MulRate(p.Rate(nft))
+    if fee < p.MinFee {
+        fee = p.MinFee
+    }
+    if fee > amount {
+        fee = amount
+    }
+
+    return fee
+}
+
+// promoted reports whether a promotion waives the fee for an NFT at a given time
+func (p *FeePolicy) promoted(nft *NFT, now int64) bool {
+    for _, promotion := range p.Promotions {
+        if now < promotion.Start || now >= promotion.End {
+            continue
+        }
+        if promotion.NFTType != "" && promotion.NFTType != nft.Type {
+            continue
+        }
+        if promotion.CollectionID != "" && promotion.CollectionID != nft.CollectionID {
+            continue
+        }
+        return true
+    }
+
+    return false
+}
+
+// clone returns a deep copy of the policy
+func (p *FeePolicy) clone() *FeePolicy {
+    cloned := &FeePolicy{
+        DefaultRate: p.DefaultRate,
+        MinFee:      p.MinFee,
+        Promotions:  append([]FeePromotion(nil), p.Promotions...),
+    }
+
+    if p.Split != nil {
+        split := *p.Split
+        cloned.Split = &split
+    }
+
+    if p.TypeRates != nil {
+        cloned.TypeRates = make(map[string]float64)
+        for nftType, rate := range p.TypeRates {
+            cloned.TypeRates[nftType] = rate
+        }
+    }
+    if p.CollectionRates != nil {
+        cloned.CollectionRates = make(map[string]float64)
+        for collectionID, rate := range p.CollectionRates {
+            cloned.CollectionRates[collectionID] = rate
+        }
+    }
+
+    return cloned
+}
+
+// GetFeePolicy returns a copy of the fee policy in force
+func (ns *NFTSystem) GetFeePolicy() *FeePolicy {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    return ns.FeePolicy.clone()
+}
+
+// decodeFeePolicy reads the policy carried by a fee policy transaction
+func decodeFeePolicy(tx core.Transaction) (*FeePolicy, error) {
+    value := txValue(tx, "policy")
+    if value == nil {
+        return nil, errors.New("fee policy is required")
+    }
+
+    data, err := json.Marshal(value)
+    if err != nil {
+        return nil, err
+    }
+
+    var policy FeePolicy
+    if err := json.Unmarshal(data, &policy); err != nil {
+        return nil, fmt.Errorf("invalid fee policy: %w", err)
+    }
+
+    return &policy, nil
+}
+
+// checkFeePolicyTransaction validates a fee policy transaction
+// The caller must hold the lock
+func (ns *NFTSystem) checkFeePolicyTransaction(tx core.Transaction) error {
+    if ns.MasterWalletAddress == "" || tx.Sender != ns.MasterWalletAddress {
+        return errors.New("only the master wallet can change the fee policy")
+    }
+    if err := core.VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+
+    policy, err := decodeFeePolicy(tx)
+    if err != nil {
+        return err
+    }
+
+    return policy.Validate()
+}
+
+// applyFeePolicyTransaction puts a checked fee policy in force
+// The caller must hold the lock
+func (ns *NFTSystem) applyFeePolicyTransaction(tx core.Transaction) {
+    policy, err := decodeFeePolicy(tx)
+    if err != nil {
+        return
+    }
+
+    ns.FeePolicy = policy
+    ns.persistFeePolicy()
+    ns.record(NFTEvent{Kind: EventKindFeePolicy, From: tx.Sender})
+}
+
+// persistFeePolicy writes the fee policy in force to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistFeePolicy() {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(ns.FeePolicy)
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(storeKeyFeePolicy, data))
+}
@@ -0,0 +1,169 @@
+package nft
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// TxTypeSetGameMetadata writes into one game's metadata namespace on an NFT
+// Data: nftId, game, metadata; keys set to null are removed; Sender must be one of the game's writers
+const TxTypeSetGameMetadata = "nft_set_game_metadata"
+
+// MaxGameMetadataSize is the largest a game's namespace on one NFT can grow, in canonically encoded bytes
+const MaxGameMetadataSize = 16 * 1024
+
+// GameNamespace restricts who may write a game's metadata on NFTs
+// An NFT's own metadata belongs to the game that minted it; other games extend it in their namespace
+type GameNamespace struct {
+    Game    string          `json:"game"`
+    Writers map[string]bool `json:"writers"` // Addresses allowed to write; an empty set allows no one
+}
+
+// GetGameWriters returns the addresses allowed to write a game's namespace, and false if the game is not registered
+func (ns *NFTSystem) GetGameWriters(game string) ([]string, bool) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    namespace, exists := ns.GameNamespaces[game]
+    if !exists {
+        return nil, false
+    }
+
+    writers := []string{}
+    for writer := range namespace.Writers {
+        writers = append(writers, writer)
+    }
+    sort.Strings(writers)
+
+    return writers, true
+}
+
+// SetGameMetadata merges metadata into a game's namespace on an NFT, removing keys set to nil
+func (ns *NFTSystem) SetGameMetadata(id string, game string, writer string, metadata map[string]interface{}) error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return errors.New("NFT not found")
+    }
+
+    if err := ns.checkGameMetadata(nft, game, writer, metadata); err != nil {
+        return err
+    }
+
+    ns.writeGameMetadata(nft, game, writer, metadata)
+    ns.persistNFT(nft)
+
+    return nil
+}
+
+// GetGameMetadata returns a game's namespace on an NFT, empty if the game has written nothing
+func (ns *NFTSystem) GetGameMetadata(id string, game string) (map[string]interface{}, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return nil, errors.New("NFT not found")
+    }
+
+    metadata := map[string]interface{}{}
+    for key, value := range nft.GameMetadata[game] {
+        metadata[key] = value
+    }
+
+    return metadata, nil
+}
+
+// checkGameMetadata reports whether a writer may merge metadata into a game's namespace on an NFT
+// The caller must hold the lock
+func (ns *NFTSystem) checkGameMetadata(nft *NFT, game string, writer string, metadata map[string]interface{}) error {
+    namespace, exists := ns.GameNamespaces[game]
+    if !exists {
+        return fmt.Errorf("game %s is not registered", game)
+    }
+    if !namespace.Writers[writer] {
+        return errors.New("sender is not allowed to write this game's metadata")
+    }
+    if len(metadata) == 0 {
+        return errors.New("metadata is required")
+    }
+
+    encoded, err := core.CanonicalEncode(mergeGameMetadata(nft.GameMetadata[game], metadata))
+    if err != nil {
+        return fmt.Errorf("invalid metadata: %w", err)
+    }
+    if len(encoded) > MaxGameMetadataSize {
+        return fmt.Errorf("game metadata exceeds %d bytes", MaxGameMetadataSize)
+    }
+
+    return nil
+}
+
+// checkGameMetadataTransaction validates a game metadata transaction
+// The caller must hold the lock
+func (ns *NFTSystem) checkGameMetadataTransaction(tx core.Transaction) error {
+    nft, exists := ns.lookupNFT(txString(tx, "nftId"))
+    if !exists {
+        return errors.New("NFT not found")
+    }
+
+    if err := core.VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+
+    metadata, _ := txValue(tx, "metadata").(map[string]interface{})
+    return ns.checkGameMetadata(nft, txString(tx, "game"), tx.Sender, metadata)
+}
+
+// applyGameMetadataTransaction executes a checked game metadata transaction
+// The caller must hold the lock
+func (ns *NFTSystem) applyGameMetadataTransaction(tx core.Transaction) {
+    nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+    metadata, _ := txValue(tx, "metadata").(map[string]interface{})
+
+    ns.writeGameMetadata(nft, txString(tx, "game"), tx.Sender, metadata)
+    ns.persistNFT(nft)
+}
+
+// writeGameMetadata merges checked metadata into a game's namespace on an NFT
+// The caller must hold the lock
+func (ns *NFTSystem) writeGameMetadata(nft *NFT, game string, writer string, metadata map[string]interface{}) {
+    merged := mergeGameMetadata(nft.GameMetadata[game], metadata)
+
+    if len(merged) == 0 {
+        delete(nft.GameMetadata, game)
+    } else {
+        if nft.GameMetadata == nil {
+            nft.GameMetadata = make(map[string]map[string]interface{})
+        }
+        nft.GameMetadata[game] = merged
+    }
+    if len(nft.GameMetadata) == 0 {
+        nft.GameMetadata = nil
+    }
+
+    ns.record(NFTEvent{Kind: EventKindGameMetadata, NFTID: nft.ID, Collection: nft.CollectionID, From: writer, Game: game})
+}
+
+// mergeGameMetadata returns a namespace with updates applied, removing keys updated to nil
+func mergeGameMetadata(current map[string]interface{}, updates map[string]interface{}) map[string]interface{} {
+    merged := make(map[string]interface{}, len(current)+len(updates))
+    for key, value := range current {
+        merged[key] = value
+    }
+
+    for key, value := range updates {
+        if value == nil {
+            delete(merged, key)
+            continue
+        }
+        merged[key] = value
+    }
+
+    return merged
+}
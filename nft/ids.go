@@ -0,0 +1,174 @@
+package nft
+
+import (
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// nftIDPrefix starts every NFT ID
+const nftIDPrefix = "nft_"
+
+// nftIDHashLength is the number of hex digits of the content hash kept in an NFT ID (128 bits)
+const nftIDHashLength = 32
+
+// DeriveNFTID returns the ID of an NFT from what identifies its minting
+// mintTxHash is empty for NFTs created directly through the registry; sequence tells apart NFTs minted by the same transaction
+func DeriveNFTID(creator string, collectionID string, mintTxHash string, sequence int64) string {
+    hash := sha256.New()
+
+    // Length-prefix each field so different field boundaries can't produce the same input
+    for _, field := range []string{creator, collectionID, mintTxHash} {
+        var length [8]byte
+        binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+        hash.Write(length[:])
+        hash.Write([]byte(field))
+    }
+
+    var seq [8]byte
+    binary.BigEndian.PutUint64(seq[:], uint64(sequence))
+    hash.Write(seq[:])
+
+    return nftIDPrefix + hex.EncodeToString(hash.Sum(nil))[:nftIDHashLength]
+}
+
+// MintedNFTID returns the ID of the NFT created by a mint transaction
+func MintedNFTID(tx core.Transaction) string {
+    return DeriveNFTID(tx.Sender, txString(tx, "collectionId"), tx.ID, 0)
+}
+
+// IsDerivedNFTID reports whether an ID has the content-derived format
+func IsDerivedNFTID(id string) bool {
+    if len(id) != len(nftIDPrefix)+nftIDHashLength || id[:len(nftIDPrefix)] != nftIDPrefix {
+        return false
+    }
+
+    for _, c := range id[len(nftIDPrefix):] {
+        if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+            return false
+        }
+    }
+
+    return true
+}
+
+// legacyMintedNFTID returns the ID mint transactions produced before IDs were content-derived
+// Transactions in existing chains still name NFTs by it
+func legacyMintedNFTID(tx core.Transaction) string {
+    return nftIDPrefix + tx.ID[:16]
+}
+
+// MigrateLegacyIDs gives every NFT whose ID predates content-derived IDs a derived one
+// The old ID stays resolvable as an alias; returns a map of old to new IDs
+func (ns *NFTSystem) MigrateLegacyIDs() map[string]string {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    legacy := []string{}
+    for id := range ns.NFTs {
+        if !IsDerivedNFTID(id) {
+            legacy = append(legacy, id)
+        }
+    }
+
+    // Migrate in ID order so sequences are assigned the same way on every run
+    sort.Strings(legacy)
+
+    migrated := make(map[string]string)
+    for _, oldID := range legacy {
+        nft := ns.NFTs[oldID]
+
+        newID := DeriveNFTID(nft.Creator, nft.CollectionID, "", int64(ns.NextID))
+        ns.NextID++
+
+        ns.rekeyNFT(nft, newID)
+        migrated[oldID] = newID
+    }
+
+    if len(migrated) > 0 {
+        ns.reindexListings()
+        ns.reindexAttributes()
+        ns.persistHead()
+    }
+
+    return migrated
+}
+
+// rekeyNFT moves an NFT to a new ID, keeping its old one as an alias
+// The caller must hold the lock
+func (ns *NFTSystem) rekeyNFT(nft *NFT, newID string) {
+    oldID := nft.ID
+
+    delete(ns.NFTs, oldID)
+    ns.deleteNFT(oldID)
+
+    nft.ID = newID
+    nft.LegacyID = oldID
+    ns.NFTs[newID] = nft
+    ns.aliases[oldID] = newID
+
+    if _, exists := ns.rented[oldID]; exists {
+        delete(ns.rented, oldID)
+        ns.rented[newID] = nft
+    }
+
+    for _, auction := range ns.Auctions {
+        if auction.NFTID == oldID {
+            auction.NFTID = newID
+            ns.persistAuction(auction)
+        }
+    }
+
+    for _, order := range ns.Orders {
+        if order.NFTID == oldID {
+            order.NFTID = newID
+            ns.persistOrder(order)
+        }
+    }
+
+    if positions, exists := ns.salesByNFT[oldID]; exists {
+        for _, i := range positions {
+            ns.Sales[i].NFTID = newID
+            ns.persistSale(ns.Sales[i])
+        }
+        ns.salesByNFT[newID] = positions
+        delete(ns.salesByNFT, oldID)
+    }
+
+    ns.persistNFT(nft)
+}
+
+// resolveNFTID returns the current ID of an NFT named by its current or legacy ID
+// The caller must hold the lock
+func (ns *NFTSystem) resolveNFTID(id string) string {
+    if _, exists := ns.NFTs[id]; exists {
+        return id
+    }
+    if current, exists := ns.aliases[id]; exists {
+        return current
+    }
+
+    return id
+}
+
+// lookupNFT gets an NFT by its current or legacy ID
+// The caller must hold the lock
+func (ns *NFTSystem) lookupNFT(id string) (*NFT, bool) {
+    nft, exists := ns.NFTs[ns.resolveNFTID(id)]
+    return nft, exists
+}
+
+// reindexAliases rebuilds the legacy ID aliases from the registry
+// The caller must hold the lock
+func (ns *NFTSystem) reindexAliases() {
+    ns.aliases = make(map[string]string)
+
+    for _, nft := range ns.NFTs {
+        if nft.LegacyID != "" {
+            ns.aliases[nft.LegacyID] = nft.ID
+        }
+    }
+}
@@ -0,0 +1,209 @@
+package nft
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Match lock transaction types
+const (
+    TxTypeLock   = "nft_lock"   // Data: nftId, game, optional duration in seconds; Sender is one of the game's writers
+    TxTypeUnlock = "nft_unlock" // Data: nftId; Sender is one of the locking game's writers
+)
+
+// DefaultLockDuration is how long a match lock holds when no duration is given, in seconds
+const DefaultLockDuration = 2 * 60 * 60
+
+// MaxLockDuration is the longest a single lock can hold, so an NFT can't stay locked if its game node goes away
+const MaxLockDuration = 12 * 60 * 60
+
+// IsLocked reports whether an NFT is locked by a game at a given time
+// A lock lapses on its own once it times out
+func (nft *NFT) IsLocked(now int64) bool {
+    return nft.LockedBy != "" && nft.LockExpires > now
+}
+
+// LockNFT locks an NFT for a game's match, keeping it from being transferred, listed or sold until unlocked or timed out
+// Locking an NFT the game already holds extends the lock; a zero duration uses the default
+func (ns *NFTSystem) LockNFT(id string, game string, node string, duration int64) error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return errors.New("NFT not found")
+    }
+
+    now := token.ClockNow(ns.Clock)
+    if err := ns.checkLock(nft, game, node, duration, now); err != nil {
+        return err
+    }
+
+    ns.lock(nft, game, node, duration, now)
+    ns.persistNFT(nft)
+
+    return nil
+}
+
+// UnlockNFT releases a game's lock on an NFT
+func (ns *NFTSystem) UnlockNFT(id string, node string) error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return errors.New("NFT not found")
+    }
+
+    if err := ns.checkUnlock(nft, node, token.ClockNow(ns.Clock)); err != nil {
+        return err
+    }
+
+    ns.unlock(nft, node)
+    ns.persistNFT(nft)
+
+    return nil
+}
+
+// checkLocked reports an error if an NFT is locked by a game at a given time
+func checkLocked(nft *NFT, now int64) error {
+    if nft.IsLocked(now) {
+        return fmt.Errorf("NFT is locked by %s", nft.LockedBy)
+    }
+
+    return nil
+}
+
+// checkLock reports whether a game node may lock an NFT
+// The caller must hold the lock
+func (ns *NFTSystem) checkLock(nft *NFT, game string, node string, duration int64, now int64) error {
+    namespace, exists := ns.GameNamespaces[game]
+    if !exists {
+        return fmt.Errorf("game %s is not registered", game)
+    }
+    if !namespace.Writers[node] {
+        return errors.New("sender is not a node of this game")
+    }
+
+    if duration < 0 || duration > MaxLockDuration {
+        return fmt.Errorf("lock duration must be between 0 and %d seconds", MaxLockDuration)
+    }
+    if nft.IsLocked(now) && nft.LockedBy != game {
+        return fmt.Errorf("NFT is locked by %s", nft.LockedBy)
+    }
+    if nft.AuctionID != "" {
+        return errors.New("NFT is in an auction")
+    }
+
+    return nil
+}
+
+// checkUnlock reports whether a game node may release the lock on an NFT
+// The caller must hold the lock
+func (ns *NFTSystem) checkUnlock(nft *NFT, node string, now int64) error {
+    if !nft.IsLocked(now) {
+        return errors.New("NFT is not locked")
+    }
+
+    namespace, exists := ns.GameNamespaces[nft.LockedBy]
+    if !exists || !namespace.Writers[node] {
+        return errors.New("sender is not a node of the locking game")
+    }
+
+    return nil
+}
+
+// checkLockTransaction validates a lock transaction at a given block time
+// The caller must hold the lock
+func (ns *NFTSystem) checkLockTransaction(tx core.Transaction, now int64) error {
+    nft, exists := ns.lookupNFT(txString(tx, "nftId"))
+    if !exists {
+        return errors.New("NFT not found")
+    }
+
+    if err := core.VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+
+    if tx.Type == TxTypeUnlock {
+        return ns.checkUnlock(nft, tx.Sender, now)
+    }
+
+    return ns.checkLock(nft, txString(tx, "game"), tx.Sender, int64(txFloat(tx, "duration")), now)
+}
+
+// applyLockTransaction executes a checked lock transaction
+// The caller must hold the lock
+func (ns *NFTSystem) applyLockTransaction(tx core.Transaction, timestamp int64) {
+    nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+
+    switch tx.Type {
+    case TxTypeLock:
+        ns.lock(nft, txString(tx, "game"), tx.Sender, int64(txFloat(tx, "duration")), timestamp)
+    case TxTypeUnlock:
+        ns.unlock(nft, tx.Sender)
+    }
+
+    ns.persistNFT(nft)
+}
+
+// lock holds an NFT for a game until a timeout
+// The caller must hold the lock
+func (ns *NFTSystem) lock(nft *NFT, game string, node string, duration int64, now int64) {
+    if duration == 0 {
+        duration = DefaultLockDuration
+    }
+
+    nft.LockedBy = game
+    nft.LockExpires = now + duration
+    ns.locked[nft.ID] = nft
+
+    ns.record(NFTEvent{Kind: EventKindLock, NFTID: nft.ID, Collection: nft.CollectionID, From: node, To: nft.Owner, Game: game})
+}
+
+// unlock releases an NFT from its game
+// The caller must hold the lock
+func (ns *NFTSystem) unlock(nft *NFT, node string) {
+    ns.record(NFTEvent{Kind: EventKindUnlock, NFTID: nft.ID, Collection: nft.CollectionID, From: node, To: nft.Owner, Game: nft.LockedBy})
+
+    nft.LockedBy = ""
+    nft.LockExpires = 0
+    delete(ns.locked, nft.ID)
+}
+
+// releaseLocks clears locks that have timed out by a block time
+// NFTs are released in ID order so every node writes the same records
+// The caller must hold the lock
+func (ns *NFTSystem) releaseLocks(timestamp int64) {
+    released := []*NFT{}
+    for _, nft := range ns.locked {
+        if !nft.IsLocked(timestamp) {
+            released = append(released, nft)
+        }
+    }
+
+    sort.Slice(released, func(i, j int) bool {
+        return released[i].ID < released[j].ID
+    })
+
+    for _, nft := range released {
+        ns.unlock(nft, "")
+        ns.persistNFT(nft)
+    }
+}
+
+// reindexLocks rebuilds the set of locked NFTs from the registry
+// The caller must hold the lock
+func (ns *NFTSystem) reindexLocks() {
+    ns.locked = make(map[string]*NFT)
+
+    for _, nft := range ns.NFTs {
+        if nft.LockedBy != "" {
+            ns.locked[nft.ID] = nft
+        }
+    }
+}
@@ -0,0 +1,315 @@
+package nft
+
+import (
+    "encoding/base64"
+    "errors"
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Marketplace sort orders
+const (
+    SortNewest    = "newest"
+    SortOldest    = "oldest"
+    SortPriceAsc  = "price_asc"
+    SortPriceDesc = "price_desc"
+)
+
+// Marketplace page sizes
+const (
+    DefaultMarketLimit = 50
+    MaxMarketLimit     = 500
+)
+
+// MarketQuery filters and orders the NFTs listed for sale
+// Zero values leave a filter unset
+type MarketQuery struct {
+    Type         string
+    CollectionID string
+    Owner        string
+    MinPrice     token.Amount
+    MaxPrice     token.Amount
+    ListedAfter  int64  // Only listings made after this time
+    Sort         string // One of the Sort constants, newest first by default
+    Cursor       string // NextCursor of the previous page
+    Limit        int
+}
+
+// MarketPage is one page of marketplace listings
+type MarketPage struct {
+    NFTs       []*NFT `json:"nfts"`
+    NextCursor string `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+// listingIndex keeps listed NFTs sorted by price and by list time, ties broken by ID
+type listingIndex struct {
+    byPrice []*NFT
+    byTime  []*NFT
+}
+
+// newListingIndex creates an empty listing index
+func newListingIndex() *listingIndex {
+    return &listingIndex{
+        byPrice: []*NFT{},
+        byTime:  []*NFT{},
+    }
+}
+
+// priceKey is the sort key of the price ordering
+func priceKey(nft *NFT) float64 {
+    return nft.ListPrice.Float()
+}
+
+// timeKey is the sort key of the list time ordering
+func timeKey(nft *NFT) float64 {
+    return float64(nft.ListedAt)
+}
+
+// insert adds a listed NFT to both orderings
+func (li *listingIndex) insert(nft *NFT) {
+    li.byPrice = insertSorted(li.byPrice, nft, priceKey)
+    li.byTime = insertSorted(li.byTime, nft, timeKey)
+}
+
+// remove drops an NFT from both orderings; it must still carry the listing it was inserted with
+func (li *listingIndex) remove(nft *NFT) {
+    li.byPrice = removeSorted(li.byPrice, nft, priceKey)
+    li.byTime = removeSorted(li.byTime, nft, timeKey)
+}
+
+// insertSorted inserts an NFT at its position in a slice ordered by key and ID
+func insertSorted(index []*NFT, nft *NFT, key func(*NFT) float64) []*NFT {
+    i := searchAfter(index, key(nft), nft.ID, key)
+    index = append(index, nil)
+    copy(index[i+1:], index[i:])
+    index[i] = nft
+    return index
+}
+
+// removeSorted removes an NFT from a slice ordered by key and ID
+func removeSorted(index []*NFT, nft *NFT, key func(*NFT) float64) []*NFT {
+    i := sort.Search(len(index), func(i int) bool {
+        return !keyLess(key(index[i]), index[i].ID, key(nft), nft.ID)
+    })
+    if i < len(index) && index[i].ID == nft.ID {
+        index = append(index[:i], index[i+1:]...)
+    }
+    return index
+}
+
+// searchAfter returns the position of the first entry ordered after a key and ID
+func searchAfter(index []*NFT, k float64, id string, key func(*NFT) float64) int {
+    return sort.Search(len(index), func(i int) bool {
+        return keyLess(k, id, key(index[i]), index[i].ID)
+    })
+}
+
+// keyLess orders entries by key, then ID
+func keyLess(ka float64, ida string, kb float64, idb string) bool {
+    if ka != kb {
+        return ka < kb
+    }
+    return ida < idb
+}
+
+// list puts an NFT up for sale and indexes the listing
+// The caller must hold the lock
+func (ns *NFTSystem) list(nft *NFT, price token.Amount, timestamp int64) {
+    if nft.IsListed {
+        ns.listings.remove(nft)
+    }
+
+    nft.IsListed = true
+    nft.ListPrice = price
+    nft.ListedAt = timestamp
+    nft.ListExpires = 0
+
+    ns.listings.insert(nft)
+
+    ns.record(nftEvent(EventKindList, nft, nft.Owner, "", price))
+}
+
+// unlist takes an NFT off sale and drops it from the index
+// The caller must hold the lock
+func (ns *NFTSystem) unlist(nft *NFT) {
+    if !nft.IsListed {
+        return
+    }
+
+    ns.delist(nft)
+
+    ns.record(nftEvent(EventKindUnlist, nft, nft.Owner, "", 0))
+}
+
+// delist clears an NFT's listing without recording an event, for changes that imply it such as a sale
+// The caller must hold the lock
+func (ns *NFTSystem) delist(nft *NFT) {
+    if !nft.IsListed {
+        return
+    }
+
+    ns.listings.remove(nft)
+
+    nft.IsListed = false
+    nft.ListPrice = 0
+    nft.ListedAt = 0
+    nft.ListExpires = 0
+}
+
+// reindexListings rebuilds the listing index from the registry
+// The caller must hold the lock
+func (ns *NFTSystem) reindexListings() {
+    ns.listings = newListingIndex()
+
+    for _, nft := range ns.NFTs {
+        if nft.IsListed {
+            ns.listings.insert(nft)
+        }
+    }
+}
+
+// QueryMarket returns a page of listed NFTs matching a query
+// Listings that had expired by the last applied block are skipped
+func (ns *NFTSystem) QueryMarket(query MarketQuery) (MarketPage, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    limit := query.Limit
+    if limit <= 0 {
+        limit = DefaultMarketLimit
+    }
+    if limit > MaxMarketLimit {
+        limit = MaxMarketLimit
+    }
+
+    // Pick the ordering and the key range the query covers
+    lower, upper := math.Inf(-1), math.Inf(1)
+    var index []*NFT
+    var key func(*NFT) float64
+    descending := false
+
+    switch query.Sort {
+    case SortNewest, "", SortOldest:
+        index, key = ns.listings.byTime, timeKey
+        descending = query.Sort != SortOldest
+        if query.ListedAfter > 0 {
+            lower = float64(query.ListedAfter + 1)
+        }
+    case SortPriceAsc, SortPriceDesc:
+        index, key = ns.listings.byPrice, priceKey
+        descending = query.Sort == SortPriceDesc
+        if query.MinPrice > 0 {
+            lower = query.MinPrice.Float()
+        }
+        if query.MaxPrice > 0 {
+            upper = query.MaxPrice.Float()
+        }
+    default:
+        return MarketPage{}, errors.New("unknown sort order")
+    }
+
+    // Find the first entry of the page
+    var start int
+    if descending {
+        start = sort.Search(len(index), func(i int) bool { return key(index[i]) > upper }) - 1
+    } else {
+        start = sort.Search(len(index), func(i int) bool { return key(index[i]) >= lower })
+    }
+
+    if query.Cursor != "" {
+        cursorKey, cursorID, err := decodeMarketCursor(query.Cursor)
+        if err != nil {
+            return MarketPage{}, err
+        }
+
+        if descending {
+            before := sort.Search(len(index), func(i int) bool {
+                return !keyLess(key(index[i]), index[i].ID, cursorKey, cursorID)
+            }) - 1
+            if before < start {
+                start = before
+            }
+        } else if after := searchAfter(index, cursorKey, cursorID, key); after > start {
+            start = after
+        }
+    }
+
+    step := 1
+    if descending {
+        step = -1
+    }
+
+    // Collect one extra match to learn whether another page follows
+    page := MarketPage{NFTs: []*NFT{}}
+    for i := start; i >= 0 && i < len(index); i += step {
+        nft := index[i]
+        if key(nft) < lower || key(nft) > upper {
+            break
+        }
+        if !query.matches(nft) || !nft.listingLive(ns.BlockTime) {
+            continue
+        }
+
+        if len(page.NFTs) == limit {
+            last := page.NFTs[limit-1]
+            page.NextCursor = encodeMarketCursor(key(last), last.ID)
+            break
+        }
+        page.NFTs = append(page.NFTs, nft)
+    }
+
+    return page, nil
+}
+
+// matches applies every filter of the query to a listing
+func (query MarketQuery) matches(nft *NFT) bool {
+    if query.Type != "" && nft.Type != query.Type {
+        return false
+    }
+    if query.CollectionID != "" && nft.CollectionID != query.CollectionID {
+        return false
+    }
+    if query.Owner != "" && nft.Owner != query.Owner {
+        return false
+    }
+    if query.MinPrice > 0 && nft.ListPrice < query.MinPrice {
+        return false
+    }
+    if query.MaxPrice > 0 && nft.ListPrice > query.MaxPrice {
+        return false
+    }
+    if query.ListedAfter > 0 && nft.ListedAt <= query.ListedAfter {
+        return false
+    }
+
+    return true
+}
+
+// encodeMarketCursor encodes the position of the last entry of a page
+func encodeMarketCursor(key float64, id string) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatFloat(key, 'g', -1, 64) + ":" + id))
+}
+
+// decodeMarketCursor decodes a cursor made by encodeMarketCursor
+func decodeMarketCursor(cursor string) (float64, string, error) {
+    data, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return 0, "", errors.New("invalid cursor")
+    }
+
+    keyText, id, found := strings.Cut(string(data), ":")
+    if !found {
+        return 0, "", errors.New("invalid cursor")
+    }
+
+    key, err := strconv.ParseFloat(keyText, 64)
+    if err != nil {
+        return 0, "", errors.New("invalid cursor")
+    }
+
+    return key, id, nil
+}
@@ -0,0 +1,153 @@
+package nft
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// DefaultIPFSGateway is the HTTP gateway ipfs:// metadata URIs are fetched through
+const DefaultIPFSGateway = "https://ipfs.io/ipfs/"
+
+// MaxMetadataDocumentSize is the largest off-chain metadata document the fetcher accepts, in bytes
+const MaxMetadataDocumentSize = 1 << 20
+
+// ErrMetadataHashMismatch is returned when a fetched document doesn't match the hash committed on chain
+var ErrMetadataHashMismatch = errors.New("metadata document does not match its committed hash")
+
+// HashMetadataDocument returns the hex SHA-256 hash committed for an off-chain metadata document
+func HashMetadataDocument(document []byte) string {
+    sum := sha256.Sum256(document)
+    return hex.EncodeToString(sum[:])
+}
+
+// MetadataFetcher retrieves off-chain NFT metadata and verifies it against the committed hash
+// Verified documents are cached by hash, since a hash always identifies the same content
+type MetadataFetcher struct {
+    Client      *http.Client
+    IPFSGateway string
+
+    // Cache of verified documents by hash
+    cache map[string]map[string]interface{}
+
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// NewMetadataFetcher creates a fetcher that resolves ipfs:// URIs through a gateway
+func NewMetadataFetcher(ipfsGateway string) *MetadataFetcher {
+    if ipfsGateway == "" {
+        ipfsGateway = DefaultIPFSGateway
+    }
+
+    return &MetadataFetcher{
+        Client:      &http.Client{Timeout: 10 * time.Second},
+        IPFSGateway: ipfsGateway,
+        cache:       make(map[string]map[string]interface{}),
+    }
+}
+
+// Fetch retrieves the document at a URI and returns it only if it matches the hash
+func (f *MetadataFetcher) Fetch(ctx context.Context, uri string, hash string) (map[string]interface{}, error) {
+    f.mutex.Lock()
+    cached, exists := f.cache[hash]
+    f.mutex.Unlock()
+    if exists {
+        return cached, nil
+    }
+
+    url, err := f.resolve(uri)
+    if err != nil {
+        return nil, err
+    }
+
+    request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    response, err := f.Client.Do(request)
+    if err != nil {
+        return nil, err
+    }
+    defer response.Body.Close()
+
+    if response.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("metadata fetch returned status %d", response.StatusCode)
+    }
+
+    // Read one byte past the limit to detect oversized documents
+    document, err := io.ReadAll(io.LimitReader(response.Body, MaxMetadataDocumentSize+1))
+    if err != nil {
+        return nil, err
+    }
+    if len(document) > MaxMetadataDocumentSize {
+        return nil, errors.New("metadata document is too large")
+    }
+
+    // Verify before parsing so callers never see unverified content
+    if HashMetadataDocument(document) != hash {
+        return nil, ErrMetadataHashMismatch
+    }
+
+    var metadata map[string]interface{}
+    if err := json.Unmarshal(document, &metadata); err != nil {
+        return nil, fmt.Errorf("invalid metadata document: %w", err)
+    }
+
+    f.mutex.Lock()
+    f.cache[hash] = metadata
+    f.mutex.Unlock()
+
+    return metadata, nil
+}
+
+// FetchNFTMetadata returns an NFT's metadata, fetching and verifying it if it is stored off chain
+func (f *MetadataFetcher) FetchNFTMetadata(ctx context.Context, nft *NFT) (map[string]interface{}, error) {
+    if nft.MetadataURI == "" {
+        return nft.Metadata, nil
+    }
+
+    return f.Fetch(ctx, nft.MetadataURI, nft.MetadataHash)
+}
+
+// resolve maps a metadata URI to the HTTP URL it is fetched from
+func (f *MetadataFetcher) resolve(uri string) (string, error) {
+    switch {
+    case strings.HasPrefix(uri, "ipfs://"):
+        return strings.TrimSuffix(f.IPFSGateway, "/") + "/" + strings.TrimPrefix(uri, "ipfs://"), nil
+    case strings.HasPrefix(uri, "https://"), strings.HasPrefix(uri, "http://"):
+        return uri, nil
+    }
+
+    return "", errors.New("metadata URI must use http, https or ipfs")
+}
+
+// checkMetadataURI validates the off-chain metadata reference of a mint transaction, if any
+func checkMetadataURI(uri string, hash string) error {
+    if uri == "" {
+        if hash != "" {
+            return errors.New("metadata hash given without a metadata URI")
+        }
+        return nil
+    }
+
+    if !strings.HasPrefix(uri, "ipfs://") && !strings.HasPrefix(uri, "https://") && !strings.HasPrefix(uri, "http://") {
+        return errors.New("metadata URI must use http, https or ipfs")
+    }
+
+    decoded, err := hex.DecodeString(hash)
+    if err != nil || len(decoded) != sha256.Size || hash != strings.ToLower(hash) {
+        return errors.New("metadata hash must be a lowercase hex SHA-256 hash")
+    }
+
+    return nil
+}
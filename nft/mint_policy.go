@@ -0,0 +1,58 @@
+package nft
+
+import (
+    "errors"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// MintPolicy restricts who may mint an NFT type
+type MintPolicy struct {
+    NFTType string          `json:"nftType"`
+    Minters map[string]bool `json:"minters"` // Addresses allowed to mint; an empty set allows no one
+}
+
+// GetMinters returns the addresses allowed to mint an NFT type, and false if anyone may mint it
+func (ns *NFTSystem) GetMinters(nftType string) ([]string, bool) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    policy, exists := ns.MintPolicies[nftType]
+    if !exists {
+        return nil, false
+    }
+
+    minters := []string{}
+    for minter := range policy.Minters {
+        minters = append(minters, minter)
+    }
+    sort.Strings(minters)
+
+    return minters, true
+}
+
+// checkMinter reports whether an address may mint an NFT type
+// The caller must hold the lock
+func (ns *NFTSystem) checkMinter(nftType string, minter string) error {
+    policy, exists := ns.MintPolicies[nftType]
+    if !exists {
+        return nil
+    }
+
+    if !policy.Minters[minter] {
+        return errors.New("sender is not allowed to mint this NFT type")
+    }
+
+    return nil
+}
+
+// checkMintAuthorization checks that a mint transaction is signed by its sender and that the sender may mint
+// The caller must hold the lock
+func (ns *NFTSystem) checkMintAuthorization(tx core.Transaction) error {
+    if err := core.VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+
+    return ns.checkMinter(txString(tx, "nftType"), tx.Sender)
+}
@@ -0,0 +1,387 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Order book transaction types
+// Asks are listings: nft_list takes an optional expiresAt and fills the best crossing bid
+const (
+    TxTypeBidPlace  = "nft_bid_place"  // Data: nftId or collectionId, price, optional expiresAt; the price is escrowed until the bid fills, is cancelled or expires
+    TxTypeBidCancel = "nft_bid_cancel" // Data: orderId
+)
+
+// Order statuses
+const (
+    OrderStatusOpen      = "open"
+    OrderStatusFilled    = "filled"
+    OrderStatusCancelled = "cancelled"
+    OrderStatusExpired   = "expired"
+)
+
+// storeKeyOrderPrefix prefixes persisted order records
+const storeKeyOrderPrefix = "order/"
+
+// Order is a standing bid for one NFT or for any NFT of a collection
+// Crossing orders fill at the price of the order that was on the book first
+type Order struct {
+    ID           string       `json:"id"`
+    NFTID        string       `json:"nftId,omitempty"`        // Set for a bid on one NFT
+    CollectionID string       `json:"collectionId,omitempty"` // Set for a bid on any NFT of a collection
+    Bidder       string       `json:"bidder"`
+    Price        token.Amount `json:"price"` // Escrowed while the order is open
+    CreatedAt    int64        `json:"createdAt"`
+    ExpiresAt    int64        `json:"expiresAt,omitempty"` // 0 means the order stands until filled or cancelled
+    Status       string       `json:"status"`
+    FilledNFTID  string       `json:"filledNftId,omitempty"`
+    FilledPrice  token.Amount `json:"filledPrice,omitempty"`
+    ClosedAt     int64        `json:"closedAt,omitempty"`
+}
+
+// OrderBook is the standing asks and bids for an NFT or a collection
+type OrderBook struct {
+    Asks []*NFT   `json:"asks"` // Lowest price first
+    Bids []*Order `json:"bids"` // Highest price first
+}
+
+// OrderID returns the ID of the order created by a bid transaction
+func OrderID(tx core.Transaction) string {
+    return "order_" + tx.ID[:16]
+}
+
+// isLive reports whether an order can still fill at a given time
+func (o *Order) isLive(now int64) bool {
+    return o.Status == OrderStatusOpen && (o.ExpiresAt == 0 || now < o.ExpiresAt)
+}
+
+// listingLive reports whether an NFT's listing can still be bought at a given time
+func (nft *NFT) listingLive(now int64) bool {
+    return nft.IsListed && (nft.ListExpires == 0 || now < nft.ListExpires)
+}
+
+// GetOrder gets an order by ID
+func (ns *NFTSystem) GetOrder(id string) (*Order, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    order, exists := ns.Orders[id]
+    if !exists {
+        return nil, errors.New("order not found")
+    }
+
+    return order, nil
+}
+
+// GetOrderBook returns the live asks and bids for an NFT, or for a collection if nftID is empty
+// An NFT's book includes the bids on its collection
+func (ns *NFTSystem) GetOrderBook(nftID string, collectionID string) OrderBook {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    book := OrderBook{Asks: []*NFT{}, Bids: []*Order{}}
+
+    if nftID != "" {
+        nft, exists := ns.lookupNFT(nftID)
+        if !exists {
+            return book
+        }
+        nftID = nft.ID
+        collectionID = nft.CollectionID
+    }
+
+    for _, nft := range ns.listings.byPrice {
+        if !nft.listingLive(ns.BlockTime) {
+            continue
+        }
+        if (nftID != "" && nft.ID == nftID) || (nftID == "" && nft.CollectionID == collectionID) {
+            book.Asks = append(book.Asks, nft)
+        }
+    }
+
+    for _, order := range ns.openBids {
+        if !order.isLive(ns.BlockTime) {
+            continue
+        }
+        if (nftID != "" && order.NFTID == nftID) || (collectionID != "" && order.CollectionID == collectionID) {
+            book.Bids = append(book.Bids, order)
+        }
+    }
+    sortBids(book.Bids)
+
+    return book
+}
+
+// sortBids orders bids best first: highest price, then oldest, then by ID
+func sortBids(bids []*Order) {
+    sort.Slice(bids, func(i, j int) bool {
+        if bids[i].Price != bids[j].Price {
+            return bids[i].Price > bids[j].Price
+        }
+        if bids[i].CreatedAt != bids[j].CreatedAt {
+            return bids[i].CreatedAt < bids[j].CreatedAt
+        }
+        return bids[i].ID < bids[j].ID
+    })
+}
+
+// checkOrderTransaction validates an order book transaction at a given block time
+// The caller must hold the lock
+func (ns *NFTSystem) checkOrderTransaction(tx core.Transaction, now int64) error {
+    switch tx.Type {
+    case TxTypeBidPlace:
+        if _, exists := ns.Orders[OrderID(tx)]; exists {
+            return errors.New("order already exists")
+        }
+
+        nftID := txString(tx, "nftId")
+        collectionID := txString(tx, "collectionId")
+        if (nftID == "") == (collectionID == "") {
+            return errors.New("bid must name either an NFT or a collection")
+        }
+
+        if nftID != "" {
+            nft, exists := ns.lookupNFT(nftID)
+            if !exists {
+                return errors.New("NFT not found")
+            }
+            if nft.Owner == tx.Sender {
+                return errors.New("owner cannot bid on their own NFT")
+            }
+        } else if _, exists := ns.Collections[collectionID]; !exists {
+            return errors.New("collection not found")
+        }
+
+        if txAmount(tx, "price") <= 0 {
+            return errors.New("bid price must be positive")
+        }
+        if expiresAt := int64(txFloat(tx, "expiresAt")); expiresAt != 0 && expiresAt <= now {
+            return errors.New("bid expiry must be in the future")
+        }
+
+    case TxTypeBidCancel:
+        order, exists := ns.Orders[txString(tx, "orderId")]
+        if !exists {
+            return errors.New("order not found")
+        }
+        if order.Bidder != tx.Sender {
+            return errors.New("sender did not place this order")
+        }
+        if order.Status != OrderStatusOpen {
+            return errors.New("order is not open")
+        }
+    }
+
+    return nil
+}
+
+// applyOrderTransaction executes a checked order book transaction, moving escrowed funds in state
+// The caller must hold the lock
+func (ns *NFTSystem) applyOrderTransaction(tx core.Transaction, timestamp int64, state *core.State) {
+    switch tx.Type {
+    case TxTypeBidPlace:
+        price := txAmount(tx, "price")
+
+        // A bidder who can't cover the escrow places nothing
+        if !collect(state, tx.Sender, price) {
+            return
+        }
+
+        order := &Order{
+            ID:           OrderID(tx),
+            CollectionID: txString(tx, "collectionId"),
+            Bidder:       tx.Sender,
+            Price:        price,
+            CreatedAt:    timestamp,
+            ExpiresAt:    int64(txFloat(tx, "expiresAt")),
+            Status:       OrderStatusOpen,
+        }
+        if nft, exists := ns.lookupNFT(txString(tx, "nftId")); exists {
+            order.NFTID = nft.ID
+        }
+
+        ns.Orders[order.ID] = order
+        ns.openBids[order.ID] = order
+        ns.record(NFTEvent{Kind: EventKindBidPlace, NFTID: order.NFTID, Collection: order.CollectionID, From: order.Bidder, Price: order.Price})
+
+        // The bid takes the cheapest crossing ask, if any
+        if ask := ns.bestAsk(order, timestamp); ask != nil {
+            ns.fill(order, ask, ask.ListPrice, timestamp, state)
+            return
+        }
+
+        ns.persistOrder(order)
+
+    case TxTypeBidCancel:
+        order := ns.Orders[txString(tx, "orderId")]
+        ns.closeOrder(order, OrderStatusCancelled, timestamp, state)
+        ns.record(NFTEvent{Kind: EventKindBidCancel, NFTID: order.NFTID, Collection: order.CollectionID, From: order.Bidder, Price: order.Price})
+    }
+}
+
+// matchListing fills the best bid crossing a newly listed NFT, if any
+// The caller must hold the lock
+func (ns *NFTSystem) matchListing(nft *NFT, timestamp int64, state *core.State) {
+    if !nft.listingLive(timestamp) || nft.IsRented(timestamp) || nft.IsLocked(timestamp) {
+        return
+    }
+
+    // The listing takes the best crossing bid, at the bid's price
+    if bid := ns.bestBid(nft, timestamp); bid != nil {
+        ns.fill(bid, nft, bid.Price, timestamp, state)
+    }
+}
+
+// bestAsk returns the cheapest live listing a bid crosses, or nil
+// The caller must hold the lock
+func (ns *NFTSystem) bestAsk(order *Order, now int64) *NFT {
+    // The price index is ascending, so the first match is the best
+    for _, nft := range ns.listings.byPrice {
+        if nft.ListPrice > order.Price {
+            break
+        }
+        if order.NFTID != "" && nft.ID != order.NFTID {
+            continue
+        }
+        if order.CollectionID != "" && nft.CollectionID != order.CollectionID {
+            continue
+        }
+        if nft.listingLive(now) && !nft.IsRented(now) && !nft.IsLocked(now) && nft.Owner != order.Bidder {
+            return nft
+        }
+    }
+
+    return nil
+}
+
+// bestBid returns the best live bid crossing a listed NFT, or nil
+// The caller must hold the lock
+func (ns *NFTSystem) bestBid(nft *NFT, now int64) *Order {
+    candidates := []*Order{}
+    for _, order := range ns.openBids {
+        if !order.isLive(now) || order.Price < nft.ListPrice || order.Bidder == nft.Owner {
+            continue
+        }
+        if order.NFTID == nft.ID || (order.CollectionID != "" && order.CollectionID == nft.CollectionID) {
+            candidates = append(candidates, order)
+        }
+    }
+
+    if len(candidates) == 0 {
+        return nil
+    }
+
+    sortBids(candidates)
+    return candidates[0]
+}
+
+// fill settles a bid against a listed NFT at a price no higher than the bid, refunding the rest of the escrow
+// The caller must hold the lock
+func (ns *NFTSystem) fill(order *Order, nft *NFT, price token.Amount, timestamp int64, state *core.State) {
+    release(state, order.Bidder, order.Price-price)
+
+    ns.settleSale(nft, order.Bidder, price, timestamp, state)
+    ns.persistNFT(nft)
+
+    order.Status = OrderStatusFilled
+    order.FilledNFTID = nft.ID
+    order.FilledPrice = price
+    order.ClosedAt = timestamp
+    delete(ns.openBids, order.ID)
+
+    ns.persistOrder(order)
+}
+
+// closeOrder closes an unfilled order and refunds its escrow
+// The caller must hold the lock
+func (ns *NFTSystem) closeOrder(order *Order, status string, timestamp int64, state *core.State) {
+    release(state, order.Bidder, order.Price)
+
+    order.Status = status
+    order.ClosedAt = timestamp
+    delete(ns.openBids, order.ID)
+
+    ns.persistOrder(order)
+}
+
+// expireOrders closes bids and listings whose expiry has passed by a block time
+// Orders are expired in ID order so refunds are applied identically on every node
+// The caller must hold the lock
+func (ns *NFTSystem) expireOrders(timestamp int64, state *core.State) {
+    expiredBids := []*Order{}
+    for _, order := range ns.openBids {
+        if order.ExpiresAt != 0 && timestamp >= order.ExpiresAt {
+            expiredBids = append(expiredBids, order)
+        }
+    }
+    sort.Slice(expiredBids, func(i, j int) bool {
+        return expiredBids[i].ID < expiredBids[j].ID
+    })
+
+    for _, order := range expiredBids {
+        ns.closeOrder(order, OrderStatusExpired, timestamp, state)
+        ns.record(NFTEvent{Kind: EventKindBidExpire, NFTID: order.NFTID, Collection: order.CollectionID, From: order.Bidder, Price: order.Price})
+    }
+
+    expiredAsks := []*NFT{}
+    for _, nft := range ns.listings.byPrice {
+        if nft.ListExpires != 0 && timestamp >= nft.ListExpires {
+            expiredAsks = append(expiredAsks, nft)
+        }
+    }
+    sort.Slice(expiredAsks, func(i, j int) bool {
+        return expiredAsks[i].ID < expiredAsks[j].ID
+    })
+
+    for _, nft := range expiredAsks {
+        ns.expireListing(nft, timestamp)
+        ns.persistNFT(nft)
+    }
+}
+
+// expireListing delists an NFT whose listing has run out by a time, recording the expiry
+// It reports whether the listing expired
+// The caller must hold the lock
+func (ns *NFTSystem) expireListing(nft *NFT, now int64) bool {
+    if !nft.IsListed || nft.listingLive(now) {
+        return false
+    }
+
+    price := nft.ListPrice
+    ns.delist(nft)
+    ns.record(nftEvent(EventKindListExpire, nft, nft.Owner, "", price))
+
+    return true
+}
+
+// reindexOrders rebuilds the set of open bids from the order records
+// The caller must hold the lock
+func (ns *NFTSystem) reindexOrders() {
+    ns.openBids = make(map[string]*Order)
+
+    for _, order := range ns.Orders {
+        if order.Status == OrderStatusOpen {
+            ns.openBids[order.ID] = order
+        }
+    }
+}
+
+// persistOrder writes an order record to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistOrder(order *Order) {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(order)
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(storeKeyOrderPrefix+order.ID, data))
+}
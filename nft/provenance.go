@@ -0,0 +1,216 @@
+package nft
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+)
+
+// TransactionSource looks up confirmed transactions, such as the blockchain
+type TransactionSource interface {
+    GetTransactionByID(id string) (core.Transaction, int64, error)
+}
+
+// transferAuth is the transaction that authorizes the transfers being executed
+type transferAuth struct {
+    txHash    string
+    signature string
+    height    int64
+}
+
+// ProvenanceHop is one entry of an NFT's transfer log with the result of checking it on chain
+type ProvenanceHop struct {
+    TransferRecord
+    Verified bool   `json:"verified"`
+    Reason   string `json:"reason,omitempty"` // Why the hop could not be verified
+}
+
+// Provenance is an NFT's ownership history checked hop by hop against the chain
+type Provenance struct {
+    NFTID    string          `json:"nftId"`
+    Verified bool            `json:"verified"` // Whether every hop was verified
+    Hops     []ProvenanceHop `json:"hops"`
+}
+
+// authorizationOf returns the authorization a confirmed transaction gives the transfers it causes
+func authorizationOf(tx core.Transaction, height int64) transferAuth {
+    return transferAuth{txHash: tx.ID, signature: tx.Signature, height: height}
+}
+
+// record links a transfer record to the authorizing transaction, if any
+func (a transferAuth) record(record TransferRecord) TransferRecord {
+    if a.txHash == "" {
+        return record
+    }
+
+    record.TxHash = a.txHash
+    record.Signature = a.signature
+    record.BlockHeight = a.height
+    return record
+}
+
+// VerifyProvenance walks an NFT's transfer log, checking that each hop continues from the previous owner
+// and was authorized by a correctly signed transaction confirmed at the recorded height
+func (ns *NFTSystem) VerifyProvenance(nftID string) (*Provenance, error) {
+    ns.mutex.Lock()
+    nft, exists := ns.lookupNFT(nftID)
+    if !exists {
+        ns.mutex.Unlock()
+        return nil, errors.New("NFT not found")
+    }
+    source := ns.Transactions
+    log := append([]TransferRecord{}, nft.TransferLog...)
+    ns.mutex.Unlock()
+
+    if source == nil {
+        return nil, errors.New("no chain to verify provenance against")
+    }
+
+    // Look transactions up without the lock, since the chain holds its own lock while applying blocks to the registry
+    type confirmed struct {
+        tx     core.Transaction
+        height int64
+        err    error
+    }
+    transactions := make([]confirmed, len(log))
+    for i, record := range log {
+        if record.TxHash != "" {
+            tx, height, err := source.GetTransactionByID(record.TxHash)
+            transactions[i] = confirmed{tx: tx, height: height, err: err}
+        }
+    }
+
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    provenance := &Provenance{
+        NFTID:    nft.ID,
+        Verified: true,
+        Hops:     make([]ProvenanceHop, 0, len(log)),
+    }
+
+    previousOwner := "0x0"
+    for i, record := range log {
+        hop := ProvenanceHop{TransferRecord: record, Verified: true}
+
+        var err error
+        switch {
+        case record.FromAddress != previousOwner:
+            err = errors.New("transfer does not continue from the previous owner")
+        case record.TxHash == "":
+            err = errors.New("transfer is not linked to a transaction")
+        case transactions[i].err != nil:
+            err = fmt.Errorf("transaction %s not found on chain", record.TxHash)
+        default:
+            err = ns.checkHop(nft, record, transactions[i].tx, transactions[i].height)
+        }
+
+        if err != nil {
+            hop.Verified = false
+            hop.Reason = err.Error()
+            provenance.Verified = false
+        }
+
+        provenance.Hops = append(provenance.Hops, hop)
+        previousOwner = record.ToAddress
+    }
+
+    return provenance, nil
+}
+
+// checkHop checks a transfer record against the confirmed transaction it links to
+// The caller must hold the lock
+func (ns *NFTSystem) checkHop(nft *NFT, record TransferRecord, tx core.Transaction, height int64) error {
+    if height != record.BlockHeight {
+        return fmt.Errorf("transaction is in block %d, not block %d", height, record.BlockHeight)
+    }
+
+    if tx.Signature != record.Signature {
+        return errors.New("recorded signature does not match the transaction")
+    }
+
+    if err := core.VerifyTransactionSignature(tx); err != nil {
+        return err
+    }
+
+    if !ns.transactionConcerns(tx, nft) {
+        return errors.New("transaction does not concern this NFT")
+    }
+
+    // The signer must be the party that gives up or takes the NFT
+    switch tx.Type {
+    case TxTypeMint:
+        owner := tx.Recipient
+        if owner == "" {
+            owner = tx.Sender
+        }
+        if owner != record.ToAddress {
+            return errors.New("mint transaction names a different owner")
+        }
+
+    case TxTypeTransfer, TxTypeTransferBatch:
+        if tx.Recipient != record.ToAddress {
+            return errors.New("transfer transaction names a different recipient")
+        }
+
+    case TxTypeBuy, TxTypeAuctionBid, TxTypeBidPlace, TxTypeVoucherBuy, TxTypeDropBuy:
+        if tx.Sender != record.ToAddress {
+            return errors.New("transaction was not signed by the buyer")
+        }
+
+    case TxTypeList:
+        if tx.Sender != record.FromAddress {
+            return errors.New("transaction was not signed by the seller")
+        }
+
+    default:
+        return fmt.Errorf("%s transactions do not transfer NFTs", tx.Type)
+    }
+
+    return nil
+}
+
+// transactionConcerns reports whether a transaction acts on an NFT
+// The caller must hold the lock
+func (ns *NFTSystem) transactionConcerns(tx core.Transaction, nft *NFT) bool {
+    switch tx.Type {
+    case TxTypeMint:
+        return MintedNFTID(tx) == nft.ID
+
+    case TxTypeVoucherBuy:
+        voucher, err := decodeVoucher(tx)
+        return err == nil && voucher.NFTID() == nft.ID
+
+    case TxTypeDropBuy:
+        drop, exists := ns.Drops[txString(tx, "dropId")]
+        if !exists {
+            return false
+        }
+        for unit := int64(0); unit < dropQuantity(tx); unit++ {
+            if DropNFTID(drop, tx, unit) == nft.ID {
+                return true
+            }
+        }
+        return false
+
+    case TxTypeTransferBatch:
+        for _, id := range batchIDs(tx) {
+            if ns.resolveNFTID(id) == nft.ID {
+                return true
+            }
+        }
+        return false
+
+    case TxTypeAuctionBid:
+        auction, exists := ns.Auctions[txString(tx, "auctionId")]
+        return exists && auction.NFTID == nft.ID
+
+    case TxTypeBidPlace:
+        if collectionID := txString(tx, "collectionId"); collectionID != "" {
+            return collectionID == nft.CollectionID
+        }
+    }
+
+    return ns.resolveNFTID(txString(tx, "nftId")) == nft.ID
+}
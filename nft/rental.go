@@ -0,0 +1,204 @@
+package nft
+
+import (
+    "errors"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Rental transaction types
+const (
+    TxTypeRentList   = "nft_rent_list"   // Data: nftId, ratePerDay, maxDuration
+    TxTypeRentUnlist = "nft_rent_unlist" // Data: nftId
+    TxTypeRent       = "nft_rent"        // Data: nftId, duration; the renter pays ratePerDay for each day
+)
+
+// SecondsPerDay converts rental durations to days
+const SecondsPerDay = 24 * 60 * 60
+
+// RentalTerms are the conditions an owner offers an NFT for rent on
+type RentalTerms struct {
+    RatePerDay  token.Amount `json:"ratePerDay"`
+    MaxDuration int64        `json:"maxDuration"` // Longest rental, in seconds
+}
+
+// RentalCost returns what renting an NFT for a duration costs
+func (terms *RentalTerms) RentalCost(duration int64) token.Amount {
+    return terms.RatePerDay.MulRate(float64(duration) / SecondsPerDay)
+}
+
+// IsRented reports whether an NFT's usage right is held by a renter at a given time
+func (nft *NFT) IsRented(now int64) bool {
+    return nft.User != "" && nft.UserExpires > now
+}
+
+// IsUsableBy reports whether an address may use an NFT in game
+// An owner loses the right while the NFT is rented out and gets it back at expiry
+func (ns *NFTSystem) IsUsableBy(id string, address string) bool {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return false
+    }
+
+    return ns.usableBy(nft, address)
+}
+
+// GetNFTsUsableBy returns the NFTs an address may use: those it owns and hasn't rented out, and those it rents
+func (ns *NFTSystem) GetNFTsUsableBy(address string) []*NFT {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    usableNFTs := []*NFT{}
+
+    for _, nft := range ns.NFTs {
+        if ns.usableBy(nft, address) {
+            usableNFTs = append(usableNFTs, nft)
+        }
+    }
+
+    return usableNFTs
+}
+
+// usableBy reports whether an address may use an NFT as of the last applied block
+// The caller must hold the lock
+func (ns *NFTSystem) usableBy(nft *NFT, address string) bool {
+    if nft.IsRented(ns.BlockTime) {
+        return nft.User == address
+    }
+
+    return nft.Owner == address
+}
+
+// checkRentalTransaction validates a rental transaction at a given block time
+// The caller must hold the lock
+func (ns *NFTSystem) checkRentalTransaction(tx core.Transaction, now int64) error {
+    nft, exists := ns.lookupNFT(txString(tx, "nftId"))
+    if !exists {
+        return errors.New("NFT not found")
+    }
+
+    switch tx.Type {
+    case TxTypeRentList:
+        if nft.Owner != tx.Sender {
+            return errors.New("sender is not the owner of this NFT")
+        }
+        if nft.AuctionID != "" {
+            return errors.New("NFT is in an auction")
+        }
+        if txAmount(tx, "ratePerDay") <= 0 {
+            return errors.New("rental rate must be positive")
+        }
+        if txFloat(tx, "maxDuration") <= 0 {
+            return errors.New("rental duration must be positive")
+        }
+
+    case TxTypeRentUnlist:
+        if nft.Owner != tx.Sender {
+            return errors.New("sender is not the owner of this NFT")
+        }
+        if nft.Rental == nil {
+            return errors.New("NFT is not listed for rent")
+        }
+
+    case TxTypeRent:
+        if nft.Rental == nil {
+            return errors.New("NFT is not listed for rent")
+        }
+        if nft.Owner == tx.Sender {
+            return errors.New("owner cannot rent their own NFT")
+        }
+        if nft.IsRented(now) {
+            return errors.New("NFT is already rented")
+        }
+
+        duration := int64(txFloat(tx, "duration"))
+        if duration <= 0 || duration > nft.Rental.MaxDuration {
+            return errors.New("rental duration is out of range")
+        }
+    }
+
+    return nil
+}
+
+// applyRentalTransaction executes a checked rental transaction, charging the renter in state
+// The caller must hold the lock
+func (ns *NFTSystem) applyRentalTransaction(tx core.Transaction, header core.BlockHeader, state *core.State) {
+    nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+
+    switch tx.Type {
+    case TxTypeRentList:
+        nft.Rental = &RentalTerms{
+            RatePerDay:  txAmount(tx, "ratePerDay"),
+            MaxDuration: int64(txFloat(tx, "maxDuration")),
+        }
+        ns.record(nftEvent(EventKindRentList, nft, nft.Owner, "", nft.Rental.RatePerDay))
+
+    case TxTypeRentUnlist:
+        nft.Rental = nil
+        ns.record(nftEvent(EventKindRentUnlist, nft, nft.Owner, "", 0))
+
+    case TxTypeRent:
+        duration := int64(txFloat(tx, "duration"))
+        cost := nft.Rental.RentalCost(duration)
+
+        // A renter who can't pay gets nothing
+        if !collect(state, tx.Sender, cost) {
+            return
+        }
+
+        // Pay the owner, less the marketplace fee
+        split := ns.primarySplit(nft, cost, header.Timestamp)
+        ns.payFee(split.FeeShares, state)
+        release(state, nft.Owner, split.SellerAmount)
+
+        nft.User = tx.Sender
+        nft.UserExpires = header.Timestamp + duration
+        ns.rented[nft.ID] = nft
+        ns.record(nftEvent(EventKindRent, nft, nft.Owner, tx.Sender, cost))
+    }
+
+    ns.persistNFT(nft)
+}
+
+// expireRentals returns usage rights whose rental has ended to their owners
+// Rentals are expired in ID order so every node writes the same records
+// The caller must hold the lock
+func (ns *NFTSystem) expireRentals(timestamp int64) {
+    expired := []*NFT{}
+    for _, nft := range ns.rented {
+        if !nft.IsRented(timestamp) {
+            expired = append(expired, nft)
+        }
+    }
+
+    sort.Slice(expired, func(i, j int) bool {
+        return expired[i].ID < expired[j].ID
+    })
+
+    for _, nft := range expired {
+        ns.record(nftEvent(EventKindRentEnd, nft, nft.User, nft.Owner, 0))
+
+        nft.User = ""
+        nft.UserExpires = 0
+        delete(ns.rented, nft.ID)
+
+        ns.persistNFT(nft)
+    }
+}
+
+// reindexRentals rebuilds the set of rented NFTs from the registry
+// The caller must hold the lock
+func (ns *NFTSystem) reindexRentals() {
+    ns.rented = make(map[string]*NFT)
+
+    for _, nft := range ns.NFTs {
+        if nft.User != "" {
+            ns.rented[nft.ID] = nft
+        }
+    }
+}
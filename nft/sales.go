@@ -0,0 +1,296 @@
+package nft
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// storeKeySalePrefix prefixes persisted sale records, which are keyed by sequence number
+const storeKeySalePrefix = "sale/"
+
+// TradePageSize is the number of trades returned per page of an address's trade history
+const TradePageSize = 50
+
+// Trade sides, from the point of view of the address whose trades are queried
+const (
+    TradeSideBuy  = "buy"
+    TradeSideSell = "sell"
+)
+
+// Analytics windows, in seconds
+const (
+    Window24h = 24 * 60 * 60
+    Window7d  = 7 * Window24h
+)
+
+// SaleRecord is one completed sale
+type SaleRecord struct {
+    Sequence   int64        `json:"sequence"`
+    NFTID      string       `json:"nftId"`
+    NFTType    string       `json:"nftType"`
+    Collection string       `json:"collection,omitempty"`
+    Seller     string       `json:"seller"`
+    Buyer      string       `json:"buyer"`
+    Price      token.Amount `json:"price"`
+    Timestamp  int64        `json:"timestamp"`
+    Split      *SaleSplit   `json:"split,omitempty"` // How the price was divided between fee, royalty and seller
+}
+
+// SaleTotals aggregates the sales of an NFT type
+type SaleTotals struct {
+    Sales        int64        `json:"sales"`
+    Volume       token.Amount `json:"volume"`
+    AveragePrice token.Amount `json:"averagePrice"`
+}
+
+// Trade is a sale seen from one of its parties
+type Trade struct {
+    Sequence     int64        `json:"sequence"`
+    NFTID        string       `json:"nftId"`
+    NFTType      string       `json:"nftType"`
+    Collection   string       `json:"collection,omitempty"`
+    Side         string       `json:"side"` // One of the TradeSide constants
+    Counterparty string       `json:"counterparty"`
+    Price        token.Amount `json:"price"`
+    Timestamp    int64        `json:"timestamp"`
+}
+
+// TradePage is one page of an address's trades, newest first
+type TradePage struct {
+    Trades     []Trade `json:"trades"`
+    NextCursor string  `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+// MarketStats summarizes the market for a collection, an NFT type, or everything
+type MarketStats struct {
+    FloorPrice   token.Amount `json:"floorPrice"` // Lowest current list price, 0 when nothing is listed
+    Listed       int          `json:"listed"`
+    Volume24h    token.Amount `json:"volume24h"`
+    Sales24h     int64        `json:"sales24h"`
+    Volume7d     token.Amount `json:"volume7d"`
+    Sales7d      int64        `json:"sales7d"`
+    TotalVolume  token.Amount `json:"totalVolume"`
+    TotalSales   int64        `json:"totalSales"`
+    AveragePrice token.Amount `json:"averagePrice"`
+}
+
+// GetSaleHistory returns the sales of an NFT, oldest first
+func (ns *NFTSystem) GetSaleHistory(nftID string) []SaleRecord {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    history := []SaleRecord{}
+    for _, i := range ns.salesByNFT[ns.resolveNFTID(nftID)] {
+        history = append(history, ns.Sales[i])
+    }
+
+    return history
+}
+
+// GetTradesByAddress returns a page of the purchases and sales an address took part in, newest first
+// cursor is the NextCursor of the previous page, or empty for the first page
+func (ns *NFTSystem) GetTradesByAddress(address string, cursor string) (*TradePage, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    positions := ns.salesByAddress[address]
+
+    // Positions are ascending, so walk back from the newest sale or from just before the cursor
+    end := len(positions)
+    if cursor != "" {
+        sequence, err := decodeTradeCursor(cursor)
+        if err != nil {
+            return nil, err
+        }
+        end = sort.Search(len(positions), func(i int) bool {
+            return ns.Sales[positions[i]].Sequence >= sequence
+        })
+    }
+
+    page := &TradePage{Trades: []Trade{}}
+    for i := end - 1; i >= 0 && len(page.Trades) < TradePageSize; i-- {
+        sale := ns.Sales[positions[i]]
+
+        trade := Trade{
+            Sequence:     sale.Sequence,
+            NFTID:        sale.NFTID,
+            NFTType:      sale.NFTType,
+            Collection:   sale.Collection,
+            Side:         TradeSideBuy,
+            Counterparty: sale.Seller,
+            Price:        sale.Price,
+            Timestamp:    sale.Timestamp,
+        }
+        if sale.Seller == address {
+            trade.Side = TradeSideSell
+            trade.Counterparty = sale.Buyer
+        }
+
+        page.Trades = append(page.Trades, trade)
+
+        if i > 0 && len(page.Trades) == TradePageSize {
+            page.NextCursor = encodeTradeCursor(sale.Sequence)
+        }
+    }
+
+    return page, nil
+}
+
+// GetAveragePriceByType returns sale totals for every NFT type that has sold
+func (ns *NFTSystem) GetAveragePriceByType() map[string]SaleTotals {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    totals := make(map[string]SaleTotals)
+    for nftType, typeTotals := range ns.saleTotals {
+        totals[nftType] = *typeTotals
+    }
+
+    return totals
+}
+
+// GetMarketStats summarizes listings and sales, optionally limited to a collection and an NFT type
+// Windows are measured back from the last applied block so every node reports the same figures
+func (ns *NFTSystem) GetMarketStats(collectionID string, nftType string) MarketStats {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    stats := MarketStats{}
+    matches := func(nftCollection string, typ string) bool {
+        return (collectionID == "" || nftCollection == collectionID) && (nftType == "" || typ == nftType)
+    }
+
+    // The price index is ascending, so the first match is the floor
+    for _, nft := range ns.listings.byPrice {
+        if matches(nft.CollectionID, nft.Type) && nft.listingLive(ns.BlockTime) {
+            if stats.Listed == 0 {
+                stats.FloorPrice = nft.ListPrice
+            }
+            stats.Listed++
+        }
+    }
+
+    for _, sale := range ns.Sales {
+        if !matches(sale.Collection, sale.NFTType) {
+            continue
+        }
+
+        stats.TotalVolume += sale.Price
+        stats.TotalSales++
+
+        age := ns.BlockTime - sale.Timestamp
+        if age < Window7d {
+            stats.Volume7d += sale.Price
+            stats.Sales7d++
+        }
+        if age < Window24h {
+            stats.Volume24h += sale.Price
+            stats.Sales24h++
+        }
+    }
+
+    if stats.TotalSales > 0 {
+        stats.AveragePrice = stats.TotalVolume / token.Amount(stats.TotalSales)
+    }
+
+    return stats
+}
+
+// recordSaleHistory appends a completed sale to the history and its indices
+// The caller must hold the lock
+func (ns *NFTSystem) recordSaleHistory(nft *NFT, seller string, buyer string, price token.Amount, timestamp int64) {
+    sale := SaleRecord{
+        Sequence:   int64(len(ns.Sales)),
+        NFTID:      nft.ID,
+        NFTType:    nft.Type,
+        Collection: nft.CollectionID,
+        Seller:     seller,
+        Buyer:      buyer,
+        Price:      price,
+        Timestamp:  timestamp,
+        Split:      ns.settling,
+    }
+
+    ns.indexSale(sale)
+    ns.persistSale(sale)
+}
+
+// indexSale adds a sale to the history, the per-NFT and per-address indices and the per-type totals
+// The caller must hold the lock
+func (ns *NFTSystem) indexSale(sale SaleRecord) {
+    ns.Sales = append(ns.Sales, sale)
+    position := len(ns.Sales) - 1
+    ns.salesByNFT[sale.NFTID] = append(ns.salesByNFT[sale.NFTID], position)
+    ns.salesByAddress[sale.Seller] = append(ns.salesByAddress[sale.Seller], position)
+    if sale.Buyer != sale.Seller {
+        ns.salesByAddress[sale.Buyer] = append(ns.salesByAddress[sale.Buyer], position)
+    }
+
+    totals, exists := ns.saleTotals[sale.NFTType]
+    if !exists {
+        totals = &SaleTotals{}
+        ns.saleTotals[sale.NFTType] = totals
+    }
+    totals.Sales++
+    totals.Volume += sale.Price
+    totals.AveragePrice = totals.Volume / token.Amount(totals.Sales)
+}
+
+// reindexSales rebuilds the sale indices from a history in sequence order
+// The caller must hold the lock
+func (ns *NFTSystem) reindexSales(history []SaleRecord) {
+    sort.Slice(history, func(i, j int) bool {
+        return history[i].Sequence < history[j].Sequence
+    })
+
+    ns.Sales = []SaleRecord{}
+    ns.salesByNFT = make(map[string][]int)
+    ns.salesByAddress = make(map[string][]int)
+    ns.saleTotals = make(map[string]*SaleTotals)
+
+    for _, sale := range history {
+        ns.indexSale(sale)
+    }
+}
+
+// persistSale writes a sale record to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistSale(sale SaleRecord) {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(sale)
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(fmt.Sprintf("%s%012d", storeKeySalePrefix, sale.Sequence), data))
+}
+
+// encodeTradeCursor encodes the sequence number of the last trade of a page
+func encodeTradeCursor(sequence int64) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(sequence, 10)))
+}
+
+// decodeTradeCursor decodes a cursor made by encodeTradeCursor
+func decodeTradeCursor(cursor string) (int64, error) {
+    data, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return 0, errors.New("invalid cursor")
+    }
+
+    sequence, err := strconv.ParseInt(string(data), 10, 64)
+    if err != nil {
+        return 0, errors.New("invalid cursor")
+    }
+
+    return sequence, nil
+}
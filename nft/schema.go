@@ -0,0 +1,251 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// Metadata field types
+const (
+    FieldString = "string"
+    FieldNumber = "number"
+    FieldBool   = "bool"
+    FieldObject = "object"
+)
+
+// FieldSpec describes one metadata attribute of an NFT type
+type FieldSpec struct {
+    Name     string   `json:"name"`
+    Type     string   `json:"type"`
+    Required bool     `json:"required"`
+    Allowed  []string `json:"allowed,omitempty"` // Permitted values of a string field, any value if empty
+    Indexed  bool     `json:"indexed,omitempty"` // Searchable through an attribute index
+
+    // Attributes of an object field; an object may hold only these
+    Fields []FieldSpec `json:"fields,omitempty"`
+}
+
+// MetadataSchema lists the metadata attributes an NFT type must carry
+type MetadataSchema struct {
+    NFTType    string      `json:"nftType"`
+    Fields     []FieldSpec `json:"fields"`
+    AllowExtra bool        `json:"allowExtra"` // Accept attributes the schema doesn't list
+}
+
+// MetadataError reports why an NFT's metadata doesn't match its type's schema
+type MetadataError struct {
+    NFTType string
+    Field   string
+    Reason  string
+}
+
+// Error implements error
+func (e *MetadataError) Error() string {
+    return fmt.Sprintf("invalid %s metadata: field %q %s", e.NFTType, e.Field, e.Reason)
+}
+
+// DefaultSchemas returns the schemas for the NFT types used by the game
+func DefaultSchemas() []*MetadataSchema {
+    return []*MetadataSchema{
+        {
+            NFTType: NFTTypeChampionSkin,
+            Fields: []FieldSpec{
+                {Name: "champion", Type: FieldString, Required: true, Indexed: true},
+                {Name: "skinName", Type: FieldString, Required: true},
+                {Name: "rarity", Type: FieldString, Required: true, Allowed: rarityNames(), Indexed: true},
+                {Name: "imageUrl", Type: FieldString},
+                {Name: "animated", Type: FieldBool},
+                {Name: "attributes", Type: FieldObject, Fields: SkinAttributeFields()},
+            },
+            AllowExtra: true,
+        },
+        {
+            NFTType: NFTTypeYieldGenerator,
+            Fields: []FieldSpec{
+                {Name: "name", Type: FieldString, Required: true},
+                {Name: "tier", Type: FieldNumber, Required: true, Indexed: true},
+                {Name: "imageUrl", Type: FieldString},
+            },
+            AllowExtra: true,
+        },
+    }
+}
+
+// RegisterSchema sets the metadata schema for an NFT type, replacing any existing one, and rebuilds the attribute indices
+// Every node must register the same schemas, since mints are validated against them
+func (ns *NFTSystem) RegisterSchema(schema *MetadataSchema) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    ns.Schemas[schema.NFTType] = schema
+    ns.reindexAttributes()
+}
+
+// GetSchema gets the metadata schema registered for an NFT type
+func (ns *NFTSystem) GetSchema(nftType string) (*MetadataSchema, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    schema, exists := ns.Schemas[nftType]
+    if !exists {
+        return nil, errors.New("no schema registered for this NFT type")
+    }
+
+    return schema, nil
+}
+
+// ValidateMetadata checks metadata against the schema of an NFT type
+// Types without a registered schema accept any metadata
+func (ns *NFTSystem) ValidateMetadata(nftType string, metadata map[string]interface{}) error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    return ns.validateMetadata(nftType, metadata)
+}
+
+// validateMetadata checks metadata against the schema of an NFT type
+// The caller must hold the lock
+func (ns *NFTSystem) validateMetadata(nftType string, metadata map[string]interface{}) error {
+    schema, exists := ns.Schemas[nftType]
+    if !exists {
+        return nil
+    }
+
+    return schema.Validate(metadata)
+}
+
+// Validate checks metadata against the schema, reporting the first problem found
+// Fields are checked in schema order, then unknown fields in name order, so errors are stable
+func (s *MetadataSchema) Validate(metadata map[string]interface{}) error {
+    return s.validateFields("", s.Fields, metadata, s.AllowExtra)
+}
+
+// validateFields checks a metadata object against field specs
+// Nested fields are reported by their dotted path
+func (s *MetadataSchema) validateFields(prefix string, fields []FieldSpec, metadata map[string]interface{}, allowExtra bool) error {
+    known := make(map[string]bool)
+
+    for _, field := range fields {
+        known[field.Name] = true
+        path := prefix + field.Name
+
+        value, exists := metadata[field.Name]
+        if !exists || value == nil {
+            if field.Required {
+                return &MetadataError{NFTType: s.NFTType, Field: path, Reason: "is required"}
+            }
+            continue
+        }
+
+        if !hasFieldType(value, field.Type) {
+            return &MetadataError{NFTType: s.NFTType, Field: path, Reason: "must be a " + field.Type}
+        }
+
+        if len(field.Allowed) > 0 && !containsString(field.Allowed, value.(string)) {
+            return &MetadataError{NFTType: s.NFTType, Field: path, Reason: fmt.Sprintf("must be one of %v", field.Allowed)}
+        }
+
+        if field.Type == FieldObject {
+            if err := s.validateFields(path+".", field.Fields, value.(map[string]interface{}), false); err != nil {
+                return err
+            }
+        }
+    }
+
+    if allowExtra {
+        return nil
+    }
+
+    extra := []string{}
+    for name := range metadata {
+        if !known[name] {
+            extra = append(extra, name)
+        }
+    }
+    if len(extra) > 0 {
+        sort.Strings(extra)
+        return &MetadataError{NFTType: s.NFTType, Field: prefix + extra[0], Reason: "is not part of the schema"}
+    }
+
+    return nil
+}
+
+// MetadataString returns a string metadata attribute
+func (nft *NFT) MetadataString(key string) (string, error) {
+    value, ok := nft.Metadata[key].(string)
+    if !ok {
+        return "", fmt.Errorf("metadata field %q is not a string", key)
+    }
+
+    return value, nil
+}
+
+// MetadataNumber returns a numeric metadata attribute
+func (nft *NFT) MetadataNumber(key string) (float64, error) {
+    value, ok := toFloat(nft.Metadata[key])
+    if !ok {
+        return 0, fmt.Errorf("metadata field %q is not a number", key)
+    }
+
+    return value, nil
+}
+
+// MetadataBool returns a boolean metadata attribute
+func (nft *NFT) MetadataBool(key string) (bool, error) {
+    value, ok := nft.Metadata[key].(bool)
+    if !ok {
+        return false, fmt.Errorf("metadata field %q is not a bool", key)
+    }
+
+    return value, nil
+}
+
+// hasFieldType reports whether a decoded metadata value has a schema field type
+func hasFieldType(value interface{}, fieldType string) bool {
+    switch fieldType {
+    case FieldString:
+        _, ok := value.(string)
+        return ok
+    case FieldNumber:
+        _, ok := toFloat(value)
+        return ok
+    case FieldBool:
+        _, ok := value.(bool)
+        return ok
+    case FieldObject:
+        _, ok := value.(map[string]interface{})
+        return ok
+    }
+
+    return false
+}
+
+// toFloat converts the numeric kinds found in decoded or locally built metadata
+func toFloat(value interface{}) (float64, bool) {
+    switch number := value.(type) {
+    case float64:
+        return number, true
+    case int:
+        return float64(number), true
+    case int64:
+        return float64(number), true
+    case json.Number:
+        parsed, err := number.Float64()
+        return parsed, err == nil
+    }
+
+    return 0, false
+}
+
+// containsString reports whether a slice holds a value
+func containsString(values []string, value string) bool {
+    for _, candidate := range values {
+        if candidate == value {
+            return true
+        }
+    }
+
+    return false
+}
@@ -0,0 +1,256 @@
+package nft
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+)
+
+// AttributeFilter matches NFTs by one indexed metadata attribute
+// Equals matches string, number and bool attributes by their text form; Min and Max bound number attributes, inclusive
+type AttributeFilter struct {
+    Field  string   `json:"field"`
+    Equals string   `json:"equals,omitempty"`
+    Min    *float64 `json:"min,omitempty"`
+    Max    *float64 `json:"max,omitempty"`
+}
+
+// SearchQuery selects NFTs by type, attributes, owner, collection and listing
+// Attribute filters need a type, since attributes are indexed per type schema
+type SearchQuery struct {
+    Type       string            `json:"type,omitempty"`
+    Owner      string            `json:"owner,omitempty"`
+    Collection string            `json:"collection,omitempty"`
+    Listed     *bool             `json:"listed,omitempty"`
+    Attributes []AttributeFilter `json:"attributes,omitempty"`
+}
+
+// attributeIndex indexes one metadata attribute of an NFT type
+type attributeIndex struct {
+    fieldType string
+    byValue   map[string]map[string]*NFT // Text form of the value to the NFTs holding it
+    byNumber  []*NFT                     // Number attributes only, ordered by value and ID
+}
+
+// Search returns the NFTs matching a query, ordered by ID
+// Attribute filters are answered from the indices declared by the type's schema
+func (ns *NFTSystem) Search(query SearchQuery) ([]*NFT, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    if len(query.Attributes) > 0 && query.Type == "" {
+        return nil, errors.New("attribute filters require a type")
+    }
+
+    // Start from the smallest attribute match, or every NFT if there are no attribute filters
+    var candidates map[string]*NFT
+    for _, filter := range query.Attributes {
+        index, exists := ns.attributes[query.Type][filter.Field]
+        if !exists {
+            return nil, fmt.Errorf("attribute %q is not indexed for type %q", filter.Field, query.Type)
+        }
+
+        matched, err := index.lookup(filter)
+        if err != nil {
+            return nil, err
+        }
+
+        candidates = intersectNFTs(candidates, matched)
+    }
+    if candidates == nil {
+        candidates = ns.NFTs
+    }
+
+    results := []*NFT{}
+    for _, nft := range candidates {
+        if query.Type != "" && nft.Type != query.Type {
+            continue
+        }
+        if query.Owner != "" && nft.Owner != query.Owner {
+            continue
+        }
+        if query.Collection != "" && nft.CollectionID != query.Collection {
+            continue
+        }
+        if query.Listed != nil && nft.IsListed != *query.Listed {
+            continue
+        }
+        results = append(results, nft)
+    }
+
+    sort.Slice(results, func(i, j int) bool {
+        return results[i].ID < results[j].ID
+    })
+
+    return results, nil
+}
+
+// lookup returns the NFTs an index holds that match a filter
+func (index *attributeIndex) lookup(filter AttributeFilter) (map[string]*NFT, error) {
+    matched := make(map[string]*NFT)
+
+    if filter.Equals != "" {
+        key := filter.Equals
+        if index.fieldType == FieldNumber {
+            number, err := strconv.ParseFloat(filter.Equals, 64)
+            if err != nil {
+                return nil, fmt.Errorf("attribute %q must be compared to a number", filter.Field)
+            }
+            key = numberKey(number)
+        }
+
+        for id, nft := range index.byValue[key] {
+            matched[id] = nft
+        }
+
+        if filter.Min == nil && filter.Max == nil {
+            return matched, nil
+        }
+    }
+
+    if filter.Min == nil && filter.Max == nil {
+        return nil, fmt.Errorf("attribute %q filter needs a value or a range", filter.Field)
+    }
+    if index.fieldType != FieldNumber {
+        return nil, fmt.Errorf("attribute %q is not a number and can't be filtered by range", filter.Field)
+    }
+
+    // Scan the ordered values from the lower bound up to the upper bound
+    start := 0
+    if filter.Min != nil {
+        start = sort.Search(len(index.byNumber), func(i int) bool {
+            value, _ := index.byNumber[i].MetadataNumber(filter.Field)
+            return value >= *filter.Min
+        })
+    }
+
+    ranged := make(map[string]*NFT)
+    for _, nft := range index.byNumber[start:] {
+        value, _ := nft.MetadataNumber(filter.Field)
+        if filter.Max != nil && value > *filter.Max {
+            break
+        }
+        ranged[nft.ID] = nft
+    }
+
+    if filter.Equals != "" {
+        return intersectNFTs(matched, ranged), nil
+    }
+    return ranged, nil
+}
+
+// intersectNFTs returns the NFTs in both sets; a nil set stands for every NFT
+func intersectNFTs(a map[string]*NFT, b map[string]*NFT) map[string]*NFT {
+    if a == nil {
+        return b
+    }
+
+    intersection := make(map[string]*NFT)
+    for id, nft := range a {
+        if _, exists := b[id]; exists {
+            intersection[id] = nft
+        }
+    }
+
+    return intersection
+}
+
+// attributeKey returns the text form a metadata value is indexed by, and false if it doesn't have the field type
+func attributeKey(value interface{}, fieldType string) (string, bool) {
+    if value == nil || !hasFieldType(value, fieldType) {
+        return "", false
+    }
+
+    switch fieldType {
+    case FieldString:
+        return value.(string), true
+    case FieldNumber:
+        number, _ := toFloat(value)
+        return numberKey(number), true
+    case FieldBool:
+        return strconv.FormatBool(value.(bool)), true
+    }
+
+    return "", false
+}
+
+// numberKey returns the text form of a number attribute
+func numberKey(number float64) string {
+    return strconv.FormatFloat(number, 'g', -1, 64)
+}
+
+// indexAttributes adds an NFT to the indices of its type's indexed attributes
+// The caller must hold the lock
+func (ns *NFTSystem) indexAttributes(nft *NFT) {
+    for field, index := range ns.attributes[nft.Type] {
+        key, ok := attributeKey(nft.Metadata[field], index.fieldType)
+        if !ok {
+            continue
+        }
+
+        if index.byValue[key] == nil {
+            index.byValue[key] = make(map[string]*NFT)
+        }
+        index.byValue[key][nft.ID] = nft
+
+        if index.fieldType == FieldNumber {
+            index.byNumber = insertSorted(index.byNumber, nft, numberAttribute(field))
+        }
+    }
+}
+
+// unindexAttributes drops an NFT from the indices of its type's indexed attributes
+// The caller must hold the lock
+func (ns *NFTSystem) unindexAttributes(nft *NFT) {
+    for field, index := range ns.attributes[nft.Type] {
+        key, ok := attributeKey(nft.Metadata[field], index.fieldType)
+        if !ok {
+            continue
+        }
+
+        delete(index.byValue[key], nft.ID)
+        if len(index.byValue[key]) == 0 {
+            delete(index.byValue, key)
+        }
+
+        if index.fieldType == FieldNumber {
+            index.byNumber = removeSorted(index.byNumber, nft, numberAttribute(field))
+        }
+    }
+}
+
+// numberAttribute returns the sort key of a number attribute index
+func numberAttribute(field string) func(*NFT) float64 {
+    return func(nft *NFT) float64 {
+        value, _ := nft.MetadataNumber(field)
+        return value
+    }
+}
+
+// reindexAttributes rebuilds the attribute indices from the schemas and the registry
+// The caller must hold the lock
+func (ns *NFTSystem) reindexAttributes() {
+    ns.attributes = make(map[string]map[string]*attributeIndex)
+
+    for nftType, schema := range ns.Schemas {
+        for _, field := range schema.Fields {
+            if !field.Indexed || field.Type == FieldObject {
+                continue
+            }
+
+            if ns.attributes[nftType] == nil {
+                ns.attributes[nftType] = make(map[string]*attributeIndex)
+            }
+            ns.attributes[nftType][field.Name] = &attributeIndex{
+                fieldType: field.Type,
+                byValue:   make(map[string]map[string]*NFT),
+                byNumber:  []*NFT{},
+            }
+        }
+    }
+
+    for _, nft := range ns.NFTs {
+        ns.indexAttributes(nft)
+    }
+}
@@ -0,0 +1,123 @@
+package nft
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+)
+
+// SettingsName is the name the registry's settings are kept under in genesis, the state and proposals
+const SettingsName = "nft"
+
+// Settings are the registry's settings genesis sets and governance replaces
+type Settings struct {
+    // Map of NFT type to the addresses allowed to mint it, besides the master wallet's yield generator and badge policies
+    MintPolicies map[string][]string `json:"mintPolicies,omitempty"`
+    
+    // Map of game to the addresses allowed to write its metadata namespace on NFTs
+    GameWriters map[string][]string `json:"gameWriters,omitempty"`
+    
+    // Map of NFT type to the boost a staked NFT of the type gives, overriding the defaults; a zero boost stops the type being staked
+    StakeBoosts map[string]float64 `json:"stakeBoosts,omitempty"`
+    
+    // Marketplace fee policy put in force, the default policy if nil
+    FeePolicy *FeePolicy `json:"feePolicy,omitempty"`
+}
+
+// SettingsName returns the name the registry's settings are kept under
+func (ns *NFTSystem) SettingsName() string {
+    return SettingsName
+}
+
+// ValidateSettings reports whether settings hold a usable fee policy and stake boosts
+func (ns *NFTSystem) ValidateSettings(settings json.RawMessage) error {
+    _, err := decodeSettings(settings)
+    return err
+}
+
+// ApplySettings replaces the mint policies, game namespaces, stake boosts and fee policy with the defaults changed by the settings
+// The fee policy stays in force until a fee policy transaction or later settings replace it
+func (ns *NFTSystem) ApplySettings(settings json.RawMessage) {
+    decoded, err := decodeSettings(settings)
+    if err != nil {
+        return
+    }
+
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    ns.MintPolicies = defaultMintPolicies(ns.MasterWalletAddress)
+    for nftType, minters := range decoded.MintPolicies {
+        policy := &MintPolicy{NFTType: nftType, Minters: make(map[string]bool)}
+        for _, minter := range minters {
+            policy.Minters[minter] = true
+        }
+        ns.MintPolicies[nftType] = policy
+    }
+
+    ns.GameNamespaces = make(map[string]*GameNamespace)
+    for game, writers := range decoded.GameWriters {
+        namespace := &GameNamespace{Game: game, Writers: make(map[string]bool)}
+        for _, writer := range writers {
+            namespace.Writers[writer] = true
+        }
+        ns.GameNamespaces[game] = namespace
+    }
+
+    ns.StakeBoosts = DefaultStakeBoosts()
+    for nftType, boost := range decoded.StakeBoosts {
+        if boost == 0 {
+            delete(ns.StakeBoosts, nftType)
+            continue
+        }
+        ns.StakeBoosts[nftType] = boost
+    }
+
+    ns.FeePolicy = DefaultFeePolicy(DefaultFeeRate)
+    if decoded.FeePolicy != nil {
+        ns.FeePolicy = decoded.FeePolicy
+    }
+    ns.persistFeePolicy()
+}
+
+// decodeSettings reads and validates the registry's settings
+func decodeSettings(settings json.RawMessage) (Settings, error) {
+    var decoded Settings
+    if err := json.Unmarshal(settings, &decoded); err != nil {
+        return Settings{}, fmt.Errorf("invalid NFT settings: %w", err)
+    }
+
+    for nftType, boost := range decoded.StakeBoosts {
+        if nftType == "" {
+            return Settings{}, errors.New("stake boost NFT type is required")
+        }
+        if boost < 0 || boost > MaxStakeBoost {
+            return Settings{}, fmt.Errorf("stake boost for %q is out of range", nftType)
+        }
+    }
+    if decoded.FeePolicy != nil {
+        if err := decoded.FeePolicy.Validate(); err != nil {
+            return Settings{}, err
+        }
+    }
+
+    return decoded, nil
+}
+
+// defaultMintPolicies returns the mint policies a registry starts with: only the master wallet mints yield generators
+// and validator badges, since they pay out tokens or weigh consensus
+func defaultMintPolicies(masterWalletAddress string) map[string]*MintPolicy {
+    policies := make(map[string]*MintPolicy)
+    if masterWalletAddress == "" {
+        return policies
+    }
+
+    for _, nftType := range []string{NFTTypeYieldGenerator, NFTTypeValidatorBadge} {
+        policies[nftType] = &MintPolicy{
+            NFTType: nftType,
+            Minters: map[string]bool{masterWalletAddress: true},
+        }
+    }
+
+    return policies
+}
@@ -0,0 +1,121 @@
+package nft
+
+import (
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// EscrowAddress is the account holding what buyers and bidders pay until a sale settles or the payment is refunded
+const EscrowAddress = "nft_escrow"
+
+// SaleSplit is how the price of an NFT sale is divided
+type SaleSplit struct {
+    Price            token.Amount `json:"price"`
+    Fee              token.Amount `json:"fee"`       // Marketplace fee
+    FeeShares        FeeShares    `json:"feeShares"` // How the fee is shared out
+    Royalty          token.Amount `json:"royalty"`
+    RoyaltyRecipient string       `json:"royaltyRecipient,omitempty"`
+    SellerAmount     token.Amount `json:"sellerAmount"`
+}
+
+// saleSplit divides a sale price at a given time into the marketplace fee, the collection royalty and the seller's share
+// The caller must hold the lock
+func (ns *NFTSystem) saleSplit(nft *NFT, price token.Amount, timestamp int64) SaleSplit {
+    split := SaleSplit{Price: price}
+
+    // A collection's royalty takes precedence over one set on the NFT by its mint voucher
+    if collection, exists := ns.Collections[nft.CollectionID]; exists && collection.RoyaltyRate > 0 {
+        split.Royalty = price.MulRate(collection.RoyaltyRate)
+        split.RoyaltyRecipient = collection.RoyaltyRecipient
+    } else if nft.Royalty > 0 {
+        split.Royalty = price.MulRate(nft.Royalty)
+        split.RoyaltyRecipient = nft.Creator
+    }
+
+    // The fee floor never eats into the royalty
+    if ns.MasterWalletAddress != "" {
+        split.Fee = ns.FeePolicy.Fee(nft, price, timestamp)
+        if split.Fee > price-split.Royalty {
+            split.Fee = price - split.Royalty
+        }
+        split.FeeShares = ns.FeePolicy.Shares(split.Fee, ns.blockProducer)
+    }
+
+    split.SellerAmount = price - split.Fee - split.Royalty
+
+    return split
+}
+
+// settleSale pays out of the escrow funds the buyer has already paid into it and hands the NFT to the buyer
+// Balances and ownership change together, so a sale is never half applied
+// The caller must hold the lock
+func (ns *NFTSystem) settleSale(nft *NFT, buyer string, price token.Amount, timestamp int64, state *core.State) SaleSplit {
+    split := ns.saleSplit(nft, price, timestamp)
+
+    ns.payFee(split.FeeShares, state)
+    release(state, split.RoyaltyRecipient, split.Royalty)
+    release(state, nft.Owner, split.SellerAmount)
+
+    // The split goes on the sale record the transfer writes
+    ns.settling = &split
+    ns.transfer(nft, buyer, price, timestamp)
+    ns.settling = nil
+
+    return split
+}
+
+// primarySplit divides the price of a sale by an NFT's creator into the marketplace fee and the creator's share
+// The caller must hold the lock
+func (ns *NFTSystem) primarySplit(nft *NFT, price token.Amount, timestamp int64) SaleSplit {
+    split := SaleSplit{Price: price}
+
+    if ns.MasterWalletAddress != "" {
+        split.Fee = ns.FeePolicy.Fee(nft, price, timestamp)
+        split.FeeShares = ns.FeePolicy.Shares(split.Fee, ns.blockProducer)
+    }
+    split.SellerAmount = price - split.Fee
+
+    return split
+}
+
+// payFee pays each share of a marketplace fee out of the escrow to its recipient
+// The caller must hold the lock
+func (ns *NFTSystem) payFee(shares FeeShares, state *core.State) {
+    release(state, ns.MasterWalletAddress, shares.Master)
+    release(state, shares.ProducerAddress, shares.Producer)
+    release(state, shares.PoolAddress, shares.Pool)
+}
+
+// collect moves a payment from a buyer or bidder into the escrow, reporting whether they could cover it
+func collect(state *core.State, from string, amount token.Amount) bool {
+    if amount == 0 {
+        return true
+    }
+
+    return state.Transfer(from, EscrowAddress, amount) == nil
+}
+
+// release pays funds out of the escrow to a seller, a fee recipient or a refunded buyer
+// Every payment is collected into the escrow before it is released, so a release can only fail if the ledger
+// is inconsistent, and the funds then stay in the escrow rather than being created
+func release(state *core.State, to string, amount token.Amount) {
+    if amount > 0 {
+        state.Transfer(EscrowAddress, to, amount)
+    }
+}
+
+// applyBuy executes a checked purchase, charging the buyer the list price
+// A buyer who can't pay leaves both the NFT and all balances untouched
+// The caller must hold the lock
+func (ns *NFTSystem) applyBuy(tx core.Transaction, timestamp int64, state *core.State) {
+    nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+    price := nft.ListPrice
+
+    if !collect(state, tx.Sender, price) {
+        return
+    }
+
+    ns.settleSale(nft, tx.Sender, price, timestamp, state)
+
+    ns.persistNFT(nft)
+}
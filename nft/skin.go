@@ -0,0 +1,240 @@
+package nft
+
+import (
+    "crypto/sha256"
+    "encoding/binary"
+    "errors"
+    "fmt"
+)
+
+// NFTTypeChampionSkin is the NFT type of champion skins
+const NFTTypeChampionSkin = "champion_skin"
+
+// Rarity is the tier of a champion skin
+type Rarity string
+
+// Rarity tiers, from most to least common
+const (
+    RarityCommon    Rarity = "common"
+    RarityRare      Rarity = "rare"
+    RarityEpic      Rarity = "epic"
+    RarityLegendary Rarity = "legendary"
+)
+
+// Rarities returns every rarity tier, from most to least common
+func Rarities() []Rarity {
+    return []Rarity{RarityCommon, RarityRare, RarityEpic, RarityLegendary}
+}
+
+// ParseRarity converts a rarity name to a Rarity
+func ParseRarity(name string) (Rarity, error) {
+    for _, rarity := range Rarities() {
+        if string(rarity) == name {
+            return rarity, nil
+        }
+    }
+
+    return "", fmt.Errorf("unknown rarity %q", name)
+}
+
+// Tier returns the rank of a rarity, 1 for common up to 4 for legendary, or 0 if unknown
+func (r Rarity) Tier() int {
+    for i, rarity := range Rarities() {
+        if rarity == r {
+            return i + 1
+        }
+    }
+
+    return 0
+}
+
+// rarityNames returns the rarity tiers as schema values
+func rarityNames() []string {
+    names := []string{}
+    for _, rarity := range Rarities() {
+        names = append(names, string(rarity))
+    }
+
+    return names
+}
+
+// SkinAttributeFields returns the cosmetic attributes a champion skin may carry
+func SkinAttributeFields() []FieldSpec {
+    return []FieldSpec{
+        {Name: "chroma", Type: FieldString},
+        {Name: "particleEffect", Type: FieldString},
+        {Name: "recallAnimation", Type: FieldString},
+        {Name: "voiceOver", Type: FieldBool},
+        {Name: "borderColor", Type: FieldString},
+    }
+}
+
+// ChampionSkin is the metadata of a champion skin NFT
+type ChampionSkin struct {
+    Champion   string                 `json:"champion"` // ID of the champion the skin applies to
+    SkinName   string                 `json:"skinName"`
+    Rarity     Rarity                 `json:"rarity"`
+    Attributes map[string]interface{} `json:"attributes,omitempty"` // Cosmetic attributes, see SkinAttributeFields
+}
+
+// Metadata returns the skin as NFT metadata
+func (skin ChampionSkin) Metadata() map[string]interface{} {
+    metadata := map[string]interface{}{
+        "champion": skin.Champion,
+        "skinName": skin.SkinName,
+        "rarity":   string(skin.Rarity),
+    }
+
+    if len(skin.Attributes) > 0 {
+        attributes := make(map[string]interface{})
+        for name, value := range skin.Attributes {
+            attributes[name] = value
+        }
+        metadata["attributes"] = attributes
+    }
+
+    return metadata
+}
+
+// Skin reads a champion skin NFT's metadata
+func (nft *NFT) Skin() (*ChampionSkin, error) {
+    if nft.Type != NFTTypeChampionSkin {
+        return nil, errors.New("NFT is not a champion skin")
+    }
+
+    champion, err := nft.MetadataString("champion")
+    if err != nil {
+        return nil, err
+    }
+    skinName, err := nft.MetadataString("skinName")
+    if err != nil {
+        return nil, err
+    }
+    rarityName, err := nft.MetadataString("rarity")
+    if err != nil {
+        return nil, err
+    }
+    rarity, err := ParseRarity(rarityName)
+    if err != nil {
+        return nil, err
+    }
+
+    attributes, _ := nft.Metadata["attributes"].(map[string]interface{})
+
+    return &ChampionSkin{
+        Champion:   champion,
+        SkinName:   skinName,
+        Rarity:     rarity,
+        Attributes: attributes,
+    }, nil
+}
+
+// CreateSkin mints a champion skin NFT, validating it against the skin schema
+func (ns *NFTSystem) CreateSkin(owner string, creator string, skin ChampionSkin) (*NFT, error) {
+    return ns.CreateNFT(NFTTypeChampionSkin, owner, creator, skin.Metadata(), 0)
+}
+
+// DefaultRarityWeights returns the relative drop weights of each rarity: 60% common, 28% rare, 10% epic, 2% legendary
+func DefaultRarityWeights() map[Rarity]uint64 {
+    return map[Rarity]uint64{
+        RarityCommon:    60,
+        RarityRare:      28,
+        RarityEpic:      10,
+        RarityLegendary: 2,
+    }
+}
+
+// DropTable lists the skins a drop can award, chosen by rarity weight then uniformly within the rarity
+type DropTable struct {
+    Weights map[Rarity]uint64 `json:"weights"` // Relative weight of each rarity; rarities without skins never drop
+    Skins   []ChampionSkin    `json:"skins"`
+}
+
+// NewDropTable creates a drop table with the default rarity weights
+func NewDropTable(skins []ChampionSkin) *DropTable {
+    return &DropTable{
+        Weights: DefaultRarityWeights(),
+        Skins:   skins,
+    }
+}
+
+// Odds returns the chance of each rarity dropping, from 0 to 1
+func (t *DropTable) Odds() map[Rarity]float64 {
+    odds := make(map[Rarity]float64)
+
+    total := t.totalWeight()
+    if total == 0 {
+        return odds
+    }
+
+    for _, rarity := range Rarities() {
+        if len(t.skinsOf(rarity)) > 0 {
+            odds[rarity] = float64(t.Weights[rarity]) / float64(total)
+        }
+    }
+
+    return odds
+}
+
+// Draw picks a skin from the table using a seed
+// The same seed always draws the same skin, so a seed derived from chain data gives every node the same drop
+func (t *DropTable) Draw(seed []byte) (ChampionSkin, error) {
+    total := t.totalWeight()
+    if total == 0 {
+        return ChampionSkin{}, errors.New("drop table has no skins with a positive weight")
+    }
+
+    // Pick the rarity, then a skin of that rarity, from independent parts of the seed hash
+    hash := sha256.Sum256(seed)
+    roll := binary.BigEndian.Uint64(hash[:8]) % total
+    pick := binary.BigEndian.Uint64(hash[8:16])
+
+    for _, rarity := range Rarities() {
+        skins := t.skinsOf(rarity)
+        if len(skins) == 0 {
+            continue
+        }
+
+        weight := t.Weights[rarity]
+        if roll < weight {
+            return skins[pick%uint64(len(skins))], nil
+        }
+        roll -= weight
+    }
+
+    return ChampionSkin{}, errors.New("drop table roll out of range")
+}
+
+// MintDrop draws a skin from a drop table and mints it
+func (ns *NFTSystem) MintDrop(table *DropTable, owner string, creator string, seed []byte) (*NFT, error) {
+    skin, err := table.Draw(seed)
+    if err != nil {
+        return nil, err
+    }
+
+    return ns.CreateSkin(owner, creator, skin)
+}
+
+// totalWeight sums the weights of the rarities that have skins
+func (t *DropTable) totalWeight() uint64 {
+    total := uint64(0)
+    for _, rarity := range Rarities() {
+        if len(t.skinsOf(rarity)) > 0 {
+            total += t.Weights[rarity]
+        }
+    }
+
+    return total
+}
+
+// skinsOf returns the table's skins of a rarity, in table order
+func (t *DropTable) skinsOf(rarity Rarity) []ChampionSkin {
+    skins := []ChampionSkin{}
+    for _, skin := range t.Skins {
+        if skin.Rarity == rarity {
+            skins = append(skins, skin)
+        }
+    }
+
+    return skins
+}
@@ -0,0 +1,271 @@
+package nft
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeVoucherBuy redeems a mint voucher, minting the NFT to the sender and paying the creator
+// Data: voucher, a MintVoucher object; the sender pays the voucher price
+const TxTypeVoucherBuy = "nft_voucher_buy"
+
+// storeKeyVoucherPrefix prefixes persisted redeemed vouchers, which map a voucher ID to the NFT it minted
+const storeKeyVoucherPrefix = "voucher/"
+
+// MintVoucher is a creator's signed offer to mint an NFT for whoever first pays its price
+// Vouchers live off chain until redeemed, so unsold items take no chain state
+type MintVoucher struct {
+    Creator      string                 `json:"creator"`
+    PublicKey    string                 `json:"publicKey"` // Creator's hex public key, needed to verify the signature
+    NFTType      string                 `json:"nftType"`
+    Metadata     map[string]interface{} `json:"metadata"`
+    MetadataHash string                 `json:"metadataHash"` // Canonical hash of the metadata
+    Price        token.Amount           `json:"price"`
+    RoyaltyRate  float64                `json:"royaltyRate"` // Paid to the creator on resales
+    Nonce        int64                  `json:"nonce"`       // Tells apart otherwise identical vouchers
+    Signature    string                 `json:"signature"`
+}
+
+// MetadataHash returns the canonical hash of voucher metadata
+func MetadataHash(metadata map[string]interface{}) string {
+    hash, _ := core.CanonicalHash(metadata)
+    return hash
+}
+
+// SigningPayload returns the bytes the creator signs for a voucher
+func (v *MintVoucher) SigningPayload() ([]byte, error) {
+    return core.CanonicalEncode(struct {
+        Creator      string       `json:"creator"`
+        NFTType      string       `json:"nftType"`
+        MetadataHash string       `json:"metadataHash"`
+        Price        token.Amount `json:"price"`
+        RoyaltyRate  float64      `json:"royaltyRate"`
+        Nonce        int64        `json:"nonce"`
+    }{
+        Creator:      v.Creator,
+        NFTType:      v.NFTType,
+        MetadataHash: v.MetadataHash,
+        Price:        v.Price,
+        RoyaltyRate:  v.RoyaltyRate,
+        Nonce:        v.Nonce,
+    })
+}
+
+// ID returns the voucher's ID, the hash of its signing payload
+func (v *MintVoucher) ID() string {
+    payload, _ := v.SigningPayload()
+    hash := sha256.Sum256(payload)
+    return hex.EncodeToString(hash[:])
+}
+
+// NFTID returns the ID of the NFT the voucher mints
+func (v *MintVoucher) NFTID() string {
+    return DeriveNFTID(v.Creator, "", v.ID(), 0)
+}
+
+// Sign fills in the metadata hash, public key and signature for a creator's key pair
+func (v *MintVoucher) Sign(keyPair *crypto.KeyPair) error {
+    v.PublicKey = crypto.PublicKeyToHex(keyPair.PublicKey)
+    v.Creator = crypto.GetAddressFromPublicKey(keyPair.PublicKey)
+    v.MetadataHash = MetadataHash(v.Metadata)
+
+    payload, err := v.SigningPayload()
+    if err != nil {
+        return err
+    }
+
+    signature, err := keyPair.Sign(payload)
+    if err != nil {
+        return err
+    }
+
+    v.Signature = signature
+    return nil
+}
+
+// Verify checks that a voucher's terms are in range, its metadata matches the signed hash and the creator signed it
+func (v *MintVoucher) Verify() error {
+    if v.NFTType == "" {
+        return errors.New("NFT type is required")
+    }
+    if v.Price <= 0 {
+        return errors.New("voucher price must be positive")
+    }
+    if v.RoyaltyRate < 0 || v.RoyaltyRate > MaxRoyaltyRate {
+        return errors.New("voucher royalty rate is out of range")
+    }
+    if MetadataHash(v.Metadata) != v.MetadataHash {
+        return errors.New("voucher metadata does not match its hash")
+    }
+
+    if v.Signature == "" || v.PublicKey == "" {
+        return errors.New("voucher is not signed")
+    }
+
+    publicKey, err := crypto.HexToPublicKey(v.PublicKey)
+    if err != nil {
+        return fmt.Errorf("invalid voucher public key: %w", err)
+    }
+
+    if crypto.GetAddressFromPublicKey(publicKey) != v.Creator {
+        return errors.New("voucher public key does not match its creator")
+    }
+
+    payload, err := v.SigningPayload()
+    if err != nil {
+        return err
+    }
+
+    valid, err := crypto.Verify(payload, v.Signature, publicKey)
+    if err != nil || !valid {
+        return errors.New("invalid voucher signature")
+    }
+
+    return nil
+}
+
+// BuyVoucher redeems a mint voucher, minting the NFT directly to the buyer
+// It returns the minted NFT and the creator's share but moves no funds; nft_voucher_buy transactions settle payment on chain
+func (ns *NFTSystem) BuyVoucher(voucher *MintVoucher, buyer string) (*NFT, token.Amount, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    if err := ns.checkVoucher(voucher, buyer); err != nil {
+        return nil, 0, err
+    }
+
+    nft, split := ns.redeemVoucher(voucher, buyer, token.ClockNow(ns.Clock))
+
+    return nft, split.SellerAmount, nil
+}
+
+// IsVoucherRedeemed reports whether a voucher has been redeemed, and the NFT it minted
+func (ns *NFTSystem) IsVoucherRedeemed(voucherID string) (string, bool) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nftID, redeemed := ns.redeemedVouchers[voucherID]
+    return nftID, redeemed
+}
+
+// decodeVoucher reads the voucher carried by a voucher transaction
+func decodeVoucher(tx core.Transaction) (*MintVoucher, error) {
+    value := txValue(tx, "voucher")
+    if value == nil {
+        return nil, errors.New("voucher is required")
+    }
+
+    data, err := json.Marshal(value)
+    if err != nil {
+        return nil, err
+    }
+
+    var voucher MintVoucher
+    if err := json.Unmarshal(data, &voucher); err != nil {
+        return nil, fmt.Errorf("invalid voucher: %w", err)
+    }
+
+    return &voucher, nil
+}
+
+// checkVoucher validates the redemption of a voucher by a buyer
+// The caller must hold the lock
+func (ns *NFTSystem) checkVoucher(voucher *MintVoucher, buyer string) error {
+    if err := voucher.Verify(); err != nil {
+        return err
+    }
+
+    if _, redeemed := ns.redeemedVouchers[voucher.ID()]; redeemed {
+        return errors.New("voucher has already been redeemed")
+    }
+    if voucher.Creator == buyer {
+        return errors.New("creator cannot buy their own voucher")
+    }
+
+    // The creator must still be allowed to mint, and the metadata must still match its schema
+    if err := ns.checkMinter(voucher.NFTType, voucher.Creator); err != nil {
+        return err
+    }
+    if err := ns.validateMetadata(voucher.NFTType, voucher.Metadata); err != nil {
+        return err
+    }
+
+    return ns.checkYieldTier(voucher.NFTType, voucher.Metadata)
+}
+
+// checkVoucherTransaction validates a voucher transaction
+// The caller must hold the lock
+func (ns *NFTSystem) checkVoucherTransaction(tx core.Transaction) error {
+    voucher, err := decodeVoucher(tx)
+    if err != nil {
+        return err
+    }
+
+    return ns.checkVoucher(voucher, tx.Sender)
+}
+
+// applyVoucherTransaction redeems a checked voucher, charging the buyer its price
+// A buyer who can't pay leaves the voucher unredeemed and all balances untouched
+// The caller must hold the lock
+func (ns *NFTSystem) applyVoucherTransaction(tx core.Transaction, timestamp int64, state *core.State) {
+    voucher, err := decodeVoucher(tx)
+    if err != nil {
+        return
+    }
+
+    if !collect(state, tx.Sender, voucher.Price) {
+        return
+    }
+
+    nft, split := ns.redeemVoucher(voucher, tx.Sender, timestamp)
+
+    ns.payFee(split.FeeShares, state)
+    release(state, voucher.Creator, split.SellerAmount)
+
+    ns.persistNFT(nft)
+}
+
+// redeemVoucher mints a voucher's NFT to the buyer and records the primary sale
+// It returns the NFT and how the price divides between the marketplace fee and the creator
+// The caller must hold the lock
+func (ns *NFTSystem) redeemVoucher(voucher *MintVoucher, buyer string, timestamp int64) (*NFT, SaleSplit) {
+    nft := ns.mint(voucher.NFTID(), voucher.NFTType, buyer, voucher.Creator, voucher.Metadata, 0, timestamp)
+    nft.Royalty = voucher.RoyaltyRate
+    nft.TransferLog[0].Price = voucher.Price
+    ns.assignYieldTier(nft)
+
+    split := ns.primarySplit(nft, voucher.Price, timestamp)
+
+    ns.redeemedVouchers[voucher.ID()] = nft.ID
+    ns.persistVoucher(voucher.ID(), nft.ID)
+
+    ns.record(nftEvent(EventKindMint, nft, voucher.Creator, buyer, voucher.Price))
+    ns.settling = &split
+    ns.recordSaleHistory(nft, voucher.Creator, buyer, voucher.Price, timestamp)
+    ns.settling = nil
+
+    return nft, split
+}
+
+// persistVoucher writes a redeemed voucher to the store
+// The caller must hold the lock
+func (ns *NFTSystem) persistVoucher(voucherID string, nftID string) {
+    if ns.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(nftID)
+    if err != nil {
+        ns.recordStoreError(err)
+        return
+    }
+
+    ns.recordStoreError(ns.store.Put(storeKeyVoucherPrefix+voucherID, data))
+}
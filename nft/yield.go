@@ -0,0 +1,194 @@
+package nft
+
+import (
+    "errors"
+    "math"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// TxTypeSetCompounding turns a yield generator's compounding on or off
+// Data: nftId, compounding; Sender is the owner
+const TxTypeSetCompounding = "nft_set_compounding"
+
+// YieldClaim is the yield a generator accrued since its last claim, and whether it was paid out or restaked
+type YieldClaim struct {
+    NFTID     string       `json:"nftId"`
+    Principal token.Amount `json:"principal"` // Stake the yield accrued on, restaked yield included
+    Days      float64      `json:"days"`      // Since the last claim
+    Accrued   token.Amount `json:"accrued"`   // Within the yearly yield cap
+    Paid      token.Amount `json:"paid"`      // Owed to the owner
+    Restaked  token.Amount `json:"restaked"`  // Added to the generator's compounded stake
+}
+
+// YieldProjection is what a generator is expected to yield over a period with its compounding setting,
+// before the yearly yield cap
+type YieldProjection struct {
+    NFTID        string       `json:"nftId"`
+    Principal    token.Amount `json:"principal"` // Stake the yield accrues on, restaked yield included
+    Compounding  bool         `json:"compounding"`
+    Days         float64      `json:"days"`
+    Yield        token.Amount `json:"yield"`
+    EffectiveAPY float64      `json:"effectiveApy"` // Yearly yield with compounding taken into account
+}
+
+// SetCompounding turns a yield generator's compounding on or off for its owner
+// A compounding generator restakes its yield at each claim instead of paying it out
+func (ns *NFTSystem) SetCompounding(id string, owner string, compounding bool) error {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return errors.New("NFT not found")
+    }
+    if err := checkCompounding(nft, owner); err != nil {
+        return err
+    }
+
+    nft.Compounding = compounding
+    ns.persistNFT(nft)
+
+    return nil
+}
+
+// ClaimYield claims the yield a generator accrued on a stake since its last claim, counted to the second;
+// a compounding generator restakes it, so later claims yield on it too, and the rest is owed to the owner
+func (ns *NFTSystem) ClaimYield(id string, stakedAmount token.Amount) (YieldClaim, error) {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return YieldClaim{}, errors.New("NFT not found")
+    }
+    if err := ns.checkYield(nft, stakedAmount); err != nil {
+        return YieldClaim{}, err
+    }
+
+    now := token.ClockNow(ns.Clock)
+    elapsed := now - nft.LastYield
+    principal := stakedAmount + nft.Compounded
+    accrued := principal.MulRate(yieldGrowth(nft.YieldRate, elapsed, nft.Compounding))
+
+    // Keep total yield emissions within the yearly cap
+    if ns.Economics != nil {
+        accrued = ns.Economics.MintYield(accrued)
+    }
+
+    claim := YieldClaim{
+        NFTID:     nft.ID,
+        Principal: principal,
+        Days:      float64(elapsed) / SecondsPerDay,
+        Accrued:   accrued,
+    }
+    if nft.Compounding {
+        claim.Restaked = accrued
+        nft.Compounded += accrued
+    } else {
+        claim.Paid = accrued
+    }
+
+    nft.LastYield = now
+    ns.persistNFT(nft)
+
+    return claim, nil
+}
+
+// ProjectYield returns what a generator is expected to yield on a stake over a number of days from now,
+// compounding or not as the generator is set to; nothing is claimed
+func (ns *NFTSystem) ProjectYield(id string, stakedAmount token.Amount, days float64) (YieldProjection, error) {
+    if days < 0 {
+        return YieldProjection{}, errors.New("projection period cannot be negative")
+    }
+
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    nft, exists := ns.lookupNFT(id)
+    if !exists {
+        return YieldProjection{}, errors.New("NFT not found")
+    }
+    if err := ns.checkYield(nft, stakedAmount); err != nil {
+        return YieldProjection{}, err
+    }
+
+    principal := stakedAmount + nft.Compounded
+    seconds := int64(days * SecondsPerDay)
+
+    return YieldProjection{
+        NFTID:        nft.ID,
+        Principal:    principal,
+        Compounding:  nft.Compounding,
+        Days:         days,
+        Yield:        principal.MulRate(yieldGrowth(nft.YieldRate, seconds, nft.Compounding)),
+        EffectiveAPY: yieldGrowth(nft.YieldRate, 365*SecondsPerDay, nft.Compounding),
+    }, nil
+}
+
+// checkYield checks that an NFT is a yield generator and a stake meets its tier's minimum
+// The caller must hold the lock
+func (ns *NFTSystem) checkYield(nft *NFT, stakedAmount token.Amount) error {
+    if nft.Type != NFTTypeYieldGenerator || nft.YieldRate <= 0 {
+        return errors.New("NFT is not a yield generator")
+    }
+
+    if number, err := yieldTierOf(nft.Metadata); err == nil {
+        if tier, exists := ns.YieldTiers[number]; exists && stakedAmount < tier.MinStake {
+            return errors.New("staked amount is below the yield tier minimum")
+        }
+    }
+
+    return nil
+}
+
+// checkCompoundingTransaction validates a compounding transaction
+// The caller must hold the lock
+func (ns *NFTSystem) checkCompoundingTransaction(tx core.Transaction) error {
+    nft, exists := ns.lookupNFT(txString(tx, "nftId"))
+    if !exists {
+        return errors.New("NFT not found")
+    }
+
+    return checkCompounding(nft, tx.Sender)
+}
+
+// applyCompoundingTransaction executes a checked compounding transaction
+// The caller must hold the lock
+func (ns *NFTSystem) applyCompoundingTransaction(tx core.Transaction) {
+    nft, _ := ns.lookupNFT(txString(tx, "nftId"))
+    nft.Compounding, _ = txValue(tx, "compounding").(bool)
+    ns.persistNFT(nft)
+}
+
+// checkCompounding checks that an NFT is a yield generator the sender owns
+func checkCompounding(nft *NFT, sender string) error {
+    if nft.Owner != sender {
+        return errors.New("sender is not the owner of this NFT")
+    }
+    if nft.Type != NFTTypeYieldGenerator {
+        return errors.New("NFT is not a yield generator")
+    }
+
+    return nil
+}
+
+// yieldGrowth returns what a stake grows by over a number of seconds at a yearly rate, as a share of the stake
+// Simple yield accrues evenly; compounding yield compounds daily over the whole days and accrues evenly over
+// the fraction of a day left, so claiming at any second gives what daily compounding had reached by then
+func yieldGrowth(rate float64, seconds int64, compounding bool) float64 {
+    if seconds <= 0 {
+        return 0
+    }
+
+    daily := rate / 365.0
+    if !compounding {
+        return daily * float64(seconds) / SecondsPerDay
+    }
+
+    days := seconds / SecondsPerDay
+    fraction := float64(seconds%SecondsPerDay) / SecondsPerDay
+
+    return math.Pow(1+daily, float64(days))*(1+daily*fraction) - 1
+}
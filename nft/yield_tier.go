@@ -0,0 +1,158 @@
+package nft
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// NFTTypeYieldGenerator is the NFT type of yield generators
+const NFTTypeYieldGenerator = "yield_generator"
+
+// YieldTier defines the yield and supply of one tier of yield generators
+type YieldTier struct {
+    Tier      int          `json:"tier"`
+    Name      string       `json:"name"`
+    APY       float64      `json:"apy"`       // Yearly yield on the staked amount (7% = 0.07)
+    MaxSupply int64        `json:"maxSupply"` // Most generators of the tier in existence, 0 means uncapped
+    MinStake  token.Amount `json:"minStake"`  // Smallest stake a generator of the tier yields on
+}
+
+// DefaultYieldTiers returns the yield generator tiers used by the game
+func DefaultYieldTiers() []*YieldTier {
+    return []*YieldTier{
+        {Tier: 1, Name: "bronze", APY: 0.05, MaxSupply: 10000, MinStake: 100 * token.ILYZ},
+        {Tier: 2, Name: "silver", APY: 0.07, MaxSupply: 5000, MinStake: 1000 * token.ILYZ},
+        {Tier: 3, Name: "gold", APY: 0.10, MaxSupply: 1000, MinStake: 10000 * token.ILYZ},
+    }
+}
+
+// SetYieldTier adds or replaces a yield generator tier
+// Every node must use the same tiers, since mints are checked against them
+func (ns *NFTSystem) SetYieldTier(tier *YieldTier) error {
+    if tier.Tier <= 0 {
+        return errors.New("tier must be positive")
+    }
+    if tier.APY < 0 {
+        return errors.New("APY cannot be negative")
+    }
+    if tier.MaxSupply < 0 {
+        return errors.New("max supply cannot be negative")
+    }
+    if tier.MinStake < 0 {
+        return errors.New("min stake cannot be negative")
+    }
+
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    ns.YieldTiers[tier.Tier] = tier
+
+    return nil
+}
+
+// GetYieldTiers returns the yield generator tiers in tier order
+func (ns *NFTSystem) GetYieldTiers() []*YieldTier {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    tiers := []*YieldTier{}
+    for _, tier := range ns.YieldTiers {
+        tiers = append(tiers, tier)
+    }
+    sort.Slice(tiers, func(i, j int) bool {
+        return tiers[i].Tier < tiers[j].Tier
+    })
+
+    return tiers
+}
+
+// GetYieldTierSupply returns how many generators of a tier exist
+func (ns *NFTSystem) GetYieldTierSupply(tier int) int64 {
+    ns.mutex.Lock()
+    defer ns.mutex.Unlock()
+
+    return ns.tierSupply[tier]
+}
+
+// yieldTierOf returns the tier named by a yield generator's metadata
+func yieldTierOf(metadata map[string]interface{}) (int, error) {
+    value, ok := toFloat(metadata["tier"])
+    if !ok {
+        return 0, errors.New("yield generator tier is required")
+    }
+
+    return int(value), nil
+}
+
+// checkYieldTier validates minting a yield generator into the tier its metadata names
+// Other NFT types always pass
+// The caller must hold the lock
+func (ns *NFTSystem) checkYieldTier(nftType string, metadata map[string]interface{}) error {
+    if nftType != NFTTypeYieldGenerator {
+        return nil
+    }
+
+    number, err := yieldTierOf(metadata)
+    if err != nil {
+        return err
+    }
+
+    tier, exists := ns.YieldTiers[number]
+    if !exists {
+        return fmt.Errorf("unknown yield tier %d", number)
+    }
+
+    if tier.MaxSupply > 0 && ns.tierSupply[number] >= tier.MaxSupply {
+        return fmt.Errorf("yield tier %d supply cap reached", number)
+    }
+
+    return nil
+}
+
+// assignYieldTier gives a newly minted yield generator its tier's APY and counts it against the tier's supply
+// The caller must hold the lock
+func (ns *NFTSystem) assignYieldTier(nft *NFT) {
+    if nft.Type != NFTTypeYieldGenerator {
+        return
+    }
+
+    number, err := yieldTierOf(nft.Metadata)
+    if err != nil {
+        return
+    }
+
+    if tier, exists := ns.YieldTiers[number]; exists {
+        nft.YieldRate = tier.APY
+    }
+    ns.tierSupply[number]++
+}
+
+// releaseYieldTier frees the tier supply held by a burned yield generator
+// The caller must hold the lock
+func (ns *NFTSystem) releaseYieldTier(nft *NFT) {
+    if nft.Type != NFTTypeYieldGenerator {
+        return
+    }
+
+    if number, err := yieldTierOf(nft.Metadata); err == nil && ns.tierSupply[number] > 0 {
+        ns.tierSupply[number]--
+    }
+}
+
+// reindexYieldTiers recounts the supply of each tier from the registry
+// The caller must hold the lock
+func (ns *NFTSystem) reindexYieldTiers() {
+    ns.tierSupply = make(map[int]int64)
+
+    for _, nft := range ns.NFTs {
+        if nft.Type != NFTTypeYieldGenerator {
+            continue
+        }
+        if number, err := yieldTierOf(nft.Metadata); err == nil {
+            ns.tierSupply[number]++
+        }
+    }
+}
@@ -0,0 +1,136 @@
+package node
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/consensus"
+)
+
+// CatchUpWait is how many block intervals a starting validator waits for a peer to announce its chain status
+// before signing without one
+const CatchUpWait = 3
+
+// signing reports whether this node signs blocks and votes: it must be a validator with a key
+// whose chain has caught up with the network
+func (r *ConsensusReactor) signing() bool {
+    return r.validatorKey != nil && !r.catchingUp
+}
+
+// announceStatus broadcasts this node's chain tip and the highest block it has seen finalized
+func (r *ConsensusReactor) announceStatus() {
+    status := consensus.StatusMessage{NodeID: r.Network.ID, Height: r.Chain.GetHeight(), FinalizedHeight: -1}
+    if justification, found := r.Consensus.FinalizedHead(); found {
+        status.FinalizedHeight = justification.Height
+    }
+
+    r.Network.Broadcast(consensus.TopicStatus, status)
+}
+
+// handleStatus raises the height to catch up to when a peer has finalized blocks this node doesn't have,
+// and requests the blocks it is missing from the peer
+// A node that falls behind the finalized chain while running stops signing until it catches up again
+func (r *ConsensusReactor) handleStatus(status consensus.StatusMessage) {
+    if status.NodeID == "" || status.NodeID == r.Network.ID {
+        return
+    }
+
+    r.heardStatus = true
+    if status.FinalizedHeight > r.syncTarget {
+        r.syncTarget = status.FinalizedHeight
+    }
+
+    height := r.Chain.GetHeight()
+    if status.FinalizedHeight <= height {
+        return
+    }
+    if !r.catchingUp {
+        r.catchingUp = true
+        fmt.Printf("Behind finalized height %d at height %d, catching up\n", status.FinalizedHeight, height)
+    }
+
+    r.requestBlocks(status.NodeID, height, status.Height)
+}
+
+// handleSyncRequest sends a peer the blocks it asked for, up to MaxSyncBlocks of them
+func (r *ConsensusReactor) handleSyncRequest(request consensus.SyncRequest) {
+    if request.NodeID == "" || request.From < 1 || request.To < request.From {
+        return
+    }
+
+    height := r.Chain.GetHeight()
+    to := min(request.To, request.From+consensus.MaxSyncBlocks-1, height)
+
+    response := consensus.SyncResponse{NodeID: r.Network.ID, Height: height}
+    for index := request.From; index <= to; index++ {
+        block, err := r.Chain.GetBlockByHeight(index)
+        if err != nil {
+            break
+        }
+        response.Blocks = append(response.Blocks, block)
+    }
+
+    r.Network.SendToPeer(request.NodeID, consensus.TopicSyncResponse, response)
+}
+
+// handleSyncResponse adds the blocks a peer sent to the chain in order, replaying the validator set changes
+// they commit through the consensus module, then requests the next batch while the peer is still ahead
+func (r *ConsensusReactor) handleSyncResponse(response consensus.SyncResponse) {
+    added := 0
+    for _, block := range response.Blocks {
+        if block.Index <= r.Chain.GetHeight() {
+            continue
+        }
+
+        r.observeHeader(block.BlockHeader)
+        if err := r.Chain.AddBlock(block); err != nil {
+            fmt.Printf("Could not sync block %d: %v\n", block.Index, err)
+            return
+        }
+        added++
+    }
+    if added == 0 {
+        return
+    }
+
+    height := r.Chain.GetHeight()
+    fmt.Printf("Synced %d blocks to height %d\n", added, height)
+
+    if height < response.Height {
+        r.requestBlocks(response.NodeID, height, response.Height)
+    }
+    r.checkCaughtUp(time.Now())
+}
+
+// requestBlocks asks a peer for the next blocks after this node's tip, up to the peer's
+func (r *ConsensusReactor) requestBlocks(peerID string, height int64, peerHeight int64) {
+    if peerHeight <= height {
+        return
+    }
+
+    request := consensus.SyncRequest{NodeID: r.Network.ID, From: height + 1, To: min(peerHeight, height+consensus.MaxSyncBlocks)}
+    if err := r.Network.SendToPeer(peerID, consensus.TopicSyncRequest, request); err != nil {
+        fmt.Printf("Could not request blocks from %s: %v\n", peerID, err)
+    }
+}
+
+// checkCaughtUp re-enables signing once the chain has reached the highest height peers announced as finalized,
+// or, if no peer has announced its status, once the wait for one is over
+func (r *ConsensusReactor) checkCaughtUp(now time.Time) {
+    if !r.catchingUp {
+        return
+    }
+    if !r.heardStatus && now.Before(r.catchUpUntil) {
+        return
+    }
+
+    height := r.Chain.GetHeight()
+    if height < r.syncTarget {
+        return
+    }
+
+    r.catchingUp = false
+    if r.validatorKey != nil {
+        fmt.Printf("Caught up at height %d, signing enabled\n", height)
+    }
+}
@@ -0,0 +1,206 @@
+package node
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/nft"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+    "github.com/txaimhawj/chulubmeadditional-files/wallet"
+)
+
+// Files kept in a node's home directory
+const (
+    ConfigFile       = "config.json"
+    GenesisFile      = "genesis.json"
+    ChainDataFile    = "chain.dat"
+    ValidatorKeyFile = "validator_wallet.json"
+    StoreFile        = "state.db"
+    ConsensusFile    = "consensus.db"
+)
+
+// Config is the node configuration written by Init and read by Open
+type Config struct {
+    Chain                core.ChainConfig `json:"chain"`
+    NodeID               string           `json:"nodeId"`
+    NodeType             string           `json:"nodeType"` // "full", "game", "light", "master"
+    P2PPort              int              `json:"p2pPort"`
+    RESTAddress          string           `json:"restAddress"`
+    AdminAddress         string           `json:"adminAddress"`
+    BootstrapNodes       []string         `json:"bootstrapNodes"`
+    ValidatorAddress     string           `json:"validatorAddress,omitempty"`
+    ValidatorStake       token.Amount     `json:"validatorStake,omitempty"`
+    MasterWalletAddress  string           `json:"masterWalletAddress,omitempty"`
+    BlockIntervalSeconds int              `json:"blockIntervalSeconds"`
+    MinValidators        int              `json:"minValidators"`
+    IPFSGateway          string           `json:"ipfsGateway"` // Gateway off-chain NFT metadata is fetched through
+
+    // Addresses whose signed ILYZ prices are served, at the median of those at most MaxPriceAge seconds old,
+    // 600 if unset, once MinPriceFeeds of them are, 1 if unset; no prices are served without reporters
+    PriceReporters []string `json:"priceReporters,omitempty"`
+    MaxPriceAge    int64    `json:"maxPriceAge,omitempty"`
+    MinPriceFeeds  int      `json:"minPriceFeeds,omitempty"`
+}
+
+// InitOptions controls how Init sets up a home directory
+type InitOptions struct {
+    ChainID   string
+    Validator bool // Create a validator key for this node
+    Archive   bool // Run as an archive node
+}
+
+// DefaultConfig returns the configuration written by Init
+func DefaultConfig() Config {
+    return Config{
+        Chain:                core.DefaultChainConfig(),
+        NodeID:               fmt.Sprintf("node-%d", time.Now().UnixNano()),
+        NodeType:             "full",
+        P2PPort:              26656,
+        RESTAddress:          "127.0.0.1:8080",
+        AdminAddress:         "127.0.0.1:8081",
+        BootstrapNodes:       []string{},
+        BlockIntervalSeconds: 5,
+        MinValidators:        1,
+        IPFSGateway:          nft.DefaultIPFSGateway,
+    }
+}
+
+// Init writes a genesis file and node configuration to a new home directory
+func Init(home string, options InitOptions) (*Config, *core.Genesis, error) {
+    if options.ChainID == "" {
+        return nil, nil, errors.New("chain ID is required")
+    }
+
+    if _, err := os.Stat(filepath.Join(home, ConfigFile)); err == nil {
+        return nil, nil, fmt.Errorf("%s is already initialized", home)
+    }
+
+    if err := os.MkdirAll(home, 0755); err != nil {
+        return nil, nil, err
+    }
+
+    schedule := token.DefaultSupplySchedule()
+    market := token.DefaultFeeMarket()
+    genesis := &core.Genesis{
+        ChainID:   options.ChainID,
+        Timestamp: time.Now().Unix(),
+        Alloc:     map[string]token.Amount{},
+        Emission:  &schedule,
+        FeeMarket: &market,
+    }
+
+    config := DefaultConfig()
+
+    if options.Archive {
+        config.Chain.StorageMode = core.StorageModeArchive
+    }
+
+    if options.Validator {
+        validatorWallet, err := wallet.CreateWallet()
+        if err != nil {
+            return nil, nil, err
+        }
+
+        walletJSON, err := wallet.SaveWallet(validatorWallet, true)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        if err := os.WriteFile(filepath.Join(home, ValidatorKeyFile), []byte(walletJSON), 0600); err != nil {
+            return nil, nil, err
+        }
+
+        config.ValidatorAddress = validatorWallet.Address
+        config.ValidatorStake = 1000 * token.ILYZ
+    }
+
+    if err := core.SaveGenesis(genesis, filepath.Join(home, GenesisFile)); err != nil {
+        return nil, nil, err
+    }
+
+    if err := writeJSONFile(filepath.Join(home, ConfigFile), config); err != nil {
+        return nil, nil, err
+    }
+
+    if err := core.CheckChainIdentity(home, genesis); err != nil {
+        return nil, nil, err
+    }
+
+    return &config, genesis, nil
+}
+
+// LoadConfig reads the node configuration and genesis from a home directory
+func LoadConfig(home string) (*Config, *core.Genesis, error) {
+    var config Config
+    if err := readJSONFile(filepath.Join(home, ConfigFile), &config); err != nil {
+        return nil, nil, fmt.Errorf("%s is not initialized: %w", home, err)
+    }
+
+    genesis, err := core.LoadGenesis(filepath.Join(home, GenesisFile))
+    if err != nil {
+        return nil, nil, err
+    }
+
+    return &config, genesis, nil
+}
+
+// CreateChain checks the chain identity of a home directory and creates its chain from the genesis
+// The chain saved in the directory is restored by LoadChain once the modules executing it are registered
+func CreateChain(home string, config Config, genesis *core.Genesis) (*core.Blockchain, error) {
+    if err := core.CheckChainIdentity(home, genesis); err != nil {
+        return nil, err
+    }
+
+    config.Chain.Genesis = genesis
+
+    return core.NewBlockchainWithConfig(config.Chain)
+}
+
+// LoadChain restores the chain saved in a home directory, executing its blocks with the registered modules
+func LoadChain(home string, bc *core.Blockchain, genesis *core.Genesis) error {
+    chainData := filepath.Join(home, ChainDataFile)
+    if _, err := os.Stat(chainData); err == nil {
+        if _, err := bc.ImportChain(chainData); err != nil {
+            return fmt.Errorf("could not load chain data: %w", err)
+        }
+    }
+
+    return bc.VerifyGenesis(genesis)
+}
+
+// SaveChain writes the full chain to a home directory, replacing the previous copy atomically
+func SaveChain(home string, bc *core.Blockchain) error {
+    chainData := filepath.Join(home, ChainDataFile)
+    tempFile := chainData + ".tmp"
+
+    if err := bc.ExportChain(tempFile, 0, bc.GetHeight()); err != nil {
+        return err
+    }
+
+    return os.Rename(tempFile, chainData)
+}
+
+// readJSONFile decodes a JSON file
+func readJSONFile(path string, v interface{}) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+
+    return json.Unmarshal(data, v)
+}
+
+// writeJSONFile writes a value as indented JSON
+func writeJSONFile(path string, v interface{}) error {
+    data, err := json.MarshalIndent(v, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,353 @@
+package node
+
+import (
+    "crypto/ed25519"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/airdrop"
+    "github.com/txaimhawj/chulubmeadditional-files/api"
+    "github.com/txaimhawj/chulubmeadditional-files/consensus"
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/network"
+    "github.com/txaimhawj/chulubmeadditional-files/nft"
+    "github.com/txaimhawj/chulubmeadditional-files/storage"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+    "github.com/txaimhawj/chulubmeadditional-files/wallet"
+)
+
+// VoteRetention is how many blocks back votes are kept for tallies and vote rewards
+const VoteRetention = 100
+
+// Node wires the chain, consensus, networking, NFT, token and API components into a running node
+type Node struct {
+    Home      string
+    Config    Config
+    Chain     *core.Blockchain
+    Consensus *consensus.ProofOfPlay
+    Network   *network.Node
+    NFTs      *nft.NFTSystem
+    Store     storage.Store
+    Economics *token.TokenEconomics
+    REST      *api.RESTServer
+    Admin     *api.AdminServer
+    Reactor   *ConsensusReactor
+
+    // Closed to stop the run loop; the loop closes done when it exits
+    quit chan struct{}
+    done chan struct{}
+
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// Open loads a node from an initialized home directory
+func Open(home string) (*Node, error) {
+    config, genesis, err := LoadConfig(home)
+    if err != nil {
+        return nil, err
+    }
+
+    return New(home, *config, genesis)
+}
+
+// New creates a node from a configuration and genesis, restoring any chain saved in home
+// The saved chain is only loaded once every module is registered, so its blocks are executed and checked as they were
+// when they were first appended
+func New(home string, config Config, genesis *core.Genesis) (*Node, error) {
+    bc, err := CreateChain(home, config, genesis)
+    if err != nil {
+        return nil, err
+    }
+
+    // Votes, locks and the last messages signed survive restarts, so a recovered validator can't sign conflicting ones
+    consensusStore, err := storage.OpenFileStore(filepath.Join(home, ConsensusFile))
+    if err != nil {
+        return nil, err
+    }
+    pop, err := consensus.OpenProofOfPlay(consensusStore)
+    if err != nil {
+        return nil, err
+    }
+    pop.MinValidators = config.MinValidators
+    pop.FeeSharing = bc.FeePolicy
+    if config.ValidatorAddress != "" {
+        pop.RegisterValidator(config.ValidatorAddress, config.ValidatorStake, config.NodeType == "game")
+    }
+
+    store, err := storage.OpenFileStore(filepath.Join(home, StoreFile))
+    if err != nil {
+        return nil, err
+    }
+
+    // The registry is derived from the chain, which is re-executed into it as it is loaded
+    nftSystem, err := nft.OpenNFTSystem(config.MasterWalletAddress, store)
+    if err != nil {
+        return nil, err
+    }
+    bc.RegisterModule(nftSystem)
+
+    // Game servers and the settings blocks are applied by come from genesis and governance, so every node applies them alike
+    pop.Configurables = []core.Configurable{nftSystem}
+    if err := genesis.CheckSettings([]core.Configurable{pop, nftSystem}); err != nil {
+        return nil, err
+    }
+
+    // Economics survive restarts, so the yearly cap isn't minted again; the chain reconciles them as they are attached
+    economics, err := token.OpenTokenEconomics(config.MasterWalletAddress, store)
+    if err != nil {
+        return nil, err
+    }
+    if genesis.HourlyEmissionCeiling > 0 {
+        economics.Breaker.HourlyCeiling = genesis.HourlyEmissionCeiling
+    }
+    if genesis.DailyEmissionCeiling > 0 {
+        economics.Breaker.DailyCeiling = genesis.DailyEmissionCeiling
+    }
+    if genesis.Referral != nil {
+        economics.Referral = *genesis.Referral
+    }
+    economics.Schedule = genesis.SupplySchedule()
+    economics.Clock = bc.Clock
+    economics.OnYearTransition(func(transition token.YearTransition) {
+        fmt.Printf("Supply year %d started: year %d minted %s of its %s cap, supply %s\n", transition.Year,
+            transition.PreviousYear, transition.Minted, transition.PreviousCap, transition.Supply)
+    })
+    pop.Economy = economics.Params
+    economics.Governance = pop
+    bc.Rewarder = pop
+    bc.ForkChoice = pop
+    bc.Params = pop
+    bc.Fees = pop
+    bc.Finality = pop
+    nftSystem.Economics = economics
+    nftSystem.Clock = bc.Clock
+    nftSystem.Transactions = bc
+    pop.Boosts = nftSystem
+
+    // Slashes are derived from the evidence committed on chain
+    bc.RegisterModule(pop)
+
+    emission := core.NewEmissionReport()
+    emission.Stakes = pop
+    bc.RegisterModule(emission)
+
+    // Airdrop claims are paid out of funds committed on chain
+    airdrops := airdrop.NewAirdrops()
+    bc.RegisterModule(airdrops)
+
+    // Every module starts from the genesis block, then sees each saved block as it is loaded; the economics are attached
+    // after, since they survived the restart and reconcile against the loaded chain
+    bc.RebuildModules()
+    if err := LoadChain(home, bc, genesis); err != nil {
+        return nil, err
+    }
+    bc.SetEconomics(economics)
+
+    validatorKey, err := loadValidatorKey(home, config.ValidatorAddress)
+    if err != nil {
+        return nil, err
+    }
+    if validatorKey != nil {
+        if err := pop.CheckConsensusKey(config.ValidatorAddress, crypto.PublicKeyToHex(validatorKey.PublicKey)); err != nil {
+            return nil, fmt.Errorf("validator key: %w", err)
+        }
+    }
+
+    n := &Node{
+        Home:      home,
+        Config:    config,
+        Chain:     bc,
+        Consensus: pop,
+        Network:   network.NewNode(config.NodeID, fmt.Sprintf("127.0.0.1:%d", config.P2PPort), config.NodeType, config.ValidatorAddress != ""),
+        NFTs:      nftSystem,
+        Store:     store,
+        Economics: economics,
+        REST:      api.NewRESTServer(bc, nftSystem),
+        Admin:     api.NewAdminServer(bc, pop),
+    }
+
+    // Blocks and votes are signed with the key in the home directory, if this node is a validator with one
+    n.Reactor = NewConsensusReactor(bc, pop, n.Network, config.ValidatorAddress, validatorKey,
+        time.Duration(config.BlockIntervalSeconds)*time.Second)
+
+    n.REST.Metadata = nft.NewMetadataFetcher(config.IPFSGateway)
+    n.REST.Consensus = pop
+    n.REST.Emission = emission
+    n.REST.Airdrops = airdrops
+    if len(config.PriceReporters) > 0 {
+        oracle := token.NewMedianOracle(config.PriceReporters)
+        if config.MaxPriceAge > 0 {
+            oracle.MaxAge = config.MaxPriceAge
+        }
+        if config.MinPriceFeeds > 0 {
+            oracle.MinFeeds = config.MinPriceFeeds
+        }
+        n.REST.Oracle = oracle
+    }
+
+    // Emission rules are consensus-critical, so only peers minting by the same schedule are connected
+    n.Network.EmissionSchedule = genesis.EmissionScheduleHash()
+
+    // Gossip transactions submitted through the REST API
+    n.REST.OnTransaction = func(tx core.Transaction) {
+        n.Network.Broadcast("transaction", tx)
+    }
+    // Validators prove they are online with each network heartbeat
+    if validatorKey != nil {
+        n.Network.HeartbeatContent = func() interface{} {
+            heartbeat, err := consensus.NewHeartbeat(config.ValidatorAddress, validatorKey)
+            if err != nil {
+                return nil
+            }
+            return heartbeat
+        }
+    }
+
+    return n, nil
+}
+
+// Start starts networking, the APIs and block production
+func (n *Node) Start() error {
+    n.mutex.Lock()
+    defer n.mutex.Unlock()
+
+    if n.quit != nil {
+        return errors.New("node is already running")
+    }
+
+    if err := n.Network.Start(n.Config.P2PPort); err != nil {
+        return err
+    }
+
+    for _, address := range n.Config.BootstrapNodes {
+        if err := n.Network.Connect(address); err != nil {
+            fmt.Printf("Could not connect to bootstrap node %s: %v\n", address, err)
+        }
+    }
+
+    if err := n.REST.Start(n.Config.RESTAddress); err != nil {
+        n.Network.Stop()
+        return err
+    }
+
+    if err := n.Admin.Start(n.Config.AdminAddress); err != nil {
+        n.REST.Stop()
+        n.Network.Stop()
+        return err
+    }
+
+    if err := n.Reactor.Start(); err != nil {
+        n.Admin.Stop()
+        n.REST.Stop()
+        n.Network.Stop()
+        return err
+    }
+
+    n.quit = make(chan struct{})
+    n.done = make(chan struct{})
+    go n.run()
+
+    return nil
+}
+
+// Stop stops the node and saves its chain and module state to the home directory
+func (n *Node) Stop() error {
+    n.mutex.Lock()
+    defer n.mutex.Unlock()
+
+    if n.quit == nil {
+        return errors.New("node is not running")
+    }
+
+    n.Reactor.Stop()
+    close(n.quit)
+    <-n.done
+    n.quit = nil
+
+    n.Admin.Stop()
+    n.REST.Stop()
+    n.Network.Stop()
+
+    if err := SaveChain(n.Home, n.Chain); err != nil {
+        return err
+    }
+    if err := n.Consensus.Flush(); err != nil {
+        return err
+    }
+    if err := n.Economics.Flush(); err != nil {
+        return err
+    }
+
+    return n.NFTs.Flush()
+}
+
+// run feeds transactions, attestations and heartbeats from the network into the chain and consensus engine
+// Blocks, votes and evidence are handled by the consensus reactor
+func (n *Node) run() {
+    defer close(n.done)
+
+    for {
+        select {
+        case txData := <-n.Network.TxQueue:
+            var tx core.Transaction
+            if err := json.Unmarshal(txData, &tx); err == nil {
+                if err := n.Chain.CreateTransaction(tx); err != nil {
+                    fmt.Printf("Rejected transaction from peer: %v\n", err)
+                }
+            }
+
+        case attestationData := <-n.Network.AttestationQueue:
+            var attestation consensus.ActivityAttestation
+            if err := json.Unmarshal(attestationData, &attestation); err == nil {
+                // Peers gossiping bare attestations have them committed like any submitted through the API
+                baseFee, _, _ := n.Chain.GetBaseFee()
+                tx := consensus.AttestationTransaction(attestation, baseFee, time.Now().Unix())
+                if err := n.Chain.CreateTransaction(tx); err != nil {
+                    fmt.Printf("Rejected attestation from peer: %v\n", err)
+                }
+            }
+
+        case heartbeatData := <-n.Network.HeartbeatQueue:
+            var heartbeat consensus.Heartbeat
+            if err := json.Unmarshal(heartbeatData, &heartbeat); err == nil {
+                n.Consensus.RecordHeartbeat(heartbeat)
+            }
+
+        case <-n.quit:
+            return
+        }
+    }
+}
+
+// loadValidatorKey reads the key pair of the validator wallet in a home directory
+// Nodes without a validator wallet produce unsigned blocks
+func loadValidatorKey(home string, address string) (*crypto.KeyPair, error) {
+    data, err := os.ReadFile(filepath.Join(home, ValidatorKeyFile))
+    if errors.Is(err, os.ErrNotExist) || address == "" {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    validatorWallet, err := wallet.LoadWallet(string(data))
+    if err != nil {
+        return nil, err
+    }
+
+    privateKey, err := crypto.HexToPrivateKey(validatorWallet.PrivateKey)
+    if err != nil {
+        return nil, fmt.Errorf("invalid validator key: %w", err)
+    }
+
+    // A validator that rotated its consensus key signs with a key its address isn't derived from;
+    // the key is checked against the validator set once the chain is loaded
+    return &crypto.KeyPair{PrivateKey: privateKey, PublicKey: privateKey.Public().(ed25519.PublicKey)}, nil
+}
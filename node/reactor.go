@@ -0,0 +1,421 @@
+package node
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/consensus"
+    "github.com/txaimhawj/chulubmeadditional-files/core"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/network"
+)
+
+// ConsensusReactor drives the consensus engine from the network
+// It feeds the proposals, votes and evidence gossiped by peers into the chain and consensus engine,
+// produces blocks when this node's validator is scheduled to, moves voting on when rounds time out,
+// and broadcasts what the validator signs
+type ConsensusReactor struct {
+    Chain            *core.Blockchain
+    Consensus        *consensus.ProofOfPlay
+    Network          *network.Node
+    ValidatorAddress string        // Empty if this node doesn't validate
+    BlockInterval    time.Duration // How often to check whether to produce a block
+
+    // Key blocks and votes are signed with, nil if this node has no validator key
+    validatorKey *crypto.KeyPair
+
+    // Consensus messages from peers
+    proposals <-chan []byte
+    votes     <-chan []byte
+    evidence  <-chan []byte
+
+    // Chain status and blocks requested by nodes catching up
+    status       <-chan []byte
+    syncRequests <-chan []byte
+    syncBlocks   <-chan []byte
+
+    // A validator starts out catching up and doesn't sign until its chain reaches the highest height peers
+    // announced as finalized; syncTarget is that height, and a node no peer announces to by catchUpUntil signs
+    // once it has reached it
+    catchingUp   bool
+    heardStatus  bool
+    syncTarget   int64
+    catchUpUntil time.Time
+
+    // Height being voted on, its current round and when the round started
+    height     int64
+    round      int64
+    roundStart time.Time
+
+    // Closed to stop the reactor; the reactor closes done when it exits
+    quit chan struct{}
+    done chan struct{}
+}
+
+// NewConsensusReactor creates a reactor subscribed to the consensus messages of a network node
+func NewConsensusReactor(chain *core.Blockchain, pop *consensus.ProofOfPlay, networkNode *network.Node, validatorAddress string, validatorKey *crypto.KeyPair, blockInterval time.Duration) *ConsensusReactor {
+    return &ConsensusReactor{
+        Chain:            chain,
+        Consensus:        pop,
+        Network:          networkNode,
+        ValidatorAddress: validatorAddress,
+        BlockInterval:    blockInterval,
+        validatorKey:     validatorKey,
+        proposals:        networkNode.Subscribe(consensus.TopicProposal, 10),
+        votes:            networkNode.Subscribe(consensus.TopicVote, 100),
+        evidence:         networkNode.Subscribe(consensus.TopicEvidence, 10),
+        status:           networkNode.Subscribe(consensus.TopicStatus, 100),
+        syncRequests:     networkNode.Subscribe(consensus.TopicSyncRequest, 10),
+        syncBlocks:       networkNode.Subscribe(consensus.TopicSyncResponse, 10),
+    }
+}
+
+// Start starts handling consensus messages and producing blocks
+func (r *ConsensusReactor) Start() error {
+    if r.quit != nil {
+        return errors.New("consensus reactor is already running")
+    }
+
+    r.catchingUp, r.heardStatus, r.syncTarget = true, false, 0
+    r.catchUpUntil = time.Now().Add(CatchUpWait * r.BlockInterval)
+
+    r.quit = make(chan struct{})
+    r.done = make(chan struct{})
+    go r.run()
+
+    return nil
+}
+
+// Stop stops the reactor and waits for it to exit
+func (r *ConsensusReactor) Stop() {
+    if r.quit == nil {
+        return
+    }
+
+    close(r.quit)
+    <-r.done
+    r.quit = nil
+}
+
+// run handles consensus messages from peers and produces blocks when this node is selected
+func (r *ConsensusReactor) run() {
+    defer close(r.done)
+
+    ticker := time.NewTicker(r.BlockInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case data := <-r.proposals:
+            var proposal consensus.ProposalMessage
+            if err := json.Unmarshal(data, &proposal); err == nil {
+                r.handleProposal(proposal)
+            }
+
+        case data := <-r.Network.BlockQueue:
+            // Blocks relayed outside consensus are handled like proposals in the round their time falls in
+            var block core.Block
+            if err := json.Unmarshal(data, &block); err == nil {
+                parent := r.Chain.GetLatestBlock()
+                r.handleProposal(consensus.ProposalMessage{Block: block, Round: r.Consensus.ProposerRound(parent.Timestamp, block.Timestamp)})
+            }
+
+        case data := <-r.votes:
+            var message consensus.VoteMessage
+            if err := json.Unmarshal(data, &message); err == nil {
+                r.handleVote(message.Vote)
+            }
+
+        case data := <-r.evidence:
+            var message consensus.EvidenceMessage
+            if err := json.Unmarshal(data, &message); err == nil {
+                r.handleEvidence(&message.Evidence)
+            }
+
+        case data := <-r.status:
+            var status consensus.StatusMessage
+            if err := json.Unmarshal(data, &status); err == nil {
+                r.handleStatus(status)
+            }
+
+        case data := <-r.syncRequests:
+            var request consensus.SyncRequest
+            if err := json.Unmarshal(data, &request); err == nil {
+                r.handleSyncRequest(request)
+            }
+
+        case data := <-r.syncBlocks:
+            var response consensus.SyncResponse
+            if err := json.Unmarshal(data, &response); err == nil {
+                r.handleSyncResponse(response)
+            }
+
+        case <-ticker.C:
+            r.announceStatus()
+            r.checkCaughtUp(time.Now())
+            r.produceBlock()
+            r.checkTimeouts(time.Now())
+
+        case <-r.quit:
+            return
+        }
+    }
+}
+
+// handleProposal adds a proposed block to the chain if its producer was scheduled to propose it, then prevotes it
+func (r *ConsensusReactor) handleProposal(proposal consensus.ProposalMessage) {
+    block := proposal.Block
+
+    // Conflicting blocks are rejected by the chain, but still prove double signing
+    r.observeHeader(block.BlockHeader)
+    if err := r.checkProposer(block.BlockHeader, proposal.Round); err != nil {
+        fmt.Printf("Rejected block %d from peer: %v\n", block.Index, err)
+        return
+    }
+    if err := r.Chain.AddBlock(block); err != nil {
+        fmt.Printf("Rejected block %d from peer: %v\n", block.Index, err)
+        if evidence := consensus.InvalidProposalEvidence(block); evidence != nil {
+            r.reportEvidence(evidence)
+        }
+        return
+    }
+
+    r.blockAdded(block.BlockHeader, proposal.Round)
+}
+
+// handleVote counts a peer's vote, submitting the evidence if it conflicts with an earlier one,
+// skips ahead when enough of the weight is voting in a later round, and precommits once a prevote completes a majority
+func (r *ConsensusReactor) handleVote(vote consensus.Vote) {
+    evidence, err := r.Consensus.CastVote(vote)
+    if errors.Is(err, consensus.ErrDuplicateVote) {
+        return
+    }
+    if err != nil {
+        fmt.Printf("Rejected vote from peer: %v\n", err)
+        return
+    }
+    if evidence != nil {
+        r.reportEvidence(evidence)
+        return
+    }
+
+    if vote.Height == r.height && vote.Round > r.round && r.Consensus.RoundAhead(vote.Height, vote.Round) {
+        r.enterRound(vote.Round, time.Now())
+    }
+    if vote.Step == consensus.StepPrevote {
+        r.precommit(vote.Height, vote.Round)
+    }
+}
+
+// handleEvidence pools verified evidence from a peer, to be committed by the next block, and gossips it on
+// Evidence already pooled has been gossiped before, so it isn't broadcast again
+func (r *ConsensusReactor) handleEvidence(evidence *consensus.Evidence) {
+    added, err := r.Consensus.AddEvidence(evidence)
+    if err != nil {
+        fmt.Printf("Rejected evidence from peer: %v\n", err)
+        return
+    }
+    if !added {
+        return
+    }
+
+    r.Network.Broadcast(consensus.TopicEvidence, consensus.EvidenceMessage{Evidence: *evidence})
+    fmt.Printf("Received %s evidence against %s at height %d\n", evidence.Kind, evidence.Validator, evidence.Height)
+}
+
+// produceBlock creates and broadcasts a block if this node is a validator scheduled to propose in the current round,
+// committing the pooled evidence along with the pending transactions
+func (r *ConsensusReactor) produceBlock() {
+    if r.ValidatorAddress == "" || r.catchingUp || (r.Chain.GetPendingTransactionCount() == 0 && len(r.Consensus.GetPooledEvidence()) == 0) {
+        return
+    }
+
+    parent := r.Chain.GetLatestBlock()
+    round := r.Consensus.ProposerRound(parent.Timestamp, time.Now().Unix())
+    producer, err := r.Consensus.Proposer(parent.Index+1, round)
+    if err != nil || producer != r.ValidatorAddress {
+        return
+    }
+    if err := r.Consensus.CheckProposal(parent.Index + 1); err != nil {
+        fmt.Printf("Not producing block %d: %v\n", parent.Index+1, err)
+        return
+    }
+    for _, tx := range r.Consensus.EvidenceTransactions(r.ValidatorAddress, time.Now().Unix()) {
+        if err := r.Chain.CreateTransaction(tx); err != nil {
+            fmt.Printf("Could not include evidence in block %d: %v\n", parent.Index+1, err)
+        }
+    }
+
+    block := r.Chain.CreateBlock(r.ValidatorAddress, r.validatorKey)
+    if err := r.Consensus.RecordProposal(block.BlockHeader); err != nil {
+        fmt.Printf("Could not record block %d as signed, not broadcasting it: %v\n", block.Index, err)
+        return
+    }
+    r.observeHeader(block.BlockHeader)
+    r.Network.Broadcast(consensus.TopicProposal, consensus.ProposalMessage{Block: block, Round: round})
+    fmt.Printf("Produced block %d with %d transactions\n", block.Index, len(block.Transactions))
+
+    r.blockAdded(block.BlockHeader, round)
+}
+
+// checkTimeouts moves voting on when the current round times out
+// While the next block is awaited, with transactions waiting for it, each round the proposer schedule passes
+// without a block gets a nil prevote; a block on the chain not yet finalized is prevoted again in the next round,
+// so it can still be finalized after a round that failed
+func (r *ConsensusReactor) checkTimeouts(now time.Time) {
+    if !r.signing() {
+        return
+    }
+
+    tip := r.Chain.GetLatestBlock()
+    if _, decided := r.Consensus.GetDecision(tip.Index); decided || tip.Index == 0 {
+        height := tip.Index + 1
+        round := r.Consensus.ProposerRound(tip.Timestamp, now.Unix())
+        if height != r.height {
+            r.height, r.round, r.roundStart = height, round, now
+        }
+
+        // An idle chain isn't stalled; its rounds pass without anything to propose
+        if r.Chain.GetPendingTransactionCount() == 0 {
+            r.round = round
+            return
+        }
+        if r.round >= round {
+            return
+        }
+
+        r.skipRound(height, r.round)
+        for skipped := r.round + 1; skipped < round; skipped++ {
+            r.Consensus.RecordSkippedRound(height, skipped)
+        }
+        r.round, r.roundStart = round, now
+        return
+    }
+
+    if tip.Index != r.height {
+        r.height, r.round, r.roundStart = tip.Index, 0, now
+    }
+    if now.Sub(r.roundStart) < time.Duration(r.Consensus.RoundDuration(r.round))*time.Second {
+        return
+    }
+
+    r.skipRound(tip.Index, r.round)
+    r.enterRound(r.round+1, now)
+}
+
+// skipRound records a round that timed out, prevoting nil in it if this validator hasn't voted yet
+// and precommitting if the prevotes have a majority
+func (r *ConsensusReactor) skipRound(height int64, round int64) {
+    r.Consensus.RecordSkippedRound(height, round)
+
+    if vote, err := r.Consensus.Prevote(height, round, "", r.validatorKey); err == nil && vote != nil {
+        r.Network.Broadcast(consensus.TopicVote, consensus.VoteMessage{Vote: *vote})
+    }
+    r.precommit(height, round)
+}
+
+// enterRound moves voting at the current height to a later round, prevoting the block at that height
+// if the chain has one and nil otherwise
+func (r *ConsensusReactor) enterRound(round int64, now time.Time) {
+    r.round, r.roundStart = round, now
+    if !r.signing() {
+        return
+    }
+
+    proposal := ""
+    if header, err := r.Chain.GetHeaderByHeight(r.height); err == nil {
+        proposal = header.Hash
+    }
+
+    if vote, err := r.Consensus.Prevote(r.height, round, proposal, r.validatorKey); err == nil && vote != nil {
+        r.Network.Broadcast(consensus.TopicVote, consensus.VoteMessage{Vote: *vote})
+    }
+    r.precommit(r.height, round)
+}
+
+// blockAdded credits a block added to the chain to its producer's liveness, prunes votes on blocks long final,
+// and signs and broadcasts this validator's prevote for the block in the round it was proposed in,
+// so it can be finalized and the voters rewarded
+func (r *ConsensusReactor) blockAdded(header core.BlockHeader, round int64) {
+    r.Consensus.RecordBlock(header)
+    r.Consensus.PruneVotes(header.Index - VoteRetention)
+    r.height, r.round, r.roundStart = header.Index, round, time.Now()
+
+    if !r.signing() {
+        return
+    }
+
+    vote, err := r.Consensus.Prevote(header.Index, round, header.Hash, r.validatorKey)
+    if err != nil || vote == nil {
+        return
+    }
+
+    r.Network.Broadcast(consensus.TopicVote, consensus.VoteMessage{Vote: *vote})
+    r.precommit(header.Index, round)
+}
+
+// precommit signs and broadcasts this validator's precommit in a round once 2/3 of the weight has prevoted alike
+func (r *ConsensusReactor) precommit(height int64, round int64) {
+    if !r.signing() {
+        return
+    }
+
+    vote, err := r.Consensus.Precommit(height, round, r.validatorKey)
+    if err != nil || vote == nil {
+        return
+    }
+
+    r.Network.Broadcast(consensus.TopicVote, consensus.VoteMessage{Vote: *vote})
+}
+
+// checkProposer rejects a peer block extending the chain tip if its producer wasn't scheduled to propose it
+// in the round it was proposed for
+// Blocks at other heights are left to the chain, which rejects or stores them as forks
+func (r *ConsensusReactor) checkProposer(header core.BlockHeader, round int64) error {
+    parent := r.Chain.GetLatestBlock()
+    if header.Index != parent.Index+1 || header.PrevHash != parent.Hash {
+        return nil
+    }
+
+    if err := r.Consensus.CheckProposer(header, parent.BlockHeader, time.Now().Unix()); err != nil {
+        return err
+    }
+
+    proposer, err := r.Consensus.Proposer(header.Index, round)
+    if err != nil {
+        return err
+    }
+    if proposer != header.Validator {
+        return fmt.Errorf("%s is not the proposer of round %d", header.Validator, round)
+    }
+
+    return nil
+}
+
+// observeHeader passes a block header to consensus and reports any double signing it proves
+func (r *ConsensusReactor) observeHeader(header core.BlockHeader) {
+    evidence, err := r.Consensus.ObserveHeader(header)
+    if err != nil || evidence == nil {
+        return
+    }
+
+    r.reportEvidence(evidence)
+}
+
+// reportEvidence pools evidence of misbehavior this node caught, for whichever validator proposes next to commit,
+// and gossips it
+func (r *ConsensusReactor) reportEvidence(evidence *consensus.Evidence) {
+    added, err := r.Consensus.AddEvidence(evidence)
+    if err != nil {
+        fmt.Printf("Could not pool evidence against %s: %v\n", evidence.Validator, err)
+        return
+    }
+    if !added {
+        return
+    }
+
+    r.Network.Broadcast(consensus.TopicEvidence, consensus.EvidenceMessage{Evidence: *evidence})
+    fmt.Printf("Reported %s evidence against %s at height %d\n", evidence.Kind, evidence.Validator, evidence.Height)
+}
@@ -0,0 +1,23 @@
+package simulation
+
+import "time"
+
+// Clock is a scripted clock: time only moves when the simulation advances it
+type Clock struct {
+    now time.Time
+}
+
+// NewClock creates a clock stopped at a time
+func NewClock(start time.Time) *Clock {
+    return &Clock{now: start}
+}
+
+// Now returns the clock's current time
+func (c *Clock) Now() time.Time {
+    return c.now
+}
+
+// Advance moves the clock forward
+func (c *Clock) Advance(d time.Duration) {
+    c.now = c.now.Add(d)
+}
@@ -0,0 +1,332 @@
+package simulation
+
+import (
+    "crypto/ed25519"
+    "crypto/sha256"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/consensus"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// Validator behaviors
+const (
+    BehaviorHonest       = "honest"       // Prevotes the proposal and precommits majorities, as the consensus reactor does
+    BehaviorSilent       = "silent"       // Never proposes or votes
+    BehaviorEquivocating = "equivocating" // Proposes and votes for two different blocks in every round
+)
+
+// ValidatorSpec is one simulated validator
+type ValidatorSpec struct {
+    Behavior string
+    Stake    token.Amount
+}
+
+// Config describes a simulation run
+// Runs with the same configuration and transport make the same votes in the same order and produce the same result
+type Config struct {
+    Validators []ValidatorSpec
+    Heights    int64     // Heights to finalize, from 1
+    MaxRounds  int64     // Rounds tried at each height before the run stops as stalled
+    Seed       int64     // Validator keys are derived from it
+    Start      time.Time // Clock time the run starts at
+}
+
+// HeightResult is how voting at one height ended
+type HeightResult struct {
+    Height    int64  `json:"height"`
+    Round     int64  `json:"round"`     // Round the height was first decided in, -1 if it stalled
+    BlockHash string `json:"blockHash"` // Block the first honest validator decided, empty if none did
+    Decided   int    `json:"decided"`   // Honest validators that decided the height from the votes they saw
+}
+
+// Result is what a simulation run observed
+type Result struct {
+    Heights   []HeightResult       `json:"heights"`
+    Evidence  []consensus.Evidence `json:"evidence"`  // Double signing caught by honest validators, once each
+    Conflicts []string             `json:"conflicts"` // Heights at which honest validators decided different blocks
+    Honest    int                  `json:"honest"`    // Honest validators in the run
+    Elapsed   time.Duration        `json:"elapsed"`   // Clock time the run took
+}
+
+// Safe reports whether no two honest validators decided different blocks at a height
+func (r Result) Safe() bool {
+    return len(r.Conflicts) == 0
+}
+
+// Live reports whether every height was decided; honest validators that missed a decision would sync the block,
+// as a node catching up does
+func (r Result) Live() bool {
+    for _, height := range r.Heights {
+        if height.Round < 0 {
+            return false
+        }
+    }
+
+    return true
+}
+
+// simValidator is a simulated validator with its own consensus engine
+type simValidator struct {
+    address  string
+    behavior string
+    keyPair  *crypto.KeyPair
+    engine   *consensus.ProofOfPlay
+}
+
+// Simulation runs ProofOfPlay voting between validators that share a scripted clock and an in-memory transport
+type Simulation struct {
+    Config    Config
+    Clock     *Clock
+    Transport *Transport
+
+    validators []*simValidator
+    evidence   map[string]bool
+    result     Result
+}
+
+// New creates a simulation with a validator and consensus engine per spec, each engine knowing every validator
+func New(config Config) (*Simulation, error) {
+    if len(config.Validators) == 0 {
+        return nil, errors.New("at least one validator is required")
+    }
+    if config.Heights <= 0 || config.MaxRounds <= 0 {
+        return nil, errors.New("heights and max rounds must be positive")
+    }
+
+    s := &Simulation{
+        Config:    config,
+        Clock:     NewClock(config.Start),
+        Transport: &Transport{},
+        evidence:  make(map[string]bool),
+    }
+
+    honest := false
+    for i, spec := range config.Validators {
+        honest = honest || spec.Behavior == BehaviorHonest
+        switch spec.Behavior {
+        case BehaviorHonest, BehaviorSilent, BehaviorEquivocating:
+        default:
+            return nil, fmt.Errorf("validator %d: unknown behavior %q", i, spec.Behavior)
+        }
+        if spec.Stake <= 0 {
+            return nil, fmt.Errorf("validator %d: stake must be positive", i)
+        }
+
+        seed := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", config.Seed, i)))
+        privateKey := ed25519.NewKeyFromSeed(seed[:])
+        keyPair := &crypto.KeyPair{PrivateKey: privateKey, PublicKey: privateKey.Public().(ed25519.PublicKey)}
+
+        s.validators = append(s.validators, &simValidator{
+            address:  crypto.GetAddressFromPublicKey(keyPair.PublicKey),
+            behavior: spec.Behavior,
+            keyPair:  keyPair,
+        })
+    }
+
+    if !honest {
+        return nil, errors.New("at least one honest validator is required")
+    }
+
+    for _, validator := range s.validators {
+        validator.engine = consensus.NewProofOfPlay()
+        validator.engine.MinValidators = 1
+        validator.engine.Clock = s.Clock.Now
+        for i, other := range s.validators {
+            validator.engine.RegisterValidator(other.address, config.Validators[i].Stake, false)
+        }
+    }
+
+    return s, nil
+}
+
+// Run votes on each height in turn until an honest validator has decided it, moving the clock on
+// by the round timeout after each round that fails, and stops early at a height that stalls
+func (s *Simulation) Run() (Result, error) {
+    s.result = Result{Heights: []HeightResult{}, Evidence: []consensus.Evidence{}, Conflicts: []string{}, Honest: s.honest()}
+
+    for height := int64(1); height <= s.Config.Heights; height++ {
+        outcome, conflicts := HeightResult{Height: height, Round: -1}, []string(nil)
+        for round := int64(0); round < s.Config.MaxRounds; round++ {
+            if err := s.runRound(height, round); err != nil {
+                return s.result, err
+            }
+
+            outcome, conflicts = s.outcome(height, round)
+            if outcome.Decided > 0 {
+                break
+            }
+            s.Clock.Advance(time.Duration(s.validators[0].engine.RoundDuration(round)) * time.Second)
+        }
+
+        s.result.Heights = append(s.result.Heights, outcome)
+        s.result.Conflicts = append(s.result.Conflicts, conflicts...)
+        if outcome.Round < 0 {
+            break
+        }
+    }
+
+    s.result.Elapsed = s.Clock.Now().Sub(s.Config.Start)
+    return s.result, nil
+}
+
+// runRound has each validator act on its proposal in a round, then delivers votes until none are left
+func (s *Simulation) runRound(height int64, round int64) error {
+    proposer, err := s.validators[0].engine.Proposer(height, round)
+    if err != nil {
+        return fmt.Errorf("height %d round %d: %w", height, round, err)
+    }
+    proposals := s.propose(proposer, height, round)
+
+    for i, validator := range s.validators {
+        switch validator.behavior {
+        case BehaviorHonest:
+            if vote, err := validator.engine.Prevote(height, round, proposals[i], validator.keyPair); err == nil && vote != nil {
+                s.Transport.Broadcast(i, len(s.validators), *vote)
+            }
+        case BehaviorEquivocating:
+            if err := s.equivocate(i, height, round); err != nil {
+                return err
+            }
+        }
+    }
+
+    for {
+        message, ok := s.Transport.next()
+        if !ok {
+            return nil
+        }
+        s.deliver(message)
+    }
+}
+
+// propose returns the block each validator receives as the proposal of a round
+// An honest proposer sends everyone the same block, an equivocating one splits the validators between two,
+// and a silent one sends nothing, leaving everyone to prevote nil
+func (s *Simulation) propose(proposer string, height int64, round int64) []string {
+    proposals := make([]string, len(s.validators))
+
+    for _, validator := range s.validators {
+        if validator.address != proposer {
+            continue
+        }
+
+        for i := range proposals {
+            switch validator.behavior {
+            case BehaviorHonest:
+                proposals[i] = blockHash(proposer, height, round, 0)
+            case BehaviorEquivocating:
+                proposals[i] = blockHash(proposer, height, round, i%2)
+            }
+        }
+    }
+
+    return proposals
+}
+
+// equivocate has a validator prevote and precommit two blocks in a round, sending each half of the validators
+// one of the blocks first, so their first votes are split and the second ones prove the double signing
+func (s *Simulation) equivocate(from int, height int64, round int64) error {
+    validator := s.validators[from]
+
+    for _, step := range []string{consensus.StepPrevote, consensus.StepPrecommit} {
+        votes := make([]consensus.Vote, 2)
+        for fork := range votes {
+            votes[fork] = consensus.Vote{
+                Validator: validator.address,
+                Height:    height,
+                Round:     round,
+                Step:      step,
+                BlockHash: blockHash(validator.address, height, round, fork),
+                Approve:   true,
+                Timestamp: s.Clock.Now().Unix(),
+            }
+            if err := votes[fork].Sign(validator.keyPair); err != nil {
+                return err
+            }
+        }
+
+        for to := range s.validators {
+            if to == from {
+                continue
+            }
+            s.Transport.Send(from, to, votes[to%2])
+            s.Transport.Send(from, to, votes[1-to%2])
+        }
+    }
+
+    return nil
+}
+
+// deliver casts a vote with its recipient, recording any double signing it proves;
+// an honest recipient then precommits if the vote completed a prevote majority
+func (s *Simulation) deliver(message envelope) {
+    validator := s.validators[message.to]
+
+    evidence, err := validator.engine.CastVote(message.vote)
+    if err != nil {
+        return
+    }
+    if evidence != nil && validator.behavior == BehaviorHonest && !s.evidence[evidence.ID()] {
+        s.evidence[evidence.ID()] = true
+        s.result.Evidence = append(s.result.Evidence, *evidence)
+    }
+
+    if validator.behavior != BehaviorHonest || message.vote.Step != consensus.StepPrevote {
+        return
+    }
+    if vote, err := validator.engine.Precommit(message.vote.Height, message.vote.Round, validator.keyPair); err == nil && vote != nil {
+        s.Transport.Broadcast(message.to, len(s.validators), *vote)
+    }
+}
+
+// outcome returns how the honest validators stand at a height after a round, and any blocks they decided
+// that differ from the first one decided
+func (s *Simulation) outcome(height int64, round int64) (HeightResult, []string) {
+    outcome := HeightResult{Height: height, Round: -1}
+    conflicts := []string{}
+
+    for _, validator := range s.validators {
+        if validator.behavior != BehaviorHonest {
+            continue
+        }
+        decision, decided := validator.engine.GetDecision(height)
+        if !decided {
+            continue
+        }
+
+        outcome.Decided++
+        if outcome.BlockHash == "" {
+            outcome.BlockHash = decision.BlockHash
+            continue
+        }
+        if decision.BlockHash != outcome.BlockHash {
+            conflicts = append(conflicts, fmt.Sprintf("height %d: %s and %s", height, outcome.BlockHash, decision.BlockHash))
+        }
+    }
+    if outcome.Decided > 0 {
+        outcome.Round = round
+    }
+
+    return outcome, conflicts
+}
+
+// honest returns how many validators are honest
+func (s *Simulation) honest() int {
+    count := 0
+    for _, validator := range s.validators {
+        if validator.behavior == BehaviorHonest {
+            count++
+        }
+    }
+
+    return count
+}
+
+// blockHash returns the hash of a block a proposer proposes in a round; an equivocating proposer's second block is fork 1
+func blockHash(proposer string, height int64, round int64, fork int) string {
+    return crypto.HashData([]byte(fmt.Sprintf("%s:%d:%d:%d", proposer, height, round, fork)))
+}
@@ -0,0 +1,111 @@
+package simulation
+
+import (
+    "testing"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/token"
+)
+
+// run builds and runs a simulation of validators with the given behaviors and equal stakes
+func run(t *testing.T, heights int64, behaviors ...string) Result {
+    validators := make([]ValidatorSpec, len(behaviors))
+    for i, behavior := range behaviors {
+        validators[i] = ValidatorSpec{Behavior: behavior, Stake: 1000 * token.ILYZ}
+    }
+
+    s, err := New(Config{
+        Validators: validators,
+        Heights:    heights,
+        MaxRounds:  8,
+        Seed:       1,
+        Start:      time.Unix(1700000000, 0),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    result, err := s.Run()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return result
+}
+
+// TestHonestValidatorsDecideEveryHeight checks that honest validators alone decide every height in its first round
+func TestHonestValidatorsDecideEveryHeight(t *testing.T) {
+    result := run(t, 5, BehaviorHonest, BehaviorHonest, BehaviorHonest, BehaviorHonest)
+
+    if !result.Live() || !result.Safe() {
+        t.Fatalf("live %v, safe %v: %+v", result.Live(), result.Safe(), result.Heights)
+    }
+    if len(result.Heights) != 5 {
+        t.Fatalf("decided %d heights, want 5", len(result.Heights))
+    }
+    for _, height := range result.Heights {
+        if height.Round != 0 || height.Decided != 4 {
+            t.Fatalf("height %d decided in round %d by %d validators, want round 0 by all 4", height.Height, height.Round, height.Decided)
+        }
+    }
+    if len(result.Evidence) != 0 {
+        t.Fatalf("honest validators produced %d pieces of evidence", len(result.Evidence))
+    }
+}
+
+// TestSilentMinorityDelaysButDecides checks that a silent validator short of a third of the stake never stops
+// the others from deciding, at worst costing the rounds it was meant to propose in
+func TestSilentMinorityDelaysButDecides(t *testing.T) {
+    result := run(t, 8, BehaviorHonest, BehaviorHonest, BehaviorHonest, BehaviorSilent)
+
+    if !result.Live() || !result.Safe() {
+        t.Fatalf("live %v, safe %v: %+v", result.Live(), result.Safe(), result.Heights)
+    }
+    if len(result.Heights) != 8 {
+        t.Fatalf("decided %d heights, want 8", len(result.Heights))
+    }
+}
+
+// TestSilentThirdStalls checks that honest validators holding no more than two thirds of the stake stall
+// rather than decide without a majority
+func TestSilentThirdStalls(t *testing.T) {
+    result := run(t, 3, BehaviorHonest, BehaviorHonest, BehaviorSilent, BehaviorSilent)
+
+    if result.Live() {
+        t.Fatalf("half the stake decided without the rest: %+v", result.Heights)
+    }
+    if !result.Safe() {
+        t.Fatalf("stalled run decided conflicting blocks: %v", result.Conflicts)
+    }
+}
+
+// TestEquivocatorIsCaughtAndCannotSplitHonestValidators checks that an equivocating validator short of a third
+// of the stake never makes honest validators decide different blocks, and that its double signing is caught
+func TestEquivocatorIsCaughtAndCannotSplitHonestValidators(t *testing.T) {
+    result := run(t, 6, BehaviorHonest, BehaviorHonest, BehaviorHonest, BehaviorEquivocating)
+
+    if !result.Safe() {
+        t.Fatalf("honest validators decided conflicting blocks: %v", result.Conflicts)
+    }
+    if !result.Live() {
+        t.Fatalf("equivocating minority stalled the run: %+v", result.Heights)
+    }
+    if len(result.Evidence) == 0 {
+        t.Fatal("double signing went uncaught")
+    }
+}
+
+// TestRunsAreDeterministic checks that runs with the same configuration produce the same result
+func TestRunsAreDeterministic(t *testing.T) {
+    first := run(t, 4, BehaviorHonest, BehaviorHonest, BehaviorHonest, BehaviorEquivocating, BehaviorSilent)
+    second := run(t, 4, BehaviorHonest, BehaviorHonest, BehaviorHonest, BehaviorEquivocating, BehaviorSilent)
+
+    if len(first.Heights) != len(second.Heights) || len(first.Evidence) != len(second.Evidence) || first.Elapsed != second.Elapsed {
+        t.Fatalf("runs differ: %+v and %+v", first, second)
+    }
+    for i := range first.Heights {
+        if first.Heights[i] != second.Heights[i] {
+            t.Fatalf("height %d differs: %+v and %+v", first.Heights[i].Height, first.Heights[i], second.Heights[i])
+        }
+    }
+}
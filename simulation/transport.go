@@ -0,0 +1,54 @@
+package simulation
+
+import "github.com/txaimhawj/chulubmeadditional-files/consensus"
+
+// envelope is a vote on its way to one validator
+type envelope struct {
+    from int
+    to   int
+    vote consensus.Vote
+}
+
+// Transport carries votes between simulated validators in memory, delivering them in the order they were sent
+type Transport struct {
+    queue []envelope
+
+    // Drop reports whether a vote from one validator to another is lost, nil to deliver everything;
+    // validators are identified by their index in the configuration
+    Drop func(from int, to int, vote consensus.Vote) bool
+
+    // Votes sent and votes dropped so far
+    Sent    int
+    Dropped int
+}
+
+// Send queues a vote from one validator to another
+func (t *Transport) Send(from int, to int, vote consensus.Vote) {
+    t.Sent++
+    if t.Drop != nil && t.Drop(from, to, vote) {
+        t.Dropped++
+        return
+    }
+
+    t.queue = append(t.queue, envelope{from: from, to: to, vote: vote})
+}
+
+// Broadcast queues a vote from one validator to each of the others, in index order
+func (t *Transport) Broadcast(from int, validators int, vote consensus.Vote) {
+    for to := 0; to < validators; to++ {
+        if to != from {
+            t.Send(from, to, vote)
+        }
+    }
+}
+
+// next removes and returns the vote sent earliest, if any are queued
+func (t *Transport) next() (envelope, bool) {
+    if len(t.queue) == 0 {
+        return envelope{}, false
+    }
+
+    message := t.queue[0]
+    t.queue = t.queue[1:]
+    return message, true
+}
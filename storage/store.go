@@ -0,0 +1,144 @@
+package storage
+
+import (
+    "encoding/json"
+    "errors"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// ErrNotFound is returned when a key is not in the store
+var ErrNotFound = errors.New("key not found")
+
+// Store is the key-value store a node keeps its module state in
+type Store interface {
+    // Get returns the value stored under a key, or ErrNotFound
+    Get(key string) ([]byte, error)
+
+    // Put stores a value under a key
+    Put(key string, value []byte) error
+
+    // Delete removes a key; deleting a missing key is not an error
+    Delete(key string) error
+
+    // Keys returns the keys with the given prefix in sorted order
+    Keys(prefix string) ([]string, error)
+
+    // Flush makes all previous writes durable
+    Flush() error
+}
+
+// MemoryStore is a Store held entirely in memory
+type MemoryStore struct {
+    data map[string][]byte
+
+    // Mutex for thread safety
+    mutex sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{
+        data: make(map[string][]byte),
+    }
+}
+
+// Get returns the value stored under a key
+func (ms *MemoryStore) Get(key string) ([]byte, error) {
+    ms.mutex.RLock()
+    defer ms.mutex.RUnlock()
+
+    value, exists := ms.data[key]
+    if !exists {
+        return nil, ErrNotFound
+    }
+
+    return append([]byte(nil), value...), nil
+}
+
+// Put stores a value under a key
+func (ms *MemoryStore) Put(key string, value []byte) error {
+    ms.mutex.Lock()
+    defer ms.mutex.Unlock()
+
+    ms.data[key] = append([]byte(nil), value...)
+    return nil
+}
+
+// Delete removes a key
+func (ms *MemoryStore) Delete(key string) error {
+    ms.mutex.Lock()
+    defer ms.mutex.Unlock()
+
+    delete(ms.data, key)
+    return nil
+}
+
+// Keys returns the keys with the given prefix in sorted order
+func (ms *MemoryStore) Keys(prefix string) ([]string, error) {
+    ms.mutex.RLock()
+    defer ms.mutex.RUnlock()
+
+    keys := []string{}
+    for key := range ms.data {
+        if strings.HasPrefix(key, prefix) {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+
+    return keys, nil
+}
+
+// Flush does nothing; a memory store is never durable
+func (ms *MemoryStore) Flush() error {
+    return nil
+}
+
+// FileStore is a Store kept in memory and written to a single file on Flush
+type FileStore struct {
+    *MemoryStore
+
+    path string
+}
+
+// OpenFileStore opens the store at path, creating it on the first Flush if it doesn't exist
+func OpenFileStore(path string) (*FileStore, error) {
+    store := &FileStore{
+        MemoryStore: NewMemoryStore(),
+        path:        path,
+    }
+
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return store, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if err := json.Unmarshal(data, &store.data); err != nil {
+        return nil, err
+    }
+
+    return store, nil
+}
+
+// Flush writes the store to its file, replacing the previous copy atomically
+func (fs *FileStore) Flush() error {
+    fs.mutex.RLock()
+    data, err := json.Marshal(fs.data)
+    fs.mutex.RUnlock()
+    if err != nil {
+        return err
+    }
+
+    tempFile := fs.path + ".tmp"
+    if err := os.WriteFile(tempFile, data, 0644); err != nil {
+        return err
+    }
+
+    return os.Rename(tempFile, fs.path)
+}
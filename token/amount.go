@@ -0,0 +1,202 @@
+package token
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "math/big"
+    "strconv"
+    "strings"
+)
+
+// Decimals is how many decimal places of ILYZ the smallest unit resolves
+const Decimals = 8
+
+// ILYZ is one token in the smallest unit
+const ILYZ Amount = 100_000_000
+
+// Amount is a quantity of ILYZ counted in the smallest unit, so every node adds and compares amounts exactly
+// It is signed, so a debit past zero shows as a negative balance instead of wrapping around
+// JSON carries amounts as exact decimal ILYZ, such as 12.5, the same text float amounts were encoded as
+type Amount int64
+
+// ParseAmount parses a decimal ILYZ quantity such as "12.5" or "-0.00000001"
+func ParseAmount(s string) (Amount, error) {
+    text := strings.TrimSpace(s)
+    negative := strings.HasPrefix(text, "-")
+    text = strings.TrimPrefix(strings.TrimPrefix(text, "-"), "+")
+
+    whole, fraction, _ := strings.Cut(text, ".")
+    if whole == "" && fraction == "" {
+        return 0, fmt.Errorf("invalid amount %q", s)
+    }
+    if len(fraction) > Decimals {
+        return 0, fmt.Errorf("amount %q has more than %d decimal places", s, Decimals)
+    }
+    for _, digits := range []string{whole, fraction} {
+        for _, c := range digits {
+            if c < '0' || c > '9' {
+                return 0, fmt.Errorf("invalid amount %q", s)
+            }
+        }
+    }
+
+    units := whole + fraction + strings.Repeat("0", Decimals-len(fraction))
+    value, err := strconv.ParseInt(units, 10, 64)
+    if err != nil {
+        return 0, fmt.Errorf("amount %q is out of range", s)
+    }
+    if negative {
+        value = -value
+    }
+
+    return Amount(value), nil
+}
+
+// AmountFromFloat converts a float ILYZ quantity to the nearest amount
+// Floats resolve every unit only up to about 90 million ILYZ; larger quantities should be parsed from text
+func AmountFromFloat(f float64) Amount {
+    if math.IsNaN(f) {
+        return 0
+    }
+
+    units := math.Round(f * float64(ILYZ))
+    switch {
+    case units >= math.MaxInt64:
+        return math.MaxInt64
+    case units <= math.MinInt64:
+        return math.MinInt64
+    }
+
+    return Amount(units)
+}
+
+// DataAmount reads an amount from a transaction data value, which holds a float once decoded from JSON
+// and may hold an amount, an integer count of ILYZ or decimal text when built locally
+func DataAmount(value interface{}) (Amount, bool) {
+    switch v := value.(type) {
+    case Amount:
+        return v, true
+    case float64:
+        return AmountFromFloat(v), true
+    case int:
+        return Amount(v) * ILYZ, true
+    case int64:
+        return Amount(v) * ILYZ, true
+    case json.Number:
+        amount, err := ParseAmount(v.String())
+        return amount, err == nil
+    case string:
+        amount, err := ParseAmount(v)
+        return amount, err == nil
+    }
+
+    return 0, false
+}
+
+// String formats an amount as decimal ILYZ without trailing zeros, such as "12.5"
+func (a Amount) String() string {
+    sign := ""
+    units := uint64(a)
+    if a < 0 {
+        sign = "-"
+        units = uint64(-a)
+    }
+
+    whole := units / uint64(ILYZ)
+    fraction := units % uint64(ILYZ)
+    if fraction == 0 {
+        return fmt.Sprintf("%s%d", sign, whole)
+    }
+
+    digits := strings.TrimRight(fmt.Sprintf("%0*d", Decimals, fraction), "0")
+    return fmt.Sprintf("%s%d.%s", sign, whole, digits)
+}
+
+// Float returns an amount in ILYZ as a float, for weights, ratios and display
+func (a Amount) Float() float64 {
+    return float64(a) / float64(ILYZ)
+}
+
+// MulRate returns an amount multiplied by a rate, such as a fee rate or a share, rounded toward zero
+// The rate's exact binary value is used, so every node computes the same result
+func (a Amount) MulRate(rate float64) Amount {
+    if math.IsNaN(rate) || math.IsInf(rate, 0) {
+        return 0
+    }
+
+    product := new(big.Rat).SetFloat64(rate)
+    product.Mul(product, new(big.Rat).SetInt64(int64(a)))
+    units := new(big.Int).Quo(product.Num(), product.Denom())
+    if !units.IsInt64() {
+        if units.Sign() > 0 {
+            return math.MaxInt64
+        }
+        return math.MinInt64
+    }
+
+    return Amount(units.Int64())
+}
+
+// Scale returns an amount multiplied by num/den, rounded toward zero, as when splitting a total in proportion to shares
+func (a Amount) Scale(num Amount, den Amount) Amount {
+    if den == 0 {
+        return 0
+    }
+
+    units := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(num)))
+    units.Quo(units, big.NewInt(int64(den)))
+    if !units.IsInt64() {
+        if units.Sign() > 0 {
+            return math.MaxInt64
+        }
+        return math.MinInt64
+    }
+
+    return Amount(units.Int64())
+}
+
+// Set parses an amount from decimal ILYZ text, so amounts can be command-line flags
+func (a *Amount) Set(s string) error {
+    amount, err := ParseAmount(s)
+    if err != nil {
+        return err
+    }
+
+    *a = amount
+    return nil
+}
+
+// MarshalJSON encodes an amount as a decimal ILYZ number
+func (a Amount) MarshalJSON() ([]byte, error) {
+    return []byte(a.String()), nil
+}
+
+// UnmarshalJSON decodes an amount from a decimal ILYZ number or string
+func (a *Amount) UnmarshalJSON(data []byte) error {
+    text := string(data)
+    if text == "null" {
+        return nil
+    }
+    if unquoted, err := strconv.Unquote(text); err == nil {
+        text = unquoted
+    }
+
+    // Exponents, as in 1e3, are still exact decimal values
+    if strings.ContainsAny(text, "eE") {
+        rat, ok := new(big.Rat).SetString(text)
+        if !ok {
+            return fmt.Errorf("invalid amount %s", data)
+        }
+        text = rat.FloatString(Decimals + 1)
+        text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+    }
+
+    amount, err := ParseAmount(text)
+    if err != nil {
+        return err
+    }
+
+    *a = amount
+    return nil
+}
@@ -0,0 +1,53 @@
+package token
+
+import "errors"
+
+// NativeAsset is the ID of ILYZ, the asset fees, stakes and rewards are paid in; its balances are the ledger's Balances
+const NativeAsset = "ILYZ"
+
+// Asset describes a currency the ledger holds beside ILYZ, such as a cosmetic currency earned in game
+// Only its minters create it, and holders may send it to each other only if it is transferable
+type Asset struct {
+    ID           string   `json:"id"`
+    Name         string   `json:"name"`
+    Transferable bool     `json:"transferable"`        // Whether holders may send it to other accounts
+    Minters      []string `json:"minters"`             // Addresses allowed to mint it, such as game servers
+    MaxSupply    Amount   `json:"maxSupply,omitempty"` // Most that may exist at once, 0 for no limit
+}
+
+// Validate checks that an asset has an ID other than ILYZ's, at least one minter and a supply limit that isn't negative
+func (a Asset) Validate() error {
+    if a.ID == "" {
+        return errors.New("asset ID is required")
+    }
+
+    if a.ID == NativeAsset {
+        return errors.New("ILYZ is the native asset and can't be redefined")
+    }
+
+    if len(a.Minters) == 0 {
+        return errors.New("asset has no minters")
+    }
+
+    if a.MaxSupply < 0 {
+        return errors.New("asset supply limit cannot be negative")
+    }
+
+    return nil
+}
+
+// CanMint reports whether an address is one of the asset's minters
+func (a Asset) CanMint(address string) bool {
+    for _, minter := range a.Minters {
+        if minter == address {
+            return true
+        }
+    }
+
+    return false
+}
+
+// CanSupply reports whether minting an amount keeps the asset within its supply limit, given the current supply
+func (a Asset) CanSupply(supply Amount, amount Amount) bool {
+    return a.MaxSupply == 0 || supply+amount <= a.MaxSupply
+}
@@ -0,0 +1,134 @@
+package token
+
+// Default emission circuit breaker settings
+const (
+    DefaultHourlyCeiling = 5_000_000 * ILYZ  // Most game rewards and yield minted in a clock hour
+    DefaultDailyCeiling  = 50_000_000 * ILYZ // Most game rewards and yield minted in a UTC day
+    DefaultThrottleStart = 0.8               // Share of a ceiling from which rewards are scaled down
+)
+
+// Lengths of the breaker's windows in seconds
+const (
+    hourDuration = int64(60 * 60)
+    dayDuration  = int64(24 * 60 * 60)
+)
+
+// EmissionWindow is what was minted in one hour or day
+type EmissionWindow struct {
+    Start  int64  `json:"start"` // Unix time the window started
+    Minted Amount `json:"minted"`
+}
+
+// CircuitBreaker caps what game rewards and yield mint per hour and per day, so an exploit farming rewards
+// can't drain the yearly budget in days; rewards are scaled down linearly once a window passes the throttle share
+// of its ceiling, reaching nothing at the ceiling
+type CircuitBreaker struct {
+    HourlyCeiling Amount  // 0 disables the hourly ceiling
+    DailyCeiling  Amount  // 0 disables the daily ceiling
+    ThrottleStart float64 // Share of a ceiling from which rewards are scaled down
+
+    Hour EmissionWindow
+    Day  EmissionWindow
+}
+
+// BreakerStatus is how close the current hour and day are to their ceilings
+type BreakerStatus struct {
+    HourlyCeiling Amount  `json:"hourlyCeiling"`
+    HourlyMinted  Amount  `json:"hourlyMinted"`
+    DailyCeiling  Amount  `json:"dailyCeiling"`
+    DailyMinted   Amount  `json:"dailyMinted"`
+    Throttle      float64 `json:"throttle"` // Share of a reward currently paid, 1 when neither window is throttled
+}
+
+// NewCircuitBreaker creates a breaker with the default ceilings
+func NewCircuitBreaker() *CircuitBreaker {
+    return &CircuitBreaker{
+        HourlyCeiling: DefaultHourlyCeiling,
+        DailyCeiling:  DefaultDailyCeiling,
+        ThrottleStart: DefaultThrottleStart,
+    }
+}
+
+// Limit scales a reward down by how close the current hour and day are to their ceilings,
+// and cuts it to what either has left
+func (cb *CircuitBreaker) Limit(now int64, amount Amount) Amount {
+    cb.roll(now)
+
+    amount = amount.MulRate(cb.throttle())
+    amount = withinCeiling(amount, cb.HourlyCeiling, cb.Hour.Minted)
+    amount = withinCeiling(amount, cb.DailyCeiling, cb.Day.Minted)
+
+    return amount
+}
+
+// Record counts a minted reward against the current hour and day
+func (cb *CircuitBreaker) Record(now int64, amount Amount) {
+    cb.roll(now)
+
+    cb.Hour.Minted += amount
+    cb.Day.Minted += amount
+}
+
+// Status returns how close the current hour and day are to their ceilings
+func (cb *CircuitBreaker) Status(now int64) BreakerStatus {
+    cb.roll(now)
+
+    return BreakerStatus{
+        HourlyCeiling: cb.HourlyCeiling,
+        HourlyMinted:  cb.Hour.Minted,
+        DailyCeiling:  cb.DailyCeiling,
+        DailyMinted:   cb.Day.Minted,
+        Throttle:      cb.throttle(),
+    }
+}
+
+// throttle returns the share of a reward paid, the lower of the two windows'
+func (cb *CircuitBreaker) throttle() float64 {
+    hourly := throttleShare(cb.HourlyCeiling, cb.Hour.Minted, cb.ThrottleStart)
+    daily := throttleShare(cb.DailyCeiling, cb.Day.Minted, cb.ThrottleStart)
+    if daily < hourly {
+        return daily
+    }
+
+    return hourly
+}
+
+// roll starts new windows once the hour or day a time falls in has moved on
+func (cb *CircuitBreaker) roll(now int64) {
+    if hour := now - now%hourDuration; hour != cb.Hour.Start {
+        cb.Hour = EmissionWindow{Start: hour}
+    }
+    if day := now - now%dayDuration; day != cb.Day.Start {
+        cb.Day = EmissionWindow{Start: day}
+    }
+}
+
+// throttleShare returns the share of a reward a window pays: all of it below the throttle share of its ceiling,
+// then linearly less down to nothing at the ceiling
+func throttleShare(ceiling Amount, minted Amount, start float64) float64 {
+    if ceiling <= 0 {
+        return 1
+    }
+
+    used := minted.Float() / ceiling.Float()
+    if used <= start {
+        return 1
+    }
+    if used >= 1 || start >= 1 {
+        return 0
+    }
+
+    return (1 - used) / (1 - start)
+}
+
+// withinCeiling cuts an amount to what a window has left before its ceiling
+func withinCeiling(amount Amount, ceiling Amount, minted Amount) Amount {
+    if ceiling > 0 && amount > ceiling-minted {
+        amount = ceiling - minted
+    }
+    if amount < 0 {
+        amount = 0
+    }
+
+    return amount
+}
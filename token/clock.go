@@ -0,0 +1,80 @@
+package token
+
+import (
+    "sync"
+    "time"
+)
+
+// Clock tells time-dependent economics logic the current Unix time
+// Chain execution reads the time of the block being applied, so replaying the chain gives the same results,
+// while everything else reads the wall clock
+type Clock interface {
+    Now() int64
+}
+
+// SystemClock is the wall clock
+type SystemClock struct{}
+
+// Now returns the wall clock's Unix time
+func (SystemClock) Now() int64 {
+    return time.Now().Unix()
+}
+
+// FixedClock is a clock stopped at a Unix time, for replaying or simulating economics at a chosen time
+type FixedClock int64
+
+// Now returns the time the clock is stopped at
+func (c FixedClock) Now() int64 {
+    return int64(c)
+}
+
+// BlockClock reads the timestamp of the block the chain is executing while it executes one, and the wall clock otherwise
+// It is safe for concurrent use
+type BlockClock struct {
+    timestamp int64 // Block being executed, 0 between blocks
+
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// NewBlockClock creates a block clock reading the wall clock until a block is executed
+func NewBlockClock() *BlockClock {
+    return &BlockClock{}
+}
+
+// Now returns the timestamp of the block being executed, or the wall clock's time between blocks
+func (c *BlockClock) Now() int64 {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    if c.timestamp != 0 {
+        return c.timestamp
+    }
+
+    return time.Now().Unix()
+}
+
+// Enter starts executing a block with a timestamp
+func (c *BlockClock) Enter(timestamp int64) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    c.timestamp = timestamp
+}
+
+// Leave finishes executing a block, returning the clock to the wall clock
+func (c *BlockClock) Leave() {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    c.timestamp = 0
+}
+
+// ClockNow returns a clock's time, the wall clock's if there is no clock
+func ClockNow(clock Clock) int64 {
+    if clock == nil {
+        return time.Now().Unix()
+    }
+
+    return clock.Now()
+}
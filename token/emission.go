@@ -0,0 +1,120 @@
+package token
+
+// Default emission controller settings
+const (
+    DefaultEmissionWindow   = 7 * 24 * 60 * 60 // Seconds of rewards the minting rate is measured over
+    DefaultEmissionInterval = 60 * 60          // Seconds between adjustments of the reward multiplier
+    DefaultEmissionGain     = 0.25             // Share of the gap to the ideal multiplier each adjustment closes
+    MinRewardMultiplier     = 0.01
+    MaxRewardMultiplier     = 10.0
+)
+
+// emissionSample is the reward a match earned before the multiplier was applied
+type emissionSample struct {
+    time   int64
+    amount Amount
+}
+
+// EmissionController steers game rewards so the yearly budget is spent evenly over the rest of the year
+// It measures the rewards matches earned before the multiplier over a rolling window, which is the rate demand
+// would mint at, and moves the multiplier toward the one that would bring that rate to the remaining budget
+// spread over the remaining time; busy periods earn less per match and quiet ones more, and the cap is approached
+// gradually instead of rewards stopping once it is reached
+type EmissionController struct {
+    Window   int64   // Seconds of rewards the rate is measured over
+    Interval int64   // Seconds between adjustments
+    Gain     float64 // Share of the gap to the ideal multiplier each adjustment closes
+
+    // Multiplier applied to game rewards, 1 until the first adjustment
+    Multiplier float64
+
+    samples    []emissionSample
+    started    int64
+    lastAdjust int64
+}
+
+// NewEmissionController creates a controller with the default settings and a multiplier of 1
+func NewEmissionController() *EmissionController {
+    return &EmissionController{
+        Window:     DefaultEmissionWindow,
+        Interval:   DefaultEmissionInterval,
+        Gain:       DefaultEmissionGain,
+        Multiplier: 1,
+    }
+}
+
+// Record adds the reward a match earned before the multiplier, dropping rewards older than the window
+func (ec *EmissionController) Record(now int64, base Amount) {
+    if ec.started == 0 {
+        ec.started = now
+    }
+
+    ec.samples = append(ec.samples, emissionSample{time: now, amount: base})
+    ec.prune(now)
+}
+
+// DemandRate returns the ILYZ per second matches earned before the multiplier over the window,
+// or over the time since the first reward while that is shorter, but at least an interval
+func (ec *EmissionController) DemandRate(now int64) float64 {
+    ec.prune(now)
+
+    total := Amount(0)
+    for _, sample := range ec.samples {
+        total += sample.amount
+    }
+
+    span := ec.Window
+    if elapsed := now - ec.started; elapsed < span {
+        span = elapsed
+    }
+    if span < ec.Interval {
+        span = ec.Interval
+    }
+    if span <= 0 {
+        return 0
+    }
+
+    return total.Float() / float64(span)
+}
+
+// Adjust moves the multiplier toward the one that spends a remaining budget evenly over the seconds left
+// at the measured demand, at most once an interval and not before an interval of rewards has been measured, and returns it
+// With nothing left to spend the multiplier drops to the minimum at once
+func (ec *EmissionController) Adjust(now int64, remaining Amount, secondsLeft int64) float64 {
+    if remaining <= 0 {
+        ec.Multiplier = MinRewardMultiplier
+        return ec.Multiplier
+    }
+    if ec.started == 0 || now-ec.started < ec.Interval || now-ec.lastAdjust < ec.Interval {
+        return ec.Multiplier
+    }
+    ec.lastAdjust = now
+
+    demand := ec.DemandRate(now)
+    if demand <= 0 {
+        return ec.Multiplier
+    }
+    if secondsLeft < 1 {
+        secondsLeft = 1
+    }
+
+    ideal := remaining.Float() / float64(secondsLeft) / demand
+    ec.Multiplier += ec.Gain * (ideal - ec.Multiplier)
+    if ec.Multiplier < MinRewardMultiplier {
+        ec.Multiplier = MinRewardMultiplier
+    }
+    if ec.Multiplier > MaxRewardMultiplier {
+        ec.Multiplier = MaxRewardMultiplier
+    }
+
+    return ec.Multiplier
+}
+
+// prune drops rewards older than the window
+func (ec *EmissionController) prune(now int64) {
+    expired := 0
+    for expired < len(ec.samples) && ec.samples[expired].time <= now-ec.Window {
+        expired++
+    }
+    ec.samples = ec.samples[expired:]
+}
@@ -0,0 +1,76 @@
+package token
+
+import "errors"
+
+// Default fee market settings
+const (
+    DefaultTargetBlockTransactions = 50          // Transactions per block the base fee steers toward
+    DefaultBaseFeeChangeRate       = 0.125       // Most the base fee moves in one block
+    DefaultMinBaseFee              = ILYZ / 1000 // 0.001 ILYZ
+)
+
+// FeeMarket sets a base fee every transaction must pay at least, raised while blocks are fuller than the target
+// and lowered while they are emptier, by at most the change rate per block, like EIP-1559's
+// The base fee above the floor is burned, so flooding the chain costs the spammer without paying producers more
+type FeeMarket struct {
+    TargetTransactions int     `json:"targetTransactions"` // Transactions per block the base fee steers toward
+    MaxChangeRate      float64 `json:"maxChangeRate"`      // Most the base fee moves in one block (12.5% = 0.125)
+    MinBaseFee         Amount  `json:"minBaseFee"`         // Floor of the base fee
+}
+
+// DefaultFeeMarket returns a market targeting 50 transactions a block, moving 12.5% a block from a 0.001 ILYZ floor
+func DefaultFeeMarket() FeeMarket {
+    return FeeMarket{
+        TargetTransactions: DefaultTargetBlockTransactions,
+        MaxChangeRate:      DefaultBaseFeeChangeRate,
+        MinBaseFee:         DefaultMinBaseFee,
+    }
+}
+
+// Validate checks that a market has a positive target, a change rate between 0 and 1 and a positive floor,
+// since a base fee of nothing can't grow
+func (m FeeMarket) Validate() error {
+    if m.TargetTransactions <= 0 {
+        return errors.New("fee market target must be at least one transaction")
+    }
+
+    if m.MaxChangeRate <= 0 || m.MaxChangeRate > 1 {
+        return errors.New("base fee change rate must be above 0 and at most 1")
+    }
+
+    if m.MinBaseFee <= 0 {
+        return errors.New("minimum base fee must be positive")
+    }
+
+    return nil
+}
+
+// NextBaseFee returns the base fee of the block after one with a base fee that held a number of transactions
+// A block without a base fee, such as genesis, is followed by the floor
+func (m FeeMarket) NextBaseFee(baseFee Amount, transactions int) Amount {
+    if baseFee <= 0 {
+        return m.MinBaseFee
+    }
+
+    // A block twice the target or more raises the fee by the full change rate, an empty one lowers it by as much
+    fullness := float64(transactions-m.TargetTransactions) / float64(m.TargetTransactions)
+    if fullness > 1 {
+        fullness = 1
+    }
+
+    next := baseFee + baseFee.MulRate(fullness*m.MaxChangeRate)
+    if next < m.MinBaseFee {
+        return m.MinBaseFee
+    }
+
+    return next
+}
+
+// Surplus returns the part of a base fee above the floor, burned for every transaction a block holds
+func (m FeeMarket) Surplus(baseFee Amount) Amount {
+    if baseFee <= m.MinBaseFee {
+        return 0
+    }
+
+    return baseFee - m.MinBaseFee
+}
@@ -0,0 +1,194 @@
+package token
+
+import (
+    "errors"
+    "fmt"
+)
+
+// Errors returned by ledger operations
+var (
+    ErrInsufficientBalance = errors.New("insufficient balance")
+    ErrNegativeAmount      = errors.New("amount cannot be negative")
+)
+
+// Ledger holds account balances and the supply minted into them
+// Tokens only enter through Mint and only leave through Burn, so the supply always matches what accounts were credited;
+// tokens modules lock away from accounts, such as stakes and escrows, still count toward it
+// ILYZ is kept in Balances and Supply; other assets are kept apart by asset ID and follow the same rules
+type Ledger struct {
+    Balances map[string]Amount `json:"balances"`
+    Supply   Amount            `json:"supply"`
+
+    // Balances of assets other than ILYZ, by asset and then account
+    AssetBalances map[string]map[string]Amount `json:"assetBalances,omitempty"`
+
+    // Supply of each asset other than ILYZ
+    AssetSupply map[string]Amount `json:"assetSupply,omitempty"`
+}
+
+// NewLedger creates an empty ledger
+func NewLedger() Ledger {
+    return Ledger{
+        Balances: make(map[string]Amount),
+    }
+}
+
+// Copy returns a deep copy of the ledger
+func (l *Ledger) Copy() Ledger {
+    copied := NewLedger()
+    for address, balance := range l.Balances {
+        copied.Balances[address] = balance
+    }
+    copied.Supply = l.Supply
+
+    if l.AssetBalances != nil {
+        copied.AssetBalances = make(map[string]map[string]Amount, len(l.AssetBalances))
+        for asset, balances := range l.AssetBalances {
+            copied.AssetBalances[asset] = make(map[string]Amount, len(balances))
+            for address, balance := range balances {
+                copied.AssetBalances[asset][address] = balance
+            }
+        }
+    }
+    if l.AssetSupply != nil {
+        copied.AssetSupply = make(map[string]Amount, len(l.AssetSupply))
+        for asset, supply := range l.AssetSupply {
+            copied.AssetSupply[asset] = supply
+        }
+    }
+
+    return copied
+}
+
+// Balance returns an account's balance
+func (l *Ledger) Balance(address string) Amount {
+    return l.Balances[address]
+}
+
+// Mint creates tokens in an account, adding them to the supply
+func (l *Ledger) Mint(to string, amount Amount) error {
+    if amount < 0 {
+        return ErrNegativeAmount
+    }
+
+    l.Balances[to] += amount
+    l.Supply += amount
+    return nil
+}
+
+// Burn destroys tokens an account holds, taking them out of the supply
+func (l *Ledger) Burn(from string, amount Amount) error {
+    if err := l.checkDebit(from, amount); err != nil {
+        return err
+    }
+
+    l.Balances[from] -= amount
+    l.Supply -= amount
+    return nil
+}
+
+// Transfer moves tokens from one account to another, leaving the supply unchanged
+func (l *Ledger) Transfer(from string, to string, amount Amount) error {
+    if err := l.checkDebit(from, amount); err != nil {
+        return err
+    }
+
+    l.Balances[from] -= amount
+    l.Balances[to] += amount
+    return nil
+}
+
+// AssetBalance returns an account's balance of an asset, ILYZ included
+func (l *Ledger) AssetBalance(asset string, address string) Amount {
+    if asset == NativeAsset {
+        return l.Balances[address]
+    }
+
+    return l.AssetBalances[asset][address]
+}
+
+// AssetHoldings returns an account's balance of every asset other than ILYZ it holds, by asset
+func (l *Ledger) AssetHoldings(address string) map[string]Amount {
+    holdings := make(map[string]Amount)
+    for asset, balances := range l.AssetBalances {
+        if balance := balances[address]; balance != 0 {
+            holdings[asset] = balance
+        }
+    }
+
+    return holdings
+}
+
+// MintAsset creates an asset in an account, adding it to the asset's supply
+func (l *Ledger) MintAsset(asset string, to string, amount Amount) error {
+    if asset == NativeAsset {
+        return l.Mint(to, amount)
+    }
+    if amount < 0 {
+        return ErrNegativeAmount
+    }
+
+    if l.AssetBalances == nil {
+        l.AssetBalances = make(map[string]map[string]Amount)
+        l.AssetSupply = make(map[string]Amount)
+    }
+    if l.AssetBalances[asset] == nil {
+        l.AssetBalances[asset] = make(map[string]Amount)
+    }
+
+    l.AssetBalances[asset][to] += amount
+    l.AssetSupply[asset] += amount
+    return nil
+}
+
+// BurnAsset destroys an asset an account holds, taking it out of the asset's supply
+func (l *Ledger) BurnAsset(asset string, from string, amount Amount) error {
+    if asset == NativeAsset {
+        return l.Burn(from, amount)
+    }
+    if err := l.checkAssetDebit(asset, from, amount); err != nil {
+        return err
+    }
+
+    l.AssetBalances[asset][from] -= amount
+    l.AssetSupply[asset] -= amount
+    return nil
+}
+
+// TransferAsset moves an asset from one account to another, leaving its supply unchanged
+func (l *Ledger) TransferAsset(asset string, from string, to string, amount Amount) error {
+    if asset == NativeAsset {
+        return l.Transfer(from, to, amount)
+    }
+    if err := l.checkAssetDebit(asset, from, amount); err != nil {
+        return err
+    }
+
+    l.AssetBalances[asset][from] -= amount
+    l.AssetBalances[asset][to] += amount
+    return nil
+}
+
+// checkAssetDebit checks that an account can give up an amount of an asset other than ILYZ
+func (l *Ledger) checkAssetDebit(asset string, from string, amount Amount) error {
+    if amount < 0 {
+        return ErrNegativeAmount
+    }
+    if balance := l.AssetBalances[asset][from]; balance < amount {
+        return fmt.Errorf("%w: %s holds %s %s, %s needed", ErrInsufficientBalance, from, balance, asset, amount)
+    }
+
+    return nil
+}
+
+// checkDebit checks that an account can give up an amount
+func (l *Ledger) checkDebit(from string, amount Amount) error {
+    if amount < 0 {
+        return ErrNegativeAmount
+    }
+    if l.Balances[from] < amount {
+        return fmt.Errorf("%w: %s holds %s, %s needed", ErrInsufficientBalance, from, l.Balances[from], amount)
+    }
+
+    return nil
+}
@@ -0,0 +1,266 @@
+package token
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// ErrMatchEscrowNotFound is returned for a match with no rewards in escrow
+var ErrMatchEscrowNotFound = errors.New("match has no rewards in escrow")
+
+// MatchEscrow is the game rewards reserved for a match's players, held until the match's result is final on chain
+// Reserved rewards count within the yearly supply cap and any pool's budget, so they can't be promised twice;
+// voiding the match returns them to both
+type MatchEscrow struct {
+    MatchID    string            `json:"matchId"`
+    PoolID     string            `json:"poolId,omitempty"` // Pool the rewards are drawn from, empty for the yearly budget
+    Rewards    map[string]Amount `json:"rewards"`          // By player
+    ReservedAt int64             `json:"reservedAt"`
+
+    // How each player's reward was calculated, by player
+    Breakdowns map[string]RewardBreakdown `json:"breakdowns,omitempty"`
+
+    // Shares of the players' rewards held for whoever referred them, by referrer
+    Referrals map[string]Amount `json:"referrals,omitempty"`
+}
+
+// SettledEscrow is a match's escrow as it was when a block paid or voided its rewards, kept so rolling the block back
+// can hold them again
+type SettledEscrow struct {
+    Escrow MatchEscrow `json:"escrow"`
+    Height int64       `json:"height"` // Block that paid or voided the rewards
+    Voided bool        `json:"voided,omitempty"`
+}
+
+// Total returns the rewards held for all of a match's players and their referrers
+func (e MatchEscrow) Total() Amount {
+    return e.PlayerTotal() + e.ReferralTotal()
+}
+
+// PlayerTotal returns the rewards held for a match's players, referral rewards aside
+func (e MatchEscrow) PlayerTotal() Amount {
+    total := Amount(0)
+    for _, reward := range e.Rewards {
+        total += reward
+    }
+
+    return total
+}
+
+// ReferralTotal returns the referral rewards held for a match's players' referrers
+func (e MatchEscrow) ReferralTotal() Amount {
+    total := Amount(0)
+    for _, reward := range e.Referrals {
+        total += reward
+    }
+
+    return total
+}
+
+// ReserveGameReward calculates a player's reward for a match like CalculateGameReward and holds it in escrow
+// until the match's result is final; every player of a match draws from the same pool, if any
+// A player still in their first days after being referred earns their referrer a share, reserved with the reward
+func (te *TokenEconomics) ReserveGameReward(
+    matchID string,
+    player string,
+    matchDuration int64,
+    playerRank int,
+    performanceScore float64,
+    poolID string,
+    mode string,
+    referral PlayerReferral,
+) (Amount, error) {
+    if matchID == "" || player == "" {
+        return 0, errors.New("match ID and player are required")
+    }
+
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    escrow, exists := te.Escrows[matchID]
+    if exists {
+        if _, reserved := escrow.Rewards[player]; reserved {
+            return 0, fmt.Errorf("reward for %s in match %s is already reserved", player, matchID)
+        }
+        if escrow.PoolID != poolID {
+            return 0, fmt.Errorf("match %s draws its rewards from pool %q", matchID, escrow.PoolID)
+        }
+    }
+
+    now := ClockNow(te.Clock)
+    reward, breakdown, err := te.gameReward(now, matchDuration, playerRank, performanceScore, poolID, mode)
+    if err != nil {
+        return 0, err
+    }
+
+    if !exists {
+        escrow = &MatchEscrow{MatchID: matchID, PoolID: poolID, Rewards: make(map[string]Amount), ReservedAt: now}
+        te.Escrows[matchID] = escrow
+    }
+    if escrow.Breakdowns == nil {
+        escrow.Breakdowns = make(map[string]RewardBreakdown)
+    }
+    escrow.Rewards[player] = reward
+    escrow.Breakdowns[player] = breakdown
+    te.Reserved += reward
+    te.reserveReferral(escrow, player, referral)
+    te.persist()
+
+    return reward, nil
+}
+
+// ReleaseMatchRewards takes a match's rewards out of escrow once its result is final and they are paid on chain
+// The chain counts the paid rewards as minted as it applies the block paying them
+func (te *TokenEconomics) ReleaseMatchRewards(matchID string) (MatchEscrow, error) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    escrow, exists := te.Escrows[matchID]
+    if !exists {
+        return MatchEscrow{}, fmt.Errorf("%w: %s", ErrMatchEscrowNotFound, matchID)
+    }
+    te.releaseEscrow(escrow)
+    te.persist()
+
+    return *escrow, nil
+}
+
+// VoidMatchRewards returns a voided match's rewards to the yearly budget, to its pool and to the referral budget,
+// and returns how much that was
+func (te *TokenEconomics) VoidMatchRewards(matchID string) (Amount, error) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    escrow, exists := te.Escrows[matchID]
+    if !exists {
+        return 0, fmt.Errorf("%w: %s", ErrMatchEscrowNotFound, matchID)
+    }
+    te.voidEscrow(escrow)
+    te.persist()
+
+    return escrow.Total(), nil
+}
+
+// SettleMatchRewards releases or voids a match's rewards as the block at a height paid or voided them on chain,
+// keeping the escrow so rolling the block back holds the rewards again
+func (te *TokenEconomics) SettleMatchRewards(matchID string, height int64, voided bool) error {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    escrow, exists := te.Escrows[matchID]
+    if !exists {
+        return fmt.Errorf("%w: %s", ErrMatchEscrowNotFound, matchID)
+    }
+    if voided {
+        te.voidEscrow(escrow)
+    } else {
+        te.releaseEscrow(escrow)
+    }
+    te.Settled = append(te.Settled, SettledEscrow{Escrow: *escrow, Height: height, Voided: voided})
+    te.persist()
+
+    return nil
+}
+
+// RestoreMatchRewards holds the rewards blocks above a height paid or voided in escrow again, as those blocks are
+// rolled back, so the matches can be settled again
+func (te *TokenEconomics) RestoreMatchRewards(height int64) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    kept := te.Settled[:0]
+    for _, settled := range te.Settled {
+        if settled.Height <= height {
+            kept = append(kept, settled)
+            continue
+        }
+
+        escrow := settled.Escrow
+        te.Reserved += escrow.Total()
+        if settled.Voided {
+            if pool := te.RewardPools[escrow.PoolID]; pool != nil {
+                pool.Paid += escrow.PlayerTotal()
+            }
+            te.ReferralPaid += escrow.ReferralTotal()
+        }
+        te.Escrows[escrow.MatchID] = &escrow
+    }
+    te.Settled = kept
+    te.persist()
+}
+
+// ForgetSettledEscrows drops the escrows settled at or below a height, which the chain can no longer be rolled back past
+func (te *TokenEconomics) ForgetSettledEscrows(height int64) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    kept := te.Settled[:0]
+    for _, settled := range te.Settled {
+        if settled.Height > height {
+            kept = append(kept, settled)
+        }
+    }
+    if len(kept) == len(te.Settled) {
+        return
+    }
+    te.Settled = kept
+    te.persist()
+}
+
+// releaseEscrow takes a match's paid rewards out of escrow
+// The caller must hold the lock
+func (te *TokenEconomics) releaseEscrow(escrow *MatchEscrow) {
+    te.Reserved -= escrow.Total()
+    delete(te.Escrows, escrow.MatchID)
+}
+
+// voidEscrow takes a voided match's rewards out of escrow and returns them to its pool and the referral budget
+// The caller must hold the lock
+func (te *TokenEconomics) voidEscrow(escrow *MatchEscrow) {
+    te.Reserved -= escrow.Total()
+    if pool := te.RewardPools[escrow.PoolID]; pool != nil {
+        pool.Paid -= escrow.PlayerTotal()
+    }
+    te.ReferralPaid -= escrow.ReferralTotal()
+    delete(te.Escrows, escrow.MatchID)
+}
+
+// GetMatchEscrow returns the rewards held for a match
+func (te *TokenEconomics) GetMatchEscrow(matchID string) (MatchEscrow, error) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    escrow, exists := te.Escrows[matchID]
+    if !exists {
+        return MatchEscrow{}, fmt.Errorf("%w: %s", ErrMatchEscrowNotFound, matchID)
+    }
+
+    return *escrow, nil
+}
+
+// GetMatchEscrows returns the rewards held for every match, oldest first
+func (te *TokenEconomics) GetMatchEscrows() []MatchEscrow {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    return te.sortedEscrows()
+}
+
+// sortedEscrows returns copies of every escrow by reservation time, then match ID
+// The caller must hold the lock
+func (te *TokenEconomics) sortedEscrows() []MatchEscrow {
+    escrows := make([]MatchEscrow, 0, len(te.Escrows))
+    for _, escrow := range te.Escrows {
+        escrows = append(escrows, *escrow)
+    }
+
+    sort.Slice(escrows, func(i, j int) bool {
+        if escrows[i].ReservedAt != escrows[j].ReservedAt {
+            return escrows[i].ReservedAt < escrows[j].ReservedAt
+        }
+        return escrows[i].MatchID < escrows[j].MatchID
+    })
+
+    return escrows
+}
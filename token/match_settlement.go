@@ -0,0 +1,159 @@
+package token
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// PlayerResult is how a player finished a match, which their reward is calculated from
+type PlayerResult struct {
+    Player      string  `json:"player"`
+    Duration    int64   `json:"duration"`    // Seconds the match lasted
+    Rank        int     `json:"rank"`
+    Performance float64 `json:"performance"` // Score between 0 and 100
+}
+
+// PlayerReferral is the referral a player is bound by, which earns the referrer a share of the player's rewards
+// while it is active
+type PlayerReferral struct {
+    Referrer string
+    BoundAt  int64 // Unix time the referral was bound
+}
+
+// SettleMatch calculates the rewards of every player of a match together and holds them in escrow at once,
+// so the game server can report them all in one game result
+// Limits are applied to the match's total rather than player by player: when the hourly or daily ceiling, the pool's
+// budget or the yearly cap cuts the total, every player's reward is cut by the same share, rounded down, instead of
+// the last players reserved getting nothing
+// Players bound by a referral earn their referrers a share beside their rewards, reserved in the same escrow
+// Either every player's reward and referral share is reserved or, on error, none is
+func (te *TokenEconomics) SettleMatch(matchID string, results []PlayerResult, referrals map[string]PlayerReferral, poolID string, mode string) (MatchEscrow, error) {
+    if matchID == "" {
+        return MatchEscrow{}, errors.New("match ID is required")
+    }
+    if len(results) == 0 {
+        return MatchEscrow{}, errors.New("match has no players")
+    }
+    listed := make(map[string]bool, len(results))
+    for _, result := range results {
+        if result.Player == "" {
+            return MatchEscrow{}, errors.New("player is required")
+        }
+        if listed[result.Player] {
+            return MatchEscrow{}, fmt.Errorf("%s is listed more than once", result.Player)
+        }
+        listed[result.Player] = true
+    }
+
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    if _, exists := te.Escrows[matchID]; exists {
+        return MatchEscrow{}, fmt.Errorf("match %s already has rewards reserved", matchID)
+    }
+    remainingYearlyCap := te.remainingYearlyCap()
+    if remainingYearlyCap <= 0 {
+        return MatchEscrow{}, errors.New("yearly token supply cap reached")
+    }
+    now := ClockNow(te.Clock)
+    pool, err := te.runningPool(poolID, now)
+    if err != nil {
+        return MatchEscrow{}, err
+    }
+
+    // Every player's reward is scaled by the same multiplier, taken once for the match
+    var multiplier float64
+    if pool != nil {
+        multiplier = pool.Multiplier
+    } else {
+        multiplier = te.Emission.Adjust(now, remainingYearlyCap, te.YearStartTime+yearDuration-now)
+    }
+
+    params := te.params()
+    breakdowns := make(map[string]RewardBreakdown, len(results))
+    uncut := make(map[string]Amount, len(results))
+    calculated := Amount(0)
+    total := Amount(0)
+    for _, result := range results {
+        base, breakdown := params.calculateGameReward(now, result.Duration, result.Rank, result.Performance, mode)
+        breakdown.Multiplier = multiplier
+        reward := base.MulRate(multiplier)
+        if pool != nil {
+            breakdown.PoolID = pool.ID
+            if pool.MaxReward > 0 {
+                reward = breakdown.cap(reward, pool.MaxReward, RewardCapPoolMax)
+            }
+        }
+
+        breakdowns[result.Player] = breakdown
+        uncut[result.Player] = reward
+        calculated += base
+        total += reward
+    }
+
+    // Cut the match's total by the limits, in the order a single reward is cut by them
+    var batch RewardBreakdown
+    granted := batch.limit(te.Breaker, now, total)
+    if pool != nil {
+        granted = batch.cap(granted, pool.Remaining(), RewardCapPoolBudget)
+    }
+    granted = batch.cap(granted, remainingYearlyCap, RewardCapYearly)
+    if granted < 0 {
+        granted = 0
+    }
+
+    escrow := &MatchEscrow{
+        MatchID:    matchID,
+        PoolID:     poolID,
+        Rewards:    make(map[string]Amount, len(results)),
+        Breakdowns: breakdowns,
+        ReservedAt: now,
+    }
+    paid := Amount(0)
+    for _, player := range sortedPlayers(uncut) {
+        reward := uncut[player]
+        if granted < total {
+            reward = reward.Scale(granted, total)
+        }
+
+        breakdown := breakdowns[player]
+        breakdown.Throttle = batch.Throttle
+        breakdown.Caps = append(breakdown.Caps, batch.Caps...)
+        breakdown.Reward = reward
+        breakdowns[player] = breakdown
+
+        escrow.Rewards[player] = reward
+        paid += reward
+    }
+
+    te.Breaker.Record(now, paid)
+    if pool != nil {
+        pool.Paid += paid
+    } else {
+        te.Emission.Record(now, calculated)
+    }
+    te.Escrows[matchID] = escrow
+    te.Reserved += paid
+
+    // Referral shares are cut by what the rewards left of the budget and the cap, in player order
+    for _, player := range sortedPlayers(escrow.Rewards) {
+        if referral, referred := referrals[player]; referred {
+            te.reserveReferral(escrow, player, referral)
+        }
+    }
+    te.persist()
+
+    return *escrow, nil
+}
+
+// sortedPlayers returns the players of a set of rewards in order
+func sortedPlayers(rewards map[string]Amount) []string {
+    players := make([]string, 0, len(rewards))
+    for player := range rewards {
+        players = append(players, player)
+    }
+    sort.Strings(players)
+
+    return players
+}
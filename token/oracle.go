@@ -0,0 +1,226 @@
+package token
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+)
+
+// PairILYZUSD is the pair marketplace and tax reports value ILYZ in
+const PairILYZUSD = "ILYZ/USD"
+
+// Default price oracle settings
+const (
+    DefaultMaxPriceAge   = int64(10 * 60) // Seconds a reported price counts toward the median
+    DefaultMinPriceFeeds = 1              // Fresh reports needed for a price
+    maxPriceClockSkew    = int64(60)      // Seconds a report may be dated ahead of this node's clock
+)
+
+// Price oracle errors
+var (
+    ErrPriceUnavailable = errors.New("no price reported for pair")
+    ErrPriceStale       = errors.New("too few fresh price reports for pair")
+)
+
+// Oracle reports the fiat price of ILYZ, such as for marketplace listings and tax reports
+type Oracle interface {
+    // Price returns the current price of a pair, such as ILYZ/USD
+    Price(pair string) (PriceQuote, error)
+
+    // Submit takes a reporter's signed price for a pair
+    Submit(submission PriceSubmission) error
+}
+
+// PriceSubmission is a price an authorized reporter signed for a pair at a time
+type PriceSubmission struct {
+    Pair      string  `json:"pair"`
+    Price     float64 `json:"price"` // Units of the quote currency per unit of the base, such as USD per ILYZ
+    Timestamp int64   `json:"timestamp"`
+    Reporter  string  `json:"reporter"`  // Address of the reporter's key
+    PublicKey string  `json:"publicKey"` // Hex Ed25519 key the reporter signed with
+    Signature string  `json:"signature"`
+}
+
+// PriceQuote is a pair's price, the median of its reporters' fresh submissions
+type PriceQuote struct {
+    Pair      string  `json:"pair"`
+    Price     float64 `json:"price"`
+    Feeds     int     `json:"feeds"`     // Fresh submissions the median was taken over
+    UpdatedAt int64   `json:"updatedAt"` // Time of the newest of them
+}
+
+// SigningPayload returns the bytes a reporter signs: the submission without its key and signature
+func (s PriceSubmission) SigningPayload() ([]byte, error) {
+    return json.Marshal(struct {
+        Pair      string  `json:"pair"`
+        Price     float64 `json:"price"`
+        Timestamp int64   `json:"timestamp"`
+        Reporter  string  `json:"reporter"`
+    }{s.Pair, s.Price, s.Timestamp, s.Reporter})
+}
+
+// Sign signs a submission with the reporter's key pair, filling in its reporter, public key and signature
+func (s *PriceSubmission) Sign(keyPair *crypto.KeyPair) error {
+    s.Reporter = crypto.GetAddressFromPublicKey(keyPair.PublicKey)
+    s.PublicKey = crypto.PublicKeyToHex(keyPair.PublicKey)
+
+    payload, err := s.SigningPayload()
+    if err != nil {
+        return err
+    }
+
+    s.Signature, err = keyPair.Sign(payload)
+    return err
+}
+
+// Verify checks that a submission was signed by the key of the reporter it names
+func (s PriceSubmission) Verify() error {
+    if s.Signature == "" || s.PublicKey == "" {
+        return errors.New("price submission is not signed")
+    }
+
+    publicKey, err := crypto.HexToPublicKey(s.PublicKey)
+    if err != nil {
+        return fmt.Errorf("invalid reporter public key: %w", err)
+    }
+
+    if crypto.GetAddressFromPublicKey(publicKey) != s.Reporter {
+        return errors.New("public key does not match the reporter")
+    }
+
+    payload, err := s.SigningPayload()
+    if err != nil {
+        return err
+    }
+
+    valid, err := crypto.Verify(payload, s.Signature, publicKey)
+    if err != nil || !valid {
+        return errors.New("invalid price signature")
+    }
+
+    return nil
+}
+
+// Value returns what an amount of ILYZ is worth at a quote's price
+func (q PriceQuote) Value(amount Amount) float64 {
+    return amount.Float() * q.Price
+}
+
+// MedianOracle prices each pair at the median of its authorized reporters' latest submissions,
+// leaving out any older than the maximum age, so a single reporter that is wrong or offline can't move the price
+type MedianOracle struct {
+    Reporters map[string]bool // Addresses allowed to submit prices
+    MaxAge    int64           // Seconds a submission counts toward the median
+    MinFeeds  int             // Fresh submissions a pair needs for a price
+
+    // Each reporter's latest submission, by pair, then reporter
+    feeds map[string]map[string]PriceSubmission
+
+    mutex sync.RWMutex
+}
+
+// NewMedianOracle creates an oracle taking prices from the given reporters, with the default age and feed settings
+func NewMedianOracle(reporters []string) *MedianOracle {
+    oracle := &MedianOracle{
+        Reporters: make(map[string]bool, len(reporters)),
+        MaxAge:    DefaultMaxPriceAge,
+        MinFeeds:  DefaultMinPriceFeeds,
+        feeds:     make(map[string]map[string]PriceSubmission),
+    }
+    for _, reporter := range reporters {
+        oracle.Reporters[reporter] = true
+    }
+
+    return oracle
+}
+
+// Submit takes an authorized reporter's signed price, replacing its earlier one for the pair
+// A submission must be positive, fresh, and newer than the reporter's last for the pair, so old ones can't be replayed
+func (o *MedianOracle) Submit(submission PriceSubmission) error {
+    if submission.Pair == "" {
+        return errors.New("pair is required")
+    }
+    if submission.Price <= 0 {
+        return errors.New("price must be positive")
+    }
+    if !o.Reporters[submission.Reporter] {
+        return fmt.Errorf("%s is not an authorized price reporter", submission.Reporter)
+    }
+    if err := submission.Verify(); err != nil {
+        return err
+    }
+
+    now := time.Now().Unix()
+    if submission.Timestamp > now+maxPriceClockSkew {
+        return errors.New("price submission is dated in the future")
+    }
+    if now-submission.Timestamp > o.MaxAge {
+        return errors.New("price submission is stale")
+    }
+
+    o.mutex.Lock()
+    defer o.mutex.Unlock()
+
+    feeds, exists := o.feeds[submission.Pair]
+    if !exists {
+        feeds = make(map[string]PriceSubmission)
+        o.feeds[submission.Pair] = feeds
+    }
+    if last, exists := feeds[submission.Reporter]; exists && submission.Timestamp <= last.Timestamp {
+        return errors.New("price submission is not newer than the reporter's last")
+    }
+    feeds[submission.Reporter] = submission
+
+    return nil
+}
+
+// Price returns the median of a pair's fresh submissions from reporters still authorized
+// It returns ErrPriceUnavailable if the pair was never reported and ErrPriceStale if too few reports are fresh
+func (o *MedianOracle) Price(pair string) (PriceQuote, error) {
+    o.mutex.RLock()
+    defer o.mutex.RUnlock()
+
+    feeds := o.feeds[pair]
+    if len(feeds) == 0 {
+        return PriceQuote{}, fmt.Errorf("%w: %s", ErrPriceUnavailable, pair)
+    }
+
+    now := time.Now().Unix()
+    quote := PriceQuote{Pair: pair}
+    prices := make([]float64, 0, len(feeds))
+    for reporter, submission := range feeds {
+        if !o.Reporters[reporter] || now-submission.Timestamp > o.MaxAge {
+            continue
+        }
+        prices = append(prices, submission.Price)
+        if submission.Timestamp > quote.UpdatedAt {
+            quote.UpdatedAt = submission.Timestamp
+        }
+    }
+
+    if len(prices) == 0 || len(prices) < o.MinFeeds {
+        return PriceQuote{}, fmt.Errorf("%w: %s has %d of %d", ErrPriceStale, pair, len(prices), o.MinFeeds)
+    }
+
+    quote.Price = median(prices)
+    quote.Feeds = len(prices)
+
+    return quote, nil
+}
+
+// median returns the middle of a set of prices, or the mean of the middle two for an even number
+func median(prices []float64) float64 {
+    sort.Float64s(prices)
+
+    middle := len(prices) / 2
+    if len(prices)%2 == 0 {
+        return (prices[middle-1] + prices[middle]) / 2
+    }
+
+    return prices[middle]
+}
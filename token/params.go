@@ -0,0 +1,82 @@
+package token
+
+// EconomicParams are the economic settings governance can change
+type EconomicParams struct {
+    TransactionFeeRate float64 `json:"transactionFeeRate"` // Fee charged on a transaction's amount (0.5% = 0.005)
+    YieldRate          float64 `json:"yieldRate"`          // Yearly yield of yield-generating NFTs (7% = 0.07), set each epoch by the yield curve
+    BaseGameReward     Amount  `json:"baseGameReward"`     // Reward for winning a game before the factors apply
+
+    // Duration factors for matches under 15, 30 and 45 minutes and for longer ones
+    ShortMatchFactor    float64 `json:"shortMatchFactor"`
+    MediumMatchFactor   float64 `json:"mediumMatchFactor"`
+    LongMatchFactor     float64 `json:"longMatchFactor"`
+    MarathonMatchFactor float64 `json:"marathonMatchFactor"`
+
+    // Rank factor: the base plus the step for each rank, up to the maximum
+    RankFactorBase float64 `json:"rankFactorBase"`
+    RankFactorStep float64 `json:"rankFactorStep"`
+    RankFactorMax  float64 `json:"rankFactorMax"`
+
+    // Performance factor for a score of 0, rising linearly to 1 for a score of 100
+    MinPerformanceFactor float64 `json:"minPerformanceFactor"`
+
+    // Sets the yield rate from the share of the supply staked as each epoch starts
+    YieldCurve YieldCurve `json:"yieldCurve"`
+
+    // Competitive seasons scaling game rewards while they run, by start; governance adds and replaces seasons,
+    // always with a new calendar, so a calendar is never changed in place
+    Seasons []Season `json:"seasons,omitempty"`
+}
+
+// EconomicParamsSource reports the economic settings in effect, such as ones governance adjusts
+type EconomicParamsSource interface {
+    // EconomicParams returns the settings fees, yield and game rewards are calculated with
+    EconomicParams() EconomicParams
+}
+
+// DefaultEconomicParams returns the economic settings a network starts with
+func DefaultEconomicParams() EconomicParams {
+    return EconomicParams{
+        TransactionFeeRate:   0.005, // 0.5%
+        YieldRate:            0.07,  // 7%
+        BaseGameReward:       10 * ILYZ,
+        ShortMatchFactor:     0.8,
+        MediumMatchFactor:    1.0,
+        LongMatchFactor:      1.2,
+        MarathonMatchFactor:  1.3,
+        RankFactorBase:       0.8,
+        RankFactorStep:       0.05,
+        RankFactorMax:        1.5,
+        MinPerformanceFactor: 0.5,
+        YieldCurve:           DefaultYieldCurve(),
+    }
+}
+
+// durationFactor returns the factor a match of a length in minutes earns
+func (p EconomicParams) durationFactor(matchMinutes float64) float64 {
+    switch {
+    case matchMinutes < 15:
+        return p.ShortMatchFactor
+    case matchMinutes < 30:
+        return p.MediumMatchFactor
+    case matchMinutes < 45:
+        return p.LongMatchFactor
+    default:
+        return p.MarathonMatchFactor
+    }
+}
+
+// rankFactor returns the factor a player's rank earns
+func (p EconomicParams) rankFactor(playerRank int) float64 {
+    factor := p.RankFactorBase + float64(playerRank)*p.RankFactorStep
+    if factor > p.RankFactorMax {
+        factor = p.RankFactorMax
+    }
+
+    return factor
+}
+
+// performanceFactor returns the factor a performance score between 0 and 100 earns
+func (p EconomicParams) performanceFactor(performanceScore float64) float64 {
+    return p.MinPerformanceFactor + (1-p.MinPerformanceFactor)*performanceScore/100
+}
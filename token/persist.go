@@ -0,0 +1,136 @@
+package token
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+
+    "github.com/txaimhawj/chulubmeadditional-files/storage"
+)
+
+// storeKeyEconomics is the store key the token economics are kept under
+const storeKeyEconomics = "economics/state"
+
+// economicsRecord is the token economics as written to the store
+type economicsRecord struct {
+    CurrentYear      int     `json:"currentYear"`
+    YearStartTime    int64   `json:"yearStartTime"`
+    YearlyMinted     Amount  `json:"yearlyMinted"`
+    Reserved         Amount  `json:"reserved,omitempty"`
+    YieldEmitted     Amount  `json:"yieldEmitted"`
+    ReferralPaid     Amount  `json:"referralPaid,omitempty"`
+    CurrentSupply    Amount  `json:"currentSupply"`
+    FeesBurned       Amount  `json:"feesBurned"`
+    RewardMultiplier float64 `json:"rewardMultiplier"`
+
+    RewardPools []RewardPool    `json:"rewardPools,omitempty"`
+    Escrows     []MatchEscrow   `json:"escrows,omitempty"`
+    Settled     []SettledEscrow `json:"settled,omitempty"`
+
+    // The breaker's windows, so restarting doesn't reset what this hour and day have minted
+    EmissionHour EmissionWindow `json:"emissionHour"`
+    EmissionDay  EmissionWindow `json:"emissionDay"`
+}
+
+// OpenTokenEconomics loads token economics persisted in a store, starting a new first year if none are
+// The supply and fees burned are reported by the chain again once it is attached, and the yearly minted count
+// is reconciled with the block rewards the chain holds
+func OpenTokenEconomics(masterWalletAddress string, store storage.Store) (*TokenEconomics, error) {
+    te := NewTokenEconomics(masterWalletAddress)
+    te.store = store
+
+    data, err := store.Get(storeKeyEconomics)
+    if errors.Is(err, storage.ErrNotFound) {
+        te.persist()
+        return te, te.storeErr
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var record economicsRecord
+    if err := json.Unmarshal(data, &record); err != nil {
+        return nil, fmt.Errorf("invalid token economics record: %w", err)
+    }
+    if record.CurrentYear < 1 {
+        return nil, fmt.Errorf("invalid token economics record: year %d", record.CurrentYear)
+    }
+
+    te.CurrentYear = record.CurrentYear
+    te.YearStartTime = record.YearStartTime
+    te.YearlyMinted = record.YearlyMinted
+    te.Reserved = record.Reserved
+    te.YieldEmitted = record.YieldEmitted
+    te.ReferralPaid = record.ReferralPaid
+    te.CurrentSupply = record.CurrentSupply
+    te.FeesBurned = record.FeesBurned
+    if record.RewardMultiplier > 0 {
+        te.Emission.Multiplier = record.RewardMultiplier
+    }
+    te.Breaker.Hour = record.EmissionHour
+    te.Breaker.Day = record.EmissionDay
+    for _, pool := range record.RewardPools {
+        te.RewardPools[pool.ID] = &pool
+    }
+    for _, escrow := range record.Escrows {
+        te.Escrows[escrow.MatchID] = &escrow
+    }
+    te.Settled = record.Settled
+
+    return te, nil
+}
+
+// Flush makes the persisted economics durable, returning any earlier write error
+func (te *TokenEconomics) Flush() error {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    if te.store == nil {
+        return nil
+    }
+
+    if te.storeErr != nil {
+        return te.storeErr
+    }
+
+    return te.store.Flush()
+}
+
+// persist writes the economics to the store
+// The caller must hold the lock
+func (te *TokenEconomics) persist() {
+    if te.store == nil {
+        return
+    }
+
+    data, err := json.Marshal(economicsRecord{
+        CurrentYear:      te.CurrentYear,
+        YearStartTime:    te.YearStartTime,
+        YearlyMinted:     te.YearlyMinted,
+        Reserved:         te.Reserved,
+        YieldEmitted:     te.YieldEmitted,
+        ReferralPaid:     te.ReferralPaid,
+        CurrentSupply:    te.CurrentSupply,
+        FeesBurned:       te.FeesBurned,
+        RewardMultiplier: te.Emission.Multiplier,
+        RewardPools:      te.sortedRewardPools(),
+        Escrows:          te.sortedEscrows(),
+        Settled:          te.Settled,
+        EmissionHour:     te.Breaker.Hour,
+        EmissionDay:      te.Breaker.Day,
+    })
+    if err != nil {
+        te.recordStoreError(err)
+        return
+    }
+
+    te.recordStoreError(te.store.Put(storeKeyEconomics, data))
+}
+
+// recordStoreError keeps the first store write error for Flush to report
+// The caller must hold the lock
+func (te *TokenEconomics) recordStoreError(err error) {
+    if err != nil && te.storeErr == nil {
+        te.storeErr = err
+    }
+}
@@ -0,0 +1,107 @@
+package token
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ReferralProgram pays whoever referred a player a share of the player's game rewards for the player's first days,
+// from a budget set aside within the yearly supply cap
+type ReferralProgram struct {
+    Rate   float64 `json:"rate"`   // Share of the referred player's reward paid to the referrer (5% = 0.05)
+    Days   int64   `json:"days"`   // Days after the referral is bound during which the player's rewards earn a share
+    Budget Amount  `json:"budget"` // Most referral rewards may mint in a year, counted within the yearly cap
+}
+
+// DefaultReferralProgram returns a program paying referrers 5% of their players' rewards for 30 days,
+// from 50 million ILYZ a year
+func DefaultReferralProgram() ReferralProgram {
+    return ReferralProgram{
+        Rate:   0.05,
+        Days:   30,
+        Budget: 50_000_000 * ILYZ,
+    }
+}
+
+// Validate checks that a program's rate is a share, and its period and budget aren't negative
+func (p ReferralProgram) Validate() error {
+    if p.Rate < 0 || p.Rate > 1 {
+        return errors.New("referral rate must be between 0 and 1")
+    }
+
+    if p.Days < 0 {
+        return errors.New("referral period cannot be negative")
+    }
+
+    if p.Budget < 0 {
+        return errors.New("referral budget cannot be negative")
+    }
+
+    return nil
+}
+
+// Active reports whether a referral bound at a time still earns its referrer a share at another
+func (p ReferralProgram) Active(boundAt int64, now int64) bool {
+    return p.Rate > 0 && now >= boundAt && now < boundAt+p.Days*dayDuration
+}
+
+// ReserveReferralReward holds the referrer's share of a player's reward in a match's escrow beside the reward,
+// if the player's referral, bound at a time, is still active; the share is cut to what is left of the year's
+// referral budget and supply cap, and returned
+// The player's reward must already be reserved
+func (te *TokenEconomics) ReserveReferralReward(matchID string, referrer string, player string, boundAt int64) (Amount, error) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    escrow, exists := te.Escrows[matchID]
+    if !exists {
+        return 0, fmt.Errorf("%w: %s", ErrMatchEscrowNotFound, matchID)
+    }
+    if _, reserved := escrow.Rewards[player]; !reserved {
+        return 0, fmt.Errorf("no reward for %s is reserved in match %s", player, matchID)
+    }
+    
+    share := te.reserveReferral(escrow, player, PlayerReferral{Referrer: referrer, BoundAt: boundAt})
+    if share > 0 {
+        te.persist()
+    }
+    
+    return share, nil
+}
+
+// reserveReferral holds the referrer's share of a player's reserved reward in a match's escrow, if the referral
+// is still active, cut to what is left of the year's referral budget and supply cap, and returns it
+// The caller must hold the lock
+func (te *TokenEconomics) reserveReferral(escrow *MatchEscrow, player string, referral PlayerReferral) Amount {
+    if referral.Referrer == "" || referral.Referrer == player || !te.Referral.Active(referral.BoundAt, ClockNow(te.Clock)) {
+        return 0
+    }
+    
+    share := escrow.Rewards[player].MulRate(te.Referral.Rate)
+    if remaining := te.Referral.Budget - te.ReferralPaid; share > remaining {
+        share = remaining
+    }
+    if remaining := te.remainingYearlyCap(); share > remaining {
+        share = remaining
+    }
+    if share <= 0 {
+        return 0
+    }
+    
+    if escrow.Referrals == nil {
+        escrow.Referrals = make(map[string]Amount)
+    }
+    escrow.Referrals[referral.Referrer] += share
+    te.Reserved += share
+    te.ReferralPaid += share
+    
+    return share
+}
+
+// GetReferralProgram returns the referral program and what it has paid or reserved this year
+func (te *TokenEconomics) GetReferralProgram() (ReferralProgram, Amount) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.Referral, te.ReferralPaid
+}
@@ -0,0 +1,48 @@
+package token
+
+// Limits that can cut a game reward, as a breakdown names them
+const (
+    RewardCapPoolMax    = "pool_max_reward"   // The pool's most for one match
+    RewardCapCeiling    = "emission_ceiling"  // What the hour or day has left before its ceiling
+    RewardCapPoolBudget = "pool_budget"       // What is left of the pool's budget
+    RewardCapYearly     = "yearly_supply_cap" // What is left of the yearly supply cap
+)
+
+// RewardBreakdown records how a game reward was calculated, so disputes and balance audits can retrace it:
+// the base reward times the factors gives the calculated reward, the multiplier and throttle adjust it,
+// and the caps listed cut it to the reward paid
+type RewardBreakdown struct {
+    BaseReward        Amount   `json:"baseReward"`
+    DurationFactor    float64  `json:"durationFactor"`
+    RankFactor        float64  `json:"rankFactor"`
+    PerformanceFactor float64  `json:"performanceFactor"`
+    Season            string   `json:"season,omitempty"`       // Season running when the reward was earned, if it applied
+    SeasonFactor      float64  `json:"seasonFactor,omitempty"` // The season's multiplier, if it applied
+    Calculated        Amount   `json:"calculated"`             // Base reward with the factors applied
+    PoolID            string   `json:"poolId,omitempty"` // Pool that paid the reward, if any
+    Multiplier        float64  `json:"multiplier"`       // The pool's, or the emission controller's without one
+    Throttle          float64  `json:"throttle"`         // Share the circuit breaker let through, 1 when not throttled
+    Caps              []string `json:"caps,omitempty"`   // Limits that cut the reward, in the order they were applied
+    Reward            Amount   `json:"reward"`
+}
+
+// limit throttles an amount by the circuit breaker, noting the throttle and whether a ceiling cut it further
+func (b *RewardBreakdown) limit(breaker *CircuitBreaker, now int64, amount Amount) Amount {
+    b.Throttle = breaker.Status(now).Throttle
+    limited := breaker.Limit(now, amount)
+    if limited < amount.MulRate(b.Throttle) {
+        b.Caps = append(b.Caps, RewardCapCeiling)
+    }
+
+    return limited
+}
+
+// cap cuts an amount to a limit, noting the limit's name if it applied
+func (b *RewardBreakdown) cap(amount Amount, limit Amount, name string) Amount {
+    if amount <= limit {
+        return amount
+    }
+
+    b.Caps = append(b.Caps, name)
+    return limit
+}
@@ -0,0 +1,139 @@
+package token
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// ErrRewardPoolNotFound is returned for a reward pool ID no pool has
+var ErrRewardPoolNotFound = errors.New("reward pool not found")
+
+// RewardPool is a budget of game rewards for a tournament or event, paid to the matches tagged with its ID
+// while it runs; its multiplier takes the place of the emission controller's, so a weekend event can pay
+// double rewards, and what it pays still counts within the yearly supply cap
+type RewardPool struct {
+    ID         string  `json:"id"`
+    Name       string  `json:"name"`
+    Budget     Amount  `json:"budget"` // Most the pool pays out in total
+    Paid       Amount  `json:"paid"` // Rewards held in escrow included
+    StartTime  int64   `json:"startTime"`
+    EndTime    int64   `json:"endTime"`
+    Multiplier float64 `json:"multiplier"`          // Applied to a tagged match's reward
+    MaxReward  Amount  `json:"maxReward,omitempty"` // Most one match earns from the pool, unlimited if zero
+}
+
+// Validate checks that a pool has an ID, a budget, a window that ends after it starts and a usable multiplier
+func (p RewardPool) Validate() error {
+    if p.ID == "" {
+        return errors.New("reward pool ID is required")
+    }
+
+    if p.Budget <= 0 {
+        return errors.New("reward pool budget must be positive")
+    }
+
+    if p.EndTime <= p.StartTime {
+        return errors.New("reward pool must end after it starts")
+    }
+
+    if p.Multiplier <= 0 || p.Multiplier > MaxRewardMultiplier {
+        return fmt.Errorf("reward pool multiplier must be above 0 and at most %v", MaxRewardMultiplier)
+    }
+
+    if p.MaxReward < 0 {
+        return errors.New("reward pool max reward cannot be negative")
+    }
+
+    return nil
+}
+
+// Running reports whether a pool pays rewards at a time
+func (p RewardPool) Running(now int64) bool {
+    return now >= p.StartTime && now < p.EndTime
+}
+
+// Remaining returns what is left of a pool's budget
+func (p RewardPool) Remaining() Amount {
+    return p.Budget - p.Paid
+}
+
+// AddRewardPool adds a pool for matches to be tagged with; IDs can't be reused
+func (te *TokenEconomics) AddRewardPool(pool RewardPool) error {
+    if err := pool.Validate(); err != nil {
+        return err
+    }
+
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    if _, exists := te.RewardPools[pool.ID]; exists {
+        return fmt.Errorf("reward pool %s already exists", pool.ID)
+    }
+
+    pool.Paid = 0
+    te.RewardPools[pool.ID] = &pool
+    te.persist()
+
+    return nil
+}
+
+// GetRewardPool returns a pool by ID
+func (te *TokenEconomics) GetRewardPool(id string) (RewardPool, error) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    pool, exists := te.RewardPools[id]
+    if !exists {
+        return RewardPool{}, fmt.Errorf("%w: %s", ErrRewardPoolNotFound, id)
+    }
+
+    return *pool, nil
+}
+
+// GetRewardPools returns every pool by start time, then ID
+func (te *TokenEconomics) GetRewardPools() []RewardPool {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    return te.sortedRewardPools()
+}
+
+// draw pays a tagged match's calculated reward from a pool, within its per-match limit, the circuit breaker's ceilings,
+// its budget and the yearly cap left, completing the reward's breakdown
+func (p *RewardPool) draw(breakdown *RewardBreakdown, breaker *CircuitBreaker, now int64, remainingYearlyCap Amount) Amount {
+    breakdown.PoolID = p.ID
+    breakdown.Multiplier = p.Multiplier
+    reward := breakdown.Calculated.MulRate(p.Multiplier)
+    if p.MaxReward > 0 {
+        reward = breakdown.cap(reward, p.MaxReward, RewardCapPoolMax)
+    }
+    reward = breakdown.limit(breaker, now, reward)
+    reward = breakdown.cap(reward, p.Remaining(), RewardCapPoolBudget)
+    reward = breakdown.cap(reward, remainingYearlyCap, RewardCapYearly)
+    if reward < 0 {
+        reward = 0
+    }
+
+    p.Paid += reward
+    breakdown.Reward = reward
+    return reward
+}
+
+// sortedRewardPools returns copies of every pool by start time, then ID
+// The caller must hold the lock
+func (te *TokenEconomics) sortedRewardPools() []RewardPool {
+    pools := make([]RewardPool, 0, len(te.RewardPools))
+    for _, pool := range te.RewardPools {
+        pools = append(pools, *pool)
+    }
+
+    sort.Slice(pools, func(i, j int) bool {
+        if pools[i].StartTime != pools[j].StartTime {
+            return pools[i].StartTime < pools[j].StartTime
+        }
+        return pools[i].ID < pools[j].ID
+    })
+
+    return pools
+}
@@ -0,0 +1,115 @@
+package token
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "sort"
+)
+
+// Season is a competitive season that scales game rewards while it runs
+type Season struct {
+    ID         string   `json:"id"`
+    Name       string   `json:"name,omitempty"`
+    Start      int64    `json:"start"`           // Unix time the season starts
+    End        int64    `json:"end"`             // Unix time the season ends, not included
+    Multiplier float64  `json:"multiplier"`      // Game rewards are multiplied by this during the season
+    Modes      []string `json:"modes,omitempty"` // Game modes whose rewards the multiplier applies to, every mode if empty
+}
+
+// Validate checks that a season has an ID, ends after it starts and has a usable multiplier
+func (s Season) Validate() error {
+    if s.ID == "" {
+        return errors.New("season ID is required")
+    }
+
+    if s.End <= s.Start {
+        return errors.New("season must end after it starts")
+    }
+
+    if !(s.Multiplier >= 0) || math.IsInf(s.Multiplier, 0) {
+        return errors.New("season multiplier must not be negative")
+    }
+
+    return nil
+}
+
+// Running reports whether a season runs at a time
+func (s Season) Running(now int64) bool {
+    return now >= s.Start && now < s.End
+}
+
+// Eligible reports whether a season's multiplier applies to a game mode's rewards
+func (s Season) Eligible(mode string) bool {
+    if len(s.Modes) == 0 {
+        return true
+    }
+
+    for _, eligible := range s.Modes {
+        if eligible == mode {
+            return true
+        }
+    }
+
+    return false
+}
+
+// ValidateSeasons checks every season of a calendar and that no two share an ID or overlap
+func ValidateSeasons(seasons []Season) error {
+    sorted := sortedSeasons(seasons)
+    for i, season := range sorted {
+        if err := season.Validate(); err != nil {
+            return fmt.Errorf("season %q: %w", season.ID, err)
+        }
+        if i > 0 && season.Start < sorted[i-1].End {
+            return fmt.Errorf("season %q overlaps season %q", season.ID, sorted[i-1].ID)
+        }
+    }
+
+    ids := make(map[string]bool, len(seasons))
+    for _, season := range seasons {
+        if ids[season.ID] {
+            return fmt.Errorf("duplicate season %q", season.ID)
+        }
+        ids[season.ID] = true
+    }
+
+    return nil
+}
+
+// CurrentSeason returns the season of a calendar running at a time, if any
+func CurrentSeason(seasons []Season, now int64) (Season, bool) {
+    for _, season := range seasons {
+        if season.Running(now) {
+            return season, true
+        }
+    }
+
+    return Season{}, false
+}
+
+// WithSeason returns a calendar with a season added, replacing any with its ID, ordered by start
+// The calendar passed is left as it was
+func WithSeason(seasons []Season, season Season) []Season {
+    calendar := make([]Season, 0, len(seasons)+1)
+    for _, existing := range seasons {
+        if existing.ID != season.ID {
+            calendar = append(calendar, existing)
+        }
+    }
+
+    return sortedSeasons(append(calendar, season))
+}
+
+// sortedSeasons returns a copy of a calendar ordered by start, then ID
+func sortedSeasons(seasons []Season) []Season {
+    sorted := append([]Season(nil), seasons...)
+    sort.Slice(sorted, func(i, j int) bool {
+        if sorted[i].Start != sorted[j].Start {
+            return sorted[i].Start < sorted[j].Start
+        }
+        return sorted[i].ID < sorted[j].ID
+    })
+
+    return sorted
+}
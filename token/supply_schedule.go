@@ -0,0 +1,77 @@
+package token
+
+import "errors"
+
+// SupplySchedule sets how many tokens may be minted in each year
+// The listed caps apply to the first years; after them each year's cap is the previous one reduced by the decay rate,
+// never falling below the tail emission, so a schedule without decay keeps minting the last listed cap forever
+type SupplySchedule struct {
+    Caps         []Amount `json:"caps"`                   // Cap of each year from the first
+    DecayRate    float64  `json:"decayRate,omitempty"`    // Share each year's cap shrinks by once the listed caps run out (10% = 0.1)
+    TailEmission Amount   `json:"tailEmission,omitempty"` // Least a year may mint once the cap has decayed
+}
+
+// DefaultSupplySchedule returns the 5B → 4B → 3B → 2B → 1B schedule, minting 1B a year from the fifth year on
+func DefaultSupplySchedule() SupplySchedule {
+    return SupplySchedule{
+        Caps: []Amount{
+            5_000_000_000 * ILYZ, // Year 1: 5 billion
+            4_000_000_000 * ILYZ, // Year 2: 4 billion
+            3_000_000_000 * ILYZ, // Year 3: 3 billion
+            2_000_000_000 * ILYZ, // Year 4: 2 billion
+            1_000_000_000 * ILYZ, // Year 5+: 1 billion
+        },
+    }
+}
+
+// Validate checks that a schedule lists at least one cap, none negative, and decays toward a tail no higher
+// than the last listed cap
+func (s SupplySchedule) Validate() error {
+    if len(s.Caps) == 0 {
+        return errors.New("supply schedule lists no yearly caps")
+    }
+
+    for _, ceiling := range s.Caps {
+        if ceiling < 0 {
+            return errors.New("yearly supply caps cannot be negative")
+        }
+    }
+
+    if s.DecayRate < 0 || s.DecayRate >= 1 {
+        return errors.New("supply decay rate must be at least 0 and below 1")
+    }
+
+    if s.TailEmission < 0 {
+        return errors.New("tail emission cannot be negative")
+    }
+
+    if s.TailEmission > s.Caps[len(s.Caps)-1] {
+        return errors.New("tail emission exceeds the last yearly cap")
+    }
+
+    return nil
+}
+
+// CapForYear returns the most that may be minted in a year, counted from 1
+func (s SupplySchedule) CapForYear(year int) Amount {
+    if year < 1 {
+        year = 1
+    }
+    if year <= len(s.Caps) {
+        return s.Caps[year-1]
+    }
+
+    ceiling := s.Caps[len(s.Caps)-1]
+    if s.DecayRate <= 0 {
+        return ceiling
+    }
+
+    for decayed := len(s.Caps); decayed < year; decayed++ {
+        ceiling -= ceiling.MulRate(s.DecayRate)
+        if ceiling <= s.TailEmission {
+            return s.TailEmission
+        }
+    }
+
+    return ceiling
+}
@@ -0,0 +1,505 @@
+package token
+
+import (
+    "errors"
+    "fmt"
+    "sync"
+
+    "github.com/txaimhawj/chulubmeadditional-files/storage"
+)
+
+// yearDuration is the length of a supply cap year in seconds
+const yearDuration = int64(365 * 24 * 60 * 60)
+
+// TokenEconomics manages the ILYZ token economics
+type TokenEconomics struct {
+    // Supply caps by year
+    Schedule SupplySchedule
+    
+    // Current year (1-indexed)
+    CurrentYear int
+    
+    // Current total supply
+    CurrentSupply Amount
+    
+    // Transaction fees burned in total
+    FeesBurned Amount
+    
+    // Tokens minted this year
+    YearlyMinted Amount
+    
+    // Least fee the next block's transactions must pay, synced from the chain's fee market; 0 without one
+    BaseFee Amount
+    
+    // Game rewards held in escrow until their matches are final, counted within the yearly supply cap
+    Reserved Amount
+    
+    // Year start timestamp
+    YearStartTime int64
+    
+    // Master wallet address
+    MasterWalletAddress string
+    
+    // Fee rate, yield rate and game reward settings, used without a governance source
+    Params EconomicParams
+    
+    // Source of the economic settings governance has changed, nil to use Params
+    Governance EconomicParamsSource
+    
+    // Most tokens yield generators may emit in a year, counted within the yearly supply cap
+    YieldEmissionCap Amount
+    
+    // Tokens emitted as yield this year
+    YieldEmitted Amount
+    
+    // Steers game rewards so the yearly cap is approached smoothly
+    Emission *EmissionController
+    
+    // Caps what game rewards and yield mint per hour and per day, beside the yearly cap
+    Breaker *CircuitBreaker
+    
+    // Source of the current time for year transitions, emission windows and escrows, the wall clock if nil
+    Clock Clock
+    
+    // Budgets for tournaments and events, by ID
+    RewardPools map[string]*RewardPool
+    
+    // Game rewards held for matches whose results aren't final yet, by match ID
+    Escrows map[string]*MatchEscrow
+    
+    // Escrows of the matches recent blocks paid or voided, oldest first, held again if those blocks are rolled back
+    Settled []SettledEscrow
+    
+    // Shares of referred players' game rewards paid to their referrers
+    Referral ReferralProgram
+    
+    // Referral rewards reserved or minted this year, within the referral budget
+    ReferralPaid Amount
+    
+    // Called after each year rollover
+    yearHooks []YearTransitionHook
+    
+    // Store the economics are persisted in, nil to keep them in memory
+    store    storage.Store
+    storeErr error
+    
+    // Mutex for thread safety
+    mutex sync.Mutex
+}
+
+// NewTokenEconomics creates a new token economics manager
+func NewTokenEconomics(masterWalletAddress string) *TokenEconomics {
+    return &TokenEconomics{
+        Schedule:             DefaultSupplySchedule(),
+        CurrentYear:          1,
+        CurrentSupply:        0,
+        YearlyMinted:         0,
+        YearStartTime:        SystemClock{}.Now(),
+        MasterWalletAddress:  masterWalletAddress,
+        Params:               DefaultEconomicParams(),
+        YieldEmissionCap:     500_000_000 * ILYZ,
+        Emission:             NewEmissionController(),
+        Breaker:              NewCircuitBreaker(),
+        RewardPools:          make(map[string]*RewardPool),
+        Escrows:              make(map[string]*MatchEscrow),
+        Referral:             DefaultReferralProgram(),
+        Clock:                SystemClock{},
+        mutex:                sync.Mutex{},
+    }
+}
+
+// CalculateGameReward calculates the reward for winning a game
+// based on match duration, player rank, and performance score, scaled by the emission controller's multiplier
+// The season running, if any, multiplies the reward of a match in one of its game modes
+// A match tagged with a reward pool's ID is paid from the pool by its rules instead, while the pool runs
+// The reward counts as minted at once; ReserveGameReward holds it in escrow until the match's result is final
+func (te *TokenEconomics) CalculateGameReward(
+    matchDuration int64,
+    playerRank int,
+    performanceScore float64,
+    poolID string,
+    mode string,
+) (Amount, error) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    reward, _, err := te.gameReward(ClockNow(te.Clock), matchDuration, playerRank, performanceScore, poolID, mode)
+    if err != nil {
+        return 0, err
+    }
+    
+    te.YearlyMinted += reward
+    te.persist()
+    
+    return reward, nil
+}
+
+// CalculateTransactionFee calculates the fee for a transaction, never below the base fee
+func (te *TokenEconomics) CalculateTransactionFee(amount Amount) Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    fee := amount.MulRate(te.params().TransactionFeeRate)
+    if fee < te.BaseFee {
+        return te.BaseFee
+    }
+    
+    return fee
+}
+
+// CalculateYield calculates the yield for a yield-generating NFT
+func (te *TokenEconomics) CalculateYield(stakedAmount Amount, daysSinceLastClaim float64) Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    // Daily yield rate (APY / 365 days)
+    dailyYieldRate := te.params().YieldRate / 365.0
+    
+    // Calculate yield
+    yield := stakedAmount.MulRate(dailyYieldRate * daysSinceLastClaim)
+    
+    return yield
+}
+
+// GetBreakerStatus returns how close the current hour and day are to their minting ceilings
+func (te *TokenEconomics) GetBreakerStatus() BreakerStatus {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.Breaker.Status(ClockNow(te.Clock))
+}
+
+// GetParams returns the economic settings in effect
+func (te *TokenEconomics) GetParams() EconomicParams {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.params()
+}
+
+// gameReward calculates a game reward and how it was reached, and counts it against the hourly and daily ceilings
+// and any pool's budget, leaving the caller to count it as minted or reserved
+// The caller must hold the lock
+func (te *TokenEconomics) gameReward(
+    now int64,
+    matchDuration int64,
+    playerRank int,
+    performanceScore float64,
+    poolID string,
+    mode string,
+) (Amount, RewardBreakdown, error) {
+    // Check if we've reached the yearly cap
+    remainingYearlyCap := te.remainingYearlyCap()
+    if remainingYearlyCap <= 0 {
+        return 0, RewardBreakdown{}, errors.New("yearly token supply cap reached")
+    }
+    
+    pool, err := te.runningPool(poolID, now)
+    if err != nil {
+        return 0, RewardBreakdown{}, err
+    }
+    
+    base, breakdown := te.params().calculateGameReward(now, matchDuration, playerRank, performanceScore, mode)
+    
+    if pool != nil {
+        reward := pool.draw(&breakdown, te.Breaker, now, remainingYearlyCap)
+        te.Breaker.Record(now, reward)
+        
+        return reward, breakdown, nil
+    }
+    
+    // Scale by how fast matches are earning against the rest of the yearly budget
+    // This spreads tokens evenly over the rest of the year instead of running the cap out early
+    multiplier := te.Emission.Adjust(now, remainingYearlyCap, te.YearStartTime+yearDuration-now)
+    te.Emission.Record(now, base)
+    breakdown.Multiplier = multiplier
+    reward := base.MulRate(multiplier)
+    
+    // Throttle rewards as the hourly and daily ceilings near, and ensure we don't exceed yearly cap
+    reward = breakdown.limit(te.Breaker, now, reward)
+    reward = breakdown.cap(reward, remainingYearlyCap, RewardCapYearly)
+    breakdown.Reward = reward
+    
+    // Update the windowed minted amounts
+    te.Breaker.Record(now, reward)
+    
+    return reward, breakdown, nil
+}
+
+// runningPool returns the reward pool a match draws from, nil for the yearly budget, if it is running at a time
+// The caller must hold the lock
+func (te *TokenEconomics) runningPool(poolID string, now int64) (*RewardPool, error) {
+    if poolID == "" {
+        return nil, nil
+    }
+    
+    pool := te.RewardPools[poolID]
+    if pool == nil {
+        return nil, fmt.Errorf("%w: %s", ErrRewardPoolNotFound, poolID)
+    }
+    if !pool.Running(now) {
+        return nil, fmt.Errorf("reward pool %s runs from %d to %d", poolID, pool.StartTime, pool.EndTime)
+    }
+    
+    return pool, nil
+}
+
+// calculateGameReward calculates a game reward from the match and the season running, before the pool or emission
+// controller scales it and any limit cuts it
+func (p EconomicParams) calculateGameReward(
+    now int64,
+    matchDuration int64,
+    playerRank int,
+    performanceScore float64,
+    mode string,
+) (Amount, RewardBreakdown) {
+    // Base reward calculation
+    // Longer matches, higher ranks, and better performance = more rewards
+    // Adjust for match duration (longer matches = more rewards, up to a cap)
+    // Convert seconds to minutes
+    matchMinutes := float64(matchDuration) / 60.0
+    durationFactor := p.durationFactor(matchMinutes)
+    
+    // Adjust for player rank (higher rank = more rewards)
+    rankFactor := p.rankFactor(playerRank)
+    
+    // Adjust for performance score
+    // Performance score should be between 0 and 100
+    if performanceScore < 0 {
+        performanceScore = 0
+    } else if performanceScore > 100 {
+        performanceScore = 100
+    }
+    
+    performanceFactor := p.performanceFactor(performanceScore)
+    
+    // Calculate reward
+    rewardILYZ := p.BaseGameReward.Float() * durationFactor * rankFactor * performanceFactor
+    breakdown := RewardBreakdown{
+        BaseReward:        p.BaseGameReward,
+        DurationFactor:    durationFactor,
+        RankFactor:        rankFactor,
+        PerformanceFactor: performanceFactor,
+    }
+    
+    // Adjust for the competitive season running, if it covers the match's game mode
+    if season, running := CurrentSeason(p.Seasons, now); running && season.Eligible(mode) {
+        rewardILYZ *= season.Multiplier
+        breakdown.Season = season.ID
+        breakdown.SeasonFactor = season.Multiplier
+    }
+    
+    base := AmountFromFloat(rewardILYZ)
+    breakdown.Calculated = base
+    
+    return base, breakdown
+}
+
+// params returns the economic settings in effect, the governance source's when there is one
+// The caller must hold the lock
+func (te *TokenEconomics) params() EconomicParams {
+    if te.Governance == nil {
+        return te.Params
+    }
+    
+    return te.Governance.EconomicParams()
+}
+
+// GetYearlySupplyCap returns the supply cap for the current year
+func (te *TokenEconomics) GetYearlySupplyCap() Amount {
+    return te.Schedule.CapForYear(te.CurrentYear)
+}
+
+// GetSchedule returns the supply schedule and the current year of it
+func (te *TokenEconomics) GetSchedule() (SupplySchedule, int) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.Schedule, te.CurrentYear
+}
+
+// remainingYearlyCap returns what can still be minted this year, less the rewards held in escrow
+// The caller must hold the lock
+func (te *TokenEconomics) remainingYearlyCap() Amount {
+    return te.GetYearlySupplyCap() - te.YearlyMinted - te.Reserved
+}
+
+// CheckYearTransition checks if we've moved to a new year by the economics' clock and updates state
+// The blockchain advances the year by each block's timestamp instead, through AdvanceYear
+func (te *TokenEconomics) CheckYearTransition() bool {
+    return len(te.AdvanceYear(ClockNow(te.Clock))) > 0
+}
+
+// GetRemainingYearlySupply returns the remaining supply that can be minted this year
+func (te *TokenEconomics) GetRemainingYearlySupply() Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.remainingYearlyCap()
+}
+
+// GetTotalSupply returns the current total supply of tokens
+func (te *TokenEconomics) GetTotalSupply() Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.CurrentSupply
+}
+
+// GetFeesBurned returns the transaction fees burned in total
+func (te *TokenEconomics) GetFeesBurned() Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.FeesBurned
+}
+
+// SyncSupply sets the total supply and fees burned to the chain ledger's, which records every mint and burn
+// The blockchain calls it as blocks are executed and reverted, so the supply can't drift from the balances
+func (te *TokenEconomics) SyncSupply(supply Amount, feesBurned Amount) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    te.CurrentSupply = supply
+    te.FeesBurned = feesBurned
+    te.persist()
+}
+
+// SyncBaseFee sets the base fee to the one the chain's fee market asks of the next block
+// The blockchain calls it as blocks are executed and reverted
+func (te *TokenEconomics) SyncBaseFee(baseFee Amount) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    te.BaseFee = baseFee
+}
+
+// GetBaseFee returns the least fee the next block's transactions must pay
+func (te *TokenEconomics) GetBaseFee() Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.BaseFee
+}
+
+// GetYearStartTime returns when the current supply cap year started
+func (te *TokenEconomics) GetYearStartTime() int64 {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.YearStartTime
+}
+
+// BlockRewardAllowance returns how much of a block reward fits within the yearly supply cap, beside rewards
+// already allowed for the same block
+// Nothing is counted until the block is applied and its rewards are recorded
+func (te *TokenEconomics) BlockRewardAllowance(amount Amount, allowed Amount) Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    // Ensure we don't exceed yearly cap
+    remainingYearlyCap := te.remainingYearlyCap() - allowed
+    if amount > remainingYearlyCap {
+        amount = remainingYearlyCap
+    }
+    if amount < 0 {
+        amount = 0
+    }
+    
+    return amount
+}
+
+// BlockRewardCeiling returns the most a block reward may mint within the yearly supply cap, as counted from the chain
+// Rewards held in escrow are left out, since only the node holding them knows of them; a producer's allowance never exceeds it
+func (te *TokenEconomics) BlockRewardCeiling() Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    ceiling := te.GetYearlySupplyCap() - te.YearlyMinted
+    if ceiling < 0 {
+        ceiling = 0
+    }
+    
+    return ceiling
+}
+
+// RecordBlockRewards counts the rewards an applied block minted against the yearly supply cap
+// The blockchain calls it for every block it applies, whoever produced it
+func (te *TokenEconomics) RecordBlockRewards(amount Amount) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    if amount <= 0 {
+        return
+    }
+    
+    te.YearlyMinted += amount
+    te.persist()
+}
+
+// ReconcileBlockRewards raises the yearly minted count to at least the block rewards the chain minted this year
+// The count is never lowered here, since game rewards and yield are counted beside block rewards;
+// the chain takes off the rewards of blocks it rolls back through RevertBlockRewards
+func (te *TokenEconomics) ReconcileBlockRewards(chainRewards Amount) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    if chainRewards > te.YearlyMinted {
+        te.YearlyMinted = chainRewards
+        te.persist()
+    }
+}
+
+// RevertBlockRewards takes the rewards of blocks rolled back off the yearly minted count
+// The blockchain calls it with the rewards of the removed blocks minted since the year started
+func (te *TokenEconomics) RevertBlockRewards(amount Amount) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    if amount <= 0 {
+        return
+    }
+    
+    te.YearlyMinted -= amount
+    if te.YearlyMinted < 0 {
+        te.YearlyMinted = 0
+    }
+    te.persist()
+}
+
+// MintYield counts yield generator emissions against the yearly yield and supply caps
+// Returns the amount actually minted, which is less than requested once either cap is near
+func (te *TokenEconomics) MintYield(amount Amount) Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    // Ensure we exceed neither the yield cap nor the yearly supply cap, throttling as the hourly and daily ceilings near
+    now := ClockNow(te.Clock)
+    amount = te.Breaker.Limit(now, amount)
+    remainingYield := te.YieldEmissionCap - te.YieldEmitted
+    if amount > remainingYield {
+        amount = remainingYield
+    }
+    remainingYearlyCap := te.remainingYearlyCap()
+    if amount > remainingYearlyCap {
+        amount = remainingYearlyCap
+    }
+    if amount < 0 {
+        amount = 0
+    }
+    
+    te.Breaker.Record(now, amount)
+    te.YieldEmitted += amount
+    te.YearlyMinted += amount
+    te.persist()
+    
+    return amount
+}
+
+// GetRemainingYieldEmissions returns the yield that can still be emitted this year
+func (te *TokenEconomics) GetRemainingYieldEmissions() Amount {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+    
+    return te.YieldEmissionCap - te.YieldEmitted
+}
@@ -0,0 +1,85 @@
+package token
+
+// YearTransition is the supply cap year rolling over, with what the year that ended minted against its cap
+type YearTransition struct {
+    PreviousYear    int    `json:"previousYear"`
+    Year            int    `json:"year"`
+    StartTime       int64  `json:"startTime"` // Unix time the new year started
+    PreviousCap     Amount `json:"previousCap"`
+    Cap             Amount `json:"cap"`
+    Minted          Amount `json:"minted"`          // Minted in the year that ended, yield and referral rewards included
+    YieldEmitted    Amount `json:"yieldEmitted"`    // Emitted as yield in the year that ended
+    ReferralPaid    Amount `json:"referralPaid"`    // Paid to referrers in the year that ended
+    Unminted        Amount `json:"unminted"`        // Left of the previous cap, which lapses rather than carrying over
+    CarriedReserved Amount `json:"carriedReserved"` // Game rewards still in escrow, counted against the new year's cap
+    Supply          Amount `json:"supply"`
+    FeesBurned      Amount `json:"feesBurned"`
+}
+
+// YearTransitionHook is called after each year rollover, such as to notify game backends or snapshot the supply
+type YearTransitionHook func(transition YearTransition)
+
+// OnYearTransition registers a hook called after each year rollover, in the order registered
+// Hooks run outside the economics' lock, so they may read the economics, but the blockchain calls them while
+// applying a block, so they must not call back into the blockchain
+func (te *TokenEconomics) OnYearTransition(hook YearTransitionHook) {
+    te.mutex.Lock()
+    defer te.mutex.Unlock()
+
+    te.yearHooks = append(te.yearHooks, hook)
+}
+
+// AdvanceYear turns the supply cap year over for every year boundary a block's timestamp has passed
+// Each year starts exactly a year after the last, so the boundaries depend only on block timestamps,
+// and the transitions are returned after the registered hooks have been called with them
+func (te *TokenEconomics) AdvanceYear(timestamp int64) []YearTransition {
+    te.mutex.Lock()
+
+    var transitions []YearTransition
+    for timestamp-te.YearStartTime >= yearDuration {
+        transitions = append(transitions, te.turnYear())
+    }
+    if len(transitions) > 0 {
+        te.persist()
+    }
+    hooks := append([]YearTransitionHook(nil), te.yearHooks...)
+
+    te.mutex.Unlock()
+
+    for _, transition := range transitions {
+        for _, hook := range hooks {
+            hook(transition)
+        }
+    }
+
+    return transitions
+}
+
+// turnYear starts the next supply cap year and returns what the year that ended minted
+// The caller must hold the lock
+func (te *TokenEconomics) turnYear() YearTransition {
+    transition := YearTransition{
+        PreviousYear:    te.CurrentYear,
+        Year:            te.CurrentYear + 1,
+        StartTime:       te.YearStartTime + yearDuration,
+        PreviousCap:     te.GetYearlySupplyCap(),
+        Minted:          te.YearlyMinted,
+        YieldEmitted:    te.YieldEmitted,
+        ReferralPaid:    te.ReferralPaid,
+        CarriedReserved: te.Reserved,
+        Supply:          te.CurrentSupply,
+        FeesBurned:      te.FeesBurned,
+    }
+    if unminted := transition.PreviousCap - te.YearlyMinted - te.Reserved; unminted > 0 {
+        transition.Unminted = unminted
+    }
+
+    te.CurrentYear++
+    te.YearStartTime = transition.StartTime
+    te.YearlyMinted = 0
+    te.YieldEmitted = 0
+    te.ReferralPaid = 0
+    transition.Cap = te.GetYearlySupplyCap()
+
+    return transition
+}
@@ -0,0 +1,63 @@
+package token
+
+import (
+    "errors"
+    "math"
+)
+
+// Default yield curve: 12% with nothing staked, falling to 3% once 60% of the supply is staked,
+// which gives the former fixed 7% with about a third of the supply staked
+const (
+    DefaultYieldFloor       = 0.03
+    DefaultYieldCeiling     = 0.12
+    DefaultYieldTargetRatio = 0.6
+)
+
+// YieldCurve sets the yearly yield rate by the share of the supply staked or locked: the rate falls linearly
+// from the ceiling with nothing staked to the floor at the target share, and stays there above it,
+// so yield draws stake in while participation is low and stops inflating the supply once it is high
+// A floor equal to the ceiling fixes the rate
+type YieldCurve struct {
+    Floor       float64 `json:"floor"`       // Rate once the target share or more is staked
+    Ceiling     float64 `json:"ceiling"`     // Rate with nothing staked
+    TargetRatio float64 `json:"targetRatio"` // Share of the supply staked from which the rate is the floor
+}
+
+// DefaultYieldCurve returns a curve from 12% with nothing staked to 3% with 60% staked
+func DefaultYieldCurve() YieldCurve {
+    return YieldCurve{
+        Floor:       DefaultYieldFloor,
+        Ceiling:     DefaultYieldCeiling,
+        TargetRatio: DefaultYieldTargetRatio,
+    }
+}
+
+// Validate checks that a curve's floor is not negative and at most its ceiling, and its target share is above 0 and at most 1
+func (c YieldCurve) Validate() error {
+    if !(c.Floor >= 0) || math.IsInf(c.Ceiling, 0) {
+        return errors.New("yield floor must not be negative and ceiling must be finite")
+    }
+
+    if c.Floor > c.Ceiling {
+        return errors.New("yield floor must not be above the ceiling")
+    }
+
+    if !(c.TargetRatio > 0) || c.TargetRatio > 1 {
+        return errors.New("yield target ratio must be above 0 and at most 1")
+    }
+
+    return nil
+}
+
+// Rate returns the yearly yield rate with a share of the supply staked
+func (c YieldCurve) Rate(stakedRatio float64) float64 {
+    progress := stakedRatio / c.TargetRatio
+    if !(progress > 0) {
+        return c.Ceiling
+    }
+    if progress >= 1 {
+        return c.Floor
+    }
+
+    return c.Ceiling - (c.Ceiling-c.Floor)*progress
+}
@@ -3,9 +3,9 @@ package wallet
 import (
     "encoding/json"
     "errors"
-    "time"
 
-    "../crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/crypto"
+    "github.com/txaimhawj/chulubmeadditional-files/token"
 )
 
 // Wallet represents a user's blockchain wallet
@@ -14,12 +14,17 @@ type Wallet struct {
     PublicKey  string `json:"publicKey"`
     PrivateKey string `json:"privateKey,omitempty"` // Only stored locally, never transmitted
     Balance    struct {
-        ILYZ float64 `json:"ilyz"`
+        ILYZ   token.Amount            `json:"ilyz"`
+        Assets map[string]token.Amount `json:"assets,omitempty"` // Other assets held, by asset ID
     } `json:"balance"`
     NFTs        []NFT      `json:"nfts"`
     Transactions []string   `json:"transactions"` // Transaction IDs
     CreatedAt   int64      `json:"createdAt"`
     LastUpdated int64      `json:"lastUpdated"`
+    Referrer    string     `json:"referrer,omitempty"` // Who referred the wallet's owner, bound on chain by its first transaction
+    
+    // Source of the current time for timestamps and yield accrual, the wall clock if nil
+    Clock token.Clock `json:"-"`
 }
 
 // NFT represents a non-fungible token in the wallet
@@ -50,11 +55,28 @@ func CreateWallet() (*Wallet, error) {
         PrivateKey: crypto.PrivateKeyToHex(keyPair.PrivateKey),
         NFTs:       []NFT{},
         Transactions: []string{},
-        CreatedAt:  time.Now().Unix(),
-        LastUpdated: time.Now().Unix(),
+    }
+    wallet.CreatedAt = wallet.now()
+    wallet.LastUpdated = wallet.CreatedAt
+    
+    wallet.Balance.ILYZ = 0
+    
+    return wallet, nil
+}
+
+// CreateReferredWallet generates a new wallet for a player referred by another address
+// The referral takes effect once the wallet sends a referral transaction naming the referrer, before it holds any ILYZ
+func CreateReferredWallet(referrer string) (*Wallet, error) {
+    if referrer == "" {
+        return nil, errors.New("referrer is required")
+    }
+    
+    wallet, err := CreateWallet()
+    if err != nil {
+        return nil, err
     }
     
-    wallet.Balance.ILYZ = 0.0
+    wallet.Referrer = referrer
     
     return wallet, nil
 }
@@ -81,7 +103,7 @@ func SaveWallet(wallet *Wallet, includePrivateKey bool) (string, error) {
     }
     
     // Update last updated timestamp
-    walletCopy.LastUpdated = time.Now().Unix()
+    walletCopy.LastUpdated = wallet.now()
     
     // Convert to JSON
     jsonData, err := json.MarshalIndent(walletCopy, "", "  ")
@@ -121,7 +143,7 @@ func (w *Wallet) SignTransaction(transactionData []byte) (string, error) {
 // AddNFT adds an NFT to the wallet
 func (w *Wallet) AddNFT(nft NFT) {
     w.NFTs = append(w.NFTs, nft)
-    w.LastUpdated = time.Now().Unix()
+    w.LastUpdated = w.now()
 }
 
 // RemoveNFT removes an NFT from the wallet
@@ -130,7 +152,7 @@ func (w *Wallet) RemoveNFT(nftID string) error {
         if nft.ID == nftID {
             // Remove NFT from slice
             w.NFTs = append(w.NFTs[:i], w.NFTs[i+1:]...)
-            w.LastUpdated = time.Now().Unix()
+            w.LastUpdated = w.now()
             return nil
         }
     }
@@ -141,19 +163,42 @@ func (w *Wallet) RemoveNFT(nftID string) error {
 // AddTransaction adds a transaction ID to the wallet's transaction history
 func (w *Wallet) AddTransaction(transactionID string) {
     w.Transactions = append(w.Transactions, transactionID)
-    w.LastUpdated = time.Now().Unix()
+    w.LastUpdated = w.now()
 }
 
 // UpdateBalance updates the wallet's ILYZ balance
-func (w *Wallet) UpdateBalance(amount float64) {
+func (w *Wallet) UpdateBalance(amount token.Amount) {
     w.Balance.ILYZ = amount
-    w.LastUpdated = time.Now().Unix()
+    w.LastUpdated = w.now()
+}
+
+// UpdateAssetBalance updates the wallet's balance of an asset, ILYZ included
+func (w *Wallet) UpdateAssetBalance(asset string, amount token.Amount) {
+    if asset == token.NativeAsset {
+        w.UpdateBalance(amount)
+        return
+    }
+
+    if w.Balance.Assets == nil {
+        w.Balance.Assets = make(map[string]token.Amount)
+    }
+    w.Balance.Assets[asset] = amount
+    w.LastUpdated = w.now()
+}
+
+// AssetBalance returns the wallet's balance of an asset, ILYZ included
+func (w *Wallet) AssetBalance(asset string) token.Amount {
+    if asset == token.NativeAsset {
+        return w.Balance.ILYZ
+    }
+
+    return w.Balance.Assets[asset]
 }
 
 // CalculateYield calculates and updates yield for yield-generating NFTs
-func (w *Wallet) CalculateYield() float64 {
-    currentTime := time.Now().Unix()
-    totalYield := 0.0
+func (w *Wallet) CalculateYield() token.Amount {
+    currentTime := w.now()
+    totalYield := token.Amount(0)
     
     for i, nft := range w.NFTs {
         if nft.Type == "yield_generator" && nft.YieldRate > 0 {
@@ -165,7 +210,7 @@ func (w *Wallet) CalculateYield() float64 {
             
             // Calculate yield based on rate (e.g., 7% APY = 0.07 / 365 per day)
             dailyRate := nft.YieldRate / 365.0
-            yield := w.Balance.ILYZ * dailyRate * daysSinceLastYield
+            yield := w.Balance.ILYZ.MulRate(dailyRate * daysSinceLastYield)
             
             // Update last yield time
             w.NFTs[i].LastYield = currentTime
@@ -180,3 +225,8 @@ func (w *Wallet) CalculateYield() float64 {
     
     return totalYield
 }
+
+// now returns the wallet clock's time
+func (w *Wallet) now() int64 {
+    return token.ClockNow(w.Clock)
+}